@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/kolobock/rockpi-quad-go/internal/button"
+	"github.com/kolobock/rockpi-quad-go/internal/config"
+)
+
+// runButtonTest implements the "button-test" subcommand: it watches a button line
+// and prints every press and release with its timing and the gestures they are
+// classified as, to check the chip and line and tune the [time] twice/press/hold values
+func runButtonTest(args []string, out io.Writer) int {
+	fs := flag.NewFlagSet("button-test", flag.ContinueOnError)
+	fs.SetOutput(out)
+	configPath := fs.String("config", config.DefaultPath, "configuration file")
+	id := fs.String("button", "", "button to watch by id, e.g. main (default the first configured)")
+	twice := fs.Float64("twice", 0, "double click window in seconds to try (default [time] twice)")
+	press := fs.Float64("press", 0, "long press time in seconds to try (default [time] press)")
+	hold := fs.Float64("hold", 0, "hold time in seconds to try (default [time] hold)")
+	force := fs.Bool("force", false, "run even while the daemon is running")
+	fs.Usage = func() {
+		fmt.Fprintln(out, "Usage: rockpi-quad-go button-test [options]")
+		fmt.Fprintln(out, "\nPrints button edges and the gestures they are classified as until interrupted.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if pids := findProcesses(isDaemon); len(pids) > 0 && !*force {
+		fmt.Fprintf(out, "rockpi-quad-go is running (pid %v) and holds the button lines; stop it first, "+
+			"e.g. `systemctl stop rockpi-quad-go`, or pass --force\n", pids)
+		return 1
+	}
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		fmt.Fprintf(out, "Failed to load config: %v\n", err)
+		return 1
+	}
+	bc, ok := findButton(cfg.Buttons, *id)
+	if !ok {
+		fmt.Fprintf(out, "no button %q configured\n", *id)
+		return 1
+	}
+	timing := cfg.Time
+	for _, o := range []struct {
+		flag  float64
+		value *float64
+	}{{*twice, &timing.Twice}, {*press, &timing.Press}, {*hold, &timing.Hold}} {
+		if o.flag > 0 {
+			*o.value = o.flag
+		}
+	}
+
+	ctrl, err := button.New(bc, timing)
+	if err != nil {
+		fmt.Fprintf(out, "button-test: %v\n", err)
+		return 1
+	}
+	defer ctrl.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Edges are printed from the detector before it emits their gesture, so the
+	// lock keeps both in the order they happened
+	var mu sync.Mutex
+	printf := func(format string, args ...any) {
+		mu.Lock()
+		defer mu.Unlock()
+		fmt.Fprintf(out, format, args...)
+	}
+	var log edgeLog
+	ctrl.OnEdge(func(pressed bool, at time.Duration) {
+		printf("%s\n", log.line(pressed, at))
+	})
+
+	printf("Button %s on %s line %s (%s, %s)\n", bc.ID, valueOr(bc.Chip, "gpiochip0"), bc.Line, valueOr(bc.Bias, "pull-up"), activeLevel(bc.ActiveHigh))
+	printf("twice %gs, press %gs, hold %gs, debounce %gs; press Ctrl-C to stop\n", timing.Twice, timing.Press, timing.Hold, timing.Debounce)
+	go ctrl.Run(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return 0
+		case evt := <-ctrl.PressChan():
+			printf("%10s-> %s\n", "", evt)
+		}
+	}
+}
+
+// findButton returns the button with id, or the first one when id is empty
+func findButton(buttons []config.ButtonConfig, id string) (config.ButtonConfig, bool) {
+	for _, bc := range buttons {
+		if id == "" || bc.ID == id {
+			return bc, true
+		}
+	}
+	return config.ButtonConfig{}, false
+}
+
+func activeLevel(activeHigh bool) string {
+	if activeHigh {
+		return "active high"
+	}
+	return "active low"
+}
+
+// edgeLog formats button edges relative to the first one, with how long each press
+// was held and how long after the last release the next press came
+type edgeLog struct {
+	start, lastPress, lastRelease time.Duration
+	started, released             bool
+}
+
+func (l *edgeLog) line(pressed bool, at time.Duration) string {
+	if !l.started {
+		l.start, l.started = at, true
+	}
+	prefix := fmt.Sprintf("%8.3fs  ", (at - l.start).Seconds())
+	if !pressed {
+		l.lastRelease, l.released = at, true
+		return prefix + "release  held " + (at - l.lastPress).Round(time.Millisecond).String()
+	}
+
+	l.lastPress = at
+	if !l.released {
+		return prefix + "press"
+	}
+	return prefix + "press    " + (at - l.lastRelease).Round(time.Millisecond).String() + " after the last release"
+}
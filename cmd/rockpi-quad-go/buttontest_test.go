@@ -0,0 +1,47 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kolobock/rockpi-quad-go/internal/config"
+)
+
+func TestEdgeLog(t *testing.T) {
+	ms := func(n int) time.Duration { return 5*time.Second + time.Duration(n)*time.Millisecond }
+	var log edgeLog
+	tests := []struct {
+		pressed bool
+		at      time.Duration
+		want    string
+	}{
+		{true, ms(0), "   0.000s  press"},
+		{false, ms(84), "   0.084s  release  held 84ms"},
+		{true, ms(301), "   0.301s  press    217ms after the last release"},
+		{false, ms(2100), "   2.100s  release  held 1.799s"},
+	}
+	for _, tt := range tests {
+		if got := log.line(tt.pressed, tt.at); got != tt.want {
+			t.Errorf("line(%t, %s) = %q, want %q", tt.pressed, tt.at, got, tt.want)
+		}
+	}
+}
+
+func TestFindButton(t *testing.T) {
+	buttons := []config.ButtonConfig{{ID: "main", Line: "17"}, {ID: "aux", Line: "27"}}
+	tests := []struct {
+		id     string
+		want   string
+		wantOK bool
+	}{
+		{"", "main", true},
+		{"aux", "aux", true},
+		{"front", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := findButton(buttons, tt.id)
+		if got.ID != tt.want || ok != tt.wantOK {
+			t.Errorf("findButton(%q) = %q, %t, want %q, %t", tt.id, got.ID, ok, tt.want, tt.wantOK)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/kolobock/rockpi-quad-go/internal/config"
+	"github.com/kolobock/rockpi-quad-go/internal/disk"
+	"github.com/kolobock/rockpi-quad-go/internal/thermal"
+)
+
+// Nagios plugin exit codes
+const (
+	checkOK       = 0
+	checkWarning  = 1
+	checkCritical = 2
+	checkUnknown  = 3
+)
+
+var checkStateNames = []string{"OK", "WARNING", "CRITICAL", "UNKNOWN"}
+
+// checkReading is one temperature measured by the check subcommand
+type checkReading struct {
+	label      string
+	temp       float64
+	warn, crit float64
+}
+
+func (r checkReading) state() int {
+	switch {
+	case r.crit > 0 && r.temp >= r.crit:
+		return checkCritical
+	case r.warn > 0 && r.temp >= r.warn:
+		return checkWarning
+	default:
+		return checkOK
+	}
+}
+
+// formatCheck renders the plugin output line with performance data and returns the exit code
+func formatCheck(readings []checkReading, problems []string) (string, int) {
+	code := checkOK
+	var summary, perf []string
+	for _, r := range readings {
+		code = max(code, r.state())
+		summary = append(summary, fmt.Sprintf("%s %.1f°C", r.label, r.temp))
+		perf = append(perf, fmt.Sprintf("%s=%.1f;%s;%s", r.label, r.temp, threshold(r.warn), threshold(r.crit)))
+	}
+	// Unreadable sensors are reported, but a real temperature problem takes precedence
+	if len(problems) > 0 {
+		summary = append(summary, problems...)
+		if code == checkOK {
+			code = checkUnknown
+		}
+	}
+
+	line := "TEMP " + checkStateNames[code] + " - " + strings.Join(summary, ", ")
+	if len(perf) > 0 {
+		line += " | " + strings.Join(perf, " ")
+	}
+	return line, code
+}
+
+func threshold(v float64) string {
+	if v <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("%g", v)
+}
+
+// runCheck implements the "check" subcommand, a Nagios/Icinga compatible service check
+func runCheck(args []string, out io.Writer) int {
+	fs := flag.NewFlagSet("check", flag.ContinueOnError)
+	fs.SetOutput(out)
+	configPath := fs.String("config", config.DefaultPath, "configuration file (for CPU sensor selection)")
+	cpuWarn := fs.Float64("cpu-warn", 70, "CPU warning temperature (°C, 0 disables)")
+	cpuCrit := fs.Float64("cpu-crit", 80, "CPU critical temperature (°C, 0 disables)")
+	diskWarn := fs.Float64("disk-warn", 50, "disk warning temperature (°C, 0 disables)")
+	diskCrit := fs.Float64("disk-crit", 60, "disk critical temperature (°C, 0 disables)")
+	disks := fs.Bool("disks", true, "check SATA disk temperatures with smartctl")
+	if err := fs.Parse(args); err != nil {
+		return checkUnknown
+	}
+
+	var sensors []string
+	mode := thermal.ModeMax
+	if cfg, err := config.Load(*configPath); err == nil {
+		sensors, mode = cfg.Fan.CPUSensors, cfg.Fan.CPUSensorMode
+	}
+
+	var readings []checkReading
+	var problems []string
+	if temp, err := thermal.Read(sensors, mode); err == nil {
+		readings = append(readings, checkReading{label: "cpu", temp: temp, warn: *cpuWarn, crit: *cpuCrit})
+	} else {
+		problems = append(problems, "cpu unreadable")
+	}
+
+	if *disks {
+		for _, dev := range disk.GetSATADisks() {
+			name := strings.TrimPrefix(dev, "/dev/")
+			temp, err := disk.GetTemperature(dev)
+			if err != nil {
+				problems = append(problems, name+" unreadable")
+				continue
+			}
+			readings = append(readings, checkReading{label: name, temp: temp, warn: *diskWarn, crit: *diskCrit})
+		}
+	}
+
+	line, code := formatCheck(readings, problems)
+	fmt.Fprintln(out, line)
+	return code
+}
@@ -0,0 +1,63 @@
+package main
+
+import "testing"
+
+func TestFormatCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		readings []checkReading
+		problems []string
+		wantLine string
+		wantCode int
+	}{
+		{
+			name: "all ok",
+			readings: []checkReading{
+				{label: "cpu", temp: 45.2, warn: 70, crit: 80},
+				{label: "sda", temp: 38, warn: 50, crit: 60},
+			},
+			wantLine: "TEMP OK - cpu 45.2°C, sda 38.0°C | cpu=45.2;70;80 sda=38.0;50;60",
+			wantCode: checkOK,
+		},
+		{
+			name: "disk warning",
+			readings: []checkReading{
+				{label: "cpu", temp: 45, warn: 70, crit: 80},
+				{label: "sdb", temp: 52, warn: 50, crit: 60},
+			},
+			wantLine: "TEMP WARNING - cpu 45.0°C, sdb 52.0°C | cpu=45.0;70;80 sdb=52.0;50;60",
+			wantCode: checkWarning,
+		},
+		{
+			name:     "critical beats unreadable",
+			readings: []checkReading{{label: "cpu", temp: 85, warn: 70, crit: 80}},
+			problems: []string{"sda unreadable"},
+			wantLine: "TEMP CRITICAL - cpu 85.0°C, sda unreadable | cpu=85.0;70;80",
+			wantCode: checkCritical,
+		},
+		{
+			name:     "nothing readable",
+			problems: []string{"cpu unreadable"},
+			wantLine: "TEMP UNKNOWN - cpu unreadable",
+			wantCode: checkUnknown,
+		},
+		{
+			name:     "disabled thresholds",
+			readings: []checkReading{{label: "cpu", temp: 90}},
+			wantLine: "TEMP OK - cpu 90.0°C | cpu=90.0;;",
+			wantCode: checkOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line, code := formatCheck(tt.readings, tt.problems)
+			if line != tt.wantLine {
+				t.Errorf("line = %q, want %q", line, tt.wantLine)
+			}
+			if code != tt.wantCode {
+				t.Errorf("code = %d, want %d", code, tt.wantCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/kolobock/rockpi-quad-go/internal/config"
+)
+
+// apiClient talks to the running daemon's API
+type apiClient struct {
+	base  string
+	token string
+	http  *http.Client
+}
+
+// apiOptions are the flags subcommands use to reach the daemon
+type apiOptions struct {
+	configPath *string
+	apiURL     *string
+	token      *string
+}
+
+func addAPIFlags(fs *flag.FlagSet) apiOptions {
+	return apiOptions{
+		configPath: fs.String("config", config.DefaultPath, "configuration file (for the API address)"),
+		apiURL:     fs.String("api", "", "API base URL (default: http://<[api] listen>)"),
+		token:      fs.String("token", os.Getenv("ROCKPI_API_TOKEN"), "bearer token (default $ROCKPI_API_TOKEN)"),
+	}
+}
+
+// client returns a client for --api, falling back to the listen address of the configuration
+func (o apiOptions) client() *apiClient {
+	base := *o.apiURL
+	if base == "" {
+		listen := "127.0.0.1:8080"
+		if cfg, err := config.Load(*o.configPath); err == nil {
+			listen = cfg.API.Listen
+		}
+		base = "http://" + listen
+	}
+	return &apiClient{base: strings.TrimRight(base, "/"), token: *o.token, http: &http.Client{Timeout: 5 * time.Second}}
+}
+
+// do sends body as JSON and decodes the response into out
+func (c *apiClient) do(method, path string, body, out any) error {
+	var reqBody io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, c.base+path, reqBody)
+	if err != nil {
+		return err
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&apiErr)
+		return fmt.Errorf("%s %s: %s %s", method, path, resp.Status, apiErr.Error)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("invalid response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kolobock/rockpi-quad-go/internal/board"
+	"github.com/kolobock/rockpi-quad-go/internal/disk"
+)
+
+// runDetectHardware implements the "detect-hardware" subcommand
+func runDetectHardware(args []string, out io.Writer) int {
+	fs := flag.NewFlagSet("detect-hardware", flag.ContinueOnError)
+	fs.SetOutput(out)
+	smart := fs.Bool("smart", true, "read disk temperatures with smartctl")
+	fs.Usage = func() {
+		fmt.Fprintln(out, "Usage: rockpi-quad-go detect-hardware [--smart=false]")
+		fmt.Fprintln(out, "\nProbes I2C buses for the SSD1306 OLED, lists PWM and GPIO chips, checks disks")
+		fmt.Fprintln(out, "with smartctl and prints a ready-to-use env snippet.")
+		fmt.Fprintln(out, "\nBoard integration notes:")
+		for _, p := range board.Profiles {
+			if p.Notes != "" {
+				fmt.Fprintf(out, "  %s: %s\n", p.Name, p.Notes)
+			}
+		}
+	}
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+
+	fmt.Fprintf(out, "Model: %s\n", valueOr(board.Model(), "unknown"))
+
+	env := map[string]string{}
+	if profile, ok := board.Detect(); ok {
+		fmt.Fprintf(out, "Board profile: %s\n", profile.Name)
+		if profile.Notes != "" {
+			fmt.Fprintf(out, "Notes: %s\n", profile.Notes)
+		}
+		env = profile.Resolve()
+	} else {
+		fmt.Fprintln(out, "Board profile: none (set the env variables manually or BOARD=<profile>)")
+	}
+
+	if bus, ok := reportI2C(out); ok {
+		env["I2C_BUS"] = strconv.Itoa(bus)
+	}
+	if chip, ok := reportPWM(out); ok && env["PWM_CHIP"] == "" {
+		env["PWM_CHIP"] = chip
+	}
+	reportGPIO(out)
+	if *smart {
+		reportDisks(out)
+	}
+
+	fmt.Fprintln(out, "\n# /etc/rockpi-quad.env")
+	writeEnvSnippet(out, env)
+	return 0
+}
+
+// reportI2C probes every I2C bus for the display and returns the first bus it answers on
+func reportI2C(out io.Writer) (int, bool) {
+	fmt.Fprintln(out, "\nI2C buses (SSD1306 at 0x3C):")
+	buses := board.I2CBuses()
+	if len(buses) == 0 {
+		fmt.Fprintln(out, "  none found (is the I2C overlay enabled?)")
+	}
+	found, ok := 0, false
+	for _, bus := range buses {
+		status := "-"
+		if board.ProbeSSD1306(bus) {
+			status = "SSD1306 found"
+			if !ok {
+				found, ok = bus, true
+			}
+		}
+		fmt.Fprintf(out, "  i2c-%d: %s\n", bus, status)
+	}
+	return found, ok
+}
+
+// reportPWM lists the PWM chips and returns the first one
+func reportPWM(out io.Writer) (string, bool) {
+	fmt.Fprintln(out, "\nPWM chips:")
+	chips := board.PWMChips()
+	if len(chips) == 0 {
+		fmt.Fprintln(out, "  none found (is a PWM overlay enabled?)")
+		return "", false
+	}
+	for _, chip := range chips {
+		fmt.Fprintf(out, "  %s: %d channel(s) %s\n", chip.Name, chip.Channels, chip.Device)
+	}
+	return chips[0].Name, true
+}
+
+func reportGPIO(out io.Writer) {
+	fmt.Fprintln(out, "\nGPIO chips (unused input lines are button candidates):")
+	chips := board.GPIOChips()
+	if len(chips) == 0 {
+		fmt.Fprintln(out, "  none found")
+	}
+	for _, chip := range chips {
+		fmt.Fprintf(out, "  %s [%s] %d lines\n", chip.Name, chip.Label, chip.Lines)
+		if len(chip.Candidates) > 0 {
+			fmt.Fprintf(out, "    candidates: %s\n", strings.Join(chip.Candidates, " "))
+		}
+	}
+}
+
+func reportDisks(out io.Writer) {
+	fmt.Fprintln(out, "\nDisks (smartctl):")
+	disks := disk.GetSATADisks()
+	if len(disks) == 0 {
+		fmt.Fprintln(out, "  none found (is the SATA controller enabled?)")
+	}
+	for _, dev := range disks {
+		if temp, err := disk.GetTemperature(dev); err == nil {
+			fmt.Fprintf(out, "  %s: %.0f°C\n", dev, temp)
+		} else {
+			fmt.Fprintf(out, "  %s: smartctl failed: %v\n", dev, err)
+		}
+	}
+}
+
+func writeEnvSnippet(out io.Writer, env map[string]string) {
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(out, "%s=%s\n", k, env[k])
+	}
+}
+
+func valueOr(v, fallback string) string {
+	if v == "" {
+		return fallback
+	}
+	return v
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/kolobock/rockpi-quad-go/internal/config"
+	"github.com/kolobock/rockpi-quad-go/pkg/pwm"
+)
+
+// defaultEnvFile is the EnvironmentFile of the systemd unit
+const defaultEnvFile = "/etc/rockpi-quad.env"
+
+// runDumpConfig implements the "dump-config" subcommand: it prints the configuration
+// the daemon would run with, after the config file, the env variables and the board
+// profile defaults are resolved, to tell which of them a value came from
+func runDumpConfig(args []string, out io.Writer) int {
+	fs := flag.NewFlagSet("dump-config", flag.ContinueOnError)
+	fs.SetOutput(out)
+	configPath := fs.String("config", config.DefaultPath, "configuration file")
+	envPath := fs.String("env", defaultEnvFile, "env file the daemon is started with, \"\" for none")
+	fs.Usage = func() {
+		fmt.Fprintln(out, "Usage: rockpi-quad-go dump-config [options]")
+		fmt.Fprintln(out, "\nPrints the effective configuration, with secrets redacted.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	// The systemd unit reads the env file, a shell does not, so apply it the same way
+	fromFile, err := applyEnvFile(*envPath)
+	if err != nil {
+		fmt.Fprintf(out, "Failed to read env file: %v\n", err)
+		return 1
+	}
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		fmt.Fprintf(out, "Failed to load config: %v\n", err)
+		return 1
+	}
+
+	fmt.Fprintf(out, "# config file: %s\n", cfg.Path)
+	fmt.Fprintf(out, "# env file: %s\n", valueOr(*envPath, "none"))
+	fmt.Fprintf(out, "# board profile: %s\n", valueOr(cfg.Env.Board, "none"))
+
+	fmt.Fprintln(out, "\n# env variables")
+	writeEnvVars(out, cfg, fromFile, *envPath)
+
+	fmt.Fprintln(out, "\n# settings")
+	if err := cfg.Dump(out); err != nil {
+		fmt.Fprintf(out, "Failed to write config: %v\n", err)
+		return 1
+	}
+
+	fmt.Fprintln(out, "\n# derived")
+	for _, line := range derivedPaths(cfg) {
+		fmt.Fprintln(out, line)
+	}
+	return 0
+}
+
+// applyEnvFile sets the variables of a KEY=VALUE env file that are not already set,
+// as systemd would for the daemon, and returns their names. A missing file is not an
+// error, the daemon runs without one.
+func applyEnvFile(path string) (map[string]bool, error) {
+	applied := map[string]bool{}
+	if path == "" {
+		return applied, nil
+	}
+	f, err := os.Open(path) // #nosec G304 - path comes from the command line
+	if errors.Is(err, os.ErrNotExist) {
+		return applied, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(strings.TrimPrefix(line, "export "), "=")
+		if !ok {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		if _, set := os.LookupEnv(key); set {
+			continue
+		}
+		value = strings.Trim(strings.TrimSpace(value), `"'`)
+		if err := os.Setenv(key, value); err != nil {
+			return nil, err
+		}
+		applied[key] = true
+	}
+	return applied, scanner.Err()
+}
+
+// writeEnvVars prints every env variable the config read with its value and where
+// it came from
+func writeEnvVars(out io.Writer, cfg *config.Config, fromFile map[string]bool, envPath string) {
+	vars := cfg.EnvVars()
+	for _, key := range slices.Sorted(maps.Keys(vars)) {
+		v := vars[key]
+		switch {
+		case v.Source == config.EnvFromEnvironment && fromFile[key]:
+			v.Source = envPath
+		case v.Source == config.EnvFromBoard:
+			v.Source += " " + cfg.Env.Board
+		}
+		fmt.Fprintf(out, "%s = %q  # %s\n", key, v.Value, v.Source)
+	}
+}
+
+// derivedPaths returns the device paths the daemon opens for the configured
+// I2C bus and fan PWM channels
+func derivedPaths(cfg *config.Config) []string {
+	lines := []string{
+		fmt.Sprintf("I2CDevice = %q", fmt.Sprintf("/dev/i2c-%d", cfg.Env.I2CBus)),
+		fmt.Sprintf("PWMPath.cpu = %q", pwm.Path(cfg.Fan.CPUPWMChip, cfg.Fan.CPUPWMChannel)),
+		fmt.Sprintf("PWMPath.disk = %q", pwm.Path(cfg.Fan.TBPWMChip, cfg.Fan.TBPWMChannel)),
+	}
+	for _, h := range cfg.HATs {
+		if h.ID != config.MainHAT && h.PWMChannel >= 0 {
+			lines = append(lines, fmt.Sprintf("PWMPath.%s = %q", h.ID, pwm.Path(h.PWMChip, h.PWMChannel)))
+		}
+	}
+	return lines
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunDumpConfig(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "rockpi-quad.conf")
+	if err := os.WriteFile(configPath, []byte("[fan]\nlv0 = 30\n\n[smtp]\npassword = hunter2\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	envPath := filepath.Join(dir, "rockpi-quad.env")
+	env := "# hardware\nI2C_BUS=5\nPWM_CHIP=\"pwmchip2\"\n"
+	if err := os.WriteFile(envPath, []byte(env), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	// The environment wins over the env file; I2C_BUS is unset but restored after the test
+	t.Setenv("PWM_CHIP", "pwmchip9")
+	t.Setenv("I2C_BUS", "")
+	os.Unsetenv("I2C_BUS")
+
+	var out bytes.Buffer
+	if code := runDumpConfig([]string{"--config", configPath, "--env", envPath}, &out); code != 0 {
+		t.Fatalf("runDumpConfig() = %d, output %q", code, out.String())
+	}
+	for _, want := range []string{
+		"I2C_BUS = \"5\"  # " + envPath + "\n",
+		"PWM_CHIP = \"pwmchip9\"  # environment\n",
+		"Fan.LV0 = 30\n",
+		"Env.I2CBus = 5\n",
+		"SMTP.Password = \"<redacted>\"\n",
+		"I2CDevice = \"/dev/i2c-5\"\n",
+		"PWMPath.cpu = \"/sys/class/pwm/pwmchip9/pwm0\"\n",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output is missing %q:\n%s", want, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "hunter2") {
+		t.Error("output contains the SMTP password")
+	}
+}
+
+func TestApplyEnvFileMissing(t *testing.T) {
+	applied, err := applyEnvFile(filepath.Join(t.TempDir(), "missing.env"))
+	if err != nil || len(applied) != 0 {
+		t.Errorf("applyEnvFile() = %v, %v, want nothing applied", applied, err)
+	}
+}
@@ -0,0 +1,327 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/kolobock/rockpi-quad-go/internal/config"
+	"github.com/kolobock/rockpi-quad-go/pkg/pwm"
+)
+
+// lv0Duty is the duty cycle in percent the stepped fan curve runs a fan at from lv0
+const lv0Duty = 25
+
+// dutySetter is the part of a PWM channel the sweep drives
+type dutySetter interface {
+	SetDutyCycle(dutyCycle float64) error
+}
+
+// sweepFan is a fan swept by fan-test
+type sweepFan struct {
+	name string
+	pwm  dutySetter
+	// tach is the hwmon fanN_input file of the fan, empty without one
+	tach string
+}
+
+// sweepRow is the speed of every fan at one duty cycle; an RPM of -1 means the fan
+// has no tach or it could not be read
+type sweepRow struct {
+	duty float64
+	rpm  []int
+	note string
+}
+
+// runFanTest implements the "fan-test" subcommand: it steps the fans from 0 to 100%
+// duty, reads their tachs at each level and prints which duty cycles they start and
+// run at, to choose the lv0-lv3 levels and find fans that do not start
+func runFanTest(args []string, in io.Reader, out io.Writer) int {
+	fs := flag.NewFlagSet("fan-test", flag.ContinueOnError)
+	fs.SetOutput(out)
+	configPath := fs.String("config", config.DefaultPath, "configuration file")
+	step := fs.Float64("step", 10, "duty cycle step in percent")
+	pause := fs.Duration("pause", 5*time.Second, "time at each level before the RPM is read")
+	only := fs.String("fan", "", "comma-separated fans to sweep: cpu, disk or a HAT id (default all)")
+	cpuTach := fs.String("cpu-tach", "", "hwmon fanN_input file of the CPU fan (default [alerts] cpu_fan_tach)")
+	diskTach := fs.String("disk-tach", "", "hwmon fanN_input file of the disk fan (default [alerts] disk_fan_tach)")
+	notes := fs.Bool("notes", false, "ask for a noise note at each level")
+	force := fs.Bool("force", false, "sweep even while the daemon is running")
+	fs.Usage = func() {
+		fmt.Fprintln(out, "Usage: rockpi-quad-go fan-test [options]")
+		fmt.Fprintln(out, "\nSweeps the fans from 0 to 100% duty and prints their RPM at each level.")
+		fmt.Fprintln(out, "The fans are left at 100% afterwards.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *step <= 0 || *step > 100 {
+		fmt.Fprintf(out, "invalid step %g, expected 0-100\n", *step)
+		return 2
+	}
+
+	if pids := findProcesses(isDaemon); len(pids) > 0 && !*force {
+		fmt.Fprintf(out, "rockpi-quad-go is running (pid %v) and drives the fans; stop it first, "+
+			"e.g. `systemctl stop rockpi-quad-go`, or pass --force\n", pids)
+		return 1
+	}
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		fmt.Fprintf(out, "Failed to load config: %v\n", err)
+		return 1
+	}
+	cfg.Alerts.CPUFanTach = valueOr(*cpuTach, cfg.Alerts.CPUFanTach)
+	cfg.Alerts.DiskFanTach = valueOr(*diskTach, cfg.Alerts.DiskFanTach)
+
+	fans, err := openSweepFans(cfg, *only)
+	if err != nil {
+		fmt.Fprintf(out, "fan-test: %v\n", err)
+		return 1
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var lines <-chan string
+	if *notes {
+		lines = readLines(in)
+	}
+	rows, err := sweep(ctx, fans, sweepLevels(*step), *pause, func(row *sweepRow) {
+		fmt.Fprintf(out, "%3.0f%%:", row.duty)
+		for i, f := range fans {
+			fmt.Fprintf(out, " %s %s", f.name, formatRPM(row.rpm[i]))
+		}
+		if *notes {
+			fmt.Fprint(out, " - noise? ")
+			var ok bool
+			if row.note, ok = readNote(ctx, lines); !ok {
+				fmt.Fprintln(out)
+			}
+			return
+		}
+		fmt.Fprintln(out)
+	})
+
+	// Nothing controls the fans once the sweep ends, so leave them at full speed
+	for _, f := range fans {
+		if serr := f.pwm.SetDutyCycle(1); serr != nil {
+			fmt.Fprintf(out, "Failed to set %s fan to 100%%: %v\n", f.name, serr)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(out, "fan-test: %v\n", err)
+		return 1
+	}
+
+	fmt.Fprintln(out)
+	writeSweepTable(out, fans, rows)
+	fmt.Fprintln(out)
+	for _, line := range sweepSummary(fans, rows) {
+		fmt.Fprintln(out, line)
+	}
+	fmt.Fprintln(out, "Fans left at 100%; start rockpi-quad-go to resume fan control")
+	return 0
+}
+
+// isDaemon reports whether a command line runs the rockpi-quad-go daemon rather than
+// one of its subcommands
+func isDaemon(args []string) bool {
+	if filepath.Base(args[0]) != "rockpi-quad-go" {
+		return false
+	}
+	return len(args) == 1 || strings.HasPrefix(args[1], "-")
+}
+
+// openSweepFans opens the PWM channel of the CPU fan, the disk fan when it has its
+// own channel and the top-board fan of every stacked HAT, limited to the
+// comma-separated names in only when set
+func openSweepFans(cfg *config.Config, only string) ([]sweepFan, error) {
+	type fanChannel struct {
+		name, chip, polarity, tach string
+		channel                    int
+	}
+	channels := []fanChannel{{"cpu", cfg.Fan.CPUPWMChip, cfg.Fan.CPUPolarity, cfg.Alerts.CPUFanTach, cfg.Fan.CPUPWMChannel}}
+	if cfg.Fan.TBPWMChip != cfg.Fan.CPUPWMChip || cfg.Fan.TBPWMChannel != cfg.Fan.CPUPWMChannel {
+		channels = append(channels, fanChannel{"disk", cfg.Fan.TBPWMChip, cfg.Fan.TBPolarity, cfg.Alerts.DiskFanTach, cfg.Fan.TBPWMChannel})
+	}
+	for _, h := range cfg.HATs {
+		if h.ID != config.MainHAT && h.PWMChannel >= 0 {
+			channels = append(channels, fanChannel{h.ID, h.PWMChip, h.Polarity, "", h.PWMChannel})
+		}
+	}
+
+	var fans []sweepFan
+	for _, c := range channels {
+		if only != "" && !slices.Contains(strings.Split(only, ","), c.name) {
+			continue
+		}
+		p, err := pwm.New(c.chip, c.channel, cfg.Fan.PWMFrequency)
+		if err != nil {
+			// Opening a channel stops its fan, so do not leave the others standing
+			for _, f := range fans {
+				_ = f.pwm.SetDutyCycle(1)
+			}
+			return nil, fmt.Errorf("%s fan: %w", c.name, err)
+		}
+		if c.polarity == "inversed" {
+			p.SetInversed(true)
+		}
+		fans = append(fans, sweepFan{name: c.name, pwm: p, tach: c.tach})
+	}
+	if len(fans) == 0 {
+		return nil, fmt.Errorf("no fan matches %q", only)
+	}
+	return fans, nil
+}
+
+// sweepLevels returns the duty cycles from 0 to 100% in steps, always ending at 100%
+func sweepLevels(step float64) []float64 {
+	var levels []float64
+	for i := 0; float64(i)*step < 100; i++ {
+		levels = append(levels, float64(i)*step)
+	}
+	return append(levels, 100)
+}
+
+// sweep sets every fan to each level in turn and reads the tachs after pause; each
+// is called with every row as it is measured
+func sweep(ctx context.Context, fans []sweepFan, levels []float64, pause time.Duration, each func(*sweepRow)) ([]sweepRow, error) {
+	rows := make([]sweepRow, 0, len(levels))
+	for _, duty := range levels {
+		if ctx.Err() != nil {
+			return rows, fmt.Errorf("interrupted at %.0f%%", duty)
+		}
+		for _, f := range fans {
+			if err := f.pwm.SetDutyCycle(duty / 100); err != nil {
+				return rows, fmt.Errorf("%s fan: %w", f.name, err)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return rows, fmt.Errorf("interrupted at %.0f%%", duty)
+		case <-time.After(pause):
+		}
+
+		row := sweepRow{duty: duty, rpm: make([]int, len(fans))}
+		for i, f := range fans {
+			row.rpm[i] = readTach(f.tach)
+		}
+		each(&row)
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// readLines sends the lines of in until it ends, from a goroutine so a prompt can
+// wait for a line and an interrupt at once
+func readLines(in io.Reader) <-chan string {
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(in)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+	return lines
+}
+
+// readNote waits for the next line, returning false when ctx is done or the input ended
+func readNote(ctx context.Context, lines <-chan string) (string, bool) {
+	select {
+	case <-ctx.Done():
+		return "", false
+	case line, ok := <-lines:
+		return strings.TrimSpace(line), ok
+	}
+}
+
+// readTach returns the RPM in an hwmon fanN_input file, -1 without one
+func readTach(path string) int {
+	if path == "" {
+		return -1
+	}
+	data, err := os.ReadFile(path) // #nosec G304 - path comes from the config or command line
+	if err != nil {
+		return -1
+	}
+	rpm, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return -1
+	}
+	return rpm
+}
+
+func formatRPM(rpm int) string {
+	if rpm < 0 {
+		return "-"
+	}
+	return strconv.Itoa(rpm)
+}
+
+// writeSweepTable prints the RPM of every fan by duty cycle, with the noise notes
+// when any were taken
+func writeSweepTable(out io.Writer, fans []sweepFan, rows []sweepRow) {
+	withNotes := slices.ContainsFunc(rows, func(r sweepRow) bool { return r.note != "" })
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprint(w, "Duty\t")
+	for _, f := range fans {
+		fmt.Fprintf(w, "%s RPM\t", f.name)
+	}
+	if withNotes {
+		fmt.Fprint(w, "Noise\t")
+	}
+	fmt.Fprintln(w)
+	for _, r := range rows {
+		fmt.Fprintf(w, "%.0f%%\t", r.duty)
+		for _, rpm := range r.rpm {
+			fmt.Fprintf(w, "%s\t", formatRPM(rpm))
+		}
+		if withNotes {
+			fmt.Fprintf(w, "%s\t", r.note)
+		}
+		fmt.Fprintln(w)
+	}
+	_ = w.Flush()
+}
+
+// sweepSummary tells for every fan at which duty cycle it starts, whether that is
+// above the lv0 duty, and flags fans that did not turn at all
+func sweepSummary(fans []sweepFan, rows []sweepRow) []string {
+	var lines []string
+	for i, f := range fans {
+		read := slices.ContainsFunc(rows, func(r sweepRow) bool { return r.rpm[i] >= 0 })
+		start := slices.IndexFunc(rows, func(r sweepRow) bool { return r.rpm[i] > 0 })
+		switch {
+		case !read && f.tach == "":
+			lines = append(lines, fmt.Sprintf("%s: no tach configured, judge the levels by ear", f.name))
+		case !read:
+			lines = append(lines, fmt.Sprintf("%s: tach %s could not be read", f.name, f.tach))
+		case start < 0:
+			lines = append(lines, fmt.Sprintf("%s: 0 RPM even at 100%% - the fan does not start; check its connector and tach", f.name))
+		default:
+			last := rows[len(rows)-1]
+			lines = append(lines, fmt.Sprintf("%s: starts at %.0f%% (%d RPM), %s RPM at 100%%",
+				f.name, rows[start].duty, rows[start].rpm[i], formatRPM(last.rpm[i])))
+			if rows[start].duty > lv0Duty {
+				lines = append(lines, fmt.Sprintf("%s: does not start at %d%%, the lv0 duty; set [fan] kickstart_seconds "+
+					"with kickstart_threshold above %.0f so it is kick-started", f.name, lv0Duty, rows[start-1].duty))
+			}
+		}
+	}
+	return lines
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// fakeChannel records the duty cycles set on it
+type fakeChannel struct {
+	duties []float64
+	// tach is written with rpm(duty) on every set, when given
+	tach string
+	rpm  func(duty float64) int
+	t    *testing.T
+}
+
+func (f *fakeChannel) SetDutyCycle(dc float64) error {
+	f.duties = append(f.duties, dc)
+	if f.tach != "" {
+		if err := os.WriteFile(f.tach, []byte(strconv.Itoa(f.rpm(dc))+"\n"), 0o600); err != nil {
+			f.t.Fatal(err)
+		}
+	}
+	return nil
+}
+
+func TestSweepLevels(t *testing.T) {
+	tests := []struct {
+		step float64
+		want []float64
+	}{
+		{25, []float64{0, 25, 50, 75, 100}},
+		{30, []float64{0, 30, 60, 90, 100}},
+		{100, []float64{0, 100}},
+	}
+	for _, tt := range tests {
+		if got := sweepLevels(tt.step); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("sweepLevels(%g) = %v, want %v", tt.step, got, tt.want)
+		}
+	}
+}
+
+func TestSweep(t *testing.T) {
+	dir := t.TempDir()
+	cpu := &fakeChannel{tach: filepath.Join(dir, "fan1_input"), t: t, rpm: func(dc float64) int {
+		if dc < 0.5 {
+			return 0
+		}
+		return int(dc * 4000)
+	}}
+	disk := &fakeChannel{}
+	fans := []sweepFan{{name: "cpu", pwm: cpu, tach: cpu.tach}, {name: "disk", pwm: disk}}
+
+	seen := 0
+	rows, err := sweep(context.Background(), fans, sweepLevels(25), 0, func(r *sweepRow) {
+		seen++
+		r.note = "quiet"
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seen != 5 || len(rows) != 5 || rows[0].note != "quiet" {
+		t.Fatalf("rows = %+v after %d callbacks, want 5 noted rows", rows, seen)
+	}
+	if want := []float64{0, 0.25, 0.5, 0.75, 1}; !reflect.DeepEqual(disk.duties, want) {
+		t.Errorf("disk duties = %v, want %v", disk.duties, want)
+	}
+	if rows[2].rpm[0] != 2000 || rows[2].rpm[1] != -1 {
+		t.Errorf("rpm at 50%% = %v, want 2000 and no tach", rows[2].rpm)
+	}
+
+	got := sweepSummary(fans, rows)
+	want := []string{
+		"cpu: starts at 50% (2000 RPM), 4000 RPM at 100%",
+		"cpu: does not start at 25%, the lv0 duty; set [fan] kickstart_seconds with kickstart_threshold above 25 so it is kick-started",
+		"disk: no tach configured, judge the levels by ear",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("sweepSummary() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := sweep(ctx, fans, []float64{0, 100}, 0, func(*sweepRow) {}); err == nil {
+		t.Error("sweep() after cancel = nil, want interrupted")
+	}
+}
+
+func TestSweepInterrupted(t *testing.T) {
+	disk := &fakeChannel{}
+	fans := []sweepFan{{name: "disk", pwm: disk}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	rows, err := sweep(ctx, fans, sweepLevels(25), 0, func(*sweepRow) { cancel() })
+	if err == nil || err.Error() != "interrupted at 25%" {
+		t.Errorf("sweep() = %v, want interrupted at 25%%", err)
+	}
+	if len(rows) != 1 || !reflect.DeepEqual(disk.duties, []float64{0}) {
+		t.Errorf("rows = %+v with duties %v after the interrupt, want only the 0%% level", rows, disk.duties)
+	}
+}
+
+func TestReadNote(t *testing.T) {
+	lines := readLines(strings.NewReader("  quiet \n"))
+	if note, ok := readNote(context.Background(), lines); !ok || note != "quiet" {
+		t.Errorf("readNote() = %q, %t, want quiet", note, ok)
+	}
+	if _, ok := readNote(context.Background(), lines); ok {
+		t.Error("readNote() at the end of the input = true, want false")
+	}
+
+	// A prompt waiting on a terminal returns once interrupted
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, ok := readNote(ctx, make(chan string)); ok {
+		t.Error("readNote() after cancel = true, want false")
+	}
+}
+
+func TestSweepSummaryStalled(t *testing.T) {
+	fans := []sweepFan{{name: "cpu", tach: "/sys/class/hwmon/hwmon3/fan1_input"}, {name: "disk", tach: "/nonexistent"}}
+	rows := []sweepRow{{duty: 0, rpm: []int{0, -1}}, {duty: 100, rpm: []int{0, -1}}}
+	got := sweepSummary(fans, rows)
+	want := []string{
+		"cpu: 0 RPM even at 100% - the fan does not start; check its connector and tach",
+		"disk: tach /nonexistent could not be read",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("sweepSummary() = %q, want %q", got, want)
+	}
+}
+
+func TestIsDaemon(t *testing.T) {
+	tests := []struct {
+		args []string
+		want bool
+	}{
+		{[]string{"/usr/bin/rockpi-quad-go"}, true},
+		{[]string{"/usr/bin/rockpi-quad-go", "--takeover"}, true},
+		{[]string{"rockpi-quad-go", "fan-test"}, false},
+		{[]string{"/usr/bin/python3", "rockpi-quad-go"}, false},
+	}
+	for _, tt := range tests {
+		if got := isDaemon(tt.args); got != tt.want {
+			t.Errorf("isDaemon(%q) = %t, want %t", tt.args, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// runFlags implements the "flags" subcommand: it lists the daemon's runtime flags,
+// or sets them from name=value arguments through the API
+func runFlags(args []string, out io.Writer) int {
+	fs := flag.NewFlagSet("flags", flag.ContinueOnError)
+	fs.SetOutput(out)
+	opts := addAPIFlags(fs)
+	persist := fs.Bool("persist", false, "also write changed flags to the configuration file")
+	fs.Usage = func() {
+		fmt.Fprintln(out, "Usage: rockpi-quad-go flags [options] [name=true|false ...]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	client := opts.client()
+
+	var values map[string]bool
+	err := client.do(http.MethodGet, "/api/flags", nil, &values)
+	for _, arg := range fs.Args() {
+		if err != nil {
+			break
+		}
+		name, raw, ok := strings.Cut(arg, "=")
+		value, parseErr := strconv.ParseBool(raw)
+		if !ok || parseErr != nil {
+			fmt.Fprintf(out, "invalid flag assignment %q, expected name=true|false\n", arg)
+			return 2
+		}
+		values = nil
+		err = client.do(http.MethodPut, "/api/flags/"+name, map[string]bool{"value": value, "persist": *persist}, &values)
+	}
+	if err != nil {
+		fmt.Fprintf(out, "flags: %v\n", err)
+		return 1
+	}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		fmt.Fprintf(out, "%s=%t\n", name, values[name])
+	}
+	return 0
+}
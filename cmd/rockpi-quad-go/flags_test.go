@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kolobock/rockpi-quad-go/internal/api"
+	"github.com/kolobock/rockpi-quad-go/internal/config"
+	"github.com/kolobock/rockpi-quad-go/internal/flags"
+)
+
+func TestRunFlags(t *testing.T) {
+	cfg := &config.Config{
+		Path:   filepath.Join(t.TempDir(), "missing.conf"),
+		Slider: config.SliderConfig{Auto: true},
+		API:    config.APIConfig{ControlTokens: []string{"admin"}},
+	}
+	set := flags.New(cfg)
+	server := api.New(cfg, nil)
+	server.SetFlags(set)
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	tests := []struct {
+		name     string
+		args     []string
+		wantCode int
+		wantOut  string
+	}{
+		{"list", []string{"--api", ts.URL}, 0, "auto_slide=true\nfahrenheit=false\n"},
+		{"set", []string{"--api", ts.URL, "--token", "admin", "fahrenheit=true"}, 0, "fahrenheit=true\n"},
+		{"set without token", []string{"--api", ts.URL, "--token", "", "verbose=true"}, 1, "403"},
+		{"unknown flag", []string{"--api", ts.URL, "--token", "admin", "colour=true"}, 1, "unknown flag"},
+		{"bad value", []string{"--api", ts.URL, "fahrenheit=maybe"}, 2, "expected name=true|false"},
+		{"persist fails without file", []string{"--api", ts.URL, "--token", "admin", "--persist", "verbose=true"}, 1, "500"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			code := runFlags(tt.args, &out)
+			if code != tt.wantCode {
+				t.Errorf("runFlags() = %d, want %d (output %q)", code, tt.wantCode, out.String())
+			}
+			if !strings.Contains(out.String(), tt.wantOut) {
+				t.Errorf("output %q does not contain %q", out.String(), tt.wantOut)
+			}
+		})
+	}
+
+	if !set.Enabled(flags.Fahrenheit) {
+		t.Error("fahrenheit flag not set on the daemon")
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/kolobock/rockpi-quad-go/internal/logger"
+)
+
+const (
+	// pythonService is the unit the original Python daemon is installed as
+	pythonService = "rockpi-quad.service"
+	// takeoverTimeout is how long --takeover waits for the Python daemon to exit
+	takeoverTimeout = 10 * time.Second
+)
+
+// procDir is replaced in tests
+var procDir = "/proc"
+
+// findPythonDaemon returns the PIDs of running original rockpi-quad Python daemons
+func findPythonDaemon() []int {
+	return findProcesses(isPythonDaemon)
+}
+
+// findProcesses returns the PIDs of other processes whose command line matches
+func findProcesses(match func(args []string) bool) []int {
+	entries, err := os.ReadDir(procDir)
+	if err != nil {
+		return nil
+	}
+
+	var pids []int
+	self := os.Getpid()
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil || pid == self {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(procDir, entry.Name(), "cmdline"))
+		if err != nil {
+			continue
+		}
+		if match(strings.Split(strings.TrimRight(string(data), "\x00"), "\x00")) {
+			pids = append(pids, pid)
+		}
+	}
+	return pids
+}
+
+// isPythonDaemon reports whether a command line runs the Python rockpi-quad scripts,
+// e.g. "python3 /usr/bin/rockpi-quad/main.py"
+func isPythonDaemon(args []string) bool {
+	if len(args) < 2 || !strings.HasPrefix(filepath.Base(args[0]), "python") {
+		return false
+	}
+	for _, arg := range args[1:] {
+		if strings.Contains(arg, "rockpi-quad") && !strings.Contains(arg, "rockpi-quad-go") {
+			return true
+		}
+	}
+	return false
+}
+
+// guardPythonDaemon refuses to start next to the Python daemon, since both would
+// fight over the fan PWM and the OLED. With takeover its service is stopped and any
+// remaining processes are terminated instead.
+func guardPythonDaemon(takeover bool) error {
+	pids := findPythonDaemon()
+	if len(pids) == 0 {
+		return nil
+	}
+	if !takeover {
+		return fmt.Errorf("the Python rockpi-quad daemon is running (pid %v); stop it with "+
+			"`systemctl disable --now %s` or start with --takeover", pids, pythonService)
+	}
+
+	logger.Errorf("Taking over from the Python rockpi-quad daemon (pid %v)", pids)
+	// Stop the unit first so systemd does not restart the process
+	if err := exec.Command("systemctl", "stop", pythonService).Run(); err != nil {
+		logger.Infof("Failed to stop %s: %v", pythonService, err)
+	}
+	for _, pid := range findPythonDaemon() {
+		if err := syscall.Kill(pid, syscall.SIGTERM); err != nil {
+			logger.Infof("Failed to terminate pid %d: %v", pid, err)
+		}
+	}
+
+	for deadline := time.Now().Add(takeoverTimeout); time.Now().Before(deadline); time.Sleep(200 * time.Millisecond) {
+		if len(findPythonDaemon()) == 0 {
+			return nil
+		}
+	}
+	return fmt.Errorf("the Python rockpi-quad daemon did not exit within %s", takeoverTimeout)
+}
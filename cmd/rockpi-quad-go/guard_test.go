@@ -0,0 +1,43 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFindPythonDaemon(t *testing.T) {
+	orig := procDir
+	procDir = t.TempDir()
+	t.Cleanup(func() { procDir = orig })
+
+	processes := map[string][]string{
+		"100": {"/usr/bin/python3", "/usr/bin/rockpi-quad/main.py"},
+		"101": {"/usr/bin/rockpi-quad/rockpi-quad-go"},
+		"102": {"python3", "-m", "http.server"},
+		"103": {"/usr/bin/python3.11", "-u", "/usr/bin/rockpi-quad/misc.py"},
+		"104": {"python3", "/opt/rockpi-quad-go/tools.py"},
+	}
+	for pid, args := range processes {
+		dir := filepath.Join(procDir, pid)
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		cmdline := strings.Join(args, "\x00") + "\x00"
+		if err := os.WriteFile(filepath.Join(dir, "cmdline"), []byte(cmdline), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(procDir, "sys"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := findPythonDaemon(), []int{100, 103}; !reflect.DeepEqual(got, want) {
+		t.Errorf("findPythonDaemon() = %v, want %v", got, want)
+	}
+	if err := guardPythonDaemon(false); err == nil || !strings.Contains(err.Error(), "--takeover") {
+		t.Errorf("guardPythonDaemon(false) = %v, want an error suggesting --takeover", err)
+	}
+}
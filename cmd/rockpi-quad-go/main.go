@@ -2,208 +2,106 @@ package main
 
 import (
 	"context"
+	"flag"
+	"fmt"
 	"os"
-	"os/exec"
 	"os/signal"
-	"sync"
 	"syscall"
-	"time"
 
-	"github.com/kolobock/rockpi-quad-go/internal/button"
+	"github.com/kolobock/rockpi-quad-go/internal/app"
 	"github.com/kolobock/rockpi-quad-go/internal/config"
 	"github.com/kolobock/rockpi-quad-go/internal/disk"
-	"github.com/kolobock/rockpi-quad-go/internal/fan"
 	"github.com/kolobock/rockpi-quad-go/internal/logger"
-	"github.com/kolobock/rockpi-quad-go/internal/oled"
+	"github.com/kolobock/rockpi-quad-go/internal/version"
 )
 
-const (
-	actionNone = "none"
-)
-
-func handleButtonEvents(ctx context.Context, cfg *config.Config, buttonCtrl *button.Controller,
-	fanCtrl *fan.Controller, oledCtrl *oled.Controller, buttonChan chan struct{}, cancel context.CancelFunc) {
-	time.Sleep(500 * time.Millisecond)
+// subcommands run instead of the daemon when named as the first argument
+var subcommands = map[string]func(args []string) int{
+	"button-test":     func(args []string) int { return runButtonTest(args, os.Stdout) },
+	"check":           func(args []string) int { return runCheck(args, os.Stdout) },
+	"detect-hardware": func(args []string) int { return runDetectHardware(args, os.Stdout) },
+	"dump-config":     func(args []string) int { return runDumpConfig(args, os.Stdout) },
+	"fan-test":        func(args []string) int { return runFanTest(args, os.Stdin, os.Stdout) },
+	"flags":           func(args []string) int { return runFlags(args, os.Stdout) },
+	"oled-test":       func(args []string) int { return runOLEDTest(args, os.Stdout) },
+	"power-gpio":      func(args []string) int { return runPowerGPIO(args, os.Stdout) },
+	"splash":          func(args []string) int { return runSplash(args, os.Stdout) },
+	"status":          func(args []string) int { return runStatus(args, os.Stdout) },
+}
 
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case event, ok := <-buttonCtrl.PressChan():
-			if !ok {
-				// Channel closed, exit
-				return
-			}
-			action := getButtonAction(cfg, event)
-			logger.Infof("Button event: %s (action: %s)", event, action)
-			oledCtrl.NotifyBtnPress()
-
-			switch action {
-			case "slider":
-				select {
-				case buttonChan <- struct{}{}:
-				default:
-				}
-			case "switch":
-				fanCtrl.ToggleFan()
-			case "poweroff":
-				executePoweroff(cancel)
-			case "reboot":
-				executeReboot(cancel)
-			case actionNone:
-			default:
-				executeCustomCommand(action)
-			}
+func main() {
+	if len(os.Args) > 1 {
+		if run, ok := subcommands[os.Args[1]]; ok {
+			os.Exit(run(os.Args[2:]))
 		}
 	}
-}
 
-func executePoweroff(cancel context.CancelFunc) {
-	logger.Infoln("Poweroff requested via button press")
-	go func() {
-		time.Sleep(1 * time.Second)
-		if err := exec.Command("poweroff").Run(); err != nil {
-			logger.Errorf("Failed to execute poweroff: %v", err)
-		}
-	}()
-	cancel()
-}
+	fs := flag.NewFlagSet("rockpi-quad-go", flag.ExitOnError)
+	takeover := fs.Bool("takeover", false, "stop a running Python rockpi-quad daemon instead of refusing to start")
+	dryRun := fs.Bool("dry-run", false, "compute and report fan duty cycles without writing them, like [fan] dry_run")
+	showVersion := fs.Bool("version", false, "print the build information and enabled features and exit")
+	_ = fs.Parse(os.Args[1:])
 
-func executeReboot(cancel context.CancelFunc) {
-	logger.Infoln("Reboot requested via button press")
-	go func() {
-		time.Sleep(1 * time.Second)
-		if err := exec.Command("reboot").Run(); err != nil {
-			logger.Errorf("Failed to execute reboot: %v", err)
-		}
-	}()
-	cancel()
-}
+	if *showVersion {
+		// The features need the config, but the build information is printed without it
+		cfg, _ := config.Load(config.DefaultPath)
+		fmt.Println(version.Get(cfg))
+		return
+	}
 
-func executeCustomCommand(action string) {
-	logger.Infof("Executing custom command: %s", action)
-	go func() {
-		cmd := exec.Command("sh", "-c", action)
-		if err := cmd.Run(); err != nil {
-			logger.Errorf("Failed to execute command '%s': %v", action, err)
-		} else {
-			logger.Infof("Command '%s' executed successfully", action)
-		}
-	}()
-}
+	if err := guardPythonDaemon(*takeover); err != nil {
+		logger.Fatalf("Refusing to start: %v", err)
+	}
 
-func main() {
 	cfg := loadConfigAndSetup()
+	if *dryRun {
+		cfg.Fan.DryRun = true
+	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	dumpCh := make(chan os.Signal, 1)
+	signal.Notify(dumpCh, syscall.SIGUSR1)
 
-	var wg sync.WaitGroup
+	daemon := app.New(cfg, app.DefaultFactories())
+	daemon.Start(ctx, cancel)
 
-	fanCtrl := startFanController(ctx, &wg, cfg)
-	defer fanCtrl.Close()
-
-	if cfg.OLED.Enabled {
-		startOLEDAndButton(ctx, &wg, cfg, fanCtrl, cancel)
-	}
-
-	<-sigCh
+	waitForShutdown(sigCh, dumpCh, daemon.DumpState)
 	logger.Infoln("Shutting down...")
 	cancel()
 
-	waitForShutdown(&wg)
+	daemon.Wait()
+}
+
+// waitForShutdown blocks until a termination signal, dumping the daemon state on each SIGUSR1
+func waitForShutdown(sigCh, dumpCh <-chan os.Signal, dump func()) {
+	for {
+		select {
+		case <-sigCh:
+			return
+		case <-dumpCh:
+			dump()
+		}
+	}
 }
 
 func loadConfigAndSetup() *config.Config {
-	cfg, err := config.Load("/etc/rockpi-quad.conf")
+	cfg, err := config.Load(config.DefaultPath)
 	if err != nil {
 		logger.Fatalf("Failed to load config: %v", err)
 	}
 
 	logger.SetVerbose(cfg.Fan.Syslog)
+	logger.Infof("%s", version.Get(cfg))
+	if cfg.Env.Board != "" {
+		logger.Infof("Detected board profile %s", cfg.Env.Board)
+	}
 	disk.EnableSATAController(cfg.Env.SATAChip, cfg.Env.SATALine1, cfg.Env.SATALine2)
+	disk.SetHATs(cfg.HATs)
+	disk.EnableHATControllers()
 
 	return cfg
 }
-
-func startFanController(ctx context.Context, wg *sync.WaitGroup, cfg *config.Config) *fan.Controller {
-	fanCtrl, err := fan.New(cfg)
-	if err != nil {
-		logger.Fatalf("Failed to create fan controller: %v", err)
-	}
-
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		if err := fanCtrl.Run(ctx); err != nil {
-			logger.Errorf("Fan controller error: %v", err)
-		}
-	}()
-
-	return fanCtrl
-}
-
-func startOLEDAndButton(ctx context.Context, wg *sync.WaitGroup, cfg *config.Config, fanCtrl *fan.Controller, cancel context.CancelFunc) {
-	buttonCtrl, err := button.New(cfg)
-	if err != nil {
-		logger.Errorf("Failed to create button controller: %v", err)
-		goto oled
-	}
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		defer buttonCtrl.Close()
-		buttonCtrl.Run(ctx)
-	}()
-
-oled:
-	oledCtrl, err := oled.New(cfg, fanCtrl)
-	if err != nil {
-		logger.Errorf("Failed to create OLED controller: %v", err)
-		return
-	}
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		defer oledCtrl.Close()
-		buttonChan := make(chan struct{}, 10)
-
-		if buttonCtrl != nil {
-			go handleButtonEvents(ctx, cfg, buttonCtrl, fanCtrl, oledCtrl, buttonChan, cancel)
-		}
-		if err := oledCtrl.Run(ctx, buttonChan); err != nil {
-			logger.Errorf("OLED controller error: %v", err)
-		}
-	}()
-}
-
-func waitForShutdown(wg *sync.WaitGroup) {
-	done := make(chan struct{})
-	go func() {
-		wg.Wait()
-		close(done)
-	}()
-
-	select {
-	case <-done:
-		logger.Infoln("Shutdown complete")
-	case <-time.After(5 * time.Second):
-		logger.Infoln("Shutdown timeout")
-	}
-}
-
-func getButtonAction(cfg *config.Config, event button.EventType) string {
-	switch event {
-	case button.Click:
-		return cfg.Key.Click
-	case button.DoubleClick:
-		return cfg.Key.Twice
-	case button.LongPress:
-		return cfg.Key.Press
-	default:
-		return actionNone
-	}
-}
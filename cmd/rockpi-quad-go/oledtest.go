@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/kolobock/rockpi-quad-go/internal/config"
+	"github.com/kolobock/rockpi-quad-go/internal/oled"
+)
+
+// runOLEDTest implements the "oled-test" subcommand: it cycles the display through
+// test patterns and, when the display fails, prints the I2C counters and probes the
+// I2C buses for the panel
+func runOLEDTest(args []string, out io.Writer) int {
+	fs := flag.NewFlagSet("oled-test", flag.ContinueOnError)
+	fs.SetOutput(out)
+	configPath := fs.String("config", config.DefaultPath, "configuration file")
+	hold := fs.Duration("hold", 2*time.Second, "time each pattern stays on screen")
+	force := fs.Bool("force", false, "run even while the daemon is running")
+	fs.Usage = func() {
+		fmt.Fprintln(out, "Usage: rockpi-quad-go oled-test [options]")
+		fmt.Fprintln(out, "\nShows fills, a checkerboard, a border, every font size and every rotation on the OLED.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if pids := findProcesses(isDaemon); len(pids) > 0 && !*force {
+		fmt.Fprintf(out, "rockpi-quad-go is running (pid %v) and drives the display; stop it first, "+
+			"e.g. `systemctl stop rockpi-quad-go`, or pass --force\n", pids)
+		return 1
+	}
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		fmt.Fprintf(out, "Failed to load config: %v\n", err)
+		return 1
+	}
+
+	fmt.Fprintf(out, "Testing the SSD1306 at 0x3C on i2c-%d\n", cfg.Env.I2CBus)
+	stats, err := oled.SelfTest(cfg, *hold, func(name string) {
+		fmt.Fprintf(out, "  %s\n", name)
+	})
+	if err != nil {
+		fmt.Fprintf(out, "OLED test failed: %v\n", err)
+		writeBusStats(out, stats)
+		if bus, ok := reportI2C(out); ok && bus != cfg.Env.I2CBus {
+			fmt.Fprintf(out, "\nThe panel answers on i2c-%d; set I2C_BUS=%d in /etc/rockpi-quad.env\n", bus, bus)
+		}
+		return 1
+	}
+	fmt.Fprintln(out, "OLED test passed")
+	writeBusStats(out, stats)
+	return 0
+}
+
+// writeBusStats prints the I2C write counters; retries or reopens on a passing test
+// point at a loose connection
+func writeBusStats(out io.Writer, stats oled.BusStats) {
+	fmt.Fprintf(out, "I2C writes: %d errors, %d retries, %d bus reopens\n", stats.WriteErrors, stats.Retries, stats.Reopens)
+}
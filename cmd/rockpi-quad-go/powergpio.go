@@ -0,0 +1,41 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/kolobock/rockpi-quad-go/internal/config"
+	"github.com/kolobock/rockpi-quad-go/internal/shutdown"
+)
+
+// runPowerGPIO asserts the [shutdown] power_gpio line; the rockpi-quad-go.shutdown
+// hook runs it as the last step of a poweroff, after the OS has shut down
+func runPowerGPIO(args []string, out io.Writer) int {
+	fs := flag.NewFlagSet("power-gpio", flag.ContinueOnError)
+	fs.SetOutput(out)
+	configPath := fs.String("config", config.DefaultPath, "configuration file")
+	fs.Usage = func() {
+		fmt.Fprintln(out, "Usage: rockpi-quad-go power-gpio [--config FILE]")
+		fmt.Fprintln(out, "\nAsserts the [shutdown] power_gpio line to cut the HAT power rails.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		fmt.Fprintf(out, "Failed to load config: %v\n", err)
+		return 1
+	}
+	if cfg.Shutdown.PowerGPIO == "" {
+		fmt.Fprintln(out, "[shutdown] power_gpio is not set, nothing to do")
+		return 0
+	}
+	if err := shutdown.AssertPowerGPIO(cfg.Shutdown); err != nil {
+		fmt.Fprintf(out, "Failed to assert power line: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/kolobock/rockpi-quad-go/internal/config"
+	"github.com/kolobock/rockpi-quad-go/internal/oled"
+)
+
+// runSplash shows a boot message on the OLED from an early oneshot unit and exits,
+// leaving the message on screen until the daemon takes the display over
+func runSplash(args []string, out io.Writer) int {
+	fs := flag.NewFlagSet("splash", flag.ContinueOnError)
+	fs.SetOutput(out)
+	configPath := fs.String("config", config.DefaultPath, "configuration file")
+	text := fs.String("text", "Booting…", "message shown above the hostname")
+	fs.Usage = func() {
+		fmt.Fprintln(out, "Usage: rockpi-quad-go splash [--text MESSAGE] [--config FILE]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		fmt.Fprintf(out, "Failed to load config: %v\n", err)
+		return 1
+	}
+	if !cfg.OLED.Enabled || !cfg.Modules.OLED {
+		fmt.Fprintln(out, "OLED disabled, no splash shown")
+		return 0
+	}
+
+	if err := oled.Splash(cfg, *text); err != nil {
+		fmt.Fprintf(out, "Splash failed: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// runStatus implements the "status" subcommand: it prints the daemon's status as
+// JSON; --debug adds the per-page render and transmit timings
+func runStatus(args []string, out io.Writer) int {
+	fs := flag.NewFlagSet("status", flag.ContinueOnError)
+	fs.SetOutput(out)
+	opts := addAPIFlags(fs)
+	debug := fs.Bool("debug", false, "include OLED page render timings")
+	fs.Usage = func() {
+		fmt.Fprintln(out, "Usage: rockpi-quad-go status [options]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	path := "/api/status"
+	if *debug {
+		path += "?debug=1"
+	}
+	var status json.RawMessage
+	if err := opts.client().do(http.MethodGet, path, nil, &status); err != nil {
+		fmt.Fprintf(out, "status: %v\n", err)
+		return 1
+	}
+
+	pretty, err := json.MarshalIndent(status, "", "  ")
+	if err != nil {
+		fmt.Fprintf(out, "status: %v\n", err)
+		return 1
+	}
+	fmt.Fprintln(out, string(pretty))
+	return 0
+}
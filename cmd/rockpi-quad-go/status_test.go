@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kolobock/rockpi-quad-go/internal/api"
+	"github.com/kolobock/rockpi-quad-go/internal/config"
+	"github.com/kolobock/rockpi-quad-go/internal/health"
+)
+
+func TestRunStatus(t *testing.T) {
+	cfg := &config.Config{API: config.APIConfig{ReadTokens: []string{"reader"}}}
+	modules := health.NewTracker()
+	modules.Set(health.Fan, health.StateOK, "")
+	server := api.New(cfg, nil)
+	server.SetModuleTracker(modules)
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	tests := []struct {
+		name     string
+		args     []string
+		wantCode int
+		wantOut  string
+	}{
+		{"status", []string{"--api", ts.URL, "--token", "reader"}, 0, "\n  \"health\": {\n"},
+		{"debug", []string{"--api", ts.URL, "--token", "reader", "--debug"}, 0, "\n  \"health\": {\n"},
+		{"without token", []string{"--api", ts.URL, "--token", ""}, 1, "401"},
+		{"bad option", []string{"--verbose"}, 2, "Usage"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			code := runStatus(tt.args, &out)
+			if code != tt.wantCode {
+				t.Errorf("runStatus() = %d, want %d (output %q)", code, tt.wantCode, out.String())
+			}
+			if !strings.Contains(out.String(), tt.wantOut) {
+				t.Errorf("output %q does not contain %q", out.String(), tt.wantOut)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+// Package fonts embeds the TrueType font the OLED pages are drawn with, so the
+// daemon does not depend on a fonts directory next to its working directory.
+package fonts
+
+import (
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"fmt"
+)
+
+//go:embed DejaVuSansMono-Bold.ttf
+var dejaVuSansMonoBold []byte
+
+// dejaVuSansMonoBoldSHA256 is the checksum of the font as shipped; a font replaced
+// or truncated in the source tree fails to load instead of rendering garbage
+const dejaVuSansMonoBoldSHA256 = "4897298e7812cddde36333396b50c133d91764cb9ac37843e62bc80de0c0cd1c"
+
+// DejaVuSansMonoBold returns the embedded font after checking its integrity
+func DejaVuSansMonoBold() ([]byte, error) {
+	if err := verify("DejaVuSansMono-Bold.ttf", dejaVuSansMonoBold, dejaVuSansMonoBoldSHA256); err != nil {
+		return nil, err
+	}
+	return dejaVuSansMonoBold, nil
+}
+
+func verify(name string, data []byte, want string) error {
+	sum := sha256.Sum256(data)
+	if got := hex.EncodeToString(sum[:]); got != want {
+		return fmt.Errorf("embedded %s is corrupt: sha256 %s, want %s", name, got, want)
+	}
+	return nil
+}
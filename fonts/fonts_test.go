@@ -0,0 +1,19 @@
+package fonts
+
+import "testing"
+
+func TestDejaVuSansMonoBold(t *testing.T) {
+	data, err := DejaVuSansMonoBold()
+	if err != nil {
+		t.Fatalf("DejaVuSansMonoBold() error: %v", err)
+	}
+	if len(data) == 0 {
+		t.Fatal("embedded font is empty")
+	}
+}
+
+func TestVerify(t *testing.T) {
+	if err := verify("font.ttf", []byte("truncated"), dejaVuSansMonoBoldSHA256); err == nil {
+		t.Error("verify accepted data with the wrong checksum")
+	}
+}
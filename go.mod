@@ -7,6 +7,7 @@ require (
 	github.com/d2r2/go-logger v0.0.0-20210606094344-60e9d1233e22
 	github.com/golang/freetype v0.0.0-20170609003504-e2365dfdc4a0
 	github.com/warthog618/go-gpiocdev v0.9.1
+	go.etcd.io/bbolt v1.4.3
 	golang.org/x/image v0.34.0
 	gopkg.in/ini.v1 v1.67.0
 )
@@ -0,0 +1,164 @@
+// Package alert is the common alert bus: monitors publish events, which are sent to
+// webhooks, Telegram and a shell command, with repeats of the same alert suppressed
+// for a cooldown period.
+package alert
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/kolobock/rockpi-quad-go/internal/logger"
+)
+
+// Rules name the kind of an alert; sinks receive them as the event name
+const (
+	RuleCPUTemp     = "cpu_temp"
+	RuleDiskTemp    = "disk_temp"
+	RuleFanStall    = "fan_stall"
+	RuleEmergency   = "thermal_emergency"
+	RuleDiskRemoved = "disk_removed"
+	RuleSMART       = "smart_degraded"
+	RuleRAID        = "raid_degraded"
+	RuleUPS         = "ups"
+	RuleRail        = "power_rail"
+	RuleKernel      = "kernel"
+	RuleIPChanged   = "ip_changed"
+	RuleShutdown    = "shutdown"
+)
+
+const (
+	sendTimeout = 10 * time.Second
+	queueSize   = 32
+)
+
+// Event is one alert
+type Event struct {
+	Rule string
+	// Subject is what the alert is about, e.g. a disk, array or rail; repeats of the
+	// same rule and subject within the cooldown are dropped
+	Subject string
+	Message string
+	// Details are added to webhook payloads
+	Details map[string]any
+	Time    time.Time
+}
+
+// Sink delivers alerts to one destination
+type Sink interface {
+	Name() string
+	Send(ctx context.Context, evt Event) error
+}
+
+// Bus queues published events and delivers them to every sink in the background
+type Bus struct {
+	sinks    []Sink
+	cooldown time.Duration
+
+	mu   sync.Mutex
+	last map[string]time.Time
+	// pending counts queued events not yet delivered, drained is closed for Flush
+	// once it drops to zero
+	pending int
+	drained []chan struct{}
+
+	queue chan Event
+}
+
+// NewBus creates a bus delivering to sinks, dropping repeats within cooldown
+func NewBus(sinks []Sink, cooldown time.Duration) *Bus {
+	return &Bus{
+		sinks:    sinks,
+		cooldown: cooldown,
+		last:     make(map[string]time.Time),
+		queue:    make(chan Event, queueSize),
+	}
+}
+
+// Publish queues an event for delivery and reports whether it was queued; it never
+// blocks, dropping the event when it repeats within the cooldown or the queue is full
+func (b *Bus) Publish(evt Event) bool {
+	if evt.Time.IsZero() {
+		evt.Time = time.Now()
+	}
+
+	key := evt.Rule + "\x00" + evt.Subject
+	b.mu.Lock()
+	if last, ok := b.last[key]; ok && evt.Time.Sub(last) < b.cooldown {
+		b.mu.Unlock()
+		logger.Infof("Alert %s %s suppressed by cooldown", evt.Rule, evt.Subject)
+		return false
+	}
+	b.last[key] = evt.Time
+	b.pending++
+	b.mu.Unlock()
+
+	select {
+	case b.queue <- evt:
+		return true
+	default:
+		logger.Errorf("Alert queue full, dropping %s alert: %s", evt.Rule, evt.Message)
+		b.done()
+		return false
+	}
+}
+
+// Flush waits until every queued event was delivered, for at most timeout, and
+// reports whether the queue drained. Call it before cancelling the context of Run,
+// which aborts a send in flight.
+func (b *Bus) Flush(ctx context.Context, timeout time.Duration) bool {
+	b.mu.Lock()
+	if b.pending == 0 {
+		b.mu.Unlock()
+		return true
+	}
+	drained := make(chan struct{})
+	b.drained = append(b.drained, drained)
+	b.mu.Unlock()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-drained:
+		return true
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+	return false
+}
+
+// done counts an event as delivered or dropped, waking Flush when none are left
+func (b *Bus) done() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.pending--; b.pending > 0 {
+		return
+	}
+	for _, ch := range b.drained {
+		close(ch)
+	}
+	b.drained = nil
+}
+
+// Run delivers queued events until the context is cancelled
+func (b *Bus) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case evt := <-b.queue:
+			b.deliver(ctx, evt)
+			b.done()
+		}
+	}
+}
+
+func (b *Bus) deliver(ctx context.Context, evt Event) {
+	for _, s := range b.sinks {
+		sendCtx, cancel := context.WithTimeout(ctx, sendTimeout)
+		if err := s.Send(sendCtx, evt); err != nil {
+			logger.Errorf("Failed to send %s alert via %s: %v", evt.Rule, s.Name(), err)
+		}
+		cancel()
+	}
+}
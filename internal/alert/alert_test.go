@@ -0,0 +1,139 @@
+package alert
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorder is a sink remembering what it was sent
+type recorder struct {
+	mu     sync.Mutex
+	events []Event
+	err    error
+}
+
+func (r *recorder) Name() string { return "recorder" }
+
+func (r *recorder) Send(_ context.Context, evt Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, evt)
+	return r.err
+}
+
+func (r *recorder) rules() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	rules := make([]string, len(r.events))
+	for i, evt := range r.events {
+		rules[i] = evt.Rule + "/" + evt.Subject
+	}
+	return rules
+}
+
+func TestPublishCooldown(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name string
+		evt  Event
+		want bool
+	}{
+		{"first", Event{Rule: RuleRAID, Subject: "md0", Time: now}, true},
+		{"repeat", Event{Rule: RuleRAID, Subject: "md0", Time: now.Add(time.Minute)}, false},
+		{"other subject", Event{Rule: RuleRAID, Subject: "md1", Time: now.Add(time.Minute)}, true},
+		{"other rule", Event{Rule: RuleSMART, Subject: "md0", Time: now.Add(time.Minute)}, true},
+		{"after cooldown", Event{Rule: RuleRAID, Subject: "md0", Time: now.Add(16 * time.Minute)}, true},
+	}
+
+	bus := NewBus(nil, 15*time.Minute)
+	for _, tt := range tests {
+		if got := bus.Publish(tt.evt); got != tt.want {
+			t.Errorf("%s: Publish() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPublishQueueFull(t *testing.T) {
+	bus := NewBus(nil, 0)
+	for i := range queueSize {
+		if !bus.Publish(Event{Rule: RuleKernel, Subject: string(rune('a' + i))}) {
+			t.Fatalf("Publish(%d) dropped before the queue was full", i)
+		}
+	}
+	if bus.Publish(Event{Rule: RuleKernel, Subject: "overflow"}) {
+		t.Error("Publish() queued an event beyond the queue size")
+	}
+}
+
+func TestBusDelivers(t *testing.T) {
+	failing := &recorder{err: errors.New("unreachable")}
+	ok := &recorder{}
+	bus := NewBus([]Sink{failing, ok}, time.Minute)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		bus.Run(ctx)
+		close(done)
+	}()
+
+	bus.Publish(Event{Rule: RuleUPS, Subject: "power_lost", Message: "Power lost"})
+	bus.Publish(Event{Rule: RuleUPS, Subject: "power_lost", Message: "Power lost"})
+	bus.Publish(Event{Rule: RuleUPS, Subject: "power_restored", Message: "Power restored"})
+
+	deadline := time.Now().Add(time.Second)
+	for len(ok.rules()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	want := []string{"ups/power_lost", "ups/power_restored"}
+	for _, r := range []*recorder{failing, ok} {
+		got := r.rules()
+		if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+			t.Errorf("delivered %v, want %v", got, want)
+		}
+	}
+}
+
+// slowSink takes delay to send, failing when its context ends first
+type slowSink struct {
+	recorder
+	delay time.Duration
+}
+
+func (s *slowSink) Send(ctx context.Context, evt Event) error {
+	select {
+	case <-time.After(s.delay):
+		return s.recorder.Send(ctx, evt)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func TestBusFlush(t *testing.T) {
+	sink := &slowSink{delay: 50 * time.Millisecond}
+	bus := NewBus([]Sink{sink}, 0)
+	if !bus.Flush(context.Background(), time.Millisecond) {
+		t.Error("Flush() of an empty bus = false")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go bus.Run(ctx)
+
+	bus.Publish(Event{Rule: RuleShutdown, Subject: "poweroff"})
+	if bus.Flush(context.Background(), time.Millisecond) {
+		t.Error("Flush() = true before the slow send finished")
+	}
+	if !bus.Flush(context.Background(), time.Second) {
+		t.Fatal("Flush() timed out waiting for the send")
+	}
+	if got := sink.rules(); len(got) != 1 || got[0] != "shutdown/poweroff" {
+		t.Errorf("delivered %v, want the shutdown alert", got)
+	}
+}
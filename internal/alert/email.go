@@ -0,0 +1,200 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// SMTP connection security
+const (
+	TLSStartTLS = "starttls"
+	TLSImplicit = "tls"
+	TLSNone     = "none"
+)
+
+// Default email templates; the data are the event, subject, message, host, time and details
+const (
+	DefaultEmailSubject = "{{.Host}}: {{.Message}}"
+	DefaultEmailBody    = `{{.Message}}
+
+Host:  {{.Host}}
+Event: {{.Event}}
+Time:  {{.Time}}
+{{range $k, $v := .Details}}{{$k}}: {{$v}}
+{{end}}`
+)
+
+// EmailConfig is the SMTP server and message templates of the email sink
+type EmailConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+	To       []string
+	// TLS is TLSStartTLS, TLSImplicit or TLSNone
+	TLS     string
+	Subject string
+	Body    string
+}
+
+type email struct {
+	cfg     EmailConfig
+	subject *template.Template
+	body    *template.Template
+}
+
+// emailData is what the subject and body templates are executed with
+type emailData struct {
+	Event   string
+	Subject string
+	Message string
+	Host    string
+	Time    string
+	Details map[string]any
+}
+
+// Email sends every alert as a plain text mail; empty templates use the defaults
+func Email(cfg EmailConfig) (Sink, error) {
+	if cfg.Host == "" || cfg.From == "" || len(cfg.To) == 0 {
+		return nil, errors.New("email needs a host, sender and recipient")
+	}
+	if cfg.Subject == "" {
+		cfg.Subject = DefaultEmailSubject
+	}
+	if cfg.Body == "" {
+		cfg.Body = DefaultEmailBody
+	}
+
+	subject, err := template.New("subject").Parse(cfg.Subject)
+	if err != nil {
+		return nil, fmt.Errorf("invalid email subject template: %w", err)
+	}
+	body, err := template.New("body").Parse(cfg.Body)
+	if err != nil {
+		return nil, fmt.Errorf("invalid email body template: %w", err)
+	}
+	return &email{cfg: cfg, subject: subject, body: body}, nil
+}
+
+func (e *email) Name() string { return "email " + e.cfg.Host }
+
+func (e *email) Send(ctx context.Context, evt Event) error {
+	msg, err := e.message(evt)
+	if err != nil {
+		return err
+	}
+
+	client, err := e.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if e.cfg.TLS == TLSStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return errors.New("server does not support STARTTLS")
+		}
+		if err := client.StartTLS(&tls.Config{ServerName: e.cfg.Host, MinVersion: tls.VersionTLS12}); err != nil {
+			return err
+		}
+	}
+	if e.cfg.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", e.cfg.Username, e.cfg.Password, e.cfg.Host)); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(e.cfg.From); err != nil {
+		return err
+	}
+	for _, to := range e.cfg.To {
+		if err := client.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// dial connects to the server, with TLS from the start for TLSImplicit, bounded by
+// the context deadline
+func (e *email) dial(ctx context.Context) (*smtp.Client, error) {
+	addr := net.JoinHostPort(e.cfg.Host, strconv.Itoa(e.cfg.Port))
+	var conn net.Conn
+	var err error
+	if e.cfg.TLS == TLSImplicit {
+		dialer := &tls.Dialer{Config: &tls.Config{ServerName: e.cfg.Host, MinVersion: tls.VersionTLS12}}
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	} else {
+		var dialer net.Dialer
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	client, err := smtp.NewClient(conn, e.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return client, nil
+}
+
+// message renders the templates into a mail with headers
+func (e *email) message(evt Event) ([]byte, error) {
+	data := emailData{
+		Event:   evt.Rule,
+		Subject: evt.Subject,
+		Message: evt.Message,
+		Host:    hostname,
+		Time:    evt.Time.Format(time.RFC1123Z),
+		Details: evt.Details,
+	}
+
+	var subject, body bytes.Buffer
+	if err := e.subject.Execute(&subject, data); err != nil {
+		return nil, fmt.Errorf("email subject: %w", err)
+	}
+	if err := e.body.Execute(&body, data); err != nil {
+		return nil, fmt.Errorf("email body: %w", err)
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", e.cfg.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(e.cfg.To, ", "))
+	// Line breaks in the subject would end the headers
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", strings.Join(strings.Fields(subject.String()), " ")))
+	fmt.Fprintf(&msg, "Date: %s\r\n", evt.Time.Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	msg.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	msg.Write(body.Bytes())
+	return msg.Bytes(), nil
+}
@@ -0,0 +1,168 @@
+package alert
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeSMTP accepts one session on a local port, returning the port and a channel
+// receiving the DATA it was sent; starttls controls whether STARTTLS is advertised
+func fakeSMTP(t *testing.T, starttls bool) (int, <-chan string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	data := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		reply := func(s string) { _, _ = conn.Write([]byte(s + "\r\n")) }
+
+		reply("220 localhost ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			cmd := strings.ToUpper(strings.TrimSpace(line))
+			switch {
+			case strings.HasPrefix(cmd, "EHLO"):
+				if starttls {
+					reply("250-localhost")
+					reply("250 STARTTLS")
+				} else {
+					reply("250 localhost")
+				}
+			case cmd == "DATA":
+				reply("354 go ahead")
+				var msg strings.Builder
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil || l == ".\r\n" {
+						break
+					}
+					msg.WriteString(l)
+				}
+				data <- msg.String()
+				reply("250 queued")
+			case cmd == "QUIT":
+				reply("221 bye")
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+	}()
+	return ln.Addr().(*net.TCPAddr).Port, data
+}
+
+func TestEmailSend(t *testing.T) {
+	port, data := fakeSMTP(t, false)
+	sink, err := Email(EmailConfig{
+		Host: "127.0.0.1",
+		Port: port,
+		From: "nas@example.com",
+		To:   []string{"admin@example.com", "ops@example.com"},
+		TLS:  TLSNone,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	evt := Event{
+		Rule:    RuleCPUTemp,
+		Subject: "cpu",
+		Message: "CPU temperature 85.0°C above 80°C for 1m0s",
+		Details: map[string]any{"temperature": 85},
+		Time:    time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := sink.Send(ctx, evt); err != nil {
+		t.Fatal(err)
+	}
+
+	msg := <-data
+	for _, want := range []string{
+		"From: nas@example.com\r\n",
+		"To: admin@example.com, ops@example.com\r\n",
+		"Subject: =?utf-8?q?",
+		"Content-Type: text/plain; charset=utf-8\r\n",
+		"\r\nCPU temperature 85.0°C above 80°C for 1m0s\r\n",
+		"Event: cpu_temp\r\n",
+		"temperature: 85\r\n",
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("message lacks %q:\n%s", want, msg)
+		}
+	}
+}
+
+func TestEmailRequiresSTARTTLS(t *testing.T) {
+	port, _ := fakeSMTP(t, false)
+	sink, err := Email(EmailConfig{Host: "127.0.0.1", Port: port, From: "a@b", To: []string{"c@d"}, TLS: TLSStartTLS})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Send(context.Background(), Event{Rule: RuleRAID}); err == nil || !strings.Contains(err.Error(), "STARTTLS") {
+		t.Errorf("Send() = %v, want a STARTTLS error", err)
+	}
+}
+
+func TestEmailTemplates(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     EmailConfig
+		wantErr bool
+		subject string
+	}{
+		{"default", EmailConfig{}, false, "Subject: host: RAID md0 degraded\r\n"},
+		{"custom", EmailConfig{Subject: "[{{.Event}}] {{.Subject}}\nsecond line"}, false, "Subject: [raid_degraded] md0 second line\r\n"},
+		{"invalid", EmailConfig{Subject: "{{.Event"}, true, ""},
+		{"unknown field", EmailConfig{Body: "{{.Missing}}"}, false, ""},
+	}
+	orig := hostname
+	t.Cleanup(func() { hostname = orig })
+	hostname = "host"
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.Host, tt.cfg.From, tt.cfg.To = "smtp.example.com", "a@b", []string{"c@d"}
+			sink, err := Email(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Email() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			msg, err := sink.(*email).message(Event{Rule: RuleRAID, Subject: "md0", Message: "RAID md0 degraded"})
+			if tt.subject == "" {
+				if err == nil {
+					t.Errorf("message() rendered %q, want a template error", msg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(msg), tt.subject) {
+				t.Errorf("message() = %q, want %q", msg, tt.subject)
+			}
+		})
+	}
+
+	if _, err := Email(EmailConfig{Host: "smtp.example.com", Port: 587}); err == nil {
+		t.Error("Email() without sender and recipient = nil error")
+	}
+}
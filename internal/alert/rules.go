@@ -0,0 +1,207 @@
+package alert
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kolobock/rockpi-quad-go/internal/logger"
+)
+
+// Rules are the conditions the watcher turns into alerts; zero values disable a rule
+type Rules struct {
+	// CPUTemp and DiskTemp alert once a temperature stayed at or above them for
+	// CPUTempFor or DiskTempFor
+	CPUTemp     float64
+	CPUTempFor  time.Duration
+	DiskTemp    float64
+	DiskTempFor time.Duration
+	// FanTachs are hwmon fanN_input files keyed by fan ("cpu", "disk"); a fan that
+	// reads 0 RPM while driven for FanStallFor has stalled
+	FanTachs    map[string]string
+	FanStallFor time.Duration
+	// DiskRemoved alerts when a disk that was present disappears
+	DiskRemoved bool
+}
+
+// Sources provide the readings the rules check; nil functions disable their rules
+type Sources struct {
+	CPUTemp func() (float64, bool)
+	// DiskTemps returns disk temperatures keyed by disk label
+	DiskTemps func() map[string]float64
+	// FanDuty returns the duty cycles (0-100) keyed like Rules.FanTachs
+	FanDuty func() map[string]float64
+	// Emergency reports whether fan control is in thermal emergency mode
+	Emergency func() bool
+	// Disks returns the present disks' labels keyed by persistent disk ID
+	Disks func() map[string]string
+}
+
+// threshold tracks a condition that must hold for some time before it alerts once;
+// it re-arms when the condition clears
+type threshold struct {
+	since time.Time
+	fired bool
+}
+
+func (t *threshold) check(now time.Time, active bool, hold time.Duration) bool {
+	if !active {
+		t.since, t.fired = time.Time{}, false
+		return false
+	}
+	if t.since.IsZero() {
+		t.since = now
+	}
+	if t.fired || now.Sub(t.since) < hold {
+		return false
+	}
+	t.fired = true
+	return true
+}
+
+// Watcher polls its sources and publishes an alert when a rule is broken
+type Watcher struct {
+	rules Rules
+	src   Sources
+	bus   *Bus
+
+	cpu       threshold
+	disks     map[string]*threshold
+	fans      map[string]*threshold
+	emergency bool
+	present   map[string]string
+}
+
+// NewWatcher creates a watcher publishing to bus
+func NewWatcher(rules Rules, src Sources, bus *Bus) *Watcher {
+	return &Watcher{
+		rules: rules,
+		src:   src,
+		bus:   bus,
+		disks: make(map[string]*threshold),
+		fans:  make(map[string]*threshold),
+	}
+}
+
+// Run checks the rules every interval until the context is cancelled, logging and
+// publishing every alert
+func (w *Watcher) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			for _, evt := range w.check(now) {
+				logger.Errorf("%s", evt.Message)
+				w.bus.Publish(evt)
+			}
+		}
+	}
+}
+
+// check evaluates every rule and returns the alerts raised at now
+func (w *Watcher) check(now time.Time) []Event {
+	var events []Event
+	raise := func(rule, subject, format string, args ...any) {
+		events = append(events, Event{Rule: rule, Subject: subject, Message: fmt.Sprintf(format, args...), Time: now})
+	}
+
+	if w.rules.CPUTemp > 0 && w.src.CPUTemp != nil {
+		if temp, ok := w.src.CPUTemp(); ok && w.cpu.check(now, temp >= w.rules.CPUTemp, w.rules.CPUTempFor) {
+			raise(RuleCPUTemp, "cpu", "CPU temperature %.1f°C above %g°C for %s", temp, w.rules.CPUTemp, w.rules.CPUTempFor)
+		}
+	}
+
+	if w.rules.DiskTemp > 0 && w.src.DiskTemps != nil {
+		temps := w.src.DiskTemps()
+		for _, label := range w.checkDisks(now, temps) {
+			raise(RuleDiskTemp, label, "Disk %s temperature %.1f°C above %g°C for %s", label, temps[label], w.rules.DiskTemp, w.rules.DiskTempFor)
+		}
+	}
+
+	if len(w.rules.FanTachs) > 0 && w.src.FanDuty != nil {
+		duty := w.src.FanDuty()
+		for _, fan := range sortedKeys(w.rules.FanTachs) {
+			rpm, err := readRPM(w.rules.FanTachs[fan])
+			if err != nil {
+				continue
+			}
+			t := w.threshold(w.fans, fan)
+			if t.check(now, duty[fan] > 0 && rpm == 0, w.rules.FanStallFor) {
+				raise(RuleFanStall, fan, "%s fan stalled: 0 RPM at %.0f%% duty for %s", fan, duty[fan], w.rules.FanStallFor)
+			}
+		}
+	}
+
+	if w.src.Emergency != nil {
+		active := w.src.Emergency()
+		if active && !w.emergency {
+			raise(RuleEmergency, "", "Thermal emergency: fans forced to 100%%")
+		}
+		w.emergency = active
+	}
+
+	if w.rules.DiskRemoved && w.src.Disks != nil {
+		disks := w.src.Disks()
+		if w.present != nil {
+			for _, id := range sortedKeys(w.present) {
+				if _, ok := disks[id]; !ok {
+					raise(RuleDiskRemoved, id, "Disk %s (%s) removed", w.present[id], id)
+				}
+			}
+		}
+		w.present = disks
+	}
+
+	return events
+}
+
+// checkDisks returns the disks whose temperature just broke the rule
+func (w *Watcher) checkDisks(now time.Time, temps map[string]float64) []string {
+	var hot []string
+	for _, label := range sortedKeys(temps) {
+		if w.threshold(w.disks, label).check(now, temps[label] >= w.rules.DiskTemp, w.rules.DiskTempFor) {
+			hot = append(hot, label)
+		}
+	}
+	// A disk that is gone no longer holds its condition
+	for label := range w.disks {
+		if _, ok := temps[label]; !ok {
+			delete(w.disks, label)
+		}
+	}
+	return hot
+}
+
+func (w *Watcher) threshold(m map[string]*threshold, key string) *threshold {
+	t, ok := m[key]
+	if !ok {
+		t = &threshold{}
+		m[key] = t
+	}
+	return t
+}
+
+func readRPM(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(string(data)))
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
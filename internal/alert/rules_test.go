@@ -0,0 +1,118 @@
+package alert
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestThreshold(t *testing.T) {
+	start := time.Now()
+	steps := []struct {
+		after  time.Duration
+		active bool
+		want   bool
+	}{
+		{0, true, false},
+		{30 * time.Second, true, false},
+		{60 * time.Second, true, true},
+		{90 * time.Second, true, false},
+		{100 * time.Second, false, false},
+		{110 * time.Second, true, false},
+		{170 * time.Second, true, true},
+	}
+
+	var th threshold
+	for _, s := range steps {
+		if got := th.check(start.Add(s.after), s.active, time.Minute); got != s.want {
+			t.Errorf("check(+%s, %v) = %v, want %v", s.after, s.active, got, s.want)
+		}
+	}
+}
+
+func rulesOf(events []Event) []string {
+	rules := make([]string, len(events))
+	for i, evt := range events {
+		rules[i] = evt.Rule + "/" + evt.Subject
+	}
+	return rules
+}
+
+func TestWatcherTemperatures(t *testing.T) {
+	cpu := 70.0
+	disks := map[string]float64{"Bay 1": 40, "Bay 2": 40}
+	w := NewWatcher(Rules{CPUTemp: 80, CPUTempFor: time.Minute, DiskTemp: 50}, Sources{
+		CPUTemp:   func() (float64, bool) { return cpu, true },
+		DiskTemps: func() map[string]float64 { return disks },
+	}, nil)
+
+	start := time.Now()
+	if got := w.check(start); len(got) != 0 {
+		t.Errorf("check() with normal temperatures = %v", rulesOf(got))
+	}
+
+	cpu, disks["Bay 2"] = 85, 55
+	if got := rulesOf(w.check(start.Add(10 * time.Second))); len(got) != 1 || got[0] != "disk_temp/Bay 2" {
+		t.Errorf("check() = %v, want the Bay 2 alert only", got)
+	}
+	got := w.check(start.Add(80 * time.Second))
+	if len(got) != 1 || got[0].Rule != RuleCPUTemp || got[0].Message != "CPU temperature 85.0°C above 80°C for 1m0s" {
+		t.Errorf("check() after a minute = %+v, want the CPU alert", got)
+	}
+}
+
+func TestWatcherFanStall(t *testing.T) {
+	tach := filepath.Join(t.TempDir(), "fan1_input")
+	setRPM := func(rpm string) {
+		if err := os.WriteFile(tach, []byte(rpm+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	duty := map[string]float64{"cpu": 60}
+	w := NewWatcher(Rules{FanTachs: map[string]string{"cpu": tach, "disk": "/nonexistent"}, FanStallFor: 30 * time.Second}, Sources{
+		FanDuty: func() map[string]float64 { return duty },
+	}, nil)
+
+	start := time.Now()
+	setRPM("1800")
+	if got := w.check(start); len(got) != 0 {
+		t.Errorf("check() with a spinning fan = %v", rulesOf(got))
+	}
+	setRPM("0")
+	w.check(start.Add(10 * time.Second))
+	if got := rulesOf(w.check(start.Add(40 * time.Second))); len(got) != 1 || got[0] != "fan_stall/cpu" {
+		t.Errorf("check() = %v, want a stalled cpu fan", got)
+	}
+
+	// A fan switched off is not stalled
+	duty["cpu"] = 0
+	w.check(start.Add(50 * time.Second))
+	if got := w.check(start.Add(90 * time.Second)); len(got) != 0 {
+		t.Errorf("check() with the fan off = %v", rulesOf(got))
+	}
+}
+
+func TestWatcherDiskRemovedAndEmergency(t *testing.T) {
+	disks := map[string]string{"wwn-1": "Bay 1", "wwn-2": "Bay 2"}
+	emergency := false
+	w := NewWatcher(Rules{DiskRemoved: true}, Sources{
+		Disks:     func() map[string]string { return disks },
+		Emergency: func() bool { return emergency },
+	}, nil)
+
+	now := time.Now()
+	if got := w.check(now); len(got) != 0 {
+		t.Errorf("first check() = %v, want nothing", rulesOf(got))
+	}
+
+	disks = map[string]string{"wwn-1": "Bay 1"}
+	emergency = true
+	got := w.check(now)
+	if len(got) != 2 || got[0].Rule != RuleEmergency || got[1].Rule != RuleDiskRemoved || got[1].Message != "Disk Bay 2 (wwn-2) removed" {
+		t.Errorf("check() = %+v, want emergency and Bay 2 removed", got)
+	}
+	if got := w.check(now); len(got) != 0 {
+		t.Errorf("repeated check() = %v, want nothing", rulesOf(got))
+	}
+}
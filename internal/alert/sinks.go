@@ -0,0 +1,138 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"slices"
+	"time"
+
+	"github.com/kolobock/rockpi-quad-go/internal/diag"
+)
+
+// telegramAPI is replaced in tests
+var telegramAPI = "https://api.telegram.org"
+
+// hostname identifies the machine in alerts
+var hostname, _ = os.Hostname()
+
+type webhook struct {
+	url string
+}
+
+// Webhook posts every alert as JSON to url: event, subject, message, host and time,
+// followed by the event details
+func Webhook(url string) Sink {
+	return webhook{url: url}
+}
+
+func (w webhook) Name() string { return "webhook " + w.url }
+
+func (w webhook) Send(ctx context.Context, evt Event) error {
+	payload := make(map[string]any, len(evt.Details)+5)
+	for k, v := range evt.Details {
+		payload[k] = v
+	}
+	payload["event"] = evt.Rule
+	payload["subject"] = evt.Subject
+	payload["message"] = evt.Message
+	payload["host"] = hostname
+	payload["time"] = evt.Time.Format(time.RFC3339)
+	return postJSON(ctx, w.url, payload)
+}
+
+type telegram struct {
+	token string
+	chat  string
+}
+
+// Telegram sends the alert message to a chat through a bot
+func Telegram(token, chat string) Sink {
+	return telegram{token: token, chat: chat}
+}
+
+func (t telegram) Name() string { return "telegram" }
+
+func (t telegram) Send(ctx context.Context, evt Event) error {
+	return postJSON(ctx, telegramAPI+"/bot"+t.token+"/sendMessage", map[string]string{
+		"chat_id": t.chat,
+		"text":    hostname + ": " + evt.Message,
+	})
+}
+
+type command struct {
+	command string
+}
+
+// Command runs a shell command for every alert, passing it in ROCKPI_ALERT_EVENT,
+// ROCKPI_ALERT_SUBJECT and ROCKPI_ALERT_MESSAGE
+func Command(cmd string) Sink {
+	return command{command: cmd}
+}
+
+func (c command) Name() string { return "command" }
+
+func (c command) Send(ctx context.Context, evt Event) error {
+	diag.ShellExecs.Add(1)
+	// #nosec G204 - command comes from the config file
+	cmd := exec.CommandContext(ctx, "sh", "-c", c.command)
+	cmd.Env = append(os.Environ(),
+		"ROCKPI_ALERT_EVENT="+evt.Rule,
+		"ROCKPI_ALERT_SUBJECT="+evt.Subject,
+		"ROCKPI_ALERT_MESSAGE="+evt.Message,
+	)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%w: %s", err, bytes.TrimSpace(out))
+	}
+	return nil
+}
+
+type filtered struct {
+	Sink
+	rules []string
+}
+
+// Only passes the alerts of the given rules on to s
+func Only(s Sink, rules ...string) Sink {
+	return filtered{Sink: s, rules: rules}
+}
+
+func (f filtered) Send(ctx context.Context, evt Event) error {
+	if !slices.Contains(f.rules, evt.Rule) {
+		return nil
+	}
+	return f.Sink.Send(ctx, evt)
+}
+
+func postJSON(ctx context.Context, target string, v any) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		// Keep tokens in the URL path out of the log
+		var uerr *url.Error
+		if errors.As(err, &uerr) {
+			return fmt.Errorf("%s %s: %w", uerr.Op, req.URL.Host, uerr.Err)
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s returned %s", req.URL.Host, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWebhook(t *testing.T) {
+	var got map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Content-Type = %q", ct)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decode webhook: %v", err)
+		}
+	}))
+	defer srv.Close()
+
+	evt := Event{
+		Rule:    RuleRAID,
+		Subject: "md0",
+		Message: "RAID md0 degraded: active [U_]",
+		Details: map[string]any{"array": "md0", "state": "active", "devices": "[U_]"},
+		Time:    time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+	}
+	if err := Webhook(srv.URL).Send(context.Background(), evt); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]any{
+		"event":   "raid_degraded",
+		"subject": "md0",
+		"array":   "md0",
+		"state":   "active",
+		"devices": "[U_]",
+		"message": "RAID md0 degraded: active [U_]",
+		"host":    hostname,
+		"time":    "2024-05-01T12:00:00Z",
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("payload[%q] = %v, want %v", k, got[k], v)
+		}
+	}
+}
+
+func TestWebhookStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	if err := Webhook(srv.URL).Send(context.Background(), Event{Rule: RuleKernel}); err == nil {
+		t.Error("Send() = nil, want an error for a 500 response")
+	}
+}
+
+func TestTelegram(t *testing.T) {
+	var path string
+	var got map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decode message: %v", err)
+		}
+	}))
+	defer srv.Close()
+
+	orig := telegramAPI
+	t.Cleanup(func() { telegramAPI = orig })
+	telegramAPI = srv.URL
+
+	if err := Telegram("123:abc", "-1001").Send(context.Background(), Event{Rule: RuleCPUTemp, Message: "CPU hot"}); err != nil {
+		t.Fatal(err)
+	}
+	if path != "/bot123:abc/sendMessage" {
+		t.Errorf("path = %q", path)
+	}
+	if got["chat_id"] != "-1001" || got["text"] != hostname+": CPU hot" {
+		t.Errorf("message = %v", got)
+	}
+}
+
+func TestPostJSONHidesURL(t *testing.T) {
+	orig := telegramAPI
+	t.Cleanup(func() { telegramAPI = orig })
+	telegramAPI = "http://127.0.0.1:1"
+
+	err := Telegram("secret-token", "1").Send(context.Background(), Event{})
+	if err == nil {
+		t.Fatal("Send() = nil, want a connection error")
+	}
+	if strings.Contains(err.Error(), "secret-token") {
+		t.Errorf("error %q leaks the bot token", err)
+	}
+}
+
+func TestCommand(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "alert")
+	cmd := Command(`echo "$ROCKPI_ALERT_EVENT $ROCKPI_ALERT_SUBJECT $ROCKPI_ALERT_MESSAGE" > ` + out)
+	if err := cmd.Send(context.Background(), Event{Rule: RuleDiskRemoved, Subject: "wwn-1", Message: "Disk gone"}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(string(data)); got != "disk_removed wwn-1 Disk gone" {
+		t.Errorf("command saw %q", got)
+	}
+
+	if err := Command("echo oops >&2; exit 3").Send(context.Background(), Event{}); err == nil || !strings.Contains(err.Error(), "oops") {
+		t.Errorf("Send() = %v, want the failing command's output", err)
+	}
+}
+
+func TestOnly(t *testing.T) {
+	r := &recorder{}
+	sink := Only(r, RuleRAID)
+	for _, rule := range []string{RuleRAID, RuleKernel, RuleRAID} {
+		if err := sink.Send(context.Background(), Event{Rule: rule}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := r.rules(); len(got) != 2 {
+		t.Errorf("Only() passed %v, want the two RAID alerts", got)
+	}
+	if sink.Name() != "recorder" {
+		t.Errorf("Name() = %q", sink.Name())
+	}
+}
@@ -0,0 +1,197 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/kolobock/rockpi-quad-go/internal/config"
+	"github.com/kolobock/rockpi-quad-go/internal/fan"
+	"github.com/kolobock/rockpi-quad-go/internal/flags"
+	"github.com/kolobock/rockpi-quad-go/internal/health"
+	"github.com/kolobock/rockpi-quad-go/internal/logger"
+	"github.com/kolobock/rockpi-quad-go/internal/network"
+	"github.com/kolobock/rockpi-quad-go/internal/oled"
+	"github.com/kolobock/rockpi-quad-go/internal/rails"
+	"github.com/kolobock/rockpi-quad-go/internal/store"
+)
+
+const shutdownTimeout = 5 * time.Second
+
+// FanController is the fan control surface exposed over the API
+type FanController interface {
+	Status() fan.Status
+	StartupReport() (fan.ThermalReport, bool)
+	ToggleFan()
+	SetOverride(percent float64, d time.Duration) error
+	ClearOverride()
+	SetProfile(name string) error
+}
+
+// Display shows messages on the front panel
+type Display interface {
+	ShowMessage(title, text string)
+}
+
+// RenderProfiler reports how long the display takes to render and transmit each page
+type RenderProfiler interface {
+	PageTimings() []oled.PageTiming
+}
+
+// DisplayBus reports the I2C write counters of the display
+type DisplayBus interface {
+	BusStats() oled.BusStats
+}
+
+// Server serves status, metrics and control endpoints over HTTP
+type Server struct {
+	cfg     *config.Config
+	fanCtrl FanController
+	display Display
+	checker *network.Checker
+	modules *health.Tracker
+	flags   *flags.Set
+	store   *store.Store
+	rails   *rails.Monitor
+	pages   RenderProfiler
+	bus     DisplayBus
+	power   map[string]func()
+	tokens  *tokenStore
+	mtls    bool
+	mux     *http.ServeMux
+}
+
+// New creates an API server; optional sources are attached with the Set methods before Run.
+// fanCtrl may be nil when the fan module is disabled.
+func New(cfg *config.Config, fanCtrl FanController) *Server {
+	s := &Server{
+		cfg:     cfg,
+		fanCtrl: fanCtrl,
+		power:   make(map[string]func()),
+		tokens:  newTokenStore(cfg.API.ReadTokens, cfg.API.ControlTokens),
+		mtls:    cfg.API.ClientCA != "",
+		mux:     http.NewServeMux(),
+	}
+	s.routes()
+	return s
+}
+
+// SetDisplay enables the display message endpoint
+func (s *Server) SetDisplay(d Display) {
+	s.display = d
+}
+
+// SetHealthChecker adds connectivity check results to the status
+func (s *Server) SetHealthChecker(checker *network.Checker) {
+	s.checker = checker
+}
+
+// SetModuleTracker adds the operating mode and per-module states to the status
+func (s *Server) SetModuleTracker(t *health.Tracker) {
+	s.modules = t
+}
+
+// SetFlags enables the runtime flag endpoints
+func (s *Server) SetFlags(f *flags.Set) {
+	s.flags = f
+}
+
+// SetRailMonitor adds power rail voltages and currents to the metrics
+func (s *Server) SetRailMonitor(m *rails.Monitor) {
+	s.rails = m
+}
+
+// SetRenderProfiler adds page render timings to the metrics and the debug status
+func (s *Server) SetRenderProfiler(p RenderProfiler) {
+	s.pages = p
+}
+
+// SetDisplayBus adds the display's I2C write counters to the status and the metrics
+func (s *Server) SetDisplayBus(b DisplayBus) {
+	s.bus = b
+}
+
+// SetPowerAction registers a power action (e.g. "poweroff", "reboot") for the power endpoint
+func (s *Server) SetPowerAction(name string, action func()) {
+	s.power[name] = action
+}
+
+func (s *Server) routes() {
+	s.mux.HandleFunc("GET /api/status", s.require(ScopeRead, s.handleStatus))
+	if s.cfg.Modules.Metrics {
+		s.mux.HandleFunc("GET /metrics", s.require(ScopeRead, s.handleMetrics))
+	}
+
+	if s.fanCtrl != nil {
+		s.mux.HandleFunc("GET /api/fan/startup", s.require(ScopeRead, s.handleFanStartup))
+		s.mux.HandleFunc("POST /api/fan/toggle", s.require(ScopeControl, s.handleFanToggle))
+		s.mux.HandleFunc("POST /api/fan/override", s.require(ScopeControl, s.handleFanOverride))
+		s.mux.HandleFunc("DELETE /api/fan/override", s.require(ScopeControl, s.handleFanOverrideClear))
+		s.mux.HandleFunc("POST /api/fan/profile", s.require(ScopeControl, s.handleFanProfile))
+	}
+	s.mux.HandleFunc("GET /api/history/temps", s.require(ScopeRead, s.handleTempHistory))
+	s.mux.HandleFunc("GET /api/history/network", s.require(ScopeRead, s.handleNetworkHistory))
+	s.mux.HandleFunc("GET /api/history/smart", s.require(ScopeRead, s.handleSMARTHistory))
+	s.mux.HandleFunc("GET /api/flags", s.require(ScopeRead, s.handleFlags))
+	s.mux.HandleFunc("PUT /api/flags/{name}", s.require(ScopeControl, s.handleFlagSet))
+	s.mux.HandleFunc("POST /api/display/message", s.require(ScopeControl, s.handleDisplayMessage))
+	s.mux.HandleFunc("POST /api/power/{action}", s.require(ScopeControl, s.handlePower))
+	if s.cfg.API.PProf {
+		s.debugRoutes()
+	}
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Run listens on the configured address until the context is cancelled
+func (s *Server) Run(ctx context.Context) error {
+	tlsConfig, err := loadTLSConfig(s.cfg.API)
+	if err != nil {
+		return err
+	}
+
+	srv := &http.Server{
+		Addr:              s.cfg.API.Listen,
+		Handler:           s,
+		TLSConfig:         tlsConfig,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			logger.Errorf("API server shutdown error: %v", err)
+		}
+	}()
+
+	logger.Infof("API listening on %s (tls: %t, mtls: %t)", s.cfg.API.Listen, tlsConfig != nil, s.mtls)
+	if tlsConfig != nil {
+		err = srv.ListenAndServeTLS(s.cfg.API.TLSCert, s.cfg.API.TLSKey)
+	} else {
+		err = srv.ListenAndServe()
+	}
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("API server failed: %w", err)
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logger.Errorf("Failed to encode API response: %v", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
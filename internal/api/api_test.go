@@ -0,0 +1,394 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kolobock/rockpi-quad-go/internal/config"
+	"github.com/kolobock/rockpi-quad-go/internal/fan"
+	"github.com/kolobock/rockpi-quad-go/internal/health"
+	"github.com/kolobock/rockpi-quad-go/internal/oled"
+	"github.com/kolobock/rockpi-quad-go/internal/rails"
+	"github.com/kolobock/rockpi-quad-go/internal/store"
+	"github.com/kolobock/rockpi-quad-go/internal/thermal"
+)
+
+type fakeFan struct {
+	status   fan.Status
+	startup  *fan.ThermalReport
+	override float64
+	profile  string
+}
+
+func (f *fakeFan) Status() fan.Status { return f.status }
+func (f *fakeFan) ToggleFan()         { f.status.Enabled = !f.status.Enabled }
+func (f *fakeFan) ClearOverride()     { f.override = 0 }
+
+func (f *fakeFan) StartupReport() (fan.ThermalReport, bool) {
+	if f.startup == nil {
+		return fan.ThermalReport{}, false
+	}
+	return *f.startup, true
+}
+
+func (f *fakeFan) SetOverride(percent float64, _ time.Duration) error {
+	f.override = percent
+	return nil
+}
+
+func (f *fakeFan) SetProfile(name string) error {
+	f.profile = name
+	return nil
+}
+
+type fakeProfiler []oled.PageTiming
+
+func (p fakeProfiler) PageTimings() []oled.PageTiming { return p }
+
+type fakeBus oled.BusStats
+
+func (b fakeBus) BusStats() oled.BusStats { return oled.BusStats(b) }
+
+func newTestServer(readTokens, controlTokens []string) (*Server, *fakeFan) {
+	cfg := &config.Config{
+		Fan:     config.FanConfig{OverrideMinutes: 30},
+		API:     config.APIConfig{ReadTokens: readTokens, ControlTokens: controlTokens},
+		Modules: config.ModulesConfig{Metrics: true},
+	}
+	f := &fakeFan{status: fan.Status{Enabled: true, CPUTemp: 42.5, CPUDuty: 25}}
+	return New(cfg, f), f
+}
+
+func doRequest(s *Server, method, path, token, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestScopes(t *testing.T) {
+	s, _ := newTestServer([]string{"reader"}, []string{"admin"})
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		want   int
+	}{
+		{"status without token", http.MethodGet, "/api/status", "", http.StatusUnauthorized},
+		{"status with bad token", http.MethodGet, "/api/status", "nope", http.StatusUnauthorized},
+		{"status with read token", http.MethodGet, "/api/status", "reader", http.StatusOK},
+		{"metrics with control token", http.MethodGet, "/metrics", "admin", http.StatusOK},
+		{"toggle with read token", http.MethodPost, "/api/fan/toggle", "reader", http.StatusForbidden},
+		{"toggle with control token", http.MethodPost, "/api/fan/toggle", "admin", http.StatusOK},
+		{"power with read token", http.MethodPost, "/api/power/poweroff", "reader", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := doRequest(s, tt.method, tt.path, tt.token, "")
+			if rec.Code != tt.want {
+				t.Errorf("%s %s = %d, want %d", tt.method, tt.path, rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestOpenReadsWithoutReadTokens(t *testing.T) {
+	s, _ := newTestServer(nil, []string{"admin"})
+
+	if rec := doRequest(s, http.MethodGet, "/api/status", "", ""); rec.Code != http.StatusOK {
+		t.Errorf("status without token = %d, want 200", rec.Code)
+	}
+	if rec := doRequest(s, http.MethodPost, "/api/fan/toggle", "", ""); rec.Code != http.StatusForbidden {
+		t.Errorf("toggle without token = %d, want 403", rec.Code)
+	}
+}
+
+func TestFanOverride(t *testing.T) {
+	s, f := newTestServer(nil, []string{"admin"})
+
+	rec := doRequest(s, http.MethodPost, "/api/fan/override", "admin", `{"percent": 60}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("override = %d, want 200: %s", rec.Code, rec.Body)
+	}
+	if f.override != 60 {
+		t.Errorf("override percent = %v, want 60", f.override)
+	}
+
+	if rec := doRequest(s, http.MethodPost, "/api/fan/override", "admin", `{`); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid body = %d, want 400", rec.Code)
+	}
+}
+
+func TestPowerAction(t *testing.T) {
+	s, _ := newTestServer(nil, []string{"admin"})
+	called := false
+	s.SetPowerAction("reboot", func() { called = true })
+
+	if rec := doRequest(s, http.MethodPost, "/api/power/reboot", "admin", ""); rec.Code != http.StatusAccepted {
+		t.Errorf("reboot = %d, want 202", rec.Code)
+	}
+	if !called {
+		t.Error("reboot action not called")
+	}
+	if rec := doRequest(s, http.MethodPost, "/api/power/halt", "admin", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown action = %d, want 404", rec.Code)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	s, _ := newTestServer(nil, nil)
+
+	rec := doRequest(s, http.MethodGet, "/metrics", "", "")
+	body := rec.Body.String()
+	for _, want := range []string{
+		`rockpi_temperature_celsius{sensor="cpu"} 42.5`,
+		`rockpi_fan_duty_percent{fan="cpu"} 25`,
+		"rockpi_fan_enabled 1",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics missing %q:\n%s", want, body)
+		}
+	}
+}
+
+func TestHATMetrics(t *testing.T) {
+	s, f := newTestServer(nil, nil)
+	f.status.HATs = []fan.HATStatus{
+		{ID: config.MainHAT, Label: "Main", DiskTemp: 38, Duty: 25},
+		{ID: "2", Label: "HAT 2", DiskTemp: 44.26, Duty: 50},
+	}
+
+	body := doRequest(s, http.MethodGet, "/metrics", "", "").Body.String()
+	for _, want := range []string{
+		`rockpi_hat_disk_temperature_celsius{hat="main"} 38`,
+		`rockpi_hat_fan_duty_percent{hat="2"} 50`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics missing %q:\n%s", want, body)
+		}
+	}
+
+	var resp statusResponse
+	if err := json.NewDecoder(doRequest(s, http.MethodGet, "/api/status", "", "").Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Fan.HATs) != 2 || resp.Fan.HATs[1].Label != "HAT 2" {
+		t.Errorf("status hats = %+v", resp.Fan.HATs)
+	}
+}
+
+func TestRailMetrics(t *testing.T) {
+	var buf strings.Builder
+	writeRailMetrics(metricsWriter{w: &buf}, []rails.State{
+		{Name: "12v", Good: true, Volts: 12.1},
+		{Name: "disks", Volts: 5.02, Amps: 3.4, HasCurrent: true, Overcurrent: true, Dropouts: 2},
+	})
+
+	body := buf.String()
+	for _, want := range []string{
+		`rockpi_rail_good{rail="12v"} 1`,
+		`rockpi_rail_good{rail="disks"} 0`,
+		`rockpi_rail_dropouts_total{rail="disks"} 2`,
+		`rockpi_rail_volts{rail="12v"} 12.1`,
+		`rockpi_rail_amps{rail="disks"} 3.4`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics missing %q:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, `rockpi_rail_amps{rail="12v"}`) {
+		t.Errorf("current reported for a rail without a power monitor:\n%s", body)
+	}
+}
+
+func TestWithoutFanModule(t *testing.T) {
+	cfg := &config.Config{Modules: config.ModulesConfig{Metrics: false}}
+	s := New(cfg, nil)
+
+	if rec := doRequest(s, http.MethodGet, "/api/status", "", ""); rec.Code != http.StatusOK {
+		t.Errorf("status = %d, want 200", rec.Code)
+	}
+	if rec := doRequest(s, http.MethodPost, "/api/fan/toggle", "", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("fan toggle = %d, want 404 without the fan module", rec.Code)
+	}
+	if rec := doRequest(s, http.MethodGet, "/metrics", "", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("metrics = %d, want 404 with metrics disabled", rec.Code)
+	}
+}
+
+func TestStatusReportsDegradedMode(t *testing.T) {
+	s, _ := newTestServer(nil, nil)
+	modules := health.NewTracker()
+	modules.Set(health.Fan, health.StateOK, "")
+	modules.Set(health.OLED, health.StateFailed, "no display")
+	s.SetModuleTracker(modules)
+
+	rec := doRequest(s, http.MethodGet, "/api/status", "", "")
+	var resp statusResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode status: %v", err)
+	}
+	if resp.Health == nil || resp.Health.Mode != health.ModeDegraded {
+		t.Fatalf("health = %+v, want degraded mode", resp.Health)
+	}
+	if len(resp.Health.Modules) != 2 || resp.Health.Modules[1].Reason != "no display" {
+		t.Errorf("modules = %+v, want oled failure reason", resp.Health.Modules)
+	}
+}
+
+func TestStatusRoundsTemperatures(t *testing.T) {
+	s, f := newTestServer(nil, nil)
+	s.cfg.Temp.API = thermal.Format{Precision: 1, Rounding: thermal.RoundDown}
+	f.status.CPUTemp = 42.58
+
+	var resp statusResponse
+	if err := json.NewDecoder(doRequest(s, http.MethodGet, "/api/status", "", "").Body).Decode(&resp); err != nil {
+		t.Fatalf("decode status: %v", err)
+	}
+	if resp.Fan == nil || resp.Fan.CPUTemp != 42.5 {
+		t.Errorf("fan = %+v, want cpu_temp 42.5", resp.Fan)
+	}
+}
+
+func TestStatusDebugPages(t *testing.T) {
+	s, _ := newTestServer(nil, nil)
+	s.SetRenderProfiler(fakeProfiler{{
+		Index: 3, Name: "Exec", Renders: 4, RenderTotal: 2 * time.Second, RenderMax: time.Second,
+		Transmits: 2, TransmitTotal: 60 * time.Millisecond, TransmitMax: 40 * time.Millisecond, Slow: true,
+	}})
+
+	var resp statusResponse
+	if err := json.NewDecoder(doRequest(s, http.MethodGet, "/api/status", "", "").Body).Decode(&resp); err != nil {
+		t.Fatalf("decode status: %v", err)
+	}
+	if resp.Pages != nil {
+		t.Errorf("pages = %+v without debug, want none", resp.Pages)
+	}
+
+	if err := json.NewDecoder(doRequest(s, http.MethodGet, "/api/status?debug=1", "", "").Body).Decode(&resp); err != nil {
+		t.Fatalf("decode status: %v", err)
+	}
+	want := pageTiming{Index: 3, Name: "Exec", Renders: 4, RenderAvgMs: 500, RenderMaxMs: 1000, TransmitAvgMs: 30, TransmitMaxMs: 40, Slow: true}
+	if len(resp.Pages) != 1 || resp.Pages[0] != want {
+		t.Errorf("pages = %+v, want [%+v]", resp.Pages, want)
+	}
+}
+
+func TestStatusDisplayBus(t *testing.T) {
+	s, _ := newTestServer(nil, nil)
+	s.SetDisplayBus(fakeBus{Available: false, WriteErrors: 31, Retries: 20, Reopens: 2, ConsecutiveFailures: 10})
+
+	var resp statusResponse
+	if err := json.NewDecoder(doRequest(s, http.MethodGet, "/api/status", "", "").Body).Decode(&resp); err != nil {
+		t.Fatalf("decode status: %v", err)
+	}
+	want := displayStatus{I2CWriteErrors: 31, I2CRetries: 20, I2CReopens: 2, ConsecutiveFailures: 10}
+	if resp.Display == nil || *resp.Display != want {
+		t.Errorf("display = %+v, want %+v", resp.Display, want)
+	}
+
+	body := doRequest(s, http.MethodGet, "/metrics", "", "").Body.String()
+	for _, want := range []string{"rockpi_oled_available 0", "rockpi_oled_i2c_write_errors_total 31", "rockpi_oled_i2c_reopens_total 2"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics missing %q", want)
+		}
+	}
+}
+
+func TestPageMetrics(t *testing.T) {
+	var buf strings.Builder
+	writePageMetrics(metricsWriter{w: &buf}, []oled.PageTiming{
+		{Index: 0, Name: "Clock", Renders: 10, RenderTotal: 50 * time.Millisecond, RenderMax: 8 * time.Millisecond},
+		{Index: 4, Name: "Exec", Renders: 2, RenderTotal: 3 * time.Second, TransmitTotal: 500 * time.Millisecond, Slow: true},
+	})
+
+	body := buf.String()
+	for _, want := range []string{
+		`rockpi_oled_renders_total{page="Clock",index="0"} 10`,
+		`rockpi_oled_render_seconds_total{page="Exec",index="4"} 3`,
+		`rockpi_oled_render_max_seconds{page="Clock",index="0"} 0.008`,
+		`rockpi_oled_transmit_seconds_total{page="Exec",index="4"} 0.5`,
+		`rockpi_oled_slow{page="Exec",index="4"} 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics missing %q:\n%s", want, body)
+		}
+	}
+}
+
+func TestFanStartupReport(t *testing.T) {
+	s, f := newTestServer(nil, nil)
+
+	if rec := doRequest(s, http.MethodGet, "/api/fan/startup", "", ""); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("startup before report = %d, want 503", rec.Code)
+	}
+
+	f.startup = &fan.ThermalReport{
+		Profile: "balanced",
+		CPU:     fan.CurveReport{Temp: 42.5, Thresholds: [4]float64{35, 40, 45, 50}, Max: 80, Level: "lv1", Duty: 50},
+	}
+	rec := doRequest(s, http.MethodGet, "/api/fan/startup", "", "")
+	var resp startupReport
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode startup report: %v", err)
+	}
+	if resp.CPU.Level != "lv1" || resp.CPU.Thresholds[3] != 50 || resp.Disk != nil {
+		t.Errorf("startup report = %+v, want cpu at lv1 and no disk curve", resp)
+	}
+}
+
+func TestHistory(t *testing.T) {
+	s, _ := newTestServer(nil, nil)
+
+	if rec := doRequest(s, http.MethodGet, "/api/history/temps", "", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("history without store = %d, want 404", rec.Code)
+	}
+
+	st, err := store.Open(filepath.Join(t.TempDir(), "metrics.db"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+	now := time.Now()
+	if err := st.RecordTemp("cpu", now, 45); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.AddNetwork("eth0", now, 100, 50); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.RecordSMART("wwn-1", now, map[string]int64{"Power_On_Hours": 7}); err != nil {
+		t.Fatal(err)
+	}
+	s.SetStore(st)
+
+	tests := []struct {
+		path     string
+		wantCode int
+		wantBody string
+	}{
+		{"/api/history/temps", http.StatusOK, `"series":["cpu"]`},
+		{"/api/history/temps?series=cpu&hours=2", http.StatusOK, `"max":45`},
+		{"/api/history/temps?series=cpu&hours=-1", http.StatusBadRequest, "hours must be a positive integer"},
+		{"/api/history/network?days=1", http.StatusOK, `"rx_bytes":100`},
+		{"/api/history/smart?disk=wwn-1", http.StatusOK, `"Power_On_Hours":7`},
+		{"/api/history/smart", http.StatusBadRequest, "disk is required"},
+	}
+
+	for _, tt := range tests {
+		rec := doRequest(s, http.MethodGet, tt.path, "", "")
+		if rec.Code != tt.wantCode || !strings.Contains(rec.Body.String(), tt.wantBody) {
+			t.Errorf("%s = %d %s, want %d containing %s", tt.path, rec.Code, rec.Body.String(), tt.wantCode, tt.wantBody)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// Scope is the permission level granted to an API token
+type Scope int
+
+const (
+	// ScopeRead allows polling status and metrics
+	ScopeRead Scope = iota + 1
+	// ScopeControl additionally allows changing fans, the display and power state
+	ScopeControl
+)
+
+func (s Scope) String() string {
+	switch s {
+	case ScopeRead:
+		return "read"
+	case ScopeControl:
+		return "control"
+	default:
+		return "none"
+	}
+}
+
+// tokenStore maps bearer tokens to scopes
+type tokenStore struct {
+	tokens map[string]Scope
+}
+
+func newTokenStore(readTokens, controlTokens []string) *tokenStore {
+	ts := &tokenStore{tokens: make(map[string]Scope)}
+	for _, t := range readTokens {
+		if t = strings.TrimSpace(t); t != "" {
+			ts.tokens[t] = ScopeRead
+		}
+	}
+	for _, t := range controlTokens {
+		if t = strings.TrimSpace(t); t != "" {
+			ts.tokens[t] = ScopeControl
+		}
+	}
+	return ts
+}
+
+// open reports whether no read tokens are configured, in which case reads need no token
+func (ts *tokenStore) open() bool {
+	for _, scope := range ts.tokens {
+		if scope == ScopeRead {
+			return false
+		}
+	}
+	return true
+}
+
+// lookup returns the scope of a token, comparing in constant time
+func (ts *tokenStore) lookup(token string) Scope {
+	var found Scope
+	for t, scope := range ts.tokens {
+		if subtle.ConstantTimeCompare([]byte(t), []byte(token)) == 1 {
+			found = scope
+		}
+	}
+	return found
+}
+
+// clientVerified reports whether the request presented a certificate signed by the client CA
+func clientVerified(r *http.Request) bool {
+	return r.TLS != nil && len(r.TLS.VerifiedChains) > 0
+}
+
+// scopeOf resolves the scope granted to a request. A verified client certificate
+// grants control; with mutual TLS enabled, control is only granted that way.
+func (s *Server) scopeOf(r *http.Request) Scope {
+	if clientVerified(r) {
+		return ScopeControl
+	}
+
+	var scope Scope
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		scope = s.tokens.lookup(strings.TrimSpace(token))
+	}
+	if scope == 0 && s.tokens.open() {
+		scope = ScopeRead
+	}
+	if scope == ScopeControl && s.mtls {
+		scope = ScopeRead
+	}
+	return scope
+}
+
+// require wraps a handler so it only runs for requests holding at least the given scope
+func (s *Server) require(scope Scope, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		got := s.scopeOf(r)
+		switch {
+		case got == 0:
+			w.Header().Set("WWW-Authenticate", `Bearer realm="rockpi-quad"`)
+			writeError(w, http.StatusUnauthorized, "missing or invalid token")
+		case got < scope && scope == ScopeControl && s.mtls:
+			writeError(w, http.StatusForbidden, "control requires an enrolled client certificate")
+		case got < scope:
+			writeError(w, http.StatusForbidden, "token lacks "+scope.String()+" scope")
+		default:
+			next(w, r)
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package api
+
+import (
+	"expvar"
+	"net/http/pprof"
+
+	// Publishes the runtime counters on /debug/vars
+	_ "github.com/kolobock/rockpi-quad-go/internal/diag"
+)
+
+// debugRoutes serves the expvar counters (memstats, I2C write errors, smartctl and
+// shell invocations, loop latencies) to readers and pprof to control clients, as
+// profiles reveal internals and CPU profiles and traces cost time on small boards
+func (s *Server) debugRoutes() {
+	s.mux.HandleFunc("GET /debug/vars", s.require(ScopeRead, expvar.Handler().ServeHTTP))
+	s.mux.HandleFunc("GET /debug/pprof/", s.require(ScopeControl, pprof.Index))
+	s.mux.HandleFunc("GET /debug/pprof/cmdline", s.require(ScopeControl, pprof.Cmdline))
+	s.mux.HandleFunc("GET /debug/pprof/profile", s.require(ScopeControl, pprof.Profile))
+	s.mux.HandleFunc("GET /debug/pprof/symbol", s.require(ScopeControl, pprof.Symbol))
+	s.mux.HandleFunc("POST /debug/pprof/symbol", s.require(ScopeControl, pprof.Symbol))
+	s.mux.HandleFunc("GET /debug/pprof/trace", s.require(ScopeControl, pprof.Trace))
+}
@@ -0,0 +1,52 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kolobock/rockpi-quad-go/internal/config"
+	"github.com/kolobock/rockpi-quad-go/internal/diag"
+)
+
+func TestDebugRoutes(t *testing.T) {
+	cfg := &config.Config{API: config.APIConfig{ReadTokens: []string{"reader"}, ControlTokens: []string{"admin"}, PProf: true}}
+	s := New(cfg, nil)
+	diag.ObserveLoop("fan", 3*time.Millisecond)
+
+	tests := []struct {
+		name  string
+		path  string
+		token string
+		want  int
+	}{
+		{"vars with read token", "/debug/vars", "reader", http.StatusOK},
+		{"vars without token", "/debug/vars", "", http.StatusUnauthorized},
+		{"pprof with read token", "/debug/pprof/", "reader", http.StatusForbidden},
+		{"pprof with control token", "/debug/pprof/", "admin", http.StatusOK},
+		{"heap profile", "/debug/pprof/heap?debug=1", "admin", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := doRequest(s, http.MethodGet, tt.path, tt.token, "")
+			if rec.Code != tt.want {
+				t.Errorf("GET %s = %d, want %d", tt.path, rec.Code, tt.want)
+			}
+		})
+	}
+
+	body := doRequest(s, http.MethodGet, "/debug/vars", "reader", "").Body.String()
+	for _, want := range []string{`"i2c_write_errors"`, `"smartctl_invocations"`, `"shell_execs"`, `"memstats"`, `"fan": {"avg_ms":3`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("/debug/vars lacks %s", want)
+		}
+	}
+}
+
+func TestDebugRoutesDisabled(t *testing.T) {
+	s, _ := newTestServer(nil, []string{"admin"})
+	if rec := doRequest(s, http.MethodGet, "/debug/pprof/", "admin", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("pprof without [api] pprof = %d, want 404", rec.Code)
+	}
+}
@@ -0,0 +1,411 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/kolobock/rockpi-quad-go/internal/disk"
+	"github.com/kolobock/rockpi-quad-go/internal/fan"
+	"github.com/kolobock/rockpi-quad-go/internal/health"
+	"github.com/kolobock/rockpi-quad-go/internal/logger"
+	"github.com/kolobock/rockpi-quad-go/internal/oled"
+	"github.com/kolobock/rockpi-quad-go/internal/thermal"
+	"github.com/kolobock/rockpi-quad-go/internal/version"
+)
+
+type fanStatus struct {
+	Enabled           bool    `json:"enabled"`
+	Profile           string  `json:"profile"`
+	CPUTemp           float64 `json:"cpu_temp"`
+	DiskTemp          float64 `json:"disk_temp"`
+	CPUDuty           float64 `json:"cpu_duty"`
+	DiskDuty          float64 `json:"disk_duty"`
+	OverrideRemaining float64 `json:"override_remaining_seconds"`
+	PWMHealthy        bool    `json:"pwm_healthy"`
+	PWMFailures       int     `json:"pwm_failures"`
+	Emergency         bool    `json:"emergency"`
+	Burst             bool    `json:"burst_sampling"`
+	Observed          bool    `json:"observed"`
+	// DryRun is set while duty cycles are computed but not written; the actual
+	// duty cycles are what the channels are really at
+	DryRun         bool     `json:"dry_run,omitempty"`
+	ActualCPUDuty  *float64 `json:"actual_cpu_duty,omitempty"`
+	ActualDiskDuty *float64 `json:"actual_disk_duty,omitempty"`
+
+	CPUSensorFailures  int `json:"cpu_sensor_failures"`
+	DiskSensorFailures int `json:"disk_sensor_failures"`
+
+	HATs []hatStatus `json:"hats,omitempty"`
+}
+
+type hatStatus struct {
+	ID       string  `json:"id"`
+	Label    string  `json:"label"`
+	DiskTemp float64 `json:"disk_temp"`
+	Duty     float64 `json:"duty"`
+}
+
+type diskPower struct {
+	Device       string  `json:"device"`
+	ID           string  `json:"id"`
+	Mode         string  `json:"mode"`
+	SpinUps      int     `json:"spin_ups"`
+	StandbyHours float64 `json:"standby_hours"`
+	// HAT is the stacked HAT holding the disk, empty with a single HAT
+	HAT string `json:"hat,omitempty"`
+}
+
+// labels identifies the disk in metrics, namespaced by HAT when HATs are stacked
+func (d diskPower) labels() []string {
+	if d.HAT == "" {
+		return []string{"device", d.Device}
+	}
+	return []string{"device", d.Device, "hat", d.HAT}
+}
+
+type driveInventory struct {
+	Device        string `json:"device"`
+	Bay           string `json:"bay,omitempty"`
+	HAT           string `json:"hat,omitempty"`
+	ID            string `json:"id"`
+	Model         string `json:"model"`
+	Serial        string `json:"serial"`
+	CapacityBytes uint64 `json:"capacity_bytes"`
+	Port          string `json:"port,omitempty"`
+	LinkSpeed     string `json:"link_speed,omitempty"`
+}
+
+type checkStatus struct {
+	Name      string  `json:"name"`
+	Kind      string  `json:"kind"`
+	Target    string  `json:"target"`
+	OK        bool    `json:"ok"`
+	LatencyMs float64 `json:"latency_ms"`
+	Failures  int     `json:"failures"`
+}
+
+type pageTiming struct {
+	Index         int     `json:"index"`
+	Name          string  `json:"name"`
+	Renders       int     `json:"renders"`
+	RenderAvgMs   float64 `json:"render_avg_ms"`
+	RenderMaxMs   float64 `json:"render_max_ms"`
+	TransmitAvgMs float64 `json:"transmit_avg_ms"`
+	TransmitMaxMs float64 `json:"transmit_max_ms"`
+	Slow          bool    `json:"slow"`
+}
+
+type displayStatus struct {
+	Available           bool   `json:"available"`
+	I2CWriteErrors      uint64 `json:"i2c_write_errors"`
+	I2CRetries          uint64 `json:"i2c_retries"`
+	I2CReopens          uint64 `json:"i2c_reopens"`
+	ConsecutiveFailures int    `json:"consecutive_failures"`
+}
+
+type statusResponse struct {
+	Version version.Info   `json:"version"`
+	Health  *health.Report `json:"health,omitempty"`
+	Fan     *fanStatus     `json:"fan,omitempty"`
+	Disks   []diskPower    `json:"disks,omitempty"`
+	// Inventory is reported with [disk] inventory
+	Inventory []driveInventory `json:"inventory,omitempty"`
+	Checks    []checkStatus    `json:"checks,omitempty"`
+	Display   *displayStatus   `json:"display,omitempty"`
+	Pages     []pageTiming     `json:"pages,omitempty"`
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// averageMs returns the mean of n durations totalling total, in milliseconds
+func averageMs(total time.Duration, n int) float64 {
+	if n == 0 {
+		return 0
+	}
+	return milliseconds(total) / float64(n)
+}
+
+func newPageTimings(timings []oled.PageTiming) []pageTiming {
+	pages := make([]pageTiming, 0, len(timings))
+	for _, t := range timings {
+		pages = append(pages, pageTiming{
+			Index:         t.Index,
+			Name:          t.Name,
+			Renders:       t.Renders,
+			RenderAvgMs:   averageMs(t.RenderTotal, t.Renders),
+			RenderMaxMs:   milliseconds(t.RenderMax),
+			TransmitAvgMs: averageMs(t.TransmitTotal, t.Transmits),
+			TransmitMaxMs: milliseconds(t.TransmitMax),
+			Slow:          t.Slow,
+		})
+	}
+	return pages
+}
+
+// handleStatus reports the daemon state; ?debug=1 adds the page render timings
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	resp := statusResponse{Version: version.Get(s.cfg), Disks: diskPowerStatus()}
+	if s.cfg.Disk.Inventory {
+		resp.Inventory = newDriveInventory(disk.Inventory())
+	}
+	if s.modules != nil {
+		report := s.modules.Report()
+		resp.Health = &report
+	}
+	if s.fanCtrl != nil {
+		st := s.fanCtrl.Status()
+		resp.Fan = &fanStatus{
+			Enabled:           st.Enabled,
+			Profile:           st.Profile,
+			CPUTemp:           s.cfg.Temp.API.Round(st.CPUTemp),
+			DiskTemp:          s.cfg.Temp.API.Round(st.DiskTemp),
+			CPUDuty:           st.CPUDuty,
+			DiskDuty:          st.DiskDuty,
+			OverrideRemaining: st.OverrideRemaining.Seconds(),
+			PWMHealthy:        st.PWMHealthy,
+			PWMFailures:       st.PWMFailures,
+			Emergency:         st.Emergency,
+			Burst:             st.Burst,
+			Observed:          st.Observed,
+
+			CPUSensorFailures:  st.CPUSensorFailures,
+			DiskSensorFailures: st.DiskSensorFailures,
+		}
+		if st.DryRun {
+			resp.Fan.DryRun = true
+			resp.Fan.ActualCPUDuty, resp.Fan.ActualDiskDuty = &st.ActualCPUDuty, &st.ActualDiskDuty
+		}
+		for _, h := range st.HATs {
+			resp.Fan.HATs = append(resp.Fan.HATs, hatStatus{
+				ID:       h.ID,
+				Label:    h.Label,
+				DiskTemp: s.cfg.Temp.API.Round(h.DiskTemp),
+				Duty:     h.Duty,
+			})
+		}
+	}
+
+	if s.checker != nil {
+		for _, res := range s.checker.Results() {
+			resp.Checks = append(resp.Checks, checkStatus{
+				Name:      res.Name,
+				Kind:      res.Kind,
+				Target:    res.Target,
+				OK:        res.OK,
+				LatencyMs: milliseconds(res.Latency),
+				Failures:  res.Failures,
+			})
+		}
+	}
+
+	if s.bus != nil {
+		st := s.bus.BusStats()
+		resp.Display = &displayStatus{
+			Available:           st.Available,
+			I2CWriteErrors:      st.WriteErrors,
+			I2CRetries:          st.Retries,
+			I2CReopens:          st.Reopens,
+			ConsecutiveFailures: st.ConsecutiveFailures,
+		}
+	}
+	if s.pages != nil && r.URL.Query().Get("debug") != "" {
+		resp.Pages = newPageTimings(s.pages.PageTimings())
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func diskPowerStatus() []diskPower {
+	stats := disk.GetPowerStats()
+	stacked := disk.Stacked()
+	disks := make([]diskPower, 0, len(stats))
+	for dev, ps := range stats {
+		d := diskPower{
+			Device:       dev,
+			ID:           disk.ID(dev),
+			Mode:         string(ps.Mode),
+			SpinUps:      ps.SpinUps,
+			StandbyHours: ps.StandbyTime.Hours(),
+		}
+		if stacked {
+			d.HAT = disk.HAT(dev)
+		}
+		disks = append(disks, d)
+	}
+	sort.Slice(disks, func(i, j int) bool { return disks[i].Device < disks[j].Device })
+	return disks
+}
+
+func newDriveInventory(drives []disk.Drive) []driveInventory {
+	inventory := make([]driveInventory, 0, len(drives))
+	for _, d := range drives {
+		inventory = append(inventory, driveInventory{
+			Device:        d.Device,
+			Bay:           d.Bay,
+			HAT:           d.HAT,
+			ID:            d.ID,
+			Model:         d.Model,
+			Serial:        d.Serial,
+			CapacityBytes: d.Capacity,
+			Port:          d.Port,
+			LinkSpeed:     d.LinkSpeed,
+		})
+	}
+	return inventory
+}
+
+type curveReport struct {
+	Temp       float64    `json:"temp"`
+	Thresholds [4]float64 `json:"thresholds"`
+	Max        float64    `json:"max"`
+	Level      string     `json:"level"`
+	Duty       float64    `json:"duty"`
+}
+
+type startupReport struct {
+	Time    time.Time    `json:"time"`
+	Profile string       `json:"profile"`
+	CPU     curveReport  `json:"cpu"`
+	Disk    *curveReport `json:"disk,omitempty"`
+}
+
+func newCurveReport(r fan.CurveReport, format thermal.Format) curveReport {
+	return curveReport{Temp: format.Round(r.Temp), Thresholds: r.Thresholds, Max: r.Max, Level: r.Level, Duty: r.Duty}
+}
+
+func (s *Server) handleFanStartup(w http.ResponseWriter, _ *http.Request) {
+	r, ok := s.fanCtrl.StartupReport()
+	if !ok {
+		writeError(w, http.StatusServiceUnavailable, "startup report not taken yet")
+		return
+	}
+
+	resp := startupReport{Time: r.Time, Profile: r.Profile, CPU: newCurveReport(r.CPU, s.cfg.Temp.API)}
+	if r.Disk != nil {
+		disk := newCurveReport(*r.Disk, s.cfg.Temp.API)
+		resp.Disk = &disk
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) handleFanToggle(w http.ResponseWriter, _ *http.Request) {
+	s.fanCtrl.ToggleFan()
+	writeJSON(w, http.StatusOK, map[string]bool{"enabled": s.fanCtrl.Status().Enabled})
+}
+
+type overrideRequest struct {
+	Percent float64 `json:"percent"`
+	Minutes int     `json:"minutes"`
+}
+
+func (s *Server) handleFanOverride(w http.ResponseWriter, r *http.Request) {
+	var req overrideRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	if req.Minutes == 0 {
+		req.Minutes = s.cfg.Fan.OverrideMinutes
+	}
+
+	if err := s.fanCtrl.SetOverride(req.Percent, time.Duration(req.Minutes)*time.Minute); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, req)
+}
+
+func (s *Server) handleFanOverrideClear(w http.ResponseWriter, _ *http.Request) {
+	s.fanCtrl.ClearOverride()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleFanProfile(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Profile string `json:"profile"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+
+	if err := s.fanCtrl.SetProfile(req.Profile); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, req)
+}
+
+func (s *Server) handleDisplayMessage(w http.ResponseWriter, r *http.Request) {
+	if s.display == nil {
+		writeError(w, http.StatusNotFound, "display is not enabled")
+		return
+	}
+
+	var req struct {
+		Title string `json:"title"`
+		Text  string `json:"text"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+
+	s.display.ShowMessage(req.Title, req.Text)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleFlags(w http.ResponseWriter, _ *http.Request) {
+	if s.flags == nil {
+		writeError(w, http.StatusNotFound, "flags are not available")
+		return
+	}
+	writeJSON(w, http.StatusOK, s.flags.All())
+}
+
+func (s *Server) handleFlagSet(w http.ResponseWriter, r *http.Request) {
+	if s.flags == nil {
+		writeError(w, http.StatusNotFound, "flags are not available")
+		return
+	}
+
+	var req struct {
+		Value   *bool `json:"value"`
+		Persist bool  `json:"persist"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Value == nil {
+		writeError(w, http.StatusBadRequest, `expected JSON body {"value": true|false}`)
+		return
+	}
+
+	name := r.PathValue("name")
+	if err := s.flags.Set(name, *req.Value); err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	logger.Infof("Flag %s set to %t via API from %s", name, *req.Value, r.RemoteAddr)
+	if req.Persist {
+		if err := s.flags.Persist(name); err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, s.flags.All())
+}
+
+func (s *Server) handlePower(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("action")
+	action, ok := s.power[name]
+	if !ok {
+		writeError(w, http.StatusNotFound, "unknown power action "+name)
+		return
+	}
+
+	logger.Infof("Power action %s requested via API from %s", name, r.RemoteAddr)
+	w.WriteHeader(http.StatusAccepted)
+	action()
+}
@@ -0,0 +1,149 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/kolobock/rockpi-quad-go/internal/store"
+)
+
+type tempAggregate struct {
+	Time  time.Time `json:"time"`
+	Min   float64   `json:"min"`
+	Avg   float64   `json:"avg"`
+	Max   float64   `json:"max"`
+	Count int       `json:"count"`
+}
+
+type netTotal struct {
+	Date      time.Time `json:"date"`
+	Interface string    `json:"interface"`
+	RxBytes   uint64    `json:"rx_bytes"`
+	TxBytes   uint64    `json:"tx_bytes"`
+}
+
+type smartSnapshot struct {
+	Time       time.Time        `json:"time"`
+	Attributes map[string]int64 `json:"attributes"`
+}
+
+// SetStore enables the history endpoints
+func (s *Server) SetStore(st *store.Store) {
+	s.store = st
+}
+
+// handleTempHistory lists the temperature series, or returns the hourly aggregates
+// of ?series= over the last ?hours= (default 24)
+func (s *Server) handleTempHistory(w http.ResponseWriter, r *http.Request) {
+	if !s.requireStore(w) {
+		return
+	}
+	series := r.URL.Query().Get("series")
+	if series == "" {
+		names, err := s.store.Series()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string][]string{"series": names})
+		return
+	}
+
+	hours, err := queryInt(r, "hours", 24)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	now := time.Now()
+	aggs, err := s.store.Temps(series, now.Add(-time.Duration(hours)*time.Hour), now)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	format := s.cfg.Temp.API
+	samples := make([]tempAggregate, 0, len(aggs))
+	for _, a := range aggs {
+		samples = append(samples, tempAggregate{
+			Time: a.Time, Min: format.Round(a.Min), Avg: format.Round(a.Avg()), Max: format.Round(a.Max), Count: a.Count,
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"series": series, "samples": samples})
+}
+
+// handleNetworkHistory returns the daily traffic totals of the last ?days= (default 30)
+func (s *Server) handleNetworkHistory(w http.ResponseWriter, r *http.Request) {
+	if !s.requireStore(w) {
+		return
+	}
+	days, err := queryInt(r, "days", 30)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	now := time.Now()
+	totals, err := s.store.Network(now.AddDate(0, 0, -days), now)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	resp := make([]netTotal, 0, len(totals))
+	for _, t := range totals {
+		resp = append(resp, netTotal{Date: t.Date, Interface: t.Interface, RxBytes: t.RxBytes, TxBytes: t.TxBytes})
+	}
+	writeJSON(w, http.StatusOK, map[string][]netTotal{"days": resp})
+}
+
+// handleSMARTHistory returns the daily SMART snapshots of ?disk= (a disk ID) over
+// the last ?days= (default 30)
+func (s *Server) handleSMARTHistory(w http.ResponseWriter, r *http.Request) {
+	if !s.requireStore(w) {
+		return
+	}
+	id := r.URL.Query().Get("disk")
+	if id == "" {
+		writeError(w, http.StatusBadRequest, "disk is required")
+		return
+	}
+	days, err := queryInt(r, "days", 30)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	now := time.Now()
+	snaps, err := s.store.SMART(id, now.AddDate(0, 0, -days), now)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	resp := make([]smartSnapshot, 0, len(snaps))
+	for _, snap := range snaps {
+		resp = append(resp, smartSnapshot{Time: snap.Time, Attributes: snap.Attributes})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"disk": id, "snapshots": resp})
+}
+
+func (s *Server) requireStore(w http.ResponseWriter) bool {
+	if s.store == nil {
+		writeError(w, http.StatusNotFound, "metrics store is not enabled")
+		return false
+	}
+	return true
+}
+
+// queryInt reads a positive integer query parameter
+func queryInt(r *http.Request, name string, fallback int) (int, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("%s must be a positive integer", name)
+	}
+	return n, nil
+}
@@ -0,0 +1,181 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/kolobock/rockpi-quad-go/internal/fan"
+	"github.com/kolobock/rockpi-quad-go/internal/oled"
+	"github.com/kolobock/rockpi-quad-go/internal/rails"
+	"github.com/kolobock/rockpi-quad-go/internal/thermal"
+)
+
+// metricsWriter renders the Prometheus text exposition format
+type metricsWriter struct {
+	w io.Writer
+}
+
+func (m metricsWriter) header(name, help, kind string) {
+	fmt.Fprintf(m.w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+func (m metricsWriter) value(name string, v float64, labels ...string) {
+	fmt.Fprint(m.w, name)
+	if len(labels) > 0 {
+		fmt.Fprint(m.w, "{")
+		for i := 0; i+1 < len(labels); i += 2 {
+			if i > 0 {
+				fmt.Fprint(m.w, ",")
+			}
+			fmt.Fprintf(m.w, "%s=%q", labels[i], labels[i+1])
+		}
+		fmt.Fprint(m.w, "}")
+	}
+	fmt.Fprintf(m.w, " %g\n", v)
+}
+
+func (m metricsWriter) gauge(name, help string, v float64, labels ...string) {
+	m.header(name, help, "gauge")
+	m.value(name, v, labels...)
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func (s *Server) handleMetrics(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	m := metricsWriter{w: w}
+
+	if s.fanCtrl != nil {
+		writeFanMetrics(m, s.fanCtrl.Status())
+	} else if temp, err := thermal.Read(s.cfg.Fan.CPUSensors, s.cfg.Fan.CPUSensorMode); err == nil {
+		// Without the fan module there is no controller reading temperatures for us
+		m.gauge("rockpi_temperature_celsius", "Temperature used for fan control", temp, "sensor", "cpu")
+	}
+
+	if disks := diskPowerStatus(); len(disks) > 0 {
+		m.header("rockpi_disk_spinups_total", "Disk spin-ups since start", "counter")
+		for _, d := range disks {
+			m.value("rockpi_disk_spinups_total", float64(d.SpinUps), d.labels()...)
+		}
+		m.header("rockpi_disk_standby_seconds_total", "Time disks spent in standby since start", "counter")
+		for _, d := range disks {
+			m.value("rockpi_disk_standby_seconds_total", d.StandbyHours*3600, d.labels()...)
+		}
+	}
+
+	if s.rails != nil {
+		writeRailMetrics(m, s.rails.States())
+	}
+
+	if s.pages != nil {
+		writePageMetrics(m, s.pages.PageTimings())
+	}
+	if s.bus != nil {
+		st := s.bus.BusStats()
+		m.gauge("rockpi_oled_available", "Whether the OLED panel accepts writes", boolValue(st.Available))
+		m.header("rockpi_oled_i2c_write_errors_total", "Failed I2C writes to the OLED panel, retries included", "counter")
+		m.value("rockpi_oled_i2c_write_errors_total", float64(st.WriteErrors))
+		m.header("rockpi_oled_i2c_reopens_total", "Times the OLED I2C bus was reopened", "counter")
+		m.value("rockpi_oled_i2c_reopens_total", float64(st.Reopens))
+	}
+
+	if s.checker != nil {
+		results := s.checker.Results()
+		m.header("rockpi_check_up", "Whether the connectivity check last succeeded", "gauge")
+		for _, res := range results {
+			m.value("rockpi_check_up", boolValue(res.OK), "check", res.Name)
+		}
+		m.header("rockpi_check_latency_seconds", "Latency of the last successful connectivity check", "gauge")
+		for _, res := range results {
+			m.value("rockpi_check_latency_seconds", float64(res.Latency)/float64(time.Second), "check", res.Name)
+		}
+	}
+}
+
+func writeRailMetrics(m metricsWriter, states []rails.State) {
+	m.header("rockpi_rail_good", "Whether the power rail is within its limits", "gauge")
+	for _, st := range states {
+		m.value("rockpi_rail_good", boolValue(st.Good), "rail", st.Name)
+	}
+	m.header("rockpi_rail_dropouts_total", "Brown-outs and over-current events since start", "counter")
+	for _, st := range states {
+		m.value("rockpi_rail_dropouts_total", float64(st.Dropouts), "rail", st.Name)
+	}
+	m.header("rockpi_rail_volts", "Last voltage reading of the power rail", "gauge")
+	for _, st := range states {
+		if st.Volts > 0 {
+			m.value("rockpi_rail_volts", st.Volts, "rail", st.Name)
+		}
+	}
+	m.header("rockpi_rail_amps", "Last current reading of the power rail", "gauge")
+	for _, st := range states {
+		if st.HasCurrent {
+			m.value("rockpi_rail_amps", st.Amps, "rail", st.Name)
+		}
+	}
+}
+
+func writePageMetrics(m metricsWriter, timings []oled.PageTiming) {
+	m.header("rockpi_oled_renders_total", "Times the OLED page was rendered", "counter")
+	for _, t := range timings {
+		m.value("rockpi_oled_renders_total", float64(t.Renders), "page", t.Name, "index", strconv.Itoa(t.Index))
+	}
+	m.header("rockpi_oled_render_seconds_total", "Time spent building the OLED page text", "counter")
+	for _, t := range timings {
+		m.value("rockpi_oled_render_seconds_total", t.RenderTotal.Seconds(), "page", t.Name, "index", strconv.Itoa(t.Index))
+	}
+	m.header("rockpi_oled_render_max_seconds", "Longest render of the OLED page", "gauge")
+	for _, t := range timings {
+		m.value("rockpi_oled_render_max_seconds", t.RenderMax.Seconds(), "page", t.Name, "index", strconv.Itoa(t.Index))
+	}
+	m.header("rockpi_oled_transmit_seconds_total", "Time spent sending the OLED page to the panel", "counter")
+	for _, t := range timings {
+		m.value("rockpi_oled_transmit_seconds_total", t.TransmitTotal.Seconds(), "page", t.Name, "index", strconv.Itoa(t.Index))
+	}
+	m.header("rockpi_oled_slow", "Whether the OLED page is consistently over the render budget", "gauge")
+	for _, t := range timings {
+		m.value("rockpi_oled_slow", boolValue(t.Slow), "page", t.Name, "index", strconv.Itoa(t.Index))
+	}
+}
+
+func writeFanMetrics(m metricsWriter, st fan.Status) {
+	m.header("rockpi_temperature_celsius", "Temperature used for fan control", "gauge")
+	m.value("rockpi_temperature_celsius", st.CPUTemp, "sensor", "cpu")
+	m.value("rockpi_temperature_celsius", st.DiskTemp, "sensor", "disk")
+	m.header("rockpi_fan_duty_percent", "Current fan duty cycle", "gauge")
+	m.value("rockpi_fan_duty_percent", st.CPUDuty, "fan", "cpu")
+	m.value("rockpi_fan_duty_percent", st.DiskDuty, "fan", "disk")
+	m.gauge("rockpi_fan_enabled", "Whether temperature-based fan control is enabled", boolValue(st.Enabled))
+	m.gauge("rockpi_fan_emergency", "Whether thermal emergency mode is active", boolValue(st.Emergency))
+	m.gauge("rockpi_fan_burst_sampling", "Whether temperatures are sampled at the burst rate", boolValue(st.Burst))
+	m.gauge("rockpi_fan_override_seconds", "Remaining manual fan override time", st.OverrideRemaining.Seconds())
+	m.gauge("rockpi_pwm_failures", "Consecutive failed PWM writes", float64(st.PWMFailures))
+	m.header("rockpi_sensor_failures", "Consecutive failed temperature reads", "gauge")
+	m.value("rockpi_sensor_failures", float64(st.CPUSensorFailures), "sensor", "cpu")
+	m.value("rockpi_sensor_failures", float64(st.DiskSensorFailures), "sensor", "disk")
+	if st.DryRun {
+		m.header("rockpi_fan_actual_duty_percent", "Duty cycle the fan channel is at while fan control only computes its own", "gauge")
+		m.value("rockpi_fan_actual_duty_percent", st.ActualCPUDuty, "fan", "cpu")
+		m.value("rockpi_fan_actual_duty_percent", st.ActualDiskDuty, "fan", "disk")
+	}
+
+	if len(st.HATs) == 0 {
+		return
+	}
+	m.header("rockpi_hat_disk_temperature_celsius", "Disk temperature of each stacked HAT", "gauge")
+	for _, h := range st.HATs {
+		m.value("rockpi_hat_disk_temperature_celsius", h.DiskTemp, "hat", h.ID)
+	}
+	m.header("rockpi_hat_fan_duty_percent", "Top-board fan duty cycle of each stacked HAT", "gauge")
+	for _, h := range st.HATs {
+		m.value("rockpi_hat_fan_duty_percent", h.Duty, "hat", h.ID)
+	}
+}
@@ -0,0 +1,42 @@
+package api
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+
+	"github.com/kolobock/rockpi-quad-go/internal/config"
+)
+
+// loadTLSConfig builds the server TLS settings, returning nil for plain HTTP.
+// With a client CA, certificates signed by it are verified when presented.
+func loadTLSConfig(cfg config.APIConfig) (*tls.Config, error) {
+	if cfg.TLSCert == "" && cfg.TLSKey == "" {
+		if cfg.ClientCA != "" {
+			return nil, fmt.Errorf("client_ca requires tls_cert and tls_key")
+		}
+		return nil, nil
+	}
+	if cfg.TLSCert == "" || cfg.TLSKey == "" {
+		return nil, fmt.Errorf("both tls_cert and tls_key must be set")
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if cfg.ClientCA == "" {
+		return tlsConfig, nil
+	}
+
+	pem, err := os.ReadFile(cfg.ClientCA)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in client CA %s", cfg.ClientCA)
+	}
+	tlsConfig.ClientCAs = pool
+	// Reads stay available to token holders, control is gated on the certificate in require
+	tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	return tlsConfig, nil
+}
@@ -0,0 +1,56 @@
+package api
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kolobock/rockpi-quad-go/internal/config"
+)
+
+func TestLoadTLSConfigValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.APIConfig
+		wantNil bool
+		wantErr bool
+	}{
+		{"plain http", config.APIConfig{}, true, false},
+		{"ca without cert", config.APIConfig{ClientCA: "/etc/ca.pem"}, true, true},
+		{"cert without key", config.APIConfig{TLSCert: "/etc/cert.pem"}, true, true},
+		{"missing ca file", config.APIConfig{TLSCert: "c", TLSKey: "k", ClientCA: "/nonexistent/ca.pem"}, true, true},
+		{"tls only", config.APIConfig{TLSCert: "c", TLSKey: "k"}, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := loadTLSConfig(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("loadTLSConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if (got == nil) != tt.wantNil {
+				t.Errorf("loadTLSConfig() = %v, wantNil %v", got, tt.wantNil)
+			}
+		})
+	}
+}
+
+func TestMTLSControl(t *testing.T) {
+	s, _ := newTestServer(nil, []string{"admin"})
+	s.mtls = true
+
+	// A control token alone is no longer enough
+	if rec := doRequest(s, http.MethodPost, "/api/fan/toggle", "admin", ""); rec.Code != http.StatusForbidden {
+		t.Errorf("toggle with token only = %d, want 403", rec.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/fan/toggle", nil)
+	req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{}}}}
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("toggle with client certificate = %d, want 200", rec.Code)
+	}
+}
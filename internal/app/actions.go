@@ -0,0 +1,299 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kolobock/rockpi-quad-go/internal/alert"
+	"github.com/kolobock/rockpi-quad-go/internal/button"
+	"github.com/kolobock/rockpi-quad-go/internal/config"
+	"github.com/kolobock/rockpi-quad-go/internal/disk"
+	"github.com/kolobock/rockpi-quad-go/internal/flags"
+	"github.com/kolobock/rockpi-quad-go/internal/logger"
+	"github.com/kolobock/rockpi-quad-go/internal/luks"
+	"github.com/kolobock/rockpi-quad-go/internal/shutdown"
+)
+
+// luksTimeout bounds opening the LUKS volumes; key derivation takes seconds per volume
+const luksTimeout = 2 * time.Minute
+
+// openLUKS opens the LUKS volumes; replaced in tests
+var openLUKS = luks.OpenAll
+
+// alertFlushTimeout bounds waiting for the shutdown alert to be sent before the
+// daemon stops
+const alertFlushTimeout = 5 * time.Second
+
+// runPowerCommand hands over to the system's poweroff or reboot; replaced in tests
+var runPowerCommand = func(action string) error {
+	// #nosec G204 - action is either poweroff or reboot
+	return exec.Command(action).Run()
+}
+
+const (
+	actionNone      = "none"
+	actionLock      = "lock"
+	actionLUKS      = "luks"
+	actionFanPrefix = "fan:"
+)
+
+// buttonEvent is a gesture tagged with the button it came from
+type buttonEvent struct {
+	button config.ButtonConfig
+	event  button.EventType
+}
+
+// forwardButtonEvents tags the gestures of one button and merges them into events
+func forwardButtonEvents(ctx context.Context, bc config.ButtonConfig, btn Button, events chan<- buttonEvent) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-btn.PressChan():
+			if !ok {
+				// Channel closed, exit
+				return
+			}
+			select {
+			case events <- buttonEvent{button: bc, event: event}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+func (a *App) handleButtonEvents(ctx context.Context, events <-chan buttonEvent, buttonChan chan struct{}, cancel context.CancelFunc) {
+	time.Sleep(500 * time.Millisecond)
+
+	lock := newPanelLock(a.cfg.Key.Unlock)
+	confirm := newConfirmation(time.Duration(a.cfg.Time.Confirm * float64(time.Second)))
+	defer confirm.stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-confirm.expired():
+			logger.Infof("Confirmation of %s timed out", confirm.action)
+			a.showMessage("Cancelled", powerActions[confirm.action])
+			confirm.stop()
+		case evt := <-events:
+			// The first press on a sleeping display only wakes it
+			if display := a.currentDisplay(); display != nil && display.NotifyBtnPress() {
+				logger.Infof("Button %s event %s woke the display", evt.button.ID, evt.event)
+				continue
+			}
+			if lock.isLocked() {
+				if lock.feed(evt.event, time.Now()) {
+					logger.Infoln("Front panel unlocked")
+				} else {
+					logger.Infof("Front panel locked, ignoring button %s event: %s", evt.button.ID, evt.event)
+				}
+				continue
+			}
+
+			if action, handled := confirm.resolve(evt); handled {
+				if action == "" {
+					logger.Infof("Button %s event %s cancelled the pending confirmation", evt.button.ID, evt.event)
+					a.showMessage("Cancelled", "")
+					continue
+				}
+				a.runAction(evt, action, buttonChan, cancel)
+				continue
+			}
+
+			action := getButtonAction(evt.button.Keys, evt.event)
+			logger.Infof("Button %s event: %s (action: %s)", evt.button.ID, evt.event, action)
+			switch {
+			case action == actionLock:
+				logger.Infoln("Front panel locked")
+				lock.lock()
+			case a.needsConfirmation(action):
+				confirm.request(evt, action)
+				a.showMessage(confirmPrompt(evt.event, action))
+			default:
+				a.runAction(evt, action, buttonChan, cancel)
+			}
+		}
+	}
+}
+
+// needsConfirmation reports whether a button action must be repeated before it runs;
+// without a display there is no way to ask, so actions run directly
+func (a *App) needsConfirmation(action string) bool {
+	_, power := powerActions[action]
+	return power && a.cfg.Key.ConfirmPower && a.currentDisplay() != nil
+}
+
+func (a *App) showMessage(title, text string) {
+	if display := a.currentDisplay(); display != nil {
+		display.ShowMessage(title, text)
+	}
+}
+
+// notify shows text in a banner over the current page for [oled] notify_time
+func (a *App) notify(text string) {
+	if display := a.currentDisplay(); display != nil {
+		display.Notify(text, time.Duration(a.cfg.OLED.NotifyTime)*time.Second)
+	}
+}
+
+// runAction performs a built-in button action or runs it as a script or shell command
+func (a *App) runAction(evt buttonEvent, action string, buttonChan chan struct{}, cancel context.CancelFunc) {
+	switch action {
+	case "slider":
+		select {
+		case buttonChan <- struct{}{}:
+		default:
+		}
+	case "switch":
+		if a.fan != nil {
+			a.fan.ToggleFan()
+			if a.fan.Status().Enabled {
+				a.notify("Fan control on")
+			} else {
+				a.notify("Fans full speed")
+			}
+		}
+	case "poweroff", "reboot":
+		a.executePower(action, cancel)
+	case actionLUKS:
+		a.openLUKSVolumes()
+	case actionNone:
+	default:
+		if strings.HasPrefix(action, actionFanPrefix) {
+			a.executeFanOverride(action)
+		} else {
+			a.executeCommand(evt, action)
+		}
+	}
+}
+
+// executePower runs the shutdown sequence with progress on the OLED, then stops the
+// daemon and hands over to the system's poweroff or reboot command
+func (a *App) executePower(action string, cancel context.CancelFunc) {
+	if !a.shuttingDown.CompareAndSwap(false, true) {
+		logger.Infof("Ignoring %s request, shutdown already in progress", action)
+		return
+	}
+	logger.Infof("%s requested", action)
+	if a.alerts != nil {
+		a.alerts.Publish(alert.Event{Rule: alert.RuleShutdown, Subject: action, Message: action + " requested"})
+	}
+
+	go func() {
+		title := "Shutting down"
+		if action == "reboot" {
+			title = "Rebooting"
+		}
+		// Keep the progress messages on screen
+		if err := a.flags.Set(flags.AutoSlide, false); err != nil {
+			logger.Errorf("Failed to stop auto-slide: %v", err)
+		}
+		steps := shutdown.Steps(a.cfg.Shutdown, action, disk.GetSATADisks())
+		shutdown.Run(context.Background(), title, steps, time.Duration(a.cfg.Shutdown.StepTimeout)*time.Second, a.showMessage)
+
+		if display := a.currentDisplay(); action == "poweroff" && a.cfg.Shutdown.FinalMessage != "" && display != nil {
+			display.SetFinalMessage(a.cfg.Shutdown.FinalMessage)
+		}
+		// Stopping the daemon aborts alert sends in flight, so let the shutdown alert out first
+		if a.alerts != nil && !a.alerts.Flush(context.Background(), alertFlushTimeout) {
+			logger.Errorf("Alerts not sent within %v of %s", alertFlushTimeout, action)
+		}
+		cancel()
+		time.Sleep(1 * time.Second)
+		if err := runPowerCommand(action); err != nil {
+			logger.Errorf("Failed to execute %s: %v", action, err)
+		}
+	}()
+}
+
+// parseFanAction parses "fan:<percent>[:<minutes>]" button actions
+func parseFanAction(action string, defaultMinutes int) (percent float64, minutes int, err error) {
+	parts := strings.Split(strings.TrimPrefix(action, actionFanPrefix), ":")
+	if len(parts) > 2 {
+		return 0, 0, fmt.Errorf("invalid fan action %q", action)
+	}
+
+	percent, err = strconv.ParseFloat(parts[0], 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid fan duty cycle in %q: %w", action, err)
+	}
+
+	minutes = defaultMinutes
+	if len(parts) == 2 {
+		if minutes, err = strconv.Atoi(parts[1]); err != nil {
+			return 0, 0, fmt.Errorf("invalid fan override minutes in %q: %w", action, err)
+		}
+	}
+
+	return percent, minutes, nil
+}
+
+func (a *App) executeFanOverride(action string) {
+	if a.fan == nil {
+		logger.Errorf("Fan module disabled, ignoring action %s", action)
+		return
+	}
+	percent, minutes, err := parseFanAction(action, a.cfg.Fan.OverrideMinutes)
+	if err != nil {
+		logger.Errorf("Failed to parse fan action: %v", err)
+		return
+	}
+	if err := a.fan.SetOverride(percent, time.Duration(minutes)*time.Minute); err != nil {
+		logger.Errorf("Failed to set fan override: %v", err)
+		return
+	}
+	a.notify(fmt.Sprintf("Fans %.0f%% %dmin", percent, minutes))
+}
+
+// openLUKSVolumes opens the locked [luks] volumes with the keyfile in the background,
+// reporting the outcome in a banner. Presses while the volumes are being opened are
+// ignored; shutdown cancels the unlock and waits for it.
+func (a *App) openLUKSVolumes() {
+	volumes := a.cfg.LUKS.Volumes
+	if len(volumes) == 0 {
+		logger.Errorf("No [luks] volumes configured, ignoring action %s", actionLUKS)
+		return
+	}
+	if !a.unlocking.CompareAndSwap(false, true) {
+		logger.Infof("Ignoring %s action, volumes are already being opened", actionLUKS)
+		return
+	}
+	a.notify("LUKS unlocking…")
+	a.goRun(func() {
+		defer a.unlocking.Store(false)
+		ctx, cancel := context.WithTimeout(a.ctx, luksTimeout)
+		defer cancel()
+		opened, err := openLUKS(ctx, volumes, a.cfg.LUKS.Keyfile)
+		if err != nil {
+			logger.Errorf("Failed to open LUKS volumes: %v", err)
+			a.notify(fmt.Sprintf("LUKS failed, %d opened", opened))
+			return
+		}
+		logger.Infof("Opened %d LUKS volumes", opened)
+		a.notify(fmt.Sprintf("LUKS %d opened", opened))
+	})
+}
+
+func getButtonAction(keys config.KeyConfig, event button.EventType) string {
+	switch event {
+	case button.Click:
+		return keys.Click
+	case button.DoubleClick:
+		return keys.Twice
+	case button.TripleClick:
+		return keys.Triple
+	case button.LongPress:
+		return keys.Press
+	case button.Hold:
+		return keys.Hold
+	default:
+		return actionNone
+	}
+}
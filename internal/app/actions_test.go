@@ -0,0 +1,308 @@
+package app
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/kolobock/rockpi-quad-go/internal/alert"
+	"github.com/kolobock/rockpi-quad-go/internal/button"
+	"github.com/kolobock/rockpi-quad-go/internal/config"
+	"github.com/kolobock/rockpi-quad-go/internal/fan"
+)
+
+// Note: This test file can run without hardware dependencies
+// since it only tests the getButtonAction function which has no hardware deps
+
+func TestGetButtonAction(t *testing.T) {
+	tests := []struct {
+		name  string
+		cfg   *config.Config
+		event button.EventType
+		want  string
+	}{
+		{
+			name: "click event returns click action",
+			cfg: &config.Config{
+				Key: config.KeyConfig{
+					Click: "slider",
+					Twice: "switch",
+					Press: "poweroff",
+				},
+			},
+			event: button.Click,
+			want:  "slider",
+		},
+		{
+			name: "double click event returns twice action",
+			cfg: &config.Config{
+				Key: config.KeyConfig{
+					Click: "slider",
+					Twice: "switch",
+					Press: "poweroff",
+				},
+			},
+			event: button.DoubleClick,
+			want:  "switch",
+		},
+		{
+			name: "long press event returns press action",
+			cfg: &config.Config{
+				Key: config.KeyConfig{
+					Click: "slider",
+					Twice: "switch",
+					Press: "poweroff",
+				},
+			},
+			event: button.LongPress,
+			want:  "poweroff",
+		},
+		{
+			name: "unknown event returns none",
+			cfg: &config.Config{
+				Key: config.KeyConfig{
+					Click: "slider",
+					Twice: "switch",
+					Press: "poweroff",
+				},
+			},
+			event: button.EventType("unknown"),
+			want:  "none",
+		},
+		{
+			name: "custom command for click",
+			cfg: &config.Config{
+				Key: config.KeyConfig{
+					Click: "echo 'custom action'",
+					Twice: "reboot",
+					Press: "poweroff",
+				},
+			},
+			event: button.Click,
+			want:  "echo 'custom action'",
+		},
+		{
+			name: "reboot action",
+			cfg: &config.Config{
+				Key: config.KeyConfig{
+					Click: "slider",
+					Twice: "reboot",
+					Press: "poweroff",
+				},
+			},
+			event: button.DoubleClick,
+			want:  "reboot",
+		},
+		{
+			name: "empty action returns empty string",
+			cfg: &config.Config{
+				Key: config.KeyConfig{
+					Click: "",
+					Twice: "",
+					Press: "",
+				},
+			},
+			event: button.Click,
+			want:  "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := getButtonAction(tt.cfg.Key, tt.event)
+			if got != tt.want {
+				t.Errorf("getButtonAction() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetButtonAction_AllEventTypes(t *testing.T) {
+	cfg := &config.Config{
+		Key: config.KeyConfig{
+			Click:  "action_click",
+			Twice:  "action_twice",
+			Triple: "action_triple",
+			Press:  "action_press",
+			Hold:   "action_hold",
+		},
+	}
+
+	eventTests := []struct {
+		event button.EventType
+		want  string
+	}{
+		{button.Click, "action_click"},
+		{button.DoubleClick, "action_twice"},
+		{button.TripleClick, "action_triple"},
+		{button.LongPress, "action_press"},
+		{button.Hold, "action_hold"},
+	}
+
+	for _, tt := range eventTests {
+		got := getButtonAction(cfg.Key, tt.event)
+		if got != tt.want {
+			t.Errorf("getButtonAction(cfg.Key, %v) = %q, want %q", tt.event, got, tt.want)
+		}
+	}
+}
+
+func TestGetButtonAction_BuiltinActions(t *testing.T) {
+	builtinActions := []string{"slider", "switch", "poweroff", "reboot", "none"}
+
+	for _, action := range builtinActions {
+		cfg := &config.Config{
+			Key: config.KeyConfig{
+				Click: action,
+				Twice: action,
+				Press: action,
+			},
+		}
+
+		t.Run("action_"+action, func(t *testing.T) {
+			got := getButtonAction(cfg.Key, button.Click)
+			if got != action {
+				t.Errorf("getButtonAction() with action %q = %q, want %q", action, got, action)
+			}
+		})
+	}
+}
+
+func TestParseFanAction(t *testing.T) {
+	tests := []struct {
+		action      string
+		wantPercent float64
+		wantMinutes int
+		wantErr     bool
+	}{
+		{"fan:60", 60, 30, false},
+		{"fan:100:5", 100, 5, false},
+		{"fan:abc", 0, 0, true},
+		{"fan:50:x", 0, 0, true},
+		{"fan:50:5:1", 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.action, func(t *testing.T) {
+			percent, minutes, err := parseFanAction(tt.action, 30)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseFanAction(%q) error = %v, wantErr %v", tt.action, err, tt.wantErr)
+			}
+			if percent != tt.wantPercent || minutes != tt.wantMinutes {
+				t.Errorf("parseFanAction(%q) = %v, %v; want %v, %v", tt.action, percent, minutes, tt.wantPercent, tt.wantMinutes)
+			}
+		})
+	}
+}
+
+func TestRunActionNotifies(t *testing.T) {
+	tests := []struct {
+		action string
+		status fan.Status
+		want   []string
+	}{
+		{"switch", fan.Status{Enabled: true}, []string{"Fan control on"}},
+		{"switch", fan.Status{}, []string{"Fans full speed"}},
+		{"fan:80:15", fan.Status{}, []string{"Fans 80% 15min"}},
+		{"slider", fan.Status{}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.action, func(t *testing.T) {
+			display := &fakeDisplay{}
+			a := &App{cfg: &config.Config{}, display: display, fan: &fakeFan{status: tt.status}}
+
+			a.runAction(buttonEvent{event: button.Click}, tt.action, make(chan struct{}, 1), func() {})
+
+			if !slices.Equal(display.notes, tt.want) {
+				t.Errorf("notifications = %q, want %q", display.notes, tt.want)
+			}
+		})
+	}
+}
+
+// slowSink takes a while to send, like an SMTP server, and fails when its context
+// is cancelled first
+type slowSink struct {
+	sent chan error
+}
+
+func (s *slowSink) Name() string { return "slow" }
+
+func (s *slowSink) Send(ctx context.Context, _ alert.Event) error {
+	select {
+	case <-time.After(200 * time.Millisecond):
+		s.sent <- nil
+		return nil
+	case <-ctx.Done():
+		s.sent <- ctx.Err()
+		return ctx.Err()
+	}
+}
+
+func TestExecutePowerSendsAlert(t *testing.T) {
+	ran := make(chan string, 1)
+	realRun := runPowerCommand
+	runPowerCommand = func(action string) error {
+		ran <- action
+		return nil
+	}
+	defer func() { runPowerCommand = realRun }()
+
+	sink := &slowSink{sent: make(chan error, 1)}
+	a := New(testConfig(config.ModulesConfig{}), newFakeFactories().factories())
+	a.alerts = alert.NewBus([]alert.Sink{sink}, 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go a.alerts.Run(ctx)
+
+	a.executePower("reboot", cancel)
+
+	select {
+	case action := <-ran:
+		if action != "reboot" {
+			t.Errorf("ran %q, want reboot", action)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("reboot was not run")
+	}
+	// The daemon is stopped only after the shutdown alert went out
+	if err := <-sink.sent; err != nil {
+		t.Errorf("shutdown alert send = %v, want it delivered before the daemon stopped", err)
+	}
+}
+
+func TestOpenLUKSVolumesOnce(t *testing.T) {
+	calls := make(chan struct{}, 2)
+	realOpen := openLUKS
+	openLUKS = func(ctx context.Context, _ []config.LUKSVolume, _ string) (int, error) {
+		calls <- struct{}{}
+		<-ctx.Done()
+		return 0, ctx.Err()
+	}
+	defer func() { openLUKS = realOpen }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	display := &fakeDisplay{}
+	a := &App{
+		cfg:     &config.Config{LUKS: config.LUKSConfig{Volumes: []config.LUKSVolume{{Name: "data", Device: "/dev/sda1"}}}},
+		display: display,
+		ctx:     ctx,
+	}
+
+	a.openLUKSVolumes()
+	<-calls
+	// A second press while the first unlock runs starts no other
+	a.openLUKSVolumes()
+
+	// Shutdown cancels the unlock and waits for it
+	cancel()
+	a.wg.Wait()
+	if len(calls) != 0 {
+		t.Errorf("started %d more unlocks while one was running", len(calls))
+	}
+	if a.unlocking.Load() {
+		t.Error("unlock still marked as running after it finished")
+	}
+}
@@ -0,0 +1,147 @@
+package app
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/kolobock/rockpi-quad-go/internal/alert"
+	"github.com/kolobock/rockpi-quad-go/internal/config"
+	"github.com/kolobock/rockpi-quad-go/internal/disk"
+	"github.com/kolobock/rockpi-quad-go/internal/flags"
+	"github.com/kolobock/rockpi-quad-go/internal/health"
+	"github.com/kolobock/rockpi-quad-go/internal/logger"
+	"github.com/kolobock/rockpi-quad-go/internal/thermal"
+)
+
+// newAlertBus returns the bus delivering to the [alerts] sinks, nil when none is
+// configured; [raid] webhook keeps receiving the RAID alerts
+func newAlertBus(cfg *config.Config) *alert.Bus {
+	var sinks []alert.Sink
+	for _, url := range cfg.Alerts.Webhooks {
+		sinks = append(sinks, alert.Webhook(url))
+	}
+	if cfg.Alerts.TelegramToken != "" && cfg.Alerts.TelegramChat != "" {
+		sinks = append(sinks, alert.Telegram(cfg.Alerts.TelegramToken, cfg.Alerts.TelegramChat))
+	}
+	if cfg.Alerts.Command != "" {
+		sinks = append(sinks, alert.Command(cfg.Alerts.Command))
+	}
+	if cfg.SMTP.Host != "" {
+		if sink := newEmailSink(cfg.SMTP); sink != nil {
+			sinks = append(sinks, sink)
+		}
+	}
+	if cfg.RAID.Webhook != "" {
+		sinks = append(sinks, alert.Only(alert.Webhook(cfg.RAID.Webhook), alert.RuleRAID))
+	}
+	if len(sinks) == 0 {
+		return nil
+	}
+	return alert.NewBus(sinks, time.Duration(cfg.Alerts.Cooldown)*time.Second)
+}
+
+// newEmailSink returns the [smtp] sink limited to its events, nil when it lacks a
+// sender or recipient or its templates are invalid
+func newEmailSink(cfg config.SMTPConfig) alert.Sink {
+	sink, err := alert.Email(alert.EmailConfig{
+		Host:     cfg.Host,
+		Port:     cfg.Port,
+		Username: cfg.Username,
+		Password: cfg.Password,
+		From:     cfg.From,
+		To:       cfg.To,
+		TLS:      cfg.TLS,
+		Subject:  cfg.Subject,
+		Body:     cfg.Body,
+	})
+	if err != nil {
+		logger.Errorf("Email alerts unavailable: %v", err)
+		return nil
+	}
+	if slices.Contains(cfg.Events, "all") {
+		return sink
+	}
+	return alert.Only(sink, cfg.Events...)
+}
+
+// startAlertBus starts delivering alerts and checking the [alerts] rules
+func (a *App) startAlertBus(ctx context.Context) {
+	bus := a.alerts
+	a.goRun(func() { bus.Run(ctx) })
+
+	watcher := alert.NewWatcher(alertRules(a.cfg.Alerts), a.alertSources(), bus)
+	a.goRun(func() { watcher.Run(ctx, time.Duration(a.cfg.Alerts.Interval)*time.Second) })
+}
+
+func alertRules(cfg config.AlertsConfig) alert.Rules {
+	rules := alert.Rules{
+		CPUTemp:     cfg.CPUTemp,
+		CPUTempFor:  time.Duration(cfg.CPUTempSeconds) * time.Second,
+		DiskTemp:    cfg.DiskTemp,
+		DiskTempFor: time.Duration(cfg.DiskTempSeconds) * time.Second,
+		FanStallFor: time.Duration(cfg.FanStallSeconds) * time.Second,
+		DiskRemoved: cfg.DiskRemoved,
+	}
+	tachs := map[string]string{"cpu": cfg.CPUFanTach, "disk": cfg.DiskFanTach}
+	for fan, path := range tachs {
+		if path == "" {
+			continue
+		}
+		if rules.FanTachs == nil {
+			rules.FanTachs = make(map[string]string)
+		}
+		rules.FanTachs[fan] = path
+	}
+	return rules
+}
+
+// alertSources returns the readings the alert rules check; disk temperatures need
+// smartctl and the fan rules the fan module
+func (a *App) alertSources() alert.Sources {
+	src := alert.Sources{
+		CPUTemp: func() (float64, bool) {
+			temp, err := thermal.Read(a.cfg.Fan.CPUSensors, a.cfg.Fan.CPUSensorMode)
+			return temp, err == nil
+		},
+		Disks: func() map[string]string {
+			disks := make(map[string]string)
+			// The cached list may still hold a disk pulled moments ago
+			for _, dev := range disk.ListSATADisks() {
+				disks[disk.ID(dev)] = disk.Label(dev, a.cfg.Disk.Aliases)
+			}
+			return disks
+		},
+	}
+	if a.fan != nil {
+		src.FanDuty = func() map[string]float64 {
+			st := a.fan.Status()
+			return map[string]float64{"cpu": st.CPUDuty, "disk": st.DiskDuty}
+		}
+		src.Emergency = func() bool { return a.fan.Status().Emergency }
+	}
+	if a.modules.State(health.SMART) == health.StateOK {
+		src.DiskTemps = func() map[string]float64 {
+			temps := make(map[string]float64)
+			for _, dev := range disk.GetSATADisks() {
+				if temp, err := disk.GetTemperature(dev); err == nil {
+					temps[disk.Label(dev, a.cfg.Disk.Aliases)] = temp
+				}
+			}
+			return temps
+		}
+	}
+	return src
+}
+
+// raise logs an alert, shows banner on the OLED unless alerts are muted and publishes
+// it to the alert bus
+func (a *App) raise(evt alert.Event, banner string) {
+	logger.Errorf("%s", evt.Message)
+	if !a.flags.Enabled(flags.MuteAlerts) {
+		a.notify(banner)
+	}
+	if a.alerts != nil {
+		a.alerts.Publish(evt)
+	}
+}
@@ -0,0 +1,72 @@
+package app
+
+import (
+	"maps"
+	"testing"
+	"time"
+
+	"github.com/kolobock/rockpi-quad-go/internal/config"
+)
+
+func TestNewAlertBus(t *testing.T) {
+	tests := []struct {
+		name    string
+		alerts  config.AlertsConfig
+		webhook string
+		want    bool
+	}{
+		{"no sinks", config.AlertsConfig{}, "", false},
+		{"telegram without chat", config.AlertsConfig{TelegramToken: "123:abc"}, "", false},
+		{"webhook", config.AlertsConfig{Webhooks: []string{"https://hooks.example"}}, "", true},
+		{"command", config.AlertsConfig{Command: "logger alert"}, "", true},
+		{"raid webhook", config.AlertsConfig{}, "https://hooks.example/raid", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{Alerts: tt.alerts, RAID: config.RAIDConfig{Webhook: tt.webhook}}
+			if got := newAlertBus(cfg) != nil; got != tt.want {
+				t.Errorf("newAlertBus() created a bus = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewEmailSink(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.SMTPConfig
+		want bool
+	}{
+		{"complete", config.SMTPConfig{Host: "smtp.example.com", From: "a@b", To: []string{"c@d"}, Events: []string{"all"}}, true},
+		{"no recipient", config.SMTPConfig{Host: "smtp.example.com", From: "a@b"}, false},
+		{"bad template", config.SMTPConfig{Host: "smtp.example.com", From: "a@b", To: []string{"c@d"}, Subject: "{{"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := newEmailSink(tt.cfg) != nil; got != tt.want {
+				t.Errorf("newEmailSink() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAlertRules(t *testing.T) {
+	rules := alertRules(config.AlertsConfig{
+		CPUTemp:         80,
+		CPUTempSeconds:  60,
+		DiskFanTach:     "/sys/class/hwmon/hwmon3/fan2_input",
+		FanStallSeconds: 30,
+		DiskRemoved:     true,
+	})
+
+	if rules.CPUTemp != 80 || rules.CPUTempFor != time.Minute || rules.FanStallFor != 30*time.Second || !rules.DiskRemoved {
+		t.Errorf("alertRules() = %+v", rules)
+	}
+	want := map[string]string{"disk": "/sys/class/hwmon/hwmon3/fan2_input"}
+	if !maps.Equal(rules.FanTachs, want) {
+		t.Errorf("FanTachs = %v, want %v", rules.FanTachs, want)
+	}
+	if rules := alertRules(config.AlertsConfig{}); rules.FanTachs != nil {
+		t.Errorf("FanTachs without tachometers = %v, want nil", rules.FanTachs)
+	}
+}
@@ -0,0 +1,466 @@
+// Package app wires the daemon's subsystems together and owns their lifecycle.
+package app
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kolobock/rockpi-quad-go/internal/alert"
+	"github.com/kolobock/rockpi-quad-go/internal/api"
+	"github.com/kolobock/rockpi-quad-go/internal/button"
+	"github.com/kolobock/rockpi-quad-go/internal/config"
+	"github.com/kolobock/rockpi-quad-go/internal/disk"
+	"github.com/kolobock/rockpi-quad-go/internal/fan"
+	"github.com/kolobock/rockpi-quad-go/internal/flags"
+	"github.com/kolobock/rockpi-quad-go/internal/health"
+	"github.com/kolobock/rockpi-quad-go/internal/kmsg"
+	"github.com/kolobock/rockpi-quad-go/internal/logger"
+	"github.com/kolobock/rockpi-quad-go/internal/network"
+	"github.com/kolobock/rockpi-quad-go/internal/oled"
+	"github.com/kolobock/rockpi-quad-go/internal/raid"
+	"github.com/kolobock/rockpi-quad-go/internal/rails"
+	"github.com/kolobock/rockpi-quad-go/internal/store"
+	"github.com/kolobock/rockpi-quad-go/internal/ups"
+)
+
+const fanStatusInterval = 10 * time.Second
+
+// Fan is the fan control subsystem
+type Fan interface {
+	oled.FanController
+	api.FanController
+	Run(ctx context.Context) error
+	Close() error
+}
+
+// Display is the front panel display subsystem
+type Display interface {
+	Run(ctx context.Context, buttonChan <-chan struct{}) error
+	Close() error
+	SetKernelWatcher(w *kmsg.Watcher)
+	SetHealthChecker(checker *network.Checker)
+	SetRAIDMonitor(m *raid.Monitor)
+	SetUPSMonitor(m *ups.Monitor)
+	SetRailMonitor(m *rails.Monitor)
+	SetStore(s *store.Store)
+	SetFlags(f oled.Flags)
+	ShowMessage(title, text string)
+	SetFinalMessage(text string)
+	Notify(text string, d time.Duration)
+	NotifyBtnPress() bool
+	CurrentPage() (int, string)
+	Pages() []string
+	PageTimings() []oled.PageTiming
+	BusStats() oled.BusStats
+}
+
+// Button reports front panel button gestures
+type Button interface {
+	Run(ctx context.Context)
+	Close() error
+	PressChan() <-chan button.EventType
+}
+
+// Factories construct the hardware-backed subsystems
+type Factories struct {
+	NewFan     func(cfg *config.Config) (Fan, error)
+	NewDisplay func(cfg *config.Config, fanCtrl oled.FanController) (Display, error)
+	NewButton  func(bc config.ButtonConfig, timing config.TimeConfig) (Button, error)
+	// SMARTAvailable reports whether disk SMART data can be read
+	SMARTAvailable func() bool
+}
+
+// DefaultFactories returns factories for the real hardware drivers
+func DefaultFactories() Factories {
+	return Factories{
+		NewFan: func(cfg *config.Config) (Fan, error) {
+			c, err := fan.New(cfg)
+			if err != nil {
+				return nil, err
+			}
+			return c, nil
+		},
+		NewDisplay: func(cfg *config.Config, fanCtrl oled.FanController) (Display, error) {
+			c, err := oled.New(cfg, fanCtrl)
+			if err != nil {
+				return nil, err
+			}
+			return c, nil
+		},
+		NewButton: func(bc config.ButtonConfig, timing config.TimeConfig) (Button, error) {
+			c, err := button.New(bc, timing)
+			if err != nil {
+				return nil, err
+			}
+			return c, nil
+		},
+		SMARTAvailable: disk.SMARTAvailable,
+	}
+}
+
+// App holds the running subsystems; any of them may be nil when its module is
+// disabled or failed to start, which the module tracker records
+type App struct {
+	cfg       *config.Config
+	factories Factories
+	// wg runs the services, which stop with the daemon context
+	wg      sync.WaitGroup
+	modules *health.Tracker
+	flags   *flags.Set
+	// buttonStage, displayStage and fanStage run the panel and fan goroutines,
+	// which Wait stops in this order
+	buttonStage  *stage
+	displayStage *stage
+	fanStage     *stage
+
+	fan Fan
+	// panelMu guards display and buttons, which may attach after startup
+	panelMu       sync.RWMutex
+	display       Display
+	buttons       []Button
+	kernelWatcher *kmsg.Watcher
+	checker       *network.Checker
+	raid          *raid.Monitor
+	ups           *ups.Monitor
+	rails         *rails.Monitor
+	store         *store.Store
+	alerts        *alert.Bus
+
+	shuttingDown atomic.Bool
+	// unlocking is set while the luks action opens the volumes
+	unlocking atomic.Bool
+	// ctx is the daemon context given to Start, for background work of button actions
+	ctx context.Context
+}
+
+// New creates an application for cfg; nothing is started until Start
+func New(cfg *config.Config, factories Factories) *App {
+	a := &App{
+		cfg:          cfg,
+		factories:    factories,
+		modules:      health.NewTracker(),
+		flags:        flags.New(cfg),
+		buttonStage:  newStage("buttons"),
+		displayStage: newStage("display"),
+		fanStage:     newStage("fan"),
+	}
+	a.flags.OnChange(flags.Verbose, logger.SetVerbose)
+	return a
+}
+
+// Modules returns the tracker reporting which modules are working
+func (a *App) Modules() *health.Tracker {
+	return a.modules
+}
+
+// Start brings up the enabled modules. Fan control starts first so cooling never
+// waits on slower peripherals, followed by the monitors, then the display and button
+// which consume them, and finally the API and heartbeat which report on everything.
+// The fan PWM and OLED I2C devices are retried with backoff for [modules] retry_seconds;
+// a module that still fails to start is reported as failed and the rest keep running.
+// cancel is invoked by poweroff and reboot actions to begin shutdown.
+func (a *App) Start(ctx context.Context, cancel context.CancelFunc) {
+	a.ctx = ctx
+	mods := a.cfg.Modules
+	for _, name := range []string{health.Fan, health.OLED, health.Button, health.SMART} {
+		a.modules.Set(name, health.StateDisabled, "")
+	}
+
+	disk.SetBays(a.cfg.Disk.Bays)
+	if mods.Fan {
+		a.startFan(ctx)
+	}
+	a.checkSMART()
+
+	if mods.DiskMonitor {
+		a.startDiskMonitors(ctx)
+	}
+	if mods.Alerts {
+		a.startAlertMonitors(ctx)
+	}
+	if len(a.cfg.Network.Checks) > 0 {
+		a.startHealthChecks(ctx)
+	}
+	if a.cfg.Store.Path != "" {
+		a.startStore(ctx)
+	}
+	if a.cfg.Status.Enabled {
+		a.startStatusFile(ctx)
+	}
+	if a.cfg.SNMP.Enabled {
+		a.startSNMP(ctx)
+	}
+	a.raid = newRAIDMonitor(a.cfg.RAID)
+	a.ups = newUPSMonitor(a.cfg.UPS, a.cfg.Env.I2CBus)
+	a.rails = newRailMonitor(a.cfg)
+
+	if mods.OLED || mods.Button {
+		a.startDisplayAndButton(cancel)
+	}
+	a.startNotifyingMonitors(ctx, cancel)
+	if mods.API {
+		a.startAPI(ctx, cancel)
+	}
+	if mods.Alerts && a.cfg.Heartbeat.URL != "" {
+		a.startHeartbeat(ctx)
+	}
+}
+
+func (a *App) retryWindow() time.Duration {
+	return time.Duration(a.cfg.Modules.RetrySeconds) * time.Second
+}
+
+// fanSource returns the fan as an interface that stays nil when the module is off
+func (a *App) fanSource() oled.FanController {
+	if a.fan == nil {
+		return nil
+	}
+	return a.fan
+}
+
+func (a *App) startFan(ctx context.Context) {
+	fanCtrl, err := retry(ctx, "Fan PWM", a.retryWindow(), func() (Fan, error) {
+		return a.factories.NewFan(a.cfg)
+	})
+	if err != nil {
+		a.modules.Set(health.Fan, health.StateFailed, fmt.Sprintf("failed to create fan controller: %v", err))
+		return
+	}
+	a.fan = fanCtrl
+	a.modules.Set(health.Fan, health.StateOK, "")
+
+	a.supervise(a.fanStage, health.Fan, func(ctx context.Context) error {
+		a.modules.Set(health.Fan, health.StateOK, "")
+		if err := fanCtrl.Run(ctx); err != nil {
+			return fmt.Errorf("fan controller error: %w", err)
+		}
+		return nil
+	})
+	a.fanStage.goRun(func() { a.watchFan(a.fanStage.ctx, fanStatusInterval) })
+}
+
+// watchFan marks the fan degraded while PWM writes or temperature sensors fail;
+// the fan keeps running on its safe fallback duty cycle
+func (a *App) watchFan(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if a.modules.State(health.Fan) == health.StateFailed {
+				return
+			}
+			st := a.fan.Status()
+			switch {
+			case !st.PWMHealthy:
+				a.modules.Set(health.Fan, health.StateDegraded, fmt.Sprintf("PWM writes failing (%d)", st.PWMFailures))
+			case st.SensorFallback:
+				a.modules.Set(health.Fan, health.StateDegraded, "temperature sensors failing, running at safe duty cycle")
+			default:
+				a.modules.Set(health.Fan, health.StateOK, "")
+			}
+		}
+	}
+}
+
+// checkSMART reports disk features as failed when smartctl is missing
+func (a *App) checkSMART() {
+	if !a.cfg.Modules.DiskMonitor && !a.cfg.Fan.TempDisks && !a.cfg.Disk.DisksTemperature {
+		return
+	}
+	if a.factories.SMARTAvailable() {
+		a.modules.Set(health.SMART, health.StateOK, "")
+	} else {
+		a.modules.Set(health.SMART, health.StateFailed, "smartctl not found, disk temperatures unavailable")
+	}
+}
+
+// startDisplayAndButton starts the buttons and the display in their shutdown stages;
+// cancel is passed on to the poweroff and reboot actions
+func (a *App) startDisplayAndButton(cancel context.CancelFunc) {
+	events := make(chan buttonEvent, 10)
+	buttonCtx := a.buttonStage.ctx
+	if a.cfg.Modules.Button {
+		a.startButtons(buttonCtx, events)
+	}
+
+	buttonChan := make(chan struct{}, 10)
+	if a.cfg.Modules.Button && len(a.cfg.Buttons) > 0 {
+		a.buttonStage.goRun(func() { a.handleButtonEvents(buttonCtx, events, buttonChan, cancel) })
+	}
+	if a.cfg.Modules.OLED {
+		a.startDisplay(a.displayStage.ctx, buttonChan)
+	}
+}
+
+// startDisplay creates the OLED controller; when it is not ready within the startup
+// window it keeps being retried in the background and attached once it appears
+func (a *App) startDisplay(ctx context.Context, buttonChan <-chan struct{}) {
+	display, err := retry(ctx, "OLED", a.retryWindow(), a.newDisplay)
+	if err != nil {
+		a.modules.Set(health.OLED, health.StateFailed, fmt.Sprintf("failed to create OLED controller: %v; retrying in the background", err))
+		a.displayStage.goRun(func() { a.reattachDisplay(ctx, buttonChan) })
+		return
+	}
+	a.attachDisplay(display, buttonChan)
+}
+
+func (a *App) newDisplay() (Display, error) {
+	return a.factories.NewDisplay(a.cfg, a.fanSource())
+}
+
+// reattachDisplay retries the display until it appears or ctx ends
+func (a *App) reattachDisplay(ctx context.Context, buttonChan <-chan struct{}) {
+	display, err := retryUntil(ctx, "OLED", a.newDisplay)
+	if err != nil {
+		return
+	}
+	logger.Infoln("OLED attached")
+	a.attachDisplay(display, buttonChan)
+}
+
+// attachDisplay hands the display its optional sources and runs it under the
+// supervisor; a display that crashed or fell off the bus is closed and created anew
+func (a *App) attachDisplay(display Display, buttonChan <-chan struct{}) {
+	a.supervise(a.displayStage, health.OLED, func(ctx context.Context) error {
+		if display == nil {
+			d, err := a.newDisplay()
+			if err != nil {
+				return fmt.Errorf("failed to create OLED controller: %w", err)
+			}
+			display = d
+			logger.Infoln("OLED attached")
+		}
+		a.setDisplaySources(display)
+
+		a.panelMu.Lock()
+		a.display = display
+		a.panelMu.Unlock()
+		a.modules.Set(health.OLED, health.StateOK, "")
+
+		defer func() {
+			display.Close()
+			if ctx.Err() == nil {
+				a.panelMu.Lock()
+				a.display = nil
+				a.panelMu.Unlock()
+				display = nil
+			}
+		}()
+		if err := display.Run(ctx, buttonChan); err != nil {
+			return fmt.Errorf("OLED controller error: %w", err)
+		}
+		return nil
+	})
+}
+
+// setDisplaySources hands the display the monitors its pages show
+func (a *App) setDisplaySources(display Display) {
+	if a.kernelWatcher != nil {
+		display.SetKernelWatcher(a.kernelWatcher)
+	}
+	if a.checker != nil {
+		display.SetHealthChecker(a.checker)
+	}
+	if a.raid != nil {
+		display.SetRAIDMonitor(a.raid)
+	}
+	if a.ups != nil {
+		display.SetUPSMonitor(a.ups)
+	}
+	if a.rails != nil {
+		display.SetRailMonitor(a.rails)
+	}
+	if a.store != nil {
+		display.SetStore(a.store)
+	}
+	display.SetFlags(a.flags)
+}
+
+// currentDisplay returns the display, nil while it is disabled or not attached yet
+func (a *App) currentDisplay() Display {
+	a.panelMu.RLock()
+	defer a.panelMu.RUnlock()
+	return a.display
+}
+
+// startButtons starts every configured button, merging their tagged gestures into
+// events; a button line that cannot be requested is retried in the background, a
+// button without a line is left disabled
+func (a *App) startButtons(ctx context.Context, events chan<- buttonEvent) {
+	for _, bc := range a.cfg.Buttons {
+		name := buttonModule(bc.ID)
+		if bc.Line == "" {
+			logger.Infof("Button %s monitoring disabled - no pin configured", bc.ID)
+			a.modules.Set(name, health.StateDisabled, "no GPIO line configured")
+			continue
+		}
+		create := func() (Button, error) {
+			return a.factories.NewButton(bc, a.cfg.Time)
+		}
+		btn, err := create()
+		if err == nil {
+			a.runButton(bc, btn, events)
+			continue
+		}
+
+		a.modules.Set(name, health.StateFailed, fmt.Sprintf("failed to create button controller: %v; retrying in the background", err))
+		a.buttonStage.goRun(func() {
+			btn, err := retryUntil(ctx, "Button "+bc.ID, create)
+			if err != nil {
+				return
+			}
+			logger.Infof("Button %s attached", bc.ID)
+			a.runButton(bc, btn, events)
+		})
+	}
+}
+
+// runButton runs a button under the supervisor, forwarding its gestures to events;
+// a button that crashed has its line released and requested again
+func (a *App) runButton(bc config.ButtonConfig, btn Button, events chan<- buttonEvent) {
+	a.panelMu.Lock()
+	index := len(a.buttons)
+	a.buttons = append(a.buttons, btn)
+	a.panelMu.Unlock()
+	a.modules.Set(buttonModule(bc.ID), health.StateOK, "")
+
+	a.supervise(a.buttonStage, buttonModule(bc.ID), func(ctx context.Context) error {
+		if btn == nil {
+			b, err := a.factories.NewButton(bc, a.cfg.Time)
+			if err != nil {
+				return fmt.Errorf("failed to create button controller: %w", err)
+			}
+			btn = b
+			a.panelMu.Lock()
+			a.buttons[index] = btn
+			a.panelMu.Unlock()
+		}
+		a.modules.Set(buttonModule(bc.ID), health.StateOK, "")
+
+		running := btn
+		forward, stop := context.WithCancel(ctx)
+		defer func() {
+			stop()
+			running.Close()
+			btn = nil
+		}()
+		a.buttonStage.goRun(func() { forwardButtonEvents(forward, bc, running, events) })
+		running.Run(ctx)
+		return nil
+	})
+}
+
+// buttonModule names the health module of a button; extra buttons are "button.<id>"
+func buttonModule(id string) string {
+	if id == config.MainButton {
+		return health.Button
+	}
+	return health.Button + "." + id
+}
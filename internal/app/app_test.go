@@ -0,0 +1,545 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kolobock/rockpi-quad-go/internal/button"
+	"github.com/kolobock/rockpi-quad-go/internal/config"
+	"github.com/kolobock/rockpi-quad-go/internal/fan"
+	"github.com/kolobock/rockpi-quad-go/internal/health"
+	"github.com/kolobock/rockpi-quad-go/internal/kmsg"
+	"github.com/kolobock/rockpi-quad-go/internal/network"
+	"github.com/kolobock/rockpi-quad-go/internal/oled"
+	"github.com/kolobock/rockpi-quad-go/internal/raid"
+	"github.com/kolobock/rockpi-quad-go/internal/rails"
+	"github.com/kolobock/rockpi-quad-go/internal/store"
+	"github.com/kolobock/rockpi-quad-go/internal/ups"
+)
+
+type fakeFan struct {
+	mu      sync.Mutex
+	closed  bool
+	toggled int
+	status  fan.Status
+}
+
+func (f *fakeFan) Run(ctx context.Context) error    { <-ctx.Done(); return nil }
+func (f *fakeFan) GetFanSpeeds() (float64, float64) { return 0, 0 }
+func (f *fakeFan) EmergencyActive() bool            { return false }
+func (f *fakeFan) ClearOverride()                   {}
+func (f *fakeFan) SetProfile(string) error          { return nil }
+
+func (f *fakeFan) SetOverride(float64, time.Duration) error { return nil }
+
+func (f *fakeFan) StartupReport() (fan.ThermalReport, bool) { return fan.ThermalReport{}, false }
+
+func (f *fakeFan) Status() fan.Status {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.status
+}
+
+func (f *fakeFan) ToggleFan() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.toggled++
+}
+
+func (f *fakeFan) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+	return nil
+}
+
+type fakeDisplay struct {
+	fanCtrl oled.FanController
+	pages   chan struct{}
+	notes   []string
+}
+
+func (d *fakeDisplay) Run(ctx context.Context, buttonChan <-chan struct{}) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-buttonChan:
+			d.pages <- struct{}{}
+		}
+	}
+}
+
+func (d *fakeDisplay) Close() error                      { return nil }
+func (d *fakeDisplay) SetKernelWatcher(*kmsg.Watcher)    {}
+func (d *fakeDisplay) SetHealthChecker(*network.Checker) {}
+func (d *fakeDisplay) SetRAIDMonitor(*raid.Monitor)      {}
+func (d *fakeDisplay) SetUPSMonitor(*ups.Monitor)        {}
+func (d *fakeDisplay) SetRailMonitor(*rails.Monitor)     {}
+func (d *fakeDisplay) SetStore(*store.Store)             {}
+func (d *fakeDisplay) ShowMessage(string, string)        {}
+func (d *fakeDisplay) SetFinalMessage(string)            {}
+func (d *fakeDisplay) NotifyBtnPress() bool              { return false }
+func (d *fakeDisplay) SetFlags(oled.Flags)               {}
+func (d *fakeDisplay) CurrentPage() (int, string)        { return 1, "SystemInfo1" }
+func (d *fakeDisplay) Pages() []string                   { return []string{"SystemInfo0", "SystemInfo1"} }
+func (d *fakeDisplay) PageTimings() []oled.PageTiming    { return nil }
+func (d *fakeDisplay) BusStats() oled.BusStats           { return oled.BusStats{Available: true} }
+
+func (d *fakeDisplay) Notify(text string, _ time.Duration) { d.notes = append(d.notes, text) }
+
+type fakeButton struct {
+	events chan button.EventType
+}
+
+func (b *fakeButton) Run(ctx context.Context)            { <-ctx.Done() }
+func (b *fakeButton) Close() error                       { return nil }
+func (b *fakeButton) PressChan() <-chan button.EventType { return b.events }
+
+// fakeFactories records what was built; a nil error field means success
+type fakeFactories struct {
+	fan        *fakeFan
+	display    *fakeDisplay
+	button     *fakeButton
+	displayErr error
+}
+
+func newFakeFactories() *fakeFactories {
+	return &fakeFactories{
+		fan:     &fakeFan{status: fan.Status{PWMHealthy: true}},
+		display: &fakeDisplay{pages: make(chan struct{}, 1)},
+		button:  &fakeButton{events: make(chan button.EventType, 1)},
+	}
+}
+
+func (f *fakeFactories) factories() Factories {
+	return Factories{
+		NewFan: func(*config.Config) (Fan, error) { return f.fan, nil },
+		NewDisplay: func(_ *config.Config, fanCtrl oled.FanController) (Display, error) {
+			if f.displayErr != nil {
+				return nil, f.displayErr
+			}
+			f.display.fanCtrl = fanCtrl
+			return f.display, nil
+		},
+		NewButton: func(config.ButtonConfig, config.TimeConfig) (Button, error) {
+			return f.button, nil
+		},
+		SMARTAvailable: func() bool { return false },
+	}
+}
+
+func testConfig(mods config.ModulesConfig) *config.Config {
+	keys := config.KeyConfig{Click: "slider", Twice: "switch"}
+	return &config.Config{
+		Modules: mods,
+		Key:     keys,
+		Buttons: []config.ButtonConfig{{ID: config.MainButton, Line: "17", Keys: keys}},
+	}
+}
+
+func TestStartModules(t *testing.T) {
+	tests := []struct {
+		name        string
+		mods        config.ModulesConfig
+		displayErr  error
+		wantFan     bool
+		wantDisplay bool
+		wantButton  bool
+	}{
+		{"all", config.ModulesConfig{Fan: true, OLED: true, Button: true}, nil, true, true, true},
+		{"fan only", config.ModulesConfig{Fan: true}, nil, true, false, false},
+		{"no fan", config.ModulesConfig{OLED: true, Button: true}, nil, false, true, true},
+		{"display fails", config.ModulesConfig{Fan: true, OLED: true, Button: true}, errors.New("no i2c"), true, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ff := newFakeFactories()
+			ff.displayErr = tt.displayErr
+			a := New(testConfig(tt.mods), ff.factories())
+
+			ctx, cancel := context.WithCancel(context.Background())
+			a.Start(ctx, cancel)
+			cancel()
+			a.Wait()
+
+			if (a.fan != nil) != tt.wantFan {
+				t.Errorf("fan started = %v, want %v", a.fan != nil, tt.wantFan)
+			}
+			if (a.display != nil) != tt.wantDisplay {
+				t.Errorf("display started = %v, want %v", a.display != nil, tt.wantDisplay)
+			}
+			if (len(a.buttons) > 0) != tt.wantButton {
+				t.Errorf("button started = %v, want %v", len(a.buttons) > 0, tt.wantButton)
+			}
+			if tt.wantDisplay && !tt.wantFan && ff.display.fanCtrl != nil {
+				t.Error("display got a non-nil fan source with the fan module disabled")
+			}
+			if tt.wantFan && !ff.fan.closed {
+				t.Error("fan was not closed on shutdown")
+			}
+		})
+	}
+}
+
+func TestStartWithoutButtonLine(t *testing.T) {
+	ff := newFakeFactories()
+	cfg := testConfig(config.ModulesConfig{Fan: true, OLED: true, Button: true})
+	cfg.Buttons[0].Line = ""
+	a := New(cfg, ff.factories())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	a.Start(ctx, cancel)
+	defer func() {
+		cancel()
+		a.Wait()
+	}()
+
+	// A board without a button is healthy, not degraded
+	if got := a.Modules().State(health.Button); got != health.StateDisabled {
+		t.Errorf("button = %v, want disabled", got)
+	}
+	if report := a.Modules().Report(); report.Mode != health.ModeNormal {
+		t.Errorf("mode = %v, want normal: %+v", report.Mode, report.Modules)
+	}
+}
+
+func TestStartDegraded(t *testing.T) {
+	ff := newFakeFactories()
+	ff.displayErr = errors.New("no i2c")
+	factories := ff.factories()
+	factories.NewFan = func(*config.Config) (Fan, error) { return nil, errors.New("no pwm") }
+	cfg := testConfig(config.ModulesConfig{Fan: true, OLED: true, Button: true, DiskMonitor: true})
+	a := New(cfg, factories)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	a.Start(ctx, cancel)
+	defer func() {
+		cancel()
+		a.Wait()
+	}()
+
+	want := map[string]health.State{
+		health.Fan:    health.StateFailed,
+		health.OLED:   health.StateFailed,
+		health.Button: health.StateOK,
+		health.SMART:  health.StateFailed,
+	}
+	for name, state := range want {
+		if got := a.Modules().State(name); got != state {
+			t.Errorf("module %s = %v, want %v", name, got, state)
+		}
+	}
+	if got := a.Modules().Report().Mode; got != health.ModeDegraded {
+		t.Errorf("mode = %v, want degraded", got)
+	}
+	if ok, _ := daemonHealth(a.fan, a.Modules())(); ok {
+		t.Error("heartbeat healthy without fan control")
+	}
+}
+
+func TestStartLateDevices(t *testing.T) {
+	retryMaxDelay, retryBackgroundMaxDelay = time.Millisecond, 2*time.Millisecond
+	defer func() { retryMaxDelay, retryBackgroundMaxDelay = 10*time.Second, time.Minute }()
+
+	ff := newFakeFactories()
+	factories := ff.factories()
+	displays, buttons := 0, 0
+	factories.NewDisplay = func(*config.Config, oled.FanController) (Display, error) {
+		if displays++; displays <= 3 {
+			return nil, errors.New("no i2c")
+		}
+		return ff.display, nil
+	}
+	factories.NewButton = func(config.ButtonConfig, config.TimeConfig) (Button, error) {
+		if buttons++; buttons <= 2 {
+			return nil, errors.New("line busy")
+		}
+		return ff.button, nil
+	}
+	a := New(testConfig(config.ModulesConfig{OLED: true, Button: true}), factories)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	a.Start(ctx, cancel)
+	defer func() {
+		cancel()
+		a.Wait()
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for a.Modules().State(health.OLED) != health.StateOK || a.Modules().State(health.Button) != health.StateOK {
+		if time.Now().After(deadline) {
+			t.Fatalf("oled = %v, button = %v, want both attached", a.Modules().State(health.OLED), a.Modules().State(health.Button))
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if a.currentDisplay() == nil {
+		t.Error("display not attached")
+	}
+}
+
+// lostDisplay falls off the bus as soon as it runs
+type lostDisplay struct {
+	*fakeDisplay
+}
+
+func (lostDisplay) Run(context.Context, <-chan struct{}) error {
+	return fmt.Errorf("write: %w", oled.ErrDisplayUnavailable)
+}
+
+func TestDisplayLostIsRetried(t *testing.T) {
+	restartDelay = time.Millisecond
+	defer func() { restartDelay = time.Second }()
+
+	ff := newFakeFactories()
+	factories := ff.factories()
+	created := 0
+	factories.NewDisplay = func(*config.Config, oled.FanController) (Display, error) {
+		if created++; created == 1 {
+			return lostDisplay{ff.display}, nil
+		}
+		return ff.display, nil
+	}
+	a := New(testConfig(config.ModulesConfig{OLED: true}), factories)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	a.Start(ctx, cancel)
+	defer func() {
+		cancel()
+		a.Wait()
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for a.currentDisplay() != Display(ff.display) || a.Modules().State(health.OLED) != health.StateOK {
+		if time.Now().After(deadline) {
+			t.Fatalf("oled = %v, want the lost display replaced", a.Modules().State(health.OLED))
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestWatchFan(t *testing.T) {
+	ff := newFakeFactories()
+	ff.fan.status = fan.Status{PWMHealthy: false, PWMFailures: 3}
+	a := New(testConfig(config.ModulesConfig{Fan: true}), ff.factories())
+	a.fan = ff.fan
+	a.Modules().Set(health.Fan, health.StateOK, "")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		a.watchFan(ctx, 5*time.Millisecond)
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for a.Modules().State(health.Fan) != health.StateDegraded {
+		if time.Now().After(deadline) {
+			t.Fatal("fan not reported degraded while PWM writes fail")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	<-done
+}
+
+func TestButtonEventsReachModules(t *testing.T) {
+	ff := newFakeFactories()
+	a := New(testConfig(config.ModulesConfig{Fan: true, OLED: true, Button: true}), ff.factories())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a.Start(ctx, cancel)
+
+	ff.button.events <- button.Click
+	select {
+	case <-ff.display.pages:
+	case <-time.After(2 * time.Second):
+		t.Fatal("click did not advance the display page")
+	}
+
+	ff.button.events <- button.DoubleClick
+	deadline := time.Now().Add(time.Second)
+	for {
+		ff.fan.mu.Lock()
+		toggled := ff.fan.toggled
+		ff.fan.mu.Unlock()
+		if toggled == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("double click did not toggle the fan")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+	a.Wait()
+}
+
+func TestMultipleButtons(t *testing.T) {
+	mainBtn := &fakeButton{events: make(chan button.EventType, 1)}
+	eject := &fakeButton{events: make(chan button.EventType, 1)}
+	ff := newFakeFactories()
+	factories := ff.factories()
+	factories.NewButton = func(bc config.ButtonConfig, _ config.TimeConfig) (Button, error) {
+		switch bc.ID {
+		case config.MainButton:
+			return mainBtn, nil
+		case "eject":
+			return eject, nil
+		default:
+			return nil, errors.New("no line")
+		}
+	}
+
+	cfg := testConfig(config.ModulesConfig{Fan: true, OLED: true, Button: true})
+	cfg.Buttons = append(cfg.Buttons,
+		config.ButtonConfig{ID: "eject", Line: "5", Keys: config.KeyConfig{Click: "switch"}},
+		config.ButtonConfig{ID: "broken", Line: "6"},
+		config.ButtonConfig{ID: "unwired"})
+	a := New(cfg, factories)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a.Start(ctx, cancel)
+
+	if len(a.buttons) != 2 {
+		t.Fatalf("started %d buttons, want 2", len(a.buttons))
+	}
+	if got := a.Modules().State("button.broken"); got != health.StateFailed {
+		t.Errorf("broken button state = %v, want failed", got)
+	}
+	// A button without a line is not retried
+	if got := a.Modules().State("button.unwired"); got != health.StateDisabled {
+		t.Errorf("unwired button state = %v, want disabled", got)
+	}
+
+	// The same gesture runs each button's own action
+	eject.events <- button.Click
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		ff.fan.mu.Lock()
+		toggled := ff.fan.toggled
+		ff.fan.mu.Unlock()
+		if toggled == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("eject button click did not run its own action")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	mainBtn.events <- button.Click
+	select {
+	case <-ff.display.pages:
+	case <-time.After(2 * time.Second):
+		t.Fatal("main button click did not advance the display page")
+	}
+
+	cancel()
+	a.Wait()
+}
+
+// teardown records the order the modules stop in
+type teardown struct {
+	mu    sync.Mutex
+	order []string
+}
+
+func (l *teardown) add(name string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.order = append(l.order, name)
+}
+
+type orderedFan struct {
+	*fakeFan
+	log *teardown
+}
+
+func (f orderedFan) Run(ctx context.Context) error {
+	<-ctx.Done()
+	f.log.add("fan")
+	return nil
+}
+
+func (f orderedFan) Close() error {
+	f.log.add("pwm")
+	return f.fakeFan.Close()
+}
+
+type orderedDisplay struct {
+	*fakeDisplay
+	log *teardown
+}
+
+func (d orderedDisplay) Run(ctx context.Context, _ <-chan struct{}) error {
+	<-ctx.Done()
+	d.log.add("display")
+	return nil
+}
+
+type orderedButton struct {
+	*fakeButton
+	log  *teardown
+	hang bool
+}
+
+func (b orderedButton) Run(ctx context.Context) {
+	if b.hang {
+		select {}
+	}
+	<-ctx.Done()
+	b.log.add("button")
+}
+
+func TestShutdownOrder(t *testing.T) {
+	tests := []struct {
+		name       string
+		hang       bool
+		wantOrder  []string
+		wantWithin time.Duration
+	}{
+		{"in order", false, []string{"button", "display", "fan", "pwm"}, time.Second},
+		{"hung button", true, []string{"display", "fan", "pwm"}, 2 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := &teardown{}
+			ff := newFakeFactories()
+			factories := ff.factories()
+			factories.NewFan = func(*config.Config) (Fan, error) { return orderedFan{ff.fan, log}, nil }
+			factories.NewDisplay = func(*config.Config, oled.FanController) (Display, error) {
+				return orderedDisplay{ff.display, log}, nil
+			}
+			factories.NewButton = func(config.ButtonConfig, config.TimeConfig) (Button, error) {
+				return orderedButton{ff.button, log, tt.hang}, nil
+			}
+			cfg := testConfig(config.ModulesConfig{Fan: true, OLED: true, Button: true, ShutdownTimeout: 1})
+			a := New(cfg, factories)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			a.Start(ctx, cancel)
+			cancel()
+			start := time.Now()
+			a.Wait()
+
+			if took := time.Since(start); took > tt.wantWithin {
+				t.Errorf("Wait() took %v, want under %v", took, tt.wantWithin)
+			}
+			log.mu.Lock()
+			defer log.mu.Unlock()
+			if !slices.Equal(log.order, tt.wantOrder) {
+				t.Errorf("teardown order = %v, want %v", log.order, tt.wantOrder)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package app
+
+import (
+	"time"
+
+	"github.com/kolobock/rockpi-quad-go/internal/button"
+)
+
+// powerActions need the gesture repeated before they run when confirmation is on
+var powerActions = map[string]string{
+	"poweroff": "power off",
+	"reboot":   "reboot",
+}
+
+// confirmation holds a power action until its gesture is repeated on the same button
+type confirmation struct {
+	window  time.Duration
+	pending *buttonEvent
+	action  string
+	timer   *time.Timer
+}
+
+func newConfirmation(window time.Duration) *confirmation {
+	return &confirmation{window: window}
+}
+
+// request starts waiting for evt to be repeated within the window
+func (c *confirmation) request(evt buttonEvent, action string) {
+	c.stop()
+	c.pending = &evt
+	c.action = action
+	c.timer = time.NewTimer(c.window)
+}
+
+// resolve consumes evt while a confirmation is pending. It returns the held action
+// when evt repeats the pending gesture, or "" when evt cancels it; handled is false
+// when nothing was pending and evt should run its own action.
+func (c *confirmation) resolve(evt buttonEvent) (action string, handled bool) {
+	if c.pending == nil {
+		return "", false
+	}
+	if evt.button.ID == c.pending.button.ID && evt.event == c.pending.event {
+		action = c.action
+	}
+	c.stop()
+	return action, true
+}
+
+// expired fires when the pending confirmation times out; it never fires when idle
+func (c *confirmation) expired() <-chan time.Time {
+	if c.timer == nil {
+		return nil
+	}
+	return c.timer.C
+}
+
+func (c *confirmation) stop() {
+	if c.timer != nil {
+		c.timer.Stop()
+	}
+	c.pending = nil
+	c.action = ""
+	c.timer = nil
+}
+
+// confirmPrompt returns the OLED lines asking for the gesture of evt to be repeated
+func confirmPrompt(evt button.EventType, action string) (title, text string) {
+	switch evt {
+	case button.LongPress, button.Hold:
+		title = "Hold again to"
+	default:
+		title = "Repeat to"
+	}
+	return title, powerActions[action]
+}
@@ -0,0 +1,86 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kolobock/rockpi-quad-go/internal/button"
+	"github.com/kolobock/rockpi-quad-go/internal/config"
+)
+
+func TestConfirmationResolve(t *testing.T) {
+	mainBtn := config.ButtonConfig{ID: config.MainButton}
+	aux := config.ButtonConfig{ID: "aux"}
+	press := buttonEvent{button: mainBtn, event: button.LongPress}
+
+	tests := []struct {
+		name        string
+		next        buttonEvent
+		wantAction  string
+		wantHandled bool
+	}{
+		{"repeated gesture confirms", press, "poweroff", true},
+		{"other gesture cancels", buttonEvent{button: mainBtn, event: button.Click}, "", true},
+		{"other button cancels", buttonEvent{button: aux, event: button.LongPress}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newConfirmation(time.Minute)
+			c.request(press, "poweroff")
+
+			action, handled := c.resolve(tt.next)
+			if action != tt.wantAction || handled != tt.wantHandled {
+				t.Errorf("resolve() = %q, %v; want %q, %v", action, handled, tt.wantAction, tt.wantHandled)
+			}
+			if c.expired() != nil {
+				t.Error("confirmation still pending after resolve")
+			}
+			if _, handled := c.resolve(press); handled {
+				t.Error("resolve() handled an event with nothing pending")
+			}
+		})
+	}
+}
+
+func TestConfirmationExpires(t *testing.T) {
+	c := newConfirmation(10 * time.Millisecond)
+	if c.expired() != nil {
+		t.Fatal("idle confirmation has a timeout channel")
+	}
+
+	c.request(buttonEvent{event: button.Hold}, "reboot")
+	select {
+	case <-c.expired():
+	case <-time.After(time.Second):
+		t.Fatal("confirmation did not expire")
+	}
+}
+
+func TestNeedsConfirmation(t *testing.T) {
+	tests := []struct {
+		name       string
+		action     string
+		confirm    bool
+		hasDisplay bool
+		want       bool
+	}{
+		{"poweroff with display", "poweroff", true, true, true},
+		{"reboot with display", "reboot", true, true, true},
+		{"confirmation disabled", "poweroff", false, true, false},
+		{"no display to ask on", "poweroff", true, false, false},
+		{"not a power action", "switch", true, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := New(&config.Config{Key: config.KeyConfig{ConfirmPower: tt.confirm}}, Factories{})
+			if tt.hasDisplay {
+				a.display = &fakeDisplay{}
+			}
+			if got := a.needsConfirmation(tt.action); got != tt.want {
+				t.Errorf("needsConfirmation(%q) = %v, want %v", tt.action, got, tt.want)
+			}
+		})
+	}
+}
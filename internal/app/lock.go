@@ -0,0 +1,84 @@
+package app
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kolobock/rockpi-quad-go/internal/button"
+	"github.com/kolobock/rockpi-quad-go/internal/logger"
+)
+
+const unlockWindow = 5 * time.Second
+
+// panelLock ignores button gestures while locked, except for the unlock sequence
+type panelLock struct {
+	mu       sync.Mutex
+	locked   bool
+	sequence []button.EventType
+	progress int
+	lastSeen time.Time
+}
+
+// newPanelLock creates a lock released by a sequence such as "press,press". A
+// sequence with an unknown gesture could never be entered, so it is replaced by
+// the default one.
+func newPanelLock(sequence string) *panelLock {
+	l := &panelLock{}
+	for _, evt := range strings.Split(sequence, ",") {
+		if evt = strings.TrimSpace(evt); evt == "" {
+			continue
+		}
+		if !button.EventType(evt).Valid() {
+			logger.Errorf("Unknown gesture %q in [key] unlock %q, unlocking with press,press", evt, sequence)
+			l.sequence = nil
+			break
+		}
+		l.sequence = append(l.sequence, button.EventType(evt))
+	}
+	if len(l.sequence) == 0 {
+		l.sequence = []button.EventType{button.LongPress, button.LongPress}
+	}
+	return l
+}
+
+func (l *panelLock) lock() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.locked = true
+	l.progress = 0
+}
+
+func (l *panelLock) isLocked() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.locked
+}
+
+// feed advances the unlock sequence and reports whether the panel was unlocked.
+// A wrong gesture or a pause longer than unlockWindow restarts the sequence.
+func (l *panelLock) feed(event button.EventType, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.progress > 0 && now.Sub(l.lastSeen) > unlockWindow {
+		l.progress = 0
+	}
+	l.lastSeen = now
+
+	if event != l.sequence[l.progress] {
+		l.progress = 0
+		if event != l.sequence[0] {
+			return false
+		}
+	}
+
+	l.progress++
+	if l.progress < len(l.sequence) {
+		return false
+	}
+
+	l.locked = false
+	l.progress = 0
+	return true
+}
@@ -0,0 +1,62 @@
+package app
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/kolobock/rockpi-quad-go/internal/button"
+)
+
+func TestPanelLockUnlockSequence(t *testing.T) {
+	l := newPanelLock("press,press")
+	l.lock()
+	now := time.Now()
+
+	if l.feed(button.Click, now) {
+		t.Error("click should not unlock")
+	}
+	if l.feed(button.LongPress, now) {
+		t.Error("first press should not unlock")
+	}
+	if !l.feed(button.LongPress, now.Add(time.Second)) {
+		t.Error("second press should unlock")
+	}
+	if l.isLocked() {
+		t.Error("lock should be released")
+	}
+}
+
+func TestPanelLockSequenceTimeout(t *testing.T) {
+	l := newPanelLock("")
+	l.lock()
+	now := time.Now()
+
+	l.feed(button.LongPress, now)
+	if l.feed(button.LongPress, now.Add(unlockWindow+time.Second)) {
+		t.Error("sequence should restart after the unlock window")
+	}
+	if !l.feed(button.LongPress, now.Add(unlockWindow+2*time.Second)) {
+		t.Error("restarted sequence should unlock")
+	}
+}
+
+func TestPanelLockInvalidSequence(t *testing.T) {
+	tests := []struct {
+		name     string
+		sequence string
+		want     []button.EventType
+	}{
+		{"valid", "click, twice", []button.EventType{button.Click, button.DoubleClick}},
+		{"empty", "", []button.EventType{button.LongPress, button.LongPress}},
+		{"typo", "long_press,long_press", []button.EventType{button.LongPress, button.LongPress}},
+		{"one unknown", "click,dbl", []button.EventType{button.LongPress, button.LongPress}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := newPanelLock(tt.sequence).sequence; !slices.Equal(got, tt.want) {
+				t.Errorf("sequence = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	"github.com/kolobock/rockpi-quad-go/internal/logger"
+)
+
+// Delays between startup attempts; the delay doubles after every failure
+var (
+	retryInitialDelay = time.Second
+	retryMaxDelay     = 10 * time.Second
+	// retryBackgroundMaxDelay caps the delay of devices still missing after startup
+	retryBackgroundMaxDelay = time.Minute
+)
+
+// retry calls create until it succeeds, ctx ends or window has passed, so devices
+// whose kernel modules are still loading at boot get a chance to appear
+func retry[T any](ctx context.Context, name string, window time.Duration, create func() (T, error)) (T, error) {
+	deadline := time.Now().Add(window)
+	delay := retryInitialDelay
+
+	for {
+		v, err := create()
+		if err == nil || time.Now().Add(delay).After(deadline) {
+			return v, err
+		}
+
+		logger.Infof("%s not ready, retrying in %v: %v", name, delay, err)
+		select {
+		case <-ctx.Done():
+			return v, err
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, retryMaxDelay)
+	}
+}
+
+// retryUntil keeps calling create until it succeeds or ctx ends, for devices that
+// were not ready within the startup window; it only fails when ctx ends
+func retryUntil[T any](ctx context.Context, name string, create func() (T, error)) (T, error) {
+	delay := retryMaxDelay
+	for {
+		select {
+		case <-ctx.Done():
+			var zero T
+			return zero, ctx.Err()
+		case <-time.After(delay):
+		}
+
+		v, err := create()
+		if err == nil {
+			return v, nil
+		}
+		delay = min(delay*2, retryBackgroundMaxDelay)
+		logger.Infof("%s still not ready, retrying in %v: %v", name, delay, err)
+	}
+}
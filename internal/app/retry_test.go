@@ -0,0 +1,89 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRetry(t *testing.T) {
+	retryInitialDelay, retryMaxDelay = time.Millisecond, 4*time.Millisecond
+	defer func() { retryInitialDelay, retryMaxDelay = time.Second, 10*time.Second }()
+
+	tests := []struct {
+		name      string
+		failures  int
+		window    time.Duration
+		wantCalls int
+		wantErr   bool
+	}{
+		{"immediate success", 0, time.Second, 1, false},
+		{"ready after retries", 3, time.Second, 4, false},
+		{"no window", 3, 0, 1, true},
+		{"window exhausted", 100, 20 * time.Millisecond, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			got, err := retry(context.Background(), "test", tt.window, func() (int, error) {
+				calls++
+				if calls <= tt.failures {
+					return 0, errors.New("not ready")
+				}
+				return 42, nil
+			})
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("retry() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != 42 {
+				t.Errorf("retry() = %d, want 42", got)
+			}
+			if tt.wantCalls > 0 && calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", calls, tt.wantCalls)
+			}
+			if tt.wantCalls == 0 && calls >= tt.failures {
+				t.Errorf("calls = %d, retry outlived its window", calls)
+			}
+		})
+	}
+}
+
+func TestRetryCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	calls := 0
+	_, err := retry(ctx, "test", time.Minute, func() (int, error) {
+		calls++
+		return 0, errors.New("not ready")
+	})
+	if err == nil || calls != 1 {
+		t.Errorf("retry() = %v after %d calls, want error after 1 call", err, calls)
+	}
+}
+
+func TestRetryUntil(t *testing.T) {
+	retryMaxDelay, retryBackgroundMaxDelay = time.Millisecond, 4*time.Millisecond
+	defer func() { retryMaxDelay, retryBackgroundMaxDelay = 10*time.Second, time.Minute }()
+
+	calls := 0
+	got, err := retryUntil(context.Background(), "test", func() (int, error) {
+		calls++
+		if calls <= 5 {
+			return 0, errors.New("not ready")
+		}
+		return 42, nil
+	})
+	if err != nil || got != 42 || calls != 6 {
+		t.Errorf("retryUntil() = %d, %v after %d calls, want 42 after 6 calls", got, err, calls)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := retryUntil(ctx, "test", func() (int, error) { return 42, nil }); err == nil {
+		t.Error("retryUntil() succeeded after its context ended")
+	}
+}
@@ -0,0 +1,106 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/kolobock/rockpi-quad-go/internal/diag"
+	"github.com/kolobock/rockpi-quad-go/internal/logger"
+)
+
+const actionScriptPrefix = "script:"
+
+// scriptWaitDelay bounds waiting for the output pipes of a stopped action, which
+// a leftover child may still hold open
+const scriptWaitDelay = time.Second
+
+// actionEnv describes the button event and the daemon state to commands run by
+// button actions, on top of the daemon's own environment
+func (a *App) actionEnv(evt buttonEvent) []string {
+	env := append(os.Environ(),
+		"ROCKPI_BUTTON="+evt.button.ID,
+		"ROCKPI_EVENT="+string(evt.event),
+	)
+	if display := a.currentDisplay(); display != nil {
+		index, name := display.CurrentPage()
+		env = append(env, "ROCKPI_PAGE="+strconv.Itoa(index), "ROCKPI_PAGE_NAME="+name)
+	}
+	if a.fan != nil {
+		st := a.fan.Status()
+		env = append(env,
+			"ROCKPI_FAN_ENABLED="+strconv.FormatBool(st.Enabled),
+			"ROCKPI_FAN_PROFILE="+st.Profile,
+			fmt.Sprintf("ROCKPI_CPU_TEMP=%.1f", st.CPUTemp),
+			fmt.Sprintf("ROCKPI_DISK_TEMP=%.1f", st.DiskTemp),
+			fmt.Sprintf("ROCKPI_CPU_DUTY=%.0f", st.CPUDuty),
+			fmt.Sprintf("ROCKPI_DISK_DUTY=%.0f", st.DiskDuty),
+		)
+	}
+	return env
+}
+
+// actionCommand builds the command for a "script:<path> [args...]" action, which
+// runs without a shell, or for any other action as a shell command line
+func actionCommand(ctx context.Context, action string) (*exec.Cmd, error) {
+	script, ok := strings.CutPrefix(action, actionScriptPrefix)
+	if !ok {
+		diag.ShellExecs.Add(1)
+		// #nosec G204 - custom actions come from the configuration file
+		return killGroupOnCancel(exec.CommandContext(ctx, "sh", "-c", action)), nil
+	}
+
+	args := strings.Fields(script)
+	if len(args) == 0 {
+		return nil, fmt.Errorf("invalid script action %q: missing path", action)
+	}
+	// #nosec G204 - scripts and their arguments come from the configuration file
+	return killGroupOnCancel(exec.CommandContext(ctx, args[0], args[1:]...)), nil
+}
+
+// killGroupOnCancel runs cmd in a process group of its own and kills the whole
+// group when its context ends, so the children of a shell line are stopped with it
+func killGroupOnCancel(cmd *exec.Cmd) *exec.Cmd {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	cmd.WaitDelay = scriptWaitDelay
+	return cmd
+}
+
+// executeCommand runs a custom action in the background with the event context in
+// its environment, stopping it after [key] script_timeout and logging its output;
+// shutdown waits for it with the buttons
+func (a *App) executeCommand(evt buttonEvent, action string) {
+	timeout := time.Duration(a.cfg.Key.ScriptTimeout * float64(time.Second))
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	cmd, err := actionCommand(ctx, action)
+	if err != nil {
+		cancel()
+		logger.Errorf("Failed to run button action: %v", err)
+		return
+	}
+	cmd.Env = a.actionEnv(evt)
+
+	logger.Infof("Executing custom command: %s", action)
+	a.buttonStage.goRun(func() {
+		defer cancel()
+		out, err := cmd.CombinedOutput()
+		output := strings.TrimSpace(string(out))
+		switch {
+		case errors.Is(ctx.Err(), context.DeadlineExceeded):
+			logger.Errorf("Command '%s' timed out after %s: %s", action, timeout, output)
+		case err != nil:
+			logger.Errorf("Failed to execute command '%s': %v: %s", action, err, output)
+		default:
+			logger.Infof("Command '%s' executed successfully: %s", action, output)
+		}
+	})
+}
@@ -0,0 +1,73 @@
+package app
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kolobock/rockpi-quad-go/internal/button"
+	"github.com/kolobock/rockpi-quad-go/internal/config"
+	"github.com/kolobock/rockpi-quad-go/internal/fan"
+)
+
+func TestActionCommand(t *testing.T) {
+	tests := []struct {
+		action  string
+		want    []string
+		wantErr bool
+	}{
+		{"script:/usr/local/bin/eject.sh sda", []string{"/usr/local/bin/eject.sh", "sda"}, false},
+		{"script: /opt/led.sh  on ", []string{"/opt/led.sh", "on"}, false},
+		{"umount /mnt/disk2 && hdparm -Y /dev/sdb", []string{"sh", "-c", "umount /mnt/disk2 && hdparm -Y /dev/sdb"}, false},
+		{"script:", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.action, func(t *testing.T) {
+			cmd, err := actionCommand(context.Background(), tt.action)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("actionCommand() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && !slices.Equal(cmd.Args, tt.want) {
+				t.Errorf("actionCommand() args = %q, want %q", cmd.Args, tt.want)
+			}
+		})
+	}
+}
+
+func TestActionCommandTimeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	// The background sleep keeps the output pipe open after the shell is stopped
+	cmd, err := actionCommand(ctx, "sleep 30 & sleep 30")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	if _, err := cmd.CombinedOutput(); err == nil {
+		t.Error("CombinedOutput() succeeded past the timeout")
+	}
+	if took := time.Since(start); took > 5*time.Second {
+		t.Errorf("command ran for %v, want it stopped at the timeout", took)
+	}
+}
+
+func TestActionEnv(t *testing.T) {
+	a := &App{
+		display: &fakeDisplay{},
+		fan:     &fakeFan{status: fan.Status{Enabled: true, Profile: "silent", CPUTemp: 48.25, CPUDuty: 40}},
+	}
+
+	env := a.actionEnv(buttonEvent{button: config.ButtonConfig{ID: "eject2"}, event: button.DoubleClick})
+	for _, want := range []string{
+		"ROCKPI_BUTTON=eject2", "ROCKPI_EVENT=twice", "ROCKPI_PAGE=1", "ROCKPI_PAGE_NAME=SystemInfo1",
+		"ROCKPI_FAN_ENABLED=true", "ROCKPI_FAN_PROFILE=silent", "ROCKPI_CPU_TEMP=48.2", "ROCKPI_CPU_DUTY=40",
+	} {
+		if !slices.Contains(env, want) {
+			t.Errorf("env is missing %s: %v", want, strings.Join(env, " "))
+		}
+	}
+}
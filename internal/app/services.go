@@ -0,0 +1,565 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/kolobock/rockpi-quad-go/internal/alert"
+	"github.com/kolobock/rockpi-quad-go/internal/api"
+	"github.com/kolobock/rockpi-quad-go/internal/config"
+	"github.com/kolobock/rockpi-quad-go/internal/disk"
+	"github.com/kolobock/rockpi-quad-go/internal/health"
+	"github.com/kolobock/rockpi-quad-go/internal/heartbeat"
+	"github.com/kolobock/rockpi-quad-go/internal/kmsg"
+	"github.com/kolobock/rockpi-quad-go/internal/logger"
+	"github.com/kolobock/rockpi-quad-go/internal/network"
+	"github.com/kolobock/rockpi-quad-go/internal/oled"
+	"github.com/kolobock/rockpi-quad-go/internal/raid"
+	"github.com/kolobock/rockpi-quad-go/internal/rails"
+	"github.com/kolobock/rockpi-quad-go/internal/snmp"
+	"github.com/kolobock/rockpi-quad-go/internal/statusfile"
+	"github.com/kolobock/rockpi-quad-go/internal/store"
+	"github.com/kolobock/rockpi-quad-go/internal/thermal"
+	"github.com/kolobock/rockpi-quad-go/internal/ups"
+)
+
+// startDiskMonitors starts the optional disk pollers of the disk-monitor module
+func (a *App) startDiskMonitors(ctx context.Context) {
+	if a.cfg.Disk.PowerStats {
+		a.goRun(func() { disk.RunPowerMonitor(ctx, time.Duration(a.cfg.Disk.PowerInterval)*time.Second) })
+	}
+	if a.cfg.Disk.LinkErrors {
+		a.goRun(func() { disk.RunLinkMonitor(ctx, time.Duration(a.cfg.Disk.LinkInterval)*time.Second) })
+	}
+}
+
+// startNotifyingMonitors starts the monitors whose first poll may already raise a
+// banner, after the display exists; cancel begins the low-battery poweroff
+func (a *App) startNotifyingMonitors(ctx context.Context, cancel context.CancelFunc) {
+	mods := a.cfg.Modules
+	if a.raid != nil {
+		a.startRAIDMonitor(ctx)
+	}
+	if a.ups != nil {
+		a.startUPSMonitor(ctx, cancel)
+	}
+	if a.rails != nil {
+		a.startRailMonitor(ctx)
+	}
+	if mods.DiskMonitor && a.cfg.Disk.SMARTHealth {
+		a.startSMARTHealth(ctx)
+	}
+	if mods.Alerts && a.cfg.Network.IPNotify {
+		a.startIPWatcher(ctx)
+	}
+}
+
+// startSMARTHealth checks disk SMART health on a slow interval
+func (a *App) startSMARTHealth(ctx context.Context) {
+	interval := time.Duration(a.cfg.Disk.SMARTInterval) * time.Second
+	a.goRun(func() {
+		disk.RunHealthMonitor(ctx, interval, func(h disk.Health) {
+			a.raise(alert.Event{
+				Rule:    alert.RuleSMART,
+				Subject: h.ID,
+				Message: fmt.Sprintf("SMART health of %s (%s) degraded: %s, %d reallocated and %d pending sectors",
+					h.Device, h.ID, h.Status, h.Reallocated, h.Pending),
+				Details: map[string]any{"device": h.Device, "id": h.ID, "status": h.Status, "reallocated": h.Reallocated, "pending": h.Pending},
+			}, fmt.Sprintf("SMART %s %s", disk.Label(h.Device, a.cfg.Disk.Aliases), h.Summary()))
+		})
+	})
+}
+
+// newRAIDMonitor returns the md/ZFS monitor of [raid], nil when neither is enabled
+func newRAIDMonitor(cfg config.RAIDConfig) *raid.Monitor {
+	if !cfg.MDStat && !cfg.ZFS {
+		return nil
+	}
+	mdstat := ""
+	if cfg.MDStat {
+		mdstat = raid.DefaultMDStat
+	}
+	return raid.NewMonitor(mdstat, cfg.ZFS)
+}
+
+// startRAIDMonitor polls md arrays and ZFS pools, raising an alert when one becomes degraded
+func (a *App) startRAIDMonitor(ctx context.Context) {
+	monitor := a.raid
+	a.goRun(func() { monitor.Run(ctx, time.Duration(a.cfg.RAID.Interval)*time.Second) })
+	a.goRun(func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case arr := <-monitor.Changes():
+				a.raise(alert.Event{Rule: alert.RuleRAID, Subject: arr.Name, Message: arr.Message(), Details: arr.Details()},
+					fmt.Sprintf("RAID %s %s", arr.Name, arr.Status()))
+			}
+		}
+	})
+}
+
+// newUPSMonitor returns the monitor of the [ups] source, nil when none is configured
+// or the fuel gauge cannot be opened
+func newUPSMonitor(cfg config.UPSConfig, i2cBus int) *ups.Monitor {
+	var src ups.Source
+	switch cfg.Source {
+	case ups.SourceNUT:
+		src = ups.NewNUT(cfg.Name)
+	case ups.SourceMAX17048:
+		gauge, err := ups.NewMAX17048(i2cBus, cfg.Address)
+		if err != nil {
+			logger.Errorf("UPS fuel gauge unavailable: %v", err)
+			return nil
+		}
+		src = gauge
+	default:
+		return nil
+	}
+	return ups.NewMonitor(src, cfg.ShutdownCharge)
+}
+
+// startUPSMonitor polls the UPS, showing a banner when the power source changes and
+// powering off through the shutdown sequence when the battery runs low
+func (a *App) startUPSMonitor(ctx context.Context, cancel context.CancelFunc) {
+	monitor := a.ups
+	a.goRun(func() { monitor.Run(ctx, time.Duration(a.cfg.UPS.Interval)*time.Second) })
+	a.goRun(func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case evt := <-monitor.Events():
+				a.handleUPSEvent(evt, cancel)
+			}
+		}
+	})
+}
+
+func (a *App) handleUPSEvent(evt ups.Event, cancel context.CancelFunc) {
+	alertEvt := alert.Event{Rule: alert.RuleUPS, Details: map[string]any{}}
+	charge := ""
+	if evt.Status.ChargeKnown {
+		alertEvt.Details["charge"] = evt.Status.Charge
+		charge = fmt.Sprintf(" (%.0f%%)", evt.Status.Charge)
+	}
+	var banner string
+	switch evt.Type {
+	case ups.PowerLost:
+		alertEvt.Subject = "power_lost"
+		alertEvt.Message = "Power lost, running on battery" + charge
+		banner = "On battery"
+		if evt.Status.ChargeKnown {
+			banner = fmt.Sprintf("On battery %.0f%%", evt.Status.Charge)
+		}
+	case ups.PowerRestored:
+		alertEvt.Subject = "power_restored"
+		alertEvt.Message = "Power restored" + charge
+		banner = "Power restored"
+	case ups.LowBattery:
+		alertEvt.Subject = "low_battery"
+		alertEvt.Message = "Battery low" + charge + ", powering off"
+		logger.Errorf("%s", alertEvt.Message)
+		if a.alerts != nil {
+			a.alerts.Publish(alertEvt)
+		}
+		a.executePower("poweroff", cancel)
+		return
+	}
+	a.raise(alertEvt, banner)
+}
+
+// newRailMonitor returns the monitor of the [rail.<name>] sections, nil when none is
+// configured or a rail cannot be opened
+func newRailMonitor(cfg *config.Config) *rails.Monitor {
+	if len(cfg.Rails.Sensors) == 0 {
+		return nil
+	}
+	monitor, err := rails.NewMonitor(cfg.Rails.Sensors, cfg.Env.I2CBus)
+	if err != nil {
+		logger.Errorf("Power rail monitoring unavailable: %v", err)
+		return nil
+	}
+	return monitor
+}
+
+// startRailMonitor watches the HAT power rails, logging and showing a banner on
+// brown-outs, over-current and recoveries
+func (a *App) startRailMonitor(ctx context.Context) {
+	monitor := a.rails
+	interval := time.Duration(a.cfg.Rails.Interval * float64(time.Second))
+	a.goRun(func() {
+		defer monitor.Close()
+		monitor.Run(ctx, interval)
+	})
+	a.goRun(func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case evt := <-monitor.Events():
+				a.raise(alert.Event{Rule: alert.RuleRail, Subject: evt.State.Name, Message: evt.Message()}, railBanner(evt.State))
+			}
+		}
+	})
+}
+
+func railBanner(st rails.State) string {
+	switch {
+	case st.Good:
+		return "Rail " + st.Name + " OK"
+	case st.Overcurrent:
+		return fmt.Sprintf("Rail %s %.1fA", st.Name, st.Amps)
+	case st.Volts > 0:
+		return fmt.Sprintf("Rail %s LOW %.1fV", st.Name, st.Volts)
+	default:
+		return "Rail " + st.Name + " LOW"
+	}
+}
+
+// startAlertMonitors starts the optional watchers of the alerts module and the alert
+// bus; the kernel log watcher is kept so the display can show its events
+func (a *App) startAlertMonitors(ctx context.Context) {
+	if a.alerts = newAlertBus(a.cfg); a.alerts != nil {
+		a.startAlertBus(ctx)
+	}
+	if a.cfg.Network.LinkMonitor {
+		a.startLinkMonitor(ctx)
+	}
+	if a.cfg.Kernel.Watch {
+		a.startKernelWatcher(ctx)
+	}
+}
+
+// goRun runs fn in a goroutine tracked by the shutdown wait group
+func (a *App) goRun(fn func()) {
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		fn()
+	}()
+}
+
+func (a *App) startLinkMonitor(ctx context.Context) {
+	interfaces := a.cfg.Network.Interfaces
+	if len(interfaces) == 0 {
+		interfaces = []string{"eth0"}
+	}
+	monitor := network.NewLinkMonitor(interfaces, a.cfg.Network.MinSpeed)
+	a.goRun(func() { monitor.Run(ctx, 10*time.Second) })
+}
+
+func (a *App) startHealthChecks(ctx context.Context) {
+	checks, err := network.ParseChecks(a.cfg.Network.Checks)
+	if err != nil {
+		logger.Errorf("Failed to parse health checks: %v", err)
+		return
+	}
+	checker := network.NewChecker(checks, a.cfg.Network.CheckFailures)
+	a.checker = checker
+	a.goRun(func() { checker.Run(ctx, time.Duration(a.cfg.Network.CheckInterval)*time.Second) })
+}
+
+func (a *App) startIPWatcher(ctx context.Context) {
+	watcher := network.NewIPWatcher()
+
+	a.goRun(func() { watcher.Run(ctx, 30*time.Second) })
+	a.goRun(func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case ip := <-watcher.Changes():
+				a.raise(alert.Event{Rule: alert.RuleIPChanged, Subject: ip, Message: "Primary IP address changed: " + ip}, "IP "+ip)
+			}
+		}
+	})
+}
+
+func (a *App) startKernelWatcher(ctx context.Context) {
+	watcher := kmsg.New(kmsg.DefaultPath)
+	a.kernelWatcher = watcher
+
+	a.goRun(func() {
+		if err := watcher.Run(ctx); err != nil {
+			logger.Errorf("Kernel log watcher error: %v", err)
+		}
+	})
+	a.goRun(func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case evt := <-watcher.Events():
+				a.raise(alert.Event{
+					Rule:    alert.RuleKernel,
+					Subject: string(evt.Category),
+					Message: fmt.Sprintf("Kernel alert [%s]: %s", evt.Category, evt.Message),
+				}, "Kernel "+string(evt.Category))
+			}
+		}
+	})
+}
+
+func (a *App) startAPI(ctx context.Context, cancel context.CancelFunc) {
+	// Keep the interface nil when the fan module is off
+	var fanSource api.FanController
+	if a.fan != nil {
+		fanSource = a.fan
+	}
+	server := api.New(a.cfg, fanSource)
+	if a.cfg.Modules.OLED {
+		server.SetDisplay(panelDisplay{a})
+		server.SetRenderProfiler(panelDisplay{a})
+		server.SetDisplayBus(panelDisplay{a})
+	}
+	if a.checker != nil {
+		server.SetHealthChecker(a.checker)
+	}
+	server.SetModuleTracker(a.modules)
+	if a.store != nil {
+		server.SetStore(a.store)
+	}
+	if a.rails != nil {
+		server.SetRailMonitor(a.rails)
+	}
+	server.SetFlags(a.flags)
+	server.SetPowerAction("poweroff", func() { a.executePower("poweroff", cancel) })
+	server.SetPowerAction("reboot", func() { a.executePower("reboot", cancel) })
+
+	a.goRun(func() {
+		if err := server.Run(ctx); err != nil {
+			logger.Errorf("API error: %v", err)
+		}
+	})
+}
+
+// panelDisplay reaches the display from the API, including one attached after the
+// API started
+type panelDisplay struct {
+	a *App
+}
+
+func (p panelDisplay) ShowMessage(title, text string) {
+	p.a.showMessage(title, text)
+}
+
+func (p panelDisplay) PageTimings() []oled.PageTiming {
+	if display := p.a.currentDisplay(); display != nil {
+		return display.PageTimings()
+	}
+	return nil
+}
+
+// BusStats reports an unattached display as unavailable
+func (p panelDisplay) BusStats() oled.BusStats {
+	if display := p.a.currentDisplay(); display != nil {
+		return display.BusStats()
+	}
+	return oled.BusStats{}
+}
+
+func (a *App) startHeartbeat(ctx context.Context) {
+	pinger := heartbeat.New(a.cfg.Heartbeat.Kind, a.cfg.Heartbeat.URL, daemonHealth(a.fan, a.modules))
+	a.goRun(func() { pinger.Run(ctx, time.Duration(a.cfg.Heartbeat.Interval)*time.Second) })
+}
+
+// daemonHealth reports the daemon unhealthy while fan control cannot be trusted;
+// other degraded modules leave the heartbeat up and are reported via the status
+func daemonHealth(fanCtrl Fan, modules *health.Tracker) heartbeat.HealthFunc {
+	return func() (bool, string) {
+		if modules.State(health.Fan) == health.StateFailed {
+			return false, "fan control unavailable"
+		}
+		if fanCtrl == nil {
+			return true, ""
+		}
+		st := fanCtrl.Status()
+		switch {
+		case st.Emergency:
+			return false, "thermal emergency"
+		case st.SensorFallback:
+			return false, fmt.Sprintf("temperature sensor failing (cpu: %d, disk: %d)", st.CPUSensorFailures, st.DiskSensorFailures)
+		case !st.PWMHealthy:
+			return false, fmt.Sprintf("fan PWM writes failing (%d)", st.PWMFailures)
+		default:
+			return true, ""
+		}
+	}
+}
+
+// startStore opens the long-term metrics store and starts recording into it; the
+// display and API read their trends from it
+func (a *App) startStore(ctx context.Context) {
+	st, err := store.Open(a.cfg.Store.Path, time.Duration(a.cfg.Store.RetentionDays)*24*time.Hour)
+	if err != nil {
+		logger.Errorf("Metrics store unavailable: %v", err)
+		return
+	}
+	a.store = st
+
+	recorder := store.NewRecorder(st, a.storeSources())
+	a.goRun(func() {
+		defer func() {
+			if err := st.Close(); err != nil {
+				logger.Errorf("Failed to close metrics store: %v", err)
+			}
+		}()
+		recorder.Run(ctx, time.Duration(a.cfg.Store.Interval)*time.Second)
+	})
+}
+
+// startStatusFile keeps the [status_file] JSON file up to date for other tools
+func (a *App) startStatusFile(ctx context.Context) {
+	writer := statusfile.New(a.cfg.Status.Path, a.statusSources())
+	a.goRun(func() { writer.Run(ctx, time.Duration(a.cfg.Status.Interval)*time.Second) })
+}
+
+// statusSources returns the readings of the status file; disk temperatures need smartctl
+func (a *App) statusSources() statusfile.Sources {
+	round := a.cfg.Temp.API.Round
+	smart := a.modules.State(health.SMART) == health.StateOK
+	src := statusfile.Sources{
+		CPUTemp: func() (float64, bool) {
+			temp, err := thermal.Read(a.cfg.Fan.CPUSensors, a.cfg.Fan.CPUSensorMode)
+			return round(temp), err == nil
+		},
+		Disks: func() []statusfile.Disk {
+			var disks []statusfile.Disk
+			for _, dev := range disk.GetSATADisks() {
+				d := statusfile.Disk{Device: dev, ID: disk.ID(dev), Label: disk.Label(dev, a.cfg.Disk.Aliases)}
+				if smart {
+					if temp, err := disk.GetTemperature(dev); err == nil {
+						temp = round(temp)
+						d.Temp = &temp
+					}
+				}
+				disks = append(disks, d)
+			}
+			return disks
+		},
+		Mounts: append([]string{"/"}, a.cfg.Disk.SpaceUsageMountPoints...),
+	}
+	if a.fan != nil {
+		src.Fan = func() statusfile.Fan {
+			st := a.fan.Status()
+			return statusfile.Fan{Enabled: st.Enabled, CPUDuty: st.CPUDuty, DiskDuty: st.DiskDuty, Emergency: st.Emergency}
+		}
+	}
+	return src
+}
+
+// startSNMP answers SNMP pollers on [snmp] listen
+func (a *App) startSNMP(ctx context.Context) {
+	base, err := snmp.ParseOID(a.cfg.SNMP.BaseOID)
+	if err != nil {
+		logger.Errorf("SNMP agent disabled: %v", err)
+		return
+	}
+	conn, err := net.ListenPacket("udp", a.cfg.SNMP.Listen)
+	if err != nil {
+		logger.Errorf("SNMP agent disabled: %v", err)
+		return
+	}
+	logger.Infof("SNMP agent listening on %s", conn.LocalAddr())
+
+	readings := a.snmpReadings()
+	agent := snmp.NewAgent(a.cfg.SNMP.Community, func() []snmp.Var { return snmp.Vars(base, readings()) })
+	a.goRun(func() {
+		if err := agent.Serve(ctx, conn); err != nil {
+			logger.Errorf("SNMP agent error: %v", err)
+		}
+	})
+}
+
+// snmpReadings returns what the SNMP agent publishes; disk temperatures and health
+// need smartctl, health also [disk] smart_health
+func (a *App) snmpReadings() func() snmp.Readings {
+	smart := a.modules.State(health.SMART) == health.StateOK
+	return func() snmp.Readings {
+		var r snmp.Readings
+		if temp, err := thermal.Read(a.cfg.Fan.CPUSensors, a.cfg.Fan.CPUSensorMode); err == nil {
+			r.CPUTemp = &temp
+		}
+		if a.fan != nil {
+			st := a.fan.Status()
+			r.Fan = &snmp.Fan{CPUDuty: st.CPUDuty, DiskDuty: st.DiskDuty, Emergency: st.Emergency}
+		}
+		checked := disk.GetHealth()
+		for _, dev := range disk.GetSATADisks() {
+			d := snmp.Disk{Device: dev, ID: disk.ID(dev), Label: disk.Label(dev, a.cfg.Disk.Aliases)}
+			if smart {
+				if temp, err := disk.GetTemperature(dev); err == nil {
+					d.Temp = &temp
+				}
+			}
+			if h, ok := checked[dev]; ok {
+				d.Health = snmpHealth(h.Status)
+				d.Reallocated, d.Pending = h.Reallocated, h.Pending
+			}
+			r.Disks = append(r.Disks, d)
+		}
+		return r
+	}
+}
+
+func snmpHealth(status disk.HealthStatus) snmp.DiskHealth {
+	switch status {
+	case disk.HealthOK:
+		return snmp.HealthOK
+	case disk.HealthWarn:
+		return snmp.HealthWarn
+	case disk.HealthFailed:
+		return snmp.HealthFailed
+	default:
+		return snmp.HealthUnknown
+	}
+}
+
+// storeSources returns the readings recorded in the metrics store; disk readings need smartctl
+func (a *App) storeSources() store.Sources {
+	src := store.Sources{
+		CPUTemp: func() (float64, bool) {
+			temp, err := thermal.Read(a.cfg.Fan.CPUSensors, a.cfg.Fan.CPUSensorMode)
+			return temp, err == nil
+		},
+		Interfaces: a.cfg.Network.Interfaces,
+	}
+	if len(src.Interfaces) == 0 {
+		src.Interfaces = []string{"eth0"}
+	}
+	if a.modules.State(health.SMART) != health.StateOK {
+		return src
+	}
+
+	src.DiskTemps = func() map[string]float64 {
+		temps := make(map[string]float64)
+		for _, dev := range disk.GetSATADisks() {
+			if temp, err := disk.GetTemperature(dev); err == nil {
+				temps[disk.ID(dev)] = temp
+			}
+		}
+		return temps
+	}
+	src.DiskTempHistory = func() map[string][]store.Sample {
+		history := make(map[string][]store.Sample)
+		for id, samples := range disk.ReadTemperatureHistory() {
+			for _, s := range samples {
+				history[id] = append(history[id], store.Sample{Time: s.Time, Temp: s.Temp})
+			}
+		}
+		return history
+	}
+	src.SMART = func() map[string]map[string]int64 {
+		attrs := make(map[string]map[string]int64)
+		for _, dev := range disk.GetSATADisks() {
+			if values, err := disk.GetSMARTAttributes(dev); err == nil {
+				attrs[disk.ID(dev)] = values
+			}
+		}
+		return attrs
+	}
+	return src
+}
@@ -0,0 +1,100 @@
+package app
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/kolobock/rockpi-quad-go/internal/logger"
+)
+
+// Shutdown timing: the default when the config leaves shutdown_timeout unset, and
+// the time every stage still gets after an earlier one used up the timeout
+const (
+	defaultShutdownTimeout = 15 * time.Second
+	stageGrace             = time.Second
+)
+
+// stage is a group of goroutines that Wait stops together. Its context outlives the
+// daemon context, so the buttons, display and fan stop one after another instead of
+// racing each other for the devices they share.
+type stage struct {
+	name   string
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func newStage(name string) *stage {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &stage{name: name, ctx: ctx, cancel: cancel}
+}
+
+// goRun runs fn in a goroutine the stage waits for
+func (s *stage) goRun(fn func()) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		fn()
+	}()
+}
+
+// shutdownTimeout is how long Wait gives the modules to stop
+func (a *App) shutdownTimeout() time.Duration {
+	if a.cfg.Modules.ShutdownTimeout <= 0 {
+		return defaultShutdownTimeout
+	}
+	return time.Duration(a.cfg.Modules.ShutdownTimeout) * time.Second
+}
+
+// Wait tears the daemon down after its context was cancelled: the buttons, so no
+// action starts meanwhile, then the display, then fan control, and finally the fan
+// PWM is released in a safe state before the remaining services are waited for.
+// Every stage is logged; a stage still running at the [modules] shutdown_timeout is
+// reported and left behind, the later stages still get stageGrace each, and the fan
+// is released regardless.
+func (a *App) Wait() {
+	start := time.Now()
+	deadline := start.Add(a.shutdownTimeout())
+	complete := true
+	for _, s := range []*stage{a.buttonStage, a.displayStage, a.fanStage} {
+		s.cancel()
+		complete = awaitStage(s.name, &s.wg, deadline) && complete
+	}
+
+	if a.fan != nil {
+		if err := a.fan.Close(); err != nil {
+			logger.Errorf("Failed to close fan controller: %v", err)
+		}
+		logger.Infoln("Shutdown: fan PWM released")
+	}
+
+	complete = awaitStage("services", &a.wg, deadline) && complete
+	if complete {
+		logger.Infof("Shutdown complete in %s", time.Since(start).Round(time.Millisecond))
+	} else {
+		logger.Errorf("Shutdown timeout after %s", a.shutdownTimeout())
+	}
+}
+
+// awaitStage waits for wg until deadline, or for stageGrace once it passed, and logs
+// how long the stage took or that it hung
+func awaitStage(name string, wg *sync.WaitGroup, deadline time.Time) bool {
+	start := time.Now()
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	timer := time.NewTimer(max(time.Until(deadline), stageGrace))
+	defer timer.Stop()
+	select {
+	case <-done:
+		logger.Infof("Shutdown: %s stopped in %s", name, time.Since(start).Round(time.Millisecond))
+		return true
+	case <-timer.C:
+		logger.Errorf("Shutdown: %s still running after %s, continuing without it", name, time.Since(start).Round(time.Millisecond))
+		return false
+	}
+}
@@ -0,0 +1,86 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"time"
+
+	"github.com/kolobock/rockpi-quad-go/internal/config"
+	"github.com/kolobock/rockpi-quad-go/internal/disk"
+	"github.com/kolobock/rockpi-quad-go/internal/fan"
+	"github.com/kolobock/rockpi-quad-go/internal/health"
+	"github.com/kolobock/rockpi-quad-go/internal/logger"
+)
+
+// Snapshot is the internal state dumped on SIGUSR1 for field debugging
+type Snapshot struct {
+	Time       time.Time        `json:"time"`
+	Config     *config.Config   `json:"config"`
+	Fan        *fan.Status      `json:"fan,omitempty"`
+	Disks      disk.CacheState  `json:"disks"`
+	Display    *DisplaySnapshot `json:"display,omitempty"`
+	Modules    health.Report    `json:"modules"`
+	Goroutines int              `json:"goroutines"`
+	// Stacks are the goroutine stacks grouped by identical stack, only in dump files
+	Stacks string `json:"stacks,omitempty"`
+}
+
+// DisplaySnapshot is the OLED page list and the page on screen
+type DisplaySnapshot struct {
+	Pages   []string `json:"pages"`
+	Current int      `json:"current"`
+}
+
+// Snapshot collects the current state; the config has its secrets redacted
+func (a *App) Snapshot() Snapshot {
+	snap := Snapshot{
+		Time:       time.Now(),
+		Config:     a.cfg.Redacted(),
+		Disks:      disk.Cache(),
+		Modules:    a.modules.Report(),
+		Goroutines: runtime.NumGoroutine(),
+	}
+	if a.fan != nil {
+		st := a.fan.Status()
+		snap.Fan = &st
+	}
+	if display := a.currentDisplay(); display != nil {
+		current, _ := display.CurrentPage()
+		snap.Display = &DisplaySnapshot{Pages: display.Pages(), Current: current}
+	}
+	return snap
+}
+
+// DumpState writes the snapshot with goroutine stacks to [debug] dump_path, or logs
+// it without them when no path is set
+func (a *App) DumpState() {
+	snap := a.Snapshot()
+	if a.cfg.Debug.DumpPath == "" {
+		data, err := json.Marshal(snap)
+		if err != nil {
+			logger.Errorf("Failed to encode state snapshot: %v", err)
+			return
+		}
+		// Logged as an error so it shows without verbose logging
+		logger.Errorf("State snapshot: %s", data)
+		return
+	}
+
+	var stacks bytes.Buffer
+	if err := pprof.Lookup("goroutine").WriteTo(&stacks, 1); err == nil {
+		snap.Stacks = stacks.String()
+	}
+	data, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		logger.Errorf("Failed to encode state snapshot: %v", err)
+		return
+	}
+	if err := os.WriteFile(a.cfg.Debug.DumpPath, append(data, '\n'), 0o600); err != nil {
+		logger.Errorf("Failed to write state snapshot: %v", err)
+		return
+	}
+	logger.Errorf("State snapshot written to %s", a.cfg.Debug.DumpPath)
+}
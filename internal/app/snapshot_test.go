@@ -0,0 +1,40 @@
+package app
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kolobock/rockpi-quad-go/internal/config"
+)
+
+func TestDumpState(t *testing.T) {
+	ff := newFakeFactories()
+	cfg := testConfig(config.ModulesConfig{})
+	cfg.Debug.DumpPath = filepath.Join(t.TempDir(), "state.json")
+	a := New(cfg, ff.factories())
+	a.fan = ff.fan
+	a.display = ff.display
+
+	a.DumpState()
+
+	data, err := os.ReadFile(cfg.Debug.DumpPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var snap Snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		t.Fatalf("dump is not a snapshot: %v", err)
+	}
+	if snap.Fan == nil || !snap.Fan.PWMHealthy {
+		t.Errorf("Fan = %+v, want the fan status", snap.Fan)
+	}
+	if snap.Display == nil || len(snap.Display.Pages) != 2 || snap.Display.Current != 1 {
+		t.Errorf("Display = %+v, want two pages on page 1", snap.Display)
+	}
+	if snap.Goroutines == 0 || !strings.Contains(snap.Stacks, "goroutine profile") {
+		t.Errorf("goroutines = %d with stacks %.40q", snap.Goroutines, snap.Stacks)
+	}
+}
@@ -0,0 +1,66 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"github.com/kolobock/rockpi-quad-go/internal/health"
+	"github.com/kolobock/rockpi-quad-go/internal/logger"
+)
+
+// Delays before a crashed module is restarted; the delay doubles with every restart
+// and starts over once the module ran for restartMaxDelay
+var (
+	restartDelay    = time.Second
+	restartMaxDelay = time.Minute
+)
+
+// errStopped is reported for a module that returned while it should be running
+var errStopped = errors.New("stopped unexpectedly")
+
+// supervise runs a module in stage s until the stage stops. When run panics, returns
+// an error or returns early, the module is reported failed and run is called again
+// after a backoff, so one crashed module does not leave the daemon half-working until
+// a reboot. Restarts are counted in the module health.
+func (a *App) supervise(s *stage, module string, run func(ctx context.Context) error) {
+	s.goRun(func() {
+		delay := restartDelay
+		for {
+			start := time.Now()
+			err := runGuarded(module, func() error { return run(s.ctx) })
+			if s.ctx.Err() != nil {
+				return
+			}
+			if err == nil {
+				err = errStopped
+			}
+			if time.Since(start) >= restartMaxDelay {
+				delay = restartDelay
+			}
+
+			a.modules.Set(module, health.StateFailed, fmt.Sprintf("%v; restarting in %s", err, delay))
+			select {
+			case <-s.ctx.Done():
+				return
+			case <-time.After(delay):
+			}
+			a.modules.Restarted(module)
+			logger.Errorf("Restarting module %s", module)
+			delay = min(delay*2, restartMaxDelay)
+		}
+	})
+}
+
+// runGuarded calls fn, turning a panic into an error after logging its stack
+func runGuarded(module string, fn func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Errorf("Module %s panicked: %v\n%s", module, r, debug.Stack())
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return fn()
+}
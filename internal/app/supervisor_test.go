@@ -0,0 +1,52 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kolobock/rockpi-quad-go/internal/config"
+	"github.com/kolobock/rockpi-quad-go/internal/health"
+)
+
+func TestSupervise(t *testing.T) {
+	restartDelay = time.Millisecond
+	defer func() { restartDelay = time.Second }()
+
+	a := New(testConfig(config.ModulesConfig{}), newFakeFactories().factories())
+	s := newStage("test")
+	var runs atomic.Int32
+	a.supervise(s, health.Fan, func(ctx context.Context) error {
+		switch runs.Add(1) {
+		case 1:
+			panic("nil map")
+		case 2:
+			return errors.New("pwm gone")
+		case 3:
+			// Returning while it should run counts as a crash too
+			return nil
+		}
+		a.modules.Set(health.Fan, health.StateOK, "")
+		<-ctx.Done()
+		return nil
+	})
+
+	deadline := time.Now().Add(time.Second)
+	for runs.Load() < 4 || a.modules.State(health.Fan) != health.StateOK {
+		if time.Now().After(deadline) {
+			t.Fatalf("module ran %d times, state %v, want running again after 3 crashes", runs.Load(), a.modules.State(health.Fan))
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	s.cancel()
+	s.wg.Wait()
+	if got := a.modules.Report().Modules[0].Restarts; got != 3 {
+		t.Errorf("restarts = %d, want 3", got)
+	}
+	if runs.Load() != 4 {
+		t.Errorf("runs = %d after the stage stopped, want 4", runs.Load())
+	}
+}
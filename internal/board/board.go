@@ -0,0 +1,160 @@
+package board
+
+import (
+	"os"
+	"strings"
+)
+
+var modelPath = "/proc/device-tree/model"
+
+// Profile holds hardware defaults for a known board. Defaults are keyed by the
+// environment variable they stand in for, so explicit env settings always win.
+type Profile struct {
+	Name     string
+	Models   []string
+	Defaults map[string]string
+	Notes    string
+
+	// resolve adjusts defaults that can only be determined at runtime
+	resolve func(defaults map[string]string)
+}
+
+// Profiles lists the boards with built-in defaults, matched by device-tree model prefix
+var Profiles = []Profile{
+	{
+		Name:   "rpi5",
+		Models: []string{"Raspberry Pi 5", "Raspberry Pi Compute Module 5"},
+		Defaults: map[string]string{
+			"I2C_BUS":         "1",
+			"OLED_RESET_CHIP": "4",
+			"BUTTON_CHIP":     "4",
+			"BUTTON_LINE":     "17",
+			"FAN_CHIP":        "4",
+			"FAN_LINE":        "27",
+			"SATA_CHIP":       "4",
+			"SATA_LINE_1":     "26",
+			"SATA_LINE_2":     "19",
+			"PWM_CHIP":        "pwmchip2",
+			"PWM_CPU_FAN":     "0",
+			"PWM_TB_FAN":      "1",
+		},
+		Notes: "Header GPIOs are on the RP1 controller (gpiochip4, or gpiochip0 on kernels >= 6.6.45) " +
+			"and are located by label. Fan PWM needs `dtoverlay=pwm-2chan,pin=12,func=4,pin2=13,func2=4` " +
+			"in /boot/firmware/config.txt, which exposes GPIO12/13 as channels 0/1 of the RP1 pwmchip (usually pwmchip2).",
+		resolve: resolveRPi5,
+	},
+	{
+		Name:   "rpi4",
+		Models: []string{"Raspberry Pi 4", "Raspberry Pi Compute Module 4", "Raspberry Pi 3"},
+		Defaults: map[string]string{
+			"I2C_BUS":     "1",
+			"BUTTON_CHIP": "0",
+			"BUTTON_LINE": "17",
+			"FAN_CHIP":    "0",
+			"FAN_LINE":    "27",
+			"SATA_CHIP":   "0",
+			"SATA_LINE_1": "26",
+			"SATA_LINE_2": "19",
+			"PWM_CHIP":    "pwmchip0",
+			"PWM_CPU_FAN": "0",
+			"PWM_TB_FAN":  "1",
+		},
+	},
+	{
+		Name:   "rockpi4",
+		Models: []string{"ROCK PI 4", "Radxa ROCK Pi 4", "Radxa ROCK 4"},
+		Defaults: map[string]string{
+			"I2C_BUS":     "7",
+			"BUTTON_CHIP": "4",
+			"BUTTON_LINE": "18",
+			"FAN_CHIP":    "4",
+			"FAN_LINE":    "27",
+			"SATA_CHIP":   "4",
+			"SATA_LINE_1": "22",
+			"SATA_LINE_2": "21",
+			"PWM_CHIP":    "pwmchip1",
+			"PWM_CPU_FAN": "0",
+			"PWM_TB_FAN":  "0",
+		},
+	},
+	{
+		Name:   "rock3a",
+		Models: []string{"Radxa ROCK3 Model A", "Radxa ROCK 3A", "Radxa ROCK 3 Model A"},
+		Defaults: map[string]string{
+			"I2C_BUS":     "3",
+			"BUTTON_CHIP": "3",
+			"BUTTON_LINE": "20",
+			"FAN_CHIP":    "0",
+			"FAN_LINE":    "13",
+			"SATA_CHIP":   "3",
+			"SATA_LINE_1": "1",
+			"SATA_LINE_2": "2",
+			"PWM_CHIP":    "pwmchip1",
+			"PWM_CPU_FAN": "0",
+			"PWM_TB_FAN":  "0",
+		},
+	},
+	{
+		Name:   "radxa-zero",
+		Models: []string{"Radxa Zero"},
+		Defaults: map[string]string{
+			"I2C_BUS":     "3",
+			"BUTTON_CHIP": "1",
+			"BUTTON_LINE": "75",
+			"FAN_CHIP":    "1",
+			"FAN_LINE":    "76",
+			"SATA_CHIP":   "1",
+			"SATA_LINE_1": "80",
+			"SATA_LINE_2": "81",
+			"PWM_CHIP":    "pwmchip0",
+			"PWM_CPU_FAN": "0",
+			"PWM_TB_FAN":  "0",
+		},
+	},
+}
+
+// Lookup returns the profile for a board by name or device-tree model
+func Lookup(nameOrModel string) (Profile, bool) {
+	nameOrModel = strings.TrimSpace(nameOrModel)
+	for _, p := range Profiles {
+		if strings.EqualFold(p.Name, nameOrModel) {
+			return p, true
+		}
+		for _, model := range p.Models {
+			if strings.HasPrefix(nameOrModel, model) {
+				return p, true
+			}
+		}
+	}
+	return Profile{}, false
+}
+
+// Resolve returns the profile defaults with runtime-detected values applied
+func (p Profile) Resolve() map[string]string {
+	defaults := make(map[string]string, len(p.Defaults))
+	for k, v := range p.Defaults {
+		defaults[k] = v
+	}
+	if p.resolve != nil {
+		p.resolve(defaults)
+	}
+	return defaults
+}
+
+// Model reads the device-tree model string of the running board
+func Model() string {
+	data, err := os.ReadFile(modelPath)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(strings.TrimRight(string(data), "\x00"))
+}
+
+// Detect selects the board profile. The BOARD environment variable forces a
+// profile by name; otherwise the device-tree model is matched.
+func Detect() (Profile, bool) {
+	if name := os.Getenv("BOARD"); name != "" {
+		return Lookup(name)
+	}
+	return Lookup(Model())
+}
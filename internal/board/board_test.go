@@ -0,0 +1,51 @@
+package board
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+		found bool
+	}{
+		{"Raspberry Pi 4 Model B Rev 1.4", "rpi4", true},
+		{"Raspberry Pi 3 Model B Plus Rev 1.3", "rpi4", true},
+		{"Radxa ROCK Pi 4B", "rockpi4", true},
+		{"Radxa ROCK3 Model A", "rock3a", true},
+		{"Radxa Zero", "radxa-zero", true},
+		{"ROCKPI4", "rockpi4", true},
+		{"Pine64 RockPro64 v2.1", "", false},
+		{"", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, ok := Lookup(tt.input)
+			if ok != tt.found || got.Name != tt.want {
+				t.Errorf("Lookup(%q) = %q, %v; want %q, %v", tt.input, got.Name, ok, tt.want, tt.found)
+			}
+		})
+	}
+}
+
+func TestDetect(t *testing.T) {
+	dir := t.TempDir()
+	modelPath = filepath.Join(dir, "model")
+	t.Cleanup(func() { modelPath = "/proc/device-tree/model" })
+
+	if err := os.WriteFile(modelPath, []byte("Radxa ROCK Pi 4B\x00"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if p, ok := Detect(); !ok || p.Name != "rockpi4" {
+		t.Errorf("Detect() = %q, %v; want rockpi4", p.Name, ok)
+	}
+
+	t.Setenv("BOARD", "rpi4")
+	if p, ok := Detect(); !ok || p.Name != "rpi4" {
+		t.Errorf("Detect() with BOARD = %q, %v; want rpi4", p.Name, ok)
+	}
+}
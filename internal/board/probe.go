@@ -0,0 +1,109 @@
+package board
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	i2c "github.com/d2r2/go-i2c"
+	i2cl "github.com/d2r2/go-logger"
+	"github.com/warthog618/go-gpiocdev"
+)
+
+const (
+	ssd1306Addr = 0x3C
+	// ssd1306NOP is a harmless command used to probe for the display
+	ssd1306NOP = 0xE3
+	// maxCandidateLines caps the candidate button lines listed per chip
+	maxCandidateLines = 16
+)
+
+var devDir = "/dev"
+
+// PWMChip describes a PWM controller exposed in sysfs
+type PWMChip struct {
+	Name     string
+	Device   string
+	Channels int
+}
+
+// GPIOChip describes a GPIO character device and its free input lines
+type GPIOChip struct {
+	Name       string
+	Label      string
+	Lines      int
+	Candidates []string
+}
+
+// I2CBuses lists the bus numbers of the available /dev/i2c-N devices
+func I2CBuses() []int {
+	paths, _ := filepath.Glob(filepath.Join(devDir, "i2c-*"))
+	var buses []int
+	for _, path := range paths {
+		if n, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(path), "i2c-")); err == nil {
+			buses = append(buses, n)
+		}
+	}
+	sort.Ints(buses)
+	return buses
+}
+
+// ProbeSSD1306 reports whether an SSD1306 acknowledges a NOP command on the bus
+func ProbeSSD1306(bus int) bool {
+	_ = i2cl.ChangePackageLogLevel("i2c", i2cl.ErrorLevel)
+
+	dev, err := i2c.NewI2C(ssd1306Addr, bus)
+	if err != nil {
+		return false
+	}
+	defer dev.Close()
+
+	_, err = dev.WriteBytes([]byte{0x00, ssd1306NOP})
+	return err == nil
+}
+
+// PWMChips lists the PWM controllers and their channel counts
+func PWMChips() []PWMChip {
+	paths, _ := filepath.Glob(filepath.Join(sysClassPWM, "pwmchip*"))
+	chips := make([]PWMChip, 0, len(paths))
+	for _, path := range paths {
+		chip := PWMChip{Name: filepath.Base(path)}
+		if data, err := os.ReadFile(filepath.Join(path, "npwm")); err == nil {
+			chip.Channels, _ = strconv.Atoi(strings.TrimSpace(string(data)))
+		}
+		if target, err := os.Readlink(filepath.Join(path, "device")); err == nil {
+			chip.Device = filepath.Base(target)
+		}
+		chips = append(chips, chip)
+	}
+	return chips
+}
+
+// GPIOChips lists the GPIO chips with their unused input lines as button candidates
+func GPIOChips() []GPIOChip {
+	var chips []GPIOChip
+	for _, name := range gpiocdev.Chips() {
+		c, err := gpiocdev.NewChip(name)
+		if err != nil {
+			continue
+		}
+
+		chip := GPIOChip{Name: name, Label: c.Label, Lines: c.Lines()}
+		for offset := 0; offset < c.Lines() && len(chip.Candidates) < maxCandidateLines; offset++ {
+			info, err := c.LineInfo(offset)
+			if err != nil || info.Used || info.Config.Direction != gpiocdev.LineDirectionInput {
+				continue
+			}
+			candidate := strconv.Itoa(offset)
+			if info.Name != "" {
+				candidate += "(" + info.Name + ")"
+			}
+			chip.Candidates = append(chip.Candidates, candidate)
+		}
+		c.Close()
+		chips = append(chips, chip)
+	}
+	return chips
+}
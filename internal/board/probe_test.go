@@ -0,0 +1,46 @@
+package board
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestI2CBuses(t *testing.T) {
+	dir := t.TempDir()
+	devDir = dir
+	t.Cleanup(func() { devDir = "/dev" })
+
+	for _, name := range []string{"i2c-7", "i2c-1", "i2c-10", "i2c-x"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got, want := I2CBuses(), []int{1, 7, 10}; !reflect.DeepEqual(got, want) {
+		t.Errorf("I2CBuses() = %v, want %v", got, want)
+	}
+}
+
+func TestPWMChips(t *testing.T) {
+	dir := t.TempDir()
+	sysClassPWM = dir
+	t.Cleanup(func() { sysClassPWM = "/sys/class/pwm" })
+
+	chip := filepath.Join(dir, "pwmchip1")
+	if err := os.MkdirAll(chip, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(chip, "npwm"), []byte("2\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("../../../ff420010.pwm", filepath.Join(chip, "device")); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []PWMChip{{Name: "pwmchip1", Device: "ff420010.pwm", Channels: 2}}
+	if got := PWMChips(); !reflect.DeepEqual(got, want) {
+		t.Errorf("PWMChips() = %+v, want %+v", got, want)
+	}
+}
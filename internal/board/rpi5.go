@@ -0,0 +1,62 @@
+package board
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/warthog618/go-gpiocdev"
+)
+
+const (
+	// rp1GPIOLabel is the label of the RP1 header GPIO controller on the Pi 5
+	rp1GPIOLabel = "pinctrl-rp1"
+	// rp1PWMDevice is the RP1 PWM0 block driving GPIO12/13
+	rp1PWMDevice = "1f00098000.pwm"
+)
+
+var sysClassPWM = "/sys/class/pwm"
+
+// resolveRPi5 locates the RP1 gpiochip and pwmchip. Their numbers depend on the
+// kernel: gpiochip4 before 6.6.45 and gpiochip0 after, pwmchip2 on most images.
+func resolveRPi5(defaults map[string]string) {
+	if chip := findGPIOChip(rp1GPIOLabel); chip != "" {
+		for _, key := range []string{"OLED_RESET_CHIP", "BUTTON_CHIP", "FAN_CHIP", "SATA_CHIP"} {
+			defaults[key] = chip
+		}
+	}
+	if chip := findPWMChip(rp1PWMDevice); chip != "" {
+		defaults["PWM_CHIP"] = chip
+	}
+}
+
+// findGPIOChip returns the number of the gpiochip with the given label
+func findGPIOChip(label string) string {
+	for _, name := range gpiocdev.Chips() {
+		chip, err := gpiocdev.NewChip(name)
+		if err != nil {
+			continue
+		}
+		matched := chip.Label == label
+		chip.Close()
+		if matched {
+			return strings.TrimPrefix(name, "gpiochip")
+		}
+	}
+	return ""
+}
+
+// findPWMChip returns the pwmchip whose device matches the given platform device name
+func findPWMChip(device string) string {
+	chips, _ := filepath.Glob(filepath.Join(sysClassPWM, "pwmchip*"))
+	for _, chip := range chips {
+		target, err := os.Readlink(filepath.Join(chip, "device"))
+		if err != nil {
+			continue
+		}
+		if filepath.Base(target) == device {
+			return filepath.Base(chip)
+		}
+	}
+	return ""
+}
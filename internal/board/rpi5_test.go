@@ -0,0 +1,52 @@
+package board
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFindPWMChip(t *testing.T) {
+	dir := t.TempDir()
+	sysClassPWM = dir
+	t.Cleanup(func() { sysClassPWM = "/sys/class/pwm" })
+
+	for chip, device := range map[string]string{
+		"pwmchip0": "../../../1f0009c000.pwm",
+		"pwmchip2": "../../../1f00098000.pwm",
+	} {
+		if err := os.MkdirAll(filepath.Join(dir, chip), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Symlink(device, filepath.Join(dir, chip, "device")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := findPWMChip(rp1PWMDevice); got != "pwmchip2" {
+		t.Errorf("findPWMChip() = %q, want pwmchip2", got)
+	}
+	if got := findPWMChip("fe20c000.pwm"); got != "" {
+		t.Errorf("findPWMChip() for missing device = %q, want empty", got)
+	}
+}
+
+func TestLookupRPi5(t *testing.T) {
+	p, ok := Lookup("Raspberry Pi 5 Model B Rev 1.0")
+	if !ok || p.Name != "rpi5" {
+		t.Fatalf("Lookup() = %q, %v; want rpi5", p.Name, ok)
+	}
+
+	defaults := p.Resolve()
+	if defaults["BUTTON_LINE"] != "17" {
+		t.Errorf("BUTTON_LINE = %q, want 17", defaults["BUTTON_LINE"])
+	}
+	if defaults["OLED_RESET_CHIP"] != defaults["BUTTON_CHIP"] {
+		t.Errorf("OLED_RESET_CHIP = %q, want the RP1 chip %q", defaults["OLED_RESET_CHIP"], defaults["BUTTON_CHIP"])
+	}
+	// Resolve must not modify the shared profile defaults
+	defaults["BUTTON_LINE"] = "1"
+	if p.Defaults["BUTTON_LINE"] != "17" {
+		t.Error("Resolve() returned the shared defaults map")
+	}
+}
@@ -18,29 +18,42 @@ type EventType string
 const (
 	Click       EventType = "click"
 	DoubleClick EventType = "twice"
+	TripleClick EventType = "triple"
 	LongPress   EventType = "press"
+	Hold        EventType = "hold"
 )
 
+// Valid reports whether e is one of the gestures a button reports
+func (e EventType) Valid() bool {
+	switch e {
+	case Click, DoubleClick, TripleClick, LongPress, Hold:
+		return true
+	}
+	return false
+}
+
 // Controller handles button press monitoring
 type Controller struct {
-	cfg         *config.Config
 	line        *gpiocdev.Line
 	pressChan   chan EventType
 	twiceWindow time.Duration
 	pressTime   time.Duration
+	holdTime    time.Duration
 	eventChan   chan gpiocdev.LineEvent
+	// onEdge sees every edge before it is classified, see OnEdge
+	onEdge func(pressed bool, at time.Duration)
 }
 
-// New creates a new button controller using chip and line number
-func New(cfg *config.Config) (*Controller, error) {
-	chip := cfg.Env.ButtonChip
-	line := cfg.Env.ButtonLine
-	twiceWindow := cfg.Time.Twice
-	pressTime := cfg.Time.Press
+// New creates a button controller for the chip and line of bc, detecting gestures with timing
+func New(bc config.ButtonConfig, timing config.TimeConfig) (*Controller, error) {
+	chip := bc.Chip
+	line := bc.Line
+	twiceWindow := timing.Twice
+	pressTime := timing.Press
 
 	if line == "" {
-		logger.Infoln("Button monitoring disabled - no pin configured")
-		return nil, fmt.Errorf("button monitoring disabled - no pin configured")
+		logger.Infof("Button %s monitoring disabled - no pin configured", bc.ID)
+		return nil, fmt.Errorf("button %s monitoring disabled - no pin configured", bc.ID)
 	}
 
 	if chip == "" {
@@ -63,26 +76,33 @@ func New(cfg *config.Config) (*Controller, error) {
 	}
 
 	ctrl := &Controller{
-		cfg:         cfg,
 		pressChan:   make(chan EventType, 10),
 		twiceWindow: time.Duration(twiceWindow * float64(time.Second)),
 		pressTime:   time.Duration(pressTime * float64(time.Second)),
+		holdTime:    time.Duration(timing.Hold * float64(time.Second)),
 	}
 
 	ctrl.eventChan = make(chan gpiocdev.LineEvent, 10)
 
 	eventHandler := func(evt gpiocdev.LineEvent) {
+		// The state machine treats a falling edge as a press
+		if bc.ActiveHigh {
+			evt.Type = invertEdge(evt.Type)
+		}
 		select {
 		case ctrl.eventChan <- evt:
 		default:
 		}
 	}
 
-	l, err := gpiocdev.RequestLine(chip, lineNum,
+	opts := []gpiocdev.LineReqOption{
 		gpiocdev.AsInput,
-		gpiocdev.WithPullUp,
+		biasOption(bc.Bias),
 		gpiocdev.WithBothEdges,
-		gpiocdev.WithEventHandler(eventHandler))
+		gpiocdev.WithEventHandler(eventHandler),
+	}
+	debounce := time.Duration(timing.Debounce * float64(time.Second))
+	l, err := requestLine(chip, lineNum, opts, debounce)
 	if err != nil {
 		logger.Errorf("Failed to request button line: %v", err)
 		return nil, fmt.Errorf("failed to request button line: %w", err)
@@ -93,125 +113,99 @@ func New(cfg *config.Config) (*Controller, error) {
 	for len(ctrl.eventChan) > 0 {
 		<-ctrl.eventChan
 	}
-	logger.Infof("Button monitoring enabled on %s line %s", chip, line)
+	logger.Infof("Button %s monitoring enabled on %s line %s", bc.ID, chip, line)
 	return ctrl, nil
 }
 
-// Run starts monitoring button presses and detects click/double-click/long-press
-func (c *Controller) Run(ctx context.Context) {
-	if c.line == nil {
-		<-ctx.Done()
-		return
-	}
-
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		default:
-			event := c.detectButtonEvent(ctx)
-			if event != "" {
-				select {
-				case c.pressChan <- event:
-					logger.Infof("Button event: %s", event)
-				default:
-					// Channel full, skip
-				}
-			}
+// requestLine requests the button line with kernel debouncing, falling back to an
+// undebounced line on kernels older than 5.10 that reject it
+func requestLine(chip string, lineNum int, opts []gpiocdev.LineReqOption, debounce time.Duration) (*gpiocdev.Line, error) {
+	if debounce > 0 {
+		l, err := gpiocdev.RequestLine(chip, lineNum, append(opts, gpiocdev.WithDebounce(debounce))...)
+		if err == nil {
+			return l, nil
 		}
+		logger.Errorf("Button debounce unsupported, continuing without it: %v", err)
 	}
+	return gpiocdev.RequestLine(chip, lineNum, opts...)
 }
 
-// detectButtonEvent waits for and detects the type of button press
-func (c *Controller) detectButtonEvent(ctx context.Context) EventType {
-	var pressStart time.Time
-	for {
-		select {
-		case <-ctx.Done():
-			return ""
-		case evt := <-c.eventChan:
-			if evt.Type == gpiocdev.LineEventFallingEdge {
-				pressStart = time.Now()
-				return c.handleButtonPress(ctx, pressStart)
-			}
-		case <-time.After(200 * time.Millisecond):
-			return ""
-		}
+func biasOption(bias string) gpiocdev.LineReqOption {
+	switch bias {
+	case "pull-down":
+		return gpiocdev.WithPullDown
+	case "disabled":
+		return gpiocdev.WithBiasDisabled
+	default:
+		return gpiocdev.WithPullUp
 	}
 }
 
-func (c *Controller) handleButtonPress(ctx context.Context, pressStart time.Time) EventType {
-	for {
-		select {
-		case <-ctx.Done():
-			return ""
-		case evt := <-c.eventChan:
-			if evt.Type == gpiocdev.LineEventRisingEdge {
-				return c.checkForDoubleClick(ctx)
-			}
-		case <-time.After(50 * time.Millisecond):
-			if time.Since(pressStart) >= c.pressTime {
-				return c.waitForLongPressRelease(ctx)
-			}
-		}
+// invertEdge swaps rising and falling edges for buttons that read high when pressed
+func invertEdge(t gpiocdev.LineEventType) gpiocdev.LineEventType {
+	switch t {
+	case gpiocdev.LineEventRisingEdge:
+		return gpiocdev.LineEventFallingEdge
+	case gpiocdev.LineEventFallingEdge:
+		return gpiocdev.LineEventRisingEdge
+	default:
+		return t
 	}
 }
 
-func (c *Controller) waitForLongPressRelease(ctx context.Context) EventType {
-	for {
-		select {
-		case <-ctx.Done():
-			return LongPress
-		case evt := <-c.eventChan:
-			if evt.Type == gpiocdev.LineEventRisingEdge {
-				return LongPress
-			}
-		case <-time.After(50 * time.Millisecond):
-		}
+// Run starts monitoring button presses and detects click/double-click/triple-click/long-press/hold
+func (c *Controller) Run(ctx context.Context) {
+	if c.line == nil {
+		<-ctx.Done()
+		return
 	}
+	c.detect(ctx)
 }
 
-func (c *Controller) checkForDoubleClick(ctx context.Context) EventType {
-	deadline := time.Now().Add(c.twiceWindow)
-	for time.Now().Before(deadline) {
-		select {
-		case <-ctx.Done():
-			return Click
-		case evt := <-c.eventChan:
-			if evt.Type == gpiocdev.LineEventFallingEdge {
-				return c.waitForSecondClickRelease(ctx)
-			}
-		case <-time.After(time.Until(deadline)):
-			return Click
-		}
-	}
-	return Click
-}
+// detect feeds line edges to the gesture detector. It sleeps until the next edge,
+// and only arms a timer while a click waits to see whether another one follows.
+func (c *Controller) detect(ctx context.Context) {
+	d := &detector{twiceWindow: c.twiceWindow, pressTime: c.pressTime, holdTime: c.holdTime}
+	var window <-chan time.Time
 
-func (c *Controller) waitForSecondClickRelease(ctx context.Context) EventType {
 	for {
 		select {
 		case <-ctx.Done():
-			return DoubleClick
+			return
 		case evt := <-c.eventChan:
-			if evt.Type == gpiocdev.LineEventRisingEdge {
-				c.drainEventChannel()
-				return DoubleClick
+			pressed := evt.Type == gpiocdev.LineEventFallingEdge
+			if c.onEdge != nil {
+				c.onEdge(pressed, evt.Timestamp)
 			}
-		case <-time.After(50 * time.Millisecond):
+			c.emit(d.edge(pressed, evt.Timestamp))
+			window = nil
+			if wait, ok := d.pending(evt.Timestamp); ok {
+				window = time.After(wait)
+			}
+		case <-window:
+			window = nil
+			c.emit(d.timeout())
 		}
 	}
 }
 
-// drainEventChannel clears any pending events from the event channel
-func (c *Controller) drainEventChannel() {
-	for {
-		select {
-		case <-c.eventChan:
-		default:
-			return
-		}
+func (c *Controller) emit(event EventType) {
+	if event == "" {
+		return
 	}
+	select {
+	case c.pressChan <- event:
+		logger.Infof("Button event: %s", event)
+	default:
+		// Channel full, skip
+	}
+}
+
+// OnEdge makes Run call fn with every press and release and its kernel timestamp
+// before the edge is classified, for diagnosing wiring and gesture timing. It must be
+// set before Run starts.
+func (c *Controller) OnEdge(fn func(pressed bool, at time.Duration)) {
+	c.onEdge = fn
 }
 
 // PressChan returns the channel that receives button press events
@@ -2,9 +2,12 @@ package button
 
 import (
 	"context"
+	"slices"
 	"testing"
 	"time"
 
+	"github.com/warthog618/go-gpiocdev"
+
 	"github.com/kolobock/rockpi-quad-go/internal/config"
 )
 
@@ -16,7 +19,9 @@ func TestEventType(t *testing.T) {
 	}{
 		{"click event", Click, "click"},
 		{"double click event", DoubleClick, "twice"},
+		{"triple click event", TripleClick, "triple"},
 		{"long press event", LongPress, "press"},
+		{"hold event", Hold, "hold"},
 	}
 
 	for _, tt := range tests {
@@ -29,21 +34,20 @@ func TestEventType(t *testing.T) {
 }
 
 func TestControllerCreation(t *testing.T) {
-	cfg := &config.Config{
-		Env: config.EnvConfig{
-			ButtonChip: "",
-			ButtonLine: "",
-		},
-		Time: config.TimeConfig{
-			Twice: 0.7,
-			Press: 1.8,
-		},
-	}
-
-	ctrl, err := New(cfg)
+	bc := config.ButtonConfig{
+		ID:   config.MainButton,
+		Chip: "",
+		Line: "",
+	}
+	timing := config.TimeConfig{
+		Twice: 0.7,
+		Press: 1.8,
+	}
+
+	ctrl, err := New(bc, timing)
 	if err != nil {
 		// Expected to fail when no GPIO pin is configured
-		if cfg.Env.ButtonLine == "" {
+		if bc.Line == "" {
 			t.Skip("Button monitoring disabled - no pin configured")
 		}
 		t.Fatalf("New failed: %v", err)
@@ -182,3 +186,163 @@ func TestControllerLifecycle(t *testing.T) {
 		t.Error("Goroutine did not complete after context cancellation")
 	}
 }
+
+func TestDetect(t *testing.T) {
+	ms := func(n int) time.Duration { return time.Duration(n) * time.Millisecond }
+	press := func(at int) gpiocdev.LineEvent {
+		return gpiocdev.LineEvent{Type: gpiocdev.LineEventFallingEdge, Timestamp: ms(at)}
+	}
+	release := func(at int) gpiocdev.LineEvent {
+		return gpiocdev.LineEvent{Type: gpiocdev.LineEventRisingEdge, Timestamp: ms(at)}
+	}
+
+	tests := []struct {
+		name   string
+		events []gpiocdev.LineEvent
+		want   []EventType
+	}{
+		{"click", []gpiocdev.LineEvent{press(0), release(50)}, []EventType{Click}},
+		{"double click", []gpiocdev.LineEvent{press(0), release(50), press(120), release(170)}, []EventType{DoubleClick}},
+		{"triple click", []gpiocdev.LineEvent{press(0), release(50), press(100), release(150), press(200), release(250)}, []EventType{TripleClick}},
+		{"long press", []gpiocdev.LineEvent{press(0), release(1500)}, []EventType{LongPress}},
+		{"hold", []gpiocdev.LineEvent{press(0), release(3500)}, []EventType{Hold}},
+		{"clicks outside the window", []gpiocdev.LineEvent{press(0), release(50), press(400), release(450)}, []EventType{Click, Click}},
+		{"long second press is still a double click", []gpiocdev.LineEvent{press(0), release(50), press(100), release(2000)}, []EventType{DoubleClick}},
+		{"stray release ignored", []gpiocdev.LineEvent{release(0), press(10), release(60)}, []EventType{Click}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := &Controller{
+				pressChan:   make(chan EventType, 10),
+				eventChan:   make(chan gpiocdev.LineEvent, 10),
+				twiceWindow: ms(200),
+				pressTime:   ms(1000),
+				holdTime:    ms(3000),
+			}
+			for _, evt := range tt.events {
+				ctrl.eventChan <- evt
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				ctrl.detect(ctx)
+			}()
+
+			var got []EventType
+			for range tt.want {
+				select {
+				case evt := <-ctrl.pressChan:
+					got = append(got, evt)
+				case <-time.After(time.Second):
+					t.Fatalf("timed out after events %v, want %v", got, tt.want)
+				}
+			}
+			cancel()
+			<-done
+
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("events = %v, want %v", got, tt.want)
+			}
+			select {
+			case extra := <-ctrl.pressChan:
+				t.Errorf("unexpected extra event %v", extra)
+			default:
+			}
+		})
+	}
+}
+
+func TestOnEdge(t *testing.T) {
+	ctrl := &Controller{
+		pressChan:   make(chan EventType, 10),
+		eventChan:   make(chan gpiocdev.LineEvent, 10),
+		twiceWindow: 20 * time.Millisecond,
+		pressTime:   time.Second,
+		holdTime:    3 * time.Second,
+	}
+	type edge struct {
+		pressed bool
+		at      time.Duration
+	}
+	var edges []edge
+	ctrl.OnEdge(func(pressed bool, at time.Duration) { edges = append(edges, edge{pressed, at}) })
+	ctrl.eventChan <- gpiocdev.LineEvent{Type: gpiocdev.LineEventFallingEdge, Timestamp: time.Second}
+	ctrl.eventChan <- gpiocdev.LineEvent{Type: gpiocdev.LineEventRisingEdge, Timestamp: time.Second + 80*time.Millisecond}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ctrl.detect(ctx)
+	}()
+	select {
+	case evt := <-ctrl.pressChan:
+		if evt != Click {
+			t.Errorf("event = %v, want click", evt)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no event")
+	}
+	cancel()
+	<-done
+
+	want := []edge{{true, time.Second}, {false, time.Second + 80*time.Millisecond}}
+	if !slices.Equal(edges, want) {
+		t.Errorf("edges = %v, want %v", edges, want)
+	}
+}
+
+func TestDetectorPending(t *testing.T) {
+	d := &detector{twiceWindow: 200 * time.Millisecond, pressTime: time.Second}
+
+	if _, ok := d.pending(0); ok {
+		t.Error("idle detector has a pending gesture")
+	}
+	d.edge(true, 0)
+	if _, ok := d.pending(10 * time.Millisecond); ok {
+		t.Error("pending gesture while the button is down")
+	}
+	d.edge(false, 50*time.Millisecond)
+	if wait, ok := d.pending(60 * time.Millisecond); !ok || wait != 190*time.Millisecond {
+		t.Errorf("pending() = %v, %v; want 190ms, true", wait, ok)
+	}
+	if got := d.timeout(); got != Click {
+		t.Errorf("timeout() = %v, want click", got)
+	}
+}
+
+func TestInvertEdge(t *testing.T) {
+	tests := []struct {
+		in, want gpiocdev.LineEventType
+	}{
+		{gpiocdev.LineEventRisingEdge, gpiocdev.LineEventFallingEdge},
+		{gpiocdev.LineEventFallingEdge, gpiocdev.LineEventRisingEdge},
+	}
+
+	for _, tt := range tests {
+		if got := invertEdge(tt.in); got != tt.want {
+			t.Errorf("invertEdge(%v) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestBiasOption(t *testing.T) {
+	tests := []struct {
+		bias string
+		want gpiocdev.LineReqOption
+	}{
+		{"pull-up", gpiocdev.WithPullUp},
+		{"pull-down", gpiocdev.WithPullDown},
+		{"disabled", gpiocdev.WithBiasDisabled},
+		{"", gpiocdev.WithPullUp},
+	}
+
+	for _, tt := range tests {
+		if got := biasOption(tt.bias); got != tt.want {
+			t.Errorf("biasOption(%q) = %v, want %v", tt.bias, got, tt.want)
+		}
+	}
+}
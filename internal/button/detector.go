@@ -0,0 +1,82 @@
+package button
+
+import "time"
+
+// detector recognizes gestures from timestamped press and release edges. It keeps
+// no clock of its own: edge timestamps come from the kernel, and the caller reports
+// when the click window returned by pending has passed without another press.
+type detector struct {
+	twiceWindow time.Duration
+	pressTime   time.Duration
+	holdTime    time.Duration
+
+	down      bool
+	pressAt   time.Duration
+	releaseAt time.Duration
+	clicks    int
+}
+
+// edge feeds one press (pressed) or release edge at kernel timestamp ts and returns
+// the gesture it completes, if any
+func (d *detector) edge(pressed bool, ts time.Duration) EventType {
+	var done EventType
+	// A press after the click window closes starts a new gesture; finish the old one
+	// first in case the caller's timer has not fired yet
+	if pressed && !d.down && d.clicks > 0 && ts-d.releaseAt > d.twiceWindow {
+		done = d.timeout()
+	}
+
+	switch {
+	case pressed && !d.down:
+		d.down = true
+		d.pressAt = ts
+	case !pressed && d.down:
+		d.down = false
+		held := ts - d.pressAt
+		if d.clicks == 0 && held >= d.pressTime {
+			d.reset()
+			if d.holdTime > d.pressTime && held >= d.holdTime {
+				return Hold
+			}
+			return LongPress
+		}
+		d.clicks++
+		d.releaseAt = ts
+		if d.clicks == 3 {
+			d.reset()
+			return TripleClick
+		}
+	}
+	return done
+}
+
+// pending returns how long after timestamp now the current click gesture resolves
+// unless another press arrives; false while the button is down or idle
+func (d *detector) pending(now time.Duration) (time.Duration, bool) {
+	if d.down || d.clicks == 0 {
+		return 0, false
+	}
+	return max(d.releaseAt+d.twiceWindow-now, 0), true
+}
+
+// timeout resolves a click gesture whose window passed without another press
+func (d *detector) timeout() EventType {
+	if d.down {
+		return ""
+	}
+	clicks := d.clicks
+	d.reset()
+	switch clicks {
+	case 1:
+		return Click
+	case 2:
+		return DoubleClick
+	default:
+		return ""
+	}
+}
+
+func (d *detector) reset() {
+	d.down = false
+	d.clicks = 0
+}
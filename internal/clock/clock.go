@@ -0,0 +1,102 @@
+// Package clock tells the wall-clock time used by schedules and the display.
+// Go reads the system timezone once at startup, so without a configured zone
+// the clock re-reads /etc/localtime whenever it changes (e.g. timedatectl).
+package clock
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kolobock/rockpi-quad-go/internal/logger"
+)
+
+// localtimePath is replaced in tests
+var localtimePath = "/etc/localtime"
+
+// Clock converts instants to the configured or current system timezone
+type Clock struct {
+	mu    sync.Mutex
+	fixed bool
+	loc   *time.Location
+	stamp string
+}
+
+// New returns a clock for the IANA zone name (e.g. "Europe/Berlin"). An empty
+// name follows the system localtime; an unknown one is logged and does the same.
+func New(name string) *Clock {
+	if name != "" {
+		loc, err := time.LoadLocation(name)
+		if err == nil {
+			return &Clock{fixed: true, loc: loc}
+		}
+		logger.Errorf("Unknown timezone %q, using system localtime: %v", name, err)
+	}
+	// TZ in the environment takes precedence over /etc/localtime and cannot change
+	if _, ok := os.LookupEnv("TZ"); ok {
+		return &Clock{fixed: true, loc: time.Local}
+	}
+	return &Clock{loc: time.Local}
+}
+
+// Now returns the current time in the clock's zone
+func (c *Clock) Now() time.Time {
+	return c.In(time.Now())
+}
+
+// In returns t in the clock's zone
+func (c *Clock) In(t time.Time) time.Time {
+	return t.In(c.Location())
+}
+
+// Location returns the zone, reloading the system localtime if it was replaced
+func (c *Clock) Location() *time.Location {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.fixed {
+		return c.loc
+	}
+
+	stamp, err := localtimeStamp()
+	if err != nil || stamp == c.stamp {
+		return c.loc
+	}
+	data, err := os.ReadFile(localtimePath)
+	if err != nil {
+		return c.loc
+	}
+	loc, err := time.LoadLocationFromTZData(zoneName(), data)
+	if err != nil {
+		logger.Errorf("Failed to load %s: %v", localtimePath, err)
+		return c.loc
+	}
+	if c.stamp != "" {
+		logger.Infof("System timezone changed, now %s", time.Now().In(loc).Format("MST -0700"))
+	}
+	c.stamp = stamp
+	c.loc = loc
+	return c.loc
+}
+
+// localtimeStamp identifies the current /etc/localtime: its symlink target, size
+// and modification time all change when the zone is switched
+func localtimeStamp() (string, error) {
+	fi, err := os.Stat(localtimePath)
+	if err != nil {
+		return "", err
+	}
+	target, _ := os.Readlink(localtimePath)
+	return fmt.Sprintf("%s:%d:%d", target, fi.Size(), fi.ModTime().UnixNano()), nil
+}
+
+// zoneName names a location loaded from /etc/localtime after its symlink target
+func zoneName() string {
+	target, _ := os.Readlink(localtimePath)
+	if _, name, ok := strings.Cut(target, "zoneinfo/"); ok && name != "" {
+		return name
+	}
+	return "Local"
+}
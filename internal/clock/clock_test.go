@@ -0,0 +1,68 @@
+package clock
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFixedZone(t *testing.T) {
+	c := New("Europe/Berlin")
+	if c.Location().String() != "Europe/Berlin" {
+		t.Fatalf("Location() = %v, want Europe/Berlin", c.Location())
+	}
+
+	tests := []struct {
+		utc  time.Time
+		want string
+	}{
+		{time.Date(2026, 3, 29, 0, 59, 0, 0, time.UTC), "01:59 CET"},
+		{time.Date(2026, 3, 29, 1, 0, 0, 0, time.UTC), "03:00 CEST"},
+		{time.Date(2026, 10, 25, 1, 0, 0, 0, time.UTC), "02:00 CET"},
+	}
+	for _, tt := range tests {
+		if got := c.In(tt.utc).Format("15:04 MST"); got != tt.want {
+			t.Errorf("In(%v) = %s, want %s", tt.utc, got, tt.want)
+		}
+	}
+}
+
+func TestUnknownZoneFallsBack(t *testing.T) {
+	c := New("Mars/Olympus_Mons")
+	if c.Location() == nil {
+		t.Fatal("Location() = nil, want system localtime")
+	}
+}
+
+func TestFollowsSystemLocaltime(t *testing.T) {
+	const zoneinfo = "/usr/share/zoneinfo/"
+	if _, err := os.Stat(zoneinfo + "Europe/Berlin"); err != nil {
+		t.Skip("zoneinfo database not installed")
+	}
+	t.Setenv("TZ", "")
+	_ = os.Unsetenv("TZ")
+
+	orig := localtimePath
+	defer func() { localtimePath = orig }()
+	localtimePath = filepath.Join(t.TempDir(), "localtime")
+
+	link := func(zone string) {
+		t.Helper()
+		_ = os.Remove(localtimePath)
+		if err := os.Symlink(zoneinfo+zone, localtimePath); err != nil {
+			t.Fatalf("failed to link localtime: %v", err)
+		}
+	}
+
+	link("Europe/Berlin")
+	c := New("")
+	if got := c.Location().String(); got != "Europe/Berlin" {
+		t.Fatalf("Location() = %s, want Europe/Berlin", got)
+	}
+
+	link("America/New_York")
+	if got := c.Location().String(); got != "America/New_York" {
+		t.Errorf("after change Location() = %s, want America/New_York", got)
+	}
+}
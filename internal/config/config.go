@@ -2,36 +2,73 @@ package config
 
 import (
 	"fmt"
+	"maps"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
 
 	"gopkg.in/ini.v1"
+
+	"github.com/kolobock/rockpi-quad-go/internal/board"
+	"github.com/kolobock/rockpi-quad-go/internal/snmp"
+	"github.com/kolobock/rockpi-quad-go/internal/thermal"
 )
 
+// DefaultPath is where the daemon reads its configuration
+const DefaultPath = "/etc/rockpi-quad.conf"
+
 type Config struct {
-	Fan     FanConfig
-	OLED    OLEDConfig
-	Disk    DiskConfig
-	Network NetworkConfig
-	Key     KeyConfig
-	Slider  SliderConfig
-	Time    TimeConfig
-	Env     EnvConfig
+	// Path is the file the configuration was loaded from
+	Path      string
+	Fan       FanConfig
+	OLED      OLEDConfig
+	Disk      DiskConfig
+	Network   NetworkConfig
+	Key       KeyConfig
+	Buttons   []ButtonConfig
+	HATs      []HATConfig
+	Slider    SliderConfig
+	Time      TimeConfig
+	Temp      TemperatureConfig
+	Kernel    KernelConfig
+	RAID      RAIDConfig
+	UPS       UPSConfig
+	Rails     RailsConfig
+	LUKS      LUKSConfig
+	API       APIConfig
+	Heartbeat HeartbeatConfig
+	Store     StoreConfig
+	Status    StatusFileConfig
+	SNMP      SNMPConfig
+	Alerts    AlertsConfig
+	SMTP      SMTPConfig
+	Shutdown  ShutdownConfig
+	Debug     DebugConfig
+	Modules   ModulesConfig
+	Env       EnvConfig
+
+	// envDefaults are the detected board's hardware defaults, keyed by env variable
+	envDefaults map[string]string
+	// envVars records every env variable read, see EnvVars
+	envVars map[string]EnvVar
 }
 
 type EnvConfig struct {
-	SDA         string
-	SCL         string
-	OLEDReset   string
-	ButtonChip  string
-	ButtonLine  string
-	FanChip     string
-	FanLine     string
-	HardwarePWM string
-	SATAChip    string
-	SATALine1   string
-	SATALine2   string
+	Board         string
+	I2CBus        int
+	SDA           string
+	SCL           string
+	OLEDReset     string
+	OLEDResetChip string
+	ButtonChip    string
+	ButtonLine    string
+	FanChip       string
+	FanLine       string
+	HardwarePWM   string
+	SATAChip      string
+	SATALine1     string
+	SATALine2     string
 }
 
 type FanConfig struct {
@@ -40,39 +77,232 @@ type FanConfig struct {
 	LV0F, LV1F, LV2F, LV3F  float64
 	MaxCPUTemp, MaxDiskTemp float64
 
+	Emergency        bool
+	EmergencySeconds float64
+	EmergencyCommand string
+
+	SensorFailures int
+	SafeDuty       float64
+
+	CPUSensors    []string
+	CPUSensorMode string
+
 	Linear    bool
 	TempDisks bool
 	Syslog    bool
 
+	Profile         string
+	Schedule        []string
+	OverrideMinutes int
+
+	SmoothingSeconds int
+	Interval         int
+	MinChange        float64
+
+	// PredictSeconds extrapolates rising temperatures this far ahead, 0 = off
+	PredictSeconds float64
+	PredictWindow  float64
+	PredictDecay   float64
+
+	KickstartSeconds   float64
+	KickstartThreshold float64
+
+	// BurstMargin starts burst sampling this many °C below max_cpu_temp/max_disk_temp, 0 = off
+	BurstMargin       float64
+	BurstInterval     float64
+	BurstDiskInterval float64
+	BurstCooldown     float64
+
+	DiskTempMode    string
+	DiskTempOffsets map[string]float64
+	DiskTempWeights map[string]float64
+
 	CPUPWMChip    string
 	CPUPWMChannel int
 	TBPWMChip     string
 	TBPWMChannel  int
 	HardwarePWM   bool
-	Polarity      string
+	CPUPolarity   string
+	TBPolarity    string
+	PWMFrequency  int
+
+	Mode        string
+	GPIOOnTemp  float64
+	GPIOOffTemp float64
+	// AutoObserve switches pwm mode to observe when a fan daemon such as fancontrol runs
+	AutoObserve bool
+	// ObserveCPUPWM and ObserveDiskPWM are hwmon pwmN files read in observe mode
+	// instead of the PWM channels
+	ObserveCPUPWM  string
+	ObserveDiskPWM string
+	// DryRun computes and reports duty cycles without writing them to the fans
+	DryRun bool
 }
 
 type OLEDConfig struct {
-	Enabled    bool
-	Rotate     bool
+	Enabled bool
+	// Rotate turns the picture clockwise by 0, 90, 180 or 270 degrees
+	Rotate     int
 	Fahrenheit bool
+	Hostname   string
+	Clock      bool
+	LargeClock bool
+	// NotifyTime is how long notification banners stay over the page, in seconds
+	NotifyTime int
+	// SleepAfter turns the panel off after this many idle seconds; 0 keeps it on
+	SleepAfter int
+	// Scroll scrolls text wider than its column at ScrollSpeed pixels per second
+	// instead of cutting it short
+	Scroll      bool
+	ScrollSpeed float64
+	// RenderBudget is the time in milliseconds a page switch may take to build and
+	// transmit the page before the page is reported as slow, 0 = off
+	RenderBudget int
+	// Prefetch collects the next page's data while the current page is shown
+	Prefetch bool
+	// Welcome and Goodbye are shown for WelcomeTime and GoodbyeTime seconds when the
+	// display starts and stops, "|" separating lines; 0 seconds skips them
+	Welcome     string
+	WelcomeTime int
+	Goodbye     string
+	GoodbyeTime int
+	// DataInterval is how often in seconds the disk usage and temperature pages
+	// refresh their df and smartctl readings in the background
+	DataInterval int
+	// Contrast is the panel contrast (0-255), ContrastSchedule switches it by time of day
+	Contrast         int
+	ContrastSchedule []string
+	// LightSensor is "none" or "bh1750"; with a sensor the contrast is scaled down to
+	// ContrastMin in the dark
+	LightSensor  string
+	LightAddress int
+	ContrastMin  int
+	// Height is the panel height in pixels, 32 or 64
+	Height int
+	// Lang is the language of the page labels, e.g. "de"; English when not bundled
+	Lang string
+	// Font is a TrueType font file used instead of the embedded font, for a Lang
+	// the embedded font has no glyphs for
+	Font        string
+	CustomPages []CustomPage
+}
+
+// CustomPage is a static text page defined by a [page.<name>] section
+type CustomPage struct {
+	Name  string
+	Lines []string
 }
 
 type DiskConfig struct {
 	SpaceUsageMountPoints []string
 	IOUsageMountPoints    []string
-	DisksTemperature      bool
+	// IOUsageDevices are block devices (sda, nvme0n1, md0) with their own I/O page
+	IOUsageDevices   []string
+	DisksTemperature bool
+	PowerStats       bool
+	PowerInterval    int
+	LinkErrors       bool
+	LinkInterval     int
+	SMARTHealth      bool
+	SMARTInterval    int
+	// Inventory shows model, serial, capacity and link speed of each disk
+	Inventory bool
+	// Aliases maps disk names (sda, nvme0n1) to the names shown on the display
+	Aliases map[string]string
+	// Bays are the physical drive slots from [bay.<n>] sections
+	Bays []BayConfig
 }
 
 type NetworkConfig struct {
 	Interfaces []string
 	SkipPage   bool
+	// SkipDown passes over the pages of interfaces without carrier
+	SkipDown bool
+	// Aggregate adds a page with the combined rates of all interfaces
+	Aggregate   bool
+	LinkMonitor bool
+	MinSpeed    int
+
+	Checks        []string
+	CheckInterval int
+	CheckFailures int
+
+	IPNotify bool
+	// IPFamily selects the addresses shown on the first page: ipv4, ipv6 or both
+	IPFamily string
+}
+
+// BayConfig is a physical drive slot, matched to the disk in it by serial number
+// and/or SATA port; when both are set both must match
+type BayConfig struct {
+	ID string
+	// Label replaces the disk name on pages and in alerts, "Bay <id>" by default
+	Label  string
+	Serial string
+	// Port is the libata port of the slot, e.g. ata2
+	Port string
+}
+
+// MainButton is the ID of the button configured by BUTTON_CHIP/BUTTON_LINE and [key]
+const MainButton = "main"
+
+// ButtonConfig is one front panel button and the actions bound to its gestures
+type ButtonConfig struct {
+	ID   string
+	Chip string
+	Line string
+	// Bias is the line's internal resistor: pull-up, pull-down or disabled
+	Bias string
+	// ActiveHigh is set when the line reads high while the button is pressed
+	ActiveHigh bool
+	Keys       KeyConfig
+}
+
+// MainHAT is the ID of the HAT configured by SATA_CHIP/SATA_LINE_* and PWM_TB_*
+const MainHAT = "main"
+
+// HATConfig is one quad SATA HAT of a stack, with its own SATA controller enable
+// lines, top-board fan and disks
+type HATConfig struct {
+	ID string
+	// Label names the HAT on pages and in metrics, "HAT <id>" by default
+	Label     string
+	SATAChip  string
+	SATALine1 string
+	SATALine2 string
+	// PWMChip, PWMChannel and Polarity drive the HAT's top-board fan
+	PWMChip    string
+	PWMChannel int
+	Polarity   string
+	// Ports are the libata ports of the HAT's SATA controller, e.g. ata5; disks on
+	// no other HAT's ports belong to the main HAT
+	Ports []string
+}
+
+var buttonBiases = []string{"pull-up", "pull-down", "disabled"}
+
+// buttonBias returns the configured bias, defaulting to a pull towards the released level
+func buttonBias(bias string, activeHigh bool) string {
+	if slices.Contains(buttonBiases, bias) {
+		return bias
+	}
+	if activeHigh {
+		return "pull-down"
+	}
+	return "pull-up"
 }
 
 type KeyConfig struct {
-	Click string
-	Twice string
-	Press string
+	Click  string
+	Twice  string
+	Triple string
+	Press  string
+	Hold   string
+	Unlock string
+	// ConfirmPower asks for the gesture to be repeated before a button powers off or reboots
+	ConfirmPower bool
+	// ScriptTimeout stops commands run by button actions after this many seconds
+	ScriptTimeout float64
 }
 
 type SliderConfig struct {
@@ -80,14 +310,222 @@ type SliderConfig struct {
 	Time int
 }
 
+type KernelConfig struct {
+	Watch bool
+}
+
+type RAIDConfig struct {
+	// MDStat enables monitoring of md arrays in /proc/mdstat
+	MDStat bool
+	// ZFS enables monitoring of ZFS pools with `zpool status -x`
+	ZFS      bool
+	Interval int
+	// Webhook receives a JSON POST when an array becomes degraded
+	Webhook string
+}
+
+// UPSConfig is the UPS or battery HAT whose charge triggers a safe poweroff
+type UPSConfig struct {
+	// Source is "none", "nut" (upsc) or "max17048" (I2C fuel gauge)
+	Source string
+	// Name is the NUT UPS name passed to upsc
+	Name     string
+	Address  int
+	Interval int
+	// ShutdownCharge is the charge in percent on battery that powers the system off, 0 = off
+	ShutdownCharge float64
+}
+
+// RailsConfig is the HAT power rail sensing that alerts on brown-outs
+type RailsConfig struct {
+	// Interval is how often ADC rails are sampled, in seconds
+	Interval float64
+	// Sensors come from [rail.<name>] sections
+	Sensors []RailConfig
+}
+
+// RailConfig is one power rail watched through a power-good GPIO line, an IIO ADC
+// channel or an INA219/INA3221 power monitor
+type RailConfig struct {
+	Name string
+	// GPIO is the chip:line of a power-good signal, high while the rail is good
+	GPIO      string
+	ActiveLow bool
+	// ADC is an IIO voltage channel such as iio:device0/in_voltage0
+	ADC string
+	// Divider is the ratio of the resistor divider in front of the ADC input
+	Divider float64
+	// Min is the lowest good voltage, 0 = only report the reading
+	Min float64
+	// INA is a power monitor such as ina219@0x40 or ina3221@0x40:2 (channel)
+	INA string
+	// Shunt is the shunt resistance of the power monitor in ohms
+	Shunt float64
+	// MaxCurrent is the highest good current in amps, 0 = off
+	MaxCurrent float64
+}
+
+// LUKSConfig lists the encrypted volumes shown on the LUKS page
+type LUKSConfig struct {
+	Volumes []LUKSVolume
+	// Keyfile opens locked volumes from the luks button action
+	Keyfile string
+}
+
+// LUKSVolume is a LUKS device and the /dev/mapper name it opens as
+type LUKSVolume struct {
+	Name   string
+	Device string
+}
+
+type APIConfig struct {
+	Enabled       bool
+	Listen        string
+	ReadTokens    []string
+	ControlTokens []string
+	TLSCert       string
+	TLSKey        string
+	ClientCA      string
+	// PProf serves net/http/pprof and expvar counters under /debug/
+	PProf bool
+}
+
+// ModulesConfig switches whole subsystems on or off; disabled modules are never initialized
+type ModulesConfig struct {
+	Fan         bool
+	OLED        bool
+	Button      bool
+	API         bool
+	Metrics     bool
+	Alerts      bool
+	DiskMonitor bool
+	// RetrySeconds is how long the fan PWM and OLED I2C devices are retried at startup
+	RetrySeconds int
+	// ShutdownTimeout is how long in seconds the modules get to stop
+	ShutdownTimeout int
+}
+
+type AlertsConfig struct {
+	// Mute suppresses alert pop-ups on the OLED; alerts are still logged
+	Mute bool
+	// Webhooks, TelegramToken/TelegramChat and Command receive every alert
+	Webhooks      []string
+	TelegramToken string
+	TelegramChat  string
+	Command       string
+	// Cooldown drops repeats of the same alert for this many seconds
+	Cooldown int
+	// Interval is how often the rules below are checked, in seconds
+	Interval int
+	// CPUTemp and DiskTemp alert after the temperature stayed at or above them for
+	// CPUTempSeconds or DiskTempSeconds; 0 disables the rule
+	CPUTemp         float64
+	CPUTempSeconds  int
+	DiskTemp        float64
+	DiskTempSeconds int
+	// CPUFanTach and DiskFanTach are hwmon fanN_input files; a driven fan reading
+	// 0 RPM for FanStallSeconds has stalled
+	CPUFanTach      string
+	DiskFanTach     string
+	FanStallSeconds int
+	// DiskRemoved alerts when a SATA disk disappears
+	DiskRemoved bool
+}
+
+// SMTPConfig is the mail server that alerts are emailed through; an empty Host disables it
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+	To       []string
+	// TLS is "starttls", "tls" (implicit TLS) or "none"
+	TLS string
+	// Subject and Body are text/template templates; empty uses the defaults
+	Subject string
+	Body    string
+	// Events are the alert rules that are mailed, "all" for every alert
+	Events []string
+}
+
+// ShutdownConfig is the sequence run before poweroff and reboot
+type ShutdownConfig struct {
+	Services    []string
+	Unmount     []string
+	Spindown    bool
+	StepTimeout int
+	// FinalMessage is left on the OLED after poweroff instead of blanking it
+	FinalMessage string
+	// PowerGPIO is the chip:line that cuts the HAT power rails after poweroff
+	PowerGPIO          string
+	PowerGPIOActiveLow bool
+	PowerGPIOHold      float64
+}
+
+// StatusFileConfig is the JSON status file written for other tools
+type StatusFileConfig struct {
+	Enabled bool
+	Path    string
+	// Interval is how often the file is rewritten, in seconds
+	Interval int
+}
+
+// SNMPConfig is the read-only SNMPv2c agent
+type SNMPConfig struct {
+	Enabled   bool
+	Listen    string
+	Community string
+	// BaseOID is the root of the published tree
+	BaseOID string
+}
+
+// DebugConfig holds field debugging aids
+type DebugConfig struct {
+	// DumpPath receives the state snapshot written on SIGUSR1; empty logs it instead
+	DumpPath string
+}
+
+type HeartbeatConfig struct {
+	Kind     string
+	URL      string
+	Interval int
+}
+
+// StoreConfig is the long-term metrics database; an empty Path disables it
+type StoreConfig struct {
+	Path          string
+	RetentionDays int
+	// Interval is how often readings are sampled, in seconds
+	Interval int
+}
+
+// TemperatureConfig sets how each surface presents temperatures
+type TemperatureConfig struct {
+	OLED thermal.Format
+	API  thermal.Format
+	Log  thermal.Format
+}
+
 type TimeConfig struct {
 	Twice float64
 	Press float64
+	Hold  float64
+	// Debounce is the kernel debounce period of button lines in seconds, 0 = off
+	Debounce float64
+	// Confirm is how long a poweroff/reboot confirmation waits for the repeated gesture
+	Confirm float64
+	// Timezone is the IANA zone for schedules and the display, "" = system localtime
+	Timezone string
 }
 
 func Load(path string) (*Config, error) {
-	cfg := &Config{}
+	cfg := &Config{Path: path}
 
+	if profile, ok := board.Detect(); ok {
+		cfg.Env.Board = profile.Name
+		cfg.envDefaults = profile.Resolve()
+	}
 	loadEnvConfig(cfg)
 
 	iniFile, err := ini.Load(path)
@@ -100,24 +538,81 @@ func Load(path string) (*Config, error) {
 	loadDiskConfig(cfg, iniFile)
 	loadNetworkConfig(cfg, iniFile)
 	loadKeyConfig(cfg, iniFile)
+	loadButtons(cfg, iniFile)
+	loadHATs(cfg, iniFile)
 	loadTimeConfig(cfg, iniFile)
 	loadSliderConfig(cfg, iniFile)
+	loadTemperatureConfig(cfg, iniFile)
+	loadKernelConfig(cfg, iniFile)
+	loadRAIDConfig(cfg, iniFile)
+	loadUPSConfig(cfg, iniFile)
+	loadRailsConfig(cfg, iniFile)
+	loadLUKSConfig(cfg, iniFile)
+	loadAPIConfig(cfg, iniFile)
+	loadHeartbeatConfig(cfg, iniFile)
+	loadStoreConfig(cfg, iniFile)
+	loadStatusFileConfig(cfg, iniFile)
+	loadSNMPConfig(cfg, iniFile)
+	loadAlertsConfig(cfg, iniFile)
+	loadSMTPConfig(cfg, iniFile)
+	loadShutdownConfig(cfg, iniFile)
+	loadDebugConfig(cfg, iniFile)
+	loadModulesConfig(cfg, iniFile)
 
 	return cfg, nil
 }
 
+// getenv returns an environment variable, falling back to the detected board's default
+func (cfg *Config) getenv(key string) string {
+	if cfg.envVars == nil {
+		cfg.envVars = map[string]EnvVar{}
+	}
+	v := EnvVar{Value: os.Getenv(key), Source: EnvFromEnvironment}
+	if v.Value == "" {
+		v.Source = EnvUnset
+		if def, ok := cfg.envDefaults[key]; ok {
+			v = EnvVar{Value: def, Source: EnvFromBoard}
+		}
+	}
+	cfg.envVars[key] = v
+	return v.Value
+}
+
+// Sources of the env variables in EnvVar
+const (
+	EnvFromEnvironment = "environment"
+	EnvFromBoard       = "board profile"
+	EnvUnset           = "unset"
+)
+
+// EnvVar is the value an env variable was read as and where it came from
+type EnvVar struct {
+	Value  string
+	Source string
+}
+
+// EnvVars returns every env variable the configuration read
+func (cfg *Config) EnvVars() map[string]EnvVar {
+	return maps.Clone(cfg.envVars)
+}
+
 func loadEnvConfig(cfg *Config) {
-	cfg.Env.SDA = os.Getenv("SDA")
-	cfg.Env.SCL = os.Getenv("SCL")
-	cfg.Env.OLEDReset = os.Getenv("OLED_RESET")
-	cfg.Env.ButtonChip = os.Getenv("BUTTON_CHIP")
-	cfg.Env.ButtonLine = os.Getenv("BUTTON_LINE")
-	cfg.Env.FanChip = os.Getenv("FAN_CHIP")
-	cfg.Env.FanLine = os.Getenv("FAN_LINE")
-	cfg.Env.HardwarePWM = os.Getenv("HARDWARE_PWM")
-	cfg.Env.SATAChip = os.Getenv("SATA_CHIP")
-	cfg.Env.SATALine1 = os.Getenv("SATA_LINE_1")
-	cfg.Env.SATALine2 = os.Getenv("SATA_LINE_2")
+	cfg.Env.I2CBus = 1
+	if bus, err := strconv.Atoi(cfg.getenv("I2C_BUS")); err == nil {
+		cfg.Env.I2CBus = bus
+	}
+	cfg.Env.SDA = cfg.getenv("SDA")
+	cfg.Env.SCL = cfg.getenv("SCL")
+	cfg.Env.OLEDReset = cfg.getenv("OLED_RESET")
+	cfg.Env.OLEDResetChip = cfg.getenv("OLED_RESET_CHIP")
+	cfg.Env.ButtonChip = cfg.getenv("BUTTON_CHIP")
+	cfg.Env.ButtonLine = cfg.getenv("BUTTON_LINE")
+	cfg.Env.FanChip = cfg.getenv("FAN_CHIP")
+	cfg.Env.FanLine = cfg.getenv("FAN_LINE")
+	cfg.Env.HardwarePWM = cfg.getenv("HARDWARE_PWM")
+	cfg.Env.SATAChip = cfg.getenv("SATA_CHIP")
+	cfg.Env.SATALine1 = cfg.getenv("SATA_LINE_1")
+	cfg.Env.SATALine2 = cfg.getenv("SATA_LINE_2")
 }
 
 func loadFanConfig(cfg *Config, iniFile *ini.File) {
@@ -139,30 +634,177 @@ func loadFanConfig(cfg *Config, iniFile *ini.File) {
 
 	cfg.Fan.MaxCPUTemp = fanSec.Key("max_cpu_temp").MustFloat64(80.0)
 	cfg.Fan.MaxDiskTemp = fanSec.Key("max_disk_temp").MustFloat64(70.0)
+	cfg.Fan.Emergency = fanSec.Key("emergency").MustBool(true)
+	cfg.Fan.EmergencySeconds = fanSec.Key("emergency_seconds").MustFloat64(10)
+	cfg.Fan.EmergencyCommand = fanSec.Key("emergency_command").String()
+	cfg.Fan.SensorFailures = fanSec.Key("sensor_failures").MustInt(3)
+	cfg.Fan.SafeDuty = fanSec.Key("safe_duty").MustFloat64(100)
+	if sensors := fanSec.Key("cpu_sensors").String(); sensors != "" {
+		cfg.Fan.CPUSensors = strings.Split(sensors, ",")
+	}
+	cfg.Fan.CPUSensorMode = fanSec.Key("cpu_sensor_mode").In("max", []string{"max", "avg"})
 
 	cfg.Fan.Linear = fanSec.Key("linear").MustBool(false)
 	cfg.Fan.TempDisks = fanSec.Key("temp_disks").MustBool(false)
 	cfg.Fan.Syslog = fanSec.Key("syslog").MustBool(false)
 
-	cfg.Fan.HardwarePWM = os.Getenv("HARDWARE_PWM") == "1"
-	cfg.Fan.CPUPWMChip = os.Getenv("PWM_CHIP")
+	cfg.Fan.Profile = fanSec.Key("profile").MustString("balanced")
+	cfg.Fan.OverrideMinutes = fanSec.Key("override_minutes").MustInt(30)
+
+	cfg.Fan.SmoothingSeconds = fanSec.Key("smoothing_seconds").MustInt(0)
+	cfg.Fan.PredictSeconds = fanSec.Key("predict_seconds").MustFloat64(0)
+	cfg.Fan.PredictWindow = fanSec.Key("predict_window").MustFloat64(60)
+	cfg.Fan.PredictDecay = fanSec.Key("predict_decay").MustFloat64(1)
+	cfg.Fan.Interval = fanSec.Key("interval").MustInt(1)
+	cfg.Fan.MinChange = fanSec.Key("min_change").MustFloat64(0)
+	cfg.Fan.KickstartSeconds = fanSec.Key("kickstart_seconds").MustFloat64(0)
+	cfg.Fan.KickstartThreshold = fanSec.Key("kickstart_threshold").MustFloat64(20)
+	cfg.Fan.BurstMargin = fanSec.Key("burst_margin").MustFloat64(0)
+	cfg.Fan.BurstInterval = fanSec.Key("burst_interval").MustFloat64(0.5)
+	cfg.Fan.BurstDiskInterval = fanSec.Key("burst_disk_interval").MustFloat64(5)
+	cfg.Fan.BurstCooldown = fanSec.Key("burst_cooldown").MustFloat64(60)
+
+	cfg.Fan.DiskTempMode = fanSec.Key("disk_temp_mode").MustString("max")
+	cfg.Fan.DiskTempOffsets = parseDeviceValues(fanSec.Key("disk_temp_offsets").String())
+	cfg.Fan.DiskTempWeights = parseDeviceValues(fanSec.Key("disk_temp_weights").String())
+	if schedule := fanSec.Key("schedule").String(); schedule != "" {
+		cfg.Fan.Schedule = strings.Split(schedule, ",")
+	}
+
+	cfg.Fan.HardwarePWM = cfg.getenv("HARDWARE_PWM") == "1"
+	cfg.Fan.CPUPWMChip = cfg.getenv("PWM_CHIP")
 	if cfg.Fan.CPUPWMChip == "" {
 		cfg.Fan.CPUPWMChip = "pwmchip0"
 	}
-	cfg.Fan.CPUPWMChannel, _ = strconv.Atoi(os.Getenv("PWM_CPU_FAN"))
-	cfg.Fan.TBPWMChannel, _ = strconv.Atoi(os.Getenv("PWM_TB_FAN"))
+	cfg.Fan.CPUPWMChannel, _ = strconv.Atoi(cfg.getenv("PWM_CPU_FAN"))
+	cfg.Fan.TBPWMChannel, _ = strconv.Atoi(cfg.getenv("PWM_TB_FAN"))
 	if cfg.Fan.TBPWMChannel == 0 {
 		cfg.Fan.TBPWMChannel = cfg.Fan.CPUPWMChannel
 	}
-	cfg.Fan.TBPWMChip = cfg.Fan.CPUPWMChip
-	cfg.Fan.Polarity = os.Getenv("POLARITY")
+	cfg.Fan.TBPWMChip = cfg.getenv("PWM_TB_CHIP")
+	if cfg.Fan.TBPWMChip == "" {
+		cfg.Fan.TBPWMChip = cfg.Fan.CPUPWMChip
+	}
+	cfg.Fan.CPUPolarity = cfg.getenv("POLARITY_CPU_FAN")
+	if cfg.Fan.CPUPolarity == "" {
+		cfg.Fan.CPUPolarity = cfg.getenv("POLARITY")
+	}
+	cfg.Fan.TBPolarity = cfg.getenv("POLARITY_TB_FAN")
+	if cfg.Fan.TBPolarity == "" {
+		cfg.Fan.TBPolarity = cfg.getenv("POLARITY")
+	}
+
+	cfg.Fan.Mode = fanSec.Key("mode").In("pwm", []string{"pwm", "gpio", "observe"})
+	cfg.Fan.AutoObserve = fanSec.Key("auto_observe").MustBool(false)
+	cfg.Fan.ObserveCPUPWM = fanSec.Key("observe_cpu_pwm").MustString("")
+	cfg.Fan.ObserveDiskPWM = fanSec.Key("observe_disk_pwm").MustString("")
+	cfg.Fan.DryRun = fanSec.Key("dry_run").MustBool(false)
+	cfg.Fan.GPIOOnTemp = fanSec.Key("gpio_on_temp").MustFloat64(cfg.Fan.LV1)
+	cfg.Fan.GPIOOffTemp = fanSec.Key("gpio_off_temp").MustFloat64(cfg.Fan.LV0)
+
+	cfg.Fan.PWMFrequency = fanSec.Key("pwm_hz").MustInt(0)
+	if hz, err := strconv.Atoi(cfg.getenv("PWM_FREQUENCY")); err == nil {
+		cfg.Fan.PWMFrequency = hz
+	}
+}
+
+// parseDeviceValues parses "/dev/sda:+5,/dev/sdb:-2" into a device to value map,
+// skipping malformed entries
+func parseDeviceValues(s string) map[string]float64 {
+	values := make(map[string]float64)
+	for _, entry := range strings.Split(s, ",") {
+		dev, val, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if !ok {
+			continue
+		}
+		v, err := strconv.ParseFloat(strings.TrimSpace(val), 64)
+		if err != nil {
+			continue
+		}
+		values[strings.TrimSpace(dev)] = v
+	}
+	return values
+}
+
+const (
+	// defaultContrast is the SSD1306 contrast set by the display driver
+	defaultContrast = 0x8F
+	// defaultLightAddress is the BH1750 address with its ADDR pin low
+	defaultLightAddress = 0x23
+	// maxGoodbyeTime is the longest goodbye screen in seconds
+	maxGoodbyeTime = 3
+)
+
+// parseRotation reads [oled] rotate in degrees; true and false, from when only 180°
+// was supported, mean 180 and 0
+func parseRotation(value string) int {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "90":
+		return 90
+	case "180", "true", "yes", "on":
+		return 180
+	case "270":
+		return 270
+	default:
+		return 0
+	}
 }
 
 func loadOLEDConfig(cfg *Config, iniFile *ini.File) {
 	oledSec := iniFile.Section("oled")
 	cfg.OLED.Enabled = true
-	cfg.OLED.Rotate = oledSec.Key("rotate").MustBool(false)
+	cfg.OLED.Rotate = parseRotation(oledSec.Key("rotate").String())
 	cfg.OLED.Fahrenheit = oledSec.Key("f-temp").MustBool(false)
+	cfg.OLED.Hostname = oledSec.Key("hostname").In("off", []string{"off", "name", "mdns"})
+	cfg.OLED.Clock = oledSec.Key("clock").MustBool(false)
+	cfg.OLED.LargeClock = oledSec.Key("large_clock").MustBool(false)
+	cfg.OLED.NotifyTime = oledSec.Key("notify_time").MustInt(3)
+	cfg.OLED.SleepAfter = oledSec.Key("sleep_after").MustInt(0)
+	cfg.OLED.Scroll = oledSec.Key("scroll").MustBool(false)
+	cfg.OLED.ScrollSpeed = max(oledSec.Key("scroll_speed").MustFloat64(30), 1)
+	cfg.OLED.RenderBudget = oledSec.Key("render_budget").MustInt(500)
+	cfg.OLED.Prefetch = oledSec.Key("prefetch").MustBool(true)
+	cfg.OLED.Welcome = oledSec.Key("welcome").MustString("ROCKPi QUAD HAT|Loading...")
+	cfg.OLED.WelcomeTime = max(oledSec.Key("welcome_time").MustInt(2), 0)
+	cfg.OLED.Goodbye = oledSec.Key("goodbye").MustString("Good Bye ~")
+	// The goodbye has to fit in the time the daemon waits for modules to stop
+	cfg.OLED.GoodbyeTime = min(max(oledSec.Key("goodbye_time").MustInt(2), 0), maxGoodbyeTime)
+	cfg.OLED.DataInterval = max(oledSec.Key("data_interval").MustInt(10), 1)
+	cfg.OLED.Contrast = min(max(oledSec.Key("contrast").MustInt(defaultContrast), 0), 255)
+	if schedule := oledSec.Key("contrast_schedule").String(); schedule != "" {
+		cfg.OLED.ContrastSchedule = strings.Split(schedule, ",")
+	}
+	cfg.OLED.LightSensor = oledSec.Key("light_sensor").In("none", []string{"none", "bh1750"})
+	cfg.OLED.LightAddress = defaultLightAddress
+	if addr, err := strconv.ParseInt(oledSec.Key("light_address").String(), 0, 8); err == nil {
+		cfg.OLED.LightAddress = int(addr)
+	}
+	cfg.OLED.ContrastMin = min(max(oledSec.Key("contrast_min").MustInt(1), 0), 255)
+	cfg.OLED.Height = 32
+	if oledSec.Key("height").In("32", []string{"32", "64"}) == "64" {
+		cfg.OLED.Height = 64
+	}
+	cfg.OLED.Lang = oledSec.Key("lang").MustString("en")
+	cfg.OLED.Font = oledSec.Key("font").String()
+	cfg.OLED.CustomPages = loadCustomPages(iniFile)
+}
+
+// loadCustomPages reads [page.<name>] sections with line1..line3 keys
+func loadCustomPages(iniFile *ini.File) []CustomPage {
+	var pages []CustomPage
+	for _, sec := range iniFile.Sections() {
+		name, ok := strings.CutPrefix(sec.Name(), "page.")
+		if !ok || name == "" {
+			continue
+		}
+
+		page := CustomPage{Name: name}
+		for i := 1; i <= 3; i++ {
+			page.Lines = append(page.Lines, sec.Key(fmt.Sprintf("line%d", i)).String())
+		}
+		pages = append(pages, page)
+	}
+	return pages
 }
 
 func loadDiskConfig(cfg *Config, iniFile *ini.File) {
@@ -173,7 +815,60 @@ func loadDiskConfig(cfg *Config, iniFile *ini.File) {
 	if ioPoints := diskSec.Key("io_usage_mnt_points").String(); ioPoints != "" {
 		cfg.Disk.IOUsageMountPoints = strings.Split(ioPoints, "|")
 	}
+	for _, dev := range strings.Split(diskSec.Key("io_usage_devices").String(), ",") {
+		if dev = strings.TrimSpace(dev); dev != "" {
+			cfg.Disk.IOUsageDevices = append(cfg.Disk.IOUsageDevices, dev)
+		}
+	}
 	cfg.Disk.DisksTemperature = diskSec.Key("disks_temp").MustBool(false)
+	cfg.Disk.PowerStats = diskSec.Key("power_stats").MustBool(false)
+	cfg.Disk.PowerInterval = max(diskSec.Key("power_interval").MustInt(60), 1)
+	cfg.Disk.LinkErrors = diskSec.Key("link_errors").MustBool(false)
+	cfg.Disk.LinkInterval = max(diskSec.Key("link_interval").MustInt(300), 1)
+	cfg.Disk.SMARTHealth = diskSec.Key("smart_health").MustBool(false)
+	cfg.Disk.SMARTInterval = max(diskSec.Key("smart_interval").MustInt(3600), 1)
+	cfg.Disk.Inventory = diskSec.Key("inventory").MustBool(false)
+	cfg.Disk.Aliases = parseAliases(diskSec.Key("aliases").String())
+	cfg.Disk.Bays = loadBays(iniFile)
+}
+
+// loadBays reads [bay.<n>] sections, skipping bays without a serial or port to match
+func loadBays(iniFile *ini.File) []BayConfig {
+	var bays []BayConfig
+	for _, sec := range iniFile.Sections() {
+		id, ok := strings.CutPrefix(sec.Name(), "bay.")
+		if !ok || id == "" {
+			continue
+		}
+
+		bay := BayConfig{
+			ID:     id,
+			Label:  sec.Key("label").MustString("Bay " + id),
+			Serial: sec.Key("serial").String(),
+			Port:   sec.Key("port").String(),
+		}
+		if bay.Serial == "" && bay.Port == "" {
+			continue
+		}
+		bays = append(bays, bay)
+	}
+	return bays
+}
+
+// parseAliases parses "sda:bay1,/dev/sdb:bay2" into a disk name to alias map,
+// skipping malformed entries
+func parseAliases(s string) map[string]string {
+	aliases := make(map[string]string)
+	for _, entry := range strings.Split(s, ",") {
+		dev, alias, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		dev = strings.TrimPrefix(strings.TrimSpace(dev), "/dev/")
+		alias = strings.TrimSpace(alias)
+		if !ok || dev == "" || alias == "" {
+			continue
+		}
+		aliases[dev] = alias
+	}
+	return aliases
 }
 
 func loadNetworkConfig(cfg *Config, iniFile *ini.File) {
@@ -182,19 +877,133 @@ func loadNetworkConfig(cfg *Config, iniFile *ini.File) {
 		cfg.Network.Interfaces = strings.Split(interfaces, ",")
 	}
 	cfg.Network.SkipPage = netSec.Key("skip_page").MustBool(false)
+	cfg.Network.SkipDown = netSec.Key("skip_down").MustBool(false)
+	cfg.Network.Aggregate = netSec.Key("aggregate").MustBool(false)
+	cfg.Network.LinkMonitor = netSec.Key("link_monitor").MustBool(false)
+	cfg.Network.MinSpeed = netSec.Key("min_speed").MustInt(1000)
+
+	if checks := netSec.Key("checks").String(); checks != "" {
+		cfg.Network.Checks = strings.Split(checks, ",")
+	}
+	cfg.Network.CheckInterval = max(netSec.Key("check_interval").MustInt(30), 1)
+	cfg.Network.CheckFailures = netSec.Key("check_failures").MustInt(3)
+	cfg.Network.IPNotify = netSec.Key("ip_notify").MustBool(false)
+	cfg.Network.IPFamily = netSec.Key("ip_family").In("ipv4", []string{"ipv4", "ipv6", "both"})
 }
 
 func loadKeyConfig(cfg *Config, iniFile *ini.File) {
 	keySec := iniFile.Section("key")
 	cfg.Key.Click = keySec.Key("click").MustString("slider")
 	cfg.Key.Twice = keySec.Key("twice").MustString("switch")
+	cfg.Key.Triple = keySec.Key("triple").MustString("none")
 	cfg.Key.Press = keySec.Key("press").MustString("poweroff")
+	// Without a hold binding, holding the button keeps doing what a long press does
+	cfg.Key.Hold = keySec.Key("hold").MustString(cfg.Key.Press)
+	cfg.Key.Unlock = keySec.Key("unlock").MustString("press,press")
+	cfg.Key.ConfirmPower = keySec.Key("confirm_power").MustBool(true)
+	cfg.Key.ScriptTimeout = max(keySec.Key("script_timeout").MustFloat64(30), 1)
+}
+
+// loadButtons lists the env-configured main button with the [key] actions, followed
+// by extra buttons from [button.<id>] sections with chip, line and their own actions
+func loadButtons(cfg *Config, iniFile *ini.File) {
+	activeHigh := cfg.getenv("BUTTON_ACTIVE") == "high"
+	cfg.Buttons = []ButtonConfig{{
+		ID:         MainButton,
+		Chip:       cfg.Env.ButtonChip,
+		Line:       cfg.Env.ButtonLine,
+		Bias:       buttonBias(cfg.getenv("BUTTON_BIAS"), activeHigh),
+		ActiveHigh: activeHigh,
+		Keys:       cfg.Key,
+	}}
+	for _, sec := range iniFile.Sections() {
+		id, ok := strings.CutPrefix(sec.Name(), "button.")
+		if !ok || id == "" || id == MainButton {
+			continue
+		}
+
+		press := sec.Key("press").MustString("none")
+		activeHigh := sec.Key("active").In("low", []string{"low", "high"}) == "high"
+		cfg.Buttons = append(cfg.Buttons, ButtonConfig{
+			ID:         id,
+			Chip:       sec.Key("chip").String(),
+			Line:       sec.Key("line").String(),
+			Bias:       buttonBias(sec.Key("bias").String(), activeHigh),
+			ActiveHigh: activeHigh,
+			Keys: KeyConfig{
+				Click:  sec.Key("click").MustString("none"),
+				Twice:  sec.Key("twice").MustString("none"),
+				Triple: sec.Key("triple").MustString("none"),
+				Press:  press,
+				Hold:   sec.Key("hold").MustString(press),
+			},
+		})
+	}
+}
+
+// loadHATs lists the env-configured main HAT, followed by stacked HATs from
+// [hat.<id>] sections; a stacked HAT without ports has no disks and is skipped
+func loadHATs(cfg *Config, iniFile *ini.File) {
+	cfg.HATs = []HATConfig{{
+		ID:         MainHAT,
+		Label:      "Main",
+		SATAChip:   cfg.Env.SATAChip,
+		SATALine1:  cfg.Env.SATALine1,
+		SATALine2:  cfg.Env.SATALine2,
+		PWMChip:    cfg.Fan.TBPWMChip,
+		PWMChannel: cfg.Fan.TBPWMChannel,
+		Polarity:   cfg.Fan.TBPolarity,
+	}}
+	for _, sec := range iniFile.Sections() {
+		id, ok := strings.CutPrefix(sec.Name(), "hat.")
+		if !ok || id == "" || id == MainHAT {
+			continue
+		}
+
+		hat := HATConfig{
+			ID:         id,
+			Label:      sec.Key("label").MustString("HAT " + id),
+			SATAChip:   sec.Key("sata_chip").String(),
+			SATALine1:  sec.Key("sata_line_1").String(),
+			SATALine2:  sec.Key("sata_line_2").String(),
+			PWMChip:    sec.Key("pwm_chip").MustString(cfg.Fan.TBPWMChip),
+			PWMChannel: sec.Key("pwm_channel").MustInt(-1),
+			Polarity:   sec.Key("polarity").MustString(cfg.Fan.TBPolarity),
+		}
+		for _, port := range strings.Split(sec.Key("ports").String(), ",") {
+			if port = strings.TrimSpace(port); port != "" {
+				hat.Ports = append(hat.Ports, port)
+			}
+		}
+		if len(hat.Ports) == 0 {
+			continue
+		}
+		cfg.HATs = append(cfg.HATs, hat)
+	}
+}
+
+func loadTemperatureConfig(cfg *Config, iniFile *ini.File) {
+	tempSec := iniFile.Section("temperature")
+	format := func(surface string, precision int) thermal.Format {
+		return thermal.Format{
+			Precision: min(max(tempSec.Key(surface+"_precision").MustInt(precision), 0), 3),
+			Rounding: tempSec.Key(surface+"_rounding").In(thermal.RoundNearest,
+				[]string{thermal.RoundNearest, thermal.RoundDown, thermal.RoundUp}),
+		}
+	}
+	cfg.Temp.OLED = format("oled", 0)
+	cfg.Temp.API = format("api", 1)
+	cfg.Temp.Log = format("log", 1)
 }
 
 func loadTimeConfig(cfg *Config, iniFile *ini.File) {
 	timeSec := iniFile.Section("time")
 	cfg.Time.Twice = timeSec.Key("twice").MustFloat64(0.7)
 	cfg.Time.Press = timeSec.Key("press").MustFloat64(1.8)
+	cfg.Time.Hold = timeSec.Key("hold").MustFloat64(5)
+	cfg.Time.Debounce = timeSec.Key("debounce").MustFloat64(0)
+	cfg.Time.Confirm = timeSec.Key("confirm").MustFloat64(5)
+	cfg.Time.Timezone = strings.TrimSpace(timeSec.Key("timezone").String())
 }
 
 func loadSliderConfig(cfg *Config, iniFile *ini.File) {
@@ -202,3 +1011,232 @@ func loadSliderConfig(cfg *Config, iniFile *ini.File) {
 	cfg.Slider.Auto = sliderSec.Key("auto").MustBool(true)
 	cfg.Slider.Time = sliderSec.Key("time").MustInt(5)
 }
+
+func loadKernelConfig(cfg *Config, iniFile *ini.File) {
+	kernelSec := iniFile.Section("kernel")
+	cfg.Kernel.Watch = kernelSec.Key("watch").MustBool(false)
+}
+
+func loadRAIDConfig(cfg *Config, iniFile *ini.File) {
+	raidSec := iniFile.Section("raid")
+	cfg.RAID.MDStat = raidSec.Key("mdstat").MustBool(false)
+	cfg.RAID.ZFS = raidSec.Key("zfs").MustBool(false)
+	cfg.RAID.Interval = max(raidSec.Key("interval").MustInt(60), 1)
+	cfg.RAID.Webhook = raidSec.Key("webhook").String()
+}
+
+// defaultUPSAddress is the MAX17048 fuel gauge address
+const defaultUPSAddress = 0x36
+
+func loadUPSConfig(cfg *Config, iniFile *ini.File) {
+	upsSec := iniFile.Section("ups")
+	cfg.UPS.Source = upsSec.Key("source").In("none", []string{"none", "nut", "max17048"})
+	cfg.UPS.Name = upsSec.Key("name").MustString("ups@localhost")
+	cfg.UPS.Address = defaultUPSAddress
+	if addr, err := strconv.ParseInt(upsSec.Key("address").String(), 0, 8); err == nil {
+		cfg.UPS.Address = int(addr)
+	}
+	cfg.UPS.Interval = max(upsSec.Key("interval").MustInt(30), 1)
+	cfg.UPS.ShutdownCharge = upsSec.Key("shutdown_charge").MustFloat64(20)
+}
+
+func loadRailsConfig(cfg *Config, iniFile *ini.File) {
+	cfg.Rails.Interval = iniFile.Section("rails").Key("interval").MustFloat64(1)
+	if cfg.Rails.Interval <= 0 {
+		cfg.Rails.Interval = 1
+	}
+	cfg.Rails.Sensors = loadRails(iniFile)
+}
+
+// loadRails reads [rail.<name>] sections, skipping rails with nothing to watch
+func loadRails(iniFile *ini.File) []RailConfig {
+	var rails []RailConfig
+	for _, sec := range iniFile.Sections() {
+		name, ok := strings.CutPrefix(sec.Name(), "rail.")
+		if !ok || name == "" {
+			continue
+		}
+
+		rail := RailConfig{
+			Name:       name,
+			GPIO:       sec.Key("gpio").String(),
+			ActiveLow:  sec.Key("active_low").MustBool(false),
+			ADC:        sec.Key("adc").String(),
+			Divider:    sec.Key("divider").MustFloat64(1),
+			Min:        sec.Key("min").MustFloat64(0),
+			INA:        sec.Key("ina").String(),
+			Shunt:      sec.Key("shunt").MustFloat64(0.1),
+			MaxCurrent: sec.Key("max_current").MustFloat64(0),
+		}
+		if rail.GPIO == "" && rail.ADC == "" && rail.INA == "" {
+			continue
+		}
+		rails = append(rails, rail)
+	}
+	return rails
+}
+
+func loadLUKSConfig(cfg *Config, iniFile *ini.File) {
+	luksSec := iniFile.Section("luks")
+	cfg.LUKS.Volumes = parseLUKSVolumes(luksSec.Key("volumes").String())
+	cfg.LUKS.Keyfile = luksSec.Key("keyfile").String()
+}
+
+// parseLUKSVolumes parses "data:/dev/sda1,backup:/dev/disk/by-uuid/..." into
+// volumes, skipping malformed entries
+func parseLUKSVolumes(s string) []LUKSVolume {
+	var volumes []LUKSVolume
+	for _, entry := range strings.Split(s, ",") {
+		name, device, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		name, device = strings.TrimSpace(name), strings.TrimSpace(device)
+		if !ok || name == "" || device == "" {
+			continue
+		}
+		volumes = append(volumes, LUKSVolume{Name: name, Device: device})
+	}
+	return volumes
+}
+
+// ParseGPIOLine parses a GPIO line such as "gpiochip0:17" or "1:5" into a chip
+// device path and line offset
+func ParseGPIOLine(spec string) (chip string, line int, err error) {
+	chip, rawLine, ok := strings.Cut(spec, ":")
+	if !ok || chip == "" {
+		return "", 0, fmt.Errorf("invalid GPIO line %q: expected chip:line", spec)
+	}
+	line, err = strconv.Atoi(rawLine)
+	if err != nil || line < 0 {
+		return "", 0, fmt.Errorf("invalid line offset in %q", spec)
+	}
+
+	if _, err := strconv.Atoi(chip); err == nil {
+		chip = "gpiochip" + chip
+	}
+	if !strings.HasPrefix(chip, "/dev/") {
+		chip = "/dev/" + chip
+	}
+	return chip, line, nil
+}
+
+func loadAPIConfig(cfg *Config, iniFile *ini.File) {
+	apiSec := iniFile.Section("api")
+	cfg.API.Enabled = apiSec.Key("enabled").MustBool(false)
+	cfg.API.Listen = apiSec.Key("listen").MustString("127.0.0.1:8080")
+	cfg.API.PProf = apiSec.Key("pprof").MustBool(false)
+	if tokens := apiSec.Key("read_tokens").String(); tokens != "" {
+		cfg.API.ReadTokens = strings.Split(tokens, ",")
+	}
+	if tokens := apiSec.Key("control_tokens").String(); tokens != "" {
+		cfg.API.ControlTokens = strings.Split(tokens, ",")
+	}
+	cfg.API.TLSCert = apiSec.Key("tls_cert").String()
+	cfg.API.TLSKey = apiSec.Key("tls_key").String()
+	cfg.API.ClientCA = apiSec.Key("client_ca").String()
+}
+
+func loadStatusFileConfig(cfg *Config, iniFile *ini.File) {
+	statusSec := iniFile.Section("status_file")
+	cfg.Status.Enabled = statusSec.Key("enabled").MustBool(false)
+	cfg.Status.Path = statusSec.Key("path").MustString("/run/rockpi-quad/status.json")
+	cfg.Status.Interval = max(statusSec.Key("interval").MustInt(10), 1)
+}
+
+func loadSNMPConfig(cfg *Config, iniFile *ini.File) {
+	snmpSec := iniFile.Section("snmp")
+	cfg.SNMP.Enabled = snmpSec.Key("enabled").MustBool(false)
+	cfg.SNMP.Listen = snmpSec.Key("listen").MustString("127.0.0.1:161")
+	cfg.SNMP.Community = snmpSec.Key("community").MustString("public")
+	cfg.SNMP.BaseOID = snmpSec.Key("base_oid").MustString(snmp.DefaultBase)
+}
+
+func loadDebugConfig(cfg *Config, iniFile *ini.File) {
+	cfg.Debug.DumpPath = iniFile.Section("debug").Key("dump_path").String()
+}
+
+func loadHeartbeatConfig(cfg *Config, iniFile *ini.File) {
+	hbSec := iniFile.Section("heartbeat")
+	cfg.Heartbeat.Kind = hbSec.Key("kind").In("", []string{"healthchecks", "uptime-kuma"})
+	cfg.Heartbeat.URL = hbSec.Key("url").String()
+	cfg.Heartbeat.Interval = max(hbSec.Key("interval").MustInt(60), 1)
+}
+
+func loadStoreConfig(cfg *Config, iniFile *ini.File) {
+	storeSec := iniFile.Section("store")
+	cfg.Store.Path = storeSec.Key("path").String()
+	cfg.Store.RetentionDays = storeSec.Key("retention_days").MustInt(365)
+	cfg.Store.Interval = max(storeSec.Key("interval").MustInt(60), 1)
+}
+
+func loadAlertsConfig(cfg *Config, iniFile *ini.File) {
+	alertSec := iniFile.Section("alerts")
+	cfg.Alerts.Mute = alertSec.Key("mute").MustBool(false)
+	if hooks := alertSec.Key("webhook").String(); hooks != "" {
+		cfg.Alerts.Webhooks = strings.Split(hooks, ",")
+	}
+	cfg.Alerts.TelegramToken = alertSec.Key("telegram_token").String()
+	cfg.Alerts.TelegramChat = alertSec.Key("telegram_chat").String()
+	cfg.Alerts.Command = alertSec.Key("command").String()
+	cfg.Alerts.Cooldown = max(alertSec.Key("cooldown").MustInt(900), 0)
+	cfg.Alerts.Interval = max(alertSec.Key("interval").MustInt(10), 1)
+	cfg.Alerts.CPUTemp = alertSec.Key("cpu_temp").MustFloat64(0)
+	cfg.Alerts.CPUTempSeconds = max(alertSec.Key("cpu_temp_seconds").MustInt(60), 0)
+	cfg.Alerts.DiskTemp = alertSec.Key("disk_temp").MustFloat64(0)
+	cfg.Alerts.DiskTempSeconds = max(alertSec.Key("disk_temp_seconds").MustInt(60), 0)
+	cfg.Alerts.CPUFanTach = alertSec.Key("cpu_fan_tach").String()
+	cfg.Alerts.DiskFanTach = alertSec.Key("disk_fan_tach").String()
+	cfg.Alerts.FanStallSeconds = max(alertSec.Key("fan_stall_seconds").MustInt(30), 0)
+	cfg.Alerts.DiskRemoved = alertSec.Key("disk_removed").MustBool(true)
+}
+
+// defaultSMTPEvents are the critical alerts mailed by default
+const defaultSMTPEvents = "cpu_temp,disk_temp,fan_stall,thermal_emergency,disk_removed,smart_degraded,raid_degraded,power_rail,shutdown"
+
+func loadSMTPConfig(cfg *Config, iniFile *ini.File) {
+	smtpSec := iniFile.Section("smtp")
+	cfg.SMTP.Host = smtpSec.Key("host").String()
+	cfg.SMTP.TLS = smtpSec.Key("tls").In("starttls", []string{"starttls", "tls", "none"})
+	port := map[string]int{"starttls": 587, "tls": 465, "none": 25}[cfg.SMTP.TLS]
+	cfg.SMTP.Port = smtpSec.Key("port").MustInt(port)
+	cfg.SMTP.Username = smtpSec.Key("username").String()
+	cfg.SMTP.Password = smtpSec.Key("password").String()
+	cfg.SMTP.From = smtpSec.Key("from").String()
+	if to := smtpSec.Key("to").String(); to != "" {
+		cfg.SMTP.To = strings.Split(to, ",")
+	}
+	cfg.SMTP.Subject = smtpSec.Key("subject").String()
+	// INI values are single lines, so the body takes \n escapes
+	cfg.SMTP.Body = strings.ReplaceAll(smtpSec.Key("body").String(), `\n`, "\n")
+	cfg.SMTP.Events = strings.Split(smtpSec.Key("events").MustString(defaultSMTPEvents), ",")
+}
+
+func loadShutdownConfig(cfg *Config, iniFile *ini.File) {
+	sec := iniFile.Section("shutdown")
+	if services := sec.Key("services").String(); services != "" {
+		cfg.Shutdown.Services = strings.Split(services, ",")
+	}
+	if mounts := sec.Key("unmount").String(); mounts != "" {
+		cfg.Shutdown.Unmount = strings.Split(mounts, "|")
+	}
+	cfg.Shutdown.Spindown = sec.Key("spindown").MustBool(true)
+	cfg.Shutdown.StepTimeout = max(sec.Key("step_timeout").MustInt(30), 1)
+	cfg.Shutdown.FinalMessage = sec.Key("final_message").String()
+	cfg.Shutdown.PowerGPIO = sec.Key("power_gpio").String()
+	cfg.Shutdown.PowerGPIOActiveLow = sec.Key("power_gpio_active_low").MustBool(false)
+	cfg.Shutdown.PowerGPIOHold = sec.Key("power_gpio_hold").MustFloat64(1)
+}
+
+func loadModulesConfig(cfg *Config, iniFile *ini.File) {
+	modSec := iniFile.Section("modules")
+	cfg.Modules.Fan = modSec.Key("fan").MustBool(true)
+	cfg.Modules.OLED = modSec.Key("oled").MustBool(cfg.OLED.Enabled)
+	cfg.Modules.Button = modSec.Key("button").MustBool(true)
+	cfg.Modules.API = modSec.Key("api").MustBool(cfg.API.Enabled)
+	cfg.Modules.Metrics = modSec.Key("metrics").MustBool(true)
+	cfg.Modules.Alerts = modSec.Key("alerts").MustBool(true)
+	cfg.Modules.DiskMonitor = modSec.Key("disk_monitor").MustBool(true)
+	cfg.Modules.RetrySeconds = modSec.Key("retry_seconds").MustInt(30)
+	cfg.Modules.ShutdownTimeout = max(modSec.Key("shutdown_timeout").MustInt(15), 1)
+
+	cfg.OLED.Enabled = cfg.Modules.OLED
+	cfg.API.Enabled = cfg.Modules.API
+}
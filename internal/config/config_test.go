@@ -3,7 +3,12 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
 	"testing"
+
+	"github.com/kolobock/rockpi-quad-go/internal/thermal"
 )
 
 func TestLoadConfig(t *testing.T) {
@@ -84,4 +89,589 @@ func TestLoadConfigDefaults(t *testing.T) {
 	if cfg.Time.Press != 1.8 {
 		t.Errorf("default Time.Press = %v, want 1.8", cfg.Time.Press)
 	}
+	if cfg.Time.Hold != 5 {
+		t.Errorf("default Time.Hold = %v, want 5", cfg.Time.Hold)
+	}
+	if cfg.Key.Triple != "none" {
+		t.Errorf("default Key.Triple = %v, want none", cfg.Key.Triple)
+	}
+	if cfg.Key.Hold != cfg.Key.Press {
+		t.Errorf("default Key.Hold = %v, want %v", cfg.Key.Hold, cfg.Key.Press)
+	}
+	if cfg.OLED.Welcome != "ROCKPi QUAD HAT|Loading..." || cfg.OLED.WelcomeTime != 2 || cfg.OLED.GoodbyeTime != 2 {
+		t.Errorf("default welcome %q for %ds, goodbye for %ds", cfg.OLED.Welcome, cfg.OLED.WelcomeTime, cfg.OLED.GoodbyeTime)
+	}
+	if cfg.OLED.Lang != "en" || cfg.OLED.Font != "" {
+		t.Errorf("default OLED.Lang = %q with font %q, want en with the embedded font", cfg.OLED.Lang, cfg.OLED.Font)
+	}
+}
+
+func TestLoadContrast(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		wantAddr    int
+		wantDefault bool
+	}{
+		{"defaults", "[oled]\n", defaultLightAddress, true},
+		{"sensor", "[oled]\ncontrast = 200\ncontrast_schedule = 22:00=16,07:00=200\nlight_sensor = bh1750\nlight_address = 0x5c\n", 0x5c, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configFile := filepath.Join(t.TempDir(), "contrast.conf")
+			if err := os.WriteFile(configFile, []byte(tt.content), 0600); err != nil {
+				t.Fatalf("failed to create test config: %v", err)
+			}
+
+			cfg, err := Load(configFile)
+			if err != nil {
+				t.Fatalf("Load failed: %v", err)
+			}
+
+			if cfg.OLED.LightAddress != tt.wantAddr {
+				t.Errorf("LightAddress = %#x, want %#x", cfg.OLED.LightAddress, tt.wantAddr)
+			}
+			if tt.wantDefault {
+				if cfg.OLED.Contrast != defaultContrast || cfg.OLED.LightSensor != "none" || cfg.OLED.ContrastSchedule != nil {
+					t.Errorf("OLED contrast defaults = %+v", cfg.OLED)
+				}
+				return
+			}
+			if cfg.OLED.Contrast != 200 || cfg.OLED.LightSensor != "bh1750" || len(cfg.OLED.ContrastSchedule) != 2 {
+				t.Errorf("OLED contrast settings = %+v", cfg.OLED)
+			}
+		})
+	}
+}
+
+func TestLoadIntervalsClamped(t *testing.T) {
+	// Intervals drive time.NewTicker, which panics on a period of zero or less, and
+	// a timeout of zero would fail every step at once
+	tests := []struct {
+		section, key string
+		got          func(*Config) int
+	}{
+		{"disk", "power_interval", func(c *Config) int { return c.Disk.PowerInterval }},
+		{"disk", "link_interval", func(c *Config) int { return c.Disk.LinkInterval }},
+		{"disk", "smart_interval", func(c *Config) int { return c.Disk.SMARTInterval }},
+		{"network", "check_interval", func(c *Config) int { return c.Network.CheckInterval }},
+		{"heartbeat", "interval", func(c *Config) int { return c.Heartbeat.Interval }},
+		{"store", "interval", func(c *Config) int { return c.Store.Interval }},
+		{"raid", "interval", func(c *Config) int { return c.RAID.Interval }},
+		{"shutdown", "step_timeout", func(c *Config) int { return c.Shutdown.StepTimeout }},
+		{"key", "script_timeout", func(c *Config) int { return int(c.Key.ScriptTimeout) }},
+	}
+	for _, tt := range tests {
+		for _, value := range []string{"0", "-5"} {
+			t.Run(tt.key+"="+value, func(t *testing.T) {
+				configFile := filepath.Join(t.TempDir(), "intervals.conf")
+				content := "[" + tt.section + "]\n" + tt.key + " = " + value + "\n"
+				if err := os.WriteFile(configFile, []byte(content), 0600); err != nil {
+					t.Fatalf("failed to create test config: %v", err)
+				}
+				cfg, err := Load(configFile)
+				if err != nil {
+					t.Fatalf("Load failed: %v", err)
+				}
+				if got := tt.got(cfg); got != 1 {
+					t.Errorf("[%s] %s = %s loaded as %d, want 1", tt.section, tt.key, value, got)
+				}
+			})
+		}
+	}
+}
+
+func TestLoadTemperature(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    TemperatureConfig
+	}{
+		{"defaults", "[temperature]\n", TemperatureConfig{
+			OLED: thermal.Format{Precision: 0, Rounding: thermal.RoundNearest},
+			API:  thermal.Format{Precision: 1, Rounding: thermal.RoundNearest},
+			Log:  thermal.Format{Precision: 1, Rounding: thermal.RoundNearest},
+		}},
+		{"per surface", "[temperature]\noled_precision = 1\noled_rounding = down\napi_precision = 9\nlog_rounding = sideways\n", TemperatureConfig{
+			OLED: thermal.Format{Precision: 1, Rounding: thermal.RoundDown},
+			API:  thermal.Format{Precision: 3, Rounding: thermal.RoundNearest},
+			Log:  thermal.Format{Precision: 1, Rounding: thermal.RoundNearest},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configFile := filepath.Join(t.TempDir(), "temperature.conf")
+			if err := os.WriteFile(configFile, []byte(tt.content), 0600); err != nil {
+				t.Fatalf("failed to create test config: %v", err)
+			}
+
+			cfg, err := Load(configFile)
+			if err != nil {
+				t.Fatalf("Load failed: %v", err)
+			}
+			if cfg.Temp != tt.want {
+				t.Errorf("Temp = %+v, want %+v", cfg.Temp, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadPWMChannels(t *testing.T) {
+	t.Setenv("PWM_CHIP", "pwmchip0")
+	t.Setenv("PWM_TB_CHIP", "pwmchip1")
+	t.Setenv("POLARITY", "normal")
+	t.Setenv("POLARITY_TB_FAN", "inversed")
+
+	tmpDir := t.TempDir()
+	configFile := filepath.Join(tmpDir, "test_pwm.conf")
+	if err := os.WriteFile(configFile, []byte("[fan]\n"), 0600); err != nil {
+		t.Fatalf("failed to create test config: %v", err)
+	}
+
+	cfg, err := Load(configFile)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if cfg.Fan.TBPWMChip != "pwmchip1" {
+		t.Errorf("Fan.TBPWMChip = %q, want pwmchip1", cfg.Fan.TBPWMChip)
+	}
+	if cfg.Fan.CPUPolarity != "normal" {
+		t.Errorf("Fan.CPUPolarity = %q, want normal", cfg.Fan.CPUPolarity)
+	}
+	if cfg.Fan.TBPolarity != "inversed" {
+		t.Errorf("Fan.TBPolarity = %q, want inversed", cfg.Fan.TBPolarity)
+	}
+}
+
+func TestLoadBoardDefaults(t *testing.T) {
+	t.Setenv("BOARD", "rockpi4")
+	t.Setenv("BUTTON_LINE", "5")
+
+	tmpDir := t.TempDir()
+	configFile := filepath.Join(tmpDir, "test_board.conf")
+	if err := os.WriteFile(configFile, []byte("[fan]\n"), 0600); err != nil {
+		t.Fatalf("failed to create test config: %v", err)
+	}
+
+	cfg, err := Load(configFile)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if cfg.Env.Board != "rockpi4" {
+		t.Errorf("Env.Board = %q, want rockpi4", cfg.Env.Board)
+	}
+	if cfg.Env.I2CBus != 7 {
+		t.Errorf("Env.I2CBus = %d, want 7 from the board profile", cfg.Env.I2CBus)
+	}
+	if cfg.Env.ButtonLine != "5" {
+		t.Errorf("Env.ButtonLine = %q, want the env override 5", cfg.Env.ButtonLine)
+	}
+	if cfg.Fan.CPUPWMChip != "pwmchip1" {
+		t.Errorf("Fan.CPUPWMChip = %q, want pwmchip1 from the board profile", cfg.Fan.CPUPWMChip)
+	}
+
+	vars := cfg.EnvVars()
+	for key, want := range map[string]EnvVar{
+		"I2C_BUS":     {"7", EnvFromBoard},
+		"BUTTON_LINE": {"5", EnvFromEnvironment},
+		"OLED_RESET":  {"", EnvUnset},
+	} {
+		if vars[key] != want {
+			t.Errorf("EnvVars()[%s] = %+v, want %+v", key, vars[key], want)
+		}
+	}
+}
+
+func TestParseDeviceValues(t *testing.T) {
+	got := parseDeviceValues("/dev/sda:+5, /dev/sdb:-2.5,bogus,/dev/sdc:x")
+
+	if len(got) != 2 {
+		t.Fatalf("got %d entries, want 2: %v", len(got), got)
+	}
+	if got["/dev/sda"] != 5 {
+		t.Errorf("/dev/sda = %v, want 5", got["/dev/sda"])
+	}
+	if got["/dev/sdb"] != -2.5 {
+		t.Errorf("/dev/sdb = %v, want -2.5", got["/dev/sdb"])
+	}
+}
+
+func TestParseRotation(t *testing.T) {
+	tests := []struct {
+		value string
+		want  int
+	}{
+		{"", 0},
+		{"false", 0},
+		{"true", 180},
+		{"90", 90},
+		{"180", 180},
+		{" 270 ", 270},
+		{"45", 0},
+	}
+	for _, tt := range tests {
+		if got := parseRotation(tt.value); got != tt.want {
+			t.Errorf("parseRotation(%q) = %d, want %d", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestParseAliases(t *testing.T) {
+	got := parseAliases("sda:bay1, /dev/sdb : bay2,bogus,sdc:")
+
+	if len(got) != 2 || got["sda"] != "bay1" || got["sdb"] != "bay2" {
+		t.Errorf("parseAliases() = %v, want sda:bay1 and sdb:bay2", got)
+	}
+}
+
+func TestParseLUKSVolumes(t *testing.T) {
+	got := parseLUKSVolumes("data:/dev/sda1, backup : /dev/disk/by-uuid/0b1c,bogus,empty:")
+
+	want := []LUKSVolume{{Name: "data", Device: "/dev/sda1"}, {Name: "backup", Device: "/dev/disk/by-uuid/0b1c"}}
+	if !slices.Equal(got, want) {
+		t.Errorf("parseLUKSVolumes() = %+v, want %+v", got, want)
+	}
+}
+
+func TestLoadCustomPages(t *testing.T) {
+	configContent := `[page.owner]
+line1 = If found, call
+line2 = +1 555 0100
+
+[page.]
+line1 = ignored
+`
+
+	configFile := filepath.Join(t.TempDir(), "pages.conf")
+	if err := os.WriteFile(configFile, []byte(configContent), 0600); err != nil {
+		t.Fatalf("failed to create test config: %v", err)
+	}
+
+	cfg, err := Load(configFile)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if len(cfg.OLED.CustomPages) != 1 {
+		t.Fatalf("got %d custom pages, want 1", len(cfg.OLED.CustomPages))
+	}
+	page := cfg.OLED.CustomPages[0]
+	if page.Name != "owner" || page.Lines[0] != "If found, call" || page.Lines[2] != "" {
+		t.Errorf("custom page = %+v", page)
+	}
+}
+
+func TestLoadBays(t *testing.T) {
+	configContent := `[bay.1]
+serial = WD-WCC7K1234567
+
+[bay.2]
+port = ata3
+label = Left
+
+[bay.3]
+label = nothing to match
+`
+
+	configFile := filepath.Join(t.TempDir(), "bays.conf")
+	if err := os.WriteFile(configFile, []byte(configContent), 0600); err != nil {
+		t.Fatalf("failed to create test config: %v", err)
+	}
+
+	cfg, err := Load(configFile)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	want := []BayConfig{
+		{ID: "1", Label: "Bay 1", Serial: "WD-WCC7K1234567"},
+		{ID: "2", Label: "Left", Port: "ata3"},
+	}
+	if !slices.Equal(cfg.Disk.Bays, want) {
+		t.Errorf("bays = %+v, want %+v", cfg.Disk.Bays, want)
+	}
+}
+
+func TestLoadButtons(t *testing.T) {
+	t.Setenv("BUTTON_CHIP", "gpiochip4")
+	t.Setenv("BUTTON_LINE", "17")
+
+	configContent := `[key]
+click = slider
+
+[button.eject2]
+chip = gpiochip0
+line = 23
+click = umount /mnt/disk2
+press = reboot
+`
+	tmpDir := t.TempDir()
+	configFile := filepath.Join(tmpDir, "test_buttons.conf")
+	if err := os.WriteFile(configFile, []byte(configContent), 0600); err != nil {
+		t.Fatalf("failed to create test config: %v", err)
+	}
+
+	cfg, err := Load(configFile)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if len(cfg.Buttons) != 2 {
+		t.Fatalf("len(Buttons) = %d, want 2", len(cfg.Buttons))
+	}
+	mainBtn := cfg.Buttons[0]
+	if mainBtn.ID != MainButton || mainBtn.Chip != "gpiochip4" || mainBtn.Line != "17" || mainBtn.Keys.Click != "slider" {
+		t.Errorf("main button = %+v, want env pins and [key] actions", mainBtn)
+	}
+	extra := cfg.Buttons[1]
+	if extra.ID != "eject2" || extra.Line != "23" || extra.Keys.Click != "umount /mnt/disk2" {
+		t.Errorf("extra button = %+v, want eject2 on line 23", extra)
+	}
+	if extra.Keys.Twice != "none" || extra.Keys.Hold != "reboot" {
+		t.Errorf("extra button defaults = %+v, want twice none and hold = press", extra.Keys)
+	}
+}
+
+func TestLoadHATs(t *testing.T) {
+	t.Setenv("SATA_CHIP", "gpiochip2")
+	t.Setenv("SATA_LINE_1", "22")
+	t.Setenv("SATA_LINE_2", "23")
+
+	configContent := `[hat.2]
+sata_chip = gpiochip3
+sata_line_1 = 4
+sata_line_2 = 5
+pwm_chip = pwmchip2
+pwm_channel = 0
+ports = ata5, ata6,ata7,ata8
+
+[hat.3]
+label = no ports
+`
+	configFile := filepath.Join(t.TempDir(), "hats.conf")
+	if err := os.WriteFile(configFile, []byte(configContent), 0600); err != nil {
+		t.Fatalf("failed to create test config: %v", err)
+	}
+
+	cfg, err := Load(configFile)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if len(cfg.HATs) != 2 {
+		t.Fatalf("len(HATs) = %d, want 2: %+v", len(cfg.HATs), cfg.HATs)
+	}
+	mainHAT := cfg.HATs[0]
+	if mainHAT.ID != MainHAT || mainHAT.SATAChip != "gpiochip2" || mainHAT.PWMChip != cfg.Fan.TBPWMChip || mainHAT.PWMChannel != cfg.Fan.TBPWMChannel {
+		t.Errorf("main HAT = %+v, want env SATA pins and the top-board fan", mainHAT)
+	}
+	stacked := cfg.HATs[1]
+	if stacked.ID != "2" || stacked.Label != "HAT 2" || stacked.SATALine2 != "5" || stacked.PWMChip != "pwmchip2" || stacked.PWMChannel != 0 {
+		t.Errorf("stacked HAT = %+v", stacked)
+	}
+	if !slices.Equal(stacked.Ports, []string{"ata5", "ata6", "ata7", "ata8"}) {
+		t.Errorf("ports = %v", stacked.Ports)
+	}
+}
+
+func TestLoadButtonPolarity(t *testing.T) {
+	t.Setenv("BUTTON_ACTIVE", "high")
+
+	configContent := `[time]
+debounce = 0.02
+
+[button.aux]
+line = 5
+bias = disabled
+`
+	tmpDir := t.TempDir()
+	configFile := filepath.Join(tmpDir, "test_polarity.conf")
+	if err := os.WriteFile(configFile, []byte(configContent), 0600); err != nil {
+		t.Fatalf("failed to create test config: %v", err)
+	}
+
+	cfg, err := Load(configFile)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if cfg.Time.Debounce != 0.02 {
+		t.Errorf("Time.Debounce = %v, want 0.02", cfg.Time.Debounce)
+	}
+	if b := cfg.Buttons[0]; !b.ActiveHigh || b.Bias != "pull-down" {
+		t.Errorf("main button = %+v, want active-high with pull-down", b)
+	}
+	if b := cfg.Buttons[1]; b.ActiveHigh || b.Bias != "disabled" {
+		t.Errorf("aux button = %+v, want active-low with bias disabled", b)
+	}
+}
+
+func TestLoadRails(t *testing.T) {
+	configContent := `[rails]
+interval = 0.5
+
+[rail.12v]
+adc = iio:device0/in_voltage0
+divider = 5.7
+min = 11.4
+
+[rail.5v]
+gpio = gpiochip3:12
+active_low = true
+
+[rail.disks]
+ina = ina3221@0x41:2
+max_current = 3.5
+
+[rail.unused]
+min = 3
+`
+
+	configFile := filepath.Join(t.TempDir(), "rails.conf")
+	if err := os.WriteFile(configFile, []byte(configContent), 0600); err != nil {
+		t.Fatalf("failed to create test config: %v", err)
+	}
+
+	cfg, err := Load(configFile)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	want := []RailConfig{
+		{Name: "12v", ADC: "iio:device0/in_voltage0", Divider: 5.7, Min: 11.4, Shunt: 0.1},
+		{Name: "5v", GPIO: "gpiochip3:12", ActiveLow: true, Divider: 1, Shunt: 0.1},
+		{Name: "disks", INA: "ina3221@0x41:2", Divider: 1, Shunt: 0.1, MaxCurrent: 3.5},
+	}
+	if cfg.Rails.Interval != 0.5 || !slices.Equal(cfg.Rails.Sensors, want) {
+		t.Errorf("rails = %+v, want interval 0.5 and %+v", cfg.Rails, want)
+	}
+}
+
+func TestLoadAlerts(t *testing.T) {
+	configContent := `[alerts]
+webhook = https://hooks.example/a,https://hooks.example/b
+telegram_token = 123:abc
+telegram_chat = -1001
+cpu_temp = 80
+disk_temp = 55
+disk_temp_seconds = 120
+cpu_fan_tach = /sys/class/hwmon/hwmon3/fan1_input
+disk_removed = false
+`
+
+	configFile := filepath.Join(t.TempDir(), "alerts.conf")
+	if err := os.WriteFile(configFile, []byte(configContent), 0600); err != nil {
+		t.Fatalf("failed to create test config: %v", err)
+	}
+
+	cfg, err := Load(configFile)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	want := AlertsConfig{
+		Webhooks:        []string{"https://hooks.example/a", "https://hooks.example/b"},
+		TelegramToken:   "123:abc",
+		TelegramChat:    "-1001",
+		Cooldown:        900,
+		Interval:        10,
+		CPUTemp:         80,
+		CPUTempSeconds:  60,
+		DiskTemp:        55,
+		DiskTempSeconds: 120,
+		CPUFanTach:      "/sys/class/hwmon/hwmon3/fan1_input",
+		FanStallSeconds: 30,
+	}
+	if !reflect.DeepEqual(cfg.Alerts, want) {
+		t.Errorf("Alerts = %+v, want %+v", cfg.Alerts, want)
+	}
+}
+
+func TestLoadSMTP(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    SMTPConfig
+	}{
+		{
+			name:    "defaults",
+			content: "[smtp]\nhost = smtp.example.com\n",
+			want: SMTPConfig{
+				Host:   "smtp.example.com",
+				Port:   587,
+				TLS:    "starttls",
+				Events: strings.Split(defaultSMTPEvents, ","),
+			},
+		},
+		{
+			name: "implicit tls",
+			content: `[smtp]
+host = smtp.example.com
+tls = tls
+username = nas
+password = secret
+from = nas@example.com
+to = admin@example.com,ops@example.com
+body = {{.Message}}\n\nSent by {{.Host}}
+events = all
+`,
+			want: SMTPConfig{
+				Host:     "smtp.example.com",
+				Port:     465,
+				TLS:      "tls",
+				Username: "nas",
+				Password: "secret",
+				From:     "nas@example.com",
+				To:       []string{"admin@example.com", "ops@example.com"},
+				Body:     "{{.Message}}\n\nSent by {{.Host}}",
+				Events:   []string{"all"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configFile := filepath.Join(t.TempDir(), "smtp.conf")
+			if err := os.WriteFile(configFile, []byte(tt.content), 0600); err != nil {
+				t.Fatalf("failed to create test config: %v", err)
+			}
+			cfg, err := Load(configFile)
+			if err != nil {
+				t.Fatalf("Load failed: %v", err)
+			}
+			if !reflect.DeepEqual(cfg.SMTP, tt.want) {
+				t.Errorf("SMTP = %+v, want %+v", cfg.SMTP, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseGPIOLine(t *testing.T) {
+	tests := []struct {
+		spec     string
+		wantChip string
+		wantLine int
+		wantErr  bool
+	}{
+		{"gpiochip0:17", "/dev/gpiochip0", 17, false},
+		{"1:5", "/dev/gpiochip1", 5, false},
+		{"/dev/gpiochip4:26", "/dev/gpiochip4", 26, false},
+		{"gpiochip0", "", 0, true},
+		{":17", "", 0, true},
+		{"gpiochip0:x", "", 0, true},
+	}
+
+	for _, tt := range tests {
+		chip, line, err := ParseGPIOLine(tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseGPIOLine(%q) error = %v, want error %v", tt.spec, err, tt.wantErr)
+			continue
+		}
+		if chip != tt.wantChip || line != tt.wantLine {
+			t.Errorf("ParseGPIOLine(%q) = %s, %d, want %s, %d", tt.spec, chip, line, tt.wantChip, tt.wantLine)
+		}
+	}
 }
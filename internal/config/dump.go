@@ -0,0 +1,78 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Dump writes every setting of cfg as a "Section.Field = value" line, in the order
+// of the Config fields, with secrets redacted. Strings are quoted, lists and maps
+// are written inline with maps sorted by key, and list entries that are sections of
+// their own, such as buttons, get an index: "Buttons[0].ID = ...". The output only
+// changes when a value does, so two dumps can be diffed.
+func (cfg *Config) Dump(w io.Writer) error {
+	var lines []string
+	dumpFields(reflect.ValueOf(*cfg.Redacted()), "", &lines)
+	for _, line := range lines {
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dumpFields appends the exported fields of the struct v, prefixed by prefix
+func dumpFields(v reflect.Value, prefix string, lines *[]string) {
+	t := v.Type()
+	for i := range t.NumField() {
+		if !t.Field(i).IsExported() {
+			continue
+		}
+		dumpValue(v.Field(i), prefix+t.Field(i).Name, lines)
+	}
+}
+
+func dumpValue(v reflect.Value, name string, lines *[]string) {
+	switch {
+	case v.Kind() == reflect.Struct:
+		dumpFields(v, name+".", lines)
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Struct:
+		if v.Len() == 0 {
+			*lines = append(*lines, name+" = []")
+		}
+		for i := range v.Len() {
+			dumpFields(v.Index(i), fmt.Sprintf("%s[%d].", name, i), lines)
+		}
+	default:
+		*lines = append(*lines, name+" = "+formatValue(v))
+	}
+}
+
+// formatValue writes a value the way Dump shows it
+func formatValue(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.String:
+		return strconv.Quote(v.String())
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 64)
+	case reflect.Slice:
+		items := make([]string, v.Len())
+		for i := range items {
+			items[i] = formatValue(v.Index(i))
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case reflect.Map:
+		items := make([]string, 0, v.Len())
+		for _, k := range v.MapKeys() {
+			items = append(items, formatValue(k)+": "+formatValue(v.MapIndex(k)))
+		}
+		slices.Sort(items)
+		return "{" + strings.Join(items, ", ") + "}"
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
@@ -0,0 +1,47 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDump(t *testing.T) {
+	cfg := &Config{Path: "/etc/rockpi-quad.conf"}
+	cfg.Fan.LV0 = 35.5
+	cfg.Fan.CPUSensors = []string{"cpu", "gpu"}
+	cfg.Fan.DiskTempOffsets = map[string]float64{"sdb": -2, "sda": 3}
+	cfg.Buttons = []ButtonConfig{{ID: "main"}, {ID: "aux", ActiveHigh: true}}
+	cfg.SMTP.Password = "hunter2"
+
+	var b strings.Builder
+	if err := cfg.Dump(&b); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+
+	for _, want := range []string{
+		"Path = \"/etc/rockpi-quad.conf\"\n",
+		"Fan.LV0 = 35.5\n",
+		"Fan.CPUSensors = [\"cpu\", \"gpu\"]\n",
+		"Fan.DiskTempOffsets = {\"sda\": 3, \"sdb\": -2}\n",
+		"Fan.Schedule = []\n",
+		"Buttons[1].ID = \"aux\"\n",
+		"Buttons[1].ActiveHigh = true\n",
+		"HATs = []\n",
+		"Temp.OLED.Precision = 0\n",
+		"SMTP.Password = \"<redacted>\"\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Dump() is missing %q", want)
+		}
+	}
+	if strings.Contains(out, "hunter2") || strings.Contains(out, "envDefaults") {
+		t.Error("Dump() wrote a secret or an unexported field")
+	}
+
+	var again strings.Builder
+	_ = cfg.Dump(&again)
+	if again.String() != out {
+		t.Error("Dump() is not stable between calls")
+	}
+}
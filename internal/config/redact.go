@@ -0,0 +1,55 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// redacted replaces secrets in dumps of the configuration
+const redacted = "<redacted>"
+
+// Redacted returns a copy of cfg without tokens, passwords and URLs that may
+// embed credentials
+func (cfg *Config) Redacted() *Config {
+	c := *cfg
+	c.API.ReadTokens = redactAll(c.API.ReadTokens)
+	c.API.ControlTokens = redactAll(c.API.ControlTokens)
+	c.Heartbeat.URL = redactURL(c.Heartbeat.URL)
+	c.RAID.Webhook = redactURL(c.RAID.Webhook)
+	webhooks := make([]string, len(c.Alerts.Webhooks))
+	for i, u := range c.Alerts.Webhooks {
+		webhooks[i] = redactURL(u)
+	}
+	c.Alerts.Webhooks = webhooks
+	if c.Alerts.TelegramToken != "" {
+		c.Alerts.TelegramToken = redacted
+	}
+	if c.SMTP.Password != "" {
+		c.SMTP.Password = redacted
+	}
+	if c.SNMP.Community != "" {
+		c.SNMP.Community = redacted
+	}
+	return &c
+}
+
+func redactAll(secrets []string) []string {
+	out := make([]string, len(secrets))
+	for i := range secrets {
+		out[i] = redacted
+	}
+	return out
+}
+
+// redactURL keeps the scheme and host of a URL, which identify the service, and
+// drops the path, query and user info, which often carry a token
+func redactURL(raw string) string {
+	if raw == "" {
+		return ""
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return redacted
+	}
+	return fmt.Sprintf("%s://%s/%s", u.Scheme, u.Host, redacted)
+}
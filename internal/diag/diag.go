@@ -0,0 +1,80 @@
+// Package diag holds the runtime counters published through expvar and served with
+// pprof on the API listener when [api] pprof is enabled.
+package diag
+
+import (
+	"encoding/json"
+	"expvar"
+	"sync"
+	"time"
+)
+
+var (
+	// I2CWriteErrors counts failed writes to the OLED over I2C
+	I2CWriteErrors = expvar.NewInt("i2c_write_errors")
+	// SMARTCalls counts smartctl invocations
+	SMARTCalls = expvar.NewInt("smartctl_invocations")
+	// ShellExecs counts commands run through sh -c
+	ShellExecs = expvar.NewInt("shell_execs")
+
+	loops = expvar.NewMap("loop_latency")
+
+	loopsMu    sync.Mutex
+	loopByName = make(map[string]*loopStats)
+)
+
+// loopStats is the latency of one iteration of a polling loop
+type loopStats struct {
+	mu    sync.Mutex
+	count int64
+	last  time.Duration
+	max   time.Duration
+	total time.Duration
+}
+
+// String implements expvar.Var, reporting milliseconds
+func (l *loopStats) String() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	avg := time.Duration(0)
+	if l.count > 0 {
+		avg = l.total / time.Duration(l.count)
+	}
+	data, _ := json.Marshal(map[string]any{
+		"count":   l.count,
+		"last_ms": ms(l.last),
+		"avg_ms":  ms(avg),
+		"max_ms":  ms(l.max),
+	})
+	return string(data)
+}
+
+// ObserveLoop records how long one iteration of the named loop took
+func ObserveLoop(name string, d time.Duration) {
+	l := loop(name)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.count++
+	l.last = d
+	l.total += d
+	l.max = max(l.max, d)
+}
+
+// loop returns the stats of the named loop, publishing new ones
+func loop(name string) *loopStats {
+	loopsMu.Lock()
+	defer loopsMu.Unlock()
+
+	l, ok := loopByName[name]
+	if !ok {
+		l = &loopStats{}
+		loopByName[name] = l
+		loops.Set(name, l)
+	}
+	return l
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
@@ -0,0 +1,24 @@
+package diag
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestObserveLoop(t *testing.T) {
+	ObserveLoop("test", 2*time.Millisecond)
+	ObserveLoop("test", 6*time.Millisecond)
+	ObserveLoop("test", 4*time.Millisecond)
+
+	var got map[string]float64
+	if err := json.Unmarshal([]byte(loops.Get("test").String()), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]float64{"count": 3, "last_ms": 4, "avg_ms": 4, "max_ms": 6}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s = %v, want %v", k, got[k], v)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package disk
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kolobock/rockpi-quad-go/internal/config"
+)
+
+var (
+	bays        []config.BayConfig
+	bayDevices  map[string]string
+	bayResolved time.Time
+	bayMu       sync.Mutex
+)
+
+// SetBays configures the drive bays that Label reports in place of disk names
+func SetBays(b []config.BayConfig) {
+	bayMu.Lock()
+	defer bayMu.Unlock()
+	bays = b
+	bayDevices = nil
+}
+
+// Bay returns the label of the bay holding a disk or partition. Serials and ports
+// are re-resolved every 30 seconds like the disk list, so a bay follows its disk when /dev/sdX letters
+// change after a reboot or hotplug.
+func Bay(device string) (string, bool) {
+	name := ParentDevice(device)
+
+	bayMu.Lock()
+	defer bayMu.Unlock()
+
+	if len(bays) == 0 {
+		return "", false
+	}
+	if bayDevices == nil || time.Since(bayResolved) > recheckInterval {
+		bayDevices = resolveBays(bays)
+		bayResolved = time.Now()
+	}
+	label, ok := bayDevices[name]
+	return label, ok
+}
+
+// resolveBays maps the name of each disk sitting in a configured bay to the bay label
+func resolveBays(bays []config.BayConfig) map[string]string {
+	ids := diskIDs()
+	devices := make(map[string]string)
+
+	entries, err := os.ReadDir(sysClassBlock)
+	if err != nil {
+		return devices
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if _, err := os.Stat(filepath.Join(sysClassBlock, name, "partition")); err == nil {
+			continue
+		}
+		for _, bay := range bays {
+			if bayMatches(bay, ids[name], GetATAPort(name)) {
+				devices[name] = bay.Label
+				break
+			}
+		}
+	}
+	return devices
+}
+
+func bayMatches(bay config.BayConfig, ids []string, port string) bool {
+	if bay.Port != "" && bay.Port != port {
+		return false
+	}
+	if bay.Serial == "" {
+		return true
+	}
+	for _, id := range ids {
+		if serialMatches(id, bay.Serial) {
+			return true
+		}
+	}
+	return false
+}
+
+// serialMatches reports whether a /dev/disk/by-id name such as
+// ata-WDC_WD40EFRX-68N32N0_WD-WCC7K1234567 or usb-JMicron_Generic_0123456789AB-0:0
+// ends with the given serial number
+func serialMatches(id, serial string) bool {
+	_, rest, ok := strings.Cut(id, "-")
+	if !ok {
+		return false
+	}
+	return strings.HasSuffix(rest, "_"+serial) || strings.Contains(rest, "_"+serial+"-") || rest == serial
+}
+
+// deviceName names a disk in log messages, adding its bay when one is configured
+func deviceName(device string) string {
+	if bay, ok := Bay(device); ok {
+		return device + " [" + bay + "]"
+	}
+	return device
+}
@@ -0,0 +1,99 @@
+package disk
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kolobock/rockpi-quad-go/internal/config"
+)
+
+// fakeBlockDevices builds sysfs entries for sda on ata2 (with partition sda1) and sdb
+// on ata3, and by-id links naming sdb's serial
+func fakeBlockDevices(t *testing.T) {
+	t.Helper()
+	root := t.TempDir()
+	origBlock, origByID := sysClassBlock, diskByID
+	t.Cleanup(func() { sysClassBlock, diskByID = origBlock, origByID })
+	sysClassBlock = filepath.Join(root, "class", "block")
+	diskByID = filepath.Join(root, "dev", "disk", "by-id")
+
+	sda := filepath.Join(root, "devices", "ata2", "host1", "block", "sda")
+	sdb := filepath.Join(root, "devices", "ata3", "host2", "block", "sdb")
+	sda1 := filepath.Join(sda, "sda1")
+	for _, dir := range []string{sda1, sdb, sysClassBlock, diskByID} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(sda1, "partition"), []byte("1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	links := map[string]string{
+		filepath.Join(sysClassBlock, "sda"):                  sda,
+		filepath.Join(sysClassBlock, "sda1"):                 sda1,
+		filepath.Join(sysClassBlock, "sdb"):                  sdb,
+		filepath.Join(diskByID, "ata-WDC_WD40_WD-123"):       sdb,
+		filepath.Join(diskByID, "ata-WDC_WD40_WD-123-part1"): sda1,
+	}
+	for link, target := range links {
+		if err := os.Symlink(target, link); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestBay(t *testing.T) {
+	fakeBlockDevices(t)
+	SetBays([]config.BayConfig{
+		{ID: "1", Label: "Bay 1", Serial: "WD-123"},
+		{ID: "2", Label: "Bay 2", Port: "ata2"},
+		{ID: "3", Label: "Bay 3", Serial: "WD-123", Port: "ata2"},
+	})
+	t.Cleanup(func() { SetBays(nil) })
+
+	tests := []struct {
+		device string
+		want   string
+		wantOK bool
+	}{
+		{"/dev/sdb", "Bay 1", true},
+		{"/dev/sda", "Bay 2", true},
+		{"/dev/sda1", "Bay 2", true},
+		{"/dev/sdc", "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := Bay(tt.device)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("Bay(%q) = %q, %v, want %q, %v", tt.device, got, ok, tt.want, tt.wantOK)
+		}
+	}
+
+	if got := Label("/dev/sda1", map[string]string{"sda": "data"}); got != "Bay 2" {
+		t.Errorf("Label() = %q, want the bay over the alias", got)
+	}
+	if got := deviceName("/dev/sdb"); got != "/dev/sdb [Bay 1]" {
+		t.Errorf("deviceName() = %q, want %q", got, "/dev/sdb [Bay 1]")
+	}
+}
+
+func TestSerialMatches(t *testing.T) {
+	tests := []struct {
+		id     string
+		serial string
+		want   bool
+	}{
+		{"ata-WDC_WD40EFRX-68N32N0_WD-WCC7K1234567", "WD-WCC7K1234567", true},
+		{"nvme-Samsung_SSD_970_EVO_S466NX0K123456", "S466NX0K123456", true},
+		{"usb-JMicron_Generic_0123456789AB-0:0", "0123456789AB", true},
+		{"ata-WDC_WD40EFRX-68N32N0_WD-WCC7K1234567", "WCC7K1234567", false},
+		{"wwn-0x50014ee2b5a1c2d3", "0x50014ee2b5a1c2d3", true},
+	}
+
+	for _, tt := range tests {
+		if got := serialMatches(tt.id, tt.serial); got != tt.want {
+			t.Errorf("serialMatches(%q, %q) = %v, want %v", tt.id, tt.serial, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package disk
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// sectorSize is the unit of the sector counts in /sys/class/block/<name>/stat
+const sectorSize = 512
+
+// BlockName returns the whole-disk name under /sys/class/block of a device as df
+// prints it: symlinks such as /dev/mapper/data or /dev/disk/by-id/... are followed
+// to the kernel name (dm-0, sda1) and partitions resolve to their disk
+func BlockName(device string) string {
+	if filepath.IsAbs(device) {
+		if target, err := filepath.EvalSymlinks(device); err == nil {
+			device = target
+		}
+		device = filepath.Base(device)
+	}
+	return ParentDevice(device)
+}
+
+// Members returns the whole disks an md array or device-mapper volume is built on,
+// following stacked devices (LVM on RAID, LUKS on LVM) down through their slaves.
+// A plain disk has no members.
+func Members(name string) []string {
+	var members []string
+	var walk func(name string, depth int)
+	walk = func(name string, depth int) {
+		// Guards against a malformed sysfs tree; real stacks are a few levels deep
+		if depth > 8 {
+			return
+		}
+		slaves, err := os.ReadDir(filepath.Join(sysClassBlock, name, "slaves"))
+		if err != nil || len(slaves) == 0 {
+			if depth > 0 && !slices.Contains(members, name) {
+				members = append(members, name)
+			}
+			return
+		}
+		for _, slave := range slaves {
+			walk(ParentDevice(slave.Name()), depth+1)
+		}
+	}
+	walk(name, 0)
+	slices.Sort(members)
+	return members
+}
+
+// ReadIOStat returns the bytes read and written by a block device since boot
+func ReadIOStat(name string) (read, written uint64, err error) {
+	data, err := os.ReadFile(filepath.Join(sysClassBlock, name, "stat"))
+	if err != nil {
+		return 0, 0, err
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) < 7 {
+		return 0, 0, fmt.Errorf("short stat for %s", name)
+	}
+	readSectors, err := strconv.ParseUint(fields[2], 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	writeSectors, err := strconv.ParseUint(fields[6], 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	return readSectors * sectorSize, writeSectors * sectorSize, nil
+}
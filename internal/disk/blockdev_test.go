@@ -0,0 +1,125 @@
+package disk
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// fakeBlockTree builds /sys/class/block with whole disks, partitions and stacked
+// devices whose slaves entries point at their members
+func fakeBlockTree(t *testing.T, slaves map[string][]string, partitions ...string) {
+	t.Helper()
+	root := t.TempDir()
+	orig := sysClassBlock
+	t.Cleanup(func() { sysClassBlock = orig })
+	sysClassBlock = root
+
+	for _, part := range partitions {
+		if err := os.MkdirAll(filepath.Join(root, part), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(root, part, "partition"), []byte("1\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for dev, members := range slaves {
+		dir := filepath.Join(root, dev, "slaves")
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		for _, m := range members {
+			if err := os.MkdirAll(filepath.Join(dir, m), 0o755); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+func TestMembers(t *testing.T) {
+	fakeBlockTree(t, map[string][]string{
+		"md0":  {"sda1", "sdb1"},
+		"dm-0": {"md0"},
+		"dm-1": {"nvme0n1p2"},
+	})
+
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{"md0", []string{"sda", "sdb"}},
+		{"dm-0", []string{"sda", "sdb"}},
+		{"dm-1", []string{"nvme0n1"}},
+		{"sda", nil},
+	}
+	for _, tt := range tests {
+		if got := Members(tt.name); !slices.Equal(got, tt.want) {
+			t.Errorf("Members(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestBlockName(t *testing.T) {
+	fakeBlockTree(t, nil)
+	dev := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dev, "mapper"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dev, "dm-2"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("../dm-2", filepath.Join(dev, "mapper", "data")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		device string
+		want   string
+	}{
+		{filepath.Join(dev, "mapper", "data"), "dm-2"},
+		{"/dev/nvme0n1p1", "nvme0n1"},
+		{"mmcblk0p2", "mmcblk0"},
+		{"md0", "md0"},
+	}
+	for _, tt := range tests {
+		if got := BlockName(tt.device); got != tt.want {
+			t.Errorf("BlockName(%q) = %q, want %q", tt.device, got, tt.want)
+		}
+	}
+}
+
+func TestLabelDeviceMapper(t *testing.T) {
+	fakeBlockTree(t, nil)
+	if err := os.MkdirAll(filepath.Join(sysClassBlock, "dm-0", "dm"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(sysClassBlock, "dm-0", "dm", "name"), []byte("vg0-data\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got := Label("dm-0", nil); got != "vg0-data" {
+		t.Errorf("Label(dm-0) = %q, want vg0-data", got)
+	}
+	if got := Label("dm-0", map[string]string{"dm-0": "vault"}); got != "vault" {
+		t.Errorf("Label(dm-0) with alias = %q, want vault", got)
+	}
+}
+
+func TestReadIOStat(t *testing.T) {
+	fakeBlockTree(t, nil)
+	if err := os.MkdirAll(filepath.Join(sysClassBlock, "sda"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	stat := "    1234        0    20480      500      567        0    40960      900        0     1000     1400\n"
+	if err := os.WriteFile(filepath.Join(sysClassBlock, "sda", "stat"), []byte(stat), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	read, written, err := ReadIOStat("sda")
+	if err != nil || read != 20480*512 || written != 40960*512 {
+		t.Errorf("ReadIOStat() = %d, %d, %v, want %d, %d", read, written, err, 20480*512, 40960*512)
+	}
+	if _, _, err := ReadIOStat("sdz"); err == nil {
+		t.Error("ReadIOStat(missing) succeeded")
+	}
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/warthog618/go-gpiocdev"
 
+	"github.com/kolobock/rockpi-quad-go/internal/diag"
 	"github.com/kolobock/rockpi-quad-go/internal/logger"
 )
 
@@ -20,14 +21,20 @@ var (
 	recheckInterval   = 30 * time.Second
 	diskTempCache     = make(map[string]float64)
 	diskLastCheckTime = make(map[string]time.Time)
+	tempInterval      time.Duration
 )
 
-// GetSATADisks returns a list of SATA disk devices (/dev/sdX)
-func GetSATADisks() []string {
-	if len(diskListCache) > 0 {
-		return diskListCache
-	}
+// SetTemperatureInterval changes how long GetTemperature reuses a reading,
+// e.g. for burst sampling during thermal events; 0 restores the default
+func SetTemperatureInterval(d time.Duration) {
+	checkMutex.Lock()
+	defer checkMutex.Unlock()
+	tempInterval = d
+}
 
+// GetSATADisks returns a list of SATA disk devices (/dev/sdX), listed again once the
+// last list is recheckInterval old so added and removed disks show up
+func GetSATADisks() []string {
 	checkMutex.Lock()
 	defer checkMutex.Unlock()
 
@@ -39,10 +46,26 @@ func GetSATADisks() []string {
 	return diskListCache
 }
 
+// ListSATADisks lists the SATA disk devices now, for checks that must not miss a
+// disk that was just removed, and refreshes the list GetSATADisks returns
+func ListSATADisks() []string {
+	disks := fetchDiskList()
+
+	checkMutex.Lock()
+	defer checkMutex.Unlock()
+	diskListCache, lastCheckTime = disks, time.Now()
+	return disks
+}
+
+// listBlockDisks prints the SATA disk devices one per line; replaced in tests
+var listBlockDisks = func() ([]byte, error) {
+	diag.ShellExecs.Add(1)
+	return exec.Command("sh", "-c", "lsblk -d | egrep ^sd | awk '{print \"/dev/\"$1}'").Output()
+}
+
 func fetchDiskList() []string {
 	var disks []string
-	cmd := exec.Command("sh", "-c", "lsblk -d | egrep ^sd | awk '{print \"/dev/\"$1}'")
-	output, err := cmd.Output()
+	output, err := listBlockDisks()
 	if err == nil {
 		diskList := strings.Split(strings.TrimSpace(string(output)), "\n")
 		for _, d := range diskList {
@@ -54,23 +77,37 @@ func fetchDiskList() []string {
 	return disks
 }
 
+// SMARTAvailable reports whether smartctl is installed; without it disk
+// temperatures, power states and link errors cannot be read
+func SMARTAvailable() bool {
+	_, err := exec.LookPath("smartctl")
+	return err == nil
+}
+
 // GetTemperature reads disk temperature using smartctl
 func GetTemperature(device string) (float64, error) {
 	checkMutex.Lock()
 	defer checkMutex.Unlock()
 
-	checkTime := diskLastCheckTime[device]
-	if time.Since(checkTime) < recheckInterval {
-		if temp, ok := diskTempCache[device]; ok {
+	interval := recheckInterval
+	if tempInterval > 0 {
+		interval = tempInterval
+	}
+	id := ID(device)
+	checkTime := diskLastCheckTime[id]
+	if time.Since(checkTime) < interval {
+		if temp, ok := diskTempCache[id]; ok {
 			return temp, nil
 		}
 	}
 
+	diag.ShellExecs.Add(1)
+	diag.SMARTCalls.Add(1)
 	// #nosec G204 - device is validated to be a safe path earlier
 	cmd := exec.Command("sh", "-c", "smartctl -A "+device+" | egrep '^190' | awk '{print $10}'")
 	output, err := cmd.Output()
 	if err != nil {
-		cmd = exec.Command("smartctl", "-A", device)
+		cmd = smartctl("-A", device)
 		output, err = cmd.Output()
 		if err != nil {
 			return 0, fmt.Errorf("smartctl failed: %w", err)
@@ -83,6 +120,7 @@ func GetTemperature(device string) (float64, error) {
 				if len(fields) >= 10 {
 					temp, parseErr := strconv.ParseFloat(fields[9], 64)
 					if parseErr == nil {
+						recordTemperature(device, temp, time.Now())
 						return temp, nil
 					}
 				}
@@ -101,11 +139,48 @@ func GetTemperature(device string) (float64, error) {
 		return 0, fmt.Errorf("failed to parse temperature '%s': %w", tempStr, err)
 	}
 
-	diskTempCache[device] = temp
-	diskLastCheckTime[device] = time.Now()
+	diskTempCache[id] = temp
+	diskLastCheckTime[id] = time.Now()
+	recordTemperature(device, temp, diskLastCheckTime[id])
 	return temp, nil
 }
 
+// CachedTemp is a cached disk temperature and when it was read
+type CachedTemp struct {
+	Temp float64   `json:"temp"`
+	Read time.Time `json:"read"`
+}
+
+// CacheState is the content of the disk list and temperature caches
+type CacheState struct {
+	Disks  []string              `json:"disks"`
+	Listed time.Time             `json:"listed"`
+	Temps  map[string]CachedTemp `json:"temps"`
+	// Refresh is how long a temperature is reused, e.g. "30s"
+	Refresh string `json:"refresh"`
+}
+
+// Cache returns the cached disk list and temperatures, keyed by disk ID, for
+// debugging stale readings
+func Cache() CacheState {
+	checkMutex.Lock()
+	defer checkMutex.Unlock()
+
+	st := CacheState{
+		Disks:   append([]string(nil), diskListCache...),
+		Listed:  lastCheckTime,
+		Temps:   make(map[string]CachedTemp, len(diskTempCache)),
+		Refresh: recheckInterval.String(),
+	}
+	if tempInterval > 0 {
+		st.Refresh = tempInterval.String()
+	}
+	for id, temp := range diskTempCache {
+		st.Temps[id] = CachedTemp{Temp: temp, Read: diskLastCheckTime[id]}
+	}
+	return st
+}
+
 // EnableSATAController enables SATA controller GPIO lines if no disks are detected
 func EnableSATAController(sataChip, sataLine1, sataLine2 string) {
 	disks := GetSATADisks()
@@ -120,7 +195,12 @@ func EnableSATAController(sataChip, sataLine1, sataLine2 string) {
 	}
 
 	logger.Infoln("No SATA disks detected, enabling SATA controller...")
+	enableSATALines(sataChip, sataLine1, sataLine2)
+}
 
+// enableSATALines drives both enable lines of a SATA controller high and waits for
+// its disks to appear
+func enableSATALines(sataChip, sataLine1, sataLine2 string) {
 	if sataChip == "" {
 		sataChip = "gpiochip0"
 	}
@@ -163,4 +243,13 @@ func EnableSATAController(sataChip, sataLine1, sataLine2 string) {
 
 	time.Sleep(2 * time.Second)
 	logger.Infoln("SATA controller enabled")
+	refreshDisks()
+}
+
+// refreshDisks makes the next GetSATADisks list the disks again
+func refreshDisks() {
+	checkMutex.Lock()
+	defer checkMutex.Unlock()
+	diskListCache = nil
+	lastCheckTime = time.Time{}
 }
@@ -1,7 +1,9 @@
 package disk
 
 import (
+	"slices"
 	"testing"
+	"time"
 )
 
 func TestGetTemperatureInvalidDevice(t *testing.T) {
@@ -14,3 +16,67 @@ func TestGetTemperatureInvalidDevice(t *testing.T) {
 func TestEnableSATAControllerNoConfig(t *testing.T) {
 	EnableSATAController("", "", "")
 }
+
+func TestCache(t *testing.T) {
+	checkMutex.Lock()
+	read := time.Now()
+	diskTempCache["wwn-0x1"] = 38
+	diskLastCheckTime["wwn-0x1"] = read
+	checkMutex.Unlock()
+	t.Cleanup(func() {
+		checkMutex.Lock()
+		delete(diskTempCache, "wwn-0x1")
+		delete(diskLastCheckTime, "wwn-0x1")
+		checkMutex.Unlock()
+		SetTemperatureInterval(0)
+	})
+
+	SetTemperatureInterval(5 * time.Second)
+	st := Cache()
+	if got := st.Temps["wwn-0x1"]; got.Temp != 38 || !got.Read.Equal(read) {
+		t.Errorf("Temps[wwn-0x1] = %+v, want 38 read at %v", got, read)
+	}
+	if st.Refresh != "5s" {
+		t.Errorf("Refresh = %q, want 5s during burst sampling", st.Refresh)
+	}
+}
+
+func TestSATADisksRelisted(t *testing.T) {
+	listed := "/dev/sda\n/dev/sdb\n"
+	old := listBlockDisks
+	listBlockDisks = func() ([]byte, error) { return []byte(listed), nil }
+	t.Cleanup(func() {
+		listBlockDisks = old
+		checkMutex.Lock()
+		diskListCache, lastCheckTime = nil, time.Time{}
+		checkMutex.Unlock()
+	})
+	checkMutex.Lock()
+	diskListCache, lastCheckTime = nil, time.Time{}
+	checkMutex.Unlock()
+
+	if got := GetSATADisks(); !slices.Equal(got, []string{"/dev/sda", "/dev/sdb"}) {
+		t.Fatalf("GetSATADisks() = %v, want sda and sdb", got)
+	}
+
+	// sdb is pulled: the live list drops it at once, the cached one after recheckInterval
+	listed = "/dev/sda\n"
+	checkMutex.Lock()
+	lastCheckTime = time.Now().Add(-recheckInterval - time.Second)
+	checkMutex.Unlock()
+	if got := GetSATADisks(); !slices.Equal(got, []string{"/dev/sda"}) {
+		t.Errorf("GetSATADisks() after recheckInterval = %v, want only sda", got)
+	}
+
+	listed = "/dev/sda\n/dev/sdb\n"
+	if got := GetSATADisks(); len(got) != 1 {
+		t.Errorf("GetSATADisks() within recheckInterval = %v, want the cached list", got)
+	}
+	listed = ""
+	if got := ListSATADisks(); len(got) != 0 {
+		t.Errorf("ListSATADisks() = %v, want no disks", got)
+	}
+	if got := GetSATADisks(); len(got) != 0 {
+		t.Errorf("GetSATADisks() after ListSATADisks() = %v, want the refreshed empty list", got)
+	}
+}
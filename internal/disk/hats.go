@@ -0,0 +1,81 @@
+package disk
+
+import (
+	"slices"
+	"sync"
+
+	"github.com/kolobock/rockpi-quad-go/internal/config"
+	"github.com/kolobock/rockpi-quad-go/internal/logger"
+)
+
+var (
+	hats  []config.HATConfig
+	hatMu sync.Mutex
+)
+
+// SetHATs configures the stacked HATs whose ports group the disks
+func SetHATs(h []config.HATConfig) {
+	hatMu.Lock()
+	defer hatMu.Unlock()
+	hats = h
+}
+
+// Stacked reports whether more than one HAT is configured
+func Stacked() bool {
+	hatMu.Lock()
+	defer hatMu.Unlock()
+	return len(hats) > 1
+}
+
+// HAT returns the ID of the HAT holding a disk or partition: the HAT whose ports
+// include the disk's libata port, otherwise the main HAT
+func HAT(device string) string {
+	port := GetATAPort(ParentDevice(device))
+
+	hatMu.Lock()
+	defer hatMu.Unlock()
+
+	if port != "" {
+		for _, h := range hats {
+			if slices.Contains(h.Ports, port) {
+				return h.ID
+			}
+		}
+	}
+	return config.MainHAT
+}
+
+// HATDisks returns the SATA disks of a HAT
+func HATDisks(id string) []string {
+	var disks []string
+	for _, dev := range GetSATADisks() {
+		if HAT(dev) == id {
+			disks = append(disks, dev)
+		}
+	}
+	return disks
+}
+
+// EnableHATControllers enables the SATA controller of every stacked HAT that shows
+// no disks yet; the main HAT is enabled by EnableSATAController
+func EnableHATControllers() {
+	hatMu.Lock()
+	stacked := slices.Clone(hats)
+	hatMu.Unlock()
+
+	for _, h := range stacked {
+		if h.ID == config.MainHAT {
+			continue
+		}
+		if len(HATDisks(h.ID)) > 0 {
+			logger.Infof("%s: SATA disks detected, skipping SATA controller enable", h.Label)
+			continue
+		}
+		if h.SATAChip == "" || h.SATALine1 == "" || h.SATALine2 == "" {
+			logger.Infof("%s: SATA controller not configured", h.Label)
+			continue
+		}
+		logger.Infof("%s: no SATA disks detected, enabling SATA controller...", h.Label)
+		enableSATALines(h.SATAChip, h.SATALine1, h.SATALine2)
+	}
+}
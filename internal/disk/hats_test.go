@@ -0,0 +1,45 @@
+package disk
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kolobock/rockpi-quad-go/internal/config"
+)
+
+func TestHAT(t *testing.T) {
+	root := t.TempDir()
+	orig := sysClassBlock
+	t.Cleanup(func() { sysClassBlock = orig })
+	sysClassBlock = root
+
+	// /sys/class/block entries link into the device tree of their libata port
+	for name, port := range map[string]string{"sda": "ata1", "sde": "ata5", "sdf": "ata6"} {
+		target := filepath.Join("..", "..", "devices", "platform", port, "host0", "block", name)
+		if err := os.Symlink(target, filepath.Join(root, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	SetHATs([]config.HATConfig{
+		{ID: config.MainHAT},
+		{ID: "2", Ports: []string{"ata5", "ata6"}},
+	})
+	t.Cleanup(func() { SetHATs(nil) })
+
+	if !Stacked() {
+		t.Error("Stacked() = false with two HATs")
+	}
+	tests := map[string]string{
+		"/dev/sda":  config.MainHAT,
+		"/dev/sde":  "2",
+		"/dev/sdf1": "2",
+		"/dev/sdz":  config.MainHAT,
+	}
+	for device, want := range tests {
+		if got := HAT(device); got != want {
+			t.Errorf("HAT(%q) = %q, want %q", device, got, want)
+		}
+	}
+}
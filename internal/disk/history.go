@@ -0,0 +1,130 @@
+package disk
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kolobock/rockpi-quad-go/internal/logger"
+)
+
+const tempHistorySize = 512
+
+// TempSample is a single disk temperature reading
+type TempSample struct {
+	Time time.Time
+	Temp float64
+}
+
+var (
+	tempHistory   = make(map[string][]TempSample)
+	tempHistoryMu sync.Mutex
+
+	sctEntryRe    = regexp.MustCompile(`^\s*\d+\s+(\d{4}-\d{2}-\d{2} \d{2}:\d{2})\s+(\d+|\?)`)
+	sctIntervalRe = regexp.MustCompile(`^Temperature Logging Interval:\s+(\d+) minutes?`)
+)
+
+// recordTemperature appends a sample to the disk's history, dropping the oldest when full
+func recordTemperature(device string, temp float64, t time.Time) {
+	id := ID(device)
+
+	tempHistoryMu.Lock()
+	defer tempHistoryMu.Unlock()
+
+	samples := append(tempHistory[id], TempSample{Time: t, Temp: temp})
+	if len(samples) > tempHistorySize {
+		samples = samples[len(samples)-tempHistorySize:]
+	}
+	tempHistory[id] = samples
+}
+
+// GetTemperatureHistory returns a copy of the recorded temperature samples for the
+// disk currently at device
+func GetTemperatureHistory(device string) []TempSample {
+	id := ID(device)
+
+	tempHistoryMu.Lock()
+	defer tempHistoryMu.Unlock()
+
+	samples := make([]TempSample, len(tempHistory[id]))
+	copy(samples, tempHistory[id])
+	return samples
+}
+
+// ReadTemperatureHistory reads the SCT temperature history log of every SATA disk,
+// keyed by persistent disk ID, so the stored history starts with data from before
+// the daemon was started; disks without the log are left out
+func ReadTemperatureHistory() map[string][]TempSample {
+	history := make(map[string][]TempSample)
+	for _, dev := range GetSATADisks() {
+		// #nosec G204 - device comes from lsblk output
+		out, err := smartctl("-l", "scttemphist", dev).Output()
+		if err != nil && len(out) == 0 {
+			logger.Infof("No SCT temperature history for %s: %v", dev, err)
+			continue
+		}
+		if samples := parseSCTTempHistory(string(out), time.Local); len(samples) > 0 {
+			history[ID(dev)] = samples
+		}
+	}
+	return history
+}
+
+// parseSCTTempHistory extracts timestamped samples from `smartctl -l scttemphist` output.
+// Lines elided by smartctl ("..( N skipped)..") are expanded using the logging interval.
+func parseSCTTempHistory(output string, loc *time.Location) []TempSample {
+	var samples []TempSample
+	interval := time.Minute
+
+	for _, line := range strings.Split(output, "\n") {
+		if m := sctIntervalRe.FindStringSubmatch(line); m != nil {
+			if minutes, err := strconv.Atoi(m[1]); err == nil && minutes > 0 {
+				interval = time.Duration(minutes) * time.Minute
+			}
+			continue
+		}
+
+		if strings.Contains(line, "skipped") && len(samples) > 0 {
+			skipped := parseSkipped(line)
+			last := samples[len(samples)-1]
+			for i := 1; i <= skipped; i++ {
+				samples = append(samples, TempSample{
+					Time: last.Time.Add(time.Duration(i) * interval),
+					Temp: last.Temp,
+				})
+			}
+			continue
+		}
+
+		m := sctEntryRe.FindStringSubmatch(line)
+		if m == nil || m[2] == "?" {
+			continue
+		}
+		ts, err := time.ParseInLocation("2006-01-02 15:04", m[1], loc)
+		if err != nil {
+			continue
+		}
+		temp, err := strconv.ParseFloat(m[2], 64)
+		if err != nil {
+			continue
+		}
+		samples = append(samples, TempSample{Time: ts, Temp: temp})
+	}
+
+	return samples
+}
+
+func parseSkipped(line string) int {
+	start := strings.Index(line, "(")
+	end := strings.Index(line, "skipped")
+	if start < 0 || end <= start {
+		return 0
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(line[start+1 : end]))
+	if err != nil {
+		return 0
+	}
+	return n
+}
@@ -0,0 +1,60 @@
+package disk
+
+import (
+	"testing"
+	"time"
+)
+
+const sctSample = `smartctl 7.3 2022-02-28 r5338 [aarch64-linux-6.1.0] (local build)
+
+SCT Temperature History Version:     2
+Temperature Sampling Period:         1 minute
+Temperature Logging Interval:        10 minutes
+Min/Max recommended Temperature:      0/60 Celsius
+
+Index    Estimated Time   Temperature Celsius
+  12    2024-03-01 10:00    ?  -
+  13    2024-03-01 10:10    34  ***************
+ ...    ..(  2 skipped).    ..  ***************
+  16    2024-03-01 10:40    36  *****************
+`
+
+func TestParseSCTTempHistory(t *testing.T) {
+	samples := parseSCTTempHistory(sctSample, time.UTC)
+
+	if len(samples) != 4 {
+		t.Fatalf("got %d samples, want 4", len(samples))
+	}
+
+	first := time.Date(2024, 3, 1, 10, 10, 0, 0, time.UTC)
+	if !samples[0].Time.Equal(first) || samples[0].Temp != 34 {
+		t.Errorf("first sample = %+v, want 34 at %v", samples[0], first)
+	}
+	if !samples[2].Time.Equal(first.Add(20*time.Minute)) || samples[2].Temp != 34 {
+		t.Errorf("skipped sample = %+v, want 34 at %v", samples[2], first.Add(20*time.Minute))
+	}
+	if samples[3].Temp != 36 {
+		t.Errorf("last sample temp = %v, want 36", samples[3].Temp)
+	}
+}
+
+func TestRecordTemperatureBounded(t *testing.T) {
+	const dev = "/dev/test-history"
+	now := time.Now()
+
+	for i := 0; i < tempHistorySize+10; i++ {
+		recordTemperature(dev, float64(i), now.Add(time.Duration(i)*time.Second))
+	}
+
+	history := GetTemperatureHistory(dev)
+	if len(history) != tempHistorySize {
+		t.Errorf("history length = %d, want %d", len(history), tempHistorySize)
+	}
+	if history[0].Temp != 10 {
+		t.Errorf("oldest sample = %v, want 10", history[0].Temp)
+	}
+
+	tempHistoryMu.Lock()
+	delete(tempHistory, ID(dev))
+	tempHistoryMu.Unlock()
+}
@@ -0,0 +1,77 @@
+package disk
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// diskByID is replaced in tests
+var diskByID = "/dev/disk/by-id"
+
+var (
+	stableIDs   map[string]string
+	idsResolved time.Time
+	idsMu       sync.Mutex
+)
+
+// ID returns a name for the physical disk behind device that survives reboots and
+// hotplug reordering: its WWN (wwn-0x..., nvme-eui....) or serial-based
+// /dev/disk/by-id name, or the disk name when udev provides neither. Temperature
+// caches, history, power and link statistics are kept per ID.
+func ID(device string) string {
+	name := strings.TrimPrefix(device, "/dev/")
+
+	idsMu.Lock()
+	defer idsMu.Unlock()
+
+	if stableIDs == nil || time.Since(idsResolved) > recheckInterval {
+		stableIDs = make(map[string]string)
+		for dev, ids := range diskIDs() {
+			stableIDs[dev] = preferredID(ids)
+		}
+		idsResolved = time.Now()
+	}
+	if id, ok := stableIDs[name]; ok {
+		return id
+	}
+	return name
+}
+
+// preferredID picks the world wide name among a disk's by-id names, falling back to
+// the first serial-based one
+func preferredID(ids []string) string {
+	slices.Sort(ids)
+	for _, id := range ids {
+		if strings.HasPrefix(id, "wwn-") || strings.HasPrefix(id, "nvme-eui.") {
+			return id
+		}
+	}
+	return ids[0]
+}
+
+// diskIDs returns the /dev/disk/by-id names of each whole disk, keyed by disk name
+func diskIDs() map[string][]string {
+	ids := make(map[string][]string)
+	entries, err := os.ReadDir(diskByID)
+	if err != nil {
+		return ids
+	}
+	for _, entry := range entries {
+		id := entry.Name()
+		// Partitions are listed as <id>-part<n>
+		if strings.Contains(id, "-part") {
+			continue
+		}
+		target, err := filepath.EvalSymlinks(filepath.Join(diskByID, id))
+		if err != nil {
+			continue
+		}
+		name := filepath.Base(target)
+		ids[name] = append(ids[name], id)
+	}
+	return ids
+}
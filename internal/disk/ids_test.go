@@ -0,0 +1,97 @@
+package disk
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// linkByID points /dev/disk/by-id style names at fake device nodes in dir
+func linkByID(t *testing.T, dir string, links map[string]string) {
+	t.Helper()
+	entries, _ := os.ReadDir(diskByID)
+	for _, entry := range entries {
+		if err := os.Remove(filepath.Join(diskByID, entry.Name())); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for id, dev := range links {
+		if err := os.Symlink(filepath.Join(dir, dev), filepath.Join(diskByID, id)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	idsMu.Lock()
+	stableIDs = nil
+	idsMu.Unlock()
+}
+
+func TestIDFollowsDisk(t *testing.T) {
+	root := t.TempDir()
+	orig := diskByID
+	diskByID = filepath.Join(root, "by-id")
+	t.Cleanup(func() {
+		diskByID = orig
+		idsMu.Lock()
+		stableIDs = nil
+		idsMu.Unlock()
+	})
+	if err := os.MkdirAll(diskByID, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, dev := range []string{"sda", "sdb", "sda1"} {
+		if err := os.WriteFile(filepath.Join(root, dev), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	linkByID(t, root, map[string]string{
+		"ata-WDC_WD40_WD-111":       "sda",
+		"wwn-0x50014ee2b5a1c2d3":    "sda",
+		"ata-WDC_WD40_WD-111-part1": "sda1",
+		"ata-ST4000_ZDH222":         "sdb",
+	})
+
+	tests := []struct {
+		device string
+		want   string
+	}{
+		{"/dev/sda", "wwn-0x50014ee2b5a1c2d3"},
+		{"/dev/sdb", "ata-ST4000_ZDH222"},
+		{"/dev/sdc", "sdc"},
+	}
+	for _, tt := range tests {
+		if got := ID(tt.device); got != tt.want {
+			t.Errorf("ID(%q) = %q, want %q", tt.device, got, tt.want)
+		}
+	}
+
+	// The WD disk sleeps as sda, then comes back as sdb after a reboot
+	start := time.Now()
+	recordPowerMode("/dev/sda", PowerStandby, start)
+	recordTemperature("/dev/sda", 31, start)
+	linkByID(t, root, map[string]string{
+		"wwn-0x50014ee2b5a1c2d3": "sdb",
+		"ata-ST4000_ZDH222":      "sda",
+	})
+	recordPowerMode("/dev/sdb", PowerActive, start.Add(time.Hour))
+	t.Cleanup(func() {
+		powerStatsMu.Lock()
+		delete(powerStats, "wwn-0x50014ee2b5a1c2d3")
+		powerStatsMu.Unlock()
+		tempHistoryMu.Lock()
+		delete(tempHistory, "wwn-0x50014ee2b5a1c2d3")
+		tempHistoryMu.Unlock()
+	})
+
+	st, ok := GetPowerStats()["/dev/sdb"]
+	if !ok || st.SpinUps != 1 || st.StandbyTime != time.Hour {
+		t.Errorf("power stats for /dev/sdb = %+v, %v; want the spin-up of the disk that slept as sda", st, ok)
+	}
+	if history := GetTemperatureHistory("/dev/sdb"); len(history) != 1 || history[0].Temp != 31 {
+		t.Errorf("history for /dev/sdb = %+v, want the sample recorded as sda", history)
+	}
+	if history := GetTemperatureHistory("/dev/sda"); len(history) != 0 {
+		t.Errorf("history for the new /dev/sda = %+v, want none", history)
+	}
+}
@@ -0,0 +1,184 @@
+package disk
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// sysClassATALink is replaced in tests
+var sysClassATALink = "/sys/class/ata_link"
+
+// Drive identifies the disk sitting in a bay, so a failed disk can be found by its
+// serial number on the label
+type Drive struct {
+	Device string
+	Bay    string
+	// HAT is the ID of the stacked HAT holding the disk, empty with a single HAT
+	HAT string
+	// ID is the persistent name of the disk, see ID
+	ID       string
+	Model    string
+	Serial   string
+	Capacity uint64
+	Port     string
+	// LinkSpeed is the negotiated SATA speed, e.g. "6.0 Gb/s"
+	LinkSpeed string
+}
+
+// Size renders the capacity in the decimal units printed on the drive, e.g. 4.0TB
+func (d Drive) Size() string {
+	switch {
+	case d.Capacity == 0:
+		return "--"
+	case d.Capacity >= 1e12:
+		return fmt.Sprintf("%.1fTB", float64(d.Capacity)/1e12)
+	default:
+		return fmt.Sprintf("%.0fGB", float64(d.Capacity)/1e9)
+	}
+}
+
+// driveInfo is what smartctl reports about a disk
+type driveInfo struct {
+	model     string
+	serial    string
+	linkSpeed string
+}
+
+var (
+	// driveInfos caches smartctl identities by disk ID; they never change, and disks
+	// in standby are not woken to read them
+	driveInfos   = make(map[string]driveInfo)
+	driveInfosMu sync.Mutex
+)
+
+// Inventory returns model, serial, capacity and link speed of every SATA disk in
+// device order. Model and serial come from smartctl, falling back to sysfs and the
+// /dev/disk/by-id name while a disk sleeps.
+func Inventory() []Drive {
+	disks := GetSATADisks()
+	drives := make([]Drive, 0, len(disks))
+	for _, dev := range disks {
+		drives = append(drives, readDrive(dev))
+	}
+	return drives
+}
+
+func readDrive(device string) Drive {
+	name := ParentDevice(device)
+	d := Drive{Device: device, ID: ID(device), Port: GetATAPort(device)}
+	d.Bay, _ = Bay(device)
+	if Stacked() {
+		d.HAT = HAT(device)
+	}
+
+	if sectors, err := readSysfsUint(filepath.Join(sysClassBlock, name, "size")); err == nil {
+		d.Capacity = sectors * 512
+	}
+	if d.Port != "" {
+		d.LinkSpeed = readLinkSpeed(d.Port)
+	}
+
+	info := lookupDriveInfo(device, d.ID)
+	d.Model, d.Serial = info.model, info.serial
+	if d.Model == "" {
+		if data, err := os.ReadFile(filepath.Join(sysClassBlock, name, "device", "model")); err == nil {
+			d.Model = strings.TrimSpace(string(data))
+		}
+	}
+	if d.Serial == "" {
+		d.Serial = serialFromIDs(diskIDs()[name])
+	}
+	if d.LinkSpeed == "" {
+		d.LinkSpeed = info.linkSpeed
+	}
+	return d
+}
+
+// lookupDriveInfo returns the cached smartctl identity of a disk, querying smartctl
+// until it answers
+func lookupDriveInfo(device, id string) driveInfo {
+	driveInfosMu.Lock()
+	info, ok := driveInfos[id]
+	driveInfosMu.Unlock()
+	if ok {
+		return info
+	}
+
+	// #nosec G204 - device comes from lsblk output
+	out, _ := smartctl("-n", "standby", "-i", device).Output()
+	info = parseDriveInfo(string(out))
+	if info.serial != "" {
+		driveInfosMu.Lock()
+		driveInfos[id] = info
+		driveInfosMu.Unlock()
+	}
+	return info
+}
+
+// parseDriveInfo reads the identity section of `smartctl -i` output. The link speed
+// is the current one of "SATA Version is: SATA 3.2, 6.0 Gb/s (current: 3.0 Gb/s)".
+func parseDriveInfo(output string) driveInfo {
+	var info driveInfo
+	for _, line := range strings.Split(output, "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.TrimSpace(key) {
+		case "Device Model", "Model Number", "Product":
+			info.model = value
+		case "Serial Number", "Serial number":
+			info.serial = value
+		case "SATA Version is":
+			if _, current, ok := strings.Cut(value, "(current: "); ok {
+				info.linkSpeed = strings.TrimSuffix(current, ")")
+			} else if _, speed, ok := strings.Cut(value, ", "); ok {
+				info.linkSpeed = speed
+			}
+		}
+	}
+	return info
+}
+
+// readLinkSpeed reads the negotiated speed of a libata port such as "ata2" from
+// /sys/class/ata_link/link2/sata_spd, "" while the link is down
+func readLinkSpeed(port string) string {
+	link := "link" + strings.TrimPrefix(port, "ata")
+	data, err := os.ReadFile(filepath.Join(sysClassATALink, link, "sata_spd"))
+	if err != nil {
+		return ""
+	}
+	speed := strings.TrimSpace(string(data))
+	if speed == "" || strings.HasPrefix(speed, "<") {
+		return ""
+	}
+	return strings.Replace(speed, "Gbps", "Gb/s", 1)
+}
+
+// serialFromIDs takes the serial number from an ata- by-id name such as
+// ata-WDC_WD40EFRX-68N32N0_WD-WCC7K1234567
+func serialFromIDs(ids []string) string {
+	for _, id := range ids {
+		rest, ok := strings.CutPrefix(id, "ata-")
+		if !ok {
+			continue
+		}
+		if i := strings.LastIndex(rest, "_"); i >= 0 {
+			return rest[i+1:]
+		}
+	}
+	return ""
+}
+
+func readSysfsUint(path string) (uint64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+}
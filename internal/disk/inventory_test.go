@@ -0,0 +1,102 @@
+package disk
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseDriveInfo(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   driveInfo
+	}{
+		{
+			name: "ata",
+			output: `=== START OF INFORMATION SECTION ===
+Model Family:     Western Digital Red
+Device Model:     WDC WD40EFRX-68N32N0
+Serial Number:    WD-WCC7K1234567
+User Capacity:    4,000,787,030,016 bytes [4.00 TB]
+SATA Version is:  SATA 3.0, 6.0 Gb/s (current: 3.0 Gb/s)
+`,
+			want: driveInfo{model: "WDC WD40EFRX-68N32N0", serial: "WD-WCC7K1234567", linkSpeed: "3.0 Gb/s"},
+		},
+		{
+			name: "no current speed",
+			output: `Device Model:     Samsung SSD 870 EVO 1TB
+Serial Number:    S6PUNX0R123456
+SATA Version is:  SATA 3.3, 6.0 Gb/s
+`,
+			want: driveInfo{model: "Samsung SSD 870 EVO 1TB", serial: "S6PUNX0R123456", linkSpeed: "6.0 Gb/s"},
+		},
+		{
+			name:   "standby",
+			output: "Device is in STANDBY mode, exit(2)\n",
+			want:   driveInfo{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseDriveInfo(tt.output); got != tt.want {
+				t.Errorf("parseDriveInfo() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadLinkSpeed(t *testing.T) {
+	root := t.TempDir()
+	orig := sysClassATALink
+	t.Cleanup(func() { sysClassATALink = orig })
+	sysClassATALink = root
+
+	for link, speed := range map[string]string{"link1": "6.0 Gbps\n", "link2": "<unknown>\n"} {
+		if err := os.MkdirAll(filepath.Join(root, link), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(root, link, "sata_spd"), []byte(speed), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := map[string]string{"ata1": "6.0 Gb/s", "ata2": "", "ata3": ""}
+	for port, want := range tests {
+		if got := readLinkSpeed(port); got != want {
+			t.Errorf("readLinkSpeed(%q) = %q, want %q", port, got, want)
+		}
+	}
+}
+
+func TestSerialFromIDs(t *testing.T) {
+	tests := []struct {
+		ids  []string
+		want string
+	}{
+		{[]string{"wwn-0x50014ee2b1234567", "ata-WDC_WD40EFRX-68N32N0_WD-WCC7K1234567"}, "WD-WCC7K1234567"},
+		{[]string{"usb-JMicron_Generic_0123456789AB-0:0"}, ""},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		if got := serialFromIDs(tt.ids); got != tt.want {
+			t.Errorf("serialFromIDs(%v) = %q, want %q", tt.ids, got, tt.want)
+		}
+	}
+}
+
+func TestDriveSize(t *testing.T) {
+	tests := []struct {
+		capacity uint64
+		want     string
+	}{
+		{0, "--"},
+		{500107862016, "500GB"},
+		{4000787030016, "4.0TB"},
+	}
+	for _, tt := range tests {
+		if got := (Drive{Capacity: tt.capacity}).Size(); got != tt.want {
+			t.Errorf("Size(%d) = %q, want %q", tt.capacity, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package disk
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kolobock/rockpi-quad-go/internal/logger"
+)
+
+// LinkError describes an increase of the UDMA CRC error counter on a disk
+type LinkError struct {
+	Device string
+	// ID is the persistent name of the disk, see ID
+	ID    string
+	Port  string
+	Count int64
+	Delta int64
+}
+
+var (
+	crcCounts   = make(map[string]int64)
+	crcCountsMu sync.Mutex
+
+	ataPortRe = regexp.MustCompile(`/(ata\d+)/`)
+)
+
+// GetATAPort returns the libata port (e.g. "ata2") a block device is attached to
+func GetATAPort(device string) string {
+	name := strings.TrimPrefix(device, "/dev/")
+	target, err := os.Readlink(filepath.Join(sysClassBlock, name))
+	if err != nil {
+		return ""
+	}
+	if m := ataPortRe.FindStringSubmatch(target); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// GetCRCErrorCount reads the raw UDMA_CRC_Error_Count SMART attribute (199)
+func GetCRCErrorCount(device string) (int64, error) {
+	// #nosec G204 - device comes from lsblk output
+	out, err := smartctl("-n", "standby", "-A", device).Output()
+	if err != nil && len(out) == 0 {
+		return 0, fmt.Errorf("smartctl failed: %w", err)
+	}
+	return parseCRCErrorCount(string(out))
+}
+
+func parseCRCErrorCount(output string) (int64, error) {
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 10 || fields[0] != "199" {
+			continue
+		}
+		return strconv.ParseInt(fields[9], 10, 64)
+	}
+	return 0, fmt.Errorf("no UDMA CRC attribute found")
+}
+
+// CheckLinkErrors compares CRC error counters with the previous poll and
+// returns every disk whose counter increased
+func CheckLinkErrors() []LinkError {
+	var errs []LinkError
+	for _, dev := range GetSATADisks() {
+		count, err := GetCRCErrorCount(dev)
+		if err != nil {
+			continue
+		}
+		if linkErr, ok := recordCRCCount(dev, count); ok {
+			linkErr.Port = GetATAPort(dev)
+			if linkErr.Port == "" {
+				linkErr.Port = "unknown"
+			}
+			errs = append(errs, linkErr)
+		}
+	}
+	return errs
+}
+
+func recordCRCCount(device string, count int64) (LinkError, bool) {
+	id := ID(device)
+
+	crcCountsMu.Lock()
+	defer crcCountsMu.Unlock()
+
+	prev, seen := crcCounts[id]
+	crcCounts[id] = count
+	if !seen || count <= prev {
+		return LinkError{}, false
+	}
+	return LinkError{Device: device, ID: id, Count: count, Delta: count - prev}, true
+}
+
+// RunLinkMonitor polls CRC error counters and reports increases until the context is cancelled
+func RunLinkMonitor(ctx context.Context, interval time.Duration) {
+	CheckLinkErrors()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, e := range CheckLinkErrors() {
+				logger.Errorf("SATA link errors on %s (%s, port %s): UDMA CRC errors +%d (total %d), check the cable",
+					deviceName(e.Device), e.ID, e.Port, e.Delta, e.Count)
+			}
+		}
+	}
+}
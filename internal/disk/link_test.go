@@ -0,0 +1,41 @@
+package disk
+
+import "testing"
+
+func TestParseCRCErrorCount(t *testing.T) {
+	output := `ID# ATTRIBUTE_NAME          FLAG     VALUE WORST THRESH TYPE      UPDATED  WHEN_FAILED RAW_VALUE
+194 Temperature_Celsius     0x0022   114   100   000    Old_age   Always       -       36
+199 UDMA_CRC_Error_Count    0x003e   200   200   000    Old_age   Always       -       17
+`
+	count, err := parseCRCErrorCount(output)
+	if err != nil {
+		t.Fatalf("parseCRCErrorCount failed: %v", err)
+	}
+	if count != 17 {
+		t.Errorf("count = %d, want 17", count)
+	}
+
+	if _, err := parseCRCErrorCount("no attributes"); err == nil {
+		t.Error("expected error for missing attribute, got nil")
+	}
+}
+
+func TestRecordCRCCount(t *testing.T) {
+	const dev = "/dev/test-crc"
+	defer func() {
+		crcCountsMu.Lock()
+		delete(crcCounts, ID(dev))
+		crcCountsMu.Unlock()
+	}()
+
+	if _, ok := recordCRCCount(dev, 5); ok {
+		t.Error("first reading should not be reported")
+	}
+	if _, ok := recordCRCCount(dev, 5); ok {
+		t.Error("unchanged counter should not be reported")
+	}
+	linkErr, ok := recordCRCCount(dev, 8)
+	if !ok || linkErr.Delta != 3 || linkErr.Count != 8 {
+		t.Errorf("recordCRCCount = %+v, %v; want delta 3, count 8", linkErr, ok)
+	}
+}
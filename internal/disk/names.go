@@ -0,0 +1,66 @@
+package disk
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// sysClassBlock is replaced in tests
+var sysClassBlock = "/sys/class/block"
+
+var (
+	// sda1, vdb2, xvda1: the partition number follows the disk letters directly
+	letteredPartRe = regexp.MustCompile(`^((?:sd|hd|vd|xvd)[a-z]+)\d+$`)
+	// nvme0n1p1, mmcblk0p2, md127p1: disks ending in a digit add "p<number>"
+	numberedPartRe = regexp.MustCompile(`^(.*\d)p\d+$`)
+)
+
+// ParentDevice returns the whole-disk name of a device or partition without /dev/,
+// e.g. "nvme0n1" for /dev/nvme0n1p1. Partitions are resolved through
+// /sys/class/block/<name>/partition; without sysfs the kernel naming rules are used.
+func ParentDevice(device string) string {
+	name := strings.TrimPrefix(device, "/dev/")
+	if name == "" {
+		return ""
+	}
+
+	link := filepath.Join(sysClassBlock, name)
+	if _, err := os.Stat(link); err == nil {
+		if _, err := os.Stat(filepath.Join(link, "partition")); err != nil {
+			return name
+		}
+		// The entry links to .../block/<disk>/<partition>
+		if target, err := filepath.EvalSymlinks(link); err == nil {
+			return filepath.Base(filepath.Dir(target))
+		}
+	}
+
+	if m := letteredPartRe.FindStringSubmatch(name); m != nil {
+		return m[1]
+	}
+	if m := numberedPartRe.FindStringSubmatch(name); m != nil {
+		return m[1]
+	}
+	return name
+}
+
+// Label returns the name pages show for a device: the label of its bay, its alias
+// from [disk] aliases, otherwise the whole-disk name, or the volume name of a
+// device-mapper device
+func Label(device string, aliases map[string]string) string {
+	if bay, ok := Bay(device); ok {
+		return bay
+	}
+	name := ParentDevice(device)
+	if alias, ok := aliases[name]; ok {
+		return alias
+	}
+	if data, err := os.ReadFile(filepath.Join(sysClassBlock, name, "dm", "name")); err == nil {
+		if dmName := strings.TrimSpace(string(data)); dmName != "" {
+			return dmName
+		}
+	}
+	return name
+}
@@ -0,0 +1,85 @@
+package disk
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParentDevice(t *testing.T) {
+	orig := sysClassBlock
+	defer func() { sysClassBlock = orig }()
+	sysClassBlock = filepath.Join(t.TempDir(), "missing")
+
+	tests := []struct {
+		device string
+		want   string
+	}{
+		{"/dev/sda1", "sda"},
+		{"/dev/sdb", "sdb"},
+		{"sdaa12", "sdaa"},
+		{"/dev/nvme0n1p1", "nvme0n1"},
+		{"/dev/nvme0n1", "nvme0n1"},
+		{"/dev/mmcblk0p2", "mmcblk0"},
+		{"/dev/mmcblk0", "mmcblk0"},
+		{"/dev/md0", "md0"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := ParentDevice(tt.device); got != tt.want {
+			t.Errorf("ParentDevice(%q) = %q, want %q", tt.device, got, tt.want)
+		}
+	}
+}
+
+func TestParentDeviceFromSysfs(t *testing.T) {
+	root := t.TempDir()
+	orig := sysClassBlock
+	defer func() { sysClassBlock = orig }()
+	sysClassBlock = filepath.Join(root, "class", "block")
+
+	// A device-mapper style name that the naming rules cannot resolve
+	disk := filepath.Join(root, "devices", "block", "mydisk")
+	part := filepath.Join(disk, "mydisk-part1")
+	for _, dir := range []string{part, sysClassBlock} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(part, "partition"), []byte("1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for name, target := range map[string]string{"mydisk": disk, "mydisk-part1": part} {
+		if err := os.Symlink(target, filepath.Join(sysClassBlock, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := ParentDevice("/dev/mydisk-part1"); got != "mydisk" {
+		t.Errorf("ParentDevice(partition) = %q, want mydisk", got)
+	}
+	if got := ParentDevice("/dev/mydisk"); got != "mydisk" {
+		t.Errorf("ParentDevice(disk) = %q, want mydisk", got)
+	}
+}
+
+func TestLabel(t *testing.T) {
+	aliases := map[string]string{"sda": "bay1", "nvme0n1": "cache"}
+
+	tests := []struct {
+		device string
+		want   string
+	}{
+		{"/dev/sda", "bay1"},
+		{"/dev/sda1", "bay1"},
+		{"/dev/nvme0n1p3", "cache"},
+		{"/dev/sdb", "sdb"},
+	}
+
+	for _, tt := range tests {
+		if got := Label(tt.device, aliases); got != tt.want {
+			t.Errorf("Label(%q) = %q, want %q", tt.device, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package disk
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kolobock/rockpi-quad-go/internal/logger"
+)
+
+// PowerMode represents the ATA power state of a disk
+type PowerMode string
+
+const (
+	PowerActive  PowerMode = "active"
+	PowerStandby PowerMode = "standby"
+	PowerUnknown PowerMode = "unknown"
+)
+
+// PowerStats holds wake/sleep statistics for a single disk
+type PowerStats struct {
+	// Device is where the disk was last seen; statistics follow the disk's ID
+	Device      string
+	Mode        PowerMode
+	SpinUps     int
+	StandbyTime time.Duration
+	LastPoll    time.Time
+}
+
+var (
+	powerStats   = make(map[string]*PowerStats)
+	powerStatsMu sync.Mutex
+)
+
+// GetPowerMode queries the disk power state without waking it up
+func GetPowerMode(device string) PowerMode {
+	// #nosec G204 - device comes from lsblk output
+	out, _ := smartctl("-n", "standby", "-i", device).CombinedOutput()
+	return parsePowerMode(string(out))
+}
+
+func parsePowerMode(output string) PowerMode {
+	for _, line := range strings.Split(output, "\n") {
+		upper := strings.ToUpper(line)
+		switch {
+		case strings.Contains(upper, "STANDBY") || strings.Contains(upper, "SLEEP"):
+			return PowerStandby
+		case strings.HasPrefix(upper, "POWER MODE IS:"):
+			return PowerActive
+		}
+	}
+	return PowerUnknown
+}
+
+// UpdatePowerStats polls every SATA disk and accounts spin-ups and standby time
+func UpdatePowerStats() {
+	now := time.Now()
+	for _, dev := range GetSATADisks() {
+		recordPowerMode(dev, GetPowerMode(dev), now)
+	}
+}
+
+func recordPowerMode(device string, mode PowerMode, now time.Time) {
+	id := ID(device)
+
+	powerStatsMu.Lock()
+	defer powerStatsMu.Unlock()
+
+	st, ok := powerStats[id]
+	if !ok {
+		powerStats[id] = &PowerStats{Device: device, Mode: mode, LastPoll: now}
+		return
+	}
+	st.Device = device
+
+	if st.Mode == PowerStandby {
+		st.StandbyTime += now.Sub(st.LastPoll)
+	}
+	if st.Mode == PowerStandby && mode == PowerActive {
+		st.SpinUps++
+		logger.Infof("Disk %s spun up (total spin-ups: %d)", device, st.SpinUps)
+	}
+	st.Mode = mode
+	st.LastPoll = now
+}
+
+// GetPowerStats returns a snapshot of the wake/sleep statistics keyed by the device
+// each disk was last seen at; when disks swapped devices the latest poll wins
+func GetPowerStats() map[string]PowerStats {
+	powerStatsMu.Lock()
+	defer powerStatsMu.Unlock()
+
+	now := time.Now()
+	stats := make(map[string]PowerStats, len(powerStats))
+	for _, st := range powerStats {
+		if prev, ok := stats[st.Device]; ok && prev.LastPoll.After(st.LastPoll) {
+			continue
+		}
+		snapshot := *st
+		if snapshot.Mode == PowerStandby {
+			snapshot.StandbyTime += now.Sub(snapshot.LastPoll)
+		}
+		stats[st.Device] = snapshot
+	}
+	return stats
+}
+
+// RunPowerMonitor polls disk power modes until the context is cancelled
+func RunPowerMonitor(ctx context.Context, interval time.Duration) {
+	UpdatePowerStats()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			UpdatePowerStats()
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package disk
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParsePowerMode(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   PowerMode
+	}{
+		{"active", "=== START OF INFORMATION SECTION ===\nPower mode is:    ACTIVE or IDLE\n", PowerActive},
+		{"standby", "Device is in STANDBY mode, exit(2)\n", PowerStandby},
+		{"sleep", "Device is in SLEEP mode, exit(2)\n", PowerStandby},
+		{"empty", "", PowerUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parsePowerMode(tt.output); got != tt.want {
+				t.Errorf("parsePowerMode() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecordPowerMode(t *testing.T) {
+	const dev = "/dev/test-power"
+	start := time.Now()
+
+	recordPowerMode(dev, PowerStandby, start)
+	recordPowerMode(dev, PowerActive, start.Add(10*time.Minute))
+	recordPowerMode(dev, PowerStandby, start.Add(20*time.Minute))
+	recordPowerMode(dev, PowerActive, start.Add(25*time.Minute))
+
+	powerStatsMu.Lock()
+	st := *powerStats[ID(dev)]
+	delete(powerStats, ID(dev))
+	powerStatsMu.Unlock()
+
+	if st.SpinUps != 2 {
+		t.Errorf("SpinUps = %d, want 2", st.SpinUps)
+	}
+	if st.StandbyTime != 15*time.Minute {
+		t.Errorf("StandbyTime = %v, want 15m", st.StandbyTime)
+	}
+	if st.Mode != PowerActive {
+		t.Errorf("Mode = %v, want %v", st.Mode, PowerActive)
+	}
+}
@@ -0,0 +1,224 @@
+package disk
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kolobock/rockpi-quad-go/internal/diag"
+	"github.com/kolobock/rockpi-quad-go/internal/logger"
+)
+
+// smartctl builds a smartctl command, counting the invocation
+func smartctl(args ...string) *exec.Cmd {
+	diag.SMARTCalls.Add(1)
+	// #nosec G204 - arguments are fixed flags and devices from lsblk output
+	return exec.Command("smartctl", args...)
+}
+
+// GetSMARTAttributes reads the raw values of the SMART attributes of a disk, keyed
+// by attribute name (e.g. Reallocated_Sector_Ct), without waking it from standby
+func GetSMARTAttributes(device string) (map[string]int64, error) {
+	// #nosec G204 - device comes from lsblk output
+	out, err := smartctl("-n", "standby", "-A", device).Output()
+	if err != nil && len(out) == 0 {
+		return nil, fmt.Errorf("smartctl failed: %w", err)
+	}
+	attrs := parseSMARTAttributes(string(out))
+	if len(attrs) == 0 {
+		return nil, fmt.Errorf("no SMART attributes for %s", device)
+	}
+	return attrs, nil
+}
+
+// parseSMARTAttributes parses the attribute table of `smartctl -A`; raw values with
+// extra detail such as "34 (Min/Max 20/45)" keep their leading number
+func parseSMARTAttributes(output string) map[string]int64 {
+	attrs := make(map[string]int64)
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 10 {
+			continue
+		}
+		if _, err := strconv.Atoi(fields[0]); err != nil {
+			continue
+		}
+		raw, err := strconv.ParseInt(fields[9], 10, 64)
+		if err != nil {
+			continue
+		}
+		attrs[fields[1]] = raw
+	}
+	return attrs
+}
+
+// HealthStatus is the verdict of a SMART health check
+type HealthStatus string
+
+const (
+	HealthOK HealthStatus = "OK"
+	// HealthWarn means the self-assessment passed but sectors were reallocated or
+	// are pending reallocation
+	HealthWarn    HealthStatus = "WARN"
+	HealthFailed  HealthStatus = "FAIL"
+	HealthUnknown HealthStatus = "--"
+)
+
+// severity orders health statuses from good to bad
+var severity = map[HealthStatus]int{HealthUnknown: 0, HealthOK: 0, HealthWarn: 1, HealthFailed: 2}
+
+// Health is the SMART health of a disk
+type Health struct {
+	// Device is where the disk was last seen; health follows the disk's ID
+	Device      string
+	ID          string
+	Status      HealthStatus
+	Reallocated int64
+	Pending     int64
+	Checked     time.Time
+}
+
+// Summary renders the health compactly for the display, e.g. "OK" or "R:8 P:2"
+func (h Health) Summary() string {
+	if h.Status == HealthWarn {
+		return fmt.Sprintf("R:%d P:%d", h.Reallocated, h.Pending)
+	}
+	return string(h.Status)
+}
+
+// worseThan reports whether h is a degradation of prev: a worse status, or more bad
+// sectors than before
+func (h Health) worseThan(prev Health) bool {
+	if severity[h.Status] != severity[prev.Status] {
+		return severity[h.Status] > severity[prev.Status]
+	}
+	return h.Reallocated > prev.Reallocated || h.Pending > prev.Pending
+}
+
+var (
+	healthState   = make(map[string]Health)
+	healthStateMu sync.Mutex
+)
+
+// GetSMARTHealth runs the SMART self-assessment of a disk and reads its reallocated
+// and pending sector counts. A disk in standby is not woken and reports an error.
+func GetSMARTHealth(device string) (Health, error) {
+	// #nosec G204 - device comes from lsblk output
+	out, err := smartctl("-n", "standby", "-H", "-A", device).Output()
+	if err != nil && len(out) == 0 {
+		return Health{}, fmt.Errorf("smartctl failed: %w", err)
+	}
+	h := parseSMARTHealth(string(out))
+	if h.Status == HealthUnknown {
+		return Health{}, fmt.Errorf("no SMART health for %s", device)
+	}
+	h.Device = device
+	return h, nil
+}
+
+// parseSMARTHealth reads the self-assessment of `smartctl -H -A` output: "PASSED" or
+// "FAILED!" for ATA disks, "OK" for SCSI/NVMe style output
+func parseSMARTHealth(output string) Health {
+	h := Health{Status: HealthUnknown}
+	for _, line := range strings.Split(output, "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		if key != "SMART overall-health self-assessment test result" && key != "SMART Health Status" {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if value == "PASSED" || value == "OK" {
+			h.Status = HealthOK
+		} else {
+			h.Status = HealthFailed
+		}
+	}
+
+	attrs := parseSMARTAttributes(output)
+	h.Reallocated = attrs["Reallocated_Sector_Ct"]
+	h.Pending = attrs["Current_Pending_Sector"]
+	if h.Status == HealthOK && (h.Reallocated > 0 || h.Pending > 0) {
+		h.Status = HealthWarn
+	}
+	return h
+}
+
+// CheckHealth checks every SATA disk and returns those whose health degraded since
+// the previous check. A disk that is unhealthy when first seen counts as degraded;
+// disks in standby keep their last result.
+func CheckHealth() []Health {
+	var degraded []Health
+	now := time.Now()
+	for _, dev := range GetSATADisks() {
+		h, err := GetSMARTHealth(dev)
+		if err != nil {
+			logger.Infof("SMART health of %s unavailable: %v", dev, err)
+			continue
+		}
+		h.Checked = now
+		if recordHealth(h) {
+			degraded = append(degraded, h)
+		}
+	}
+	return degraded
+}
+
+func recordHealth(h Health) bool {
+	h.ID = ID(h.Device)
+
+	healthStateMu.Lock()
+	defer healthStateMu.Unlock()
+
+	prev, seen := healthState[h.ID]
+	healthState[h.ID] = h
+	if !seen {
+		return h.Status == HealthWarn || h.Status == HealthFailed
+	}
+	return h.worseThan(prev)
+}
+
+// GetHealth returns the last SMART health of each checked disk keyed by the device
+// it was last seen at
+func GetHealth() map[string]Health {
+	healthStateMu.Lock()
+	defer healthStateMu.Unlock()
+
+	health := make(map[string]Health, len(healthState))
+	for _, h := range healthState {
+		if prev, ok := health[h.Device]; ok && prev.Checked.After(h.Checked) {
+			continue
+		}
+		health[h.Device] = h
+	}
+	return health
+}
+
+// RunHealthMonitor checks SMART health every interval until the context is cancelled
+// and calls alert for every disk whose health degraded
+func RunHealthMonitor(ctx context.Context, interval time.Duration, alert func(Health)) {
+	check := func() {
+		for _, h := range CheckHealth() {
+			alert(h)
+		}
+	}
+	check()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			check()
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package disk
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestParseSMARTAttributes(t *testing.T) {
+	output := `smartctl 7.3 2022-02-28 r5338 [aarch64-linux-6.1.0] (local build)
+
+=== START OF READ SMART DATA SECTION ===
+SMART Attributes Data Structure revision number: 16
+Vendor Specific SMART Attributes with Thresholds:
+ID# ATTRIBUTE_NAME          FLAG     VALUE WORST THRESH TYPE      UPDATED  WHEN_FAILED RAW_VALUE
+  5 Reallocated_Sector_Ct   0x0033   100   100   010    Pre-fail  Always       -       8
+  9 Power_On_Hours          0x0032   096   096   000    Old_age   Always       -       18234
+194 Temperature_Celsius     0x0022   066   045   000    Old_age   Always       -       34 (Min/Max 20/45)
+197 Current_Pending_Sector  0x0012   100   100   000    Old_age   Always       -       0
+`
+	got := parseSMARTAttributes(output)
+	want := map[string]int64{
+		"Reallocated_Sector_Ct":  8,
+		"Power_On_Hours":         18234,
+		"Temperature_Celsius":    34,
+		"Current_Pending_Sector": 0,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d attributes, want %d: %v", len(got), len(want), got)
+	}
+	for name, v := range want {
+		if got[name] != v {
+			t.Errorf("%s = %d, want %d", name, got[name], v)
+		}
+	}
+}
+
+func TestParseSMARTHealth(t *testing.T) {
+	attrs := func(realloc, pending int) string {
+		return fmt.Sprintf(`ID# ATTRIBUTE_NAME          FLAG     VALUE WORST THRESH TYPE      UPDATED  WHEN_FAILED RAW_VALUE
+  5 Reallocated_Sector_Ct   0x0033   100   100   010    Pre-fail  Always       -       %d
+197 Current_Pending_Sector  0x0012   100   100   000    Old_age   Always       -       %d
+`, realloc, pending)
+	}
+
+	tests := []struct {
+		name        string
+		output      string
+		want        HealthStatus
+		wantSummary string
+	}{
+		{"passed", "SMART overall-health self-assessment test result: PASSED\n" + attrs(0, 0), HealthOK, "OK"},
+		{"bad sectors", "SMART overall-health self-assessment test result: PASSED\n" + attrs(8, 2), HealthWarn, "R:8 P:2"},
+		{"failed", "SMART overall-health self-assessment test result: FAILED!\n" + attrs(8, 2), HealthFailed, "FAIL"},
+		{"scsi ok", "SMART Health Status: OK\n", HealthOK, "OK"},
+		{"standby", "Device is in STANDBY mode, exit(2)\n", HealthUnknown, "--"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := parseSMARTHealth(tt.output)
+			if h.Status != tt.want || h.Summary() != tt.wantSummary {
+				t.Errorf("got %s %q, want %s %q", h.Status, h.Summary(), tt.want, tt.wantSummary)
+			}
+		})
+	}
+}
+
+func TestRecordHealthDegradation(t *testing.T) {
+	t.Cleanup(func() {
+		healthStateMu.Lock()
+		healthState = make(map[string]Health)
+		healthStateMu.Unlock()
+	})
+
+	dev := "/dev/sdz"
+	steps := []struct {
+		name     string
+		health   Health
+		degraded bool
+	}{
+		{"healthy when first seen", Health{Status: HealthOK}, false},
+		{"sectors reallocated", Health{Status: HealthWarn, Reallocated: 2}, true},
+		{"unchanged", Health{Status: HealthWarn, Reallocated: 2}, false},
+		{"more pending sectors", Health{Status: HealthWarn, Reallocated: 2, Pending: 1}, true},
+		{"self-assessment failed", Health{Status: HealthFailed, Reallocated: 2, Pending: 1}, true},
+		{"recovered", Health{Status: HealthOK}, false},
+	}
+
+	for i, step := range steps {
+		step.health.Device = dev
+		step.health.Checked = time.Unix(int64(i), 0)
+		if got := recordHealth(step.health); got != step.degraded {
+			t.Errorf("%s: degraded = %v, want %v", step.name, got, step.degraded)
+		}
+	}
+
+	if h, ok := GetHealth()[dev]; !ok || h.Status != HealthOK || h.ID != ID(dev) {
+		t.Errorf("GetHealth()[%s] = %+v, %v, want the latest OK result", dev, h, ok)
+	}
+}
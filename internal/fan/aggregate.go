@@ -0,0 +1,83 @@
+package fan
+
+import (
+	"github.com/kolobock/rockpi-quad-go/internal/logger"
+)
+
+const (
+	aggregateMax      = "max"
+	aggregateAvg      = "avg"
+	aggregateWeighted = "weighted"
+)
+
+// diskReading is a single disk temperature after applying its configured offset
+type diskReading struct {
+	device string
+	temp   float64
+}
+
+// TempAggregator combines per-disk temperatures into the value used for the disk fan curve
+type TempAggregator interface {
+	Aggregate(readings []diskReading) float64
+}
+
+type maxAggregator struct{}
+
+func (maxAggregator) Aggregate(readings []diskReading) float64 {
+	var maxTemp float64
+	for _, r := range readings {
+		if r.temp > maxTemp {
+			maxTemp = r.temp
+		}
+	}
+	return maxTemp
+}
+
+type avgAggregator struct{}
+
+func (avgAggregator) Aggregate(readings []diskReading) float64 {
+	if len(readings) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, r := range readings {
+		sum += r.temp
+	}
+	return sum / float64(len(readings))
+}
+
+// weightedAggregator averages temperatures by per-disk weight (default weight 1)
+type weightedAggregator struct {
+	weights map[string]float64
+}
+
+func (a weightedAggregator) Aggregate(readings []diskReading) float64 {
+	var sum, total float64
+	for _, r := range readings {
+		w, ok := a.weights[r.device]
+		if !ok {
+			w = 1
+		}
+		sum += r.temp * w
+		total += w
+	}
+	if total <= 0 {
+		return 0
+	}
+	return sum / total
+}
+
+// newTempAggregator returns the aggregation strategy for the configured mode
+func newTempAggregator(mode string, weights map[string]float64) TempAggregator {
+	switch mode {
+	case aggregateAvg:
+		return avgAggregator{}
+	case aggregateWeighted:
+		return weightedAggregator{weights: weights}
+	case aggregateMax, "":
+		return maxAggregator{}
+	default:
+		logger.Errorf("Unknown disk temperature mode %q, using %s", mode, aggregateMax)
+		return maxAggregator{}
+	}
+}
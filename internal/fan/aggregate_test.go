@@ -0,0 +1,38 @@
+package fan
+
+import "testing"
+
+func TestTempAggregators(t *testing.T) {
+	readings := []diskReading{
+		{device: "/dev/sda", temp: 30},
+		{device: "/dev/sdb", temp: 40},
+		{device: "/dev/sdc", temp: 50},
+	}
+
+	tests := []struct {
+		name       string
+		aggregator TempAggregator
+		want       float64
+	}{
+		{"max", newTempAggregator("max", nil), 50},
+		{"avg", newTempAggregator("avg", nil), 40},
+		{"weighted", newTempAggregator("weighted", map[string]float64{"/dev/sdc": 0, "/dev/sda": 3}), 32.5},
+		{"unknown falls back to max", newTempAggregator("median", nil), 50},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.aggregator.Aggregate(readings); got != tt.want {
+				t.Errorf("Aggregate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTempAggregatorsEmpty(t *testing.T) {
+	for _, mode := range []string{"max", "avg", "weighted"} {
+		if got := newTempAggregator(mode, nil).Aggregate(nil); got != 0 {
+			t.Errorf("%s Aggregate(nil) = %v, want 0", mode, got)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package fan
+
+import (
+	"time"
+
+	"github.com/kolobock/rockpi-quad-go/internal/disk"
+	"github.com/kolobock/rockpi-quad-go/internal/logger"
+)
+
+// diskPollInterval is how often disk temperatures are read outside burst sampling
+const diskPollInterval = 10 * time.Second
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// checkBurst switches to burst sampling once the CPU or disks come within
+// burst_margin of their maximum temperature, and back once both stayed below
+// that warning level for burst_cooldown seconds. It reports whether burst
+// sampling is active.
+func (c *Controller) checkBurst(now time.Time, cpuTemp, diskTemp float64) bool {
+	margin := c.cfg.Fan.BurstMargin
+	if margin <= 0 {
+		return false
+	}
+
+	warm := cpuTemp >= c.cfg.Fan.MaxCPUTemp-margin ||
+		(c.cfg.Fan.TempDisks && diskTemp >= c.cfg.Fan.MaxDiskTemp-margin)
+	switch {
+	case warm:
+		c.coolSince = time.Time{}
+		if !c.burst {
+			c.burst = true
+			logger.Errorf("Thermal warning: cpu %s, disk %s - sampling every %s",
+				c.cfg.Temp.Log.Celsius(cpuTemp), c.cfg.Temp.Log.Celsius(diskTemp), seconds(c.cfg.Fan.BurstInterval))
+			disk.SetTemperatureInterval(seconds(c.cfg.Fan.BurstDiskInterval))
+		}
+	case c.burst && c.coolSince.IsZero():
+		c.coolSince = now
+	case c.burst && now.Sub(c.coolSince) >= seconds(c.cfg.Fan.BurstCooldown):
+		c.burst = false
+		c.coolSince = time.Time{}
+		logger.Errorf("Thermal warning cleared (cpu: %s, disk: %s), normal sampling resumed",
+			c.cfg.Temp.Log.Celsius(cpuTemp), c.cfg.Temp.Log.Celsius(diskTemp))
+		disk.SetTemperatureInterval(0)
+	}
+	return c.burst
+}
+
+// sampleInterval returns how often Run reads temperatures and updates the fans
+func (c *Controller) sampleInterval() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.burst && c.cfg.Fan.BurstInterval > 0 {
+		return seconds(c.cfg.Fan.BurstInterval)
+	}
+	interval := time.Duration(c.cfg.Fan.Interval) * time.Second
+	if interval <= 0 {
+		interval = time.Second
+	}
+	return interval
+}
+
+// diskInterval returns how often disk temperatures are read; the caller holds c.mu
+func (c *Controller) diskInterval() time.Duration {
+	if c.burst && c.cfg.Fan.BurstDiskInterval > 0 {
+		return seconds(c.cfg.Fan.BurstDiskInterval)
+	}
+	return diskPollInterval
+}
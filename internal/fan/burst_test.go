@@ -0,0 +1,58 @@
+package fan
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kolobock/rockpi-quad-go/internal/config"
+)
+
+func TestCheckBurst(t *testing.T) {
+	cfg := &config.Config{
+		Fan: config.FanConfig{
+			MaxCPUTemp:        80,
+			MaxDiskTemp:       60,
+			TempDisks:         true,
+			Interval:          2,
+			BurstMargin:       5,
+			BurstInterval:     0.5,
+			BurstDiskInterval: 5,
+			BurstCooldown:     30,
+		},
+	}
+	ctrl := &Controller{cfg: cfg}
+	start := time.Now()
+
+	if ctrl.checkBurst(start, 74, 54) || ctrl.sampleInterval() != 2*time.Second {
+		t.Fatal("burst started below the warning level")
+	}
+	if !ctrl.checkBurst(start, 70, 55) {
+		t.Fatal("burst not started at max_disk_temp - burst_margin")
+	}
+	if ctrl.sampleInterval() != 500*time.Millisecond || ctrl.diskInterval() != 5*time.Second {
+		t.Errorf("burst intervals = %v/%v, want 500ms/5s", ctrl.sampleInterval(), ctrl.diskInterval())
+	}
+
+	if !ctrl.checkBurst(start.Add(time.Second), 60, 40) {
+		t.Error("burst ended without cooldown")
+	}
+	if !ctrl.checkBurst(start.Add(20*time.Second), 76, 40) {
+		t.Error("burst ended while warm")
+	}
+	if !ctrl.checkBurst(start.Add(21*time.Second), 60, 40) || !ctrl.checkBurst(start.Add(50*time.Second), 60, 40) {
+		t.Error("burst ended before burst_cooldown after the last warm reading")
+	}
+	if ctrl.checkBurst(start.Add(51*time.Second), 60, 40) {
+		t.Error("burst still active after burst_cooldown")
+	}
+	if ctrl.sampleInterval() != 2*time.Second || ctrl.diskInterval() != diskPollInterval {
+		t.Errorf("intervals after burst = %v/%v, want 2s/%v", ctrl.sampleInterval(), ctrl.diskInterval(), diskPollInterval)
+	}
+}
+
+func TestCheckBurstDisabled(t *testing.T) {
+	ctrl := &Controller{cfg: &config.Config{Fan: config.FanConfig{MaxCPUTemp: 80, MaxDiskTemp: 60}}}
+	if ctrl.checkBurst(time.Now(), 90, 90) {
+		t.Error("burst active with burst_margin = 0")
+	}
+}
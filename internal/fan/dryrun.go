@@ -0,0 +1,77 @@
+package fan
+
+import (
+	"github.com/kolobock/rockpi-quad-go/internal/logger"
+	"github.com/kolobock/rockpi-quad-go/pkg/pwm"
+)
+
+// channel is a fan PWM output: a sysfs PWM channel, or in dry-run mode a stand-in
+// that leaves the channel alone
+type channel interface {
+	SetDutyCycle(dutyCycle float64) error
+	Healthy() bool
+	Failures() int
+	Close() error
+}
+
+// dryChannel takes duty cycles without writing them and reads back the duty cycle
+// the channel is really at, so a new curve can be compared with the running one
+type dryChannel struct {
+	actual dutyReader
+}
+
+func (*dryChannel) SetDutyCycle(float64) error { return nil }
+func (*dryChannel) Healthy() bool              { return true }
+func (*dryChannel) Failures() int              { return 0 }
+func (*dryChannel) Close() error               { return nil }
+
+// dryLine stands in for the fan power GPIO line in dry-run mode
+type dryLine struct{}
+
+func (dryLine) SetValue(int) error { return nil }
+func (dryLine) Close() error       { return nil }
+
+// openChannel exports and enables a fan PWM channel; in dry-run mode nothing is
+// written to sysfs
+func (c *Controller) openChannel(chip string, ch int, polarity string) (channel, error) {
+	if c.dryRun {
+		return &dryChannel{actual: channelDuty(chip, ch, polarity == polarityInversed)}, nil
+	}
+	p, err := pwm.New(chip, ch, c.cfg.Fan.PWMFrequency)
+	if err != nil {
+		return nil, err
+	}
+	if polarity == polarityInversed {
+		p.SetInversed(true)
+	}
+	return p, nil
+}
+
+// actualPercent is the duty cycle (0-100) a dry-run channel is really at, 0 when it
+// cannot be read
+func actualPercent(ch channel) float64 {
+	d, ok := ch.(*dryChannel)
+	if !ok {
+		return 0
+	}
+	dc, err := d.actual()
+	if err != nil {
+		return 0
+	}
+	return dc * 100
+}
+
+// logDuty logs the applied duty cycles when they changed. In dry-run mode the
+// computed duty cycles are logged every cycle next to the ones actually set.
+func (c *Controller) logDuty(changed bool, cpuTemp, diskTemp float64) {
+	if c.dryRun {
+		logger.Infof("dry run: cpu_temp: %.2f, cpu_dc: %.2f (actual %.2f), disk_temp: %.2f, disk_dc: %.2f (actual %.2f)",
+			cpuTemp, c.lastCPUDC*100, actualPercent(c.cpuPWM), diskTemp, c.lastDiskDC*100, actualPercent(c.diskPWM))
+		return
+	}
+	if changed {
+		fansRunning := c.enabled && (c.lastCPUDC > 0 || c.lastDiskDC > 0)
+		logger.Infof("cpu_temp: %.2f, cpu_dc: %.2f, disk_temp: %.2f, disk_dc: %.2f, run: %t",
+			cpuTemp, c.lastCPUDC*100, diskTemp, c.lastDiskDC*100, fansRunning)
+	}
+}
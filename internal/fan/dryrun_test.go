@@ -0,0 +1,54 @@
+package fan
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kolobock/rockpi-quad-go/internal/config"
+)
+
+func TestDryRun(t *testing.T) {
+	dir := t.TempDir()
+	old := sysClassPWM
+	sysClassPWM = dir
+	defer func() { sysClassPWM = old }()
+
+	duty := filepath.Join(dir, "pwmchip0", "pwm0", "duty_cycle")
+	writeFile(t, filepath.Join(dir, "pwmchip0", "pwm0", "period"), "40000\n")
+	writeFile(t, duty, "10000\n")
+
+	ctrl, err := New(&config.Config{Fan: config.FanConfig{
+		DryRun:        true,
+		Profile:       ProfileBalanced,
+		CPUPWMChip:    "pwmchip0",
+		CPUPWMChannel: 0,
+		TBPWMChip:     "pwmchip0",
+		TBPWMChannel:  1,
+	}})
+	if err != nil {
+		t.Fatalf("New() = %v, want no PWM channels opened", err)
+	}
+
+	if _, err := ctrl.applyDuty(ctrl.cpuPWM, 0.75, &ctrl.lastCPUDC, &ctrl.cpuKickUntil, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	ctrl.ToggleFan()
+	ctrl.ToggleFan()
+	if err := ctrl.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, _ := os.ReadFile(duty); string(got) != "10000\n" {
+		t.Errorf("duty_cycle = %q, want it untouched", got)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "pwmchip0", "export")); !os.IsNotExist(err) {
+		t.Errorf("export written, want no sysfs writes")
+	}
+
+	st := ctrl.Status()
+	if !st.DryRun || st.CPUDuty != 100 || st.ActualCPUDuty != 25 || st.ActualDiskDuty != 0 {
+		t.Errorf("status = %+v, want dry run with cpu duty 100 (toggled), actual 25 and no disk reading", st)
+	}
+}
@@ -0,0 +1,82 @@
+package fan
+
+import (
+	"os/exec"
+	"time"
+
+	"github.com/kolobock/rockpi-quad-go/internal/diag"
+	"github.com/kolobock/rockpi-quad-go/internal/logger"
+)
+
+// checkEmergency tracks how long the CPU or disks have been above their maximum
+// temperature and reports whether emergency mode is active. Emergency mode starts once
+// the overheat lasted emergency_seconds and ends as soon as both temperatures recover.
+func (c *Controller) checkEmergency(now time.Time, cpuTemp, diskTemp float64) bool {
+	if !c.cfg.Fan.Emergency {
+		return false
+	}
+
+	overheated := cpuTemp >= c.cfg.Fan.MaxCPUTemp || diskTemp >= c.cfg.Fan.MaxDiskTemp
+	if !overheated {
+		if c.emergency {
+			logger.Errorf("Thermal emergency cleared (cpu: %s, disk: %s)", c.cfg.Temp.Log.Celsius(cpuTemp), c.cfg.Temp.Log.Celsius(diskTemp))
+		}
+		c.overheatSince = time.Time{}
+		c.emergency = false
+		return false
+	}
+
+	if c.overheatSince.IsZero() {
+		c.overheatSince = now
+	}
+	if c.emergency || now.Sub(c.overheatSince) < time.Duration(c.cfg.Fan.EmergencySeconds*float64(time.Second)) {
+		return c.emergency
+	}
+
+	c.emergency = true
+	logger.Errorf("Thermal emergency: cpu %s (max %g), disk %s (max %g) - forcing fans to 100%%",
+		c.cfg.Temp.Log.Celsius(cpuTemp), c.cfg.Fan.MaxCPUTemp, c.cfg.Temp.Log.Celsius(diskTemp), c.cfg.Fan.MaxDiskTemp)
+	if c.cfg.Fan.EmergencyCommand != "" {
+		runEmergencyCommand(c.cfg.Fan.EmergencyCommand)
+	}
+	return true
+}
+
+// applyEmergency forces every fan to full speed, ignoring the enabled state and overrides
+func (c *Controller) applyEmergency() error {
+	if c.fanSwitch != nil {
+		if err := c.fanSwitch.set(true); err != nil {
+			return err
+		}
+	}
+	if c.cpuPWM != nil && c.lastCPUDC != 1.0 {
+		if err := c.cpuPWM.SetDutyCycle(1.0); err != nil {
+			return err
+		}
+	}
+	if c.diskPWM != nil && c.lastDiskDC != 1.0 {
+		if err := c.diskPWM.SetDutyCycle(1.0); err != nil {
+			return err
+		}
+	}
+	c.lastCPUDC, c.lastDiskDC = 1.0, 1.0
+	return c.setHATFans(1.0)
+}
+
+// EmergencyActive reports whether the fans are forced on by thermal protection
+func (c *Controller) EmergencyActive() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.emergency
+}
+
+func runEmergencyCommand(command string) {
+	go func() {
+		logger.Errorf("Running emergency command: %s", command)
+		diag.ShellExecs.Add(1)
+		// #nosec G204 - command comes from the config file
+		if out, err := exec.Command("sh", "-c", command).CombinedOutput(); err != nil {
+			logger.Errorf("Emergency command failed: %v: %s", err, out)
+		}
+	}()
+}
@@ -0,0 +1,55 @@
+package fan
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kolobock/rockpi-quad-go/internal/config"
+)
+
+func TestCheckEmergency(t *testing.T) {
+	cfg := &config.Config{
+		Fan: config.FanConfig{
+			MaxCPUTemp:       80,
+			MaxDiskTemp:      60,
+			Emergency:        true,
+			EmergencySeconds: 10,
+		},
+	}
+	ctrl := &Controller{cfg: cfg}
+	start := time.Now()
+
+	if ctrl.checkEmergency(start, 85, 40) {
+		t.Error("emergency started before emergency_seconds elapsed")
+	}
+	if ctrl.checkEmergency(start.Add(5*time.Second), 85, 40) {
+		t.Error("emergency started after 5s, want 10s")
+	}
+	if !ctrl.checkEmergency(start.Add(10*time.Second), 70, 65) {
+		t.Error("emergency not started after 10s of disk overheat")
+	}
+	if !ctrl.checkEmergency(start.Add(11*time.Second), 85, 40) {
+		t.Error("emergency ended while still overheated")
+	}
+	if ctrl.checkEmergency(start.Add(12*time.Second), 70, 40) {
+		t.Error("emergency still active after temperatures recovered")
+	}
+
+	// A recovery resets the timer
+	if ctrl.checkEmergency(start.Add(13*time.Second), 85, 40) {
+		t.Error("emergency restarted immediately after recovery")
+	}
+}
+
+func TestCheckEmergencyDisabled(t *testing.T) {
+	cfg := &config.Config{
+		Fan: config.FanConfig{MaxCPUTemp: 80, MaxDiskTemp: 60},
+	}
+	ctrl := &Controller{cfg: cfg}
+	start := time.Now()
+
+	ctrl.checkEmergency(start, 90, 90)
+	if ctrl.checkEmergency(start.Add(time.Minute), 90, 90) {
+		t.Error("emergency active while disabled")
+	}
+}
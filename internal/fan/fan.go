@@ -3,16 +3,16 @@ package fan
 import (
 	"context"
 	"fmt"
-	"os"
-	"strconv"
-	"strings"
+	"math"
 	"sync"
 	"time"
 
+	"github.com/kolobock/rockpi-quad-go/internal/clock"
 	"github.com/kolobock/rockpi-quad-go/internal/config"
+	"github.com/kolobock/rockpi-quad-go/internal/diag"
 	"github.com/kolobock/rockpi-quad-go/internal/disk"
 	"github.com/kolobock/rockpi-quad-go/internal/logger"
-	"github.com/kolobock/rockpi-quad-go/pkg/pwm"
+	"github.com/kolobock/rockpi-quad-go/internal/thermal"
 )
 
 const (
@@ -22,8 +22,8 @@ const (
 
 type Controller struct {
 	cfg     *config.Config
-	cpuPWM  *pwm.PWM
-	diskPWM *pwm.PWM
+	cpuPWM  channel
+	diskPWM channel
 
 	lastCPUDC    float64
 	lastDiskDC   float64
@@ -31,35 +31,123 @@ type Controller struct {
 	lastDiskTemp float64
 	enabled      bool
 	mu           sync.Mutex
+
+	profile       string
+	schedule      []scheduleEntry
+	lastScheduled string
+	clock         *clock.Clock
+
+	overrideDC    float64
+	overrideUntil time.Time
+
+	aggregator TempAggregator
+
+	cpuSmoother  *tempSmoother
+	diskSmoother *tempSmoother
+
+	cpuPredictor  *tempPredictor
+	diskPredictor *tempPredictor
+
+	cpuKickUntil  time.Time
+	diskKickUntil time.Time
+
+	fanSwitch *gpioSwitch
+	observer  *observer
+	// dryRun computes duty cycles without writing them, see openChannel
+	dryRun bool
+
+	// hatFans are the top-board fans of stacked HATs; hatTemps holds the disk
+	// temperature of every HAT by ID while HATs are stacked
+	hatFans  []*hatFan
+	hatTemps map[string]float64
+
+	overheatSince time.Time
+	emergency     bool
+
+	burst     bool
+	coolSince time.Time
+
+	startup *ThermalReport
+
+	lastCPUTemp float64
+
+	cpuSensorFailures  int
+	diskSensorFailures int
 }
 
 func New(cfg *config.Config) (*Controller, error) {
+	schedule, err := parseSchedule(cfg.Fan.Schedule)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse fan schedule: %w", err)
+	}
+
 	ctrl := &Controller{
-		cfg:      cfg,
-		lastTemp: time.Now().Add(-time.Hour),
-		enabled:  true,
+		cfg:        cfg,
+		lastTemp:   time.Now().Add(-time.Hour),
+		enabled:    true,
+		profile:    ProfileBalanced,
+		schedule:   schedule,
+		clock:      clock.New(cfg.Time.Timezone),
+		aggregator: newTempAggregator(cfg.Fan.DiskTempMode, cfg.Fan.DiskTempWeights),
+	}
+	if cfg.Fan.SmoothingSeconds > 0 {
+		window := time.Duration(cfg.Fan.SmoothingSeconds) * time.Second
+		ctrl.cpuSmoother = newTempSmoother(window)
+		ctrl.diskSmoother = newTempSmoother(window)
+	}
+	if cfg.Fan.PredictSeconds > 0 {
+		window, lead := seconds(cfg.Fan.PredictWindow), seconds(cfg.Fan.PredictSeconds)
+		ctrl.cpuPredictor = newTempPredictor(window, lead, cfg.Fan.PredictDecay)
+		ctrl.diskPredictor = newTempPredictor(window, lead, cfg.Fan.PredictDecay)
+	}
+	if err := ctrl.SetProfile(cfg.Fan.Profile); err != nil {
+		return nil, err
 	}
 
-	cpuPWM, err := pwm.New(cfg.Fan.CPUPWMChip, cfg.Fan.CPUPWMChannel)
+	mode := cfg.Fan.Mode
+	if mode != modeGPIO && mode != modeObserve && cfg.Fan.AutoObserve {
+		if name, ok := detectFanDaemon(); ok {
+			logger.Errorf("%s is running - observing its fan duty cycles instead of driving the fans", name)
+			mode = modeObserve
+		}
+	}
+	if mode == modeObserve {
+		ctrl.observer = newObserver(cfg)
+		return ctrl, nil
+	}
+
+	ctrl.dryRun = cfg.Fan.DryRun
+	if ctrl.dryRun {
+		logger.Errorf("Fan dry run: duty cycles are computed and reported but not written to the fans")
+	}
+
+	if mode == modeGPIO {
+		fanSwitch, err := newGPIOSwitch(cfg.Env.FanChip, cfg.Env.FanLine, cfg.Fan.GPIOOnTemp, cfg.Fan.GPIOOffTemp, cfg.Fan.DryRun)
+		if err != nil {
+			return nil, fmt.Errorf("failed to init GPIO fan switch: %w", err)
+		}
+		ctrl.fanSwitch = fanSwitch
+		return ctrl, nil
+	}
+
+	cpuPWM, err := ctrl.openChannel(cfg.Fan.CPUPWMChip, cfg.Fan.CPUPWMChannel, cfg.Fan.CPUPolarity)
 	if err != nil {
 		return nil, fmt.Errorf("failed to init CPU PWM: %w", err)
 	}
 	ctrl.cpuPWM = cpuPWM
 
-	if cfg.Fan.Polarity == polarityInversed {
-		cpuPWM.SetInversed(true)
-	}
-
-	if cfg.Fan.TBPWMChannel != cfg.Fan.CPUPWMChannel {
-		diskPWM, err := pwm.New(cfg.Fan.TBPWMChip, cfg.Fan.TBPWMChannel)
+	if cfg.Fan.TBPWMChip != cfg.Fan.CPUPWMChip || cfg.Fan.TBPWMChannel != cfg.Fan.CPUPWMChannel {
+		diskPWM, err := ctrl.openChannel(cfg.Fan.TBPWMChip, cfg.Fan.TBPWMChannel, cfg.Fan.TBPolarity)
 		if err != nil {
 			cpuPWM.Close()
 			return nil, fmt.Errorf("failed to init disk PWM: %w", err)
 		}
 		ctrl.diskPWM = diskPWM
-		if cfg.Fan.Polarity == polarityInversed {
-			diskPWM.SetInversed(true)
-		}
+	}
+
+	if err := ctrl.newHATFans(); err != nil {
+		ctrl.Close()
+		return nil, err
 	}
 
 	return ctrl, nil
@@ -70,17 +158,27 @@ func (c *Controller) ToggleFan() {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	if c.observer != nil {
+		logger.Infoln("Fan toggle ignored - fans are driven by another program")
+		return
+	}
+
 	c.enabled = !c.enabled
+	c.clearOverride()
 
 	if c.enabled {
 		logger.Infoln("Fan control enabled - temperature-based control resumed")
 	} else {
-		fullSpeed := 100.0
-		if c.cfg.Fan.Polarity == polarityInversed {
-			fullSpeed = 0.0
-		}
+		// Polarity is applied by each PWM channel, so full speed is always 1.0
+		fullSpeed := 1.0
 
-		logger.Infof("Fan control disabled - setting fans to full speed (DC: %.0f%%)", fullSpeed)
+		logger.Infoln("Fan control disabled - setting fans to full speed")
+		if c.fanSwitch != nil {
+			if err := c.fanSwitch.set(true); err != nil {
+				logger.Errorf("Failed to switch fan on: %v", err)
+			}
+			c.lastCPUDC, c.lastDiskDC = 1.0, 1.0
+		}
 		if c.cpuPWM != nil {
 			if err := c.cpuPWM.SetDutyCycle(fullSpeed); err != nil {
 				logger.Errorf("Failed to set CPU fan duty cycle: %v", err)
@@ -93,11 +191,18 @@ func (c *Controller) ToggleFan() {
 			}
 			c.lastDiskDC = fullSpeed
 		}
+		if err := c.setHATFans(fullSpeed); err != nil {
+			logger.Errorf("Failed to set HAT fan duty cycle: %v", err)
+		}
 	}
 }
 
 func (c *Controller) Run(ctx context.Context) error {
-	ticker := time.NewTicker(time.Second)
+	c.applySchedule(c.clock.Now())
+	c.reportStartup()
+
+	interval := c.sampleInterval()
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	for {
@@ -105,9 +210,16 @@ func (c *Controller) Run(ctx context.Context) error {
 		case <-ctx.Done():
 			return nil
 		case <-ticker.C:
+			c.applySchedule(c.clock.Now())
+			start := time.Now()
 			if err := c.update(); err != nil {
 				logger.Errorf("Fan update error: %v", err)
 			}
+			diag.ObserveLoop("fan", time.Since(start))
+			if next := c.sampleInterval(); next != interval {
+				interval = next
+				ticker.Reset(interval)
+			}
 		}
 	}
 }
@@ -116,15 +228,51 @@ func (c *Controller) update() error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	cpuTemp, diskTemp := c.getTemperatures()
+	c.lastCPUTemp = cpuTemp
+	now := time.Now()
+	c.checkBurst(now, cpuTemp, diskTemp)
+	if c.observer != nil {
+		// The fans belong to the other program, except in an emergency
+		if c.checkEmergency(now, cpuTemp, diskTemp) {
+			return c.forceFull()
+		}
+		c.observe(cpuTemp, diskTemp)
+		return nil
+	}
+	if c.checkEmergency(now, cpuTemp, diskTemp) {
+		return c.applyEmergency()
+	}
+
 	if !c.enabled {
 		return nil
 	}
 
-	cpuTemp, diskTemp := c.getTemperatures()
+	// With stacked HATs the main disk fan only follows the disks on the main HAT
+	if c.hatTemps != nil && c.fanSwitch == nil {
+		diskTemp = c.hatTemps[config.MainHAT]
+	}
+
+	cpuTemp = c.cpuSmoother.add(now, cpuTemp)
+	diskTemp = c.diskSmoother.add(now, diskTemp)
+	cpuTemp = c.cpuPredictor.add(now, cpuTemp)
+	diskTemp = c.diskPredictor.add(now, diskTemp)
 
 	cpuDC := c.calculateDutyCycle(cpuTemp, 'c')
 	diskDC := c.calculateDutyCycle(diskTemp, 'f')
 
+	dc, forced := c.activeOverride(now)
+	if c.sensorFailed() {
+		dc, forced = c.cfg.Fan.SafeDuty/100, true
+	}
+	if forced {
+		cpuDC, diskDC = dc, dc
+	}
+
+	if c.fanSwitch != nil {
+		return c.updateSwitch(max(cpuTemp, diskTemp), dc, forced)
+	}
+
 	if cpuDC > 0 && cpuDC < MinDutyCycle {
 		cpuDC = MinDutyCycle
 	}
@@ -132,38 +280,102 @@ func (c *Controller) update() error {
 		diskDC = MinDutyCycle
 	}
 
-	if cpuDC != c.lastCPUDC {
-		if err := c.cpuPWM.SetDutyCycle(cpuDC); err != nil {
-			return err
-		}
-		c.lastCPUDC = cpuDC
+	cpuChanged, err := c.applyDuty(c.cpuPWM, cpuDC, &c.lastCPUDC, &c.cpuKickUntil, now)
+	if err != nil {
+		return err
+	}
+	diskChanged, err := c.applyDuty(c.diskPWM, diskDC, &c.lastDiskDC, &c.diskKickUntil, now)
+	if err != nil {
+		return err
+	}
+	changed := cpuChanged || diskChanged
+	if err := c.updateHATFans(now, dc, forced); err != nil {
+		return err
 	}
 
-	if c.diskPWM != nil {
-		if diskDC != c.lastDiskDC {
-			if err := c.diskPWM.SetDutyCycle(diskDC); err != nil {
-				return err
-			}
-			c.lastDiskDC = diskDC
+	c.logDuty(changed, cpuTemp, diskTemp)
+	return nil
+}
+
+// updateSwitch drives the GPIO fan switch from the hottest temperature. A forced duty
+// cycle (override or sensor failure) sets the state: on for any non-zero duty, off otherwise.
+func (c *Controller) updateSwitch(temp, forcedDC float64, forced bool) error {
+	var on bool
+	var err error
+	if forced {
+		on, err = forcedDC > 0, c.fanSwitch.set(forcedDC > 0)
+	} else {
+		on, err = c.fanSwitch.update(temp)
+	}
+	if err != nil {
+		return err
+	}
+
+	dc := 0.0
+	if on {
+		dc = 1.0
+	}
+	if dc != c.lastCPUDC {
+		logger.Infof("temp: %.2f, fan switch: %t", temp, on)
+	}
+	c.lastCPUDC, c.lastDiskDC = dc, dc
+	return nil
+}
+
+// applyDuty writes a new duty cycle to a channel if it changed enough. When a stopped
+// fan is asked to spin slowly it is first kick-started at full speed for the configured time.
+func (c *Controller) applyDuty(p channel, dc float64, last *float64, kickUntil *time.Time, now time.Time) (bool, error) {
+	if p == nil {
+		return false, nil
+	}
+	if dc > 0 && now.Before(*kickUntil) {
+		return false, nil
+	}
+
+	kick := time.Duration(c.cfg.Fan.KickstartSeconds * float64(time.Second))
+	if kick > 0 && *last == 0 && dc > 0 && dc*100 < c.cfg.Fan.KickstartThreshold {
+		if err := p.SetDutyCycle(1.0); err != nil {
+			return false, err
 		}
+		logger.Infof("Kick-starting fan at full speed for %s before settling at %.0f%%", kick, dc*100)
+		*last = 1.0
+		*kickUntil = now.Add(kick)
+		return true, nil
 	}
 
-	fansRunning := c.enabled && (cpuDC > 0 || diskDC > 0)
-	logger.Infof("cpu_temp: %.2f, cpu_dc: %.2f, disk_temp: %.2f, disk_dc: %.2f, run: %t",
-		cpuTemp, cpuDC*100, diskTemp, diskDC*100, fansRunning)
+	if !c.shouldApply(dc, *last) {
+		return false, nil
+	}
+	if err := p.SetDutyCycle(dc); err != nil {
+		return false, err
+	}
+	*last = dc
+	return true, nil
+}
 
-	return nil
+// shouldApply reports whether a new duty cycle differs enough from the last applied one.
+// Switching fully off or to full speed is always applied.
+func (c *Controller) shouldApply(dc, last float64) bool {
+	if dc == last {
+		return false
+	}
+	if dc == 0 || dc == 1.0 {
+		return true
+	}
+	return math.Abs(dc-last)*100 >= c.cfg.Fan.MinChange
 }
 
 func (c *Controller) getTemperatures() (cpuTemp, diskTemp float64) {
-	if data, err := os.ReadFile("/sys/class/thermal/thermal_zone0/temp"); err == nil {
-		if temp, err := strconv.ParseFloat(strings.TrimSpace(string(data)), 64); err == nil {
-			cpuTemp = temp / 1000.0
-		}
+	cpuTemp, err := thermal.Read(c.cfg.Fan.CPUSensors, c.cfg.Fan.CPUSensorMode)
+	if err != nil {
+		logger.Infof("Failed to read CPU temperature: %v", err)
 	}
+	c.recordSensor("CPU", &c.cpuSensorFailures, err == nil)
 
-	if c.cfg.Fan.TempDisks && time.Since(c.lastTemp) > 10*time.Second {
-		c.lastDiskTemp = c.getMaxDiskTemp()
+	if c.cfg.Fan.TempDisks && time.Since(c.lastTemp) > c.diskInterval() {
+		temp, ok := c.getDiskTemp()
+		c.recordSensor("disk", &c.diskSensorFailures, ok)
+		c.lastDiskTemp = temp
 		c.lastTemp = time.Now()
 	}
 	diskTemp = c.lastDiskTemp
@@ -171,37 +383,63 @@ func (c *Controller) getTemperatures() (cpuTemp, diskTemp float64) {
 	return cpuTemp, diskTemp
 }
 
-func (c *Controller) getMaxDiskTemp() float64 {
+// getDiskTemp reads every disk, applies per-disk offsets and combines the
+// readings with the configured aggregation strategy. It reports false when
+// disks are present but none of them could be read.
+func (c *Controller) getDiskTemp() (float64, bool) {
 	disks := disk.GetSATADisks()
 	if len(disks) == 0 {
-		return 0.01
+		return 0.01, true
 	}
 
-	var maxTemp float64
+	readings := make([]diskReading, 0, len(disks))
 	for _, diskDev := range disks {
 		temp, err := disk.GetTemperature(diskDev)
 		if err != nil {
 			continue
 		}
-		if temp > maxTemp {
-			maxTemp = temp
-		}
+		offset := c.cfg.Fan.DiskTempOffsets[diskDev]
+		readings = append(readings, diskReading{device: diskDev, temp: temp + offset})
+		logger.Infof("disk %s: temp %.1f, offset %+.1f, effective %.1f", diskDev, temp, offset, temp+offset)
 	}
 
-	return maxTemp
-}
+	if len(readings) == 0 {
+		return 0, false
+	}
 
-func (c *Controller) calculateDutyCycle(temp float64, key byte) float64 {
-	var lv0, lv1, lv2, lv3, maxTemp float64
+	aggregator := c.aggregator
+	if aggregator == nil {
+		aggregator = maxAggregator{}
+	}
+	if len(c.cfg.HATs) > 1 {
+		c.recordHATTemps(aggregator, readings)
+	}
+	return aggregator.Aggregate(readings), true
+}
 
+// curve returns the lv0-lv3 thresholds of the active profile and the maximum
+// temperature for the CPU ('c') or disk ('f') fan
+func (c *Controller) curve(key byte) (levels [4]float64, maxTemp float64) {
 	if key == 'c' {
-		lv0, lv1, lv2, lv3 = c.cfg.Fan.LV0C, c.cfg.Fan.LV1C, c.cfg.Fan.LV2C, c.cfg.Fan.LV3C
+		levels = [4]float64{c.cfg.Fan.LV0C, c.cfg.Fan.LV1C, c.cfg.Fan.LV2C, c.cfg.Fan.LV3C}
 		maxTemp = c.cfg.Fan.MaxCPUTemp
 	} else {
-		lv0, lv1, lv2, lv3 = c.cfg.Fan.LV0F, c.cfg.Fan.LV1F, c.cfg.Fan.LV2F, c.cfg.Fan.LV3F
+		levels = [4]float64{c.cfg.Fan.LV0F, c.cfg.Fan.LV1F, c.cfg.Fan.LV2F, c.cfg.Fan.LV3F}
 		maxTemp = c.cfg.Fan.MaxDiskTemp
 	}
 
+	if offset := profileOffsets[c.profile]; offset != 0 {
+		for i := range levels {
+			levels[i] = shiftLevel(levels[i], offset, maxTemp)
+		}
+	}
+	return levels, maxTemp
+}
+
+func (c *Controller) calculateDutyCycle(temp float64, key byte) float64 {
+	levels, maxTemp := c.curve(key)
+	lv0, lv1, lv2, lv3 := levels[0], levels[1], levels[2], levels[3]
+
 	if c.cfg.Fan.Linear {
 		return c.linearInterpolate(temp, lv0, lv1, lv2, lv3, maxTemp)
 	}
@@ -218,6 +456,11 @@ func (c *Controller) calculateDutyCycle(temp float64, key byte) float64 {
 	return 1.0
 }
 
+// shiftLevel moves a threshold by the profile offset without exceeding the max temperature
+func shiftLevel(level, offset, maxTemp float64) float64 {
+	return min(level+offset, maxTemp)
+}
+
 func (c *Controller) linearInterpolate(temp, lv0, lv1, lv2, lv3, maxTemp float64) float64 {
 	if temp < lv0 {
 		return 0
@@ -244,6 +487,9 @@ func (c *Controller) GetFanSpeeds() (cpuPercent, diskPercent float64) {
 }
 
 func (c *Controller) Close() error {
+	if c.fanSwitch != nil {
+		c.fanSwitch.Close()
+	}
 	if c.cpuPWM != nil {
 		if err := c.cpuPWM.SetDutyCycle(0); err != nil {
 			logger.Errorf("Failed to reset CPU PWM duty cycle: %v", err)
@@ -256,5 +502,11 @@ func (c *Controller) Close() error {
 		}
 		c.diskPWM.Close()
 	}
+	for _, f := range c.hatFans {
+		if err := f.pwm.SetDutyCycle(0); err != nil {
+			logger.Errorf("Failed to reset %s PWM duty cycle: %v", f.hat.Label, err)
+		}
+		f.pwm.Close()
+	}
 	return nil
 }
@@ -2,6 +2,7 @@ package fan
 
 import (
 	"testing"
+	"time"
 
 	"github.com/kolobock/rockpi-quad-go/internal/config"
 )
@@ -59,3 +60,57 @@ func TestGetFanSpeeds(t *testing.T) {
 		t.Errorf("Disk fan speed = %v%%, want 75.0%%", diskPercent)
 	}
 }
+
+func TestOverrideExpires(t *testing.T) {
+	ctrl := &Controller{}
+
+	if err := ctrl.SetOverride(60, time.Minute); err != nil {
+		t.Fatalf("SetOverride failed: %v", err)
+	}
+	if dc, ok := ctrl.activeOverride(time.Now()); !ok || dc != 0.6 {
+		t.Errorf("activeOverride = %v, %v; want 0.6, true", dc, ok)
+	}
+	if _, ok := ctrl.activeOverride(time.Now().Add(2 * time.Minute)); ok {
+		t.Error("override should have expired")
+	}
+	if ctrl.OverrideRemaining() != 0 {
+		t.Errorf("OverrideRemaining = %v, want 0", ctrl.OverrideRemaining())
+	}
+
+	if err := ctrl.SetOverride(120, time.Minute); err == nil {
+		t.Error("SetOverride(120) expected error, got nil")
+	}
+}
+
+func TestShouldApply(t *testing.T) {
+	ctrl := &Controller{cfg: &config.Config{Fan: config.FanConfig{MinChange: 5}}}
+
+	tests := []struct {
+		name     string
+		dc, last float64
+		want     bool
+	}{
+		{"unchanged", 0.5, 0.5, false},
+		{"small change", 0.52, 0.5, false},
+		{"large change", 0.6, 0.5, true},
+		{"switch off", 0, 0.02, true},
+		{"full speed", 1.0, 0.98, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ctrl.shouldApply(tt.dc, tt.last); got != tt.want {
+				t.Errorf("shouldApply(%v, %v) = %v, want %v", tt.dc, tt.last, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStatus(t *testing.T) {
+	ctrl := &Controller{enabled: true, profile: ProfileSilent, lastCPUDC: 0.25}
+
+	st := ctrl.Status()
+	if !st.Enabled || st.Profile != ProfileSilent || st.CPUDuty != 25 || !st.PWMHealthy {
+		t.Errorf("Status() = %+v", st)
+	}
+}
@@ -0,0 +1,99 @@
+package fan
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/warthog618/go-gpiocdev"
+
+	"github.com/kolobock/rockpi-quad-go/internal/logger"
+)
+
+const modeGPIO = "gpio"
+
+// lineSetter is the subset of gpiocdev.Line used to switch fan power
+type lineSetter interface {
+	SetValue(value int) error
+	Close() error
+}
+
+// gpioSwitch turns fan power on and off through a GPIO line with hysteresis
+type gpioSwitch struct {
+	line    lineSetter
+	on      bool
+	onTemp  float64
+	offTemp float64
+}
+
+// newGPIOSwitch requests the fan power line; in dry-run mode the line is left alone
+func newGPIOSwitch(chip, line string, onTemp, offTemp float64, dryRun bool) (*gpioSwitch, error) {
+	if line == "" {
+		return nil, fmt.Errorf("FAN_LINE is not configured")
+	}
+	if offTemp > onTemp {
+		return nil, fmt.Errorf("gpio_off_temp %.1f must not exceed gpio_on_temp %.1f", offTemp, onTemp)
+	}
+
+	if chip == "" {
+		chip = "gpiochip0"
+	}
+	var chipNum int
+	if _, err := fmt.Sscanf(chip, "%d", &chipNum); err == nil {
+		chip = "gpiochip" + chip
+	}
+	if !strings.HasPrefix(chip, "/dev/") {
+		chip = "/dev/" + chip
+	}
+
+	lineNum := 0
+	if _, err := fmt.Sscanf(line, "%d", &lineNum); err != nil {
+		return nil, fmt.Errorf("invalid FAN_LINE: %s", line)
+	}
+
+	if dryRun {
+		return &gpioSwitch{line: dryLine{}, onTemp: onTemp, offTemp: offTemp}, nil
+	}
+
+	l, err := gpiocdev.RequestLine(chip, lineNum, gpiocdev.AsOutput(0))
+	if err != nil {
+		return nil, fmt.Errorf("failed to request fan line: %w", err)
+	}
+	logger.Infof("GPIO fan switch on %s line %d (on >= %.1f°C, off <= %.1f°C)", chip, lineNum, onTemp, offTemp)
+
+	return &gpioSwitch{line: l, onTemp: onTemp, offTemp: offTemp}, nil
+}
+
+// update decides the fan state for a temperature, keeping the current state
+// while the temperature is between the off and on thresholds
+func (s *gpioSwitch) update(temp float64) (bool, error) {
+	want := s.on
+	switch {
+	case temp >= s.onTemp:
+		want = true
+	case temp <= s.offTemp:
+		want = false
+	}
+	return want, s.set(want)
+}
+
+func (s *gpioSwitch) set(on bool) error {
+	if on == s.on {
+		return nil
+	}
+	value := 0
+	if on {
+		value = 1
+	}
+	if err := s.line.SetValue(value); err != nil {
+		return err
+	}
+	s.on = on
+	return nil
+}
+
+func (s *gpioSwitch) Close() error {
+	if err := s.set(false); err != nil {
+		logger.Errorf("Failed to switch fan off: %v", err)
+	}
+	return s.line.Close()
+}
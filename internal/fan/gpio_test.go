@@ -0,0 +1,64 @@
+package fan
+
+import "testing"
+
+type fakeLine struct {
+	value  int
+	writes int
+}
+
+func (l *fakeLine) SetValue(value int) error {
+	l.value = value
+	l.writes++
+	return nil
+}
+
+func (l *fakeLine) Close() error {
+	return nil
+}
+
+func TestGPIOSwitchHysteresis(t *testing.T) {
+	line := &fakeLine{}
+	s := &gpioSwitch{line: line, onTemp: 50, offTemp: 45}
+
+	tests := []struct {
+		temp float64
+		want bool
+	}{
+		{40, false},
+		{48, false},
+		{50, true},
+		{47, true},
+		{45, false},
+		{49, false},
+	}
+
+	for _, tt := range tests {
+		on, err := s.update(tt.temp)
+		if err != nil {
+			t.Fatalf("update(%v) error: %v", tt.temp, err)
+		}
+		if on != tt.want {
+			t.Errorf("update(%v) = %v, want %v", tt.temp, on, tt.want)
+		}
+	}
+
+	if line.writes != 2 {
+		t.Errorf("line writes = %d, want 2", line.writes)
+	}
+}
+
+func TestGPIOSwitchCloseTurnsOff(t *testing.T) {
+	line := &fakeLine{}
+	s := &gpioSwitch{line: line, onTemp: 50, offTemp: 45}
+
+	if _, err := s.update(55); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if line.value != 0 {
+		t.Errorf("line value after Close = %d, want 0", line.value)
+	}
+}
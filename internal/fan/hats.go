@@ -0,0 +1,129 @@
+package fan
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/kolobock/rockpi-quad-go/internal/config"
+	"github.com/kolobock/rockpi-quad-go/internal/disk"
+	"github.com/kolobock/rockpi-quad-go/internal/logger"
+)
+
+// hatFan is the top-board fan of a stacked HAT, following the temperature of the
+// disks on that HAT with the disk curve
+type hatFan struct {
+	hat       config.HATConfig
+	pwm       channel
+	smoother  *tempSmoother
+	predictor *tempPredictor
+	last      float64
+	kickUntil time.Time
+}
+
+// HATStatus is the disk temperature and top-board fan duty (0-100) of one HAT
+type HATStatus struct {
+	ID       string
+	Label    string
+	DiskTemp float64
+	Duty     float64
+}
+
+// newHATFans opens the top-board fan of every stacked HAT that has one
+func (c *Controller) newHATFans() error {
+	for _, h := range c.cfg.HATs {
+		if h.ID == config.MainHAT || h.PWMChannel < 0 {
+			continue
+		}
+		p, err := c.openChannel(h.PWMChip, h.PWMChannel, h.Polarity)
+		if err != nil {
+			return fmt.Errorf("failed to init %s PWM: %w", h.Label, err)
+		}
+
+		f := &hatFan{hat: h, pwm: p}
+		if c.cfg.Fan.SmoothingSeconds > 0 {
+			f.smoother = newTempSmoother(time.Duration(c.cfg.Fan.SmoothingSeconds) * time.Second)
+		}
+		if c.cfg.Fan.PredictSeconds > 0 {
+			f.predictor = newTempPredictor(seconds(c.cfg.Fan.PredictWindow), seconds(c.cfg.Fan.PredictSeconds), c.cfg.Fan.PredictDecay)
+		}
+		c.hatFans = append(c.hatFans, f)
+	}
+	return nil
+}
+
+// recordHATTemps combines the disk readings of each HAT with the configured aggregation
+func (c *Controller) recordHATTemps(aggregator TempAggregator, readings []diskReading) {
+	groups := make(map[string][]diskReading)
+	for _, r := range readings {
+		hat := disk.HAT(r.device)
+		groups[hat] = append(groups[hat], r)
+	}
+
+	c.hatTemps = make(map[string]float64, len(groups))
+	for hat, group := range groups {
+		c.hatTemps[hat] = aggregator.Aggregate(group)
+	}
+}
+
+// updateHATFans applies the disk curve to the temperature of each stacked HAT; a
+// forced duty cycle (override or sensor failure) applies to them as well
+func (c *Controller) updateHATFans(now time.Time, forcedDC float64, forced bool) error {
+	for _, f := range c.hatFans {
+		temp := f.smoother.add(now, c.hatTemps[f.hat.ID])
+		temp = f.predictor.add(now, temp)
+
+		dc := c.calculateDutyCycle(temp, 'f')
+		if forced {
+			dc = forcedDC
+		}
+		if dc > 0 && dc < MinDutyCycle {
+			dc = MinDutyCycle
+		}
+
+		changed, err := c.applyDuty(f.pwm, dc, &f.last, &f.kickUntil, now)
+		if err != nil {
+			return fmt.Errorf("%s: %w", f.hat.Label, err)
+		}
+		if changed {
+			logger.Infof("%s: disk_temp: %.2f, disk_dc: %.2f", f.hat.Label, temp, f.last*100)
+		}
+	}
+	return nil
+}
+
+// setHATFans runs every stacked HAT fan at the same duty cycle, for toggles and emergencies
+func (c *Controller) setHATFans(dc float64) error {
+	for _, f := range c.hatFans {
+		if f.last == dc {
+			continue
+		}
+		if err := f.pwm.SetDutyCycle(dc); err != nil {
+			return fmt.Errorf("%s: %w", f.hat.Label, err)
+		}
+		f.last = dc
+	}
+	return nil
+}
+
+// hatStatus reports every HAT, the main one with the main disk fan; nil until
+// stacked HATs have a fan or disk readings
+func (c *Controller) hatStatus() []HATStatus {
+	if c.hatTemps == nil && len(c.hatFans) == 0 {
+		return nil
+	}
+
+	statuses := make([]HATStatus, 0, len(c.cfg.HATs))
+	for _, h := range c.cfg.HATs {
+		st := HATStatus{ID: h.ID, Label: h.Label, DiskTemp: c.hatTemps[h.ID]}
+		if h.ID == config.MainHAT {
+			st.Duty = c.lastDiskDC * 100
+		}
+		for _, f := range c.hatFans {
+			if f.hat.ID == h.ID {
+				st.Duty = f.last * 100
+			}
+		}
+		statuses = append(statuses, st)
+	}
+	return statuses
+}
@@ -0,0 +1,37 @@
+package fan
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/kolobock/rockpi-quad-go/internal/config"
+)
+
+func TestHATStatus(t *testing.T) {
+	cfg := &config.Config{HATs: []config.HATConfig{
+		{ID: config.MainHAT, Label: "Main"},
+		{ID: "2", Label: "HAT 2"},
+		{ID: "3", Label: "HAT 3", PWMChannel: -1},
+	}}
+	ctrl := &Controller{
+		cfg:        cfg,
+		lastDiskDC: 0.5,
+		hatFans:    []*hatFan{{hat: cfg.HATs[1], last: 0.75}},
+	}
+	// Disks whose port matches no HAT count for the main HAT
+	ctrl.recordHATTemps(maxAggregator{}, []diskReading{{device: "/dev/sda", temp: 38}, {device: "/dev/sdb", temp: 41}})
+
+	want := []HATStatus{
+		{ID: config.MainHAT, Label: "Main", DiskTemp: 41, Duty: 50},
+		{ID: "2", Label: "HAT 2", Duty: 75},
+		{ID: "3", Label: "HAT 3"},
+	}
+	if got := ctrl.hatStatus(); !slices.Equal(got, want) {
+		t.Errorf("hatStatus() = %+v, want %+v", got, want)
+	}
+
+	single := &Controller{cfg: &config.Config{HATs: cfg.HATs[:1]}}
+	if got := single.hatStatus(); got != nil {
+		t.Errorf("hatStatus() without stacked HATs = %+v, want nil", got)
+	}
+}
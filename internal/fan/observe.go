@@ -0,0 +1,195 @@
+package fan
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/kolobock/rockpi-quad-go/internal/config"
+	"github.com/kolobock/rockpi-quad-go/internal/logger"
+)
+
+const modeObserve = "observe"
+
+// errObserved is returned for requests that would drive fans owned by another program
+var errObserved = errors.New("fans are driven by another program (fan mode observe)")
+
+// Paths replaced in tests
+var (
+	sysClassPWM = "/sys/class/pwm"
+	procDir     = "/proc"
+)
+
+// fanDaemons are programs known to drive fan PWM channels themselves
+var fanDaemons = []string{"fancontrol", "thinkfan", "fan2go"}
+
+// detectFanDaemon returns the name of a running fan control daemon, if any
+func detectFanDaemon() (string, bool) {
+	entries, err := os.ReadDir(procDir)
+	if err != nil {
+		return "", false
+	}
+	for _, e := range entries {
+		if _, err := strconv.Atoi(e.Name()); err != nil {
+			continue
+		}
+		comm, err := os.ReadFile(filepath.Join(procDir, e.Name(), "comm"))
+		if err != nil {
+			continue
+		}
+		if name := strings.TrimSpace(string(comm)); slices.Contains(fanDaemons, name) {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// dutyReader returns the duty cycle (0-1) another program set on a fan
+type dutyReader func() (float64, error)
+
+// hwmonDuty reads an hwmon pwmN file (0-255), the interface fancontrol writes
+func hwmonDuty(path string) dutyReader {
+	return func() (float64, error) {
+		v, err := readInt(path)
+		if err != nil {
+			return 0, err
+		}
+		return min(max(float64(v)/255, 0), 1), nil
+	}
+}
+
+// hwmonFull sets an hwmon pwmN file to full speed
+func hwmonFull(path string) func() error {
+	return func() error {
+		return os.WriteFile(path, []byte("255"), 0o644) // #nosec G306 - sysfs attribute
+	}
+}
+
+// channelDuty reads duty_cycle and period of an exported sysfs PWM channel
+func channelDuty(chip string, channel int, inversed bool) dutyReader {
+	base := filepath.Join(sysClassPWM, chip, fmt.Sprintf("pwm%d", channel))
+	return func() (float64, error) {
+		period, err := readInt(filepath.Join(base, "period"))
+		if err != nil {
+			return 0, err
+		}
+		if period <= 0 {
+			return 0, fmt.Errorf("PWM %s/pwm%d has no period", chip, channel)
+		}
+		duty, err := readInt(filepath.Join(base, "duty_cycle"))
+		if err != nil {
+			return 0, err
+		}
+		dc := min(max(float64(duty)/float64(period), 0), 1)
+		if inversed {
+			dc = 1 - dc
+		}
+		return dc, nil
+	}
+}
+
+// channelFull sets an exported sysfs PWM channel to full speed
+func channelFull(chip string, channel int, inversed bool) func() error {
+	base := filepath.Join(sysClassPWM, chip, fmt.Sprintf("pwm%d", channel))
+	return func() error {
+		period, err := readInt(filepath.Join(base, "period"))
+		if err != nil {
+			return err
+		}
+		duty := period
+		if inversed {
+			duty = 0
+		}
+		// #nosec G306 - sysfs attribute
+		return os.WriteFile(filepath.Join(base, "duty_cycle"), []byte(strconv.FormatInt(duty, 10)), 0o644)
+	}
+}
+
+// observer reads the fan duty cycles another program sets; it only writes them
+// during a thermal emergency, to force the fans to full speed
+type observer struct {
+	cpu  dutyReader
+	disk dutyReader
+
+	cpuFull  func() error
+	diskFull func() error
+}
+
+// newObserver reads each fan from its configured hwmon file, or else from its PWM
+// channel. A disk fan sharing the CPU channel is not read separately, as in pwm mode.
+func newObserver(cfg *config.Config) *observer {
+	o := &observer{}
+	if cfg.Fan.ObserveCPUPWM != "" {
+		o.cpu, o.cpuFull = hwmonDuty(cfg.Fan.ObserveCPUPWM), hwmonFull(cfg.Fan.ObserveCPUPWM)
+	} else {
+		inversed := cfg.Fan.CPUPolarity == polarityInversed
+		o.cpu = channelDuty(cfg.Fan.CPUPWMChip, cfg.Fan.CPUPWMChannel, inversed)
+		o.cpuFull = channelFull(cfg.Fan.CPUPWMChip, cfg.Fan.CPUPWMChannel, inversed)
+	}
+	switch {
+	case cfg.Fan.ObserveDiskPWM != "":
+		o.disk, o.diskFull = hwmonDuty(cfg.Fan.ObserveDiskPWM), hwmonFull(cfg.Fan.ObserveDiskPWM)
+	case cfg.Fan.TBPWMChip != cfg.Fan.CPUPWMChip || cfg.Fan.TBPWMChannel != cfg.Fan.CPUPWMChannel:
+		inversed := cfg.Fan.TBPolarity == polarityInversed
+		o.disk = channelDuty(cfg.Fan.TBPWMChip, cfg.Fan.TBPWMChannel, inversed)
+		o.diskFull = channelFull(cfg.Fan.TBPWMChip, cfg.Fan.TBPWMChannel, inversed)
+	}
+	return o
+}
+
+// observe records the duty cycles currently set by the other program; a fan that
+// cannot be read keeps its previous value
+func (c *Controller) observe(cpuTemp, diskTemp float64) {
+	changed := false
+	for _, f := range []struct {
+		name string
+		read dutyReader
+		last *float64
+	}{
+		{"CPU", c.observer.cpu, &c.lastCPUDC},
+		{"disk", c.observer.disk, &c.lastDiskDC},
+	} {
+		if f.read == nil {
+			continue
+		}
+		dc, err := f.read()
+		if err != nil {
+			logger.Infof("Failed to read %s fan duty cycle: %v", f.name, err)
+			continue
+		}
+		if dc != *f.last {
+			*f.last = dc
+			changed = true
+		}
+	}
+
+	if changed {
+		logger.Infof("cpu_temp: %.2f, cpu_dc: %.2f, disk_temp: %.2f, disk_dc: %.2f (observed)",
+			cpuTemp, c.lastCPUDC*100, diskTemp, c.lastDiskDC*100)
+	}
+}
+
+// forceFull sets the observed fans to full speed. The other program may turn them
+// down again, so it is repeated every update while the emergency lasts.
+func (c *Controller) forceFull() error {
+	var errs []error
+	for _, full := range []func() error{c.observer.cpuFull, c.observer.diskFull} {
+		if full != nil {
+			errs = append(errs, full())
+		}
+	}
+	c.lastCPUDC, c.lastDiskDC = 1.0, 1.0
+	return errors.Join(errs...)
+}
+
+func readInt(path string) (int64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+}
@@ -0,0 +1,146 @@
+package fan
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kolobock/rockpi-quad-go/internal/config"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDetectFanDaemon(t *testing.T) {
+	tests := []struct {
+		name  string
+		procs map[string]string
+		want  string
+	}{
+		{"none", map[string]string{"1": "systemd\n", "42": "sshd\n"}, ""},
+		{"fancontrol", map[string]string{"1": "systemd\n", "873": "fancontrol\n"}, "fancontrol"},
+		{"non-pid entries ignored", map[string]string{"self": "fancontrol\n"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for pid, comm := range tt.procs {
+				writeFile(t, filepath.Join(dir, pid, "comm"), comm)
+			}
+			old := procDir
+			procDir = dir
+			defer func() { procDir = old }()
+
+			got, ok := detectFanDaemon()
+			if got != tt.want || ok != (tt.want != "") {
+				t.Errorf("detectFanDaemon() = %q, %t, want %q", got, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestDutyReaders(t *testing.T) {
+	dir := t.TempDir()
+	old := sysClassPWM
+	sysClassPWM = dir
+	defer func() { sysClassPWM = old }()
+
+	writeFile(t, filepath.Join(dir, "pwmchip0", "pwm0", "period"), "40000\n")
+	writeFile(t, filepath.Join(dir, "pwmchip0", "pwm0", "duty_cycle"), "10000\n")
+	writeFile(t, filepath.Join(dir, "hwmon", "pwm1"), "153\n")
+
+	tests := []struct {
+		name    string
+		read    dutyReader
+		want    float64
+		wantErr bool
+	}{
+		{"channel", channelDuty("pwmchip0", 0, false), 0.25, false},
+		{"channel inversed", channelDuty("pwmchip0", 0, true), 0.75, false},
+		{"channel not exported", channelDuty("pwmchip0", 1, false), 0, true},
+		{"hwmon", hwmonDuty(filepath.Join(dir, "hwmon", "pwm1")), 0.6, false},
+		{"hwmon missing", hwmonDuty(filepath.Join(dir, "hwmon", "pwm2")), 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.read()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %t", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("duty = %g, want %g", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestObserveMode(t *testing.T) {
+	dir := t.TempDir()
+	cpu, disk := filepath.Join(dir, "pwm1"), filepath.Join(dir, "pwm2")
+	writeFile(t, cpu, "255")
+	writeFile(t, disk, "51")
+
+	cfg := &config.Config{Fan: config.FanConfig{ObserveCPUPWM: cpu, ObserveDiskPWM: disk}}
+	ctrl := &Controller{cfg: cfg, enabled: true, observer: newObserver(cfg)}
+
+	ctrl.observe(50, 40)
+	if ctrl.lastCPUDC != 1 || ctrl.lastDiskDC != 0.2 {
+		t.Errorf("observed duties = %g, %g, want 1, 0.2", ctrl.lastCPUDC, ctrl.lastDiskDC)
+	}
+
+	// An unreadable fan keeps its last value
+	if err := os.Remove(disk); err != nil {
+		t.Fatal(err)
+	}
+	ctrl.observe(50, 40)
+	if ctrl.lastDiskDC != 0.2 {
+		t.Errorf("disk duty after failed read = %g, want 0.2", ctrl.lastDiskDC)
+	}
+
+	if err := ctrl.SetOverride(50, time.Minute); !errors.Is(err, errObserved) {
+		t.Errorf("SetOverride() = %v, want errObserved", err)
+	}
+	ctrl.ToggleFan()
+	if !ctrl.Status().Enabled || !ctrl.Status().Observed {
+		t.Errorf("status = %+v, want enabled and observed", ctrl.Status())
+	}
+}
+
+func TestObserveEmergency(t *testing.T) {
+	dir := t.TempDir()
+	cpu := filepath.Join(dir, "pwm1")
+	writeFile(t, cpu, "60")
+	channel := filepath.Join(dir, "pwmchip0", "pwm1")
+	writeFile(t, filepath.Join(channel, "period"), "40000")
+	writeFile(t, filepath.Join(channel, "duty_cycle"), "10000")
+	old := sysClassPWM
+	sysClassPWM = dir
+	t.Cleanup(func() { sysClassPWM = old })
+
+	cfg := &config.Config{Fan: config.FanConfig{
+		ObserveCPUPWM: cpu, TBPWMChip: "pwmchip0", TBPWMChannel: 1,
+		Emergency: true, MaxCPUTemp: 80, MaxDiskTemp: 70,
+	}}
+	ctrl := &Controller{cfg: cfg, enabled: true, observer: newObserver(cfg)}
+
+	if !ctrl.checkEmergency(time.Now(), 85, 40) {
+		t.Fatal("checkEmergency() = false at 85°C")
+	}
+	if err := ctrl.forceFull(); err != nil {
+		t.Fatal(err)
+	}
+	for path, want := range map[string]string{cpu: "255", filepath.Join(channel, "duty_cycle"): "40000"} {
+		if data, _ := os.ReadFile(path); string(data) != want {
+			t.Errorf("%s = %q during the emergency, want %s", path, data, want)
+		}
+	}
+}
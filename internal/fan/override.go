@@ -0,0 +1,69 @@
+package fan
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/kolobock/rockpi-quad-go/internal/logger"
+)
+
+// SetOverride pins both fans to a fixed duty cycle (0-100%) for the given duration,
+// after which temperature-based control resumes automatically
+func (c *Controller) SetOverride(percent float64, d time.Duration) error {
+	if percent < 0 || percent > 100 {
+		return fmt.Errorf("duty cycle %.0f%% out of range 0-100", percent)
+	}
+	if d <= 0 {
+		return fmt.Errorf("override duration must be positive")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.observer != nil {
+		return errObserved
+	}
+	c.enabled = true
+	c.overrideDC = percent / 100
+	c.overrideUntil = time.Now().Add(d)
+	logger.Infof("Fan override: %.0f%% for %s", percent, d)
+	return nil
+}
+
+// ClearOverride cancels a manual override and resumes temperature-based control
+func (c *Controller) ClearOverride() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.clearOverride()
+}
+
+// OverrideRemaining returns how long the current override is still active (0 if none)
+func (c *Controller) OverrideRemaining() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.overrideUntil.IsZero() {
+		return 0
+	}
+	return max(time.Until(c.overrideUntil), 0)
+}
+
+func (c *Controller) clearOverride() {
+	if c.overrideUntil.IsZero() {
+		return
+	}
+	c.overrideUntil = time.Time{}
+	logger.Infoln("Fan override ended - temperature-based control resumed")
+}
+
+// activeOverride reports the override duty cycle, expiring the override when its time is up
+func (c *Controller) activeOverride(now time.Time) (float64, bool) {
+	if c.overrideUntil.IsZero() {
+		return 0, false
+	}
+	if !now.Before(c.overrideUntil) {
+		c.clearOverride()
+		return 0, false
+	}
+	return c.overrideDC, true
+}
@@ -0,0 +1,53 @@
+package fan
+
+import "time"
+
+// tempPredictor is a feed-forward term on the fan curve: while the temperature rises
+// it extrapolates the slope over the window lead ahead, so fans ramp before the heat
+// arrives, and when the temperature falls the anticipated value drops at most decay
+// °C per second, so fans slow down gradually.
+type tempPredictor struct {
+	window time.Duration
+	lead   time.Duration
+	decay  float64
+
+	samples []tempSample
+	last    tempSample
+	primed  bool
+}
+
+func newTempPredictor(window, lead time.Duration, decayPerMinute float64) *tempPredictor {
+	return &tempPredictor{window: window, lead: lead, decay: decayPerMinute / 60}
+}
+
+// add records a sample and returns the temperature the fan curve should see.
+// A nil predictor returns the sample unchanged.
+func (p *tempPredictor) add(now time.Time, temp float64) float64 {
+	if p == nil || p.lead <= 0 {
+		return temp
+	}
+
+	p.samples = append(p.samples, tempSample{at: now, temp: temp})
+	cutoff := now.Add(-p.window)
+	drop := 0
+	for drop < len(p.samples)-1 && p.samples[drop].at.Before(cutoff) {
+		drop++
+	}
+	p.samples = p.samples[drop:]
+
+	target := temp
+	first := p.samples[0]
+	if elapsed := now.Sub(first.at).Seconds(); elapsed > 0 {
+		if slope := (temp - first.temp) / elapsed; slope > 0 {
+			target += slope * p.lead.Seconds()
+		}
+	}
+
+	if p.primed && target < p.last.temp && p.decay > 0 {
+		floor := p.last.temp - p.decay*now.Sub(p.last.at).Seconds()
+		target = max(target, floor)
+	}
+	p.last = tempSample{at: now, temp: target}
+	p.primed = true
+	return target
+}
@@ -0,0 +1,41 @@
+package fan
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestTempPredictor(t *testing.T) {
+	p := newTempPredictor(60*time.Second, 30*time.Second, 6)
+	start := time.Now()
+
+	steps := []struct {
+		at   time.Duration
+		temp float64
+		want float64
+	}{
+		{0, 40, 40},
+		// Rising 0.1°C/s: anticipate 30s ahead
+		{10 * time.Second, 41, 44},
+		{20 * time.Second, 42, 45},
+		// Falling: the anticipated value drops 6°C per minute at most
+		{30 * time.Second, 38, 44},
+		{40 * time.Second, 38, 43},
+		// Settled: never below the measured temperature
+		{5 * time.Minute, 38, 38},
+	}
+
+	for _, step := range steps {
+		if got := p.add(start.Add(step.at), step.temp); math.Abs(got-step.want) > 1e-9 {
+			t.Errorf("at %s temp %.0f: got %.2f, want %.2f", step.at, step.temp, got, step.want)
+		}
+	}
+}
+
+func TestTempPredictorDisabled(t *testing.T) {
+	var nilPredictor *tempPredictor
+	if got := nilPredictor.add(time.Now(), 42); got != 42 {
+		t.Errorf("nil predictor = %v, want 42", got)
+	}
+}
@@ -0,0 +1,115 @@
+package fan
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kolobock/rockpi-quad-go/internal/logger"
+)
+
+const (
+	ProfileSilent      = "silent"
+	ProfileBalanced    = "balanced"
+	ProfilePerformance = "performance"
+)
+
+// profileOffsets shift the lv0-lv3 thresholds (in °C) for each named profile
+var profileOffsets = map[string]float64{
+	ProfileSilent:      5,
+	ProfileBalanced:    0,
+	ProfilePerformance: -5,
+}
+
+// scheduleEntry switches to a profile at a given minute of the day
+type scheduleEntry struct {
+	minute  int
+	profile string
+}
+
+// parseSchedule parses entries like "22:00=silent" sorted by time of day
+func parseSchedule(entries []string) ([]scheduleEntry, error) {
+	schedule := make([]scheduleEntry, 0, len(entries))
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		at, profile, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid schedule entry %q: expected HH:MM=profile", entry)
+		}
+		profile = strings.TrimSpace(profile)
+		if _, ok := profileOffsets[profile]; !ok {
+			return nil, fmt.Errorf("invalid schedule entry %q: unknown profile %q", entry, profile)
+		}
+
+		hh, mm, ok := strings.Cut(strings.TrimSpace(at), ":")
+		hour, errH := strconv.Atoi(hh)
+		minute, errM := strconv.Atoi(mm)
+		if !ok || errH != nil || errM != nil || hour < 0 || hour > 23 || minute < 0 || minute > 59 {
+			return nil, fmt.Errorf("invalid schedule entry %q: bad time %q", entry, at)
+		}
+
+		schedule = append(schedule, scheduleEntry{minute: hour*60 + minute, profile: profile})
+	}
+
+	sort.Slice(schedule, func(i, j int) bool { return schedule[i].minute < schedule[j].minute })
+	return schedule, nil
+}
+
+// scheduledProfile returns the profile active at time t, wrapping around midnight
+func scheduledProfile(schedule []scheduleEntry, t time.Time) string {
+	if len(schedule) == 0 {
+		return ""
+	}
+
+	now := t.Hour()*60 + t.Minute()
+	active := schedule[len(schedule)-1].profile
+	for _, entry := range schedule {
+		if entry.minute > now {
+			break
+		}
+		active = entry.profile
+	}
+	return active
+}
+
+// SetProfile switches the active fan profile
+func (c *Controller) SetProfile(name string) error {
+	if _, ok := profileOffsets[name]; !ok {
+		return fmt.Errorf("unknown fan profile %q", name)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.profile != name {
+		logger.Infof("Fan profile changed: %s -> %s", c.profile, name)
+	}
+	c.profile = name
+	return nil
+}
+
+// Profile returns the name of the active fan profile
+func (c *Controller) Profile() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.profile
+}
+
+// applySchedule switches profile when the schedule crosses into a new entry,
+// leaving manual changes alone until the next scheduled boundary
+func (c *Controller) applySchedule(t time.Time) {
+	profile := scheduledProfile(c.schedule, t)
+	if profile == "" || profile == c.lastScheduled {
+		return
+	}
+	c.lastScheduled = profile
+	if err := c.SetProfile(profile); err != nil {
+		logger.Errorf("Failed to apply scheduled fan profile: %v", err)
+	}
+}
@@ -0,0 +1,79 @@
+package fan
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kolobock/rockpi-quad-go/internal/config"
+)
+
+func TestParseSchedule(t *testing.T) {
+	schedule, err := parseSchedule([]string{"22:00=silent", " 07:30=balanced"})
+	if err != nil {
+		t.Fatalf("parseSchedule failed: %v", err)
+	}
+	if len(schedule) != 2 {
+		t.Fatalf("got %d entries, want 2", len(schedule))
+	}
+	if schedule[0].minute != 7*60+30 || schedule[0].profile != ProfileBalanced {
+		t.Errorf("first entry = %+v, want 07:30=balanced", schedule[0])
+	}
+
+	for _, bad := range []string{"22:00", "25:00=silent", "22:00=loud", "aa:bb=silent"} {
+		if _, err := parseSchedule([]string{bad}); err == nil {
+			t.Errorf("parseSchedule(%q) expected error, got nil", bad)
+		}
+	}
+}
+
+func TestScheduledProfile(t *testing.T) {
+	schedule, _ := parseSchedule([]string{"22:00=silent", "07:00=balanced"})
+
+	tests := []struct {
+		hour, minute int
+		want         string
+	}{
+		{3, 0, ProfileSilent},
+		{7, 0, ProfileBalanced},
+		{12, 0, ProfileBalanced},
+		{22, 15, ProfileSilent},
+	}
+
+	for _, tt := range tests {
+		at := time.Date(2024, 1, 1, tt.hour, tt.minute, 0, 0, time.UTC)
+		if got := scheduledProfile(schedule, at); got != tt.want {
+			t.Errorf("scheduledProfile(%02d:%02d) = %q, want %q", tt.hour, tt.minute, got, tt.want)
+		}
+	}
+}
+
+func TestProfileShiftsThresholds(t *testing.T) {
+	cfg := &config.Config{
+		Fan: config.FanConfig{
+			LV0C:       35,
+			LV1C:       40,
+			LV2C:       45,
+			LV3C:       50,
+			MaxCPUTemp: 80,
+		},
+	}
+	ctrl := &Controller{cfg: cfg}
+
+	if err := ctrl.SetProfile(ProfileSilent); err != nil {
+		t.Fatalf("SetProfile failed: %v", err)
+	}
+	if got := ctrl.calculateDutyCycle(37, 'c'); got != 0 {
+		t.Errorf("silent duty at 37°C = %v, want 0", got)
+	}
+
+	if err := ctrl.SetProfile(ProfilePerformance); err != nil {
+		t.Fatalf("SetProfile failed: %v", err)
+	}
+	if got := ctrl.calculateDutyCycle(37, 'c'); got != 0.50 {
+		t.Errorf("performance duty at 37°C = %v, want 0.50", got)
+	}
+
+	if err := ctrl.SetProfile("loud"); err == nil {
+		t.Error("SetProfile(loud) expected error, got nil")
+	}
+}
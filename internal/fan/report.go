@@ -0,0 +1,99 @@
+package fan
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kolobock/rockpi-quad-go/internal/logger"
+)
+
+// ThermalReport compares the temperatures read when the controller started with
+// the configured fan curves, so configuration changes can be checked after a restart
+type ThermalReport struct {
+	Time    time.Time
+	Profile string
+	CPU     CurveReport
+	// Disk is only filled in when disk temperatures drive the fans ([fan] temp_disks)
+	Disk *CurveReport
+}
+
+// CurveReport places one temperature on its fan curve
+type CurveReport struct {
+	Temp       float64
+	Thresholds [4]float64
+	Max        float64
+	// Level is "off" below lv0, "lv0".."lv3" for the highest threshold reached, or "max"
+	Level string
+	// Duty is the duty cycle in percent the curve asks for
+	Duty float64
+}
+
+func (r CurveReport) String() string {
+	return fmt.Sprintf("%.1f°C -> %s (lv0-lv3 %g/%g/%g/%g, max %g), duty %.0f%%",
+		r.Temp, r.Level, r.Thresholds[0], r.Thresholds[1], r.Thresholds[2], r.Thresholds[3], r.Max, r.Duty)
+}
+
+// curveLevel names the highest threshold temp has reached
+func curveLevel(temp float64, levels [4]float64, maxTemp float64) string {
+	if temp >= maxTemp {
+		return "max"
+	}
+	for i := len(levels) - 1; i >= 0; i-- {
+		if temp >= levels[i] {
+			return fmt.Sprintf("lv%d", i)
+		}
+	}
+	return "off"
+}
+
+// curveReport places temp on the CPU ('c') or disk ('f') curve; the caller holds c.mu
+func (c *Controller) curveReport(temp float64, key byte) CurveReport {
+	levels, maxTemp := c.curve(key)
+	return CurveReport{
+		Temp:       temp,
+		Thresholds: levels,
+		Max:        maxTemp,
+		Level:      curveLevel(temp, levels, maxTemp),
+		Duty:       c.calculateDutyCycle(temp, key) * 100,
+	}
+}
+
+// reportStartup reads the temperatures once before the control loop starts and
+// logs where they sit on the fan curves
+func (c *Controller) reportStartup() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cpuTemp, diskTemp := c.getTemperatures()
+	c.lastCPUTemp = cpuTemp
+
+	r := ThermalReport{Time: time.Now(), Profile: c.profile, CPU: c.curveReport(cpuTemp, 'c')}
+	parts := []string{"cpu " + r.CPU.String()}
+	if c.cfg.Fan.TempDisks {
+		disk := c.curveReport(diskTemp, 'f')
+		r.Disk = &disk
+		parts = append(parts, "disk "+disk.String())
+	}
+	c.startup = &r
+
+	logger.Errorf("Startup thermal report (profile %s, %s curve): %s", r.Profile, c.curveMode(), strings.Join(parts, "; "))
+}
+
+func (c *Controller) curveMode() string {
+	if c.cfg.Fan.Linear {
+		return "linear"
+	}
+	return "stepped"
+}
+
+// StartupReport returns the report taken when the controller started; false until then
+func (c *Controller) StartupReport() (ThermalReport, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.startup == nil {
+		return ThermalReport{}, false
+	}
+	return *c.startup, true
+}
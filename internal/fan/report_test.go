@@ -0,0 +1,40 @@
+package fan
+
+import (
+	"testing"
+
+	"github.com/kolobock/rockpi-quad-go/internal/config"
+)
+
+func TestCurveReport(t *testing.T) {
+	cfg := &config.Config{Fan: config.FanConfig{
+		LV0C: 35, LV1C: 40, LV2C: 45, LV3C: 50, MaxCPUTemp: 80,
+		LV0F: 30, LV1F: 35, LV2F: 40, LV3F: 45, MaxDiskTemp: 60,
+	}}
+
+	tests := []struct {
+		name      string
+		profile   string
+		temp      float64
+		key       byte
+		wantLevel string
+		wantDuty  float64
+		wantLV0   float64
+	}{
+		{"cpu below curve", ProfileBalanced, 30, 'c', "off", 0, 35},
+		{"cpu at lv1", ProfileBalanced, 42, 'c', "lv1", 50, 35},
+		{"silent profile shifts thresholds", ProfileSilent, 42, 'c', "lv0", 25, 40},
+		{"disk at lv3", ProfileBalanced, 50, 'f', "lv3", 100, 30},
+		{"disk over max", ProfileBalanced, 61, 'f', "max", 100, 30},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := &Controller{cfg: cfg, profile: tt.profile}
+			got := ctrl.curveReport(tt.temp, tt.key)
+			if got.Level != tt.wantLevel || got.Duty != tt.wantDuty || got.Thresholds[0] != tt.wantLV0 {
+				t.Errorf("curveReport(%v) = %+v, want level %s, duty %v, lv0 %v", tt.temp, got, tt.wantLevel, tt.wantDuty, tt.wantLV0)
+			}
+		})
+	}
+}
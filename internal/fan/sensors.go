@@ -0,0 +1,33 @@
+package fan
+
+import (
+	"github.com/kolobock/rockpi-quad-go/internal/logger"
+)
+
+// recordSensor updates the consecutive failure counter of a temperature sensor,
+// alerting when it reaches the configured threshold and again on recovery
+func (c *Controller) recordSensor(name string, failures *int, ok bool) {
+	threshold := c.cfg.Fan.SensorFailures
+	if ok {
+		if threshold > 0 && *failures >= threshold {
+			logger.Errorf("%s temperature sensor recovered after %d failed reads", name, *failures)
+		}
+		*failures = 0
+		return
+	}
+
+	*failures++
+	if *failures == threshold {
+		logger.Errorf("%s temperature sensor failed %d times in a row - running fans at safe duty %.0f%%",
+			name, *failures, c.cfg.Fan.SafeDuty)
+	}
+}
+
+// sensorFailed reports whether a sensor has failed often enough to fall back to the safe duty
+func (c *Controller) sensorFailed() bool {
+	if c.cfg == nil || c.cfg.Fan.SensorFailures <= 0 {
+		return false
+	}
+	threshold := c.cfg.Fan.SensorFailures
+	return c.cpuSensorFailures >= threshold || c.diskSensorFailures >= threshold
+}
@@ -0,0 +1,45 @@
+package fan
+
+import (
+	"testing"
+
+	"github.com/kolobock/rockpi-quad-go/internal/config"
+)
+
+func TestSensorFallback(t *testing.T) {
+	cfg := &config.Config{
+		Fan: config.FanConfig{SensorFailures: 3, SafeDuty: 100},
+	}
+	ctrl := &Controller{cfg: cfg}
+
+	for i := 0; i < 2; i++ {
+		ctrl.recordSensor("CPU", &ctrl.cpuSensorFailures, false)
+	}
+	if ctrl.sensorFailed() {
+		t.Error("fallback active after 2 failures, want 3")
+	}
+
+	ctrl.recordSensor("CPU", &ctrl.cpuSensorFailures, false)
+	if !ctrl.sensorFailed() {
+		t.Error("fallback not active after 3 failures")
+	}
+	if st := ctrl.Status(); st.CPUSensorFailures != 3 {
+		t.Errorf("Status().CPUSensorFailures = %d, want 3", st.CPUSensorFailures)
+	}
+
+	ctrl.recordSensor("CPU", &ctrl.cpuSensorFailures, true)
+	if ctrl.sensorFailed() {
+		t.Error("fallback still active after a successful read")
+	}
+}
+
+func TestSensorFallbackDisabled(t *testing.T) {
+	ctrl := &Controller{cfg: &config.Config{}}
+
+	for i := 0; i < 10; i++ {
+		ctrl.recordSensor("disk", &ctrl.diskSensorFailures, false)
+	}
+	if ctrl.sensorFailed() {
+		t.Error("fallback active with sensor_failures = 0")
+	}
+}
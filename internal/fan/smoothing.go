@@ -0,0 +1,42 @@
+package fan
+
+import "time"
+
+type tempSample struct {
+	at   time.Time
+	temp float64
+}
+
+// tempSmoother averages temperature samples over a sliding time window so a
+// single spike does not rev the fans
+type tempSmoother struct {
+	window  time.Duration
+	samples []tempSample
+}
+
+func newTempSmoother(window time.Duration) *tempSmoother {
+	return &tempSmoother{window: window}
+}
+
+// add records a sample and returns the average over the window.
+// A nil smoother or a zero window returns the sample unchanged.
+func (s *tempSmoother) add(now time.Time, temp float64) float64 {
+	if s == nil || s.window <= 0 {
+		return temp
+	}
+
+	s.samples = append(s.samples, tempSample{at: now, temp: temp})
+
+	cutoff := now.Add(-s.window)
+	drop := 0
+	for drop < len(s.samples)-1 && s.samples[drop].at.Before(cutoff) {
+		drop++
+	}
+	s.samples = s.samples[drop:]
+
+	var sum float64
+	for _, sample := range s.samples {
+		sum += sample.temp
+	}
+	return sum / float64(len(s.samples))
+}
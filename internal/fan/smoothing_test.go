@@ -0,0 +1,32 @@
+package fan
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTempSmoother(t *testing.T) {
+	s := newTempSmoother(10 * time.Second)
+	start := time.Now()
+
+	s.add(start, 40)
+	s.add(start.Add(time.Second), 40)
+	if got := s.add(start.Add(2*time.Second), 70); got != 50 {
+		t.Errorf("average with spike = %v, want 50", got)
+	}
+
+	// Samples older than the window are dropped
+	if got := s.add(start.Add(20*time.Second), 45); got != 45 {
+		t.Errorf("average after window = %v, want 45", got)
+	}
+}
+
+func TestTempSmootherDisabled(t *testing.T) {
+	var nilSmoother *tempSmoother
+	if got := nilSmoother.add(time.Now(), 42); got != 42 {
+		t.Errorf("nil smoother = %v, want 42", got)
+	}
+	if got := newTempSmoother(0).add(time.Now(), 42); got != 42 {
+		t.Errorf("zero window smoother = %v, want 42", got)
+	}
+}
@@ -0,0 +1,73 @@
+package fan
+
+import "time"
+
+// Status is a snapshot of the fan controller state
+type Status struct {
+	Enabled            bool
+	Profile            string
+	CPUTemp            float64
+	DiskTemp           float64
+	CPUDuty            float64
+	DiskDuty           float64
+	OverrideRemaining  time.Duration
+	PWMHealthy         bool
+	PWMFailures        int
+	Emergency          bool
+	Burst              bool
+	CPUSensorFailures  int
+	DiskSensorFailures int
+	SensorFallback     bool
+	// Observed is set when another program drives the fans and the duty cycles are only read
+	Observed bool
+	// DryRun is set when duty cycles are computed but never written; ActualCPUDuty and
+	// ActualDiskDuty are then the duty cycles (0-100) the channels are really at
+	DryRun         bool
+	ActualCPUDuty  float64
+	ActualDiskDuty float64
+	// HATs lists each HAT of a stack, nil with a single HAT
+	HATs []HATStatus
+}
+
+// Status returns the current controller state; duty cycles are percentages (0-100)
+func (c *Controller) Status() Status {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	st := Status{
+		Enabled:    c.enabled,
+		Profile:    c.profile,
+		CPUTemp:    c.lastCPUTemp,
+		DiskTemp:   c.lastDiskTemp,
+		CPUDuty:    c.lastCPUDC * 100,
+		DiskDuty:   c.lastDiskDC * 100,
+		PWMHealthy: true,
+		Emergency:  c.emergency,
+		Burst:      c.burst,
+
+		CPUSensorFailures:  c.cpuSensorFailures,
+		DiskSensorFailures: c.diskSensorFailures,
+		SensorFallback:     c.sensorFailed(),
+		Observed:           c.observer != nil,
+		HATs:               c.hatStatus(),
+	}
+	if !c.overrideUntil.IsZero() {
+		st.OverrideRemaining = max(time.Until(c.overrideUntil), 0)
+	}
+	if c.dryRun {
+		st.DryRun = true
+		st.ActualCPUDuty, st.ActualDiskDuty = actualPercent(c.cpuPWM), actualPercent(c.diskPWM)
+	}
+	pwms := []channel{c.cpuPWM, c.diskPWM}
+	for _, f := range c.hatFans {
+		pwms = append(pwms, f.pwm)
+	}
+	for _, p := range pwms {
+		if p == nil {
+			continue
+		}
+		st.PWMHealthy = st.PWMHealthy && p.Healthy()
+		st.PWMFailures += p.Failures()
+	}
+	return st
+}
@@ -0,0 +1,133 @@
+// Package flags holds preferences that can be changed while the daemon runs and
+// optionally written back to the configuration file.
+package flags
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"sync"
+
+	"gopkg.in/ini.v1"
+
+	"github.com/kolobock/rockpi-quad-go/internal/config"
+)
+
+// Flag names
+const (
+	AutoSlide  = "auto_slide"
+	Fahrenheit = "fahrenheit"
+	MuteAlerts = "mute_alerts"
+	Verbose    = "verbose"
+)
+
+// ErrUnknown is returned for a flag name that does not exist
+var ErrUnknown = errors.New("unknown flag")
+
+// flagDef maps a flag to its configuration key and initial value
+type flagDef struct {
+	section, key string
+	initial      func(cfg *config.Config) bool
+}
+
+var defs = map[string]flagDef{
+	AutoSlide:  {"slider", "auto", func(cfg *config.Config) bool { return cfg.Slider.Auto }},
+	Fahrenheit: {"oled", "f-temp", func(cfg *config.Config) bool { return cfg.OLED.Fahrenheit }},
+	MuteAlerts: {"alerts", "mute", func(cfg *config.Config) bool { return cfg.Alerts.Mute }},
+	Verbose:    {"fan", "syslog", func(cfg *config.Config) bool { return cfg.Fan.Syslog }},
+}
+
+// Names returns all flag names in sorted order
+func Names() []string {
+	names := make([]string, 0, len(defs))
+	for name := range defs {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Set is the current value of every flag; it is safe for concurrent use
+type Set struct {
+	mu     sync.RWMutex
+	path   string
+	values map[string]bool
+	hooks  map[string][]func(bool)
+}
+
+// New creates a set initialized from cfg, persisting to the file cfg was loaded from
+func New(cfg *config.Config) *Set {
+	s := &Set{
+		path:   cfg.Path,
+		values: make(map[string]bool, len(defs)),
+		hooks:  make(map[string][]func(bool)),
+	}
+	for name, def := range defs {
+		s.values[name] = def.initial(cfg)
+	}
+	return s
+}
+
+// Enabled returns the current value of a flag; unknown flags are false
+func (s *Set) Enabled(name string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.values[name]
+}
+
+// All returns a copy of every flag value
+func (s *Set) All() map[string]bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	all := make(map[string]bool, len(s.values))
+	for name, v := range s.values {
+		all[name] = v
+	}
+	return all
+}
+
+// OnChange registers fn to be called with the new value whenever name is set
+func (s *Set) OnChange(name string, fn func(bool)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hooks[name] = append(s.hooks[name], fn)
+}
+
+// Set changes a flag at runtime and notifies its hooks
+func (s *Set) Set(name string, value bool) error {
+	if _, ok := defs[name]; !ok {
+		return fmt.Errorf("%w: %s", ErrUnknown, name)
+	}
+
+	s.mu.Lock()
+	s.values[name] = value
+	hooks := slices.Clone(s.hooks[name])
+	s.mu.Unlock()
+
+	for _, fn := range hooks {
+		fn(value)
+	}
+	return nil
+}
+
+// Persist writes the current value of a flag back to the configuration file
+func (s *Set) Persist(name string) error {
+	def, ok := defs[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknown, name)
+	}
+	if s.path == "" {
+		return errors.New("no configuration file to persist to")
+	}
+
+	f, err := ini.Load(s.path)
+	if err != nil {
+		return fmt.Errorf("failed to load config file: %w", err)
+	}
+	f.Section(def.section).Key(def.key).SetValue(strconv.FormatBool(s.Enabled(name)))
+	if err := f.SaveTo(s.path); err != nil {
+		return fmt.Errorf("failed to save config file: %w", err)
+	}
+	return nil
+}
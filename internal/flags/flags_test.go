@@ -0,0 +1,61 @@
+package flags
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kolobock/rockpi-quad-go/internal/config"
+)
+
+func TestSet(t *testing.T) {
+	s := New(&config.Config{Slider: config.SliderConfig{Auto: true}})
+
+	if !s.Enabled(AutoSlide) || s.Enabled(Fahrenheit) {
+		t.Fatalf("initial flags = %v, want auto_slide only", s.All())
+	}
+
+	var got []bool
+	s.OnChange(Verbose, func(v bool) { got = append(got, v) })
+	if err := s.Set(Verbose, true); err != nil {
+		t.Fatalf("Set(verbose) error: %v", err)
+	}
+	if !s.Enabled(Verbose) || len(got) != 1 || !got[0] {
+		t.Errorf("verbose = %v, hook calls = %v; want true, [true]", s.Enabled(Verbose), got)
+	}
+
+	if err := s.Set("colour", true); !errors.Is(err, ErrUnknown) {
+		t.Errorf("Set(unknown) error = %v, want ErrUnknown", err)
+	}
+}
+
+func TestPersist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rockpi-quad.conf")
+	if err := os.WriteFile(path, []byte("[oled]\nrotate = true\n"), 0600); err != nil {
+		t.Fatalf("failed to create test config: %v", err)
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	s := New(cfg)
+	for _, name := range []string{Fahrenheit, MuteAlerts} {
+		if err := s.Set(name, true); err != nil {
+			t.Fatalf("Set(%s) error: %v", name, err)
+		}
+		if err := s.Persist(name); err != nil {
+			t.Fatalf("Persist(%s) error: %v", name, err)
+		}
+	}
+
+	reloaded, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("reload failed: %v", err)
+	}
+	if !reloaded.OLED.Fahrenheit || !reloaded.Alerts.Mute || reloaded.OLED.Rotate != 180 {
+		t.Errorf("reloaded fahrenheit=%v mute=%v rotate=%v, want on, on and 180",
+			reloaded.OLED.Fahrenheit, reloaded.Alerts.Mute, reloaded.OLED.Rotate)
+	}
+}
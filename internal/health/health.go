@@ -0,0 +1,120 @@
+// Package health tracks which subsystems are working so the daemon can keep
+// running in a reduced mode instead of exiting or silently losing features.
+package health
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/kolobock/rockpi-quad-go/internal/logger"
+)
+
+// State is the condition of a single module
+type State string
+
+const (
+	StateOK       State = "ok"
+	StateDegraded State = "degraded"
+	StateFailed   State = "failed"
+	StateDisabled State = "disabled"
+)
+
+// Mode is the overall operating mode of the daemon
+type Mode string
+
+const (
+	ModeNormal   Mode = "normal"
+	ModeDegraded Mode = "degraded"
+)
+
+// Module names used in reports
+const (
+	Fan    = "fan"
+	OLED   = "oled"
+	Button = "button"
+	SMART  = "smart"
+)
+
+// ModuleStatus is the reported state of a module with the reason it is not ok
+type ModuleStatus struct {
+	Name   string `json:"name"`
+	State  State  `json:"state"`
+	Reason string `json:"reason,omitempty"`
+	// Restarts counts how often the supervisor restarted the module
+	Restarts int `json:"restarts,omitempty"`
+}
+
+// Report is a snapshot of all tracked modules
+type Report struct {
+	Mode    Mode           `json:"mode"`
+	Modules []ModuleStatus `json:"modules"`
+}
+
+// Tracker records module states; it is safe for concurrent use
+type Tracker struct {
+	mu      sync.Mutex
+	modules map[string]ModuleStatus
+}
+
+// NewTracker creates an empty tracker
+func NewTracker() *Tracker {
+	return &Tracker{modules: make(map[string]ModuleStatus)}
+}
+
+// Set records the state of a module, logging transitions away from and back to ok
+func (t *Tracker) Set(name string, state State, reason string) {
+	t.mu.Lock()
+	prev, known := t.modules[name]
+	t.modules[name] = ModuleStatus{Name: name, State: state, Reason: reason, Restarts: prev.Restarts}
+	t.mu.Unlock()
+
+	if known && prev.State == state && prev.Reason == reason {
+		return
+	}
+	switch state {
+	case StateDegraded, StateFailed:
+		logger.Errorf("Module %s %s: %s", name, state, reason)
+	case StateOK:
+		if prev.State == StateDegraded || prev.State == StateFailed {
+			logger.Errorf("Module %s recovered", name)
+		}
+	case StateDisabled:
+	}
+}
+
+// Restarted counts a restart of a module
+func (t *Tracker) Restarted(name string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	m := t.modules[name]
+	m.Name = name
+	m.Restarts++
+	t.modules[name] = m
+}
+
+// State returns the recorded state of a module, or disabled if it was never set
+func (t *Tracker) State(name string) State {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if m, ok := t.modules[name]; ok {
+		return m.State
+	}
+	return StateDisabled
+}
+
+// Report returns the modules sorted by name and the overall mode; any module that
+// is enabled but not ok puts the daemon in degraded mode
+func (t *Tracker) Report() Report {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	r := Report{Mode: ModeNormal, Modules: make([]ModuleStatus, 0, len(t.modules))}
+	for _, m := range t.modules {
+		r.Modules = append(r.Modules, m)
+		if m.State == StateDegraded || m.State == StateFailed {
+			r.Mode = ModeDegraded
+		}
+	}
+	sort.Slice(r.Modules, func(i, j int) bool { return r.Modules[i].Name < r.Modules[j].Name })
+	return r
+}
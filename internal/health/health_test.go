@@ -0,0 +1,65 @@
+package health
+
+import "testing"
+
+func TestReportMode(t *testing.T) {
+	tests := []struct {
+		name   string
+		states map[string]State
+		want   Mode
+	}{
+		{"empty", nil, ModeNormal},
+		{"all ok", map[string]State{Fan: StateOK, OLED: StateOK}, ModeNormal},
+		{"disabled modules", map[string]State{Fan: StateOK, OLED: StateDisabled}, ModeNormal},
+		{"oled failed", map[string]State{Fan: StateOK, OLED: StateFailed, Button: StateOK}, ModeDegraded},
+		{"pwm degraded", map[string]State{Fan: StateDegraded}, ModeDegraded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := NewTracker()
+			for name, state := range tt.states {
+				tr.Set(name, state, "")
+			}
+			if got := tr.Report().Mode; got != tt.want {
+				t.Errorf("Report().Mode = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReportSortedAndState(t *testing.T) {
+	tr := NewTracker()
+	tr.Set(SMART, StateFailed, "smartctl not found")
+	tr.Set(Fan, StateOK, "")
+	tr.Set(Button, StateOK, "")
+
+	r := tr.Report()
+	if len(r.Modules) != 3 || r.Modules[0].Name != Button || r.Modules[2].Name != SMART {
+		t.Errorf("Report().Modules = %+v, want sorted by name", r.Modules)
+	}
+	if r.Modules[2].Reason != "smartctl not found" {
+		t.Errorf("reason = %q, want smartctl not found", r.Modules[2].Reason)
+	}
+
+	if got := tr.State(OLED); got != StateDisabled {
+		t.Errorf("State(unset) = %v, want disabled", got)
+	}
+	tr.Set(SMART, StateOK, "")
+	if got := tr.State(SMART); got != StateOK {
+		t.Errorf("State(smart) = %v, want ok", got)
+	}
+}
+
+func TestRestartsKept(t *testing.T) {
+	tr := NewTracker()
+	tr.Set(OLED, StateFailed, "crashed")
+	tr.Restarted(OLED)
+	tr.Restarted(OLED)
+	tr.Set(OLED, StateOK, "")
+
+	r := tr.Report()
+	if len(r.Modules) != 1 || r.Modules[0].Restarts != 2 || r.Modules[0].State != StateOK {
+		t.Errorf("modules = %+v, want oled ok with 2 restarts", r.Modules)
+	}
+}
@@ -0,0 +1,126 @@
+package heartbeat
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/kolobock/rockpi-quad-go/internal/logger"
+)
+
+const requestTimeout = 10 * time.Second
+
+const (
+	// KindHealthchecks pings URL on success and URL/fail on failure (healthchecks.io)
+	KindHealthchecks = "healthchecks"
+	// KindUptimeKuma requests an Uptime Kuma push URL with status=up|down and msg
+	KindUptimeKuma = "uptime-kuma"
+)
+
+// HealthFunc reports whether the daemon is healthy, with a reason when it is not
+type HealthFunc func() (ok bool, reason string)
+
+// Pinger periodically pings a dead-man-switch URL. A healthy daemon reports up, an
+// unhealthy one reports failure, and a dead one stops pinging so the service raises
+// the alarm.
+type Pinger struct {
+	kind   string
+	url    string
+	health HealthFunc
+	client *http.Client
+}
+
+// New creates a pinger for the given URL. An empty kind is detected from the URL:
+// Uptime Kuma push URLs contain /api/push/, anything else is treated as healthchecks.io.
+func New(kind, rawURL string, health HealthFunc) *Pinger {
+	if kind == "" {
+		kind = KindHealthchecks
+		if strings.Contains(rawURL, "/api/push/") {
+			kind = KindUptimeKuma
+		}
+	}
+	if kind == KindHealthchecks {
+		rawURL = strings.TrimRight(rawURL, "/")
+	}
+	return &Pinger{
+		kind:   kind,
+		url:    rawURL,
+		health: health,
+		client: &http.Client{Timeout: requestTimeout},
+	}
+}
+
+// pingURL returns the URL to request for the given health state
+func (p *Pinger) pingURL(ok bool, reason string) (string, error) {
+	if p.kind != KindUptimeKuma {
+		if ok {
+			return p.url, nil
+		}
+		return p.url + "/fail", nil
+	}
+
+	u, err := url.Parse(p.url)
+	if err != nil {
+		return "", err
+	}
+	status, msg := "up", "OK"
+	if !ok {
+		status, msg = "down", reason
+	}
+	q := u.Query()
+	q.Set("status", status)
+	q.Set("msg", msg)
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// Ping reports the current health once
+func (p *Pinger) Ping(ctx context.Context) error {
+	ok, reason := true, ""
+	if p.health != nil {
+		ok, reason = p.health()
+	}
+
+	target, err := p.pingURL(ok, reason)
+	if err != nil {
+		return err
+	}
+	method := http.MethodPost
+	if p.kind == KindUptimeKuma {
+		method = http.MethodGet
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, strings.NewReader(reason))
+	if err != nil {
+		return err
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("heartbeat returned %s", resp.Status)
+	}
+	return nil
+}
+
+// Run pings periodically until the context is cancelled
+func (p *Pinger) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := p.Ping(ctx); err != nil && ctx.Err() == nil {
+			logger.Errorf("Heartbeat ping failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package heartbeat
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestPing(t *testing.T) {
+	var gotPath, gotBody string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		body, _ := io.ReadAll(r.Body)
+		gotBody = string(body)
+	}))
+	defer srv.Close()
+
+	healthy := true
+	p := New("", srv.URL+"/ping/abc/", func() (bool, string) {
+		if healthy {
+			return true, ""
+		}
+		return false, "fan PWM failing"
+	})
+
+	if err := p.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping() error: %v", err)
+	}
+	if gotPath != "/ping/abc" {
+		t.Errorf("healthy ping path = %q, want /ping/abc", gotPath)
+	}
+
+	healthy = false
+	if err := p.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping() error: %v", err)
+	}
+	if gotPath != "/ping/abc/fail" {
+		t.Errorf("unhealthy ping path = %q, want /ping/abc/fail", gotPath)
+	}
+	if gotBody != "fan PWM failing" {
+		t.Errorf("unhealthy ping body = %q, want the reason", gotBody)
+	}
+}
+
+func TestPingHTTPError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	if err := New(KindHealthchecks, srv.URL, nil).Ping(context.Background()); err == nil {
+		t.Error("Ping() error = nil, want error for 404")
+	}
+}
+
+func TestPingUptimeKuma(t *testing.T) {
+	var gotQuery url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotQuery = r.URL.Query()
+	}))
+	defer srv.Close()
+
+	healthy := true
+	p := New("", srv.URL+"/api/push/abc123?ping=", func() (bool, string) {
+		if healthy {
+			return true, ""
+		}
+		return false, "thermal emergency"
+	})
+	if p.kind != KindUptimeKuma {
+		t.Fatalf("kind = %q, want %q detected from the URL", p.kind, KindUptimeKuma)
+	}
+
+	if err := p.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping() error: %v", err)
+	}
+	if gotQuery.Get("status") != "up" || gotQuery.Get("msg") != "OK" {
+		t.Errorf("healthy query = %v, want status=up msg=OK", gotQuery)
+	}
+
+	healthy = false
+	if err := p.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping() error: %v", err)
+	}
+	if gotQuery.Get("status") != "down" || gotQuery.Get("msg") != "thermal emergency" {
+		t.Errorf("unhealthy query = %v, want status=down with the reason", gotQuery)
+	}
+}
@@ -0,0 +1,155 @@
+package kmsg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/kolobock/rockpi-quad-go/internal/logger"
+)
+
+// DefaultPath is the kernel log device followed by the watcher
+const DefaultPath = "/dev/kmsg"
+
+const maxRecent = 10
+
+// Category classifies a matched kernel message
+type Category string
+
+const (
+	IOError   Category = "io-error"
+	ReadOnly  Category = "read-only"
+	OOMKill   Category = "oom-kill"
+	LinkReset Category = "link-reset"
+)
+
+// Event is a kernel log message that matched one of the watched patterns
+type Event struct {
+	Category Category
+	Message  string
+	Time     time.Time
+}
+
+var patterns = []struct {
+	category Category
+	re       *regexp.Regexp
+}{
+	{ReadOnly, regexp.MustCompile(`(?i)remounting filesystem read-only|forced readonly|remounted read-only`)},
+	{OOMKill, regexp.MustCompile(`(?i)out of memory: killed process|oom-kill:`)},
+	{LinkReset, regexp.MustCompile(`ata\d+(\.\d+)?: (hard resetting link|SATA link down|exception Emask)`)},
+	{IOError, regexp.MustCompile(`(?i)i/o error`)},
+}
+
+// Watcher follows the kernel log and reports storage and memory errors
+type Watcher struct {
+	path   string
+	events chan Event
+	recent []Event
+	mu     sync.Mutex
+}
+
+// New creates a watcher for the given kmsg device path
+func New(path string) *Watcher {
+	return &Watcher{
+		path:   path,
+		events: make(chan Event, 10),
+	}
+}
+
+// Classify returns the category of a kernel message, if it is one we watch for
+func Classify(msg string) (Category, bool) {
+	for _, p := range patterns {
+		if p.re.MatchString(msg) {
+			return p.category, true
+		}
+	}
+	return "", false
+}
+
+// parseRecord extracts the message text from a "prio,seq,ts,flags;message" record
+func parseRecord(record string) string {
+	_, msg, ok := strings.Cut(record, ";")
+	if !ok {
+		return ""
+	}
+	msg, _, _ = strings.Cut(msg, "\n")
+	return strings.TrimSpace(msg)
+}
+
+// Run follows new kernel log records until the context is cancelled
+func (w *Watcher) Run(ctx context.Context) error {
+	f, err := os.Open(w.path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", w.path, err)
+	}
+
+	// Skip the existing ring buffer, only new messages are interesting
+	if _, err := f.Seek(0, io.SeekEnd); err != nil {
+		logger.Infof("Failed to seek %s to end: %v", w.path, err)
+	}
+
+	go func() {
+		<-ctx.Done()
+		f.Close()
+	}()
+
+	buf := make([]byte, 8192)
+	for {
+		n, err := f.Read(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			if errors.Is(err, syscall.EPIPE) {
+				// Records were overwritten before we read them, keep following
+				continue
+			}
+			return fmt.Errorf("failed to read %s: %w", w.path, err)
+		}
+
+		w.handle(parseRecord(string(buf[:n])))
+	}
+}
+
+func (w *Watcher) handle(msg string) {
+	category, ok := Classify(msg)
+	if !ok {
+		return
+	}
+
+	evt := Event{Category: category, Message: msg, Time: time.Now()}
+
+	w.mu.Lock()
+	w.recent = append(w.recent, evt)
+	if len(w.recent) > maxRecent {
+		w.recent = w.recent[len(w.recent)-maxRecent:]
+	}
+	w.mu.Unlock()
+
+	select {
+	case w.events <- evt:
+	default:
+	}
+}
+
+// Events returns the channel that receives matched kernel events
+func (w *Watcher) Events() <-chan Event {
+	return w.events
+}
+
+// Recent returns the most recent matched events, oldest first
+func (w *Watcher) Recent() []Event {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	recent := make([]Event, len(w.recent))
+	copy(recent, w.recent)
+	return recent
+}
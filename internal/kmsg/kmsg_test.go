@@ -0,0 +1,50 @@
+package kmsg
+
+import "testing"
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		msg  string
+		want Category
+		ok   bool
+	}{
+		{"blk_update_request: I/O error, dev sda, sector 1234", IOError, true},
+		{"EXT4-fs (sda1): Remounting filesystem read-only", ReadOnly, true},
+		{"Out of memory: Killed process 1234 (java)", OOMKill, true},
+		{"ata2: hard resetting link", LinkReset, true},
+		{"ata3.00: exception Emask 0x10 SAct 0x0", LinkReset, true},
+		{"usb 1-1: new high-speed USB device", "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := Classify(tt.msg)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("Classify(%q) = %q, %v; want %q, %v", tt.msg, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestParseRecord(t *testing.T) {
+	record := "3,1234,5678901,-;EXT4-fs (sda1): Remounting filesystem read-only\n SUBSYSTEM=block\n"
+	if got := parseRecord(record); got != "EXT4-fs (sda1): Remounting filesystem read-only" {
+		t.Errorf("parseRecord() = %q", got)
+	}
+	if got := parseRecord("garbage"); got != "" {
+		t.Errorf("parseRecord(garbage) = %q, want empty", got)
+	}
+}
+
+func TestWatcherRecent(t *testing.T) {
+	w := New("")
+	for i := 0; i < maxRecent+2; i++ {
+		w.handle("ata1: SATA link down")
+	}
+	w.handle("nothing interesting")
+
+	if got := len(w.Recent()); got != maxRecent {
+		t.Errorf("len(Recent()) = %d, want %d", got, maxRecent)
+	}
+	if got := len(w.Events()); got != cap(w.events) {
+		t.Errorf("len(Events()) = %d, want %d", got, cap(w.events))
+	}
+}
@@ -0,0 +1,63 @@
+// Package luks reports whether LUKS volumes are open and opens them with a keyfile,
+// for encrypted data disks that are unlocked after boot
+package luks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/kolobock/rockpi-quad-go/internal/config"
+)
+
+// devMapper is replaced in tests
+var devMapper = "/dev/mapper"
+
+// IsOpen reports whether the volume's mapping exists
+func IsOpen(v config.LUKSVolume) bool {
+	_, err := os.Stat(filepath.Join(devMapper, v.Name))
+	return err == nil
+}
+
+// Open opens a volume with `cryptsetup open --key-file`; an open volume is left alone
+func Open(ctx context.Context, v config.LUKSVolume, keyfile string) error {
+	if IsOpen(v) {
+		return nil
+	}
+	// #nosec G204 - the device, name and keyfile come from the configuration file
+	out, err := exec.CommandContext(ctx, "cryptsetup", "open", "--key-file", keyfile, v.Device, v.Name).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("cryptsetup open %s failed: %w: %s", v.Name, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// OpenAll opens every locked volume and returns how many it opened. The keyfile is
+// checked first, so a missing key (e.g. a USB stick not yet plugged in) gives one
+// clear error instead of one per volume.
+func OpenAll(ctx context.Context, volumes []config.LUKSVolume, keyfile string) (int, error) {
+	if keyfile == "" {
+		return 0, errors.New("no [luks] keyfile configured")
+	}
+	if _, err := os.Stat(keyfile); err != nil {
+		return 0, fmt.Errorf("keyfile unavailable: %w", err)
+	}
+
+	opened := 0
+	var errs []error
+	for _, v := range volumes {
+		if IsOpen(v) {
+			continue
+		}
+		if err := Open(ctx, v, keyfile); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		opened++
+	}
+	return opened, errors.Join(errs...)
+}
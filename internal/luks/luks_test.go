@@ -0,0 +1,50 @@
+package luks
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kolobock/rockpi-quad-go/internal/config"
+)
+
+func TestIsOpen(t *testing.T) {
+	devMapper = t.TempDir()
+	t.Cleanup(func() { devMapper = "/dev/mapper" })
+
+	if err := os.WriteFile(filepath.Join(devMapper, "data"), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if !IsOpen(config.LUKSVolume{Name: "data", Device: "/dev/sda1"}) {
+		t.Error("IsOpen(data) = false with /dev/mapper/data present")
+	}
+	if IsOpen(config.LUKSVolume{Name: "backup", Device: "/dev/sdb1"}) {
+		t.Error("IsOpen(backup) = true without a mapping")
+	}
+}
+
+func TestOpenAllNeedsKeyfile(t *testing.T) {
+	devMapper = t.TempDir()
+	t.Cleanup(func() { devMapper = "/dev/mapper" })
+	volumes := []config.LUKSVolume{{Name: "data", Device: "/dev/sda1"}}
+
+	if _, err := OpenAll(context.Background(), volumes, ""); err == nil {
+		t.Error("OpenAll() without a keyfile succeeded")
+	}
+	if _, err := OpenAll(context.Background(), volumes, filepath.Join(devMapper, "missing.key")); err == nil {
+		t.Error("OpenAll() with a missing keyfile succeeded")
+	}
+
+	// Open volumes are skipped without running cryptsetup
+	keyfile := filepath.Join(t.TempDir(), "data.key")
+	for _, path := range []string{keyfile, filepath.Join(devMapper, "data")} {
+		if err := os.WriteFile(path, nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if opened, err := OpenAll(context.Background(), volumes, keyfile); opened != 0 || err != nil {
+		t.Errorf("OpenAll() = %d, %v, want nothing to open", opened, err)
+	}
+}
@@ -0,0 +1,188 @@
+package network
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kolobock/rockpi-quad-go/internal/logger"
+)
+
+const (
+	checkICMP = "icmp"
+	checkTCP  = "tcp"
+
+	checkTimeout = 2 * time.Second
+)
+
+var pingTimeRe = regexp.MustCompile(`time[=<]([\d.]+) ?ms`)
+
+// Check is a single connectivity probe, e.g. "gw=icmp:192.168.1.1" or "dns=tcp:1.1.1.1:53"
+type Check struct {
+	Name   string
+	Kind   string
+	Target string
+}
+
+// CheckResult holds the latest outcome of a check
+type CheckResult struct {
+	Check
+	OK        bool
+	Latency   time.Duration
+	Failures  int
+	LastCheck time.Time
+}
+
+// ParseChecks parses entries in the form name=kind:target. Results are kept by
+// name, so every check needs a name of its own.
+func ParseChecks(entries []string) ([]Check, error) {
+	checks := make([]Check, 0, len(entries))
+	seen := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		name, spec, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid check %q: expected name=kind:target", entry)
+		}
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return nil, fmt.Errorf("invalid check %q: missing name", entry)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("invalid check %q: duplicate name %q", entry, name)
+		}
+		seen[name] = true
+		kind, target, ok := strings.Cut(spec, ":")
+		if !ok || target == "" {
+			return nil, fmt.Errorf("invalid check %q: expected name=kind:target", entry)
+		}
+		if kind != checkICMP && kind != checkTCP {
+			return nil, fmt.Errorf("invalid check %q: unknown kind %q", entry, kind)
+		}
+		if kind == checkTCP {
+			if _, _, err := net.SplitHostPort(target); err != nil {
+				return nil, fmt.Errorf("invalid check %q: %w", entry, err)
+			}
+		}
+
+		checks = append(checks, Check{Name: name, Kind: kind, Target: target})
+	}
+	return checks, nil
+}
+
+// Checker periodically runs connectivity checks and alerts on sustained failure
+type Checker struct {
+	checks    []Check
+	threshold int
+	results   map[string]*CheckResult
+	mu        sync.Mutex
+}
+
+// NewChecker creates a checker alerting after threshold consecutive failures
+func NewChecker(checks []Check, threshold int) *Checker {
+	results := make(map[string]*CheckResult, len(checks))
+	for _, chk := range checks {
+		results[chk.Name] = &CheckResult{Check: chk}
+	}
+	return &Checker{checks: checks, threshold: threshold, results: results}
+}
+
+func probe(ctx context.Context, chk Check) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout+time.Second)
+	defer cancel()
+
+	start := time.Now()
+	switch chk.Kind {
+	case checkTCP:
+		dialer := net.Dialer{Timeout: checkTimeout}
+		conn, err := dialer.DialContext(ctx, "tcp", chk.Target)
+		if err != nil {
+			return 0, err
+		}
+		conn.Close()
+		return time.Since(start), nil
+	default:
+		// #nosec G204 - target comes from the config file
+		out, err := exec.CommandContext(ctx, "ping", "-c", "1", "-W", "2", chk.Target).Output()
+		if err != nil {
+			return 0, err
+		}
+		if m := pingTimeRe.FindStringSubmatch(string(out)); m != nil {
+			if ms, err := strconv.ParseFloat(m[1], 64); err == nil {
+				return time.Duration(ms * float64(time.Millisecond)), nil
+			}
+		}
+		return time.Since(start), nil
+	}
+}
+
+// RunChecks probes every configured target once
+func (c *Checker) RunChecks(ctx context.Context) {
+	for _, chk := range c.checks {
+		latency, err := probe(ctx, chk)
+		c.record(chk.Name, latency, err)
+	}
+}
+
+func (c *Checker) record(name string, latency time.Duration, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	res := c.results[name]
+	res.LastCheck = time.Now()
+	if err != nil {
+		res.OK = false
+		res.Latency = 0
+		res.Failures++
+		if res.Failures == c.threshold {
+			logger.Errorf("Health check %s (%s %s) failing: %v", name, res.Kind, res.Target, err)
+		}
+		return
+	}
+
+	if res.Failures >= c.threshold {
+		logger.Errorf("Health check %s (%s %s) recovered after %d failures", name, res.Kind, res.Target, res.Failures)
+	}
+	res.OK = true
+	res.Latency = latency
+	res.Failures = 0
+}
+
+// Results returns the latest result of every check in configuration order
+func (c *Checker) Results() []CheckResult {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	results := make([]CheckResult, 0, len(c.checks))
+	for _, chk := range c.checks {
+		results = append(results, *c.results[chk.Name])
+	}
+	return results
+}
+
+// Run probes all checks periodically until the context is cancelled
+func (c *Checker) Run(ctx context.Context, interval time.Duration) {
+	c.RunChecks(ctx)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.RunChecks(ctx)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package network
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestParseChecks(t *testing.T) {
+	checks, err := ParseChecks([]string{"gw=icmp:192.168.1.1", " dns=tcp:1.1.1.1:53", ""})
+	if err != nil {
+		t.Fatalf("ParseChecks failed: %v", err)
+	}
+	if len(checks) != 2 {
+		t.Fatalf("got %d checks, want 2", len(checks))
+	}
+	if checks[1] != (Check{Name: "dns", Kind: "tcp", Target: "1.1.1.1:53"}) {
+		t.Errorf("checks[1] = %+v", checks[1])
+	}
+
+	for _, bad := range []string{"gw", "gw=icmp", "gw=udp:1.1.1.1", "dns=tcp:1.1.1.1", " =icmp:1.1.1.1"} {
+		if _, err := ParseChecks([]string{bad}); err == nil {
+			t.Errorf("ParseChecks(%q) expected error, got nil", bad)
+		}
+	}
+	// Two checks of one name would share their result
+	if _, err := ParseChecks([]string{"gw=icmp:192.168.1.1", "gw =tcp:192.168.1.1:80"}); err == nil {
+		t.Error("ParseChecks() accepted a duplicate name")
+	}
+}
+
+func TestCheckerTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("cannot listen: %v", err)
+	}
+	defer ln.Close()
+
+	checker := NewChecker([]Check{{Name: "local", Kind: "tcp", Target: ln.Addr().String()}}, 2)
+	checker.RunChecks(context.Background())
+
+	res := checker.Results()[0]
+	if !res.OK || res.Failures != 0 {
+		t.Errorf("result = %+v, want OK", res)
+	}
+}
+
+func TestCheckerFailureCount(t *testing.T) {
+	checker := NewChecker([]Check{{Name: "x", Kind: "tcp", Target: "x:1"}}, 2)
+
+	checker.record("x", 0, errors.New("down"))
+	checker.record("x", 0, errors.New("down"))
+	if res := checker.Results()[0]; res.OK || res.Failures != 2 {
+		t.Errorf("after failures = %+v", res)
+	}
+
+	checker.record("x", 5*time.Millisecond, nil)
+	if res := checker.Results()[0]; !res.OK || res.Failures != 0 || res.Latency != 5*time.Millisecond {
+		t.Errorf("after recovery = %+v", res)
+	}
+}
@@ -0,0 +1,172 @@
+package network
+
+import (
+	"context"
+	"net"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Address families for InterfaceAddresses
+const (
+	FamilyIPv4 = "ipv4"
+	FamilyIPv6 = "ipv6"
+	FamilyBoth = "both"
+)
+
+// containerPrefixes name the bridges and veth pairs of container runtimes and VMs,
+// left out when no interfaces are configured
+var containerPrefixes = []string{"docker", "veth", "br-", "virbr", "cni", "flannel"}
+
+// Address is an IP address and the interface it is assigned to
+type Address struct {
+	Interface string
+	IP        net.IP
+}
+
+// InterfaceAddresses returns the addresses of the named interfaces, or of every
+// interface that is up except container bridges when names is empty, limited to
+// family. Loopback and IPv6 link-local addresses are left out; with FamilyBoth an
+// interface lists its IPv4 addresses first.
+func InterfaceAddresses(names []string, family string) []Address {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil
+	}
+
+	var all []Address
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok {
+				all = append(all, Address{Interface: iface.Name, IP: ipNet.IP})
+			}
+		}
+	}
+	return selectAddresses(all, names, family)
+}
+
+// selectAddresses filters addrs by interface name and family, ordered by names when
+// given and IPv4 before IPv6 within each interface
+func selectAddresses(addrs []Address, names []string, family string) []Address {
+	order := names
+	if len(order) == 0 {
+		for _, addr := range addrs {
+			if !slices.Contains(order, addr.Interface) && !isContainerInterface(addr.Interface) {
+				order = append(order, addr.Interface)
+			}
+		}
+	}
+
+	var selected []Address
+	for _, name := range order {
+		for _, v4 := range []bool{true, false} {
+			if v4 && family == FamilyIPv6 || !v4 && family == FamilyIPv4 {
+				continue
+			}
+			for _, addr := range addrs {
+				if addr.Interface != name || (addr.IP.To4() != nil) != v4 {
+					continue
+				}
+				if addr.IP.IsLoopback() || !v4 && addr.IP.IsLinkLocalUnicast() {
+					continue
+				}
+				selected = append(selected, addr)
+			}
+		}
+	}
+	return selected
+}
+
+func isContainerInterface(name string) bool {
+	for _, prefix := range containerPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// PrimaryIP returns the source address used for the default route, falling back
+// to the first non-loopback IPv4 address
+func PrimaryIP() string {
+	// UDP "dial" only selects a route, no packets are sent
+	if conn, err := net.Dial("udp", "192.0.2.1:9"); err == nil {
+		defer conn.Close()
+		if addr, ok := conn.LocalAddr().(*net.UDPAddr); ok && !addr.IP.IsLoopback() {
+			return addr.IP.String()
+		}
+	}
+
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return ""
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && !ipNet.IP.IsLoopback() && ipNet.IP.To4() != nil {
+			return ipNet.IP.String()
+		}
+	}
+	return ""
+}
+
+// IPWatcher reports changes of the primary IP address
+type IPWatcher struct {
+	last    string
+	lookup  func() string
+	changes chan string
+}
+
+// NewIPWatcher creates a watcher for the primary IP address
+func NewIPWatcher() *IPWatcher {
+	return &IPWatcher{
+		lookup:  PrimaryIP,
+		changes: make(chan string, 1),
+	}
+}
+
+// Changes returns the channel receiving the new address after each change
+func (w *IPWatcher) Changes() <-chan string {
+	return w.changes
+}
+
+// check looks up the current address and reports whether it changed since the last call.
+// The first lookup only establishes the baseline.
+func (w *IPWatcher) check() (string, bool) {
+	ip := w.lookup()
+	if ip == "" || ip == w.last {
+		return ip, false
+	}
+	first := w.last == ""
+	w.last = ip
+	return ip, !first
+}
+
+// Run polls the primary address until the context is cancelled
+func (w *IPWatcher) Run(ctx context.Context, interval time.Duration) {
+	w.check()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if ip, changed := w.check(); changed {
+				select {
+				case w.changes <- ip:
+				default:
+				}
+			}
+		}
+	}
+}
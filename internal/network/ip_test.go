@@ -0,0 +1,61 @@
+package network
+
+import (
+	"net"
+	"slices"
+	"testing"
+)
+
+func TestIPWatcherCheck(t *testing.T) {
+	addrs := []string{"", "192.168.1.10", "192.168.1.10", "192.168.1.23"}
+	i := 0
+	w := &IPWatcher{lookup: func() string {
+		ip := addrs[i]
+		i++
+		return ip
+	}}
+
+	wantChanged := []bool{false, false, false, true}
+	for n, want := range wantChanged {
+		if _, changed := w.check(); changed != want {
+			t.Errorf("check #%d changed = %v, want %v", n, changed, want)
+		}
+	}
+}
+
+func TestSelectAddresses(t *testing.T) {
+	addrs := []Address{
+		{"eth0", net.ParseIP("2001:db8::10")},
+		{"eth0", net.ParseIP("fe80::1")},
+		{"eth0", net.ParseIP("192.168.1.10")},
+		{"docker0", net.ParseIP("172.17.0.1")},
+		{"wlan0", net.ParseIP("192.168.1.20")},
+		{"wlan0", net.ParseIP("2001:db8::20")},
+	}
+
+	tests := []struct {
+		name   string
+		names  []string
+		family string
+		want   []string
+	}{
+		{"ipv4 of all interfaces", nil, FamilyIPv4, []string{"eth0 192.168.1.10", "wlan0 192.168.1.20"}},
+		{"ipv6 skips link-local", nil, FamilyIPv6, []string{"eth0 2001:db8::10", "wlan0 2001:db8::20"}},
+		{"both, ipv4 first", []string{"eth0"}, FamilyBoth, []string{"eth0 192.168.1.10", "eth0 2001:db8::10"}},
+		{"configured order", []string{"wlan0", "eth0"}, FamilyIPv4, []string{"wlan0 192.168.1.20", "eth0 192.168.1.10"}},
+		{"configured container bridge", []string{"docker0"}, FamilyIPv4, []string{"docker0 172.17.0.1"}},
+		{"missing interface", []string{"eth1"}, FamilyBoth, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, a := range selectAddresses(addrs, tt.names, tt.family) {
+				got = append(got, a.Interface+" "+a.IP.String())
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("selectAddresses() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package network
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kolobock/rockpi-quad-go/internal/logger"
+)
+
+var sysClassNet = "/sys/class/net"
+
+// LinkState describes the physical link of a network interface
+type LinkState struct {
+	Carrier   bool
+	SpeedMbps int
+}
+
+// ReadLinkState reads carrier and negotiated speed from sysfs.
+// SpeedMbps is 0 when the driver does not report a speed (e.g. wireless).
+func ReadLinkState(iface string) LinkState {
+	var state LinkState
+
+	if data, err := os.ReadFile(sysClassNet + "/" + iface + "/carrier"); err == nil {
+		state.Carrier = strings.TrimSpace(string(data)) == "1"
+	}
+	if !state.Carrier {
+		return state
+	}
+
+	if data, err := os.ReadFile(sysClassNet + "/" + iface + "/speed"); err == nil {
+		if speed, err := strconv.Atoi(strings.TrimSpace(string(data))); err == nil && speed > 0 {
+			state.SpeedMbps = speed
+		}
+	}
+	return state
+}
+
+// FormatSpeed renders a link speed compactly, e.g. 100M, 1G, 2.5G
+func FormatSpeed(state LinkState) string {
+	switch {
+	case !state.Carrier:
+		return "down"
+	case state.SpeedMbps == 0:
+		return "up"
+	case state.SpeedMbps >= 1000:
+		return strconv.FormatFloat(float64(state.SpeedMbps)/1000, 'f', -1, 64) + "G"
+	default:
+		return fmt.Sprintf("%dM", state.SpeedMbps)
+	}
+}
+
+// LinkMonitor watches interfaces for carrier and speed changes
+type LinkMonitor struct {
+	interfaces []string
+	minSpeed   int
+	last       map[string]LinkState
+}
+
+// NewLinkMonitor creates a monitor alerting when a link negotiates below minSpeed Mb/s
+func NewLinkMonitor(interfaces []string, minSpeed int) *LinkMonitor {
+	return &LinkMonitor{
+		interfaces: interfaces,
+		minSpeed:   minSpeed,
+		last:       make(map[string]LinkState),
+	}
+}
+
+// Check compares the current link states with the previous poll and logs changes
+func (m *LinkMonitor) Check() {
+	for _, iface := range m.interfaces {
+		if _, err := os.Stat(sysClassNet + "/" + iface); err != nil {
+			continue
+		}
+
+		state := ReadLinkState(iface)
+		prev, seen := m.last[iface]
+		m.last[iface] = state
+		if seen && prev == state {
+			continue
+		}
+
+		if seen {
+			logger.Infof("Link %s changed: %s -> %s", iface, FormatSpeed(prev), FormatSpeed(state))
+			if prev.Carrier && !state.Carrier {
+				logger.Errorf("Network link %s lost carrier", iface)
+			}
+		}
+		if m.isDegraded(state) {
+			logger.Errorf("Network link %s negotiated %d Mb/s, below the expected %d Mb/s (check the cable)",
+				iface, state.SpeedMbps, m.minSpeed)
+		}
+	}
+}
+
+func (m *LinkMonitor) isDegraded(state LinkState) bool {
+	return m.minSpeed > 0 && state.Carrier && state.SpeedMbps > 0 && state.SpeedMbps < m.minSpeed
+}
+
+// Run polls link states until the context is cancelled
+func (m *LinkMonitor) Run(ctx context.Context, interval time.Duration) {
+	m.Check()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.Check()
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package network
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeLink(t *testing.T, root, iface, carrier, speed string) {
+	t.Helper()
+	dir := filepath.Join(root, iface)
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		t.Fatalf("mkdir failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "carrier"), []byte(carrier+"\n"), 0600); err != nil {
+		t.Fatalf("write carrier failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "speed"), []byte(speed+"\n"), 0600); err != nil {
+		t.Fatalf("write speed failed: %v", err)
+	}
+}
+
+func TestReadLinkState(t *testing.T) {
+	root := t.TempDir()
+	old := sysClassNet
+	sysClassNet = root
+	defer func() { sysClassNet = old }()
+
+	writeLink(t, root, "eth0", "1", "100")
+	writeLink(t, root, "eth1", "0", "-1")
+
+	if got := ReadLinkState("eth0"); got != (LinkState{Carrier: true, SpeedMbps: 100}) {
+		t.Errorf("ReadLinkState(eth0) = %+v", got)
+	}
+	if got := ReadLinkState("eth1"); got != (LinkState{}) {
+		t.Errorf("ReadLinkState(eth1) = %+v", got)
+	}
+
+	m := NewLinkMonitor([]string{"eth0", "eth1", "missing"}, 1000)
+	m.Check()
+	if len(m.last) != 2 {
+		t.Errorf("monitor tracked %d interfaces, want 2", len(m.last))
+	}
+	if !m.isDegraded(m.last["eth0"]) {
+		t.Error("eth0 at 100 Mb/s should be degraded")
+	}
+}
+
+func TestFormatSpeed(t *testing.T) {
+	tests := []struct {
+		state LinkState
+		want  string
+	}{
+		{LinkState{}, "down"},
+		{LinkState{Carrier: true}, "up"},
+		{LinkState{Carrier: true, SpeedMbps: 100}, "100M"},
+		{LinkState{Carrier: true, SpeedMbps: 1000}, "1G"},
+		{LinkState{Carrier: true, SpeedMbps: 2500}, "2.5G"},
+	}
+
+	for _, tt := range tests {
+		if got := FormatSpeed(tt.state); got != tt.want {
+			t.Errorf("FormatSpeed(%+v) = %q, want %q", tt.state, got, tt.want)
+		}
+	}
+}
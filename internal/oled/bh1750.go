@@ -0,0 +1,57 @@
+package oled
+
+import (
+	"fmt"
+	"time"
+
+	i2c "github.com/d2r2/go-i2c"
+)
+
+// BH1750 commands
+const (
+	bh1750PowerOn        = 0x01
+	bh1750ContinuousHRes = 0x10
+
+	// bh1750MeasureTime is the worst-case high resolution conversion time
+	bh1750MeasureTime = 180 * time.Millisecond
+
+	// BH1750DefaultAddr is the sensor address with ADDR pulled low
+	BH1750DefaultAddr = 0x23
+)
+
+// BH1750 is an I2C ambient light sensor
+type BH1750 struct {
+	i2c *i2c.I2C
+}
+
+// NewBH1750 opens a BH1750 on the given I2C bus and starts continuous measurement
+func NewBH1750(bus, addr int) (*BH1750, error) {
+	i2cBus, err := i2c.NewI2C(uint8(addr), bus) // #nosec G115 - addresses are 7-bit
+	if err != nil {
+		return nil, fmt.Errorf("failed to open I2C: %w", err)
+	}
+
+	for _, cmd := range []byte{bh1750PowerOn, bh1750ContinuousHRes} {
+		if _, err := i2cBus.WriteBytes([]byte{cmd}); err != nil {
+			i2cBus.Close()
+			return nil, fmt.Errorf("failed to start BH1750: %w", err)
+		}
+	}
+	time.Sleep(bh1750MeasureTime)
+
+	return &BH1750{i2c: i2cBus}, nil
+}
+
+// Lux returns the latest illuminance measurement
+func (s *BH1750) Lux() (float64, error) {
+	buf := make([]byte, 2)
+	if _, err := s.i2c.ReadBytes(buf); err != nil {
+		return 0, err
+	}
+	return float64(uint16(buf[0])<<8|uint16(buf[1])) / 1.2, nil
+}
+
+// Close releases the I2C connection
+func (s *BH1750) Close() error {
+	return s.i2c.Close()
+}
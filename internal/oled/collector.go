@@ -0,0 +1,121 @@
+package oled
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kolobock/rockpi-quad-go/internal/disk"
+)
+
+// staleIntervals is how many refresh intervals old a reading gets before its page
+// shows its age
+const staleIntervals = 3
+
+// reading is page data that is slow to collect, such as df or smartctl output. It is
+// refreshed in the background every interval and pages render the last value, so a
+// disk that is slow to answer never holds up the display.
+type reading[T any] struct {
+	interval time.Duration
+	read     func() T
+
+	mu    sync.Mutex
+	value T
+	at    time.Time
+}
+
+func newReading[T any](interval time.Duration, read func() T) *reading[T] {
+	return &reading[T]{interval: interval, read: read}
+}
+
+// get returns the last value and when it was read, zero before the first read
+func (r *reading[T]) get() (T, time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.value, r.at
+}
+
+// run reads the value right away and then every interval until ctx is done. Reads
+// run one at a time, so a read that hangs does not pile up more.
+func (r *reading[T]) run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		r.refresh()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *reading[T]) refresh() {
+	value := r.read()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.value, r.at = value, time.Now()
+}
+
+// stale reports whether a value read at was read too long ago to pass as current
+func (r *reading[T]) stale(at, now time.Time) bool {
+	return !at.IsZero() && now.Sub(at) > staleIntervals*r.interval
+}
+
+// ageTitle marks a page title with the age of its data when it is stale,
+// e.g. "Disk Temps 5m"
+func ageTitle[T any](r *reading[T], title string, at, now time.Time) string {
+	if !r.stale(at, now) {
+		return title
+	}
+	return strings.TrimSuffix(title, ":") + " " + formatAge(now.Sub(at))
+}
+
+// formatAge renders the age of a reading compactly, e.g. 40s or 5m
+func formatAge(d time.Duration) string {
+	if d < time.Minute {
+		return fmt.Sprintf("%.0fs", d.Seconds())
+	}
+	return formatDuration(d)
+}
+
+// initReadings creates the background readings of the disk usage and temperature pages
+func (c *Controller) initReadings() {
+	interval := time.Duration(max(c.cfg.OLED.DataInterval, 1)) * time.Second
+	c.usage = newReading(interval, c.readDiskUsage)
+	c.diskTemps = newReading(interval, readDiskTemperatures)
+}
+
+// startReadings starts collecting the data the configured pages show in the
+// background, so it is fresh whenever a page comes up
+func (c *Controller) startReadings(ctx context.Context) {
+	var usage, temps bool
+	for _, page := range c.pages {
+		switch page.(type) {
+		case *DiskUsagePage:
+			usage = true
+		case *DiskTempPage:
+			temps = true
+		}
+	}
+	if usage {
+		go c.usage.run(ctx)
+	}
+	if temps {
+		go c.diskTemps.run(ctx)
+	}
+}
+
+// readDiskTemperatures reads the temperature of every SATA disk with smartctl, keyed
+// by device; disks that do not report one are left out
+func readDiskTemperatures() map[string]float64 {
+	temps := make(map[string]float64)
+	for _, dev := range disk.GetSATADisks() {
+		if temp, err := disk.GetTemperature(dev); err == nil && temp > 0 {
+			temps[dev] = temp
+		}
+	}
+	return temps
+}
@@ -0,0 +1,78 @@
+package oled
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestReadingRun(t *testing.T) {
+	release := make(chan struct{})
+	reads := make(chan int, 10)
+	n := 0
+	r := newReading(10*time.Millisecond, func() int {
+		n++
+		reads <- n
+		if n == 1 {
+			<-release
+		}
+		return n
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		r.run(ctx)
+	}()
+
+	// The first read starts right away and get does not wait for it
+	<-reads
+	if v, at := r.get(); v != 0 || !at.IsZero() {
+		t.Errorf("get = %d, %v during the first read, want no value yet", v, at)
+	}
+	// Ticks while the read hangs do not start another one
+	time.Sleep(30 * time.Millisecond)
+	if len(reads) != 0 {
+		t.Errorf("%d reads started while the first hung", len(reads))
+	}
+
+	close(release)
+	if got := <-reads; got != 2 {
+		t.Errorf("read %d after the first finished, want 2", got)
+	}
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("run did not stop with the context")
+	}
+	if v, at := r.get(); v < 1 || at.IsZero() {
+		t.Errorf("get = %d, %v, want a read value", v, at)
+	}
+}
+
+func TestAgeTitle(t *testing.T) {
+	r := newReading(10*time.Second, func() int { return 0 })
+	read := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		at   time.Time
+		now  time.Time
+		want string
+	}{
+		{"never read", time.Time{}, read, "Disk Temps:"},
+		{"fresh", read, read.Add(10 * time.Second), "Disk Temps:"},
+		{"three intervals", read, read.Add(30 * time.Second), "Disk Temps:"},
+		{"stale", read, read.Add(45 * time.Second), "Disk Temps 45s"},
+		{"minutes", read, read.Add(5 * time.Minute), "Disk Temps 5m"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ageTitle(r, "Disk Temps:", tt.at, tt.now); got != tt.want {
+				t.Errorf("ageTitle() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
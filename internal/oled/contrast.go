@@ -0,0 +1,119 @@
+package oled
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kolobock/rockpi-quad-go/internal/logger"
+)
+
+const (
+	// contrastInterval is how often the schedule and light sensor are checked
+	contrastInterval = 5 * time.Second
+	// fullLightLux is the illuminance at which the scheduled contrast is used unscaled
+	fullLightLux = 300.0
+)
+
+// LightSensor reports ambient illuminance
+type LightSensor interface {
+	Lux() (float64, error)
+	Close() error
+}
+
+// contrastEntry sets the panel contrast from a given minute of the day
+type contrastEntry struct {
+	minute   int
+	contrast int
+}
+
+// parseContrastSchedule parses entries like "22:00=16" sorted by time of day
+func parseContrastSchedule(entries []string) ([]contrastEntry, error) {
+	schedule := make([]contrastEntry, 0, len(entries))
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		at, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid contrast entry %q: expected HH:MM=contrast", entry)
+		}
+		contrast, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || contrast < 0 || contrast > 255 {
+			return nil, fmt.Errorf("invalid contrast entry %q: contrast must be 0-255", entry)
+		}
+
+		hh, mm, ok := strings.Cut(strings.TrimSpace(at), ":")
+		hour, errH := strconv.Atoi(hh)
+		minute, errM := strconv.Atoi(mm)
+		if !ok || errH != nil || errM != nil || hour < 0 || hour > 23 || minute < 0 || minute > 59 {
+			return nil, fmt.Errorf("invalid contrast entry %q: bad time %q", entry, at)
+		}
+
+		schedule = append(schedule, contrastEntry{minute: hour*60 + minute, contrast: contrast})
+	}
+
+	sort.Slice(schedule, func(i, j int) bool { return schedule[i].minute < schedule[j].minute })
+	return schedule, nil
+}
+
+// scheduledContrast returns the contrast active at time t, wrapping around midnight,
+// or fallback without a schedule
+func scheduledContrast(schedule []contrastEntry, t time.Time, fallback int) int {
+	if len(schedule) == 0 {
+		return fallback
+	}
+
+	now := t.Hour()*60 + t.Minute()
+	active := schedule[len(schedule)-1].contrast
+	for _, entry := range schedule {
+		if entry.minute > now {
+			break
+		}
+		active = entry.contrast
+	}
+	return active
+}
+
+// ambientContrast scales contrast down towards minimum as the room gets darker, on a
+// log scale that follows how bright the panel looks; full light keeps contrast
+func ambientContrast(contrast, minimum int, lux float64) int {
+	if contrast <= minimum {
+		return contrast
+	}
+	factor := math.Log10(1+max(lux, 0)) / math.Log10(1+fullLightLux)
+	factor = min(factor, 1)
+	return minimum + int(math.Round(float64(contrast-minimum)*factor))
+}
+
+// updateContrast applies the scheduled contrast at time t, adjusted for ambient light
+// when a sensor is fitted; the panel is only written when the level changes
+func (c *Controller) updateContrast(t time.Time) {
+	contrast := scheduledContrast(c.contrastSchedule, t, c.cfg.OLED.Contrast)
+	if c.light != nil {
+		lux, err := c.light.Lux()
+		if err != nil {
+			logger.Infof("Failed to read light sensor: %v", err)
+		} else {
+			contrast = ambientContrast(contrast, c.cfg.OLED.ContrastMin, lux)
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if contrast == c.contrast {
+		return
+	}
+	if err := c.dev.SetContrast(byte(contrast)); err != nil { // #nosec G115 - contrast is 0-255
+		logger.Errorf("Failed to set display contrast: %v", err)
+		return
+	}
+	logger.Infof("Display contrast: %d -> %d", c.contrast, contrast)
+	c.contrast = contrast
+}
@@ -0,0 +1,96 @@
+package oled
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/kolobock/rockpi-quad-go/internal/config"
+)
+
+type fakeLight struct {
+	lux float64
+	err error
+}
+
+func (l *fakeLight) Lux() (float64, error) { return l.lux, l.err }
+func (l *fakeLight) Close() error          { return nil }
+
+func TestScheduledContrast(t *testing.T) {
+	schedule, err := parseContrastSchedule([]string{"07:00=143", " 22:30=16"})
+	if err != nil {
+		t.Fatalf("parseContrastSchedule() error = %v", err)
+	}
+
+	tests := []struct {
+		at   string
+		want int
+	}{
+		{"06:59", 16},
+		{"07:00", 143},
+		{"22:29", 143},
+		{"23:00", 16},
+	}
+	for _, tt := range tests {
+		at, _ := time.Parse("15:04", tt.at)
+		if got := scheduledContrast(schedule, at, 255); got != tt.want {
+			t.Errorf("scheduledContrast(%s) = %d, want %d", tt.at, got, tt.want)
+		}
+	}
+
+	if got := scheduledContrast(nil, time.Now(), 200); got != 200 {
+		t.Errorf("scheduledContrast() without schedule = %d, want the fallback 200", got)
+	}
+
+	for _, bad := range []string{"07:00", "07:00=256", "25:00=10", "7=10"} {
+		if _, err := parseContrastSchedule([]string{bad}); err == nil {
+			t.Errorf("parseContrastSchedule(%q) succeeded, want an error", bad)
+		}
+	}
+}
+
+func TestAmbientContrast(t *testing.T) {
+	tests := []struct {
+		name string
+		lux  float64
+		want int
+	}{
+		{"dark", 0, 1},
+		{"dim room", 10, 61},
+		{"daylight", 300, 143},
+		{"sunlight", 10000, 143},
+	}
+	for _, tt := range tests {
+		if got := ambientContrast(143, 1, tt.lux); got != tt.want {
+			t.Errorf("%s: ambientContrast(%v lx) = %d, want %d", tt.name, tt.lux, got, tt.want)
+		}
+	}
+
+	if got := ambientContrast(16, 32, 1000); got != 16 {
+		t.Errorf("ambientContrast() below the minimum = %d, want 16", got)
+	}
+}
+
+func TestUpdateContrast(t *testing.T) {
+	dev := &mockSSD1306{}
+	light := &fakeLight{lux: 1000}
+	c := &Controller{
+		cfg:      &config.Config{OLED: config.OLEDConfig{Contrast: 143, ContrastMin: 1}},
+		dev:      dev,
+		light:    light,
+		contrast: -1,
+	}
+
+	c.updateContrast(time.Now())
+	c.updateContrast(time.Now())
+	light.lux = 0
+	c.updateContrast(time.Now())
+	// A failing sensor falls back to the scheduled contrast
+	light.err = errors.New("i2c timeout")
+	c.updateContrast(time.Now())
+
+	if want := []byte{143, 1, 143}; !slices.Equal(dev.contrast, want) {
+		t.Errorf("contrast writes = %v, want %v", dev.contrast, want)
+	}
+}
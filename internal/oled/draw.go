@@ -0,0 +1,49 @@
+package oled
+
+import (
+	"image"
+	"image/color"
+)
+
+var (
+	pixelOff = color.Gray{Y: 0}
+	pixelOn  = color.Gray{Y: 255}
+)
+
+// Bar is a horizontal progress bar drawn in place of text
+type Bar struct {
+	Width, Height int
+	// Fill is the filled fraction, 0 to 1
+	Fill float64
+}
+
+// fillRect sets every pixel of r that lies on the image
+func fillRect(img *image.Gray, r image.Rectangle, c color.Gray) {
+	r = r.Intersect(img.Bounds())
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			img.SetGray(x, y, c)
+		}
+	}
+}
+
+// strokeRect draws the one pixel outline of r
+func strokeRect(img *image.Gray, r image.Rectangle, c color.Gray) {
+	fillRect(img, image.Rect(r.Min.X, r.Min.Y, r.Max.X, r.Min.Y+1), c)
+	fillRect(img, image.Rect(r.Min.X, r.Max.Y-1, r.Max.X, r.Max.Y), c)
+	fillRect(img, image.Rect(r.Min.X, r.Min.Y, r.Min.X+1, r.Max.Y), c)
+	fillRect(img, image.Rect(r.Max.X-1, r.Min.Y, r.Max.X, r.Max.Y), c)
+}
+
+// drawBar draws an outlined bar at x, y filled from the left by b.Fill
+func drawBar(img *image.Gray, x, y int, b Bar) {
+	r := image.Rect(x, y, x+b.Width, y+b.Height)
+	strokeRect(img, r, pixelOn)
+	inner := r.Inset(2)
+	if inner.Empty() {
+		return
+	}
+	fill := min(max(b.Fill, 0), 1)
+	inner.Max.X = inner.Min.X + int(float64(inner.Dx())*fill+0.5)
+	fillRect(img, inner, pixelOn)
+}
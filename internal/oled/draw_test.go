@@ -0,0 +1,43 @@
+package oled
+
+import (
+	"image"
+	"testing"
+)
+
+func TestDrawBar(t *testing.T) {
+	tests := []struct {
+		name string
+		fill float64
+		// wantLit is the number of lit pixels on the middle row: outline plus fill
+		wantLit int
+	}{
+		{"empty", 0, 2},
+		{"half", 0.5, 2 + 8},
+		{"full", 1, 2 + 16},
+		{"over full", 1.5, 2 + 16},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img := image.NewGray(image.Rect(0, 0, 32, 16))
+			drawBar(img, 4, 4, Bar{Width: 20, Height: 7, Fill: tt.fill})
+
+			lit := 0
+			for x := 0; x < 32; x++ {
+				if img.GrayAt(x, 7).Y != 0 {
+					lit++
+				}
+			}
+			if lit != tt.wantLit {
+				t.Errorf("middle row has %d lit pixels, want %d", lit, tt.wantLit)
+			}
+			if img.GrayAt(4, 4).Y == 0 || img.GrayAt(23, 10).Y == 0 {
+				t.Error("outline corners are not drawn")
+			}
+			if img.GrayAt(3, 7).Y != 0 || img.GrayAt(24, 7).Y != 0 {
+				t.Error("pixels outside the bar are lit")
+			}
+		})
+	}
+}
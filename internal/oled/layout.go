@@ -0,0 +1,263 @@
+package oled
+
+import (
+	"math"
+
+	"golang.org/x/image/font"
+)
+
+// defaultFontSize is used by rows that do not set a font size
+const defaultFontSize = 11
+
+// glyphTop is the blank space above the glyphs of a line; rows start this far above
+// the display edge so the first line of text touches the top
+const glyphTop = 2
+
+// maxRowGap is the most space left between two rows, in pixels
+const maxRowGap = 1
+
+// barMargin is the space kept clear around progress bars, in pixels
+const barMargin = 1
+
+// ellipsis replaces the end of text that does not fit its column
+const ellipsis = "…"
+
+// Align positions text within its column
+type Align int
+
+const (
+	AlignLeft Align = iota
+	AlignRight
+	AlignCenter
+)
+
+// Cell is text placed in one column of a row, or a progress bar when Bar is set
+type Cell struct {
+	Text  string
+	Align Align
+	Bar   bool
+	// Fill is the filled fraction of a bar, 0 to 1
+	Fill float64
+}
+
+// Row is one line of text split into equal-width columns
+type Row struct {
+	Cells    []Cell
+	FontSize int
+}
+
+// Line returns a row holding one left-aligned text
+func Line(text string) Row {
+	return Row{Cells: []Cell{{Text: text}}}
+}
+
+// Centered returns a row holding one centered text in the given font size
+func Centered(text string, size int) Row {
+	return Row{Cells: []Cell{{Text: text, Align: AlignCenter}}, FontSize: size}
+}
+
+// Columns returns a row with one left-aligned column per text
+func Columns(texts ...string) Row {
+	cells := make([]Cell, len(texts))
+	for i, text := range texts {
+		cells[i] = Cell{Text: text}
+	}
+	return Row{Cells: cells}
+}
+
+// Progress returns a row with a label, a bar filled by fill (0 to 1) and a value
+// after it. The text takes the width it needs and the bar stretches over the rest, so
+// pad labels to one length to line bars up.
+func Progress(label string, fill float64, value string) Row {
+	return Row{Cells: []Cell{{Text: label}, {Bar: true, Fill: fill}, {Text: value, Align: AlignRight}}}
+}
+
+// Grid lays texts out left to right in rows of cols columns
+func Grid(cols int, texts []string) []Row {
+	var rows []Row
+	for start := 0; start < len(texts); start += cols {
+		row := Columns(texts[start:min(start+cols, len(texts))]...)
+		for len(row.Cells) < cols {
+			row.Cells = append(row.Cells, Cell{})
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func (r Row) fontSize() int {
+	if r.FontSize > 0 {
+		return r.FontSize
+	}
+	return defaultFontSize
+}
+
+// Layout places rows on a display of the given size. Rows are stacked from the top,
+// sharing out spare height up to maxRowGap between them; rows that do not fit are
+// dropped and text wider than its column is cut short with an ellipsis, or marked for
+// scrolling with Scroll set.
+type Layout struct {
+	Width, Height int
+	// Measure returns the width of text in pixels; nil assumes a monospace font
+	Measure func(text string, fontSize int) int
+	// Scroll keeps text wider than its column whole, to be scrolled by the renderer
+	Scroll bool
+}
+
+// Capacity returns how many rows of the given font size fit on the display
+func (l Layout) Capacity(fontSize int) int {
+	if fontSize <= 0 {
+		fontSize = defaultFontSize
+	}
+	return max((l.Height+glyphTop)/fontSize, 1)
+}
+
+// Place converts rows into positioned text items
+func (l Layout) Place(rows []Row) []TextItem {
+	rows = l.fit(rows)
+	if len(rows) == 0 {
+		return nil
+	}
+
+	used := 0
+	for _, row := range rows {
+		used += row.fontSize()
+	}
+	gap := 0.0
+	if len(rows) > 1 {
+		gap = min(float64(l.Height+glyphTop-used)/float64(len(rows)-1), maxRowGap)
+	}
+
+	var items []TextItem
+	y := float64(-glyphTop)
+	for _, row := range rows {
+		items = append(items, l.placeRow(row, int(math.Round(y)))...)
+		y += float64(row.fontSize()) + gap
+	}
+	return items
+}
+
+// fit keeps the leading rows whose font sizes add up to at most the display height
+func (l Layout) fit(rows []Row) []Row {
+	used := 0
+	for i, row := range rows {
+		used += row.fontSize()
+		if used > l.Height+glyphTop {
+			return rows[:max(i, 1)]
+		}
+	}
+	return rows
+}
+
+func (l Layout) placeRow(row Row, y int) []TextItem {
+	if len(row.Cells) == 0 {
+		return nil
+	}
+
+	size := row.fontSize()
+	widths := l.columnWidths(row)
+	items := make([]TextItem, 0, len(row.Cells))
+	x := 0
+	for i, cell := range row.Cells {
+		colWidth := widths[i]
+		colX := x
+		x += colWidth
+		if cell.Bar {
+			// Keep a pixel clear around the bar and inside the text line
+			bar := &Bar{Width: colWidth - 2*barMargin, Height: size - glyphTop - 2*barMargin, Fill: cell.Fill}
+			items = append(items, TextItem{X: colX + barMargin, Y: y + glyphTop + barMargin, FontSize: size, Bar: bar})
+			continue
+		}
+		if cell.Text == "" {
+			continue
+		}
+		if l.Scroll && l.measure(cell.Text, size) > colWidth {
+			items = append(items, TextItem{X: colX, Y: y, Text: cell.Text, FontSize: size, ScrollWidth: colWidth})
+			continue
+		}
+		text := l.truncate(cell.Text, size, colWidth)
+		switch cell.Align {
+		case AlignRight:
+			colX += colWidth - l.measure(text, size)
+		case AlignCenter:
+			colX += (colWidth - l.measure(text, size)) / 2
+		}
+		items = append(items, TextItem{X: colX, Y: y, Text: text, FontSize: size})
+	}
+	return items
+}
+
+// columnWidths splits the display width between the cells of a row: equally, or
+// when the row holds bars, text cells take their text's width and bars share the rest
+func (l Layout) columnWidths(row Row) []int {
+	widths := make([]int, len(row.Cells))
+	bars, used := 0, 0
+	for i, cell := range row.Cells {
+		if cell.Bar {
+			bars++
+			continue
+		}
+		widths[i] = l.measure(cell.Text, row.fontSize())
+		used += widths[i]
+	}
+	if bars == 0 {
+		for i := range widths {
+			widths[i] = l.Width / len(row.Cells)
+		}
+		return widths
+	}
+
+	barWidth := max(l.Width-used, 0) / bars
+	for i, cell := range row.Cells {
+		if cell.Bar {
+			widths[i] = barWidth
+		}
+	}
+	return widths
+}
+
+// truncate shortens text with an ellipsis until it fits width
+func (l Layout) truncate(text string, size, width int) string {
+	if l.measure(text, size) <= width {
+		return text
+	}
+	runes := []rune(text)
+	for n := len(runes) - 1; n > 0; n-- {
+		if short := string(runes[:n]) + ellipsis; l.measure(short, size) <= width {
+			return short
+		}
+	}
+	return ellipsis
+}
+
+func (l Layout) measure(text string, size int) int {
+	if l.Measure != nil {
+		return l.Measure(text, size)
+	}
+	return len([]rune(text)) * monospaceAdvance(size)
+}
+
+// monospaceAdvance approximates the glyph width of DejaVu Sans Mono (0.6 em)
+func monospaceAdvance(size int) int {
+	return int(math.Round(float64(size) * 0.6))
+}
+
+// layout returns the layout for the display, measuring text with the loaded fonts
+func (c *Controller) layout() Layout {
+	l := Layout{Width: displayWidth, Height: displayHeight, Scroll: c.cfg.OLED.Scroll}
+	if c.img != nil {
+		l.Width, l.Height = c.img.Bounds().Dx(), c.img.Bounds().Dy()
+	}
+	if len(c.fonts) > 0 {
+		l.Measure = func(text string, size int) int {
+			c.fontMu.Lock()
+			defer c.fontMu.Unlock()
+			face, ok := c.fonts[size]
+			if !ok {
+				face = c.fonts[defaultFontSize]
+			}
+			return font.MeasureString(face, text).Ceil()
+		}
+	}
+	return l
+}
@@ -0,0 +1,81 @@
+package oled
+
+import (
+	"testing"
+)
+
+func TestLayoutPlace(t *testing.T) {
+	texts := []string{"sda: 12%", "sdb: 40%", "sdc: 7%", "sdd: 95%", "nvme0n1: 3%", "sde: 1%"}
+
+	tests := []struct {
+		name      string
+		height    int
+		rows      []Row
+		wantY     []int
+		wantItems int
+	}{
+		{"three lines on 32px", 32, []Row{Line("a"), Line("b"), Line("c")}, []int{-2, 10, 21}, 3},
+		{"extra rows dropped on 32px", 32, append([]Row{Line("Disks:")}, Grid(2, texts)...), []int{-2, 10, 10, 21, 21}, 5},
+		{"more rows on 64px", 64, append([]Row{Line("Disks:")}, Grid(2, texts)...), []int{-2, 10, 10, 22, 22, 34, 34}, 7},
+		{"mixed font sizes", 32, []Row{{Cells: []Cell{{Text: "12:00"}}, FontSize: 14}, {Cells: []Cell{{Text: "Mon"}}, FontSize: 10}}, []int{-2, 13}, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items := Layout{Width: 128, Height: tt.height}.Place(tt.rows)
+			if len(items) != tt.wantItems {
+				t.Fatalf("Place() returned %d items, want %d: %+v", len(items), tt.wantItems, items)
+			}
+			for i, item := range items {
+				if item.Y != tt.wantY[i] {
+					t.Errorf("item %d (%q) Y = %d, want %d", i, item.Text, item.Y, tt.wantY[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLayoutColumns(t *testing.T) {
+	l := Layout{Width: 128, Height: 32}
+
+	items := l.Place([]Row{{Cells: []Cell{{Text: "Usage:"}, {Text: "42%", Align: AlignRight}}}})
+	if len(items) != 2 || items[0].X != 0 || items[1].X != 128-3*7 {
+		t.Errorf("columns = %+v, want left at 0 and right-aligned at %d", items, 128-3*7)
+	}
+
+	items = l.Place([]Row{{Cells: []Cell{{Text: "OK", Align: AlignCenter}}}})
+	if items[0].X != (128-2*7)/2 {
+		t.Errorf("centered X = %d, want %d", items[0].X, (128-2*7)/2)
+	}
+}
+
+func TestLayoutTruncate(t *testing.T) {
+	l := Layout{Width: 128, Height: 32}
+
+	items := l.Place([]Row{Columns("nvme0n1: 100%", "sda: 1%")})
+	if items[0].Text != "nvme0n1:…" {
+		t.Errorf("truncated text = %q, want %q", items[0].Text, "nvme0n1:…")
+	}
+	if items[1].Text != "sda: 1%" || items[1].X != 64 {
+		t.Errorf("second column = %+v, want untouched at X 64", items[1])
+	}
+}
+
+func TestLayoutProgress(t *testing.T) {
+	items := Layout{Width: 128, Height: 64}.Place([]Row{Progress("sda ", 0.45, " 45%")})
+	if len(items) != 3 {
+		t.Fatalf("Place() returned %d items, want 3: %+v", len(items), items)
+	}
+
+	label, bar, value := items[0], items[1], items[2]
+	if label.Text != "sda " || label.X != 0 {
+		t.Errorf("label = %+v, want sda at the left edge", label)
+	}
+	if value.Text != " 45%" || value.X != 100 {
+		t.Errorf("value = %+v, want 45%% right-aligned at x 100", value)
+	}
+	want := Bar{Width: 70, Height: 7, Fill: 0.45}
+	if bar.Bar == nil || *bar.Bar != want || bar.X != 29 || bar.Y != 1 {
+		t.Errorf("bar = %+v (%+v), want %+v at 29,1 between the texts", bar, bar.Bar, want)
+	}
+}
@@ -0,0 +1,113 @@
+package oled
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/golang/freetype/truetype"
+
+	"github.com/kolobock/rockpi-quad-go/internal/logger"
+)
+
+// locales are the bundled translations of the page labels by [oled] lang, keyed by
+// the English label. A label a language does not list is shown in English.
+var locales = map[string]map[string]string{
+	"de": {
+		"Up:":         "Lauf:",
+		"Uptime:":     "Laufzeit:",
+		"CPU Load:":   "CPU-Last:",
+		"Mem:":        "RAM:",
+		"N/A":         "k.A.",
+		"Fan:":        "Lüfter:",
+		"off":         "aus",
+		"Usage:":      "Belegt:",
+		"Disk Temps:": "Disk-Temp.:",
+		"Good Bye ~":  "Tschüss ~",
+	},
+	"fr": {
+		"Up:":         "Actif:",
+		"Uptime:":     "Durée:",
+		"Host:":       "Hôte:",
+		"CPU Load:":   "Charge:",
+		"Mem:":        "Mém:",
+		"N/A":         "N/D",
+		"Fan:":        "Vent.:",
+		"off":         "arrêt",
+		"Usage:":      "Espace:",
+		"Disk Temps:": "Temp. disques:",
+		"Good Bye ~":  "Au revoir ~",
+	},
+	"ru": {
+		"Up:":         "Раб.:",
+		"Uptime:":     "Аптайм:",
+		"Host:":       "Хост:",
+		"CPU:":        "ЦП:",
+		"CPU Load:":   "Загр. ЦП:",
+		"Mem:":        "ОЗУ:",
+		"N/A":         "н/д",
+		"Fan:":        "Вент.:",
+		"off":         "выкл",
+		"Usage:":      "Занято:",
+		"Disk Temps:": "Темп. дисков:",
+		"Good Bye ~":  "До свидания ~",
+	},
+	"zh": {
+		"Up:":         "运行:",
+		"Uptime:":     "运行时间:",
+		"Host:":       "主机:",
+		"CPU Load:":   "CPU负载:",
+		"Mem:":        "内存:",
+		"N/A":         "无",
+		"Fan:":        "风扇:",
+		"off":         "关",
+		"Usage:":      "用量:",
+		"Disk Temps:": "硬盘温度:",
+		"Good Bye ~":  "再见 ~",
+	},
+}
+
+// localeLabels returns the labels of lang, e.g. "de" or "de_DE.UTF-8", or nil for
+// English. A language that is not bundled, or that needs glyphs f does not have, is
+// logged and shown in English rather than as empty boxes.
+func localeLabels(lang string, f *truetype.Font) map[string]string {
+	base, _, _ := strings.Cut(strings.ToLower(lang), "_")
+	base, _, _ = strings.Cut(base, "-")
+	if base == "" || base == "en" {
+		return nil
+	}
+	labels, ok := locales[base]
+	if !ok {
+		logger.Errorf("Unknown [oled] lang %q, showing English labels", lang)
+		return nil
+	}
+	if missing := missingGlyphs(f, labels); missing != "" {
+		logger.Errorf("The OLED font has no glyphs for %q used by [oled] lang %s, showing English labels; "+
+			"set [oled] font to a font that has them", missing, base)
+		return nil
+	}
+	return labels
+}
+
+// missingGlyphs returns the characters of the labels f has no glyph for, sorted
+func missingGlyphs(f *truetype.Font, labels map[string]string) string {
+	var missing []rune
+	for _, label := range labels {
+		for _, r := range label {
+			if !unicode.IsSpace(r) && f.Index(r) == 0 && !slices.Contains(missing, r) {
+				missing = append(missing, r)
+			}
+		}
+	}
+	slices.Sort(missing)
+	return string(missing)
+}
+
+// tr translates a page label to the [oled] lang; labels without a translation, such
+// as a custom goodbye, are returned as they are
+func (c *Controller) tr(label string) string {
+	if t, ok := c.labels[label]; ok {
+		return t
+	}
+	return label
+}
@@ -0,0 +1,62 @@
+package oled
+
+import "testing"
+
+func TestLocaleLabels(t *testing.T) {
+	embedded, err := parseFont("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		lang string
+		want string
+	}{
+		{"en", "Fan:"},
+		{"", "Fan:"},
+		{"de", "Lüfter:"},
+		{"de_DE.UTF-8", "Lüfter:"},
+		{"FR", "Vent.:"},
+		{"ru-RU", "Вент.:"},
+		// The embedded font has no CJK glyphs, so zh needs [oled] font
+		{"zh", "Fan:"},
+		{"xx", "Fan:"},
+	}
+	for _, tt := range tests {
+		c := &Controller{labels: localeLabels(tt.lang, embedded)}
+		if got := c.tr("Fan:"); got != tt.want {
+			t.Errorf("lang %q: tr(Fan:) = %q, want %q", tt.lang, got, tt.want)
+		}
+	}
+}
+
+func TestEmbeddedFontCoversLocales(t *testing.T) {
+	embedded, err := parseFont("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for lang, labels := range locales {
+		missing := missingGlyphs(embedded, labels)
+		if lang == "zh" {
+			if missing == "" {
+				t.Error("the embedded font covers zh, which no longer needs [oled] font")
+			}
+			continue
+		}
+		if missing != "" {
+			t.Errorf("the embedded font has no glyphs for %q used by %s", missing, lang)
+		}
+	}
+}
+
+func TestTranslatedPage(t *testing.T) {
+	c := newTestController()
+	c.labels = locales["de"]
+	items := (&SystemInfoPage1{ctrl: c}).GetPageText()
+	if len(items) == 0 || items[0].Text != "Lüfter: aus" {
+		t.Errorf("first line = %+v, want Lüfter: aus", items)
+	}
+	if got := c.tr("Good Bye|see you"); got != "Good Bye|see you" {
+		t.Errorf("tr() translated a custom message to %q", got)
+	}
+}
@@ -2,10 +2,13 @@ package oled
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"image"
 	"image/color"
+	"image/draw"
 	"os"
+	"strings"
 	"sync"
 	"time"
 
@@ -13,8 +16,18 @@ import (
 	"golang.org/x/image/font"
 	"golang.org/x/image/math/fixed"
 
+	"github.com/kolobock/rockpi-quad-go/fonts"
+	"github.com/kolobock/rockpi-quad-go/internal/clock"
 	"github.com/kolobock/rockpi-quad-go/internal/config"
+	"github.com/kolobock/rockpi-quad-go/internal/diag"
+	"github.com/kolobock/rockpi-quad-go/internal/flags"
+	"github.com/kolobock/rockpi-quad-go/internal/kmsg"
 	"github.com/kolobock/rockpi-quad-go/internal/logger"
+	"github.com/kolobock/rockpi-quad-go/internal/network"
+	"github.com/kolobock/rockpi-quad-go/internal/raid"
+	"github.com/kolobock/rockpi-quad-go/internal/rails"
+	"github.com/kolobock/rockpi-quad-go/internal/store"
+	"github.com/kolobock/rockpi-quad-go/internal/ups"
 )
 
 const (
@@ -22,15 +35,27 @@ const (
 	displayHeight = 32
 )
 
+const emergencyFlashInterval = 500 * time.Millisecond
+
 // FanController interface for getting fan speeds
 type FanController interface {
 	GetFanSpeeds() (cpuPercent, diskPercent float64)
+	EmergencyActive() bool
+}
+
+// Flags reports runtime-settable preferences such as auto-slide and Fahrenheit
+type Flags interface {
+	Enabled(name string) bool
 }
 
 // Display interface for OLED display devices
 type Display interface {
 	Display(img *image.Gray) error
 	Clear() error
+	SetDisplayOn(on bool) error
+	SetContrast(contrast byte) error
+	// Release closes the device but leaves the panel showing its last image
+	Release() error
 	Close() error
 }
 
@@ -44,10 +69,81 @@ type Controller struct {
 	netStats  map[string]netIOStats
 	diskStats map[string]diskIOStats
 	fonts     map[int]font.Face
+	// labels translates page labels to the [oled] lang, nil for English
+	labels    map[string]string
 	fanCtrl   FanController
+	kernelLog *kmsg.Watcher
+	checker   *network.Checker
+	raid      *raid.Monitor
+	ups       *ups.Monitor
+	rails     *rails.Monitor
+	store     *store.Store
+	flags     Flags
+	clock     *clock.Clock
+
+	// rotation is the clockwise turn in degrees done in software on the way to the
+	// panel, through frame
+	rotation int
+	frame    *image.Gray
+	// back is the image the next page is composed into without holding mu; only
+	// the Run goroutine uses it
+	back *image.Gray
+	// pageSeq counts page switches and messages, so a page composed while a newer
+	// one replaced it is dropped
+	pageSeq int
+	// woke asks Run to collect the page on screen again after the panel woke up
+	woke chan struct{}
+
+	// items are the texts on screen, kept to redraw scrolling text without
+	// regenerating the page; scrollStart is when they appeared and scrollShown the
+	// scroll positions on screen
+	items       []TextItem
+	scrolling   bool
+	scrollStart time.Time
+	scrollShown []int
+	profile     *pageProfile
+	// ipIndex picks the address the first page shows next, hostIndex its first line on
+	// short panels and driveIndex the disk of the inventory page; they move on when the
+	// page is left, guarded by dataMu
+	ipIndex    int
+	hostIndex  int
+	driveIndex int
+	prefetched *prefetch
+	// usage and diskTemps are df and smartctl output read in the background
+	usage     *reading[[]diskUsage]
+	diskTemps *reading[map[string]float64]
+	// dataMu serializes GetPageText between renders and the prefetch
+	dataMu sync.Mutex
+	// fontMu guards the font faces, which keep glyph caches, while the prefetch
+	// measures text
+	fontMu sync.Mutex
+
+	// message replaces the current page until the next page switch
+	message    []TextItem
+	toast      string
+	toastSeq   int
+	toastTimer *time.Timer
+	// finalMessage stays on the panel after Close when set
+	finalMessage string
+
+	// welcomeUntil is when the welcome screen may give way to the first page
+	welcomeUntil time.Time
+
+	// lastActive is the last button press or notification, for [oled] sleep_after
+	lastActive time.Time
+	asleep     bool
+
+	contrastSchedule []contrastEntry
+	light            LightSensor
+	// contrast is the level last written to the panel, -1 before the first update
+	contrast int
 
 	timer         *time.Ticker
 	timerDuration time.Duration
+
+	// lost is closed when the device gave up after repeated write failures
+	lost     chan struct{}
+	lostOnce sync.Once
 }
 
 type netIOStats struct {
@@ -62,50 +158,58 @@ type diskIOStats struct {
 	timestamp  time.Time
 }
 
-func loadFont(path string, size float64) (font.Face, error) {
-	fontBytes, err := os.ReadFile(path)
-	if err != nil {
-		return nil, err
-	}
-
-	f, err := truetype.Parse(fontBytes)
-	if err != nil {
-		return nil, err
-	}
-
+func loadFont(f *truetype.Font, size float64) font.Face {
 	return truetype.NewFace(f, &truetype.Options{
 		Size:    size,
 		DPI:     72,
 		Hinting: font.HintingFull,
-	}), nil
+	})
 }
 
 func New(cfg *config.Config, fanCtrl FanController) (*Controller, error) {
-	display, err := NewSSD1306(displayWidth, displayHeight)
+	contrastSchedule, err := parseContrastSchedule(cfg.OLED.ContrastSchedule)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse contrast schedule: %w", err)
+	}
+
+	height := panelHeight(cfg)
+	display, err := NewSSD1306(cfg.Env.I2CBus, displayWidth, height, resetPin(cfg))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create SSD1306 display: %w", err)
 	}
 
-	fonts := make(map[int]font.Face)
-	for _, size := range []int{10, 11, 12, 14} {
-		fontFace, err := loadFont("fonts/DejaVuSansMono-Bold.ttf", float64(size))
-		if err != nil {
-			return nil, fmt.Errorf("failed to load font size %d: %w", size, err)
-		}
-		fonts[size] = fontFace
+	ttf, err := parseFont(cfg.OLED.Font)
+	if err != nil {
+		return nil, err
 	}
 
 	c := &Controller{
 		cfg:           cfg,
 		dev:           display,
-		img:           image.NewGray(image.Rect(0, 0, displayWidth, displayHeight)),
+		woke:          make(chan struct{}, 1),
 		netStats:      make(map[string]netIOStats),
 		diskStats:     make(map[string]diskIOStats),
-		fonts:         fonts,
+		fonts:         fontFaces(ttf),
+		labels:        localeLabels(cfg.OLED.Lang, ttf),
 		fanCtrl:       fanCtrl,
+		clock:         clock.New(cfg.Time.Timezone),
 		timerDuration: time.Duration(cfg.Slider.Time) * time.Second,
+
+		contrastSchedule: contrastSchedule,
+		contrast:         -1,
+		lost:             make(chan struct{}),
+	}
+	c.setRotation(displayWidth, height, cfg.OLED.Rotate)
+	if cfg.OLED.LightSensor == "bh1750" {
+		sensor, err := NewBH1750(cfg.Env.I2CBus, cfg.OLED.LightAddress)
+		if err != nil {
+			logger.Errorf("Light sensor unavailable, using the contrast schedule only: %v", err)
+		} else {
+			c.light = sensor
+		}
 	}
 
+	c.initReadings()
 	c.updateNetworkStats()
 	c.updateDiskStats()
 	c.showWelcome()
@@ -113,64 +217,274 @@ func New(cfg *config.Config, fanCtrl FanController) (*Controller, error) {
 	return c, nil
 }
 
+// panelHeight returns the configured panel height, 32 or 64 pixels
+func panelHeight(cfg *config.Config) int {
+	if cfg.OLED.Height > 0 {
+		return cfg.OLED.Height
+	}
+	return displayHeight
+}
+
+// resetPin returns the panel reset GPIO from OLED_RESET on the board's OLED_RESET_CHIP
+func resetPin(cfg *config.Config) ResetPin {
+	return ResetPin{Chip: cfg.Env.OLEDResetChip, Line: cfg.Env.OLEDReset}
+}
+
+// parseFont parses the TrueType font at path, or the embedded font when path is empty
+func parseFont(path string) (*truetype.Font, error) {
+	var fontBytes []byte
+	var err error
+	if path == "" {
+		fontBytes, err = fonts.DejaVuSansMonoBold()
+	} else {
+		fontBytes, err = os.ReadFile(path) // #nosec G304 - path comes from the config
+	}
+	if err != nil {
+		return nil, err
+	}
+	f, err := truetype.Parse(fontBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse font %s: %w", valueOr(path, "DejaVuSansMono-Bold.ttf"), err)
+	}
+	return f, nil
+}
+
+// fontFaces returns f in every size pages use
+func fontFaces(f *truetype.Font) map[int]font.Face {
+	faces := make(map[int]font.Face)
+	for _, size := range []int{10, 11, 12, 14} {
+		faces[size] = loadFont(f, float64(size))
+	}
+	return faces
+}
+
+// loadFonts loads the TrueType font at path, or the embedded one when path is
+// empty, in every size pages use
+func loadFonts(path string) (map[int]font.Face, error) {
+	f, err := parseFont(path)
+	if err != nil {
+		return nil, err
+	}
+	return fontFaces(f), nil
+}
+
 func (c *Controller) Run(ctx context.Context, buttonChan <-chan struct{}) error {
 	c.pages = c.generatePages()
+	c.profile = newPageProfile(c.pages, time.Duration(c.cfg.OLED.RenderBudget)*time.Millisecond)
 	if len(c.pages) == 0 {
 		logger.Infoln("No OLED pages configured, display disabled")
 		<-ctx.Done()
 		return nil
 	}
+	c.startReadings(ctx)
+	c.waitWelcome(ctx)
 
+	c.updateContrast(c.clock.Now())
 	c.nextPage()
+	c.mu.Lock()
+	c.lastActive = time.Now()
+	c.mu.Unlock()
+
+	contrastTicker := time.NewTicker(contrastInterval)
+	defer contrastTicker.Stop()
 
 	ticker := time.NewTicker(c.timerDuration)
 	defer ticker.Stop()
 
 	c.timer = ticker
 
+	flash := time.NewTicker(emergencyFlashInterval)
+	defer flash.Stop()
+	var flashing, flashOn bool
+
+	scroll := time.NewTicker(scrollInterval)
+	defer scroll.Stop()
+
 	for {
 		select {
 		case <-ctx.Done():
 			c.showGoodbye()
+			c.showFinalMessage()
 			return nil
+		case <-c.lost:
+			return ErrDisplayUnavailable
+		case now := <-flash.C:
+			emergency := c.fanCtrl != nil && c.fanCtrl.EmergencyActive()
+			switch {
+			case emergency:
+				flashOn = !flashOn
+				c.showEmergency(flashOn)
+			case flashing:
+				c.nextPage()
+			default:
+				c.sleepIfIdle(now)
+			}
+			flashing = emergency
+		case now := <-scroll.C:
+			if !flashing {
+				c.scrollFrame(now)
+			}
+		case <-contrastTicker.C:
+			c.updateContrast(c.clock.Now())
 		case <-ticker.C:
-			if c.cfg.Slider.Auto {
+			if c.flag(flags.AutoSlide, c.cfg.Slider.Auto) && !flashing {
 				c.nextPage()
 			}
 		case <-buttonChan:
-			c.nextPage()
+			if !flashing {
+				c.nextPage()
+			}
+		case <-c.woke:
+			if !flashing {
+				c.showPage(false)
+			}
 		}
 	}
 }
 
+// showEmergency draws the overheat warning, blanking it on alternate calls to flash
+func (c *Controller) showEmergency(visible bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.wakeLocked()
+	c.clearImage()
+	if visible {
+		c.drawText(0, 0, "!! OVERHEAT !!", 14)
+		c.drawText(0, 18, "Fans forced 100%", 12)
+	}
+	if err := c.display(); err != nil {
+		logger.Errorf("Failed to display emergency warning: %v", err)
+	}
+}
+
 func (c *Controller) Close() error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	if c.light != nil {
+		if err := c.light.Close(); err != nil {
+			logger.Errorf("Failed to close light sensor: %v", err)
+		}
+	}
+
+	if c.finalMessage != "" {
+		return c.dev.Release()
+	}
 	c.clearImage()
 	if err := c.displayToDevice(); err != nil {
 		logger.Errorf("Failed to clear display: %v", err)
 	}
-
 	return c.dev.Close()
 }
 
-func (c *Controller) NotifyBtnPress() {
+// SetKernelWatcher enables the kernel events page, must be called before Run
+func (c *Controller) SetKernelWatcher(w *kmsg.Watcher) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	c.kernelLog = w
+}
 
-	c.timer.Reset(c.timerDuration)
+// SetHealthChecker enables the connectivity page, must be called before Run
+func (c *Controller) SetHealthChecker(checker *network.Checker) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checker = checker
 }
 
-func (c *Controller) clearImage() {
-	for y := 0; y < displayHeight; y++ {
-		for x := 0; x < displayWidth; x++ {
-			c.img.SetGray(x, y, color.Gray{Y: 0})
-		}
+// SetRAIDMonitor enables the RAID page, must be called before Run
+func (c *Controller) SetRAIDMonitor(m *raid.Monitor) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.raid = m
+}
+
+// SetUPSMonitor enables the UPS page, must be called before Run
+func (c *Controller) SetUPSMonitor(m *ups.Monitor) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ups = m
+}
+
+// SetRailMonitor enables the Power page, must be called before Run
+func (c *Controller) SetRailMonitor(m *rails.Monitor) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rails = m
+}
+
+// SetFlags makes auto-slide and the temperature unit follow runtime flags instead of the config
+func (c *Controller) SetFlags(f Flags) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.flags = f
+}
+
+// flag returns a runtime flag, or fallback from the config when no flags are attached
+func (c *Controller) flag(name string, fallback bool) bool {
+	if c.flags == nil {
+		return fallback
+	}
+	return c.flags.Enabled(name)
+}
+
+// ShowMessage replaces the current page with a message until the next page switch
+func (c *Controller) ShowMessage(title, text string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.wakeLocked()
+	c.message = []TextItem{
+		{X: 0, Y: 0, Text: title, FontSize: 12},
+		{X: 0, Y: 16, Text: text, FontSize: 12},
+	}
+	c.pageSeq++
+	c.render()
+	if err := c.display(); err != nil {
+		logger.Errorf("Failed to display message: %v", err)
+	}
+	if c.timer != nil {
+		c.timer.Reset(c.timerDuration)
+	}
+}
+
+// NotifyBtnPress restarts the slide and sleep timers on a button press. It returns
+// true when the press woke a sleeping display, in which case it should not act.
+func (c *Controller) NotifyBtnPress() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.timer != nil {
+		c.timer.Reset(c.timerDuration)
+	}
+	if !c.wakeLocked() {
+		return false
+	}
+	// Show the page as it was at once, then let Run bring its text up to date
+	c.render()
+	if err := c.display(); err != nil {
+		logger.Errorf("Failed to display page: %v", err)
 	}
+	select {
+	case c.woke <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+func (c *Controller) clearImage() {
+	fillRect(c.img, c.img.Bounds(), pixelOff)
 }
 
 func (c *Controller) drawText(x, y int, text string, fontSize int) {
+	c.drawTextOn(c.img, x, y, text, fontSize)
+}
+
+// drawTextOn draws text into dst, which may be a sub-image of the display image that
+// clips it
+func (c *Controller) drawTextOn(dst draw.Image, x, y int, text string, fontSize int) {
+	c.fontMu.Lock()
+	defer c.fontMu.Unlock()
 	fontFace, ok := c.fonts[fontSize]
 	if !ok {
 		fontFace = c.fonts[11]
@@ -185,7 +499,7 @@ func (c *Controller) drawText(x, y int, text string, fontSize int) {
 	}
 
 	d := &font.Drawer{
-		Dst:  c.img,
+		Dst:  dst,
 		Src:  image.NewUniform(color.White),
 		Face: fontFace,
 		Dot:  point,
@@ -194,77 +508,281 @@ func (c *Controller) drawText(x, y int, text string, fontSize int) {
 }
 
 func (c *Controller) display() error {
-	if c.cfg.OLED.Rotate {
-		rotated := c.rotateImage180(c.img)
-		return c.dev.Display(rotated)
+	if c.rotation != 0 {
+		rotateInto(c.frame, c.img, c.rotation)
+		return c.checkDevice(c.dev.Display(c.frame))
 	}
 	return c.displayToDevice()
 }
 
 func (c *Controller) displayToDevice() error {
-	return c.dev.Display(c.img)
+	return c.checkDevice(c.dev.Display(c.img))
 }
 
-func (c *Controller) rotateImage180(src *image.Gray) *image.Gray {
-	bounds := src.Bounds()
-	dst := image.NewGray(bounds)
-	w, h := bounds.Dx(), bounds.Dy()
-	for y := 0; y < h; y++ {
-		for x := 0; x < w; x++ {
-			dst.Set(w-1-x, h-1-y, src.At(x, y))
-		}
+// checkDevice stops Run once the device reports it is unavailable
+func (c *Controller) checkDevice(err error) error {
+	if errors.Is(err, ErrDisplayUnavailable) && c.lost != nil {
+		c.lostOnce.Do(func() {
+			logger.Errorf("OLED unavailable: %v", err)
+			close(c.lost)
+		})
 	}
-	return dst
+	return err
 }
 
+// BusStats returns the I2C write counters of the panel, zero for other devices
+func (c *Controller) BusStats() BusStats {
+	if bus, ok := c.dev.(interface{ Stats() BusStats }); ok {
+		return bus.Stats()
+	}
+	return BusStats{Available: true}
+}
+
+// showWelcome puts the [oled] welcome screen up; Run leaves it there for the rest of
+// welcome_time before the first page, so creating the display does not wait for it
 func (c *Controller) showWelcome() {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	c.clearImage()
-	c.drawText(0, 0, "ROCKPi QUAD HAT", 14)
-	c.drawText(32, 16, "Loading...", 12)
+	if c.cfg.OLED.Welcome == "" || c.cfg.OLED.WelcomeTime <= 0 {
+		return
+	}
+	c.showLines(c.cfg.OLED.Welcome)
 	if err := c.display(); err != nil {
 		logger.Errorf("Failed to display welcome: %v", err)
 	}
-	time.Sleep(2 * time.Second)
+	c.welcomeUntil = time.Now().Add(time.Duration(c.cfg.OLED.WelcomeTime) * time.Second)
 }
 
-func (c *Controller) showGoodbye() {
+// waitWelcome keeps the welcome screen up until its time is over or ctx ends
+func (c *Controller) waitWelcome(ctx context.Context) {
 	c.mu.Lock()
-	defer c.mu.Unlock()
+	wait := time.Until(c.welcomeUntil)
+	c.mu.Unlock()
+	if wait <= 0 {
+		return
+	}
 
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+}
+
+// showLines draws text centered in the large font, "|" separating lines
+func (c *Controller) showLines(text string) {
 	c.clearImage()
-	c.drawText(32, 8, "Good Bye ~", 14)
+	var rows []Row
+	for _, line := range strings.Split(text, "|") {
+		rows = append(rows, Centered(strings.TrimSpace(line), 14))
+	}
+	c.drawRows(rows)
+}
+
+// SetFinalMessage makes the display end on text instead of blanking when it stops,
+// e.g. "Safe to unplug" at poweroff; "|" separates lines
+func (c *Controller) SetFinalMessage(text string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.finalMessage = text
+}
+
+// showFinalMessage draws the final message, which Close leaves on the panel
+func (c *Controller) showFinalMessage() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.finalMessage == "" {
+		return
+	}
+	c.wakeLocked()
+	c.showLines(c.finalMessage)
 	if err := c.display(); err != nil {
+		logger.Errorf("Failed to display final message: %v", err)
+	}
+}
+
+// showGoodbye shows the [oled] goodbye screen for goodbye_time, then blanks the
+// panel. The lock is released while it waits, and a panel that dropped off the bus
+// ends the wait.
+func (c *Controller) showGoodbye() {
+	c.mu.Lock()
+	if c.cfg.OLED.Goodbye == "" || c.cfg.OLED.GoodbyeTime <= 0 {
+		c.mu.Unlock()
+		return
+	}
+	c.showLines(c.tr(c.cfg.OLED.Goodbye))
+	err := c.display()
+	c.mu.Unlock()
+	if err != nil {
 		logger.Errorf("Failed to display goodbye: %v", err)
+		return
+	}
+
+	timer := time.NewTimer(time.Duration(c.cfg.OLED.GoodbyeTime) * time.Second)
+	defer timer.Stop()
+	select {
+	case <-c.lost:
+		return
+	case <-timer.C:
 	}
-	time.Sleep(2 * time.Second)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	c.clearImage()
 	if err := c.display(); err != nil {
 		logger.Errorf("Failed to clear display: %v", err)
 	}
 }
 
+// CurrentPage returns the position and kind of the page on screen, e.g. 2 and "DiskUsage"
+func (c *Controller) CurrentPage() (int, string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.pages) == 0 {
+		return 0, ""
+	}
+	return c.pageIndex, pageName(c.pages[c.pageIndex])
+}
+
+// Pages returns the kinds of the pages in display order
+func (c *Controller) Pages() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	names := make([]string, len(c.pages))
+	for i, p := range c.pages {
+		names[i] = pageName(p)
+	}
+	return names
+}
+
 func (c *Controller) nextPage() {
+	c.showPage(true)
+}
+
+// showPage collects and composes the page, the following one when advance is set,
+// without holding mu, so slow pages do not block button presses, notifications or
+// Close; the finished image is swapped in and sent under mu
+func (c *Controller) showPage(advance bool) {
 	if len(c.pages) == 0 {
 		return
 	}
 
 	c.mu.Lock()
-	defer c.mu.Unlock()
+	if c.asleep {
+		c.mu.Unlock()
+		return
+	}
+	var left Page
+	if advance && c.timer != nil {
+		left = c.pages[c.pageIndex]
+		c.pageIndex = c.followingPage()
+	}
+	c.message = nil
+	c.pageSeq++
+	seq, index, toastSeq := c.pageSeq, c.pageIndex, c.toastSeq
+	pf := c.takePrefetch(index)
+	toast := c.toast
+	if c.back == nil {
+		c.back = image.NewGray(c.img.Bounds())
+	}
+	c.mu.Unlock()
 
-	if c.timer != nil {
-		c.pageIndex = (c.pageIndex + 1) % len(c.pages)
+	if r, ok := left.(rotating); ok {
+		c.dataMu.Lock()
+		r.rotate()
+		c.dataMu.Unlock()
 	}
-	page := c.pages[c.pageIndex]
+	renderStart := time.Now()
+	items, took := c.pageText(pf, index)
+	c.paint(c.back, items, 0, toast)
 
-	c.clearImage()
-	items := page.GetPageText()
-	for _, item := range items {
-		c.drawText(item.X, item.Y, item.Text, item.FontSize)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if seq != c.pageSeq || c.asleep {
+		return
+	}
+	if c.profile != nil {
+		c.profile.render(index, took)
 	}
+	c.setItems(items)
+	c.scrollStart = time.Now()
+	c.scrollShown = c.scrollOffsets(c.scrollStart)
+	copy(c.img.Pix, c.back.Pix)
+	if c.toastSeq != toastSeq {
+		// The banner changed while the page was composed
+		c.draw(time.Now())
+	}
+
+	start := time.Now()
 	if err := c.display(); err != nil {
 		logger.Errorf("Failed to display page: %v", err)
 	}
+	c.recordTransmit(time.Since(start))
+	diag.ObserveLoop("oled_page", time.Since(renderStart))
+	c.startPrefetch()
+}
+
+// followingPage returns the index of the next page that is not skipped, or simply
+// the next one when every other page is skipped
+func (c *Controller) followingPage() int {
+	for step := 1; step < len(c.pages); step++ {
+		i := (c.pageIndex + step) % len(c.pages)
+		if p, ok := c.pages[i].(skippable); !ok || !p.Skip() {
+			return i
+		}
+	}
+	return (c.pageIndex + 1) % len(c.pages)
+}
+
+// render draws the message, or else the page on screen, into the image with any
+// notification banner on top; the caller holds mu and sends the image to the display.
+// Fresh page text is only collected by showPage.
+func (c *Controller) render() {
+	if c.message != nil {
+		c.setItems(c.message)
+	}
+	c.draw(time.Now())
+}
+
+// setItems makes items the texts on screen; the caller holds mu
+func (c *Controller) setItems(items []TextItem) {
+	c.items = items
+	c.scrolling = false
+	for _, item := range items {
+		if item.ScrollWidth > 0 {
+			c.scrolling = true
+		}
+	}
+}
+
+// draw paints the current items, scrolled to time now, and the notification banner;
+// the caller holds mu
+func (c *Controller) draw(now time.Time) {
+	c.paint(c.img, c.items, now.Sub(c.scrollStart), c.toast)
+	c.scrollShown = c.scrollOffsets(now)
+}
+
+// paint draws items, scrolled as elapsed after they appeared, and the notification
+// banner toast into dst; it only touches state guarded by its own locks, so pages
+// can be composed without mu
+func (c *Controller) paint(dst *image.Gray, items []TextItem, elapsed time.Duration, toast string) {
+	fillRect(dst, dst.Bounds(), pixelOff)
+	for _, item := range items {
+		switch {
+		case item.Bar != nil:
+			drawBar(dst, item.X, item.Y, *item.Bar)
+		case item.ScrollWidth > 0:
+			c.drawScrolling(dst, item, elapsed)
+		default:
+			c.drawTextOn(dst, item.X, item.Y, item.Text, item.FontSize)
+		}
+	}
+	if toast != "" {
+		c.drawToast(dst, toast)
+	}
 }
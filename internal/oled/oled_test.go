@@ -11,6 +11,7 @@ import (
 	"golang.org/x/image/font"
 	"golang.org/x/image/math/fixed"
 
+	"github.com/kolobock/rockpi-quad-go/internal/clock"
 	"github.com/kolobock/rockpi-quad-go/internal/config"
 )
 
@@ -70,23 +71,6 @@ func TestClearImage(t *testing.T) {
 	}
 }
 
-func TestRotateImage180(t *testing.T) {
-	ctrl := &Controller{}
-	src := image.NewGray(image.Rect(0, 0, 4, 4))
-
-	src.SetGray(0, 0, color.Gray{Y: 255})
-	src.SetGray(3, 3, color.Gray{Y: 200})
-
-	dst := ctrl.rotateImage180(src)
-
-	if dst.GrayAt(3, 3).Y != 255 {
-		t.Errorf("rotated pixel at (3,3) = %v, want 255", dst.GrayAt(3, 3).Y)
-	}
-	if dst.GrayAt(0, 0).Y != 200 {
-		t.Errorf("rotated pixel at (0,0) = %v, want 200", dst.GrayAt(0, 0).Y)
-	}
-}
-
 func TestConstants(t *testing.T) {
 	if displayWidth != 128 {
 		t.Errorf("displayWidth = %v, want 128", displayWidth)
@@ -111,7 +95,7 @@ func TestControllerContextCancellation(t *testing.T) {
 		cfg: &config.Config{
 			OLED: config.OLEDConfig{
 				Enabled:    true,
-				Rotate:     false,
+				Rotate:     0,
 				Fahrenheit: false,
 			},
 			Slider: config.SliderConfig{
@@ -138,6 +122,7 @@ func TestControllerContextCancellation(t *testing.T) {
 			12: &mockFontFace{},
 			14: &mockFontFace{},
 		},
+		clock:         clock.New(""),
 		timerDuration: 100 * time.Millisecond,
 	}
 
@@ -179,6 +164,9 @@ type mockSSD1306 struct {
 	closeCount        int
 	displayCalls      []bool
 	displayAfterClose bool
+	on                bool
+	released          bool
+	contrast          []byte
 }
 
 func (m *mockSSD1306) Display(img *image.Gray) error {
@@ -197,8 +185,172 @@ func (m *mockSSD1306) Clear() error {
 	return nil
 }
 
+func (m *mockSSD1306) SetDisplayOn(on bool) error {
+	m.on = on
+	return nil
+}
+
+func (m *mockSSD1306) SetContrast(contrast byte) error {
+	m.contrast = append(m.contrast, contrast)
+	return nil
+}
+
+func (m *mockSSD1306) Release() error {
+	m.released = true
+	return nil
+}
+
 func (m *mockSSD1306) Close() error {
 	m.closeCount++
 	m.closed = true
 	return nil
 }
+
+func TestFinalMessageSurvivesClose(t *testing.T) {
+	tests := []struct {
+		name         string
+		final        string
+		wantReleased bool
+	}{
+		{"blank by default", "", false},
+		{"final message", "Safe to|unplug", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDev := &mockSSD1306{}
+			ctrl := &Controller{
+				cfg:    &config.Config{},
+				dev:    mockDev,
+				img:    image.NewGray(image.Rect(0, 0, displayWidth, displayHeight)),
+				fonts:  map[int]font.Face{14: &mockFontFace{}},
+				asleep: true,
+			}
+
+			ctrl.SetFinalMessage(tt.final)
+			ctrl.showFinalMessage()
+			if err := ctrl.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			if mockDev.released != tt.wantReleased || (mockDev.closeCount == 1) == tt.wantReleased {
+				t.Errorf("released = %v, closed %d times, want released %v", mockDev.released, mockDev.closeCount, tt.wantReleased)
+			}
+			if tt.wantReleased && (!mockDev.on || len(mockDev.displayCalls) != 1) {
+				t.Errorf("final message not shown on a woken panel: on=%v, %d display calls", mockDev.on, len(mockDev.displayCalls))
+			}
+		})
+	}
+}
+
+func TestWelcomeDoesNotBlock(t *testing.T) {
+	mockDev := &mockSSD1306{}
+	ctrl := &Controller{
+		cfg:   &config.Config{OLED: config.OLEDConfig{Welcome: "Hello|there", WelcomeTime: 60}},
+		dev:   mockDev,
+		img:   image.NewGray(image.Rect(0, 0, displayWidth, displayHeight)),
+		fonts: map[int]font.Face{14: &mockFontFace{}},
+	}
+
+	start := time.Now()
+	ctrl.showWelcome()
+	if len(mockDev.displayCalls) != 1 {
+		t.Fatalf("welcome shown %d times, want once", len(mockDev.displayCalls))
+	}
+
+	// Stopping during the welcome ends the wait
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	ctrl.waitWelcome(ctx)
+	if took := time.Since(start); took > time.Second {
+		t.Errorf("welcome held up the caller for %v", took)
+	}
+}
+
+func TestGoodbye(t *testing.T) {
+	tests := []struct {
+		name      string
+		seconds   int
+		lost      bool
+		wantCalls int
+	}{
+		{"off", 0, false, 0},
+		{"shown then blanked", 1, false, 2},
+		{"panel lost", 60, true, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDev := &mockSSD1306{}
+			ctrl := &Controller{
+				cfg:   &config.Config{OLED: config.OLEDConfig{Goodbye: "Bye", GoodbyeTime: tt.seconds}},
+				dev:   mockDev,
+				img:   image.NewGray(image.Rect(0, 0, displayWidth, displayHeight)),
+				fonts: map[int]font.Face{14: &mockFontFace{}},
+				lost:  make(chan struct{}),
+			}
+			if tt.lost {
+				close(ctrl.lost)
+			}
+
+			start := time.Now()
+			ctrl.showGoodbye()
+			if len(mockDev.displayCalls) != tt.wantCalls {
+				t.Errorf("display calls = %d, want %d", len(mockDev.displayCalls), tt.wantCalls)
+			}
+			if took := time.Since(start); tt.lost && took > time.Second {
+				t.Errorf("goodbye waited %v for a lost panel", took)
+			}
+		})
+	}
+}
+
+// blockingPage holds GetPageText until release is closed, like a disk slow to answer
+type blockingPage struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (p *blockingPage) GetPageText() []TextItem {
+	close(p.started)
+	<-p.release
+	return []TextItem{{Text: "slow", FontSize: 12}}
+}
+
+func TestSlowPageDoesNotBlock(t *testing.T) {
+	page := &blockingPage{started: make(chan struct{}), release: make(chan struct{})}
+	ctrl := &Controller{
+		cfg:   &config.Config{},
+		dev:   &mockSSD1306{},
+		img:   image.NewGray(image.Rect(0, 0, displayWidth, displayHeight)),
+		fonts: map[int]font.Face{11: &mockFontFace{}, 12: &mockFontFace{}},
+		pages: []Page{page},
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ctrl.nextPage()
+	}()
+	<-page.started
+
+	returned := make(chan struct{})
+	go func() {
+		defer close(returned)
+		ctrl.ShowMessage("Hello", "world")
+		ctrl.NotifyBtnPress()
+	}()
+	select {
+	case <-returned:
+	case <-time.After(time.Second):
+		t.Fatal("ShowMessage blocked behind a slow page")
+	}
+
+	close(page.release)
+	<-done
+	// The message came after the page switch began, so it stays on screen
+	ctrl.mu.Lock()
+	defer ctrl.mu.Unlock()
+	if len(ctrl.items) == 0 || ctrl.items[0].Text != "Hello" {
+		t.Errorf("items = %+v, want the message", ctrl.items)
+	}
+}
@@ -4,16 +4,26 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/kolobock/rockpi-quad-go/internal/config"
+	"github.com/kolobock/rockpi-quad-go/internal/diag"
 	"github.com/kolobock/rockpi-quad-go/internal/disk"
+	"github.com/kolobock/rockpi-quad-go/internal/flags"
+	"github.com/kolobock/rockpi-quad-go/internal/luks"
+	"github.com/kolobock/rockpi-quad-go/internal/network"
+	"github.com/kolobock/rockpi-quad-go/internal/rails"
+	"github.com/kolobock/rockpi-quad-go/internal/thermal"
+	"github.com/kolobock/rockpi-quad-go/internal/ups"
 )
 
 const (
-	cpuTempNA = "CPU: N/A"
-	ipNA      = "IP: N/A"
+	hostnameName = "name"
+	hostnameMDNS = "mdns"
 )
 
 // Page represents a displayable page
@@ -21,25 +31,71 @@ type Page interface {
 	GetPageText() []TextItem
 }
 
+// skippable is implemented by pages that have nothing to show at times; the slider
+// passes over them while Skip returns true
+type skippable interface {
+	Skip() bool
+}
+
+// linkState is replaced in tests
+var linkState = network.ReadLinkState
+
+// pageName names a page after its type, e.g. "NetworkIO" for *NetworkIOPage
+func pageName(p Page) string {
+	name := strings.TrimPrefix(fmt.Sprintf("%T", p), "*oled.")
+	return strings.Replace(name, "Page", "", 1)
+}
+
 // TextItem represents a text element to be drawn
 type TextItem struct {
 	X        int
 	Y        int
 	Text     string
 	FontSize int
+	// Bar, when set, is drawn at X, Y instead of text
+	Bar *Bar
+	// ScrollWidth is the width of the column that text too wide for it scrolls
+	// through, 0 for text that fits
+	ScrollWidth int
+}
+
+// rotating is implemented by pages that show one of several items at a time, such
+// as one address or disk. They move on to the next item when the page is left, not
+// on every render, so prefetches and redraws do not skip items. rotate is called
+// with dataMu held.
+type rotating interface {
+	rotate()
 }
 
-// SystemInfoPage0 - Uptime, CPU Temp, IP Address
+// SystemInfoPage0 - Uptime, CPU Temp, IP Address, and the hostname and its mDNS name
+// when enabled. Panels too short for the extra rows show them in turn with the uptime.
 type SystemInfoPage0 struct {
 	ctrl *Controller
 }
 
 func (p *SystemInfoPage0) GetPageText() []TextItem {
-	return []TextItem{
-		{X: 0, Y: -2, Text: p.ctrl.getUptime(), FontSize: 11},
-		{X: 0, Y: 10, Text: p.ctrl.getCPUTemp(), FontSize: 11},
-		{X: 0, Y: 21, Text: p.ctrl.getIPAddress(), FontSize: 11},
+	rows := []Row{
+		Line(p.ctrl.getUptime()),
+		Line(p.ctrl.getCPUTemp()),
+		Line(p.ctrl.getIPAddress()),
 	}
+
+	l := p.ctrl.layout()
+	if hosts := p.ctrl.getHostnames(); len(hosts) > 0 {
+		if l.Capacity(defaultFontSize) >= len(rows)+len(hosts) {
+			for _, host := range hosts {
+				rows = append(rows, Line(host))
+			}
+		} else if i := p.ctrl.hostIndex % (len(hosts) + 1); i > 0 {
+			rows[0] = Line(hosts[i-1])
+		}
+	}
+	return l.Place(rows)
+}
+
+func (p *SystemInfoPage0) rotate() {
+	p.ctrl.ipIndex++
+	p.ctrl.hostIndex++
 }
 
 // SystemInfoPage1 - Fan speed, CPU load, Memory usage
@@ -51,16 +107,49 @@ func (p *SystemInfoPage1) GetPageText() []TextItem {
 	cpuFan, diskFan := p.ctrl.getFanSpeeds()
 	var fanText string
 	if cpuFan == 0 && diskFan == 0 {
-		fanText = "Fan: off"
+		fanText = p.ctrl.tr("Fan:") + " " + p.ctrl.tr("off")
 	} else {
-		fanText = fmt.Sprintf("Fan: C-%2.0f%%, D-%2.0f%%", cpuFan, diskFan)
+		fanText = p.ctrl.tr("Fan:") + fmt.Sprintf(" C-%2.0f%%, D-%2.0f%%", cpuFan, diskFan)
 	}
 
-	return []TextItem{
-		{X: 0, Y: -2, Text: fanText, FontSize: 11},
-		{X: 0, Y: 10, Text: p.ctrl.getCPULoad(), FontSize: 11},
-		{X: 0, Y: 21, Text: p.ctrl.getMemoryUsage(), FontSize: 11},
-	}
+	return p.ctrl.layout().Place([]Row{
+		Line(fanText),
+		Line(p.ctrl.getCPULoad()),
+		Line(p.ctrl.getMemoryUsage()),
+	})
+}
+
+// ClockPage - Local time, date and timezone
+type ClockPage struct {
+	ctrl *Controller
+}
+
+func (p *ClockPage) GetPageText() []TextItem {
+	now := p.ctrl.clock.Now()
+	return p.ctrl.layout().Place([]Row{
+		{Cells: []Cell{{Text: now.Format("15:04")}}, FontSize: 14},
+		{Cells: []Cell{{Text: now.Format("Mon 02 Jan 2006")}}, FontSize: 10},
+		{Cells: []Cell{{Text: now.Format("MST -0700")}}, FontSize: 10},
+	})
+}
+
+// LargeClockPage - Time and hostname in the large font, with the date and timezone
+// below when the panel is 64px high
+type LargeClockPage struct {
+	ctrl *Controller
+}
+
+func (p *LargeClockPage) GetPageText() []TextItem {
+	now := p.ctrl.clock.Now()
+	host, _ := os.Hostname()
+	host = formatHostname(host, p.ctrl.cfg.OLED.Hostname == hostnameMDNS)
+
+	return p.ctrl.layout().Place([]Row{
+		Centered(now.Format("15:04"), 14),
+		Centered(host, 14),
+		Centered(now.Format("Mon 02 Jan 2006"), 12),
+		Centered(now.Format("MST -0700"), 12),
+	})
 }
 
 // DiskUsagePage - Disk space usage
@@ -69,32 +158,40 @@ type DiskUsagePage struct {
 }
 
 func (p *DiskUsagePage) GetPageText() []TextItem {
-	items := []TextItem{}
-	usage := p.ctrl.getDiskUsage()
-
+	now := time.Now()
+	usage, at := p.ctrl.usage.get()
+	title := ageTitle(p.ctrl.usage, p.ctrl.tr("Usage:"), at, now)
+	l := p.ctrl.layout()
+	if at.IsZero() {
+		return l.Place([]Row{Columns(title, "...")})
+	}
 	if len(usage) == 0 {
-		return items
+		return []TextItem{}
 	}
 
-	items = append(items,
-		TextItem{X: 0, Y: -2, Text: "Usage:", FontSize: 11},
-		TextItem{X: 64, Y: -2, Text: usage[0], FontSize: 11})
-
-	if len(usage) > 1 {
-		items = append(items, TextItem{X: 0, Y: 10, Text: usage[1], FontSize: 11})
+	if l.Height >= 64 {
+		return l.Place(usageBars(title, usage))
 	}
-	if len(usage) > 2 {
-		items = append(items, TextItem{X: 64, Y: 10, Text: usage[2], FontSize: 11})
+	texts := make([]string, len(usage))
+	for i, u := range usage {
+		texts[i] = u.label + " " + u.percent
 	}
+	rows := append([]Row{Columns(title, texts[0])}, Grid(2, texts[1:])...)
+	return l.Place(rows)
+}
 
-	if len(usage) > 3 {
-		items = append(items, TextItem{X: 0, Y: 21, Text: usage[3], FontSize: 11})
+// usageBars lays disk usage out as one labeled bar per mount point for 64-row panels
+func usageBars(title string, usage []diskUsage) []Row {
+	width := 0
+	for _, u := range usage {
+		width = max(width, len([]rune(u.label)))
 	}
-	if len(usage) > 4 {
-		items = append(items, TextItem{X: 64, Y: 21, Text: usage[4], FontSize: 11})
+	rows := []Row{Line(title)}
+	for _, u := range usage {
+		percent, _ := strconv.ParseFloat(strings.TrimSuffix(u.percent, "%"), 64)
+		rows = append(rows, Progress(fmt.Sprintf("%-*s", width, u.label), percent/100, fmt.Sprintf("%4s", u.percent)))
 	}
-
-	return items
+	return rows
 }
 
 // NetworkIOPage - Network I/O rates
@@ -105,52 +202,299 @@ type NetworkIOPage struct {
 
 func (p *NetworkIOPage) GetPageText() []TextItem {
 	rx, tx := p.ctrl.getNetworkRate(p.iface)
-	return []TextItem{
-		{X: 0, Y: -2, Text: fmt.Sprintf("Network (%s):", p.iface), FontSize: 11},
-		{X: 0, Y: 10, Text: fmt.Sprintf("Rx:%10.6f MB/s", rx), FontSize: 11},
-		{X: 0, Y: 21, Text: fmt.Sprintf("Tx:%10.6f MB/s", tx), FontSize: 11},
+	link := network.FormatSpeed(linkState(p.iface))
+	return p.ctrl.layout().Place([]Row{
+		Line(fmt.Sprintf("Network (%s) %s", p.iface, link)),
+		Line(fmt.Sprintf("Rx:%10.6f MB/s", rx)),
+		Line(fmt.Sprintf("Tx:%10.6f MB/s", tx)),
+	})
+}
+
+// Skip hides the page while the interface has no carrier when [network] skip_down is set
+func (p *NetworkIOPage) Skip() bool {
+	return p.ctrl.cfg.Network.SkipDown && !linkState(p.iface).Carrier
+}
+
+// NetworkTotalPage - Combined I/O rates of all configured interfaces
+type NetworkTotalPage struct {
+	ctrl       *Controller
+	interfaces []string
+}
+
+func (p *NetworkTotalPage) GetPageText() []TextItem {
+	var rxTotal, txTotal float64
+	up := 0
+	for _, iface := range p.interfaces {
+		rx, tx := p.ctrl.getNetworkRate(iface)
+		rxTotal += rx
+		txTotal += tx
+		if linkState(iface).Carrier {
+			up++
+		}
 	}
+	return p.ctrl.layout().Place([]Row{
+		Line(fmt.Sprintf("Network (%d/%d up)", up, len(p.interfaces))),
+		Line(fmt.Sprintf("Rx:%10.6f MB/s", rxTotal)),
+		Line(fmt.Sprintf("Tx:%10.6f MB/s", txTotal)),
+	})
 }
 
-// DiskIOPage - Disk I/O rates
+// DiskIOPage - Disk I/O rates, with the member disks of an md array or
+// device-mapper volume on 64-row panels
 type DiskIOPage struct {
-	ctrl *Controller
-	disk string
+	ctrl    *Controller
+	disk    string
+	members []string
 }
 
 func (p *DiskIOPage) GetPageText() []TextItem {
 	read, write := p.ctrl.getDiskRate(p.disk)
-	return []TextItem{
-		{X: 0, Y: -2, Text: fmt.Sprintf("Disk (%s):", p.disk), FontSize: 11},
-		{X: 0, Y: 10, Text: fmt.Sprintf("R:%11.6f MB/s", read), FontSize: 11},
-		{X: 0, Y: 21, Text: fmt.Sprintf("W:%11.6f MB/s", write), FontSize: 11},
+	rows := []Row{
+		Line(fmt.Sprintf("Disk (%s):", p.ctrl.diskLabel(p.disk))),
+		Line(fmt.Sprintf("R:%11.6f MB/s", read)),
+		Line(fmt.Sprintf("W:%11.6f MB/s", write)),
+	}
+	if len(p.members) > 0 && p.ctrl.layout().Height >= 64 {
+		labels := make([]string, len(p.members))
+		for i, m := range p.members {
+			labels[i] = p.ctrl.diskLabel(m)
+		}
+		rows = append(rows, Line("on "+strings.Join(labels, ",")))
 	}
+	return p.ctrl.layout().Place(rows)
 }
 
-// DiskTempPage - Disk temperatures
+// DiskTempPage - Disk temperatures, of one HAT when HATs are stacked
 type DiskTempPage struct {
 	ctrl *Controller
+	hat  config.HATConfig
 }
 
 func (p *DiskTempPage) GetPageText() []TextItem {
-	temps := p.ctrl.getDiskTemperatures()
-	items := []TextItem{{X: 0, Y: -2, Text: "Disk Temps:", FontSize: 11}}
+	now := time.Now()
+	read, at := p.ctrl.diskTemps.get()
+	temps := p.ctrl.diskTemperatures(p.ctrl.hatDisks(p.hat), read)
+	title := ageTitle(p.ctrl.diskTemps, hatTitle(p.hat, p.ctrl.tr("Disk Temps:")), at, now)
+	rows := append([]Row{Line(title)}, Grid(2, temps)...)
+	return p.ctrl.layout().Place(rows)
+}
+
+// DiskPowerPage - Disk spin-up counts and time in standby, of one HAT when HATs are stacked
+type DiskPowerPage struct {
+	ctrl *Controller
+	hat  config.HATConfig
+}
 
-	if len(temps) > 0 {
-		items = append(items, TextItem{X: 0, Y: 10, Text: temps[0], FontSize: 11})
+func (p *DiskPowerPage) GetPageText() []TextItem {
+	rows := []Row{Line(hatTitle(p.hat, "Disk Sleep:"))}
+	for _, line := range p.ctrl.getDiskPowerStats(p.ctrl.hatDisks(p.hat)) {
+		rows = append(rows, Line(line))
 	}
-	if len(temps) > 1 {
-		items = append(items, TextItem{X: 64, Y: 10, Text: temps[1], FontSize: 11})
+	return p.ctrl.layout().Place(rows)
+}
+
+// SMARTHealthPage - SMART self-assessment and bad sector counts per disk, of one HAT
+// when HATs are stacked
+type SMARTHealthPage struct {
+	ctrl *Controller
+	hat  config.HATConfig
+}
+
+func (p *SMARTHealthPage) GetPageText() []TextItem {
+	health := p.ctrl.getDiskHealth(p.ctrl.hatDisks(p.hat))
+	rows := append([]Row{Line(hatTitle(p.hat, "SMART Health:"))}, Grid(2, health)...)
+	return p.ctrl.layout().Place(rows)
+}
+
+// DiskInventoryPage - Model, serial, capacity and link speed of one disk at a time
+type DiskInventoryPage struct {
+	ctrl *Controller
+}
+
+func (p *DiskInventoryPage) GetPageText() []TextItem {
+	drives := disk.Inventory()
+	if len(drives) == 0 {
+		return p.ctrl.layout().Place([]Row{Line("Inventory:"), Line("No disks")})
 	}
 
-	if len(temps) > 2 {
-		items = append(items, TextItem{X: 0, Y: 21, Text: temps[2], FontSize: 11})
+	d := drives[p.ctrl.driveIndex%len(drives)]
+	rows := []Row{
+		Line(fmt.Sprintf("%s %s", p.ctrl.diskLabel(d.Device), d.Size())),
+		Line(valueOr(d.Model, "Unknown model")),
+		Line("SN " + valueOr(d.Serial, "--")),
 	}
-	if len(temps) > 3 {
-		items = append(items, TextItem{X: 64, Y: 21, Text: temps[3], FontSize: 11})
+	if d.LinkSpeed != "" {
+		rows = append(rows, Line("Link "+d.LinkSpeed))
 	}
+	return p.ctrl.layout().Place(rows)
+}
 
-	return items
+func (p *DiskInventoryPage) rotate() { p.ctrl.driveIndex++ }
+
+func valueOr(s, fallback string) string {
+	if s == "" {
+		return fallback
+	}
+	return s
+}
+
+// RAIDPage - State of md arrays and ZFS pools with resync/resilver progress
+type RAIDPage struct {
+	ctrl *Controller
+}
+
+func (p *RAIDPage) GetPageText() []TextItem {
+	arrays := p.ctrl.raid.Arrays()
+	rows := []Row{Line("RAID:")}
+	if len(arrays) == 0 {
+		return p.ctrl.layout().Place(append(rows, Line("No arrays")))
+	}
+
+	// Degraded arrays first so they stay visible on small panels
+	sort.SliceStable(arrays, func(i, j int) bool { return arrays[i].Degraded && !arrays[j].Degraded })
+	for _, a := range arrays {
+		rows = append(rows, Line(fmt.Sprintf("%s %s", a.Name, a.Status())))
+	}
+	return p.ctrl.layout().Place(rows)
+}
+
+// UPSPage - Battery charge and whether the system runs on mains or battery
+type UPSPage struct {
+	ctrl *Controller
+}
+
+func (p *UPSPage) GetPageText() []TextItem {
+	st, ok := p.ctrl.ups.Status()
+	return p.ctrl.layout().Place(upsRows(st, ok))
+}
+
+func upsRows(st ups.Status, ok bool) []Row {
+	rows := []Row{Line("UPS:")}
+	if !ok {
+		return append(rows, Line("No data"))
+	}
+
+	state := "On mains"
+	if st.OnBattery {
+		state = "On battery"
+	}
+	if st.LowBattery {
+		state += " LOW"
+	}
+	if st.ChargeKnown {
+		rows = append(rows, Line(fmt.Sprintf("Charge: %.0f%%", st.Charge)))
+	}
+	rows = append(rows, Line(state))
+	if st.OnBattery && st.Runtime > 0 {
+		rows = append(rows, Line("Runtime: "+formatDuration(st.Runtime)))
+	}
+	return rows
+}
+
+// PowerPage - Voltage and current of the watched power rails
+type PowerPage struct {
+	ctrl *Controller
+}
+
+func (p *PowerPage) GetPageText() []TextItem {
+	return p.ctrl.layout().Place(railRows(p.ctrl.rails.States()))
+}
+
+// railRows shows one line per rail; a bad rail is marked with "!" after its name
+func railRows(states []rails.State) []Row {
+	rows := []Row{Line("Power:")}
+	for _, st := range states {
+		label := st.Name
+		if !st.Good {
+			label += "!"
+		}
+		switch {
+		case st.HasCurrent:
+			rows = append(rows, Line(fmt.Sprintf("%s %.2fV %.2fA", label, st.Volts, st.Amps)))
+		case st.Volts > 0:
+			rows = append(rows, Line(fmt.Sprintf("%s %.2fV", label, st.Volts)))
+		case st.Good:
+			rows = append(rows, Line(st.Name+" OK"))
+		default:
+			rows = append(rows, Line(st.Name+" LOW"))
+		}
+	}
+	return rows
+}
+
+// LUKSPage - Whether the configured encrypted volumes are open
+type LUKSPage struct {
+	ctrl *Controller
+}
+
+func (p *LUKSPage) GetPageText() []TextItem {
+	rows := []Row{Line("LUKS:")}
+	for _, v := range p.ctrl.cfg.LUKS.Volumes {
+		state := "locked"
+		if luks.IsOpen(v) {
+			state = "open"
+		}
+		rows = append(rows, Line(v.Name+" "+state))
+	}
+	return p.ctrl.layout().Place(rows)
+}
+
+// KernelEventsPage - Most recent critical kernel log events
+type KernelEventsPage struct {
+	ctrl *Controller
+}
+
+func (p *KernelEventsPage) GetPageText() []TextItem {
+	events := p.ctrl.kernelLog.Recent()
+	rows := []Row{Line(fmt.Sprintf("Kernel (%d):", len(events)))}
+
+	if len(events) == 0 {
+		return p.ctrl.layout().Place(append(rows, Line("No errors")))
+	}
+
+	// Newest first; the layout keeps as many as fit
+	for i := len(events) - 1; i >= 0; i-- {
+		evt := events[i]
+		rows = append(rows, Line(fmt.Sprintf("%s %s", p.ctrl.clock.In(evt.Time).Format("15:04"), evt.Category)))
+	}
+
+	return p.ctrl.layout().Place(rows)
+}
+
+// ConnectivityPage - Results of the configured ping/TCP health checks
+type ConnectivityPage struct {
+	ctrl *Controller
+}
+
+func (p *ConnectivityPage) GetPageText() []TextItem {
+	var checks []string
+	for _, res := range p.ctrl.checker.Results() {
+		status := "--"
+		switch {
+		case res.OK:
+			status = fmt.Sprintf("%.0fms", float64(res.Latency)/float64(time.Millisecond))
+		case res.Failures > 0:
+			status = "FAIL"
+		}
+		checks = append(checks, res.Name+" "+status)
+	}
+
+	rows := append([]Row{Line("Connectivity:")}, Grid(2, checks)...)
+	return p.ctrl.layout().Place(rows)
+}
+
+// StaticTextPage - Free text from a [page.<name>] config section
+type StaticTextPage struct {
+	ctrl  *Controller
+	lines []string
+}
+
+func (p *StaticTextPage) GetPageText() []TextItem {
+	rows := make([]Row, 0, len(p.lines))
+	for _, line := range p.lines {
+		rows = append(rows, Line(line))
+	}
+	return p.ctrl.layout().Place(rows)
 }
 
 // Utility functions to get system information
@@ -162,96 +506,128 @@ func (c *Controller) getFanSpeeds() (cpuPercent, diskPercent float64) {
 	return 0, 0
 }
 
+// shellOutput runs a command line through sh -c and returns its output
+func shellOutput(cmd string) ([]byte, error) {
+	diag.ShellExecs.Add(1)
+	// #nosec G204 - command lines are fixed or built from configured mount points
+	return exec.Command("sh", "-c", cmd).Output()
+}
+
 func (c *Controller) getUptime() string {
-	out, err := exec.Command("sh", "-c", "uptime | sed 's/.*up \\([^,]*\\),.*/\\1/'").Output()
+	out, err := shellOutput("uptime | sed 's/.*up \\([^,]*\\),.*/\\1/'")
 	if err != nil {
-		return "Uptime: N/A"
+		return c.tr("Uptime:") + " " + c.tr("N/A")
 	}
-	return "Up: " + strings.TrimSpace(string(out))
+	return c.tr("Up:") + " " + strings.TrimSpace(string(out))
 }
 
-func (c *Controller) getCPUTemp() string {
-	data, err := os.ReadFile("/sys/class/thermal/thermal_zone0/temp")
-	if err != nil {
-		return cpuTempNA
+// getHostnames returns the hostname and its mDNS name lines, or nil when the
+// hostname lines are disabled
+func (c *Controller) getHostnames() []string {
+	mode := c.cfg.OLED.Hostname
+	if mode != hostnameName && mode != hostnameMDNS {
+		return nil
 	}
-	temp, err := strconv.ParseFloat(strings.TrimSpace(string(data)), 64)
-	if err != nil {
-		return cpuTempNA
+
+	name, err := os.Hostname()
+	if err != nil || name == "" {
+		return nil
 	}
-	temp /= 1000.0
+	return []string{
+		c.tr("Host:") + " " + formatHostname(name, false),
+		"mDNS: " + formatHostname(name, true),
+	}
+}
 
-	if c.cfg.OLED.Fahrenheit {
-		return fmt.Sprintf("CPU: %.0f°F", temp*1.8+32)
+func formatHostname(name string, mdns bool) string {
+	if !mdns {
+		return name
 	}
-	return fmt.Sprintf("CPU: %.1f°C", temp)
+	short, _, _ := strings.Cut(name, ".")
+	return short + ".local"
 }
 
-func (c *Controller) getIPAddress() string {
-	out, err := exec.Command("hostname", "-I").Output()
+func (c *Controller) getCPUTemp() string {
+	temp, err := thermal.Read(c.cfg.Fan.CPUSensors, c.cfg.Fan.CPUSensorMode)
 	if err != nil {
-		return ipNA
+		return c.tr("CPU:") + " " + c.tr("N/A")
 	}
-	fields := strings.Fields(string(out))
-	if len(fields) > 0 {
-		return "IP: " + fields[0]
+
+	return c.tr("CPU:") + " " + c.formatTemp(temp)
+}
+
+// formatTemp renders a temperature in the OLED precision and the unit of the f-temp flag
+func (c *Controller) formatTemp(temp float64) string {
+	return c.cfg.Temp.OLED.String(temp, c.flag(flags.Fahrenheit, c.cfg.OLED.Fahrenheit))
+}
+
+// formatTempRange renders a range of temperatures, e.g. "31-48°C"
+func (c *Controller) formatTempRange(lo, hi float64) string {
+	return c.cfg.Temp.OLED.Number(lo, c.flag(flags.Fahrenheit, c.cfg.OLED.Fahrenheit)) + "-" + c.formatTemp(hi)
+}
+
+// getIPAddress returns the current address of the configured interfaces, labelled
+// with its interface; the addresses take turns each time the page comes round
+func (c *Controller) getIPAddress() string {
+	addrs := network.InterfaceAddresses(c.cfg.Network.Interfaces, c.cfg.Network.IPFamily)
+	if len(addrs) == 0 {
+		return "IP: " + c.tr("N/A")
 	}
-	return ipNA
+	addr := addrs[c.ipIndex%len(addrs)]
+	return addr.Interface + ": " + addr.IP.String()
 }
 
 func (c *Controller) getCPULoad() string {
-	out, err := exec.Command("sh", "-c", "uptime | awk '{print $(NF-2)}'").Output()
+	out, err := shellOutput("uptime | awk '{print $(NF-2)}'")
 	if err != nil {
-		return "CPU Load: N/A"
+		return c.tr("CPU Load:") + " " + c.tr("N/A")
 	}
 	load := strings.TrimSpace(string(out))
 	load = strings.TrimSuffix(load, ",")
-	return "CPU: " + load
+	return c.tr("CPU:") + " " + load
 }
 
 func (c *Controller) getMemoryUsage() string {
-	out, err := exec.Command("sh", "-c", "free -m | awk 'NR==2{printf \"%s/%sMB\", $3,$2}'").Output()
+	out, err := shellOutput("free -m | awk 'NR==2{printf \"%s/%sMB\", $3,$2}'")
 	if err != nil {
-		return "Mem: N/A"
+		return c.tr("Mem:") + " " + c.tr("N/A")
 	}
-	return "Mem: " + strings.TrimSpace(string(out))
+	return c.tr("Mem:") + " " + strings.TrimSpace(string(out))
 }
 
-// stripDeviceName removes /dev/ prefix and partition numbers from device names
-// e.g., /dev/sda1 -> sda, /dev/nvme0n1p1 -> nvme0n1
-func stripDeviceName(device string) string {
-	if strings.HasPrefix(device, "/dev/") {
-		device = strings.TrimPrefix(device, "/dev/")
-		// Remove partition number
-		for i := len(device) - 1; i >= 0; i-- {
-			if device[i] < '0' || device[i] > '9' {
-				return device[:i+1]
-			}
-		}
-	}
-	return device
+// diskLabel returns the display name of a disk or partition, honoring [disk] aliases
+func (c *Controller) diskLabel(device string) string {
+	return disk.Label(device, c.cfg.Disk.Aliases)
 }
 
-func (c *Controller) getDiskUsage() []string {
-	usage := make([]string, 0, 1+len(c.cfg.Disk.SpaceUsageMountPoints))
+// diskUsage is the used space of the root filesystem or a disk as df prints it, e.g. "45%"
+type diskUsage struct {
+	label   string
+	percent string
+}
 
-	out, err := exec.Command("sh", "-c", "df -h / | awk 'NR==2{print $5}'").Output()
+// readDiskUsage runs df on / and the space usage mount points; it blocks while a
+// disk is slow to answer, so pages read it through c.usage
+func (c *Controller) readDiskUsage() []diskUsage {
+	usage := make([]diskUsage, 0, 1+len(c.cfg.Disk.SpaceUsageMountPoints))
+
+	out, err := shellOutput("df -h / | awk 'NR==2{print $5}'")
 	if err == nil {
 		percentage := strings.TrimSpace(string(out))
 		if percentage != "" {
-			usage = append(usage, "/ "+percentage)
+			usage = append(usage, diskUsage{label: "/", percent: percentage})
 		}
 	}
 
-	diskMap := make(map[string]string)
+	diskMap := make(map[string]diskUsage)
 	for _, mnt := range c.cfg.Disk.SpaceUsageMountPoints {
 		cmd := fmt.Sprintf("df -h %s | awk 'NR==2{print $1, $5}'", mnt)
-		out, err := exec.Command("sh", "-c", cmd).Output()
+		out, err := shellOutput(cmd)
 		if err == nil && len(out) > 0 {
 			parts := strings.Fields(strings.TrimSpace(string(out)))
 			if len(parts) >= 2 {
-				diskName := stripDeviceName(parts[0])
-				diskMap[diskName] = diskName + " " + parts[1]
+				diskName := disk.ParentDevice(parts[0])
+				diskMap[diskName] = diskUsage{label: c.diskLabel(diskName), percent: parts[1]}
 			}
 		}
 	}
@@ -276,11 +652,9 @@ func (c *Controller) getDiskUsage() []string {
 	return usage
 }
 
+// getNetworkInterfaces lists the configured interfaces that exist, or the usual
+// defaults when none are configured
 func (c *Controller) getNetworkInterfaces() (interfaces []string) {
-	if c.cfg.Network.SkipPage {
-		return
-	}
-
 	var ifs = c.cfg.Network.Interfaces
 	if len(c.cfg.Network.Interfaces) == 0 {
 		ifs = []string{"eth0", "wlan0", "enp0s3"}
@@ -295,8 +669,7 @@ func (c *Controller) getNetworkInterfaces() (interfaces []string) {
 }
 
 func (c *Controller) updateNetworkStats() {
-	interfaces := c.getNetworkInterfaces()
-	for _, iface := range interfaces {
+	for _, iface := range c.getNetworkInterfaces() {
 		path := "/sys/class/net/" + iface + "/statistics/"
 
 		rxData, _ := os.ReadFile(path + "rx_bytes")
@@ -343,127 +716,234 @@ func (c *Controller) getNetworkRate(iface string) (rxRate, txRate float64) {
 }
 
 func (c *Controller) getDiskNameFromMount(mount string) string {
-	// #nosec G204 - mount is a hardcoded path from config, not user input
-	out, err := exec.Command("sh", "-c", fmt.Sprintf("df %s | awk 'NR==2{print $1}'", mount)).Output()
+	out, err := shellOutput(fmt.Sprintf("df %s | awk 'NR==2{print $1}'", mount))
 	if err != nil {
 		return ""
 	}
 	device := strings.TrimSpace(string(out))
-	if strings.HasPrefix(device, "/dev/") {
-		device = strings.TrimPrefix(device, "/dev/")
-		for i := len(device) - 1; i >= 0; i-- {
-			device = device[:i+1]
-			if device[i] < '0' || device[i] > '9' {
-				break
-			}
-		}
+	if !strings.HasPrefix(device, "/dev/") {
+		return device
 	}
-	return device
+	return disk.BlockName(device)
 }
 
-func (c *Controller) updateDiskStats() {
-	for _, mnt := range c.cfg.Disk.IOUsageMountPoints {
-		diskName := c.getDiskNameFromMount(mnt)
-		if diskName == "" {
-			continue
-		}
-
-		path := "/sys/block/" + diskName + "/"
-		readData, _ := os.ReadFile(path + "stat")
-
-		if len(readData) > 0 {
-			fields := strings.Fields(string(readData))
-			if len(fields) >= 10 {
-				readSectors, _ := strconv.ParseUint(fields[2], 10, 64)
-				writeSectors, _ := strconv.ParseUint(fields[6], 10, 64)
-
-				c.diskStats[diskName] = diskIOStats{
-					readBytes:  readSectors * 512,
-					writeBytes: writeSectors * 512,
-					timestamp:  time.Now(),
-				}
-			}
+// ioDevices lists the disks with an I/O page: those holding io_usage_mnt_points,
+// then io_usage_devices, each once
+func (c *Controller) ioDevices() []string {
+	var devices []string
+	add := func(name string) {
+		if name != "" && !slices.Contains(devices, name) {
+			devices = append(devices, name)
 		}
 	}
+	for _, mnt := range c.cfg.Disk.IOUsageMountPoints {
+		add(c.getDiskNameFromMount(mnt))
+	}
+	for _, dev := range c.cfg.Disk.IOUsageDevices {
+		add(disk.BlockName(dev))
+	}
+	return devices
 }
 
-func (c *Controller) getDiskRate(diskName string) (readRate, writeRate float64) {
-	oldStats, exists := c.diskStats[diskName]
-	if !exists {
-		c.updateDiskStats()
-		return 0, 0
+// updateDiskStats takes the baseline of every disk with an I/O page
+func (c *Controller) updateDiskStats() {
+	for _, name := range c.ioDevices() {
+		c.getDiskRate(name)
 	}
+}
 
-	path := "/sys/block/" + diskName + "/stat"
-	data, err := os.ReadFile(path)
+// getDiskRate returns the read and write rates of a disk in MB/s since the previous
+// call; the first call only takes the baseline
+func (c *Controller) getDiskRate(diskName string) (readRate, writeRate float64) {
+	read, written, err := disk.ReadIOStat(diskName)
 	if err != nil {
 		return 0, 0
 	}
 
-	fields := strings.Fields(string(data))
-	if len(fields) < 10 {
+	now := time.Now()
+	oldStats, exists := c.diskStats[diskName]
+	c.diskStats[diskName] = diskIOStats{
+		readBytes:  read,
+		writeBytes: written,
+		timestamp:  now,
+	}
+	if !exists {
 		return 0, 0
 	}
 
-	readSectors, _ := strconv.ParseUint(fields[2], 10, 64)
-	writeSectors, _ := strconv.ParseUint(fields[6], 10, 64)
-
-	now := time.Now()
 	elapsed := now.Sub(oldStats.timestamp).Seconds()
+	readRate = float64(read-oldStats.readBytes) / elapsed / 1024 / 1024
+	writeRate = float64(written-oldStats.writeBytes) / elapsed / 1024 / 1024
+	return readRate, writeRate
+}
 
-	readRate = float64(readSectors*512-oldStats.readBytes) / elapsed / 1024 / 1024
-	writeRate = float64(writeSectors*512-oldStats.writeBytes) / elapsed / 1024 / 1024
+// hatDisks returns the disks of a HAT, or every disk for the zero HAT of an unstacked setup
+func (c *Controller) hatDisks(hat config.HATConfig) []string {
+	if hat.ID == "" {
+		return disk.GetSATADisks()
+	}
+	return disk.HATDisks(hat.ID)
+}
 
-	c.diskStats[diskName] = diskIOStats{
-		readBytes:  readSectors * 512,
-		writeBytes: writeSectors * 512,
-		timestamp:  now,
+// hatTitle prefixes a page title with the HAT label when HATs are stacked
+func hatTitle(hat config.HATConfig, title string) string {
+	if hat.ID == "" {
+		return title
 	}
+	return hat.Label + " " + title
+}
 
-	return readRate, writeRate
+// perHAT creates one page per HAT when HATs are stacked, otherwise a single page
+// for all disks
+func (c *Controller) perHAT(newPage func(hat config.HATConfig) Page) []Page {
+	if len(c.cfg.HATs) < 2 {
+		return []Page{newPage(config.HATConfig{})}
+	}
+	pages := make([]Page, 0, len(c.cfg.HATs))
+	for _, hat := range c.cfg.HATs {
+		pages = append(pages, newPage(hat))
+	}
+	return pages
 }
 
-func (c *Controller) getDiskTemperatures() []string {
+// diskTemperatures formats the temperatures read of disks, "--" for those without one
+func (c *Controller) diskTemperatures(disks []string, read map[string]float64) []string {
 	var temps []string
 
-	for _, diskDev := range disk.GetSATADisks() {
-		temp, err := disk.GetTemperature(diskDev)
-		diskName := strings.TrimPrefix(diskDev, "/dev/")
-		if err == nil && temp > 0 {
-			temps = append(temps, fmt.Sprintf("%s %.0f°C", diskName, temp))
+	for _, diskDev := range disks {
+		temp, ok := read[diskDev]
+		diskName := c.diskLabel(diskDev)
+		if ok {
+			temps = append(temps, diskName+" "+c.formatTemp(temp))
 		} else {
-			temps = append(temps, fmt.Sprintf("%s --°C", diskName))
+			temps = append(temps, diskName+" --"+thermal.Unit(c.flag(flags.Fahrenheit, c.cfg.OLED.Fahrenheit)))
 		}
 	}
 
 	return temps
 }
 
+func (c *Controller) getDiskHealth(disks []string) []string {
+	health := disk.GetHealth()
+	var entries []string
+
+	for _, diskDev := range disks {
+		status := string(disk.HealthUnknown)
+		if h, ok := health[diskDev]; ok {
+			status = h.Summary()
+		}
+		entries = append(entries, fmt.Sprintf("%s %s", c.diskLabel(diskDev), status))
+	}
+
+	return entries
+}
+
+func (c *Controller) getDiskPowerStats(disks []string) []string {
+	stats := disk.GetPowerStats()
+	lines := make([]string, 0, len(stats))
+
+	for _, diskDev := range disks {
+		st, ok := stats[diskDev]
+		if !ok {
+			continue
+		}
+		diskName := c.diskLabel(diskDev)
+		lines = append(lines, fmt.Sprintf("%s %dx %s", diskName, st.SpinUps, formatDuration(st.StandbyTime)))
+	}
+
+	return lines
+}
+
+// formatDuration renders a duration compactly, e.g. 45m or 12h
+func formatDuration(d time.Duration) string {
+	switch {
+	case d >= 48*time.Hour:
+		return fmt.Sprintf("%.0fd", d.Hours()/24)
+	case d >= time.Hour:
+		return fmt.Sprintf("%.0fh", d.Hours())
+	default:
+		return fmt.Sprintf("%.0fm", d.Minutes())
+	}
+}
+
 func (c *Controller) generatePages() []Page {
-	pages := make([]Page, 0, 2+len(c.cfg.Disk.SpaceUsageMountPoints)+len(c.cfg.Network.Interfaces)+len(c.cfg.Disk.IOUsageMountPoints)+1)
+	pages := make([]Page, 0, 2+len(c.cfg.Disk.SpaceUsageMountPoints)+len(c.cfg.Network.Interfaces)+len(c.cfg.Disk.IOUsageMountPoints)+len(c.cfg.Disk.IOUsageDevices)+1)
 
 	pages = append(pages,
 		&SystemInfoPage0{ctrl: c},
 		&SystemInfoPage1{ctrl: c})
 
+	if c.cfg.OLED.Clock {
+		pages = append(pages, &ClockPage{ctrl: c})
+	}
+	if c.cfg.OLED.LargeClock {
+		pages = append(pages, &LargeClockPage{ctrl: c})
+	}
+
 	if len(c.cfg.Disk.SpaceUsageMountPoints) > 0 {
 		pages = append(pages, &DiskUsagePage{ctrl: c})
 	}
 
 	interfaces := c.getNetworkInterfaces()
-	for _, iface := range interfaces {
-		pages = append(pages, &NetworkIOPage{ctrl: c, iface: iface})
+	if !c.cfg.Network.SkipPage {
+		for _, iface := range interfaces {
+			pages = append(pages, &NetworkIOPage{ctrl: c, iface: iface})
+		}
+	}
+	if c.cfg.Network.Aggregate && len(interfaces) > 0 {
+		pages = append(pages, &NetworkTotalPage{ctrl: c, interfaces: interfaces})
 	}
 
-	for _, mnt := range c.cfg.Disk.IOUsageMountPoints {
-		diskName := c.getDiskNameFromMount(mnt)
-		if diskName != "" {
-			pages = append(pages, &DiskIOPage{ctrl: c, disk: diskName})
-		}
+	for _, diskName := range c.ioDevices() {
+		pages = append(pages, &DiskIOPage{ctrl: c, disk: diskName, members: disk.Members(diskName)})
 	}
 
 	if c.cfg.Disk.DisksTemperature {
-		pages = append(pages, &DiskTempPage{ctrl: c})
+		pages = append(pages, c.perHAT(func(hat config.HATConfig) Page { return &DiskTempPage{ctrl: c, hat: hat} })...)
+	}
+
+	if c.cfg.Disk.PowerStats {
+		pages = append(pages, c.perHAT(func(hat config.HATConfig) Page { return &DiskPowerPage{ctrl: c, hat: hat} })...)
+	}
+
+	if c.cfg.Disk.SMARTHealth {
+		pages = append(pages, c.perHAT(func(hat config.HATConfig) Page { return &SMARTHealthPage{ctrl: c, hat: hat} })...)
+	}
+
+	if c.cfg.Disk.Inventory {
+		pages = append(pages, &DiskInventoryPage{ctrl: c})
+	}
+
+	if c.raid != nil {
+		pages = append(pages, &RAIDPage{ctrl: c})
+	}
+
+	if c.ups != nil {
+		pages = append(pages, &UPSPage{ctrl: c})
+	}
+
+	if c.rails != nil {
+		pages = append(pages, &PowerPage{ctrl: c})
+	}
+
+	if len(c.cfg.LUKS.Volumes) > 0 {
+		pages = append(pages, &LUKSPage{ctrl: c})
+	}
+
+	if c.kernelLog != nil {
+		pages = append(pages, &KernelEventsPage{ctrl: c})
+	}
+
+	if c.checker != nil {
+		pages = append(pages, &ConnectivityPage{ctrl: c})
+	}
+
+	if c.store != nil {
+		pages = append(pages, &TrendPage{ctrl: c})
+	}
+
+	for _, custom := range c.cfg.OLED.CustomPages {
+		pages = append(pages, &StaticTextPage{ctrl: c, lines: custom.Lines})
 	}
 
 	return pages
@@ -1,34 +1,23 @@
 package oled
 
 import (
+	"context"
+	"image"
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/kolobock/rockpi-quad-go/internal/clock"
 	"github.com/kolobock/rockpi-quad-go/internal/config"
+	"github.com/kolobock/rockpi-quad-go/internal/network"
+	"github.com/kolobock/rockpi-quad-go/internal/raid"
+	"github.com/kolobock/rockpi-quad-go/internal/rails"
+	"github.com/kolobock/rockpi-quad-go/internal/ups"
 )
 
-func TestStripDeviceName(t *testing.T) {
-	tests := []struct {
-		name   string
-		device string
-		want   string
-	}{
-		{"simple device", "/dev/sda1", "sda"},
-		{"nvme device", "/dev/nvme0n1p1", "nvme0n1p"},
-		{"no partition", "/dev/sdb", "sdb"},
-		{"no prefix", "sda1", "sda1"},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			got := stripDeviceName(tt.device)
-			if got != tt.want {
-				t.Errorf("stripDeviceName(%v) = %v, want %v", tt.device, got, tt.want)
-			}
-		})
-	}
-}
-
 func TestTextItem(t *testing.T) {
 	item := TextItem{
 		X:        10,
@@ -65,3 +54,253 @@ func TestNetworkIOPage(t *testing.T) {
 		t.Errorf("first item should contain 'Network', got %v", items[0].Text)
 	}
 }
+
+func TestDiskIOPageMembers(t *testing.T) {
+	for _, height := range []int{32, 64} {
+		ctrl := &Controller{
+			cfg:       &config.Config{},
+			img:       image.NewGray(image.Rect(0, 0, 128, height)),
+			diskStats: make(map[string]diskIOStats),
+		}
+		items := (&DiskIOPage{ctrl: ctrl, disk: "md0", members: []string{"sda", "sdb"}}).GetPageText()
+
+		want := 3
+		if height == 64 {
+			want = 4
+		}
+		if len(items) != want {
+			t.Fatalf("height %d: got %d items, want %d", height, len(items), want)
+		}
+		if height == 64 && items[3].Text != "on sda,sdb" {
+			t.Errorf("members line = %q, want on sda,sdb", items[3].Text)
+		}
+	}
+}
+
+func TestFollowingPageSkipsDownInterfaces(t *testing.T) {
+	old := linkState
+	linkState = func(iface string) network.LinkState { return network.LinkState{Carrier: iface == "eth0"} }
+	defer func() { linkState = old }()
+
+	for _, skipDown := range []bool{false, true} {
+		ctrl := &Controller{cfg: &config.Config{Network: config.NetworkConfig{SkipDown: skipDown}}}
+		ctrl.pages = []Page{
+			&SystemInfoPage0{ctrl: ctrl},
+			&NetworkIOPage{ctrl: ctrl, iface: "eth0"},
+			&NetworkIOPage{ctrl: ctrl, iface: "wlan0"},
+		}
+
+		var order []int
+		for range 3 {
+			ctrl.pageIndex = ctrl.followingPage()
+			order = append(order, ctrl.pageIndex)
+		}
+		want := []int{1, 2, 0}
+		if skipDown {
+			want = []int{1, 0, 1}
+		}
+		if !slices.Equal(order, want) {
+			t.Errorf("skip_down=%v: pages %v, want %v", skipDown, order, want)
+		}
+	}
+}
+
+func TestNetworkTotalPage(t *testing.T) {
+	old := linkState
+	linkState = func(iface string) network.LinkState { return network.LinkState{Carrier: iface == "eth0"} }
+	defer func() { linkState = old }()
+
+	ctrl := &Controller{cfg: &config.Config{}, netStats: make(map[string]netIOStats)}
+	items := (&NetworkTotalPage{ctrl: ctrl, interfaces: []string{"eth0", "wlan0"}}).GetPageText()
+	if len(items) != 3 || items[0].Text != "Network (1/2 up)" {
+		t.Errorf("items = %+v, want 3 lines starting with Network (1/2 up)", items)
+	}
+}
+
+func TestSystemInfoPage0Hostnames(t *testing.T) {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		t.Skip("no hostname")
+	}
+	name := "Host: " + formatHostname(host, false)
+	mdns := "mDNS: " + formatHostname(host, true)
+
+	ctrl := &Controller{
+		cfg: &config.Config{OLED: config.OLEDConfig{Hostname: hostnameName}},
+		img: image.NewGray(image.Rect(0, 0, 128, 64)),
+	}
+	page := &SystemInfoPage0{ctrl: ctrl}
+	items := page.GetPageText()
+	if len(items) != 5 || !strings.HasPrefix(items[0].Text, "Up") || items[3].Text != name || items[4].Text != mdns {
+		t.Fatalf("64-row items = %+v, want uptime, CPU, IP, %q and %q", items, name, mdns)
+	}
+
+	// A 32-row panel keeps three rows and shows the names in turn with the uptime
+	ctrl.img = image.NewGray(image.Rect(0, 0, 128, 32))
+	var first []string
+	for range 4 {
+		items = page.GetPageText()
+		if len(items) != 3 {
+			t.Fatalf("32-row page has %d items, want 3", len(items))
+		}
+		first = append(first, items[0].Text)
+		page.rotate()
+	}
+	if !strings.HasPrefix(first[0], "Up") || first[1] != name || first[2] != mdns || first[3] != first[0] {
+		t.Errorf("32-row first lines = %q, want uptime, %q, %q, uptime", first, name, mdns)
+	}
+}
+
+func TestFormatHostname(t *testing.T) {
+	tests := []struct {
+		name string
+		mdns bool
+		want string
+	}{
+		{"nas", false, "nas"},
+		{"nas", true, "nas.local"},
+		{"nas.example.com", true, "nas.local"},
+	}
+
+	for _, tt := range tests {
+		if got := formatHostname(tt.name, tt.mdns); got != tt.want {
+			t.Errorf("formatHostname(%q, %v) = %q, want %q", tt.name, tt.mdns, got, tt.want)
+		}
+	}
+}
+
+func TestPageName(t *testing.T) {
+	if got := pageName(&NetworkIOPage{}); got != "NetworkIO" {
+		t.Errorf("pageName(NetworkIOPage) = %q, want NetworkIO", got)
+	}
+	if got := pageName(&SystemInfoPage0{}); got != "SystemInfo0" {
+		t.Errorf("pageName(SystemInfoPage0) = %q, want SystemInfo0", got)
+	}
+}
+
+func TestPages(t *testing.T) {
+	c := &Controller{pages: []Page{&SystemInfoPage0{}, &NetworkIOPage{}}}
+	if got := c.Pages(); len(got) != 2 || got[0] != "SystemInfo0" || got[1] != "NetworkIO" {
+		t.Errorf("Pages() = %v, want [SystemInfo0 NetworkIO]", got)
+	}
+}
+
+func TestLargeClockPage(t *testing.T) {
+	tests := []struct {
+		height    int
+		wantItems int
+	}{
+		{32, 2},
+		{64, 4},
+	}
+
+	for _, tt := range tests {
+		ctrl := &Controller{
+			cfg:   &config.Config{},
+			img:   image.NewGray(image.Rect(0, 0, displayWidth, tt.height)),
+			clock: clock.New("UTC"),
+		}
+
+		items := (&LargeClockPage{ctrl: ctrl}).GetPageText()
+		if len(items) != tt.wantItems {
+			t.Fatalf("%dpx: got %d items, want %d: %+v", tt.height, len(items), tt.wantItems, items)
+		}
+		if items[0].FontSize != 14 || len(items[0].Text) != 5 || items[0].X == 0 {
+			t.Errorf("%dpx: time item = %+v, want a centered HH:MM in 14pt", tt.height, items[0])
+		}
+	}
+}
+
+func TestRAIDPage(t *testing.T) {
+	mdstat := filepath.Join(t.TempDir(), "mdstat")
+	data := "md0 : active raid1 sdb1[1] sda1[0]\n      523264 blocks [2/2] [UU]\n\n" +
+		"md1 : active raid5 sdd1[2] sdc1[1] sdb2[0]\n      1046528 blocks [3/2] [UU_]\n\n"
+	if err := os.WriteFile(mdstat, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	monitor := raid.NewMonitor(mdstat, false)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	monitor.Run(ctx, time.Minute) // a single poll
+
+	ctrl := &Controller{
+		cfg:  &config.Config{},
+		img:  image.NewGray(image.Rect(0, 0, displayWidth, 64)),
+		raid: monitor,
+	}
+	items := (&RAIDPage{ctrl: ctrl}).GetPageText()
+
+	if len(items) != 3 {
+		t.Fatalf("got %d items, want 3: %+v", len(items), items)
+	}
+	if items[1].Text != "md1 DEGRADED" || items[2].Text != "md0 OK" {
+		t.Errorf("rows = %q, %q, want the degraded array first", items[1].Text, items[2].Text)
+	}
+}
+
+func TestUPSRows(t *testing.T) {
+	tests := []struct {
+		name   string
+		status ups.Status
+		ok     bool
+		want   []string
+	}{
+		{"no reading", ups.Status{}, false, []string{"UPS:", "No data"}},
+		{"on mains", ups.Status{Charge: 100, ChargeKnown: true, Runtime: time.Hour}, true, []string{"UPS:", "Charge: 100%", "On mains"}},
+		{
+			"on battery", ups.Status{Charge: 18.4, ChargeKnown: true, OnBattery: true, LowBattery: true, Runtime: 5 * time.Minute}, true,
+			[]string{"UPS:", "Charge: 18%", "On battery LOW", "Runtime: 5m"},
+		},
+		{"no charge", ups.Status{OnBattery: true}, true, []string{"UPS:", "On battery"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows := upsRows(tt.status, tt.ok)
+			if len(rows) != len(tt.want) {
+				t.Fatalf("got %d rows, want %q", len(rows), tt.want)
+			}
+			for i, row := range rows {
+				if row.Cells[0].Text != tt.want[i] {
+					t.Errorf("row %d = %q, want %q", i, row.Cells[0].Text, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestUsageBars(t *testing.T) {
+	rows := usageBars("Usage:", []diskUsage{{label: "/", percent: "45%"}, {label: "Bay 1", percent: "100%"}})
+
+	if len(rows) != 3 || rows[0].Cells[0].Text != "Usage:" {
+		t.Fatalf("rows = %+v, want a title and one bar per mount point", rows)
+	}
+	for i, want := range []struct {
+		label, value string
+		fill         float64
+	}{{"/    ", " 45%", 0.45}, {"Bay 1", "100%", 1}} {
+		cells := rows[i+1].Cells
+		if cells[0].Text != want.label || cells[2].Text != want.value || !cells[1].Bar || cells[1].Fill != want.fill {
+			t.Errorf("row %d = %+v, want %q bar %.2f %q", i+1, cells, want.label, want.fill, want.value)
+		}
+	}
+}
+
+func TestRailRows(t *testing.T) {
+	rows := railRows([]rails.State{
+		{Name: "12v", Good: true, Volts: 12.05, Amps: 1.234, HasCurrent: true},
+		{Name: "5v", Good: false, Volts: 4.61},
+		{Name: "pg", Good: true},
+		{Name: "disks", Good: false, Overcurrent: true, Volts: 5.02, Amps: 3.6, HasCurrent: true},
+	})
+
+	want := []string{"Power:", "12v 12.05V 1.23A", "5v! 4.61V", "pg OK", "disks! 5.02V 3.60A"}
+	if len(rows) != len(want) {
+		t.Fatalf("got %d rows, want %q", len(rows), want)
+	}
+	for i, row := range rows {
+		if row.Cells[0].Text != want[i] {
+			t.Errorf("row %d = %q, want %q", i, row.Cells[0].Text, want[i])
+		}
+	}
+}
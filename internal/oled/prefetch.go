@@ -0,0 +1,72 @@
+package oled
+
+import "time"
+
+// liveOnly is implemented by pages whose text goes stale within one slide, such as
+// clocks; they are always rendered when shown instead of being prefetched
+type liveOnly interface {
+	LiveOnly()
+}
+
+func (p *ClockPage) LiveOnly()      {}
+func (p *LargeClockPage) LiveOnly() {}
+
+// prefetch holds the text of a page collected in the background before it is shown
+type prefetch struct {
+	index int
+	done  chan struct{}
+	items []TextItem
+	took  time.Duration
+}
+
+// startPrefetch collects the text of the page after the current one while the
+// current one is on screen, so slow pages (SMART, exec) switch in without a pause.
+// The caller holds mu.
+func (c *Controller) startPrefetch() {
+	c.prefetched = nil
+	if !c.cfg.OLED.Prefetch || len(c.pages) < 2 {
+		return
+	}
+	index := c.followingPage()
+	page := c.pages[index]
+	if _, live := page.(liveOnly); live {
+		return
+	}
+
+	pf := &prefetch{index: index, done: make(chan struct{})}
+	c.prefetched = pf
+	go func() {
+		defer close(pf.done)
+		pf.items, pf.took = c.collect(page)
+	}()
+}
+
+// takePrefetch returns the prefetch when it is for page index; the caller holds mu
+func (c *Controller) takePrefetch(index int) *prefetch {
+	pf := c.prefetched
+	c.prefetched = nil
+	if pf == nil || pf.index != index {
+		return nil
+	}
+	return pf
+}
+
+// pageText returns the text of page index, from pf when set, waiting for it to
+// finish if needed
+func (c *Controller) pageText(pf *prefetch, index int) ([]TextItem, time.Duration) {
+	if pf != nil {
+		<-pf.done
+		return pf.items, pf.took
+	}
+	return c.collect(c.pages[index])
+}
+
+// collect runs GetPageText; dataMu keeps the prefetch and renders from collecting
+// page data at the same time
+func (c *Controller) collect(page Page) ([]TextItem, time.Duration) {
+	c.dataMu.Lock()
+	defer c.dataMu.Unlock()
+	start := time.Now()
+	items := page.GetPageText()
+	return items, time.Since(start)
+}
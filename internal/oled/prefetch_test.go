@@ -0,0 +1,113 @@
+package oled
+
+import (
+	"image"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kolobock/rockpi-quad-go/internal/config"
+)
+
+type countingPage struct {
+	text  string
+	calls atomic.Int32
+}
+
+func (p *countingPage) GetPageText() []TextItem {
+	p.calls.Add(1)
+	return []TextItem{{Text: p.text}}
+}
+
+func TestPrefetch(t *testing.T) {
+	first, second := &countingPage{text: "first"}, &countingPage{text: "second"}
+	ctrl := &Controller{cfg: &config.Config{OLED: config.OLEDConfig{Prefetch: true}}}
+	ctrl.pages = []Page{first, second, &ClockPage{ctrl: ctrl}}
+
+	ctrl.startPrefetch()
+	if ctrl.prefetched == nil || ctrl.prefetched.index != 1 {
+		t.Fatalf("prefetch = %+v, want page 1", ctrl.prefetched)
+	}
+
+	items, _ := ctrl.pageText(ctrl.takePrefetch(1), 1)
+	if len(items) != 1 || items[0].Text != "second" || second.calls.Load() != 1 {
+		t.Errorf("items = %+v after %d calls, want the prefetched page", items, second.calls.Load())
+	}
+	if ctrl.prefetched != nil {
+		t.Error("prefetch not consumed")
+	}
+
+	// The next page is a clock, which would be stale by the time it is shown
+	ctrl.pageIndex = 1
+	ctrl.startPrefetch()
+	if ctrl.prefetched != nil {
+		t.Errorf("prefetched live page %d", ctrl.prefetched.index)
+	}
+
+	// A prefetch for another page is ignored
+	ctrl.pageIndex = 2
+	ctrl.startPrefetch()
+	pf := ctrl.prefetched
+	ctrl.pageText(ctrl.takePrefetch(1), 1)
+	<-pf.done
+	if first.calls.Load() != 1 || second.calls.Load() != 2 {
+		t.Errorf("calls = %d/%d, want 1/2", first.calls.Load(), second.calls.Load())
+	}
+}
+
+func TestPrefetchDisabled(t *testing.T) {
+	ctrl := &Controller{cfg: &config.Config{}}
+	ctrl.pages = []Page{&countingPage{}, &countingPage{}}
+	ctrl.startPrefetch()
+	if ctrl.prefetched != nil {
+		t.Error("prefetched with prefetch off")
+	}
+}
+
+// rotatingPage shows one of several items, moving on when the page is left
+type rotatingPage struct {
+	countingPage
+	index int
+	shown []int
+}
+
+func (p *rotatingPage) GetPageText() []TextItem {
+	p.shown = append(p.shown, p.index)
+	return p.countingPage.GetPageText()
+}
+
+func (p *rotatingPage) rotate() { p.index++ }
+
+func TestRotatesWhenPageLeft(t *testing.T) {
+	faces, err := loadFonts("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	ips := &rotatingPage{}
+	ctrl := &Controller{
+		cfg:   &config.Config{OLED: config.OLEDConfig{Prefetch: true}},
+		img:   image.NewGray(image.Rect(0, 0, displayWidth, displayHeight)),
+		dev:   &mockSSD1306{},
+		fonts: faces,
+		timer: ticker,
+	}
+	ctrl.pages = []Page{ips, &countingPage{text: "other"}}
+
+	ctrl.showPage(false)
+	// Redraws after a wake and the prefetch of the page while the other is shown
+	// render the same item
+	ctrl.showPage(false)
+	ctrl.nextPage()
+	<-ctrl.prefetched.done
+	ctrl.nextPage()
+	ctrl.nextPage()
+	<-ctrl.prefetched.done
+	ctrl.nextPage()
+
+	if want := []int{0, 0, 1, 2}; !slices.Equal(ips.shown, want) {
+		t.Errorf("items rendered = %v, want %v", ips.shown, want)
+	}
+}
@@ -0,0 +1,94 @@
+package oled
+
+import (
+	"time"
+
+	"github.com/kolobock/rockpi-quad-go/internal/logger"
+)
+
+// slowRenders is how many page switches in a row must exceed the render budget
+// before a page is reported as slow
+const slowRenders = 3
+
+// PageTiming is how long a page takes to build its text and to be sent to the panel
+type PageTiming struct {
+	Index int
+	Name  string
+
+	Renders       int
+	RenderTotal   time.Duration
+	RenderMax     time.Duration
+	Transmits     int
+	TransmitTotal time.Duration
+	TransmitMax   time.Duration
+	// Slow is set while the page exceeds the render budget on every switch
+	Slow bool
+}
+
+// pageProfile records the timings of every page and notices pages that are
+// consistently over budget
+type pageProfile struct {
+	budget  time.Duration
+	timings []PageTiming
+	streaks []int
+	// lastRender is the render time of the page on screen, added to its transmit
+	// time when checking the budget
+	lastRender time.Duration
+}
+
+func newPageProfile(pages []Page, budget time.Duration) *pageProfile {
+	p := &pageProfile{budget: budget, timings: make([]PageTiming, len(pages)), streaks: make([]int, len(pages))}
+	for i, page := range pages {
+		p.timings[i] = PageTiming{Index: i, Name: pageName(page)}
+	}
+	return p
+}
+
+func (p *pageProfile) render(i int, d time.Duration) {
+	t := &p.timings[i]
+	t.Renders++
+	t.RenderTotal += d
+	t.RenderMax = max(t.RenderMax, d)
+	p.lastRender = d
+}
+
+// transmit records the transmit time of a page switch and returns true when the page
+// has now exceeded the budget slowRenders times in a row
+func (p *pageProfile) transmit(i int, d time.Duration) bool {
+	t := &p.timings[i]
+	t.Transmits++
+	t.TransmitTotal += d
+	t.TransmitMax = max(t.TransmitMax, d)
+
+	if p.budget <= 0 || p.lastRender+d <= p.budget {
+		p.streaks[i] = 0
+		t.Slow = false
+		return false
+	}
+	p.streaks[i]++
+	t.Slow = p.streaks[i] >= slowRenders
+	return p.streaks[i] == slowRenders
+}
+
+// PageTimings returns the render and transmit timings of every page
+func (c *Controller) PageTimings() []PageTiming {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.profile == nil {
+		return nil
+	}
+	return append([]PageTiming(nil), c.profile.timings...)
+}
+
+// recordTransmit adds the transmit time of a page switch, warning once when the page
+// has been over budget for slowRenders switches in a row
+func (c *Controller) recordTransmit(d time.Duration) {
+	if c.profile == nil {
+		return
+	}
+	if c.profile.transmit(c.pageIndex, d) {
+		t := c.profile.timings[c.pageIndex]
+		logger.Errorf("OLED page %d (%s) is slow: %v to render and %v to transmit, over the %v budget %d times in a row",
+			c.pageIndex, t.Name, c.profile.lastRender.Round(time.Millisecond), d.Round(time.Millisecond), c.profile.budget, slowRenders)
+	}
+}
@@ -0,0 +1,58 @@
+package oled
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPageProfileSlowStreak(t *testing.T) {
+	p := newPageProfile([]Page{&ClockPage{}, &DiskUsagePage{}}, 100*time.Millisecond)
+	if p.timings[1].Name != "DiskUsage" {
+		t.Fatalf("name = %q, want DiskUsage", p.timings[1].Name)
+	}
+
+	switches := []struct {
+		render, transmit time.Duration
+		wantWarn         bool
+		wantSlow         bool
+	}{
+		{80 * time.Millisecond, 30 * time.Millisecond, false, false},
+		{80 * time.Millisecond, 30 * time.Millisecond, false, false},
+		{20 * time.Millisecond, 30 * time.Millisecond, false, false}, // streak broken
+		{150 * time.Millisecond, 10 * time.Millisecond, false, false},
+		{150 * time.Millisecond, 10 * time.Millisecond, false, false},
+		{150 * time.Millisecond, 10 * time.Millisecond, true, true},
+		{150 * time.Millisecond, 10 * time.Millisecond, false, true}, // warned once per streak
+		{10 * time.Millisecond, 10 * time.Millisecond, false, false},
+	}
+	for i, s := range switches {
+		p.render(1, s.render)
+		if warn := p.transmit(1, s.transmit); warn != s.wantWarn {
+			t.Errorf("switch %d: warn = %v, want %v", i, warn, s.wantWarn)
+		}
+		if p.timings[1].Slow != s.wantSlow {
+			t.Errorf("switch %d: slow = %v, want %v", i, p.timings[1].Slow, s.wantSlow)
+		}
+	}
+
+	got := p.timings[1]
+	if got.Renders != len(switches) || got.Transmits != len(switches) {
+		t.Errorf("renders/transmits = %d/%d, want %d", got.Renders, got.Transmits, len(switches))
+	}
+	if got.RenderMax != 150*time.Millisecond || got.TransmitMax != 30*time.Millisecond {
+		t.Errorf("max = %v/%v, want 150ms/30ms", got.RenderMax, got.TransmitMax)
+	}
+	if p.timings[0].Renders != 0 {
+		t.Errorf("other page renders = %d, want 0", p.timings[0].Renders)
+	}
+}
+
+func TestPageProfileNoBudget(t *testing.T) {
+	p := newPageProfile([]Page{&ClockPage{}}, 0)
+	for range slowRenders + 1 {
+		p.render(0, time.Second)
+		if p.transmit(0, time.Second) {
+			t.Fatal("warned with the budget disabled")
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package oled
+
+import (
+	"image"
+
+	"github.com/kolobock/rockpi-quad-go/internal/logger"
+)
+
+// flipper is implemented by drivers that turn the picture 180° themselves
+type flipper interface {
+	SetFlipped(flipped bool) error
+}
+
+// setRotation applies the [oled] rotate setting to a width x height panel and
+// allocates the image pages are drawn into, upright for the mounted panel: tall for
+// 90° and 270°. 180° is left to the driver when it can flip the panel; other
+// rotations are done in software into a frame allocated once.
+func (c *Controller) setRotation(width, height, degrees int) {
+	c.rotation = degrees
+	if degrees == 180 {
+		if f, ok := c.dev.(flipper); ok {
+			if err := f.SetFlipped(true); err != nil {
+				logger.Errorf("Failed to flip the OLED, rotating in software: %v", err)
+			} else {
+				c.rotation = 0
+			}
+		}
+	}
+
+	panel := image.Rect(0, 0, width, height)
+	switch c.rotation {
+	case 90, 270:
+		c.img = image.NewGray(image.Rect(0, 0, height, width))
+		c.frame = image.NewGray(panel)
+	case 180:
+		c.img = image.NewGray(panel)
+		c.frame = image.NewGray(panel)
+	default:
+		c.img = image.NewGray(panel)
+	}
+}
+
+// rotateInto turns src clockwise by degrees (90, 180 or 270) into dst, which has
+// src's size for 180° and its width and height swapped otherwise
+func rotateInto(dst, src *image.Gray, degrees int) {
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	for y := 0; y < h; y++ {
+		row := src.Pix[y*src.Stride : y*src.Stride+w]
+		for x, v := range row {
+			var dx, dy int
+			switch degrees {
+			case 90:
+				dx, dy = h-1-y, x
+			case 180:
+				dx, dy = w-1-x, h-1-y
+			default:
+				dx, dy = y, w-1-x
+			}
+			dst.Pix[dy*dst.Stride+dx] = v
+		}
+	}
+}
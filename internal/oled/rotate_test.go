@@ -0,0 +1,93 @@
+package oled
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestRotateInto(t *testing.T) {
+	// A 4x2 image with a marked top-left corner
+	src := image.NewGray(image.Rect(0, 0, 4, 2))
+	src.SetGray(0, 0, color.Gray{Y: 255})
+
+	tests := []struct {
+		degrees int
+		size    image.Point
+		corner  image.Point
+	}{
+		{90, image.Pt(2, 4), image.Pt(1, 0)},
+		{180, image.Pt(4, 2), image.Pt(3, 1)},
+		{270, image.Pt(2, 4), image.Pt(0, 3)},
+	}
+	for _, tt := range tests {
+		dst := image.NewGray(image.Rectangle{Max: tt.size})
+		rotateInto(dst, src, tt.degrees)
+		for y := 0; y < tt.size.Y; y++ {
+			for x := 0; x < tt.size.X; x++ {
+				want := uint8(0)
+				if image.Pt(x, y) == tt.corner {
+					want = 255
+				}
+				if got := dst.GrayAt(x, y).Y; got != want {
+					t.Errorf("%d°: pixel at (%d, %d) = %d, want %d", tt.degrees, x, y, got, want)
+				}
+			}
+		}
+	}
+}
+
+// flippingDisplay is a display whose driver can flip the panel
+type flippingDisplay struct {
+	mockSSD1306
+	err     error
+	flipped bool
+}
+
+func (f *flippingDisplay) SetFlipped(flipped bool) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.flipped = flipped
+	return nil
+}
+
+func TestSetRotation(t *testing.T) {
+	tests := []struct {
+		name         string
+		dev          Display
+		degrees      int
+		wantRotation int
+		wantSize     image.Point
+	}{
+		{"none", &mockSSD1306{}, 0, 0, image.Pt(128, 32)},
+		{"flipped by the driver", &flippingDisplay{}, 180, 0, image.Pt(128, 32)},
+		{"driver cannot flip", &mockSSD1306{}, 180, 180, image.Pt(128, 32)},
+		{"flip fails", &flippingDisplay{err: errors.New("i2c")}, 180, 180, image.Pt(128, 32)},
+		{"quarter turn", &flippingDisplay{}, 90, 90, image.Pt(32, 128)},
+		{"three quarters", &mockSSD1306{}, 270, 270, image.Pt(32, 128)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Controller{dev: tt.dev}
+			c.setRotation(128, 32, tt.degrees)
+
+			if c.rotation != tt.wantRotation {
+				t.Errorf("rotation = %d, want %d", c.rotation, tt.wantRotation)
+			}
+			if got := c.img.Rect.Size(); got != tt.wantSize {
+				t.Errorf("image size = %v, want %v", got, tt.wantSize)
+			}
+			if (c.frame != nil) != (c.rotation != 0) {
+				t.Errorf("frame allocated = %v with software rotation %d", c.frame != nil, c.rotation)
+			}
+			if c.frame != nil && c.frame.Rect.Size() != image.Pt(128, 32) {
+				t.Errorf("frame size = %v, want the panel size", c.frame.Rect.Size())
+			}
+			if f, ok := tt.dev.(*flippingDisplay); ok && f.flipped != (tt.degrees == 180 && f.err == nil) {
+				t.Errorf("driver flipped = %v", f.flipped)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package oled
+
+import (
+	"image"
+	"slices"
+	"time"
+
+	"github.com/kolobock/rockpi-quad-go/internal/logger"
+)
+
+const (
+	// scrollInterval is the frame time of scrolling text
+	scrollInterval = 100 * time.Millisecond
+	// scrollPause holds scrolling text at its start before every pass
+	scrollPause = 2 * time.Second
+	// scrollGap is the space between the end of scrolling text and its repeat
+	scrollGap = 24
+)
+
+// scrollOffset returns how many pixels text of width textWidth has moved left after
+// elapsed. Each pass pauses at the start, then moves at speed pixels per second until
+// the repeat that follows scrollGap behind has taken the text's place.
+func scrollOffset(elapsed time.Duration, textWidth int, speed float64) int {
+	cycle := textWidth + scrollGap
+	pass := scrollPause + time.Duration(float64(cycle)/speed*float64(time.Second))
+	moving := elapsed%pass - scrollPause
+	if moving <= 0 {
+		return 0
+	}
+	return min(int(moving.Seconds()*speed), cycle)
+}
+
+// drawScrolling draws text wider than its column at its scroll position, clipped to
+// the column, followed by its repeat
+func (c *Controller) drawScrolling(img *image.Gray, item TextItem, elapsed time.Duration) {
+	bounds := img.Bounds()
+	clip := image.Rect(item.X, bounds.Min.Y, item.X+item.ScrollWidth, bounds.Max.Y).Intersect(bounds)
+	dst, ok := img.SubImage(clip).(*image.Gray)
+	if !ok {
+		return
+	}
+
+	width := c.layout().measure(item.Text, item.FontSize)
+	x := item.X - scrollOffset(elapsed, width, c.cfg.OLED.ScrollSpeed)
+	c.drawTextOn(dst, x, item.Y, item.Text, item.FontSize)
+	c.drawTextOn(dst, x+width+scrollGap, item.Y, item.Text, item.FontSize)
+}
+
+// scrollOffsets returns the scroll position of every scrolling item at now; the
+// caller holds mu
+func (c *Controller) scrollOffsets(now time.Time) []int {
+	if !c.scrolling {
+		return nil
+	}
+	var offsets []int
+	for _, item := range c.items {
+		if item.ScrollWidth > 0 {
+			width := c.layout().measure(item.Text, item.FontSize)
+			offsets = append(offsets, scrollOffset(now.Sub(c.scrollStart), width, c.cfg.OLED.ScrollSpeed))
+		}
+	}
+	return offsets
+}
+
+// scrollFrame redraws the screen when it holds scrolling text that moved since it
+// was drawn, so the pause at the start of every pass sends nothing over the bus
+func (c *Controller) scrollFrame(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.scrolling || c.asleep || slices.Equal(c.scrollOffsets(now), c.scrollShown) {
+		return
+	}
+	c.draw(now)
+	if err := c.display(); err != nil {
+		logger.Errorf("Failed to display scrolling text: %v", err)
+	}
+}
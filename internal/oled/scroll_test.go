@@ -0,0 +1,119 @@
+package oled
+
+import (
+	"image"
+	"testing"
+	"time"
+
+	"github.com/kolobock/rockpi-quad-go/internal/config"
+)
+
+func TestScrollOffset(t *testing.T) {
+	// 176px of text plus the 24px gap at 40px/s: 2s pause, then 5s moving
+	tests := []struct {
+		elapsed time.Duration
+		want    int
+	}{
+		{0, 0},
+		{time.Second, 0},
+		{2 * time.Second, 0},
+		{3 * time.Second, 40},
+		{6500 * time.Millisecond, 180},
+		{7 * time.Second, 0},
+		{10 * time.Second, 40},
+	}
+
+	for _, tt := range tests {
+		if got := scrollOffset(tt.elapsed, 176, 40); got != tt.want {
+			t.Errorf("scrollOffset(%v) = %d, want %d", tt.elapsed, got, tt.want)
+		}
+	}
+}
+
+func TestLayoutScroll(t *testing.T) {
+	long := "fe80::1ff:fe23:4567:890a"
+	rows := []Row{Columns("IP:", long), Line("short")}
+
+	items := Layout{Width: 128, Height: 32, Scroll: true}.Place(rows)
+	if len(items) != 3 {
+		t.Fatalf("Place() returned %d items, want 3: %+v", len(items), items)
+	}
+	if items[1].Text != long || items[1].ScrollWidth != 64 || items[1].X != 64 {
+		t.Errorf("long item = %+v, want the whole text scrolling in the right column", items[1])
+	}
+	if items[0].ScrollWidth != 0 || items[2].ScrollWidth != 0 {
+		t.Errorf("items that fit scroll: %+v", items)
+	}
+
+	if items := (Layout{Width: 128, Height: 32}).Place(rows); items[1].ScrollWidth != 0 || items[1].Text == long {
+		t.Errorf("without Scroll the text is not cut short: %+v", items[1])
+	}
+}
+
+func TestScrollingIsClipped(t *testing.T) {
+	faces, err := loadFonts("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctrl := &Controller{
+		cfg:   &config.Config{OLED: config.OLEDConfig{Scroll: true, ScrollSpeed: 30}},
+		img:   image.NewGray(image.Rect(0, 0, displayWidth, displayHeight)),
+		fonts: faces,
+	}
+	ctrl.message = ctrl.layout().Place([]Row{Columns("IP:", "fe80::1ff:fe23:4567:890a")})
+	ctrl.render()
+
+	if !ctrl.scrolling {
+		t.Fatal("render() did not notice the scrolling text")
+	}
+	for _, elapsed := range []time.Duration{0, 3 * time.Second} {
+		ctrl.draw(ctrl.scrollStart.Add(elapsed))
+		lit := 0
+		for y := 0; y < displayHeight; y++ {
+			for x := 0; x < displayWidth; x++ {
+				if ctrl.img.GrayAt(x, y).Y == 0 {
+					continue
+				}
+				lit++
+				if x >= 24 && x < 64 {
+					t.Fatalf("after %v pixel %d,%d lit between the label and the scrolling column", elapsed, x, y)
+				}
+			}
+		}
+		if lit == 0 {
+			t.Errorf("after %v nothing was drawn", elapsed)
+		}
+	}
+}
+
+func TestScrollFrameOnlyWhenMoved(t *testing.T) {
+	faces, err := loadFonts("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dev := &mockSSD1306{}
+	ctrl := &Controller{
+		cfg:   &config.Config{OLED: config.OLEDConfig{Scroll: true, ScrollSpeed: 30}},
+		img:   image.NewGray(image.Rect(0, 0, displayWidth, displayHeight)),
+		dev:   dev,
+		fonts: faces,
+	}
+	ctrl.message = ctrl.layout().Place([]Row{Columns("IP:", "fe80::1ff:fe23:4567:890a")})
+	ctrl.scrollStart = time.Now()
+	ctrl.render()
+
+	// Nothing moves during the pause at the start of the pass
+	for _, elapsed := range []time.Duration{100 * time.Millisecond, time.Second, scrollPause} {
+		ctrl.scrollFrame(ctrl.scrollStart.Add(elapsed))
+	}
+	if len(dev.displayCalls) != 0 {
+		t.Errorf("sent %d frames while the text was paused", len(dev.displayCalls))
+	}
+
+	moving := ctrl.scrollStart.Add(scrollPause + time.Second)
+	ctrl.scrollFrame(moving)
+	ctrl.scrollFrame(moving.Add(10 * time.Millisecond))
+	if len(dev.displayCalls) != 1 {
+		t.Errorf("sent %d frames, want one for the text that moved", len(dev.displayCalls))
+	}
+}
@@ -0,0 +1,137 @@
+package oled
+
+import (
+	"fmt"
+	"image"
+	"slices"
+	"time"
+
+	"github.com/kolobock/rockpi-quad-go/internal/config"
+)
+
+// testPattern is one screen of the OLED self-test, drawn into the image at rotation
+type testPattern struct {
+	name     string
+	rotation int
+	draw     func(c *Controller)
+}
+
+// selfTestPatterns returns the screens of the self-test: solid and checkered fills to
+// find dead pixels and columns, a border to check the panel offset, a sample of every
+// font size and a marker for every rotation
+func (c *Controller) selfTestPatterns() []testPattern {
+	patterns := []testPattern{
+		{name: "all on", draw: func(c *Controller) { fillRect(c.img, c.img.Bounds(), pixelOn) }},
+		{name: "checkerboard", draw: func(c *Controller) { checkerboard(c.img, 4, false) }},
+		{name: "inverted checkerboard", draw: func(c *Controller) { checkerboard(c.img, 4, true) }},
+		{name: "border", draw: func(c *Controller) { strokeRect(c.img, c.img.Bounds(), pixelOn) }},
+	}
+
+	sizes := make([]int, 0, len(c.fonts))
+	for size := range c.fonts {
+		sizes = append(sizes, size)
+	}
+	slices.Sort(sizes)
+	for _, size := range sizes {
+		patterns = append(patterns, testPattern{
+			name: fmt.Sprintf("font %dpt", size),
+			draw: func(c *Controller) {
+				c.drawRows([]Row{Centered(fmt.Sprintf("%dpt AaZz", size), size), Centered("0189 °C %", size)})
+			},
+		})
+	}
+
+	for _, degrees := range []int{0, 90, 180, 270} {
+		patterns = append(patterns, testPattern{
+			name:     fmt.Sprintf("rotation %d°", degrees),
+			rotation: degrees,
+			draw: func(c *Controller) {
+				c.drawRows([]Row{Centered("^", 14), Centered(fmt.Sprintf("%d°", degrees), 12)})
+			},
+		})
+	}
+	return patterns
+}
+
+// checkerboard fills img with squares of cell pixels, starting with a lit square in
+// the top-left corner unless inverted
+func checkerboard(img *image.Gray, cell int, inverted bool) {
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if ((x/cell+y/cell)%2 == 0) != inverted {
+				img.SetGray(x, y, pixelOn)
+			}
+		}
+	}
+}
+
+// drawRows lays rows out on the image and draws them
+func (c *Controller) drawRows(rows []Row) {
+	for _, item := range c.layout().Place(rows) {
+		c.drawText(item.X, item.Y, item.Text, item.FontSize)
+	}
+}
+
+// runSelfTest shows every test pattern for hold, calling step with its name before
+// it is drawn, and ends on a confirmation in the configured rotation
+func (c *Controller) runSelfTest(hold time.Duration, step func(name string)) error {
+	width, height := displayWidth, panelHeight(c.cfg)
+	rotate := func(degrees int) error {
+		// setRotation only ever flips the panel, so undo the 180° step first
+		if f, ok := c.dev.(flipper); ok {
+			if err := f.SetFlipped(false); err != nil {
+				return err
+			}
+		}
+		c.setRotation(width, height, degrees)
+		return nil
+	}
+
+	for _, p := range c.selfTestPatterns() {
+		step(p.name)
+		if err := rotate(p.rotation); err != nil {
+			return fmt.Errorf("%s: %w", p.name, err)
+		}
+		c.clearImage()
+		p.draw(c)
+		if err := c.display(); err != nil {
+			return fmt.Errorf("%s: %w", p.name, err)
+		}
+		time.Sleep(hold)
+	}
+
+	if err := rotate(c.cfg.OLED.Rotate); err != nil {
+		return err
+	}
+	c.showLines("OLED test|OK")
+	return c.display()
+}
+
+// SelfTest opens the display, cycles through the test patterns and leaves a
+// confirmation on screen. It returns the I2C write counters, which tell a flaky
+// connection from a dead panel when the test fails.
+func SelfTest(cfg *config.Config, hold time.Duration, step func(name string)) (BusStats, error) {
+	dev, err := NewSSD1306(cfg.Env.I2CBus, displayWidth, panelHeight(cfg), resetPin(cfg))
+	if err != nil {
+		return BusStats{}, fmt.Errorf("failed to create SSD1306 display: %w", err)
+	}
+	fonts, err := loadFonts(cfg.OLED.Font)
+	if err != nil {
+		dev.Close()
+		return BusStats{}, err
+	}
+
+	c := &Controller{
+		cfg:   cfg,
+		dev:   dev,
+		fonts: fonts,
+	}
+	err = c.runSelfTest(hold, step)
+	stats := dev.Stats()
+	if err != nil {
+		dev.Close()
+		return stats, err
+	}
+	return stats, dev.Release()
+}
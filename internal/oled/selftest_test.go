@@ -0,0 +1,66 @@
+package oled
+
+import (
+	"image"
+	"testing"
+
+	"github.com/kolobock/rockpi-quad-go/internal/config"
+)
+
+// recordingDisplay keeps a copy of every frame and whether the panel was flipped
+type recordingDisplay struct {
+	mockSSD1306
+	frames  []*image.Gray
+	flips   []bool
+	flipped bool
+}
+
+func (r *recordingDisplay) Display(img *image.Gray) error {
+	frame := image.NewGray(img.Rect)
+	copy(frame.Pix, img.Pix)
+	r.frames = append(r.frames, frame)
+	r.flips = append(r.flips, r.flipped)
+	return nil
+}
+
+func (r *recordingDisplay) SetFlipped(flipped bool) error {
+	r.flipped = flipped
+	return nil
+}
+
+func TestRunSelfTest(t *testing.T) {
+	faces, err := loadFonts("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dev := &recordingDisplay{}
+	c := &Controller{cfg: &config.Config{}, dev: dev, fonts: faces}
+
+	var steps []string
+	if err := c.runSelfTest(0, func(name string) { steps = append(steps, name) }); err != nil {
+		t.Fatalf("runSelfTest() = %v", err)
+	}
+
+	// Four fills, one sample per font size, four rotations and the confirmation
+	if want := 4 + len(faces) + 4; len(steps) != want || len(dev.frames) != want+1 {
+		t.Fatalf("%d steps and %d frames, want %d and %d: %q", len(steps), len(dev.frames), want, want+1, steps)
+	}
+	if all := dev.frames[0]; all.GrayAt(0, 0) != pixelOn || all.GrayAt(displayWidth-1, displayHeight-1) != pixelOn {
+		t.Errorf("%s: corners not lit", steps[0])
+	}
+	if checker := dev.frames[1]; checker.GrayAt(0, 0) != pixelOn || checker.GrayAt(4, 0) != pixelOff {
+		t.Errorf("%s: want a lit first square next to a dark one", steps[1])
+	}
+	if inverted := dev.frames[2]; inverted.GrayAt(0, 0) != pixelOff || inverted.GrayAt(4, 0) != pixelOn {
+		t.Errorf("%s: want the checkerboard inverted", steps[2])
+	}
+
+	for i, name := range steps {
+		if name == "rotation 180°" && !dev.flips[i] {
+			t.Errorf("%s sent without flipping the panel", name)
+		}
+	}
+	if last := len(dev.flips) - 1; dev.flips[last] {
+		t.Error("confirmation shown flipped, want the configured rotation restored")
+	}
+}
@@ -0,0 +1,39 @@
+package oled
+
+import (
+	"time"
+
+	"github.com/kolobock/rockpi-quad-go/internal/logger"
+)
+
+// sleepIfIdle turns the panel off once nothing has happened for [oled] sleep_after
+func (c *Controller) sleepIfIdle(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	idle := time.Duration(c.cfg.OLED.SleepAfter) * time.Second
+	if idle <= 0 || c.asleep || now.Sub(c.lastActive) < idle {
+		return
+	}
+	logger.Infof("Display idle for %v, turning it off", idle)
+	if err := c.dev.SetDisplayOn(false); err != nil {
+		logger.Errorf("Failed to turn off display: %v", err)
+		return
+	}
+	c.asleep = true
+}
+
+// wakeLocked records activity and turns a sleeping panel back on, reporting whether it
+// was asleep; the caller holds mu and redraws the display
+func (c *Controller) wakeLocked() bool {
+	c.lastActive = time.Now()
+	if !c.asleep {
+		return false
+	}
+	c.asleep = false
+	logger.Infoln("Display woken")
+	if err := c.dev.SetDisplayOn(true); err != nil {
+		logger.Errorf("Failed to turn on display: %v", err)
+	}
+	return true
+}
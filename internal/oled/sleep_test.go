@@ -0,0 +1,51 @@
+package oled
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kolobock/rockpi-quad-go/internal/config"
+)
+
+func TestSleepAndWake(t *testing.T) {
+	dev := &mockSSD1306{on: true}
+	c := newTestController()
+	c.dev = dev
+	c.cfg = &config.Config{OLED: config.OLEDConfig{SleepAfter: 60}}
+	c.lastActive = time.Now()
+
+	c.sleepIfIdle(c.lastActive.Add(30 * time.Second))
+	if c.asleep || !dev.on {
+		t.Fatal("display slept before sleep_after")
+	}
+
+	c.sleepIfIdle(c.lastActive.Add(61 * time.Second))
+	if !c.asleep || dev.on {
+		t.Fatal("display still on after sleep_after")
+	}
+
+	c.nextPage()
+	if len(dev.displayCalls) != 0 {
+		t.Error("page drawn while the display sleeps")
+	}
+
+	if !c.NotifyBtnPress() {
+		t.Error("first press on a sleeping display did not report waking it")
+	}
+	if c.asleep || !dev.on || len(dev.displayCalls) != 1 {
+		t.Errorf("after wake asleep = %v, on = %v, draws = %d; want awake and redrawn", c.asleep, dev.on, len(dev.displayCalls))
+	}
+	if c.NotifyBtnPress() {
+		t.Error("press on an awake display reported waking it")
+	}
+}
+
+func TestSleepDisabled(t *testing.T) {
+	c := newTestController()
+	c.lastActive = time.Now().Add(-time.Hour)
+
+	c.sleepIfIdle(time.Now())
+	if c.asleep {
+		t.Error("display slept with sleep_after = 0")
+	}
+}
@@ -0,0 +1,45 @@
+package oled
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/kolobock/rockpi-quad-go/internal/config"
+)
+
+// splashItems lays out the boot splash: the message and the hostname, centered
+func (c *Controller) splashItems(text string) []TextItem {
+	host, _ := os.Hostname()
+	host = formatHostname(host, c.cfg.OLED.Hostname == hostnameMDNS)
+	return c.layout().Place([]Row{Centered(text, 14), Centered(host, 12)})
+}
+
+// Splash shows text and the hostname during early boot, then releases the panel
+// without turning it off, so the message stays up until the daemon initializes it
+func Splash(cfg *config.Config, text string) error {
+	dev, err := NewSSD1306(cfg.Env.I2CBus, displayWidth, panelHeight(cfg), resetPin(cfg))
+	if err != nil {
+		return fmt.Errorf("failed to create SSD1306 display: %w", err)
+	}
+	fonts, err := loadFonts(cfg.OLED.Font)
+	if err != nil {
+		dev.Close()
+		return err
+	}
+
+	c := &Controller{
+		cfg:   cfg,
+		dev:   dev,
+		fonts: fonts,
+	}
+	c.setRotation(displayWidth, panelHeight(cfg), cfg.OLED.Rotate)
+	c.clearImage()
+	for _, item := range c.splashItems(text) {
+		c.drawText(item.X, item.Y, item.Text, item.FontSize)
+	}
+	if err := c.display(); err != nil {
+		dev.Close()
+		return fmt.Errorf("failed to show splash: %w", err)
+	}
+	return dev.Release()
+}
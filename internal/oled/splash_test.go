@@ -0,0 +1,27 @@
+package oled
+
+import (
+	"image"
+	"strings"
+	"testing"
+
+	"github.com/kolobock/rockpi-quad-go/internal/config"
+)
+
+func TestSplashItems(t *testing.T) {
+	ctrl := &Controller{
+		cfg: &config.Config{OLED: config.OLEDConfig{Hostname: hostnameMDNS}},
+		img: image.NewGray(image.Rect(0, 0, displayWidth, 32)),
+	}
+
+	items := ctrl.splashItems("Booting…")
+	if len(items) != 2 {
+		t.Fatalf("got %d items, want 2: %+v", len(items), items)
+	}
+	if items[0].Text != "Booting…" || items[0].FontSize != 14 || items[0].X == 0 {
+		t.Errorf("message = %+v, want a centered 14pt Booting…", items[0])
+	}
+	if items[1].FontSize != 12 || !strings.HasSuffix(items[1].Text, ".local") {
+		t.Errorf("hostname = %+v, want the mDNS hostname in 12pt", items[1])
+	}
+}
@@ -1,17 +1,21 @@
 package oled
 
 import (
+	"errors"
 	"fmt"
 	"image"
 	"os"
 	"strconv"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	i2c "github.com/d2r2/go-i2c"
 	i2cl "github.com/d2r2/go-logger"
 	"github.com/warthog618/go-gpiocdev"
 
+	"github.com/kolobock/rockpi-quad-go/internal/diag"
 	"github.com/kolobock/rockpi-quad-go/internal/logger"
 )
 
@@ -47,34 +51,103 @@ const (
 	ssd1306I2CAddr = 0x3C
 )
 
+// Write recovery: a failed write is retried, reopening the bus when the device
+// dropped off it; after ssd1306MaxFailures writes in a row failed all retries the
+// display is given up as unavailable
+const (
+	ssd1306WriteAttempts = 3
+	ssd1306MaxFailures   = 10
+)
+
+// ssd1306RetryDelay is replaced in tests
+var ssd1306RetryDelay = 5 * time.Millisecond
+
+// ErrDisplayUnavailable is returned by every write once the display has failed
+// ssd1306MaxFailures writes in a row
+var ErrDisplayUnavailable = errors.New("display unavailable")
+
+// i2cDevice is the part of an I2C connection the driver uses
+type i2cDevice interface {
+	WriteBytes(b []byte) (int, error)
+	Close() error
+}
+
+// openI2C is replaced in tests
+var openI2C = func(addr uint8, bus int) (i2cDevice, error) {
+	dev, err := i2c.NewI2C(addr, bus)
+	if err != nil {
+		return nil, err
+	}
+	return dev, nil
+}
+
+// BusStats are the I2C write counters of the display
+type BusStats struct {
+	// Available is false once the display was given up after repeated failures
+	Available   bool
+	WriteErrors uint64
+	Retries     uint64
+	Reopens     uint64
+	// ConsecutiveFailures counts writes in a row that failed all their retries
+	ConsecutiveFailures int
+}
+
 // SSD1306 represents an SSD1306 OLED display driver
 type SSD1306 struct {
-	i2c    *i2c.I2C
+	i2c    i2cDevice
+	bus    int
 	width  int
 	height int
 	buffer []byte
+	// shown is what the panel RAM holds, nil when unknown
+	shown []byte
+
+	// mu guards the connection, the counters and the panel settings below, which
+	// are sent again when the panel comes back from a bus loss
+	mu    sync.Mutex
+	stats BusStats
+	// flipped turns the picture 180° through the scan directions
+	flipped  bool
+	contrast byte
+	off      bool
+	// reinitialized is set when the panel was set up again after a reopen, which
+	// leaves its RAM unknown
+	reinitialized bool
+}
+
+// ResetPin is the GPIO wired to the panel's reset input; an empty Line means none
+type ResetPin struct {
+	// Chip is the gpiochip name or number, gpiochip0 when empty
+	Chip string
+	// Line is the GPIO, e.g. "D23" or "23"
+	Line string
 }
 
-// NewSSD1306 creates a new SSD1306 driver instance
-func NewSSD1306(width, height int) (*SSD1306, error) {
+// NewSSD1306 creates a new SSD1306 driver instance on the given I2C bus, pulsing
+// its reset line first when one is wired
+func NewSSD1306(bus, width, height int, reset ResetPin) (*SSD1306, error) {
 	if err := i2cl.ChangePackageLogLevel("i2c", i2cl.InfoLevel); err != nil {
 		logger.Infof("Failed to change i2c log level: %v", err)
 	}
 
-	i2cBus, err := i2c.NewI2C(ssd1306I2CAddr, 1)
+	i2cBus, err := openI2C(ssd1306I2CAddr, bus)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open I2C: %w", err)
 	}
 
 	d := &SSD1306{
 		i2c:    i2cBus,
+		bus:    bus,
 		width:  width,
 		height: height,
 		buffer: make([]byte, width*height/8),
+		stats:  BusStats{Available: true},
+		// 0x8F from tinygo, 0xFF was
+		contrast: 0x8F,
 	}
 	logger.Infof("[SSD1306] Initialized %dx%d display, buffer size: %d bytes", width, height, len(d.buffer))
 
-	if err := d.reset(); err != nil {
+	if err := reset.pulse(); err != nil {
 		i2cBus.Close()
 		return nil, fmt.Errorf("failed to reset SSD1306: %w", err)
 	}
@@ -87,27 +160,34 @@ func NewSSD1306(width, height int) (*SSD1306, error) {
 	return d, nil
 }
 
-// reset performs a hardware reset of the SSD1306 display using GPIO
-func (d *SSD1306) reset() error {
-	chip, err := gpiocdev.NewChip("gpiochip0")
-	if err != nil {
-		return fmt.Errorf("cannot open gpiochip0: %w", err)
+// chipName returns the gpiochip name of the reset pin, e.g. "gpiochip4" for "4"
+func (r ResetPin) chipName() string {
+	if r.Chip == "" {
+		return "gpiochip0"
 	}
-	defer chip.Close()
+	if _, err := strconv.Atoi(r.Chip); err == nil {
+		return "gpiochip" + r.Chip
+	}
+	return r.Chip
+}
 
-	resetPin := os.Getenv("OLED_RESET") // "D23"
-	if resetPin == "" {
+// pulse performs a hardware reset of the SSD1306 display using GPIO; without a
+// reset line no chip is opened
+func (r ResetPin) pulse() error {
+	if r.Line == "" {
 		return nil
 	}
-
-	resetPin = strings.ToLower(resetPin)
-	resetPin = strings.TrimPrefix(resetPin, "d")
-
-	pinNum, err := strconv.Atoi(resetPin)
+	pinNum, err := strconv.Atoi(strings.TrimPrefix(strings.ToLower(r.Line), "d"))
 	if err != nil {
 		return fmt.Errorf("invalid OLED_RESET pin: %w", err)
 	}
 
+	chip, err := gpiocdev.NewChip(r.chipName())
+	if err != nil {
+		return fmt.Errorf("cannot open %s: %w", r.chipName(), err)
+	}
+	defer chip.Close()
+
 	line, err := chip.RequestLine(pinNum, gpiocdev.AsOutput(0))
 	if err != nil {
 		return fmt.Errorf("cannot request gpio line: %w", err)
@@ -127,6 +207,20 @@ func (d *SSD1306) reset() error {
 
 // init initializes the SSD1306 display with proper configuration
 func (d *SSD1306) init() error {
+	d.mu.Lock()
+	cmds := d.setupCmds()
+	d.mu.Unlock()
+	for _, cmd := range cmds {
+		if err := d.writeCmd(cmd); err != nil {
+			return err
+		}
+	}
+	return d.Clear()
+}
+
+// setupCmds returns the commands that configure the panel with the current
+// orientation, contrast and power state. Called with mu held.
+func (d *SSD1306) setupCmds() []byte {
 	cmds := []byte{
 		ssd1306DisplayOff,
 		ssd1306MemoryMode, 0x00, // 0x00 from tinygo, 0x02 working but stops with some time
@@ -134,9 +228,8 @@ func (d *SSD1306) init() error {
 		ssd1306SetMultiplex, byte(d.height - 1),
 		ssd1306SetDisplayOffset, 0x00,
 		ssd1306SetStartLine,
-		ssd1306SegRemap | 0x01,
-		ssd1306ComScanDec,
 	}
+	cmds = append(cmds, d.scanDirection()...)
 
 	switch d.height {
 	case 32:
@@ -148,67 +241,153 @@ func (d *SSD1306) init() error {
 	cmds = append(cmds,
 		ssd1306SetPrecharge, 0xF1,
 		ssd1306SetVcomDetect, 0x40,
-		ssd1306SetContrast, 0x8F, // 0x8F from tinygo, 0xFF was
+		ssd1306SetContrast, d.contrast,
 		ssd1306DisplayAllOnResume,
 		ssd1306NormalDisplay,
 		ssd1306DeactivateScroll,
 		ssd1306ChargePump, 0x14,
 	)
+	if d.off {
+		return cmds
+	}
+	return append(cmds, ssd1306DisplayOn)
+}
 
-	for _, cmd := range cmds {
-		if err := d.writeCmd(cmd); err != nil {
-			return err
-		}
+// scanDirection returns the segment remap and COM scan commands of the orientation
+func (d *SSD1306) scanDirection() []byte {
+	if d.flipped {
+		return []byte{ssd1306SegRemap, ssd1306ComScanInc}
 	}
+	return []byte{ssd1306SegRemap | 0x01, ssd1306ComScanDec}
+}
 
-	if err := d.writeCmd(ssd1306DisplayOn); err != nil {
+// SetFlipped turns the picture 180° in the controller, for panels mounted upside
+// down, instead of rotating every frame before it is sent
+func (d *SSD1306) SetFlipped(flipped bool) error {
+	d.mu.Lock()
+	d.flipped = flipped
+	cmds := append([]byte{0x00}, d.scanDirection()...)
+	d.mu.Unlock()
+	if err := d.write(cmds); err != nil {
 		return err
 	}
-
-	return d.Clear()
+	// The segment remap only applies to data written from now on, so the next
+	// frame is sent whole
+	d.shown = nil
+	return nil
 }
 
 // writeCmd sends a command byte to the display
 func (d *SSD1306) writeCmd(cmd byte) error {
-	_, err := d.i2c.WriteBytes([]byte{0x00, cmd})
-	return err
+	return d.write([]byte{0x00, cmd})
 }
 
-// Display updates the OLED display with the contents of the image
-func (d *SSD1306) Display(img *image.Gray) error {
-	for page := 0; page < d.height/8; page++ {
-		for x := 0; x < d.width; x++ {
-			var b byte
-			for bit := 0; bit < 8; bit++ {
-				y := page*8 + bit
-				if img.GrayAt(x, y).Y > 128 {
-					b |= (1 << bit)
-				}
-			}
-			d.buffer[page*d.width+x] = b
-		}
+// write sends one or more writes to the display as a unit, retrying them from the
+// first when any fails and reopening the bus when the device dropped off it. A
+// retried data write only lands right after its address window was sent again.
+func (d *SSD1306) write(msgs ...[]byte) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.stats.Available {
+		return ErrDisplayUnavailable
 	}
-	for page := 0; page < d.height/8; page++ {
-		if err := d.writeCmd(0xB0 | byte(page)); err != nil {
-			return err
+
+	var err error
+	for attempt := 1; attempt <= ssd1306WriteAttempts; attempt++ {
+		if attempt > 1 {
+			d.stats.Retries++
+			time.Sleep(ssd1306RetryDelay)
+			if busLost(err) {
+				d.reopen()
+			}
 		}
-		if err := d.writeCmd(ssd1306SetLowColumn); err != nil {
-			return err
+		if err = d.send(msgs); err == nil {
+			d.stats.ConsecutiveFailures = 0
+			return nil
 		}
-		if err := d.writeCmd(ssd1306SetHighColumn); err != nil {
+		diag.I2CWriteErrors.Add(1)
+		d.stats.WriteErrors++
+	}
+
+	d.stats.ConsecutiveFailures++
+	if d.stats.ConsecutiveFailures >= ssd1306MaxFailures {
+		d.stats.Available = false
+		return fmt.Errorf("%w after %d failed writes: %w", ErrDisplayUnavailable, d.stats.ConsecutiveFailures, err)
+	}
+	return err
+}
+
+// send writes msgs in order, stopping at the first failure
+func (d *SSD1306) send(msgs [][]byte) error {
+	for _, b := range msgs {
+		if _, err := d.i2c.WriteBytes(b); err != nil {
 			return err
 		}
+	}
+	return nil
+}
 
-		pageData := make([]byte, d.width+1)
-		pageData[0] = 0x40
-		copy(pageData[1:], d.buffer[page*d.width:(page+1)*d.width])
+// busLost reports whether a write failed because the device or the adapter went
+// away, which a fresh file descriptor may recover from
+func busLost(err error) bool {
+	return errors.Is(err, syscall.EIO) || errors.Is(err, syscall.ENXIO) || errors.Is(err, os.ErrClosed)
+}
 
-		if _, err := d.i2c.WriteBytes(pageData); err != nil {
-			return err
+// reopen replaces the I2C connection; the old one is kept when the bus cannot be
+// opened, so the next attempt tries again. A panel that browned out comes back
+// unconfigured and dark, so it is set up again on the new connection.
+func (d *SSD1306) reopen() {
+	dev, err := openI2C(ssd1306I2CAddr, d.bus)
+	if err != nil {
+		logger.Infof("[SSD1306] Failed to reopen I2C bus %d: %v", d.bus, err)
+		return
+	}
+	if err := d.i2c.Close(); err != nil {
+		logger.Infof("[SSD1306] Failed to close I2C bus %d: %v", d.bus, err)
+	}
+	d.i2c = dev
+	d.stats.Reopens++
+
+	for _, cmd := range d.setupCmds() {
+		if _, err := d.i2c.WriteBytes([]byte{0x00, cmd}); err != nil {
+			logger.Infof("[SSD1306] Failed to set up the display again: %v", err)
+			break
 		}
 	}
+	d.reinitialized = true
+}
 
-	return nil
+// takeReinitialized reports whether the panel was set up again since the last call
+func (d *SSD1306) takeReinitialized() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	r := d.reinitialized
+	d.reinitialized = false
+	return r
+}
+
+// Stats returns the I2C write counters
+func (d *SSD1306) Stats() BusStats {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.stats
+}
+
+// Display updates the OLED display with the contents of the image, sending only
+// the part that changed since the last update
+func (d *SSD1306) Display(img *image.Gray) error {
+	for page := 0; page < d.height/8; page++ {
+		for x := 0; x < d.width; x++ {
+			var v byte
+			for bit := 0; bit < 8; bit++ {
+				if img.Pix[img.PixOffset(x, page*8+bit)] > 128 {
+					v |= 1 << bit
+				}
+			}
+			d.buffer[page*d.width+x] = v
+		}
+	}
+	return d.flush()
 }
 
 // Clear clears the display (turns all pixels off)
@@ -216,29 +395,82 @@ func (d *SSD1306) Clear() error {
 	for i := range d.buffer {
 		d.buffer[i] = 0
 	}
+	// Whatever the panel showed before is unknown, e.g. after a restart
+	d.shown = nil
+	return d.flush()
+}
 
-	zeroPage := make([]byte, d.width+1)
-	zeroPage[0] = 0x40
+// flush sends the rectangle of display RAM that differs from what the panel shows
+// in one transaction, using the address window of horizontal addressing mode
+func (d *SSD1306) flush() error {
+	r, dirty := dirtyRegion(d.shown, d.buffer, d.width, d.height/8)
+	if !dirty {
+		return nil
+	}
 
-	for page := 0; page < d.height/8; page++ {
-		if err := d.writeCmd(0xB0 | byte(page)); err != nil {
-			return err
-		}
-		if err := d.writeCmd(ssd1306SetLowColumn); err != nil {
-			return err
-		}
-		if err := d.writeCmd(ssd1306SetHighColumn); err != nil {
-			return err
-		}
-		if _, err := d.i2c.WriteBytes(zeroPage); err != nil {
-			return err
-		}
+	cmds := []byte{
+		0x00,
+		ssd1306ColumnAddr, byte(r.firstCol), byte(r.lastCol),
+		ssd1306PageAddr, byte(r.firstPage), byte(r.lastPage),
+	}
+	data := make([]byte, 1, 1+(r.lastCol-r.firstCol+1)*(r.lastPage-r.firstPage+1))
+	data[0] = 0x40
+	for page := r.firstPage; page <= r.lastPage; page++ {
+		data = append(data, d.buffer[page*d.width+r.firstCol:page*d.width+r.lastCol+1]...)
+	}
+
+	// The window and the data are retried together, a partly sent frame resent on
+	// its own would land wherever the RAM pointer stopped
+	if err := d.write(cmds, data); err != nil {
+		// A partly written frame leaves the panel RAM unknown
+		d.shown = nil
+		return err
+	}
+	if d.takeReinitialized() && d.shown != nil {
+		// Only the changed part reached a panel whose RAM was lost, send it whole
+		d.shown = nil
+		return d.flush()
 	}
+	if d.shown == nil {
+		d.shown = make([]byte, len(d.buffer))
+	}
+	copy(d.shown, d.buffer)
 	return nil
 }
 
+// region is a rectangle of display RAM, in pages of 8 rows and columns
+type region struct {
+	firstPage, lastPage int
+	firstCol, lastCol   int
+}
+
+// dirtyRegion returns the smallest rectangle holding every byte of next that differs
+// from prev; a nil prev marks the whole display dirty
+func dirtyRegion(prev, next []byte, width, pages int) (region, bool) {
+	if prev == nil {
+		return region{lastPage: pages - 1, lastCol: width - 1}, true
+	}
+	r := region{firstPage: pages, lastPage: -1, firstCol: width, lastCol: -1}
+	for page := 0; page < pages; page++ {
+		for col := 0; col < width; col++ {
+			i := page*width + col
+			if prev[i] == next[i] {
+				continue
+			}
+			r.firstPage = min(r.firstPage, page)
+			r.lastPage = max(r.lastPage, page)
+			r.firstCol = min(r.firstCol, col)
+			r.lastCol = max(r.lastCol, col)
+		}
+	}
+	return r, r.lastPage >= 0
+}
+
 // SetContrast sets the display contrast (0-255)
 func (d *SSD1306) SetContrast(contrast byte) error {
+	d.mu.Lock()
+	d.contrast = contrast
+	d.mu.Unlock()
 	if err := d.writeCmd(ssd1306SetContrast); err != nil {
 		return err
 	}
@@ -247,16 +479,29 @@ func (d *SSD1306) SetContrast(contrast byte) error {
 
 // SetDisplayOn turns the display on or off
 func (d *SSD1306) SetDisplayOn(on bool) error {
+	d.mu.Lock()
+	d.off = !on
+	d.mu.Unlock()
 	if on {
 		return d.writeCmd(ssd1306DisplayOn)
 	}
 	return d.writeCmd(ssd1306DisplayOff)
 }
 
+// Release closes the I2C connection but leaves the display on, so the last image stays
+// on screen for the next process that opens the panel
+func (d *SSD1306) Release() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.i2c.Close()
+}
+
 // Close closes the I2C connection and turns off the display
 func (d *SSD1306) Close() error {
 	if err := d.SetDisplayOn(false); err != nil {
 		logger.Errorf("Failed to turn off display: %v", err)
 	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
 	return d.i2c.Close()
 }
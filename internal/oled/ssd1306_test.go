@@ -0,0 +1,349 @@
+package oled
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// fakeI2C fails its first failures writes with err, and write number failNth
+type fakeI2C struct {
+	failures int
+	failNth  int
+	err      error
+	writes   int
+	closed   bool
+	// sent are the successful writes
+	sent [][]byte
+}
+
+func (f *fakeI2C) WriteBytes(b []byte) (int, error) {
+	f.writes++
+	if f.failures > 0 {
+		f.failures--
+		return 0, f.err
+	}
+	if f.writes == f.failNth {
+		return 0, f.err
+	}
+	f.sent = append(f.sent, append([]byte(nil), b...))
+	return len(b), nil
+}
+
+func (f *fakeI2C) Close() error {
+	f.closed = true
+	return nil
+}
+
+func TestSSD1306Write(t *testing.T) {
+	ssd1306RetryDelay = 0
+	defer func() { ssd1306RetryDelay = 5 * time.Millisecond }()
+	eio := &os.PathError{Op: "write", Path: "/dev/i2c-7", Err: syscall.EIO}
+	realOpen := openI2C
+	defer func() { openI2C = realOpen }()
+
+	tests := []struct {
+		name        string
+		failures    int
+		err         error
+		wantErr     bool
+		wantWrites  int
+		wantReopens uint64
+	}{
+		{name: "ok", wantWrites: 1},
+		{name: "transient error retried", failures: 1, err: syscall.EREMOTEIO, wantWrites: 2},
+		{name: "bus error reopens", failures: 1, err: eio, wantWrites: 1, wantReopens: 1},
+		{name: "retries exhausted", failures: 5, err: syscall.EREMOTEIO, wantErr: true, wantWrites: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dev := &fakeI2C{failures: tt.failures, err: tt.err}
+			// A reopened bus works
+			fresh := &fakeI2C{}
+			openI2C = func(uint8, int) (i2cDevice, error) { return fresh, nil }
+
+			d := &SSD1306{i2c: dev, stats: BusStats{Available: true}}
+			err := d.write([]byte{0x00, ssd1306DisplayOn})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("write() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			st := d.Stats()
+			if st.Reopens != tt.wantReopens {
+				t.Errorf("Reopens = %d, want %d", st.Reopens, tt.wantReopens)
+			}
+			if writes := dev.writes + fresh.writes; tt.wantReopens == 0 && writes != tt.wantWrites {
+				t.Errorf("writes = %d, want %d", writes, tt.wantWrites)
+			}
+			// The reopened panel is set up before the write is sent again
+			if setup := len(d.setupCmds()); tt.wantReopens > 0 && (!dev.closed || fresh.writes != setup+tt.wantWrites) {
+				t.Errorf("old bus closed = %v, writes on the new bus = %d, want closed and %d", dev.closed, fresh.writes, setup+tt.wantWrites)
+			}
+			if st.WriteErrors != uint64(min(tt.failures, ssd1306WriteAttempts)) {
+				t.Errorf("WriteErrors = %d, want %d", st.WriteErrors, min(tt.failures, ssd1306WriteAttempts))
+			}
+		})
+	}
+}
+
+func TestSSD1306Unavailable(t *testing.T) {
+	ssd1306RetryDelay = 0
+	defer func() { ssd1306RetryDelay = 5 * time.Millisecond }()
+
+	dev := &fakeI2C{failures: 1000, err: syscall.EREMOTEIO}
+	d := &SSD1306{i2c: dev, stats: BusStats{Available: true}}
+	var err error
+	for i := 0; i < ssd1306MaxFailures; i++ {
+		err = d.write([]byte{0x00, ssd1306DisplayOn})
+	}
+	if !errors.Is(err, ErrDisplayUnavailable) || !errors.Is(err, syscall.EREMOTEIO) {
+		t.Fatalf("write() = %v, want ErrDisplayUnavailable wrapping the bus error", err)
+	}
+	if st := d.Stats(); st.Available || st.ConsecutiveFailures != ssd1306MaxFailures {
+		t.Errorf("Stats() = %+v, want unavailable after %d failures", st, ssd1306MaxFailures)
+	}
+
+	// An unavailable display no longer touches the bus
+	writes := dev.writes
+	if err := d.write([]byte{0x00, ssd1306DisplayOn}); !errors.Is(err, ErrDisplayUnavailable) || dev.writes != writes {
+		t.Errorf("write() = %v after %d more writes, want ErrDisplayUnavailable without writing", err, dev.writes-writes)
+	}
+}
+
+func TestSSD1306FailuresReset(t *testing.T) {
+	ssd1306RetryDelay = 0
+	defer func() { ssd1306RetryDelay = 5 * time.Millisecond }()
+
+	dev := &fakeI2C{failures: ssd1306WriteAttempts, err: syscall.EREMOTEIO}
+	d := &SSD1306{i2c: dev, stats: BusStats{Available: true}}
+	if err := d.write([]byte{0x00}); err == nil {
+		t.Fatal("write() succeeded with the bus failing")
+	}
+	if err := d.write([]byte{0x00}); err != nil {
+		t.Fatalf("write() error = %v", err)
+	}
+	if st := d.Stats(); st.ConsecutiveFailures != 0 || !st.Available {
+		t.Errorf("Stats() = %+v, want failures reset by a good write", st)
+	}
+}
+
+func TestDirtyRegion(t *testing.T) {
+	const width, pages = 4, 3
+	changed := func(idx ...int) []byte {
+		b := make([]byte, width*pages)
+		for _, i := range idx {
+			b[i] = 0xff
+		}
+		return b
+	}
+
+	tests := []struct {
+		name      string
+		prev      []byte
+		next      []byte
+		want      region
+		wantDirty bool
+	}{
+		{name: "unknown panel", prev: nil, next: changed(), want: region{0, 2, 0, 3}, wantDirty: true},
+		{name: "unchanged", prev: changed(5), next: changed(5)},
+		{name: "one byte", prev: changed(), next: changed(6), want: region{1, 1, 2, 2}, wantDirty: true},
+		{name: "bounding box", prev: changed(), next: changed(1, 10), want: region{0, 2, 1, 2}, wantDirty: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, dirty := dirtyRegion(tt.prev, tt.next, width, pages)
+			if dirty != tt.wantDirty || (dirty && got != tt.want) {
+				t.Errorf("dirtyRegion() = %+v, %v, want %+v, %v", got, dirty, tt.want, tt.wantDirty)
+			}
+		})
+	}
+}
+
+func TestSSD1306DisplayUpdates(t *testing.T) {
+	dev := &fakeI2C{}
+	d := &SSD1306{i2c: dev, width: 128, height: 32, buffer: make([]byte, 128*32/8), stats: BusStats{Available: true}}
+	img := image.NewGray(image.Rect(0, 0, 128, 32))
+
+	// The first frame is sent whole: one command and one data write
+	if err := d.Display(img); err != nil {
+		t.Fatal(err)
+	}
+	if len(dev.sent) != 2 || len(dev.sent[1]) != 1+128*4 {
+		t.Fatalf("first frame sent %d writes, want the command and a full frame", len(dev.sent))
+	}
+
+	// An unchanged frame sends nothing
+	dev.sent = nil
+	if err := d.Display(img); err != nil {
+		t.Fatal(err)
+	}
+	if len(dev.sent) != 0 {
+		t.Errorf("unchanged frame sent %d writes", len(dev.sent))
+	}
+
+	// A pixel at (10, 9) only sends column 10 of page 1
+	img.SetGray(10, 9, color.Gray{Y: 255})
+	if err := d.Display(img); err != nil {
+		t.Fatal(err)
+	}
+	wantCmd := []byte{0x00, ssd1306ColumnAddr, 10, 10, ssd1306PageAddr, 1, 1}
+	if len(dev.sent) != 2 || !bytes.Equal(dev.sent[0], wantCmd) || !bytes.Equal(dev.sent[1], []byte{0x40, 0x02}) {
+		t.Errorf("sent % x, want % x then 40 02", dev.sent, wantCmd)
+	}
+}
+
+func TestSSD1306FailedFrameResent(t *testing.T) {
+	ssd1306RetryDelay = 0
+	defer func() { ssd1306RetryDelay = 5 * time.Millisecond }()
+
+	dev := &fakeI2C{}
+	d := &SSD1306{i2c: dev, width: 128, height: 32, buffer: make([]byte, 128*32/8), stats: BusStats{Available: true}}
+	img := image.NewGray(image.Rect(0, 0, 128, 32))
+	if err := d.Display(img); err != nil {
+		t.Fatal(err)
+	}
+
+	img.SetGray(0, 0, color.Gray{Y: 255})
+	dev.failures, dev.err = ssd1306WriteAttempts, syscall.EREMOTEIO
+	if err := d.Display(img); err == nil {
+		t.Fatal("Display() succeeded with the bus failing")
+	}
+
+	// The panel RAM is unknown after the failure, so the next frame is sent whole
+	dev.sent = nil
+	if err := d.Display(img); err != nil {
+		t.Fatal(err)
+	}
+	if len(dev.sent) != 2 || len(dev.sent[1]) != 1+128*4 {
+		t.Errorf("frame after a failure sent %d writes, want a full frame", len(dev.sent))
+	}
+}
+
+func TestSSD1306RetryResendsWindow(t *testing.T) {
+	ssd1306RetryDelay = 0
+	defer func() { ssd1306RetryDelay = 5 * time.Millisecond }()
+
+	dev := &fakeI2C{}
+	d := &SSD1306{i2c: dev, width: 128, height: 32, buffer: make([]byte, 128*32/8), stats: BusStats{Available: true}}
+	img := image.NewGray(image.Rect(0, 0, 128, 32))
+	if err := d.Display(img); err != nil {
+		t.Fatal(err)
+	}
+
+	// The data write of the next frame fails once
+	img.SetGray(10, 9, color.Gray{Y: 255})
+	dev.sent = nil
+	dev.failNth, dev.err = dev.writes+2, syscall.EREMOTEIO
+	if err := d.Display(img); err != nil {
+		t.Fatal(err)
+	}
+	wantCmd := []byte{0x00, ssd1306ColumnAddr, 10, 10, ssd1306PageAddr, 1, 1}
+	if len(dev.sent) != 3 || !bytes.Equal(dev.sent[1], wantCmd) || !bytes.Equal(dev.sent[2], []byte{0x40, 0x02}) {
+		t.Errorf("sent % x, want the window sent again before the data", dev.sent)
+	}
+}
+
+func TestSSD1306ReopenSetsUp(t *testing.T) {
+	ssd1306RetryDelay = 0
+	defer func() { ssd1306RetryDelay = 5 * time.Millisecond }()
+	realOpen := openI2C
+	defer func() { openI2C = realOpen }()
+	fresh := &fakeI2C{}
+	openI2C = func(uint8, int) (i2cDevice, error) { return fresh, nil }
+
+	dev := &fakeI2C{}
+	d := &SSD1306{i2c: dev, width: 128, height: 32, buffer: make([]byte, 128*32/8), stats: BusStats{Available: true}}
+	img := image.NewGray(image.Rect(0, 0, 128, 32))
+	if err := d.Display(img); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.SetFlipped(true); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.SetContrast(0x20); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Display(img); err != nil {
+		t.Fatal(err)
+	}
+
+	// The panel browns out: the bus fails with EIO and comes back unconfigured
+	img.SetGray(10, 9, color.Gray{Y: 255})
+	dev.failures, dev.err = 1, &os.PathError{Op: "write", Path: "/dev/i2c-7", Err: syscall.EIO}
+	if err := d.Display(img); err != nil {
+		t.Fatal(err)
+	}
+
+	var cmds []byte
+	for _, b := range fresh.sent {
+		if len(b) == 2 && b[0] == 0x00 {
+			cmds = append(cmds, b[1])
+		}
+	}
+	for _, want := range [][]byte{
+		{ssd1306SegRemap, ssd1306ComScanInc},
+		{ssd1306SetContrast, 0x20},
+		{ssd1306DisplayOn},
+	} {
+		if !bytes.Contains(cmds, want) {
+			t.Errorf("setup after reopen sent % x, want % x", cmds, want)
+		}
+	}
+	// The panel RAM was lost, so the frame is sent whole after the changed part
+	if last := fresh.sent[len(fresh.sent)-1]; len(last) != 1+128*4 {
+		t.Errorf("last write after reopen is %d bytes, want a full frame", len(last))
+	}
+}
+
+func TestSSD1306SetFlipped(t *testing.T) {
+	dev := &fakeI2C{}
+	d := &SSD1306{i2c: dev, width: 128, height: 32, buffer: make([]byte, 128*32/8), stats: BusStats{Available: true}}
+	img := image.NewGray(image.Rect(0, 0, 128, 32))
+	if err := d.Display(img); err != nil {
+		t.Fatal(err)
+	}
+
+	dev.sent = nil
+	if err := d.SetFlipped(true); err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{0x00, ssd1306SegRemap, ssd1306ComScanInc}
+	if len(dev.sent) != 1 || !bytes.Equal(dev.sent[0], want) {
+		t.Fatalf("SetFlipped sent % x, want % x", dev.sent, want)
+	}
+
+	// The remap applies to new data only, so the unchanged frame is sent again
+	dev.sent = nil
+	if err := d.Display(img); err != nil {
+		t.Fatal(err)
+	}
+	if len(dev.sent) != 2 || len(dev.sent[1]) != 1+128*4 {
+		t.Errorf("frame after flipping sent %d writes, want a full frame", len(dev.sent))
+	}
+}
+
+func TestResetPinChipName(t *testing.T) {
+	for chip, want := range map[string]string{
+		"":          "gpiochip0",
+		"4":         "gpiochip4",
+		"gpiochip1": "gpiochip1",
+	} {
+		if got := (ResetPin{Chip: chip}).chipName(); got != want {
+			t.Errorf("chipName() for %q = %q, want %q", chip, got, want)
+		}
+	}
+}
+
+func TestResetPinPulseUnwired(t *testing.T) {
+	// Without a reset line no chip is opened, so this passes on any host
+	if err := (ResetPin{Chip: "gpiochip99"}).pulse(); err != nil {
+		t.Errorf("pulse() without a line = %v, want nil", err)
+	}
+	if err := (ResetPin{Chip: "gpiochip99", Line: "Dx"}).pulse(); err == nil {
+		t.Error("pulse() with an invalid line returned nil")
+	}
+}
@@ -0,0 +1,75 @@
+package oled
+
+import (
+	"image"
+	"time"
+
+	"github.com/kolobock/rockpi-quad-go/internal/logger"
+)
+
+// toastFontSize is the font of notification banners
+const toastFontSize = 11
+
+// toastHeight is the height of the banner across the bottom of the display, border included
+const toastHeight = toastFontSize + 3
+
+// Notify overlays text in a banner across the bottom of the current page for d, then
+// restores the page. A newer notification replaces the one on screen. During a thermal
+// emergency the banner is kept off the flashing warning.
+func (c *Controller) Notify(text string, d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.toastTimer != nil {
+		c.toastTimer.Stop()
+	}
+	c.toast = text
+	c.toastSeq++
+	seq := c.toastSeq
+	c.toastTimer = time.AfterFunc(d, func() { c.clearToast(seq) })
+
+	c.refresh("notification")
+}
+
+// clearToast removes notification seq and restores the page underneath, unless a
+// newer notification has replaced it
+func (c *Controller) clearToast(seq int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if seq != c.toastSeq || c.toast == "" {
+		return
+	}
+	c.toast = ""
+	c.refresh("page")
+}
+
+// refresh redraws the display outside the page rotation, waking it if asleep; the
+// caller holds mu
+func (c *Controller) refresh(what string) {
+	if c.fanCtrl != nil && c.fanCtrl.EmergencyActive() {
+		return
+	}
+	c.wakeLocked()
+	c.render()
+	if err := c.display(); err != nil {
+		logger.Errorf("Failed to display %s: %v", what, err)
+	}
+}
+
+// drawToast clears a band across the bottom of dst and draws the notification text
+// centered in it below a one pixel border
+func (c *Controller) drawToast(dst *image.Gray, text string) {
+	bounds := dst.Bounds()
+	top := bounds.Max.Y - toastHeight
+	fillRect(dst, image.Rect(bounds.Min.X, top, bounds.Max.X, bounds.Max.Y), pixelOff)
+	fillRect(dst, image.Rect(bounds.Min.X, top, bounds.Max.X, top+1), pixelOn)
+
+	row := Row{Cells: []Cell{{Text: text, Align: AlignCenter}}, FontSize: toastFontSize}
+	// Rows are placed glyphTop above their text; keep a pixel free below the border
+	l := c.layout()
+	l.Scroll = false
+	for _, item := range l.placeRow(row, top+2-glyphTop) {
+		c.drawTextOn(dst, item.X, item.Y, item.Text, item.FontSize)
+	}
+}
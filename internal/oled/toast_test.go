@@ -0,0 +1,81 @@
+package oled
+
+import (
+	"image"
+	"testing"
+	"time"
+
+	"golang.org/x/image/font"
+
+	"github.com/kolobock/rockpi-quad-go/internal/config"
+)
+
+func newTestController() *Controller {
+	c := &Controller{
+		cfg:   &config.Config{},
+		dev:   &mockSSD1306{},
+		img:   image.NewGray(image.Rect(0, 0, displayWidth, displayHeight)),
+		fonts: map[int]font.Face{11: &mockFontFace{}, 12: &mockFontFace{}},
+	}
+	c.pages = []Page{&StaticTextPage{ctrl: c, lines: []string{"page"}}}
+	return c
+}
+
+func TestNotifyOverlaysAndRestores(t *testing.T) {
+	c := newTestController()
+	border := displayHeight - toastHeight
+
+	c.Notify("Fan off", 20*time.Millisecond)
+	if c.img.GrayAt(0, border).Y != 255 {
+		t.Fatal("banner border not drawn after Notify")
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		c.mu.Lock()
+		restored := c.toast == "" && c.img.GrayAt(0, border).Y == 0
+		c.mu.Unlock()
+		if restored {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("page not restored after the notification expired")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestNotifyReplacesOlder(t *testing.T) {
+	c := newTestController()
+
+	c.Notify("first", time.Hour)
+	c.Notify("second", time.Hour)
+	t.Cleanup(func() { c.toastTimer.Stop() })
+
+	// A late timer of the first notification must not clear the second
+	c.clearToast(1)
+	if c.toast != "second" {
+		t.Errorf("toast = %q, want %q", c.toast, "second")
+	}
+
+	c.clearToast(2)
+	if c.toast != "" {
+		t.Errorf("toast = %q after its timer, want cleared", c.toast)
+	}
+}
+
+func TestNextPageDropsMessage(t *testing.T) {
+	c := newTestController()
+	c.timerDuration = time.Hour
+	c.timer = time.NewTicker(c.timerDuration)
+	t.Cleanup(c.timer.Stop)
+
+	c.ShowMessage("Cancelled", "")
+	if c.message == nil {
+		t.Fatal("ShowMessage did not keep the message")
+	}
+	c.nextPage()
+	if c.message != nil {
+		t.Error("message still shown after a page switch")
+	}
+}
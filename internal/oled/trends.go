@@ -0,0 +1,94 @@
+package oled
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kolobock/rockpi-quad-go/internal/logger"
+	"github.com/kolobock/rockpi-quad-go/internal/store"
+)
+
+const (
+	trendTempWindow = 24 * time.Hour
+	trendNetWindow  = 7 * 24 * time.Hour
+)
+
+// SetStore enables the trends page, must be called before Run
+func (c *Controller) SetStore(s *store.Store) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.store = s
+}
+
+// TrendPage - Temperature ranges over the last day and traffic over the last week
+type TrendPage struct {
+	ctrl *Controller
+}
+
+func (p *TrendPage) GetPageText() []TextItem {
+	now := time.Now()
+	rows := []Row{Line("Trends (24h/7d):")}
+	if lo, hi, ok := p.ctrl.tempRange(now, func(series string) bool { return series == "cpu" }); ok {
+		rows = append(rows, Line("CPU "+p.ctrl.formatTempRange(lo, hi)))
+	}
+	if lo, hi, ok := p.ctrl.tempRange(now, func(series string) bool { return strings.HasPrefix(series, "disk:") }); ok {
+		rows = append(rows, Line("Disk "+p.ctrl.formatTempRange(lo, hi)))
+	}
+
+	totals, err := p.ctrl.store.Network(now.Add(-trendNetWindow), now)
+	if err != nil {
+		logger.Infof("Failed to read network history: %v", err)
+	}
+	if len(totals) > 0 {
+		var rx, tx uint64
+		for _, t := range totals {
+			rx += t.RxBytes
+			tx += t.TxBytes
+		}
+		rows = append(rows, Line(fmt.Sprintf("Net R:%s T:%s", formatBytes(rx), formatBytes(tx))))
+	}
+	return p.ctrl.layout().Place(rows)
+}
+
+// tempRange returns the lowest and highest temperature of the matching series over
+// the last day
+func (c *Controller) tempRange(now time.Time, match func(series string) bool) (lo, hi float64, ok bool) {
+	series, err := c.store.Series()
+	if err != nil {
+		logger.Infof("Failed to read temperature history: %v", err)
+		return 0, 0, false
+	}
+	for _, name := range series {
+		if !match(name) {
+			continue
+		}
+		aggs, err := c.store.Temps(name, now.Add(-trendTempWindow), now)
+		if err != nil {
+			continue
+		}
+		for _, agg := range aggs {
+			if !ok {
+				lo, hi, ok = agg.Min, agg.Max, true
+			}
+			lo, hi = min(lo, agg.Min), max(hi, agg.Max)
+		}
+	}
+	return lo, hi, ok
+}
+
+// formatBytes renders a byte count compactly, e.g. 512M or 1.2T
+func formatBytes(n uint64) string {
+	value, suffix := float64(n), "B"
+	for _, next := range []string{"K", "M", "G", "T"} {
+		if value < 1024 {
+			break
+		}
+		value /= 1024
+		suffix = next
+	}
+	if value < 10 && suffix != "B" {
+		return fmt.Sprintf("%.1f%s", value, suffix)
+	}
+	return fmt.Sprintf("%.0f%s", value, suffix)
+}
@@ -0,0 +1,78 @@
+package oled
+
+import (
+	"image"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kolobock/rockpi-quad-go/internal/config"
+	"github.com/kolobock/rockpi-quad-go/internal/store"
+)
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		n    uint64
+		want string
+	}{
+		{512, "512B"},
+		{1536, "1.5K"},
+		{300 << 20, "300M"},
+		{5 << 30, "5.0G"},
+		{1300 << 30, "1.3T"},
+	}
+
+	for _, tt := range tests {
+		if got := formatBytes(tt.n); got != tt.want {
+			t.Errorf("formatBytes(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestTrendPage(t *testing.T) {
+	s, err := store.Open(filepath.Join(t.TempDir(), "metrics.db"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	now := time.Now()
+	samples := []struct {
+		series string
+		ago    time.Duration
+		temp   float64
+	}{
+		{"cpu", time.Hour, 41},
+		{"cpu", 2 * time.Hour, 55},
+		{"cpu", 48 * time.Hour, 80}, // outside the window
+		{"disk:wwn-1", time.Hour, 33},
+		{"disk:wwn-2", time.Hour, 38},
+	}
+	for _, sample := range samples {
+		if err := s.RecordTemp(sample.series, now.Add(-sample.ago), sample.temp); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.AddNetwork("eth0", now, 3<<30, 200<<20); err != nil {
+		t.Fatal(err)
+	}
+
+	ctrl := &Controller{
+		cfg:   &config.Config{},
+		img:   image.NewGray(image.Rect(0, 0, displayWidth, 64)),
+		store: s,
+	}
+	items := (&TrendPage{ctrl: ctrl}).GetPageText()
+
+	var texts []string
+	for _, item := range items {
+		texts = append(texts, item.Text)
+	}
+	got := strings.Join(texts, "|")
+	for _, want := range []string{"CPU 41-55°C", "Disk 33-38°C", "Net R:3.0G T:200M"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("trend page %q missing %q", got, want)
+		}
+	}
+}
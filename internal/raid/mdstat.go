@@ -0,0 +1,130 @@
+// Package raid monitors software RAID arrays (/proc/mdstat) and ZFS pools and
+// reports when one becomes degraded.
+package raid
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DefaultMDStat is where the kernel reports md arrays
+const DefaultMDStat = "/proc/mdstat"
+
+// Kinds of arrays
+const (
+	KindMD  = "md"
+	KindZFS = "zfs"
+)
+
+var (
+	// md0 : active raid5 sdd1[3] sdc1[2] sdb1[1] sda1[0]
+	mdHeaderRe = regexp.MustCompile(`^(md\S+)\s*:\s*(\S+)(.*)$`)
+	// [4/3] [UUU_]
+	mdDevicesRe = regexp.MustCompile(`\[(\d+)/(\d+)\]\s+\[([U_]+)\]`)
+	// recovery = 12.6% (246194176/1953382400) finish=150.2min
+	mdProgressRe = regexp.MustCompile(`(recovery|resync|reshape|check)\s*=\s*([\d.]+)%`)
+	// resync=DELAYED / resync=PENDING
+	mdPendingRe = regexp.MustCompile(`(recovery|resync|reshape|check)\s*=\s*(DELAYED|PENDING)`)
+)
+
+// Array is the state of one md array or ZFS pool
+type Array struct {
+	Name string
+	Kind string
+	// Level is the RAID level of md arrays, e.g. raid5
+	Level string
+	// State is active/inactive for md arrays and the pool state (ONLINE, DEGRADED,
+	// FAULTED...) for ZFS
+	State string
+	// Devices is the md member map, e.g. [UU_U] with _ for a missing member
+	Devices  string
+	Degraded bool
+	// Action is a running recovery, resync, reshape, check, resilver or scrub
+	Action string
+	// Progress is the completion of Action in percent, negative when unknown
+	Progress float64
+}
+
+// Status renders the array state compactly for the display, e.g. "OK", "DEGRADED"
+// "INACTIVE" or "recovery 12%"
+func (a Array) Status() string {
+	switch {
+	case a.Action != "" && a.Progress >= 0:
+		return a.Action + " " + strconv.FormatFloat(a.Progress, 'f', 0, 64) + "%"
+	case a.Action != "":
+		return a.Action
+	case a.State == "inactive":
+		return "INACTIVE"
+	case a.Degraded:
+		return "DEGRADED"
+	default:
+		return "OK"
+	}
+}
+
+// Message describes a degraded array for logs and notifications
+func (a Array) Message() string {
+	msg := fmt.Sprintf("RAID %s degraded: %s", a.Name, a.State)
+	if a.Devices != "" {
+		msg += " " + a.Devices
+	}
+	if a.Action != "" {
+		msg += ", " + a.Status()
+	}
+	return msg
+}
+
+// Details describes a degraded array for alert webhooks
+func (a Array) Details() map[string]any {
+	details := map[string]any{"array": a.Name, "kind": a.Kind, "state": a.State}
+	if a.Devices != "" {
+		details["devices"] = a.Devices
+	}
+	if a.Action != "" {
+		details["action"] = a.Action
+	}
+	if a.Progress > 0 {
+		details["progress"] = a.Progress
+	}
+	return details
+}
+
+// ParseMDStat parses the contents of /proc/mdstat
+func ParseMDStat(data string) []Array {
+	var arrays []Array
+	var cur *Array
+	for _, line := range strings.Split(data, "\n") {
+		if m := mdHeaderRe.FindStringSubmatch(line); m != nil {
+			arrays = append(arrays, Array{Name: m[1], Kind: KindMD, State: m[2], Progress: -1})
+			cur = &arrays[len(arrays)-1]
+			for _, field := range strings.Fields(m[3]) {
+				if strings.HasPrefix(field, "raid") || field == "linear" {
+					cur.Level = field
+					break
+				}
+			}
+			cur.Degraded = cur.State == "inactive"
+			continue
+		}
+		if cur == nil || strings.TrimSpace(line) == "" {
+			cur = nil
+			continue
+		}
+
+		if m := mdDevicesRe.FindStringSubmatch(line); m != nil {
+			cur.Devices = "[" + m[3] + "]"
+			if strings.Contains(m[3], "_") || m[1] != m[2] {
+				cur.Degraded = true
+			}
+		}
+		if m := mdProgressRe.FindStringSubmatch(line); m != nil {
+			cur.Action = m[1]
+			cur.Progress, _ = strconv.ParseFloat(m[2], 64)
+		} else if m := mdPendingRe.FindStringSubmatch(line); m != nil {
+			cur.Action = m[1]
+		}
+	}
+	return arrays
+}
@@ -0,0 +1,81 @@
+package raid
+
+import (
+	"reflect"
+	"testing"
+)
+
+const mdstatSample = `Personalities : [raid1] [raid6] [raid5] [raid4]
+md0 : active raid5 sdd1[4] sdc1[2] sdb1[1] sda1[0]
+      5860147200 blocks super 1.2 level 5, 512k chunk, algorithm 2 [4/3] [UUU_]
+      [==>..................]  recovery = 12.6% (246194176/1953382400) finish=150.2min speed=189384K/sec
+      bitmap: 0/15 pages [0KB], 65536KB chunk
+
+md1 : active raid1 sdb2[1] sda2[0]
+      523264 blocks super 1.2 [2/2] [UU]
+
+md2 : active raid1 sdd3[1] sdc3[0]
+      1048576 blocks super 1.2 [2/2] [UU]
+        resync=DELAYED
+
+md127 : inactive sdc4[0](S)
+      1953382400 blocks super 1.2
+
+unused devices: <none>
+`
+
+func TestParseMDStat(t *testing.T) {
+	arrays := ParseMDStat(mdstatSample)
+
+	want := []struct {
+		name     string
+		level    string
+		devices  string
+		degraded bool
+		status   string
+	}{
+		{"md0", "raid5", "[UUU_]", true, "recovery 13%"},
+		{"md1", "raid1", "[UU]", false, "OK"},
+		{"md2", "raid1", "[UU]", false, "resync"},
+		{"md127", "", "", true, "INACTIVE"},
+	}
+	if len(arrays) != len(want) {
+		t.Fatalf("got %d arrays, want %d: %+v", len(arrays), len(want), arrays)
+	}
+	for i, w := range want {
+		a := arrays[i]
+		if a.Name != w.name || a.Level != w.level || a.Devices != w.devices || a.Degraded != w.degraded || a.Status() != w.status {
+			t.Errorf("array %d = %+v (status %q), want %+v", i, a, a.Status(), w)
+		}
+	}
+
+	if got := arrays[0].Message(); got != "RAID md0 degraded: active [UUU_], recovery 13%" {
+		t.Errorf("Message() = %q", got)
+	}
+}
+
+func TestArrayDetails(t *testing.T) {
+	tests := []struct {
+		name string
+		arr  Array
+		want map[string]any
+	}{
+		{
+			name: "degraded",
+			arr:  Array{Name: "md0", Kind: KindMD, State: "active", Devices: "[U_]", Degraded: true, Progress: -1},
+			want: map[string]any{"array": "md0", "kind": KindMD, "state": "active", "devices": "[U_]"},
+		},
+		{
+			name: "recovering",
+			arr:  Array{Name: "md0", Kind: KindMD, State: "active", Devices: "[UUU_]", Action: "recovery", Progress: 12.6},
+			want: map[string]any{"array": "md0", "kind": KindMD, "state": "active", "devices": "[UUU_]", "action": "recovery", "progress": 12.6},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.arr.Details(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Details() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package raid
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/kolobock/rockpi-quad-go/internal/logger"
+)
+
+// Monitor polls md arrays and, when enabled, ZFS pools
+type Monitor struct {
+	mdstat string
+	zfs    bool
+	// zpool is replaced in tests
+	zpool func(ctx context.Context) ([]Array, error)
+
+	mu     sync.Mutex
+	arrays []Array
+	// degraded holds the kind of every array degraded at the last poll
+	degraded map[string]string
+	changes  chan Array
+}
+
+// NewMonitor creates a monitor reading mdstat (empty to skip md arrays) and running
+// `zpool status -x` when zfs is set
+func NewMonitor(mdstat string, zfs bool) *Monitor {
+	return &Monitor{
+		mdstat:   mdstat,
+		zfs:      zfs,
+		zpool:    readZpoolStatus,
+		degraded: make(map[string]string),
+		changes:  make(chan Array, 8),
+	}
+}
+
+// Changes returns the channel receiving each array that became degraded
+func (m *Monitor) Changes() <-chan Array {
+	return m.changes
+}
+
+// Arrays returns the state of every array from the last poll
+func (m *Monitor) Arrays() []Array {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Array(nil), m.arrays...)
+}
+
+// check polls every source and returns the arrays that became degraded since the
+// previous poll; an array that is degraded when first seen counts as well
+func (m *Monitor) check(ctx context.Context) []Array {
+	var arrays []Array
+	polled := make(map[string]bool)
+	if m.mdstat != "" {
+		data, err := os.ReadFile(m.mdstat)
+		if err != nil {
+			logger.Infof("Failed to read %s: %v", m.mdstat, err)
+		} else {
+			arrays = append(arrays, ParseMDStat(string(data))...)
+			polled[KindMD] = true
+		}
+	}
+	if m.zfs {
+		pools, err := m.zpool(ctx)
+		if err != nil {
+			logger.Infof("Failed to read ZFS pool status: %v", err)
+		} else {
+			arrays = append(arrays, pools...)
+			polled[KindZFS] = true
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var newlyDegraded []Array
+	seen := make(map[string]bool)
+	for _, a := range arrays {
+		seen[a.Name] = true
+		_, was := m.degraded[a.Name]
+		switch {
+		case a.Degraded && !was:
+			newlyDegraded = append(newlyDegraded, a)
+			m.degraded[a.Name] = a.Kind
+		case !a.Degraded && was:
+			logger.Errorf("RAID %s is healthy again", a.Name)
+			delete(m.degraded, a.Name)
+		}
+	}
+	// `zpool status -x` leaves out pools that are healthy, so a degraded array
+	// missing from a successful poll of its kind has recovered
+	for name, kind := range m.degraded {
+		if polled[kind] && !seen[name] {
+			logger.Errorf("RAID %s is healthy again", name)
+			delete(m.degraded, name)
+		}
+	}
+	m.arrays = arrays
+	return newlyDegraded
+}
+
+// Run polls the arrays until the context is cancelled
+func (m *Monitor) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		for _, a := range m.check(ctx) {
+			select {
+			case m.changes <- a:
+			default:
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package raid
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMonitorReportsDegradation(t *testing.T) {
+	mdstat := filepath.Join(t.TempDir(), "mdstat")
+	write := func(devices string) {
+		t.Helper()
+		data := "md0 : active raid1 sdb1[1] sda1[0]\n      523264 blocks super 1.2 " + devices + "\n\n"
+		if err := os.WriteFile(mdstat, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m := NewMonitor(mdstat, true)
+	zfsPools := []Array{{Name: "tank", Kind: KindZFS, State: "ONLINE", Progress: -1}}
+	m.zpool = func(context.Context) ([]Array, error) { return zfsPools, nil }
+
+	steps := []struct {
+		name    string
+		devices string
+		zfs     string
+		want    []string
+	}{
+		{"healthy", "[2/2] [UU]", "ONLINE", nil},
+		{"md degraded", "[2/1] [U_]", "ONLINE", []string{"md0"}},
+		{"still degraded", "[2/1] [U_]", "ONLINE", nil},
+		{"pool degraded, md rebuilt", "[2/2] [UU]", "DEGRADED", []string{"tank"}},
+	}
+
+	for _, step := range steps {
+		write(step.devices)
+		zfsPools[0].State, zfsPools[0].Degraded = step.zfs, step.zfs != "ONLINE"
+
+		var got []string
+		for _, a := range m.check(context.Background()) {
+			got = append(got, a.Name)
+		}
+		if len(got) != len(step.want) || (len(got) > 0 && got[0] != step.want[0]) {
+			t.Errorf("%s: degraded = %v, want %v", step.name, got, step.want)
+		}
+	}
+
+	if arrays := m.Arrays(); len(arrays) != 2 {
+		t.Errorf("Arrays() = %+v, want md0 and tank", arrays)
+	}
+}
+
+func TestMonitorPoolRecovers(t *testing.T) {
+	m := NewMonitor("", true)
+	var pools []Array
+	m.zpool = func(context.Context) ([]Array, error) { return pools, nil }
+
+	degraded := []Array{{Name: "tank", Kind: KindZFS, State: "DEGRADED", Degraded: true, Progress: -1}}
+	steps := []struct {
+		name  string
+		pools []Array
+		want  int
+	}{
+		{"degraded", degraded, 1},
+		{"still degraded", degraded, 0},
+		// `zpool status -x` only lists pools with problems
+		{"recovered", parseZpoolStatus("all pools are healthy\n"), 0},
+		{"degraded again", degraded, 1},
+	}
+	for _, step := range steps {
+		pools = step.pools
+		if got := m.check(context.Background()); len(got) != step.want {
+			t.Errorf("%s: degraded = %+v, want %d", step.name, got, step.want)
+		}
+	}
+
+	// A failed poll does not count as a recovery
+	m.zpool = func(context.Context) ([]Array, error) { return nil, context.DeadlineExceeded }
+	m.check(context.Background())
+	pools = degraded
+	m.zpool = func(context.Context) ([]Array, error) { return pools, nil }
+	if got := m.check(context.Background()); len(got) != 0 {
+		t.Errorf("degraded after a failed poll = %+v, want no new alert", got)
+	}
+}
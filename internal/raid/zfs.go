@@ -0,0 +1,57 @@
+package raid
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// zpool status reports e.g. "300G resilvered, 25.00% done, 01:00:00 to go"
+var zfsProgressRe = regexp.MustCompile(`([\d.]+)% done`)
+
+// zfsHealthy stands in for every pool when `zpool status -x` has nothing to report
+const zfsHealthy = "all pools are healthy"
+
+// readZpoolStatus runs `zpool status -x`, which lists only pools with problems
+func readZpoolStatus(ctx context.Context) ([]Array, error) {
+	out, err := exec.CommandContext(ctx, "zpool", "status", "-x").Output()
+	if err != nil {
+		return nil, fmt.Errorf("zpool status failed: %w", err)
+	}
+	return parseZpoolStatus(string(out)), nil
+}
+
+// parseZpoolStatus parses `zpool status -x` output. When every pool is healthy a
+// single "zfs" entry in state ONLINE is returned, so the page still shows ZFS.
+func parseZpoolStatus(output string) []Array {
+	if strings.Contains(output, zfsHealthy) {
+		return []Array{{Name: "zfs", Kind: KindZFS, State: "ONLINE", Progress: -1}}
+	}
+
+	var pools []Array
+	var cur *Array
+	for _, line := range strings.Split(output, "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), ":")
+		value = strings.TrimSpace(value)
+		switch {
+		case ok && key == "pool":
+			pools = append(pools, Array{Name: value, Kind: KindZFS, Progress: -1})
+			cur = &pools[len(pools)-1]
+		case cur == nil:
+			continue
+		case ok && key == "state":
+			cur.State = value
+			cur.Degraded = value != "ONLINE"
+		case ok && key == "scan" && strings.Contains(value, "in progress"):
+			cur.Action = strings.Fields(value)[0]
+		case cur.Action != "" && cur.Progress < 0:
+			if m := zfsProgressRe.FindStringSubmatch(line); m != nil {
+				cur.Progress, _ = strconv.ParseFloat(m[1], 64)
+			}
+		}
+	}
+	return pools
+}
@@ -0,0 +1,43 @@
+package raid
+
+import "testing"
+
+func TestParseZpoolStatus(t *testing.T) {
+	healthy := parseZpoolStatus("all pools are healthy\n")
+	if len(healthy) != 1 || healthy[0].Degraded || healthy[0].Status() != "OK" {
+		t.Errorf("healthy pools = %+v, want one OK entry", healthy)
+	}
+
+	output := `  pool: tank
+ state: DEGRADED
+status: One or more devices could not be used because the label is missing or
+	invalid.  Sufficient replicas exist for the pool to continue
+	functioning in a degraded state.
+action: Replace the device using 'zpool replace'.
+  scan: resilver in progress since Sun Jul 25 10:00:00 2026
+	1.20T scanned at 1.1G/s, 600G issued at 500M/s, 2.40T total
+	300G resilvered, 25.00% done, 01:00:00 to go
+config:
+
+	NAME        STATE     READ WRITE CKSUM
+	tank        DEGRADED     0     0     0
+	  raidz1-0  DEGRADED     0     0     0
+	    sda     ONLINE       0     0     0
+	    sdb     UNAVAIL      0     0     0
+
+errors: No known data errors
+
+  pool: backup
+ state: FAULTED
+`
+	pools := parseZpoolStatus(output)
+	if len(pools) != 2 {
+		t.Fatalf("got %d pools, want 2: %+v", len(pools), pools)
+	}
+	if p := pools[0]; p.Name != "tank" || !p.Degraded || p.Status() != "resilver 25%" {
+		t.Errorf("tank = %+v (status %q), want degraded resilver 25%%", p, p.Status())
+	}
+	if p := pools[1]; p.Name != "backup" || p.State != "FAULTED" || p.Status() != "DEGRADED" {
+		t.Errorf("backup = %+v (status %q), want FAULTED", p, p.Status())
+	}
+}
@@ -0,0 +1,39 @@
+package rails
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// sysBusIIO is replaced in tests
+var sysBusIIO = "/sys/bus/iio/devices"
+
+// readIIOVolts reads an IIO voltage channel such as "iio:device0/in_voltage0" in
+// volts: raw × scale (millivolts) × the external divider ratio. The scale is taken
+// from the channel or, when the device shares one, from in_voltage_scale.
+func readIIOVolts(channel string, divider float64) (float64, error) {
+	base := filepath.Join(sysBusIIO, channel)
+	raw, err := readFloat(base + "_raw")
+	if err != nil {
+		return 0, err
+	}
+	scale, err := readFloat(base + "_scale")
+	if err != nil {
+		scale, err = readFloat(filepath.Join(filepath.Dir(base), "in_voltage_scale"))
+		if err != nil {
+			return 0, fmt.Errorf("no scale for %s: %w", channel, err)
+		}
+	}
+	return raw * scale / 1000 * divider, nil
+}
+
+func readFloat(path string) (float64, error) {
+	data, err := os.ReadFile(path) // #nosec G304 - the channel comes from the configuration file
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseFloat(strings.TrimSpace(string(data)), 64)
+}
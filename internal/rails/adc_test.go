@@ -0,0 +1,53 @@
+package rails
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadIIOVolts(t *testing.T) {
+	sysBusIIO = t.TempDir()
+	t.Cleanup(func() { sysBusIIO = "/sys/bus/iio/devices" })
+
+	dev := filepath.Join(sysBusIIO, "iio:device0")
+	if err := os.MkdirAll(dev, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"in_voltage0_raw":   "2048\n",
+		"in_voltage0_scale": "1.0\n",
+		"in_voltage1_raw":   "1000\n",
+		"in_voltage_scale":  "0.5\n",
+		"in_voltage2_raw":   "bogus\n",
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dev, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		channel string
+		divider float64
+		want    float64
+		wantErr bool
+	}{
+		{"iio:device0/in_voltage0", 6, 12.288, false},
+		{"iio:device0/in_voltage1", 10, 5, false},
+		{"iio:device0/in_voltage2", 1, 0, true},
+		{"iio:device0/in_voltage3", 1, 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := readIIOVolts(tt.channel, tt.divider)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("readIIOVolts(%s) error = %v, wantErr %v", tt.channel, err, tt.wantErr)
+			continue
+		}
+		if math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("readIIOVolts(%s) = %v, want %v", tt.channel, got, tt.want)
+		}
+	}
+}
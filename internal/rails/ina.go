@@ -0,0 +1,112 @@
+package rails
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	i2c "github.com/d2r2/go-i2c"
+)
+
+// Supported I2C power monitors
+const (
+	chipINA219  = "ina219"
+	chipINA3221 = "ina3221"
+
+	// defaultINAAddr is the address of both chips with their address pins grounded
+	defaultINAAddr = 0x40
+)
+
+// inaSpec is a parsed `ina` rail setting such as "ina219@0x40" or "ina3221@0x41:2"
+type inaSpec struct {
+	chip    string
+	addr    int
+	channel int
+}
+
+func parseINA(spec string) (inaSpec, error) {
+	s := inaSpec{addr: defaultINAAddr, channel: 1}
+	rest := spec
+	if chip, addr, ok := strings.Cut(spec, "@"); ok {
+		s.chip, rest = chip, addr
+		if addr, channel, ok := strings.Cut(rest, ":"); ok {
+			ch, err := strconv.Atoi(channel)
+			if err != nil || ch < 1 || ch > 3 {
+				return inaSpec{}, fmt.Errorf("invalid INA3221 channel in %q", spec)
+			}
+			s.channel, rest = ch, addr
+		}
+		a, err := strconv.ParseInt(rest, 0, 8)
+		if err != nil {
+			return inaSpec{}, fmt.Errorf("invalid I2C address in %q", spec)
+		}
+		s.addr = int(a)
+	} else {
+		s.chip = spec
+	}
+
+	switch s.chip {
+	case chipINA219:
+		if s.channel != 1 {
+			return inaSpec{}, fmt.Errorf("the INA219 has a single channel: %q", spec)
+		}
+	case chipINA3221:
+	default:
+		return inaSpec{}, fmt.Errorf("unknown power monitor %q, expected ina219 or ina3221", s.chip)
+	}
+	return s, nil
+}
+
+// ina reads one channel of an INA219 or INA3221 in its power-on configuration:
+// continuous shunt and bus conversions, no calibration needed since the current is
+// derived from the shunt voltage
+type ina struct {
+	i2c   *i2c.I2C
+	spec  inaSpec
+	shunt float64
+}
+
+func openINA(bus int, spec string, shunt float64) (*ina, error) {
+	s, err := parseINA(spec)
+	if err != nil {
+		return nil, err
+	}
+	if shunt <= 0 {
+		return nil, fmt.Errorf("invalid shunt resistance %v", shunt)
+	}
+	i2cBus, err := i2c.NewI2C(uint8(s.addr), bus) // #nosec G115 - addresses are 7-bit
+	if err != nil {
+		return nil, fmt.Errorf("failed to open I2C: %w", err)
+	}
+	return &ina{i2c: i2cBus, spec: s, shunt: shunt}, nil
+}
+
+// Read returns the bus voltage and the current through the shunt
+func (d *ina) Read() (volts, amps float64, err error) {
+	// INA219: shunt 0x01, bus 0x02; INA3221: shunt 0x01/0x03/0x05, bus 0x02/0x04/0x06
+	shuntReg := byte(2*d.spec.channel - 1) // #nosec G115 - channels are 1 to 3
+	shuntRaw, err := d.i2c.ReadRegS16BE(shuntReg)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to read shunt voltage: %w", err)
+	}
+	busRaw, err := d.i2c.ReadRegU16BE(shuntReg + 1)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to read bus voltage: %w", err)
+	}
+	volts, amps = inaConvert(d.spec.chip, shuntRaw, busRaw, d.shunt)
+	return volts, amps, nil
+}
+
+// inaConvert scales raw registers. INA219: shunt LSB 10µV, bus bits 15-3 at 4mV.
+// INA3221: shunt and bus both in bits 15-3, at 40µV and 8mV.
+func inaConvert(chip string, shuntRaw int16, busRaw uint16, shunt float64) (volts, amps float64) {
+	if chip == chipINA3221 {
+		return float64(busRaw>>3) * 0.008, float64(shuntRaw>>3) * 40e-6 / shunt
+	}
+	return float64(busRaw>>3) * 0.004, float64(shuntRaw) * 10e-6 / shunt
+}
+
+// Close releases the I2C connection
+func (d *ina) Close() error {
+	return d.i2c.Close()
+}
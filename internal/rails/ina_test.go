@@ -0,0 +1,61 @@
+package rails
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseINA(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    inaSpec
+		wantErr bool
+	}{
+		{"ina219", inaSpec{chip: chipINA219, addr: 0x40, channel: 1}, false},
+		{"ina219@0x45", inaSpec{chip: chipINA219, addr: 0x45, channel: 1}, false},
+		{"ina3221@0x41:3", inaSpec{chip: chipINA3221, addr: 0x41, channel: 3}, false},
+		{"ina3221@64:2", inaSpec{chip: chipINA3221, addr: 0x40, channel: 2}, false},
+		{"ina219@0x40:2", inaSpec{}, true},
+		{"ina3221@0x40:4", inaSpec{}, true},
+		{"ina226@0x40", inaSpec{}, true},
+		{"ina219@bogus", inaSpec{}, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseINA(tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseINA(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseINA(%q) = %+v, want %+v", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestINAConvert(t *testing.T) {
+	tests := []struct {
+		name      string
+		chip      string
+		shuntRaw  int16
+		busRaw    uint16
+		shunt     float64
+		wantVolts float64
+		wantAmps  float64
+	}{
+		// 12V = 3000 × 4mV; 1000 × 10µV = 10mV across 0.1Ω → 0.1A
+		{"ina219", chipINA219, 1000, 3000 << 3, 0.1, 12, 0.1},
+		{"ina219 reverse current", chipINA219, -500, 1250 << 3, 0.1, 5, -0.05},
+		// 5V = 625 × 8mV, 250 × 40µV = 10mV across 0.01Ω → 1A
+		{"ina3221", chipINA3221, 250 << 3, 625 << 3, 0.01, 5, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			volts, amps := inaConvert(tt.chip, tt.shuntRaw, tt.busRaw, tt.shunt)
+			if math.Abs(volts-tt.wantVolts) > 1e-9 || math.Abs(amps-tt.wantAmps) > 1e-9 {
+				t.Errorf("inaConvert() = %vV %vA, want %vV %vA", volts, amps, tt.wantVolts, tt.wantAmps)
+			}
+		})
+	}
+}
@@ -0,0 +1,242 @@
+// Package rails watches the HAT power rails through power-good GPIO lines, IIO ADC
+// channels and INA219/INA3221 power monitors. It reports brown-outs, which commonly
+// make disks drop off the bus on under-powered setups, and over-current draw that
+// points at a failing PSU or a shorted drive.
+package rails
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/warthog618/go-gpiocdev"
+
+	"github.com/kolobock/rockpi-quad-go/internal/config"
+	"github.com/kolobock/rockpi-quad-go/internal/logger"
+)
+
+// State is the condition of one rail
+type State struct {
+	Name string
+	Good bool
+	// Volts is the last ADC or power monitor reading, 0 for rails without either
+	Volts float64
+	// Amps is the last current reading of a power monitor, valid with HasCurrent
+	Amps        float64
+	HasCurrent  bool
+	Overcurrent bool
+	// Dropouts counts the times the rail went bad since startup
+	Dropouts int
+	LastDrop time.Time
+}
+
+// Event reports a rail going bad or recovering
+type Event struct {
+	State
+}
+
+// Message describes the event for the log
+func (e Event) Message() string {
+	switch {
+	case e.Good:
+		return fmt.Sprintf("Power rail %s recovered", e.Name)
+	case e.Overcurrent:
+		return fmt.Sprintf("Power rail %s over-current: %.2fA at %.2fV, check for a shorted drive", e.Name, e.Amps, e.Volts)
+	case e.HasCurrent:
+		return fmt.Sprintf("Power rail %s brown-out: %.2fV at %.2fA, check the power supply", e.Name, e.Volts, e.Amps)
+	case e.Volts > 0:
+		return fmt.Sprintf("Power rail %s brown-out: %.2fV", e.Name, e.Volts)
+	default:
+		return fmt.Sprintf("Power rail %s brown-out: power-good signal lost", e.Name)
+	}
+}
+
+// meter reads the voltage and current of a rail
+type meter interface {
+	Read() (volts, amps float64, err error)
+	Close() error
+}
+
+type rail struct {
+	cfg   config.RailConfig
+	line  *gpiocdev.Line
+	meter meter
+	state State
+	known bool
+	// gpioGood and voltsGood are the two signals; a rail is good while both are
+	gpioGood  bool
+	voltsGood bool
+}
+
+// Monitor watches the configured rails
+type Monitor struct {
+	mu     sync.Mutex
+	rails  []*rail
+	events chan Event
+}
+
+// NewMonitor requests the power-good lines and opens the power monitors of the rails
+// on I2C bus i2cBus. GPIO edges are reported as they happen so short dips are caught;
+// ADC channels and power monitors are sampled by Run.
+func NewMonitor(cfgs []config.RailConfig, i2cBus int) (*Monitor, error) {
+	m := &Monitor{events: make(chan Event, 8)}
+	for _, rc := range cfgs {
+		r := &rail{cfg: rc, state: State{Name: rc.Name}, gpioGood: true, voltsGood: true}
+		m.rails = append(m.rails, r)
+		if err := m.open(r, i2cBus); err != nil {
+			m.Close()
+			return nil, fmt.Errorf("rail %s: %w", rc.Name, err)
+		}
+	}
+	return m, nil
+}
+
+func (m *Monitor) open(r *rail, i2cBus int) error {
+	if r.cfg.INA != "" {
+		meter, err := openINA(i2cBus, r.cfg.INA, r.cfg.Shunt)
+		if err != nil {
+			return err
+		}
+		r.meter = meter
+	}
+	if r.cfg.GPIO != "" {
+		return m.requestLine(r)
+	}
+	return nil
+}
+
+func (m *Monitor) requestLine(r *rail) error {
+	chip, offset, err := config.ParseGPIOLine(r.cfg.GPIO)
+	if err != nil {
+		return err
+	}
+	opts := []gpiocdev.LineReqOption{
+		gpiocdev.AsInput,
+		gpiocdev.WithBothEdges,
+		gpiocdev.WithEventHandler(func(evt gpiocdev.LineEvent) {
+			m.setGPIO(r, evt.Type == gpiocdev.LineEventRisingEdge, time.Now())
+		}),
+	}
+	if r.cfg.ActiveLow {
+		opts = append(opts, gpiocdev.AsActiveLow)
+	}
+	l, err := gpiocdev.RequestLine(chip, offset, opts...)
+	if err != nil {
+		return fmt.Errorf("failed to request power-good line: %w", err)
+	}
+	r.line = l
+
+	value, err := l.Value()
+	if err != nil {
+		return fmt.Errorf("failed to read power-good line: %w", err)
+	}
+	m.setGPIO(r, value == 1, time.Now())
+	return nil
+}
+
+// Events returns the channel receiving brown-outs and recoveries
+func (m *Monitor) Events() <-chan Event {
+	return m.events
+}
+
+// States returns the current state of every rail
+func (m *Monitor) States() []State {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	states := make([]State, len(m.rails))
+	for i, r := range m.rails {
+		states[i] = r.state
+	}
+	return states
+}
+
+func (m *Monitor) setGPIO(r *rail, good bool, now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	r.gpioGood = good
+	m.evaluate(r, now)
+}
+
+// setReading records a voltage and, when hasCurrent is set, a current reading
+func (m *Monitor) setReading(r *rail, volts, amps float64, hasCurrent bool, now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	r.state.Volts = volts
+	r.voltsGood = r.cfg.Min <= 0 || volts >= r.cfg.Min
+	if hasCurrent {
+		r.state.Amps, r.state.HasCurrent = amps, true
+		r.state.Overcurrent = r.cfg.MaxCurrent > 0 && amps > r.cfg.MaxCurrent
+	}
+	m.evaluate(r, now)
+}
+
+// evaluate updates the rail state and sends an event when it changed. A rail that is
+// already bad when first checked is reported too. Must be called with mu held.
+func (m *Monitor) evaluate(r *rail, now time.Time) {
+	good := r.gpioGood && r.voltsGood && !r.state.Overcurrent
+	if r.known && good == r.state.Good {
+		return
+	}
+	first := !r.known
+	r.known = true
+	r.state.Good = good
+	if !good {
+		r.state.Dropouts++
+		r.state.LastDrop = now
+	}
+	if first && good {
+		return
+	}
+	select {
+	case m.events <- Event{State: r.state}:
+	default:
+	}
+}
+
+// Run samples the ADC and power monitor rails every interval until the context is cancelled
+func (m *Monitor) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		m.sample(time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (m *Monitor) sample(now time.Time) {
+	for _, r := range m.rails {
+		var volts, amps float64
+		var err error
+		switch {
+		case r.meter != nil:
+			volts, amps, err = r.meter.Read()
+		case r.cfg.ADC != "":
+			volts, err = readIIOVolts(r.cfg.ADC, r.cfg.Divider)
+		default:
+			continue
+		}
+		if err != nil {
+			logger.Infof("Failed to read rail %s: %v", r.cfg.Name, err)
+			continue
+		}
+		m.setReading(r, volts, amps, r.meter != nil, now)
+	}
+}
+
+// Close releases the power-good lines and power monitors
+func (m *Monitor) Close() {
+	for _, r := range m.rails {
+		if r.line != nil {
+			r.line.Close()
+		}
+		if r.meter != nil {
+			r.meter.Close()
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package rails
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kolobock/rockpi-quad-go/internal/config"
+)
+
+func TestMonitorBrownOut(t *testing.T) {
+	m, err := NewMonitor([]config.RailConfig{{Name: "12v", ADC: "iio:device0/in_voltage0", Divider: 1, Min: 11.4}}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := m.rails[0]
+	now := time.Now()
+
+	steps := []struct {
+		name      string
+		volts     float64
+		wantEvent bool
+		wantGood  bool
+	}{
+		{"good at startup", 12.1, false, true},
+		{"still good", 11.9, false, true},
+		{"sagging", 10.8, true, false},
+		{"still low", 10.5, false, false},
+		{"recovered", 12.0, true, true},
+		{"sagging again", 11.0, true, false},
+	}
+
+	for _, step := range steps {
+		m.setReading(r, step.volts, 0, false, now)
+		select {
+		case evt := <-m.Events():
+			if !step.wantEvent {
+				t.Errorf("%s: unexpected event %+v", step.name, evt)
+			} else if evt.Good != step.wantGood || evt.Volts != step.volts {
+				t.Errorf("%s: event = %+v, want good %v at %.1fV", step.name, evt, step.wantGood, step.volts)
+			}
+		default:
+			if step.wantEvent {
+				t.Errorf("%s: no event", step.name)
+			}
+		}
+	}
+
+	if st := m.States()[0]; st.Dropouts != 2 || st.Good {
+		t.Errorf("state = %+v, want 2 dropouts and bad", st)
+	}
+}
+
+func TestMonitorBadAtStartup(t *testing.T) {
+	m, err := NewMonitor([]config.RailConfig{{Name: "5v", ADC: "iio:device0/in_voltage1", Divider: 1, Min: 4.75}}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.setReading(m.rails[0], 4.5, 0, false, time.Now())
+
+	select {
+	case evt := <-m.Events():
+		if want := "Power rail 5v brown-out: 4.50V"; evt.Message() != want {
+			t.Errorf("Message() = %q, want %q", evt.Message(), want)
+		}
+	default:
+		t.Error("a rail bad at startup was not reported")
+	}
+}
+
+func TestMonitorOvercurrent(t *testing.T) {
+	m, err := NewMonitor([]config.RailConfig{{Name: "disks", Divider: 1, Min: 4.75, MaxCurrent: 3}}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := m.rails[0]
+	now := time.Now()
+
+	m.setReading(r, 5.05, 1.2, true, now)
+	m.setReading(r, 5.01, 3.4, true, now)
+	select {
+	case evt := <-m.Events():
+		want := "Power rail disks over-current: 3.40A at 5.01V, check for a shorted drive"
+		if !evt.Overcurrent || evt.Message() != want {
+			t.Errorf("event = %+v (%q), want %q", evt, evt.Message(), want)
+		}
+	default:
+		t.Fatal("over-current was not reported")
+	}
+
+	m.setReading(r, 5.04, 1.1, true, now)
+	if evt := <-m.Events(); !evt.Good || evt.Overcurrent {
+		t.Errorf("event = %+v, want the rail recovered", evt)
+	}
+}
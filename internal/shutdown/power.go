@@ -0,0 +1,35 @@
+package shutdown
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/warthog618/go-gpiocdev"
+
+	"github.com/kolobock/rockpi-quad-go/internal/config"
+	"github.com/kolobock/rockpi-quad-go/internal/logger"
+)
+
+// AssertPowerGPIO drives the carrier board's soft power-off line to its active level
+// and holds it for power_gpio_hold seconds, cutting the HAT's fan and disk rails. It
+// is meant to run after the OS has shut down, from a systemd-shutdown hook.
+func AssertPowerGPIO(cfg config.ShutdownConfig) error {
+	chip, line, err := config.ParseGPIOLine(cfg.PowerGPIO)
+	if err != nil {
+		return fmt.Errorf("invalid power_gpio: %w", err)
+	}
+
+	active := 1
+	if cfg.PowerGPIOActiveLow {
+		active = 0
+	}
+	l, err := gpiocdev.RequestLine(chip, line, gpiocdev.AsOutput(active))
+	if err != nil {
+		return fmt.Errorf("failed to request power line: %w", err)
+	}
+	defer l.Close()
+
+	logger.Infof("Asserted power-off line %s:%d for %.1fs", chip, line, cfg.PowerGPIOHold)
+	time.Sleep(time.Duration(cfg.PowerGPIOHold * float64(time.Second)))
+	return nil
+}
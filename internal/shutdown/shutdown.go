@@ -0,0 +1,81 @@
+// Package shutdown runs the steps that leave the disks behind the HAT in a safe
+// state before the system powers off or reboots.
+package shutdown
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/kolobock/rockpi-quad-go/internal/config"
+	"github.com/kolobock/rockpi-quad-go/internal/logger"
+)
+
+// Step is one stage of the shutdown sequence
+type Step struct {
+	Label string
+	Run   func(ctx context.Context) error
+}
+
+// Progress is told about each step as it starts, e.g. to show it on the OLED
+type Progress func(title, text string)
+
+// runCommand is replaced in tests
+var runCommand = func(ctx context.Context, name string, args ...string) error {
+	// #nosec G204 - commands and arguments come from the configuration file and lsblk
+	out, err := exec.CommandContext(ctx, name, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s %s: %w: %s", name, strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func command(label, name string, args ...string) Step {
+	return Step{Label: label, Run: func(ctx context.Context) error { return runCommand(ctx, name, args...) }}
+}
+
+// Steps builds the sequence for action ("poweroff" or "reboot"): flush caches, stop
+// the configured services, unmount the configured mount points, flush again and,
+// for poweroff, put the SATA disks in standby so their heads park before power is cut
+func Steps(cfg config.ShutdownConfig, action string, disks []string) []Step {
+	steps := []Step{command("Syncing disks", "sync")}
+	for _, svc := range cfg.Services {
+		if svc = strings.TrimSpace(svc); svc != "" {
+			steps = append(steps, command("Stopping "+svc, "systemctl", "stop", svc))
+		}
+	}
+	for _, mnt := range cfg.Unmount {
+		if mnt = strings.TrimSpace(mnt); mnt != "" {
+			steps = append(steps, command("Unmounting "+mnt, "umount", mnt))
+		}
+	}
+	if len(cfg.Services) > 0 || len(cfg.Unmount) > 0 {
+		steps = append(steps, command("Syncing disks", "sync"))
+	}
+	if action == "poweroff" && cfg.Spindown {
+		for _, dev := range disks {
+			steps = append(steps, command("Spinning down "+strings.TrimPrefix(dev, "/dev/"), "hdparm", "-y", dev))
+		}
+	}
+	return steps
+}
+
+// Run executes the steps in order, reporting each to progress. A failing step is
+// logged and skipped so a busy mount can never prevent the system from going down.
+func Run(ctx context.Context, title string, steps []Step, timeout time.Duration, progress Progress) {
+	for i, step := range steps {
+		logger.Infof("Shutdown step %d/%d: %s", i+1, len(steps), step.Label)
+		if progress != nil {
+			progress(title, step.Label)
+		}
+
+		stepCtx, cancel := context.WithTimeout(ctx, timeout)
+		err := step.Run(stepCtx)
+		cancel()
+		if err != nil {
+			logger.Errorf("Shutdown step %q failed: %v", step.Label, err)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package shutdown
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kolobock/rockpi-quad-go/internal/config"
+)
+
+func TestSteps(t *testing.T) {
+	disks := []string{"/dev/sda", "/dev/sdb"}
+	full := config.ShutdownConfig{Services: []string{"smbd", " nfs-server"}, Unmount: []string{"/mnt/disk1"}, Spindown: true}
+
+	tests := []struct {
+		name   string
+		cfg    config.ShutdownConfig
+		action string
+		want   []string
+	}{
+		{"sync only", config.ShutdownConfig{}, "poweroff", []string{"Syncing disks"}},
+		{"full poweroff", full, "poweroff", []string{
+			"Syncing disks", "Stopping smbd", "Stopping nfs-server", "Unmounting /mnt/disk1",
+			"Syncing disks", "Spinning down sda", "Spinning down sdb",
+		}},
+		{"reboot keeps disks spinning", full, "reboot", []string{
+			"Syncing disks", "Stopping smbd", "Stopping nfs-server", "Unmounting /mnt/disk1", "Syncing disks",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, step := range Steps(tt.cfg, tt.action, disks) {
+				got = append(got, step.Label)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("Steps() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunContinuesAfterFailure(t *testing.T) {
+	var commands, shown []string
+	orig := runCommand
+	defer func() { runCommand = orig }()
+	runCommand = func(_ context.Context, name string, args ...string) error {
+		commands = append(commands, name+" "+strings.Join(args, " "))
+		if name == "umount" {
+			return errors.New("target is busy")
+		}
+		return nil
+	}
+
+	cfg := config.ShutdownConfig{Unmount: []string{"/mnt/disk1"}, Spindown: true}
+	Run(context.Background(), "Shutting down", Steps(cfg, "poweroff", []string{"/dev/sda"}), time.Second,
+		func(_, text string) { shown = append(shown, text) })
+
+	want := []string{"sync ", "umount /mnt/disk1", "sync ", "hdparm -y /dev/sda"}
+	if strings.Join(commands, ",") != strings.Join(want, ",") {
+		t.Errorf("commands = %q, want %q", commands, want)
+	}
+	if len(shown) != len(want) || shown[3] != "Spinning down sda" {
+		t.Errorf("progress = %v, want one message per step", shown)
+	}
+}
@@ -0,0 +1,248 @@
+// Package snmp is a minimal read-only SNMPv2c agent publishing temperatures, fan
+// speeds and disk health under a private OID tree, for LibreNMS, Zabbix and other
+// SNMP pollers.
+package snmp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+
+	"github.com/kolobock/rockpi-quad-go/internal/logger"
+)
+
+const (
+	versionV2c = 1
+
+	// errNotWritable is the v2c error status answering a set on a read-only agent
+	errNotWritable = 17
+
+	// maxVarBinds bounds a GETBULK response so it fits a single datagram
+	maxVarBinds = 64
+)
+
+// Var is a variable binding; Value is an int, int64, string, Gauge32, Counter32 or
+// TimeTicks
+type Var struct {
+	OID   OID
+	Value any
+}
+
+// Agent answers GET, GETNEXT and GETBULK requests from the variables of its source
+type Agent struct {
+	community string
+	source    func() []Var
+}
+
+// NewAgent creates an agent answering requests with the given community; source
+// returns the current variables and is called once per request
+func NewAgent(community string, source func() []Var) *Agent {
+	return &Agent{community: community, source: source}
+}
+
+// Serve answers requests on conn until the context is cancelled
+func (a *Agent) Serve(ctx context.Context, conn net.PacketConn) error {
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	buf := make([]byte, 65535)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		resp, err := a.handle(buf[:n])
+		if err != nil {
+			logger.Infof("Ignoring SNMP request from %s: %v", addr, err)
+			continue
+		}
+		if resp == nil {
+			continue
+		}
+		if _, err := conn.WriteTo(resp, addr); err != nil {
+			logger.Infof("Failed to answer SNMP request from %s: %v", addr, err)
+		}
+	}
+}
+
+// request is a decoded SNMP message
+type request struct {
+	community string
+	pdu       byte
+	id        int64
+	// nonRepeaters and maxRepetitions are only set for GETBULK
+	nonRepeaters   int64
+	maxRepetitions int64
+	oids           []OID
+}
+
+// handle answers a message; requests for another version or community get no
+// answer, as an agent that does not know them would
+func (a *Agent) handle(msg []byte) ([]byte, error) {
+	req, err := decodeRequest(msg)
+	if err != nil {
+		return nil, err
+	}
+	if req.community != a.community {
+		return nil, nil
+	}
+
+	vars := a.source()
+	sort.Slice(vars, func(i, j int) bool { return compare(vars[i].OID, vars[j].OID) < 0 })
+
+	var errStatus, errIndex int64
+	var out []Var
+	switch req.pdu {
+	case pduGet:
+		for _, oid := range req.oids {
+			out = append(out, get(vars, oid))
+		}
+	case pduGetNext:
+		for _, oid := range req.oids {
+			out = append(out, next(vars, oid))
+		}
+	case pduGetBulk:
+		out = bulk(vars, req)
+	case pduSet:
+		errStatus, errIndex = errNotWritable, 1
+		for _, oid := range req.oids {
+			out = append(out, Var{OID: oid})
+		}
+	default:
+		return nil, fmt.Errorf("unsupported PDU 0x%02x", req.pdu)
+	}
+	return encodeResponse(req, errStatus, errIndex, out)
+}
+
+func get(vars []Var, oid OID) Var {
+	i := sort.Search(len(vars), func(i int) bool { return compare(vars[i].OID, oid) >= 0 })
+	if i < len(vars) && compare(vars[i].OID, oid) == 0 {
+		return vars[i]
+	}
+	return Var{OID: oid, Value: exception(tagNoSuchObject)}
+}
+
+func next(vars []Var, oid OID) Var {
+	i := sort.Search(len(vars), func(i int) bool { return compare(vars[i].OID, oid) > 0 })
+	if i < len(vars) {
+		return vars[i]
+	}
+	return Var{OID: oid, Value: exception(tagEndOfMibView)}
+}
+
+// bulk answers a GETBULK: one GETNEXT for each of the first non-repeaters, then
+// max-repetitions successive GETNEXTs for each of the rest
+func bulk(vars []Var, req request) []Var {
+	nonRepeaters := min(max(req.nonRepeaters, 0), int64(len(req.oids)))
+	var out []Var
+	for _, oid := range req.oids[:nonRepeaters] {
+		out = append(out, next(vars, oid))
+	}
+
+	cursors := append([]OID(nil), req.oids[nonRepeaters:]...)
+	for r := int64(0); r < req.maxRepetitions && len(cursors) > 0; r++ {
+		done := true
+		for i, oid := range cursors {
+			if len(out) >= maxVarBinds {
+				return out
+			}
+			v := next(vars, oid)
+			out = append(out, v)
+			cursors[i] = v.OID
+			if _, end := v.Value.(exception); !end {
+				done = false
+			}
+		}
+		if done {
+			break
+		}
+	}
+	return out
+}
+
+func decodeRequest(msg []byte) (request, error) {
+	var req request
+	body, _, err := readExpected(msg, tagSequence)
+	if err != nil {
+		return req, err
+	}
+	version, body, err := readInt(body)
+	if err != nil {
+		return req, err
+	}
+	if version != versionV2c {
+		return req, fmt.Errorf("unsupported SNMP version %d", version+1)
+	}
+	community, body, err := readExpected(body, tagOctetString)
+	if err != nil {
+		return req, err
+	}
+	req.community = string(community)
+
+	tag, pdu, _, err := readTLV(body)
+	if err != nil {
+		return req, err
+	}
+	req.pdu = tag
+	if req.id, pdu, err = readInt(pdu); err != nil {
+		return req, err
+	}
+	if req.nonRepeaters, pdu, err = readInt(pdu); err != nil {
+		return req, err
+	}
+	if req.maxRepetitions, pdu, err = readInt(pdu); err != nil {
+		return req, err
+	}
+
+	list, _, err := readExpected(pdu, tagSequence)
+	if err != nil {
+		return req, err
+	}
+	for len(list) > 0 {
+		var vb, content []byte
+		if vb, list, err = readExpected(list, tagSequence); err != nil {
+			return req, err
+		}
+		if content, _, err = readExpected(vb, tagOID); err != nil {
+			return req, err
+		}
+		oid, err := decodeOID(content)
+		if err != nil {
+			return req, err
+		}
+		req.oids = append(req.oids, oid)
+	}
+	if len(req.oids) == 0 {
+		return req, errors.New("no variable bindings")
+	}
+	return req, nil
+}
+
+func encodeResponse(req request, errStatus, errIndex int64, vars []Var) ([]byte, error) {
+	var list [][]byte
+	for _, v := range vars {
+		value, err := encodeValue(v.Value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", v.OID, err)
+		}
+		list = append(list, encodeSequence(tagSequence, encodeOID(v.OID), value))
+	}
+	pdu := encodeSequence(pduResponse,
+		encodeInt(tagInteger, req.id),
+		encodeInt(tagInteger, errStatus),
+		encodeInt(tagInteger, errIndex),
+		encodeSequence(tagSequence, list...),
+	)
+	return encodeSequence(tagSequence,
+		encodeInt(tagInteger, versionV2c),
+		encodeTLV(tagOctetString, []byte(req.community)),
+		pdu,
+	), nil
+}
@@ -0,0 +1,207 @@
+package snmp
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testVars() []Var {
+	base, _ := ParseOID("1.3.6.1.4.1.8072.9999.9999.4243")
+	// Out of order on purpose, the agent sorts them
+	return []Var{
+		{OID: base.Append(2, 1, 2, 1), Value: "/dev/sda"},
+		{OID: base.Append(1, 1, 0), Value: int64(452)},
+		{OID: base.Append(2, 1, 2, 2), Value: "/dev/sdb"},
+		{OID: base.Append(1, 2, 0), Value: Gauge32(60)},
+	}
+}
+
+func encodeRequest(community string, pdu byte, a, b int64, oids ...string) []byte {
+	var list [][]byte
+	for _, s := range oids {
+		oid, _ := ParseOID(s)
+		list = append(list, encodeSequence(tagSequence, encodeOID(oid), encodeTLV(tagNull, nil)))
+	}
+	return encodeSequence(tagSequence,
+		encodeInt(tagInteger, versionV2c),
+		encodeTLV(tagOctetString, []byte(community)),
+		encodeSequence(pdu,
+			encodeInt(tagInteger, 42),
+			encodeInt(tagInteger, a),
+			encodeInt(tagInteger, b),
+			encodeSequence(tagSequence, list...),
+		),
+	)
+}
+
+// decodeResponse returns the error status and the variable bindings of a response
+// as "oid=tag"
+func decodeResponse(t *testing.T, msg []byte) (int64, []string) {
+	t.Helper()
+	body, _, err := readExpected(msg, tagSequence)
+	if err != nil {
+		t.Fatalf("response: %v", err)
+	}
+	_, body, _ = readInt(body)
+	_, body, _ = readExpected(body, tagOctetString)
+	pdu, _, err := readExpected(body, pduResponse)
+	if err != nil {
+		t.Fatalf("response PDU: %v", err)
+	}
+	id, pdu, _ := readInt(pdu)
+	if id != 42 {
+		t.Errorf("request ID = %d, want 42", id)
+	}
+	status, pdu, _ := readInt(pdu)
+	_, pdu, _ = readInt(pdu)
+	list, _, err := readExpected(pdu, tagSequence)
+	if err != nil {
+		t.Fatalf("varbind list: %v", err)
+	}
+
+	var binds []string
+	for len(list) > 0 {
+		var vb []byte
+		vb, list, _ = readExpected(list, tagSequence)
+		content, rest, _ := readExpected(vb, tagOID)
+		oid, _ := decodeOID(content)
+		tag, _, _, _ := readTLV(rest)
+		binds = append(binds, strings.TrimPrefix(oid.String(), "1.3.6.1.4.1.8072.9999.9999.4243.")+"="+tagName(tag))
+	}
+	return status, binds
+}
+
+func tagName(tag byte) string {
+	return map[byte]string{
+		tagInteger:        "int",
+		tagOctetString:    "string",
+		tagNull:           "null",
+		tagGauge32:        "gauge",
+		tagNoSuchObject:   "noSuchObject",
+		tagEndOfMibView:   "endOfMibView",
+		tagNoSuchInstance: "noSuchInstance",
+	}[tag]
+}
+
+func TestHandle(t *testing.T) {
+	const base = "1.3.6.1.4.1.8072.9999.9999.4243"
+	tests := []struct {
+		name       string
+		request    []byte
+		wantStatus int64
+		want       []string
+	}{
+		{
+			name:    "get",
+			request: encodeRequest("public", pduGet, 0, 0, base+".1.1.0", base+".1.3.0"),
+			want:    []string{"1.1.0=int", "1.3.0=noSuchObject"},
+		},
+		{
+			name:    "getnext walks in OID order",
+			request: encodeRequest("public", pduGetNext, 0, 0, base, base+".1.2.0", base+".2.1.2.2"),
+			want:    []string{"1.1.0=int", "2.1.2.1=string", "2.1.2.2=endOfMibView"},
+		},
+		{
+			name:    "getbulk",
+			request: encodeRequest("public", pduGetBulk, 1, 3, base, base+".1.2.0"),
+			want:    []string{"1.1.0=int", "2.1.2.1=string", "2.1.2.2=string", "2.1.2.2=endOfMibView"},
+		},
+		{
+			name:       "set is refused",
+			request:    encodeRequest("public", pduSet, 0, 0, base+".1.2.0"),
+			wantStatus: errNotWritable,
+			want:       []string{"1.2.0=null"},
+		},
+	}
+	agent := NewAgent("public", testVars)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := agent.handle(tt.request)
+			if err != nil {
+				t.Fatalf("handle() error = %v", err)
+			}
+			status, binds := decodeResponse(t, resp)
+			if status != tt.wantStatus {
+				t.Errorf("error status = %d, want %d", status, tt.wantStatus)
+			}
+			if strings.Join(binds, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("bindings = %v, want %v", binds, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleIgnored(t *testing.T) {
+	agent := NewAgent("public", testVars)
+	v1 := encodeRequest("public", pduGet, 0, 0, "1.3.6.1")
+	v1[4] = 0 // version field of the encoded message
+
+	tests := []struct {
+		name    string
+		request []byte
+		wantErr bool
+	}{
+		{name: "wrong community", request: encodeRequest("private", pduGet, 0, 0, "1.3.6.1")},
+		{name: "SNMPv1", request: v1, wantErr: true},
+		{name: "garbage", request: []byte{0x30, 0x05, 0x02}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := agent.handle(tt.request)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("handle() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if resp != nil {
+				t.Errorf("handle() answered % x", resp)
+			}
+		})
+	}
+}
+
+func TestBulkLimit(t *testing.T) {
+	base, _ := ParseOID("1.3.6.1.4.1.8072.9999.9999.4243")
+	var vars []Var
+	for i := range 200 {
+		vars = append(vars, Var{OID: base.Append(3, uint32(i)), Value: i}) // #nosec G115 - test indexes
+	}
+	got := bulk(vars, request{maxRepetitions: 1000, oids: []OID{base}})
+	if len(got) != maxVarBinds {
+		t.Errorf("bulk() returned %d bindings, want %d", len(got), maxVarBinds)
+	}
+}
+
+func TestServe(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("UDP unavailable: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- NewAgent("public", testVars).Serve(ctx, conn) }()
+
+	client, err := net.Dial("udp", conn.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	if _, err := client.Write(encodeRequest("public", pduGet, 0, 0, "1.3.6.1.4.1.8072.9999.9999.4243.1.2.0")); err != nil {
+		t.Fatal(err)
+	}
+	_ = client.SetReadDeadline(time.Now().Add(2 * time.Second))
+	buf := make([]byte, 1500)
+	n, err := client.Read(buf)
+	if err != nil {
+		t.Fatalf("no answer: %v", err)
+	}
+	if _, binds := decodeResponse(t, buf[:n]); len(binds) != 1 || binds[0] != "1.2.0=gauge" {
+		t.Errorf("bindings = %v, want [1.2.0=gauge]", binds)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Serve() error = %v", err)
+	}
+}
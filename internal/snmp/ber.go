@@ -0,0 +1,251 @@
+package snmp
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// BER tags used by SNMPv2c
+const (
+	tagInteger     = 0x02
+	tagOctetString = 0x04
+	tagNull        = 0x05
+	tagOID         = 0x06
+	tagSequence    = 0x30
+	tagCounter32   = 0x41
+	tagGauge32     = 0x42
+	tagTimeTicks   = 0x43
+
+	tagNoSuchObject   = 0x80
+	tagNoSuchInstance = 0x81
+	tagEndOfMibView   = 0x82
+
+	pduGet      = 0xa0
+	pduGetNext  = 0xa1
+	pduResponse = 0xa2
+	pduSet      = 0xa3
+	pduGetBulk  = 0xa5
+)
+
+var errTruncated = errors.New("truncated BER encoding")
+
+// OID is an object identifier, e.g. 1.3.6.1.2.1.1.1.0
+type OID []uint32
+
+// ParseOID parses a dotted OID; a leading dot is allowed
+func ParseOID(s string) (OID, error) {
+	parts := strings.Split(strings.TrimPrefix(s, "."), ".")
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("invalid OID %q", s)
+	}
+	oid := make(OID, len(parts))
+	for i, p := range parts {
+		n, err := strconv.ParseUint(p, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid OID %q: %w", s, err)
+		}
+		oid[i] = uint32(n)
+	}
+	return oid, nil
+}
+
+func (o OID) String() string {
+	parts := make([]string, len(o))
+	for i, n := range o {
+		parts[i] = strconv.FormatUint(uint64(n), 10)
+	}
+	return strings.Join(parts, ".")
+}
+
+// Append returns o extended by sub, leaving o unchanged
+func (o OID) Append(sub ...uint32) OID {
+	return append(append(OID(nil), o...), sub...)
+}
+
+// compare orders OIDs lexicographically, as SNMP walks them
+func compare(a, b OID) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		switch {
+		case a[i] < b[i]:
+			return -1
+		case a[i] > b[i]:
+			return 1
+		}
+	}
+	return len(a) - len(b)
+}
+
+// Gauge32 is an unsigned value that may go up and down, e.g. a percentage
+type Gauge32 uint32
+
+// Counter32 is a wrapping counter
+type Counter32 uint32
+
+// TimeTicks is a time in hundredths of a second
+type TimeTicks uint32
+
+// exception values of a variable binding
+type exception byte
+
+func encodeLength(n int) []byte {
+	if n < 0x80 {
+		return []byte{byte(n)}
+	}
+	var b []byte
+	for ; n > 0; n >>= 8 {
+		b = append([]byte{byte(n)}, b...)
+	}
+	return append([]byte{0x80 | byte(len(b))}, b...)
+}
+
+func encodeTLV(tag byte, content []byte) []byte {
+	out := append([]byte{tag}, encodeLength(len(content))...)
+	return append(out, content...)
+}
+
+func encodeSequence(tag byte, items ...[]byte) []byte {
+	var content []byte
+	for _, item := range items {
+		content = append(content, item...)
+	}
+	return encodeTLV(tag, content)
+}
+
+// encodeInt encodes a two's complement integer in the fewest bytes
+func encodeInt(tag byte, n int64) []byte {
+	b := []byte{byte(n)}
+	for n > 127 || n < -128 {
+		n >>= 8
+		b = append([]byte{byte(n)}, b...)
+	}
+	return encodeTLV(tag, b)
+}
+
+// encodeUint encodes an unsigned application type, adding a zero byte when the top
+// bit is set so it does not read as negative
+func encodeUint(tag byte, n uint32) []byte {
+	b := []byte{byte(n)}
+	for v := n >> 8; v > 0; v >>= 8 {
+		b = append([]byte{byte(v)}, b...)
+	}
+	if b[0]&0x80 != 0 {
+		b = append([]byte{0}, b...)
+	}
+	return encodeTLV(tag, b)
+}
+
+func encodeOID(oid OID) []byte {
+	if len(oid) < 2 {
+		return encodeTLV(tagOID, nil)
+	}
+	content := encodeSubID(oid[0]*40 + oid[1])
+	for _, n := range oid[2:] {
+		content = append(content, encodeSubID(n)...)
+	}
+	return encodeTLV(tagOID, content)
+}
+
+func encodeSubID(n uint32) []byte {
+	b := []byte{byte(n & 0x7f)}
+	for n >>= 7; n > 0; n >>= 7 {
+		b = append([]byte{byte(n&0x7f) | 0x80}, b...)
+	}
+	return b
+}
+
+func encodeValue(v any) ([]byte, error) {
+	switch v := v.(type) {
+	case nil:
+		return encodeTLV(tagNull, nil), nil
+	case int:
+		return encodeInt(tagInteger, int64(v)), nil
+	case int64:
+		return encodeInt(tagInteger, v), nil
+	case string:
+		return encodeTLV(tagOctetString, []byte(v)), nil
+	case Gauge32:
+		return encodeUint(tagGauge32, uint32(v)), nil
+	case Counter32:
+		return encodeUint(tagCounter32, uint32(v)), nil
+	case TimeTicks:
+		return encodeUint(tagTimeTicks, uint32(v)), nil
+	case exception:
+		return encodeTLV(byte(v), nil), nil
+	default:
+		return nil, fmt.Errorf("unsupported SNMP value %T", v)
+	}
+}
+
+// readTLV splits the first element off b
+func readTLV(b []byte) (tag byte, content, rest []byte, err error) {
+	if len(b) < 2 {
+		return 0, nil, nil, errTruncated
+	}
+	tag, n := b[0], int(b[1])
+	b = b[2:]
+	if n&0x80 != 0 {
+		size := n & 0x7f
+		if size == 0 || size > 3 || len(b) < size {
+			return 0, nil, nil, errTruncated
+		}
+		n = 0
+		for _, c := range b[:size] {
+			n = n<<8 | int(c)
+		}
+		b = b[size:]
+	}
+	if len(b) < n {
+		return 0, nil, nil, errTruncated
+	}
+	return tag, b[:n], b[n:], nil
+}
+
+// readExpected reads an element that must have the given tag
+func readExpected(b []byte, want byte) (content, rest []byte, err error) {
+	tag, content, rest, err := readTLV(b)
+	if err != nil {
+		return nil, nil, err
+	}
+	if tag != want {
+		return nil, nil, fmt.Errorf("unexpected BER tag 0x%02x, want 0x%02x", tag, want)
+	}
+	return content, rest, nil
+}
+
+func readInt(b []byte) (int64, []byte, error) {
+	content, rest, err := readExpected(b, tagInteger)
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(content) == 0 || len(content) > 8 {
+		return 0, nil, errTruncated
+	}
+	n := int64(int8(content[0]))
+	for _, c := range content[1:] {
+		n = n<<8 | int64(c)
+	}
+	return n, rest, nil
+}
+
+func decodeOID(content []byte) (OID, error) {
+	if len(content) == 0 {
+		return nil, errTruncated
+	}
+	var subs []uint32
+	var n uint32
+	for i, c := range content {
+		n = n<<7 | uint32(c&0x7f)
+		if c&0x80 != 0 {
+			if i == len(content)-1 {
+				return nil, errTruncated
+			}
+			continue
+		}
+		subs = append(subs, n)
+		n = 0
+	}
+	first := min(subs[0]/40, 2)
+	return append(OID{first, subs[0] - first*40}, subs[1:]...), nil
+}
@@ -0,0 +1,121 @@
+package snmp
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestParseOID(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "1.3.6.1.4.1.8072", want: "1.3.6.1.4.1.8072"},
+		{in: ".1.3.6.1.2.1.1.1.0", want: "1.3.6.1.2.1.1.1.0"},
+		{in: "1", wantErr: true},
+		{in: "1.3.x", wantErr: true},
+		{in: "1.3.-1", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			oid, err := ParseOID(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseOID(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if err == nil && oid.String() != tt.want {
+				t.Errorf("ParseOID(%q) = %s, want %s", tt.in, oid, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{a: "1.3.6", b: "1.3.6", want: 0},
+		{a: "1.3.6", b: "1.3.6.1", want: -1},
+		{a: "1.3.7", b: "1.3.6.1", want: 1},
+		{a: "1.3.6.2", b: "1.3.6.10", want: -1},
+	}
+	for _, tt := range tests {
+		a, _ := ParseOID(tt.a)
+		b, _ := ParseOID(tt.b)
+		got := compare(a, b)
+		if (got < 0) != (tt.want < 0) || (got > 0) != (tt.want > 0) {
+			t.Errorf("compare(%s, %s) = %d, want sign of %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestEncodeInt(t *testing.T) {
+	tests := []struct {
+		n    int64
+		want []byte
+	}{
+		{n: 0, want: []byte{0x02, 0x01, 0x00}},
+		{n: 127, want: []byte{0x02, 0x01, 0x7f}},
+		{n: 128, want: []byte{0x02, 0x02, 0x00, 0x80}},
+		{n: -1, want: []byte{0x02, 0x01, 0xff}},
+		{n: -129, want: []byte{0x02, 0x02, 0xff, 0x7f}},
+		{n: 452, want: []byte{0x02, 0x02, 0x01, 0xc4}},
+	}
+	for _, tt := range tests {
+		got := encodeInt(tagInteger, tt.n)
+		if !bytes.Equal(got, tt.want) {
+			t.Errorf("encodeInt(%d) = % x, want % x", tt.n, got, tt.want)
+		}
+		n, rest, err := readInt(got)
+		if err != nil || n != tt.n || len(rest) != 0 {
+			t.Errorf("readInt(% x) = %d, %v, want %d", got, n, err, tt.n)
+		}
+	}
+}
+
+func TestEncodeUint(t *testing.T) {
+	tests := []struct {
+		n    uint32
+		want []byte
+	}{
+		{n: 0, want: []byte{0x42, 0x01, 0x00}},
+		{n: 100, want: []byte{0x42, 0x01, 0x64}},
+		{n: 200, want: []byte{0x42, 0x02, 0x00, 0xc8}},
+		{n: 1<<32 - 1, want: []byte{0x42, 0x05, 0x00, 0xff, 0xff, 0xff, 0xff}},
+	}
+	for _, tt := range tests {
+		if got := encodeUint(tagGauge32, tt.n); !bytes.Equal(got, tt.want) {
+			t.Errorf("encodeUint(%d) = % x, want % x", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestOIDRoundTrip(t *testing.T) {
+	for _, s := range []string{"1.3.6.1.4.1.8072.9999.9999.4243.2.1.5.1", "2.999.3", "0.0"} {
+		oid, _ := ParseOID(s)
+		content, rest, err := readExpected(encodeOID(oid), tagOID)
+		if err != nil || len(rest) != 0 {
+			t.Fatalf("readExpected(%s) error = %v", s, err)
+		}
+		got, err := decodeOID(content)
+		if err != nil || got.String() != s {
+			t.Errorf("decodeOID(encodeOID(%s)) = %s, %v", s, got, err)
+		}
+	}
+}
+
+func TestLongLength(t *testing.T) {
+	content := bytes.Repeat([]byte{'x'}, 300)
+	encoded := encodeTLV(tagOctetString, content)
+	if !bytes.Equal(encoded[:4], []byte{0x04, 0x82, 0x01, 0x2c}) {
+		t.Fatalf("header = % x, want 04 82 01 2c", encoded[:4])
+	}
+	tag, got, rest, err := readTLV(encoded)
+	if err != nil || tag != tagOctetString || !bytes.Equal(got, content) || len(rest) != 0 {
+		t.Errorf("readTLV() = 0x%02x, %d bytes, %v", tag, len(got), err)
+	}
+	if _, _, _, err := readTLV(encoded[:100]); err == nil {
+		t.Error("readTLV() of a truncated element succeeded")
+	}
+}
@@ -0,0 +1,116 @@
+package snmp
+
+// DefaultBase is the root of the published tree, a branch of NET-SNMP-MIB::netSnmpPlaypen
+// (1.3.6.1.4.1.8072.9999.9999), which net-snmp sets aside for unregistered local trees.
+// Below it:
+//
+//	base.1.1.0    cpuTemperature    INTEGER, tenths of °C
+//	base.1.2.0    cpuFanDuty        Gauge32, percent
+//	base.1.3.0    diskFanDuty       Gauge32, percent
+//	base.1.4.0    thermalEmergency  INTEGER, TruthValue (1 true, 2 false)
+//	base.1.5.0    diskCount         Gauge32
+//	base.2.1.1.i  diskIndex         INTEGER, 1 to diskCount
+//	base.2.1.2.i  diskDevice        OCTET STRING, e.g. /dev/sda
+//	base.2.1.3.i  diskID            OCTET STRING, persistent ID
+//	base.2.1.4.i  diskLabel         OCTET STRING, alias or device name
+//	base.2.1.5.i  diskTemperature   INTEGER, tenths of °C
+//	base.2.1.6.i  diskHealth        INTEGER, DiskHealth
+//	base.2.1.7.i  diskReallocated   Gauge32, reallocated sectors
+//	base.2.1.8.i  diskPending       Gauge32, sectors pending reallocation
+//
+// Readings that are unavailable, such as temperatures without smartctl, are left out.
+const DefaultBase = "1.3.6.1.4.1.8072.9999.9999.4243"
+
+// DiskHealth is the SMART verdict published as diskHealth
+type DiskHealth int
+
+const (
+	HealthUnknown DiskHealth = iota
+	HealthOK
+	HealthWarn
+	HealthFailed
+)
+
+const (
+	truthTrue  = 1
+	truthFalse = 2
+)
+
+// Readings are the values published by the agent
+type Readings struct {
+	CPUTemp *float64
+	// Fan is nil while the fan module is off
+	Fan   *Fan
+	Disks []Disk
+}
+
+// Fan is the fan state; duty cycles are percentages (0-100)
+type Fan struct {
+	CPUDuty   float64
+	DiskDuty  float64
+	Emergency bool
+}
+
+// Disk is a SATA disk; Temp is nil while it cannot be read
+type Disk struct {
+	Device      string
+	ID          string
+	Label       string
+	Temp        *float64
+	Health      DiskHealth
+	Reallocated int64
+	Pending     int64
+}
+
+// Vars maps readings onto the tree under base
+func Vars(base OID, r Readings) []Var {
+	var vars []Var
+	add := func(v any, sub ...uint32) {
+		vars = append(vars, Var{OID: base.Append(sub...), Value: v})
+	}
+
+	if r.CPUTemp != nil {
+		add(tenths(*r.CPUTemp), 1, 1, 0)
+	}
+	if r.Fan != nil {
+		add(percent(r.Fan.CPUDuty), 1, 2, 0)
+		add(percent(r.Fan.DiskDuty), 1, 3, 0)
+		emergency := truthFalse
+		if r.Fan.Emergency {
+			emergency = truthTrue
+		}
+		add(emergency, 1, 4, 0)
+	}
+	add(Gauge32(len(r.Disks)), 1, 5, 0) // #nosec G115 - a handful of disks
+
+	for i, d := range r.Disks {
+		idx := uint32(i + 1) // #nosec G115 - a handful of disks
+		add(int(idx), 2, 1, 1, idx)
+		add(d.Device, 2, 1, 2, idx)
+		add(d.ID, 2, 1, 3, idx)
+		add(d.Label, 2, 1, 4, idx)
+		if d.Temp != nil {
+			add(tenths(*d.Temp), 2, 1, 5, idx)
+		}
+		add(int(d.Health), 2, 1, 6, idx)
+		add(sectors(d.Reallocated), 2, 1, 7, idx)
+		add(sectors(d.Pending), 2, 1, 8, idx)
+	}
+	return vars
+}
+
+// tenths keeps one decimal of a temperature, as SNMP has no floating point type
+func tenths(temp float64) int64 {
+	if temp < 0 {
+		return int64(temp*10 - 0.5)
+	}
+	return int64(temp*10 + 0.5)
+}
+
+func percent(duty float64) Gauge32 {
+	return Gauge32(min(max(duty, 0), 100) + 0.5)
+}
+
+func sectors(n int64) Gauge32 {
+	return Gauge32(min(max(n, 0), 1<<32-1)) // #nosec G115 - clamped to the gauge range
+}
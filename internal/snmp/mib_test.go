@@ -0,0 +1,60 @@
+package snmp
+
+import (
+	"testing"
+)
+
+func TestVars(t *testing.T) {
+	base, _ := ParseOID(DefaultBase)
+	cpu, hot := 45.23, 38.96
+	r := Readings{
+		CPUTemp: &cpu,
+		Fan:     &Fan{CPUDuty: 62.5, DiskDuty: 120, Emergency: true},
+		Disks: []Disk{
+			{Device: "/dev/sda", ID: "ata-A", Label: "bay1", Temp: &hot, Health: HealthOK},
+			{Device: "/dev/sdb", ID: "ata-B", Label: "sdb", Health: HealthWarn, Reallocated: 8, Pending: -1},
+		},
+	}
+
+	want := map[string]any{
+		"1.1.0":   int64(452),
+		"1.2.0":   Gauge32(63),
+		"1.3.0":   Gauge32(100),
+		"1.4.0":   truthTrue,
+		"1.5.0":   Gauge32(2),
+		"2.1.1.1": 1,
+		"2.1.2.1": "/dev/sda",
+		"2.1.3.1": "ata-A",
+		"2.1.4.1": "bay1",
+		"2.1.5.1": int64(390),
+		"2.1.6.1": int(HealthOK),
+		"2.1.7.1": Gauge32(0),
+		"2.1.8.1": Gauge32(0),
+		"2.1.1.2": 2,
+		"2.1.2.2": "/dev/sdb",
+		"2.1.3.2": "ata-B",
+		"2.1.4.2": "sdb",
+		"2.1.6.2": int(HealthWarn),
+		"2.1.7.2": Gauge32(8),
+		"2.1.8.2": Gauge32(0),
+	}
+	vars := Vars(base, r)
+	if len(vars) != len(want) {
+		t.Errorf("Vars() returned %d variables, want %d", len(vars), len(want))
+	}
+	prefix := DefaultBase + "."
+	for _, v := range vars {
+		sub := v.OID.String()[len(prefix):]
+		if w, ok := want[sub]; !ok || w != v.Value {
+			t.Errorf("%s = %#v, want %#v", sub, v.Value, w)
+		}
+	}
+}
+
+func TestVarsWithoutReadings(t *testing.T) {
+	base, _ := ParseOID(DefaultBase)
+	vars := Vars(base, Readings{})
+	if len(vars) != 1 || vars[0].Value != Gauge32(0) {
+		t.Errorf("Vars() = %v, want only diskCount 0", vars)
+	}
+}
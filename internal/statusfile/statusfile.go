@@ -0,0 +1,240 @@
+// Package statusfile periodically writes the daemon state to a JSON file, so tools
+// such as an OMV plugin, a node_exporter textfile script or shell scripts can read
+// it without the HTTP API.
+package statusfile
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/kolobock/rockpi-quad-go/internal/logger"
+)
+
+// procDir is replaced in tests
+var procDir = "/proc"
+
+// Status is the content of the status file
+type Status struct {
+	Time    time.Time `json:"time"`
+	CPUTemp *float64  `json:"cpu_temp,omitempty"`
+	Fan     *Fan      `json:"fan,omitempty"`
+	Disks   []Disk    `json:"disks"`
+	Usage   []Usage   `json:"usage"`
+	// Load is the 1, 5 and 15 minute load average
+	Load   []float64 `json:"load,omitempty"`
+	Memory *Memory   `json:"memory,omitempty"`
+}
+
+// Fan is the fan state; duty cycles are percentages (0-100)
+type Fan struct {
+	Enabled   bool    `json:"enabled"`
+	CPUDuty   float64 `json:"cpu_duty"`
+	DiskDuty  float64 `json:"disk_duty"`
+	Emergency bool    `json:"emergency"`
+}
+
+// Disk is a SATA disk; Temp is missing while it cannot be read
+type Disk struct {
+	Device string   `json:"device"`
+	ID     string   `json:"id"`
+	Label  string   `json:"label"`
+	Temp   *float64 `json:"temp,omitempty"`
+}
+
+// Usage is the space used on a mounted filesystem
+type Usage struct {
+	Mount       string  `json:"mount"`
+	TotalBytes  uint64  `json:"total_bytes"`
+	UsedBytes   uint64  `json:"used_bytes"`
+	UsedPercent float64 `json:"used_percent"`
+}
+
+// Memory is the system memory, available as reported by the kernel
+type Memory struct {
+	TotalBytes     uint64 `json:"total_bytes"`
+	AvailableBytes uint64 `json:"available_bytes"`
+}
+
+// Sources provide the readings written; nil functions leave their part out
+type Sources struct {
+	CPUTemp func() (float64, bool)
+	Fan     func() Fan
+	Disks   func() []Disk
+	// Mounts are the filesystems whose usage is written
+	Mounts []string
+}
+
+// Writer writes the status file
+type Writer struct {
+	path    string
+	src     Sources
+	failing bool
+}
+
+// New creates a writer for path
+func New(path string, src Sources) *Writer {
+	return &Writer{path: path, src: src}
+}
+
+// Run writes the status file every interval until the context is cancelled, then
+// removes it so readers do not mistake a stopped daemon for a running one
+func (w *Writer) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		w.update()
+		select {
+		case <-ctx.Done():
+			if err := os.Remove(w.path); err != nil && !os.IsNotExist(err) {
+				logger.Errorf("Failed to remove status file: %v", err)
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// update writes the current status, logging only the first of consecutive failures
+func (w *Writer) update() {
+	err := w.write(w.collect(time.Now()))
+	switch {
+	case err != nil && !w.failing:
+		logger.Errorf("Failed to write status file: %v", err)
+	case err == nil && w.failing:
+		logger.Infof("Status file %s written again", w.path)
+	}
+	w.failing = err != nil
+}
+
+func (w *Writer) collect(now time.Time) Status {
+	st := Status{Time: now, Disks: []Disk{}, Usage: []Usage{}}
+	if w.src.CPUTemp != nil {
+		if temp, ok := w.src.CPUTemp(); ok {
+			st.CPUTemp = &temp
+		}
+	}
+	if w.src.Fan != nil {
+		f := w.src.Fan()
+		st.Fan = &f
+	}
+	if w.src.Disks != nil {
+		if disks := w.src.Disks(); disks != nil {
+			st.Disks = disks
+		}
+	}
+	for _, mount := range w.src.Mounts {
+		if u, err := mountUsage(mount); err == nil {
+			st.Usage = append(st.Usage, u)
+		}
+	}
+	st.Load = readLoad()
+	st.Memory = readMemory()
+	return st
+}
+
+// write replaces the file atomically, so readers never see a partial file
+func (w *Writer) write(st Status) error {
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(w.path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(w.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	// CreateTemp makes the file private; the status is meant for other tools
+	if err := tmp.Chmod(0o644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), w.path)
+}
+
+// mountUsage reads the space used on the filesystem at mount, like df
+func mountUsage(mount string) (Usage, error) {
+	var fs syscall.Statfs_t
+	if err := syscall.Statfs(mount, &fs); err != nil {
+		return Usage{}, err
+	}
+	bsize := uint64(fs.Bsize) // #nosec G115 - block sizes are positive
+	total := fs.Blocks * bsize
+	used := (fs.Blocks - fs.Bfree) * bsize
+	avail := fs.Bavail * bsize
+
+	u := Usage{Mount: mount, TotalBytes: total, UsedBytes: used}
+	if used+avail > 0 {
+		u.UsedPercent = float64(used) / float64(used+avail) * 100
+	}
+	return u, nil
+}
+
+func readLoad() []float64 {
+	data, err := os.ReadFile(filepath.Join(procDir, "loadavg"))
+	if err != nil {
+		return nil
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) < 3 {
+		return nil
+	}
+	load := make([]float64, 3)
+	for i := range load {
+		if load[i], err = strconv.ParseFloat(fields[i], 64); err != nil {
+			return nil
+		}
+	}
+	return load
+}
+
+func readMemory() *Memory {
+	f, err := os.Open(filepath.Join(procDir, "meminfo"))
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var mem Memory
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		kb, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		switch fields[0] {
+		case "MemTotal:":
+			mem.TotalBytes = kb * 1024
+		case "MemAvailable:":
+			mem.AvailableBytes = kb * 1024
+		}
+	}
+	if mem.TotalBytes == 0 {
+		return nil
+	}
+	return &mem
+}
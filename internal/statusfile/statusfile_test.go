@@ -0,0 +1,113 @@
+package statusfile
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestWriteAndRemove(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rockpi-quad", "status.json")
+	temp := 41.0
+	w := New(path, Sources{
+		CPUTemp: func() (float64, bool) { return 52.5, true },
+		Fan:     func() Fan { return Fan{Enabled: true, CPUDuty: 25, DiskDuty: 50} },
+		Disks:   func() []Disk { return []Disk{{Device: "/dev/sda", ID: "wwn-0x1", Label: "Bay 1", Temp: &temp}} },
+		Mounts:  []string{filepath.Dir(filepath.Dir(path))},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		w.Run(ctx, time.Hour)
+		close(done)
+	}()
+
+	var data []byte
+	deadline := time.Now().Add(time.Second)
+	for {
+		var err error
+		if data, err = os.ReadFile(path); err == nil || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	var st Status
+	if err := json.Unmarshal(data, &st); err != nil {
+		t.Fatalf("status file %q: %v", data, err)
+	}
+	if st.CPUTemp == nil || *st.CPUTemp != 52.5 || st.Fan == nil || st.Fan.DiskDuty != 50 {
+		t.Errorf("status = %+v, want CPU 52.5 and disk fan 50%%", st)
+	}
+	if len(st.Disks) != 1 || st.Disks[0].Temp == nil || *st.Disks[0].Temp != 41 {
+		t.Errorf("Disks = %+v, want Bay 1 at 41", st.Disks)
+	}
+	if len(st.Usage) != 1 || st.Usage[0].TotalBytes == 0 {
+		t.Errorf("Usage = %+v, want the temp dir filesystem", st.Usage)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o644 {
+		t.Errorf("status file mode = %v (%v), want 0644", info.Mode().Perm(), err)
+	}
+
+	cancel()
+	<-done
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("status file left behind after shutdown: %v", err)
+	}
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 0 {
+		t.Errorf("temporary files left behind: %v", entries)
+	}
+}
+
+func TestCollectWithoutSources(t *testing.T) {
+	st := New("", Sources{Disks: func() []Disk { return nil }}).collect(time.Now())
+	data, err := json.Marshal(st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]any
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := got["fan"]; ok {
+		t.Error("status has a fan without a fan source")
+	}
+	if disks, ok := got["disks"].([]any); !ok || len(disks) != 0 {
+		t.Errorf("disks = %v, want an empty list", got["disks"])
+	}
+}
+
+func TestReadProc(t *testing.T) {
+	dir := t.TempDir()
+	orig := procDir
+	t.Cleanup(func() { procDir = orig })
+	procDir = dir
+
+	if readLoad() != nil || readMemory() != nil {
+		t.Error("missing proc files should read as nil")
+	}
+
+	files := map[string]string{
+		"loadavg": "0.52 0.58 0.59 1/389 12345\n",
+		"meminfo": "MemTotal:        3884612 kB\nMemFree:          123456 kB\nMemAvailable:    2942316 kB\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := readLoad(); !slices.Equal(got, []float64{0.52, 0.58, 0.59}) {
+		t.Errorf("readLoad() = %v", got)
+	}
+	want := Memory{TotalBytes: 3884612 * 1024, AvailableBytes: 2942316 * 1024}
+	if got := readMemory(); got == nil || *got != want {
+		t.Errorf("readMemory() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,152 @@
+package store
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kolobock/rockpi-quad-go/internal/logger"
+)
+
+const (
+	// smartInterval is how often SMART attributes are snapshotted
+	smartInterval = time.Hour
+	// pruneInterval is how often data past the retention period is deleted
+	pruneInterval = 24 * time.Hour
+)
+
+// sysClassNet is replaced in tests
+var sysClassNet = "/sys/class/net"
+
+// Sources provide the readings the recorder stores; nil functions are skipped
+type Sources struct {
+	// CPUTemp returns the CPU temperature, false while it is unknown
+	CPUTemp func() (float64, bool)
+	// DiskTemps returns disk temperatures keyed by persistent disk ID
+	DiskTemps func() map[string]float64
+	// SMART returns SMART attributes keyed by persistent disk ID
+	SMART func() map[string]map[string]int64
+	// DiskTempHistory returns the temperatures the disks logged themselves, keyed
+	// by persistent disk ID; it is read once to seed the disk series
+	DiskTempHistory func() map[string][]Sample
+	// Interfaces are the network interfaces whose traffic is totalled
+	Interfaces []string
+}
+
+// Recorder samples its sources into a store
+type Recorder struct {
+	store *Store
+	src   Sources
+
+	netLast   map[string][2]uint64
+	lastSMART time.Time
+	lastPrune time.Time
+}
+
+// NewRecorder creates a recorder writing to s
+func NewRecorder(s *Store, src Sources) *Recorder {
+	return &Recorder{store: s, src: src, netLast: make(map[string][2]uint64)}
+}
+
+// Run samples every interval until the context is cancelled
+func (r *Recorder) Run(ctx context.Context, interval time.Duration) {
+	r.seed()
+	r.sample(time.Now())
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			r.sample(now)
+		}
+	}
+}
+
+func (r *Recorder) sample(now time.Time) {
+	if r.src.CPUTemp != nil {
+		if temp, ok := r.src.CPUTemp(); ok {
+			logWriteError(r.store.RecordTemp("cpu", now, temp))
+		}
+	}
+	if r.src.DiskTemps != nil {
+		for id, temp := range r.src.DiskTemps() {
+			logWriteError(r.store.RecordTemp("disk:"+id, now, temp))
+		}
+	}
+	r.sampleNetwork(now)
+
+	if r.src.SMART != nil && now.Sub(r.lastSMART) >= smartInterval {
+		r.lastSMART = now
+		for id, attrs := range r.src.SMART() {
+			logWriteError(r.store.RecordSMART(id, now, attrs))
+		}
+	}
+	if now.Sub(r.lastPrune) >= pruneInterval {
+		r.lastPrune = now
+		logWriteError(r.store.Prune(now))
+	}
+}
+
+// seed fills the disk series with the history the disks logged before the daemon
+// started, in the hours nothing was recorded
+func (r *Recorder) seed() {
+	if r.src.DiskTempHistory == nil {
+		return
+	}
+	for id, samples := range r.src.DiskTempHistory() {
+		added, err := r.store.SeedTemps("disk:"+id, samples)
+		if err != nil {
+			logWriteError(err)
+			continue
+		}
+		logger.Infof("Seeded %d hours of temperature history for %s", added, id)
+	}
+}
+
+// sampleNetwork adds the traffic since the previous sample to the daily totals.
+// Traffic while the daemon was stopped is not counted; a counter that went
+// backwards (interface reset) counts from zero.
+func (r *Recorder) sampleNetwork(now time.Time) {
+	for _, iface := range r.src.Interfaces {
+		rx, errRx := readCounter(iface, "rx_bytes")
+		tx, errTx := readCounter(iface, "tx_bytes")
+		if errRx != nil || errTx != nil {
+			continue
+		}
+
+		last, seen := r.netLast[iface]
+		r.netLast[iface] = [2]uint64{rx, tx}
+		if !seen {
+			continue
+		}
+		logWriteError(r.store.AddNetwork(iface, now, counterDelta(last[0], rx), counterDelta(last[1], tx)))
+	}
+}
+
+func counterDelta(prev, cur uint64) uint64 {
+	if cur < prev {
+		return cur
+	}
+	return cur - prev
+}
+
+func readCounter(iface, name string) (uint64, error) {
+	data, err := os.ReadFile(filepath.Join(sysClassNet, iface, "statistics", name))
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+}
+
+func logWriteError(err error) {
+	if err != nil {
+		logger.Errorf("Failed to write metrics store: %v", err)
+	}
+}
@@ -0,0 +1,80 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func writeCounters(t *testing.T, iface string, rx, tx uint64) {
+	t.Helper()
+	dir := filepath.Join(sysClassNet, iface, "statistics")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, v := range map[string]uint64{"rx_bytes": rx, "tx_bytes": tx} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(strconv.FormatUint(v, 10)+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRecorderSample(t *testing.T) {
+	orig := sysClassNet
+	sysClassNet = t.TempDir()
+	t.Cleanup(func() { sysClassNet = orig })
+
+	s := openTestStore(t, filepath.Join(t.TempDir(), "metrics.db"))
+	defer s.Close()
+
+	smartCalls := 0
+	r := NewRecorder(s, Sources{
+		CPUTemp:   func() (float64, bool) { return 45, true },
+		DiskTemps: func() map[string]float64 { return map[string]float64{"wwn-1": 35} },
+		SMART: func() map[string]map[string]int64 {
+			smartCalls++
+			return map[string]map[string]int64{"wwn-1": {"Power_On_Hours": 10}}
+		},
+		DiskTempHistory: func() map[string][]Sample {
+			return map[string][]Sample{"wwn-1": {{Time: time.Date(2026, 3, 1, 8, 30, 0, 0, time.Local), Temp: 33}}}
+		},
+		Interfaces: []string{"eth0", "missing0"},
+	})
+	r.seed()
+
+	now := time.Date(2026, 3, 1, 10, 0, 0, 0, time.Local)
+	steps := []struct {
+		rx, tx uint64
+	}{
+		{1000, 500}, // baseline, not counted
+		{1600, 700},
+		{200, 100}, // counters reset
+	}
+	for i, step := range steps {
+		writeCounters(t, "eth0", step.rx, step.tx)
+		r.sample(now.Add(time.Duration(i) * time.Minute))
+	}
+
+	totals, err := s.Network(now, now.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(totals) != 1 || totals[0].RxBytes != 800 || totals[0].TxBytes != 300 {
+		t.Errorf("network totals = %+v, want rx 800 tx 300", totals)
+	}
+
+	for _, series := range []string{"cpu", "disk:wwn-1"} {
+		aggs, _ := s.Temps(series, now, now.Add(time.Hour))
+		if len(aggs) != 1 || aggs[0].Count != len(steps) {
+			t.Errorf("%s aggregates = %+v, want one hour of %d samples", series, aggs, len(steps))
+		}
+	}
+	if aggs, _ := s.Temps("disk:wwn-1", now.Add(-2*time.Hour), now.Add(-time.Hour)); len(aggs) != 1 || aggs[0].Max != 33 {
+		t.Errorf("seeded disk history = %+v, want the 08:00 hour from the disk log", aggs)
+	}
+	if smartCalls != 1 {
+		t.Errorf("SMART read %d times within an hour, want 1", smartCalls)
+	}
+}
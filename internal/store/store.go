@@ -0,0 +1,345 @@
+// Package store keeps downsampled metrics in an embedded bbolt database so their
+// history survives restarts: hourly temperature aggregates, daily network totals and
+// daily SMART attribute snapshots.
+package store
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Top-level buckets; each holds one nested bucket per series, interface or disk,
+// keyed by the start of the hour or day as big-endian Unix seconds
+var (
+	tempsBucket   = []byte("temps")
+	networkBucket = []byte("network")
+	smartBucket   = []byte("smart")
+)
+
+// Aggregate summarizes the temperature samples of one hour
+type Aggregate struct {
+	Time  time.Time
+	Min   float64
+	Max   float64
+	Sum   float64
+	Count int
+}
+
+// Avg returns the mean of the samples
+func (a Aggregate) Avg() float64 {
+	if a.Count == 0 {
+		return 0
+	}
+	return a.Sum / float64(a.Count)
+}
+
+// Sample is a single temperature reading
+type Sample struct {
+	Time time.Time
+	Temp float64
+}
+
+// NetTotal is the traffic of one interface on one day
+type NetTotal struct {
+	Date      time.Time
+	Interface string
+	RxBytes   uint64
+	TxBytes   uint64
+}
+
+// SMARTSnapshot holds the raw SMART attribute values of a disk, one per day
+type SMARTSnapshot struct {
+	Time       time.Time
+	Disk       string
+	Attributes map[string]int64
+}
+
+type aggregateValue struct {
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+	Sum   float64 `json:"sum"`
+	Count int     `json:"count"`
+}
+
+type netValue struct {
+	Rx uint64 `json:"rx"`
+	Tx uint64 `json:"tx"`
+}
+
+type smartValue struct {
+	Time       time.Time        `json:"time"`
+	Attributes map[string]int64 `json:"attributes"`
+}
+
+// Store is the metrics database
+type Store struct {
+	db        *bolt.DB
+	retention time.Duration
+}
+
+// Open opens or creates the database at path; data older than retention is pruned
+func Open(path string, retention time.Duration) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create store directory: %w", err)
+	}
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open store: %w", err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{tempsBucket, networkBucket, smartBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize store: %w", err)
+	}
+	return &Store{db: db, retention: retention}, nil
+}
+
+// Close closes the database
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// RecordTemp adds a temperature sample to the hourly aggregate of a series such as
+// "cpu" or "disk:<id>"
+func (s *Store) RecordTemp(series string, t time.Time, temp float64) error {
+	key := timeKey(t.Truncate(time.Hour))
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.Bucket(tempsBucket).CreateBucketIfNotExists([]byte(series))
+		if err != nil {
+			return err
+		}
+		agg := aggregateValue{Min: temp, Max: temp}
+		if err := decode(b.Get(key), &agg); err != nil {
+			return err
+		}
+		agg.Min = min(agg.Min, temp)
+		agg.Max = max(agg.Max, temp)
+		agg.Sum += temp
+		agg.Count++
+		return put(b, key, agg)
+	})
+}
+
+// SeedTemps adds samples from before the daemon started, such as a drive's own
+// temperature log, to the hourly aggregates of a series. Hours that already hold
+// data are left alone, so seeding on every start never counts a sample twice.
+// It returns the number of hours added.
+func (s *Store) SeedTemps(series string, samples []Sample) (int, error) {
+	hours := make(map[time.Time]*aggregateValue)
+	for _, sample := range samples {
+		hour := sample.Time.Truncate(time.Hour)
+		agg, ok := hours[hour]
+		if !ok {
+			agg = &aggregateValue{Min: sample.Temp, Max: sample.Temp}
+			hours[hour] = agg
+		}
+		agg.Min = min(agg.Min, sample.Temp)
+		agg.Max = max(agg.Max, sample.Temp)
+		agg.Sum += sample.Temp
+		agg.Count++
+	}
+
+	added := 0
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.Bucket(tempsBucket).CreateBucketIfNotExists([]byte(series))
+		if err != nil {
+			return err
+		}
+		for hour, agg := range hours {
+			key := timeKey(hour)
+			if b.Get(key) != nil {
+				continue
+			}
+			if err := put(b, key, *agg); err != nil {
+				return err
+			}
+			added++
+		}
+		return nil
+	})
+	return added, err
+}
+
+// Temps returns the hourly aggregates of a series between from and to
+func (s *Store) Temps(series string, from, to time.Time) ([]Aggregate, error) {
+	var aggs []Aggregate
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(tempsBucket).Bucket([]byte(series))
+		return forRange(b, from, to, func(t time.Time, v []byte) error {
+			var agg aggregateValue
+			if err := decode(v, &agg); err != nil {
+				return err
+			}
+			aggs = append(aggs, Aggregate{Time: t, Min: agg.Min, Max: agg.Max, Sum: agg.Sum, Count: agg.Count})
+			return nil
+		})
+	})
+	return aggs, err
+}
+
+// Series lists the temperature series with stored data
+func (s *Store) Series() ([]string, error) {
+	var names []string
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(tempsBucket).ForEachBucket(func(k []byte) error {
+			names = append(names, string(k))
+			return nil
+		})
+	})
+	return names, err
+}
+
+// AddNetwork adds traffic of an interface to its total for the day of t
+func (s *Store) AddNetwork(iface string, t time.Time, rx, tx uint64) error {
+	key := timeKey(startOfDay(t))
+	return s.db.Update(func(btx *bolt.Tx) error {
+		b, err := btx.Bucket(networkBucket).CreateBucketIfNotExists([]byte(iface))
+		if err != nil {
+			return err
+		}
+		var total netValue
+		if err := decode(b.Get(key), &total); err != nil {
+			return err
+		}
+		total.Rx += rx
+		total.Tx += tx
+		return put(b, key, total)
+	})
+}
+
+// Network returns the daily totals of every interface between from and to, by date
+func (s *Store) Network(from, to time.Time) ([]NetTotal, error) {
+	var totals []NetTotal
+	err := s.db.View(func(tx *bolt.Tx) error {
+		root := tx.Bucket(networkBucket)
+		return root.ForEachBucket(func(iface []byte) error {
+			return forRange(root.Bucket(iface), startOfDay(from), to, func(t time.Time, v []byte) error {
+				var total netValue
+				if err := decode(v, &total); err != nil {
+					return err
+				}
+				totals = append(totals, NetTotal{Date: t, Interface: string(iface), RxBytes: total.Rx, TxBytes: total.Tx})
+				return nil
+			})
+		})
+	})
+	sort.SliceStable(totals, func(i, j int) bool { return totals[i].Date.Before(totals[j].Date) })
+	return totals, err
+}
+
+// RecordSMART stores the SMART attributes of a disk as its snapshot for the day of t,
+// replacing an earlier snapshot of the same day
+func (s *Store) RecordSMART(disk string, t time.Time, attrs map[string]int64) error {
+	key := timeKey(startOfDay(t))
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.Bucket(smartBucket).CreateBucketIfNotExists([]byte(disk))
+		if err != nil {
+			return err
+		}
+		return put(b, key, smartValue{Time: t, Attributes: attrs})
+	})
+}
+
+// SMART returns the daily snapshots of a disk between from and to
+func (s *Store) SMART(disk string, from, to time.Time) ([]SMARTSnapshot, error) {
+	var snapshots []SMARTSnapshot
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(smartBucket).Bucket([]byte(disk))
+		return forRange(b, startOfDay(from), to, func(_ time.Time, v []byte) error {
+			var snap smartValue
+			if err := decode(v, &snap); err != nil {
+				return err
+			}
+			snapshots = append(snapshots, SMARTSnapshot{Time: snap.Time, Disk: disk, Attributes: snap.Attributes})
+			return nil
+		})
+	})
+	return snapshots, err
+}
+
+// Prune deletes data older than the retention period
+func (s *Store) Prune(now time.Time) error {
+	if s.retention <= 0 {
+		return nil
+	}
+	cutoff := timeKey(now.Add(-s.retention))
+	return s.db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{tempsBucket, networkBucket, smartBucket} {
+			root := tx.Bucket(name)
+			err := root.ForEachBucket(func(k []byte) error {
+				c := root.Bucket(k).Cursor()
+				for key, _ := c.First(); key != nil && bytes.Compare(key, cutoff) < 0; key, _ = c.First() {
+					if err := c.Delete(); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// forRange calls fn for every entry of b from from up to and including to; b may be nil
+func forRange(b *bolt.Bucket, from, to time.Time, fn func(t time.Time, v []byte) error) error {
+	if b == nil {
+		return nil
+	}
+	end := timeKey(to)
+	c := b.Cursor()
+	for k, v := c.Seek(timeKey(from)); k != nil && bytes.Compare(k, end) <= 0; k, v = c.Next() {
+		if err := fn(keyTime(k), v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func timeKey(t time.Time) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(t.Unix())) // #nosec G115 - times are after 1970
+	return key
+}
+
+func keyTime(k []byte) time.Time {
+	return time.Unix(int64(binary.BigEndian.Uint64(k)), 0) // #nosec G115 - keys come from timeKey
+}
+
+// startOfDay returns local midnight of the day of t
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+func decode(v []byte, dst any) error {
+	if v == nil {
+		return nil
+	}
+	return json.Unmarshal(v, dst)
+}
+
+func put(b *bolt.Bucket, key []byte, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return b.Put(key, data)
+}
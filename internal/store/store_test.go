@@ -0,0 +1,202 @@
+package store
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func openTestStore(t *testing.T, path string) *Store {
+	t.Helper()
+	s, err := Open(path, 30*24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestRecordTempSurvivesReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db", "metrics.db")
+	base := time.Date(2026, 3, 1, 10, 0, 0, 0, time.Local)
+
+	s := openTestStore(t, path)
+	for i, temp := range []float64{40, 44} {
+		if err := s.RecordTemp("cpu", base.Add(time.Duration(i)*time.Minute), temp); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	s = openTestStore(t, path)
+	defer s.Close()
+	if err := s.RecordTemp("cpu", base.Add(30*time.Minute), 39); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RecordTemp("cpu", base.Add(time.Hour), 50); err != nil {
+		t.Fatal(err)
+	}
+
+	aggs, err := s.Temps("cpu", base, base.Add(2*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(aggs) != 2 {
+		t.Fatalf("got %d aggregates, want 2", len(aggs))
+	}
+	first := aggs[0]
+	if !first.Time.Equal(base) || first.Min != 39 || first.Max != 44 || first.Count != 3 || first.Avg() != 41 {
+		t.Errorf("first hour = %+v avg %.1f, want 39-44 over 3 samples avg 41", first, first.Avg())
+	}
+	if aggs[1].Count != 1 || aggs[1].Max != 50 {
+		t.Errorf("second hour = %+v", aggs[1])
+	}
+
+	names, err := s.Series()
+	if err != nil || len(names) != 1 || names[0] != "cpu" {
+		t.Errorf("Series() = %v, %v", names, err)
+	}
+}
+
+func TestAddNetworkTotalsByDay(t *testing.T) {
+	s := openTestStore(t, filepath.Join(t.TempDir(), "metrics.db"))
+	defer s.Close()
+
+	day := time.Date(2026, 3, 1, 0, 0, 0, 0, time.Local)
+	adds := []struct {
+		iface  string
+		at     time.Time
+		rx, tx uint64
+	}{
+		{"eth0", day.Add(time.Hour), 100, 10},
+		{"eth0", day.Add(23 * time.Hour), 50, 5},
+		{"eth0", day.Add(25 * time.Hour), 7, 1},
+		{"wlan0", day.Add(2 * time.Hour), 3, 3},
+	}
+	for _, a := range adds {
+		if err := s.AddNetwork(a.iface, a.at, a.rx, a.tx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	totals, err := s.Network(day.Add(12*time.Hour), day.Add(48*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(totals) != 3 {
+		t.Fatalf("got %d totals, want 3: %+v", len(totals), totals)
+	}
+	want := map[string]NetTotal{
+		"eth0 1":  {RxBytes: 150, TxBytes: 15},
+		"eth0 2":  {RxBytes: 7, TxBytes: 1},
+		"wlan0 1": {RxBytes: 3, TxBytes: 3},
+	}
+	for _, got := range totals {
+		key := fmt.Sprintf("%s %d", got.Interface, got.Date.Day())
+		w, ok := want[key]
+		if !ok || got.RxBytes != w.RxBytes || got.TxBytes != w.TxBytes {
+			t.Errorf("unexpected total %+v", got)
+		}
+	}
+	if totals[len(totals)-1].Date.Day() != 2 {
+		t.Errorf("totals are not sorted by date: %+v", totals)
+	}
+}
+
+func TestRecordSMARTKeepsLatestOfDay(t *testing.T) {
+	s := openTestStore(t, filepath.Join(t.TempDir(), "metrics.db"))
+	defer s.Close()
+
+	day := time.Date(2026, 3, 1, 0, 0, 0, 0, time.Local)
+	if err := s.RecordSMART("wwn-1", day.Add(time.Hour), map[string]int64{"Reallocated_Sector_Ct": 0}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RecordSMART("wwn-1", day.Add(5*time.Hour), map[string]int64{"Reallocated_Sector_Ct": 8}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RecordSMART("wwn-1", day.Add(30*time.Hour), map[string]int64{"Reallocated_Sector_Ct": 9}); err != nil {
+		t.Fatal(err)
+	}
+
+	snaps, err := s.SMART("wwn-1", day, day.Add(48*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snaps) != 2 {
+		t.Fatalf("got %d snapshots, want 2", len(snaps))
+	}
+	if got := snaps[0].Attributes["Reallocated_Sector_Ct"]; got != 8 || !snaps[0].Time.Equal(day.Add(5*time.Hour)) {
+		t.Errorf("first snapshot = %+v, want the 05:00 one", snaps[0])
+	}
+	if snaps, _ := s.SMART("wwn-2", day, day.Add(48*time.Hour)); len(snaps) != 0 {
+		t.Errorf("unknown disk returned %d snapshots", len(snaps))
+	}
+}
+
+func TestPrune(t *testing.T) {
+	s := openTestStore(t, filepath.Join(t.TempDir(), "metrics.db"))
+	defer s.Close()
+
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.Local)
+	old := now.AddDate(0, 0, -40)
+	recent := now.AddDate(0, 0, -2)
+	for _, at := range []time.Time{old, recent} {
+		if err := s.RecordTemp("cpu", at, 40); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.AddNetwork("eth0", at, 1, 1); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.RecordSMART("wwn-1", at, map[string]int64{"Power_On_Hours": 1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := s.Prune(now); err != nil {
+		t.Fatal(err)
+	}
+
+	if aggs, _ := s.Temps("cpu", old.Add(-time.Hour), now); len(aggs) != 1 {
+		t.Errorf("temps after prune = %d, want 1", len(aggs))
+	}
+	if totals, _ := s.Network(old, now); len(totals) != 1 {
+		t.Errorf("network totals after prune = %d, want 1", len(totals))
+	}
+	if snaps, _ := s.SMART("wwn-1", old, now); len(snaps) != 1 {
+		t.Errorf("SMART snapshots after prune = %d, want 1", len(snaps))
+	}
+}
+
+func TestSeedTempsSkipsRecordedHours(t *testing.T) {
+	s := openTestStore(t, filepath.Join(t.TempDir(), "metrics.db"))
+	defer s.Close()
+	base := time.Date(2026, 3, 1, 10, 0, 0, 0, time.Local)
+
+	// The daemon was running from 11:00
+	if err := s.RecordTemp("disk:wwn-1", base.Add(time.Hour+5*time.Minute), 40); err != nil {
+		t.Fatal(err)
+	}
+	samples := []Sample{
+		{Time: base, Temp: 30},
+		{Time: base.Add(30 * time.Minute), Temp: 34},
+		{Time: base.Add(time.Hour), Temp: 38},
+	}
+	added, err := s.SeedTemps("disk:wwn-1", samples)
+	if err != nil || added != 1 {
+		t.Fatalf("SeedTemps() = %d, %v, want the one hour not recorded", added, err)
+	}
+	// Seeding again on the next start adds nothing
+	if added, err := s.SeedTemps("disk:wwn-1", samples); err != nil || added != 0 {
+		t.Errorf("second SeedTemps() = %d, %v, want 0", added, err)
+	}
+
+	aggs, err := s.Temps("disk:wwn-1", base, base.Add(2*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(aggs) != 2 || aggs[0].Count != 2 || aggs[0].Avg() != 32 || aggs[1].Count != 1 || aggs[1].Max != 40 {
+		t.Errorf("aggregates = %+v, want the seeded 10:00 hour and the recorded 11:00 hour", aggs)
+	}
+}
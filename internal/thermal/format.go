@@ -0,0 +1,60 @@
+package thermal
+
+import (
+	"math"
+	"strconv"
+)
+
+// Rounding modes of a Format
+const (
+	RoundNearest = "nearest"
+	RoundDown    = "down"
+	RoundUp      = "up"
+)
+
+// Format is how one surface (OLED, API, logs) presents temperatures
+type Format struct {
+	// Precision is the number of decimals
+	Precision int
+	Rounding  string
+}
+
+// Round rounds t to the precision of the format
+func (f Format) Round(t float64) float64 {
+	scale := math.Pow10(f.Precision)
+	switch f.Rounding {
+	case RoundDown:
+		return math.Floor(t*scale) / scale
+	case RoundUp:
+		return math.Ceil(t*scale) / scale
+	default:
+		return math.Round(t*scale) / scale
+	}
+}
+
+// Number renders t without a unit, e.g. "45.3", converted from Celsius to Fahrenheit
+// when fahrenheit is set
+func (f Format) Number(t float64, fahrenheit bool) string {
+	if fahrenheit {
+		t = t*1.8 + 32
+	}
+	return strconv.FormatFloat(f.Round(t), 'f', f.Precision, 64)
+}
+
+// Unit returns "°C", or "°F" when fahrenheit is set
+func Unit(fahrenheit bool) string {
+	if fahrenheit {
+		return "°F"
+	}
+	return "°C"
+}
+
+// String renders t with its unit, e.g. "45.3°C"
+func (f Format) String(t float64, fahrenheit bool) string {
+	return f.Number(t, fahrenheit) + Unit(fahrenheit)
+}
+
+// Celsius renders t in degrees Celsius, e.g. "45.3°C"
+func (f Format) Celsius(t float64) string {
+	return f.String(t, false)
+}
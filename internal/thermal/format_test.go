@@ -0,0 +1,33 @@
+package thermal
+
+import "testing"
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		format     Format
+		temp       float64
+		fahrenheit bool
+		want       string
+	}{
+		{Format{Precision: 1}, 45.26, false, "45.3°C"},
+		{Format{Precision: 0}, 45.5, false, "46°C"},
+		{Format{Precision: 0, Rounding: RoundDown}, 45.9, false, "45°C"},
+		{Format{Precision: 1, Rounding: RoundUp}, 45.21, false, "45.3°C"},
+		{Format{Precision: 2}, 38, false, "38.00°C"},
+		{Format{Precision: 0}, 45.3, true, "114°F"},
+		{Format{Precision: 1, Rounding: RoundDown}, 45.3, true, "113.5°F"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.format.String(tt.temp, tt.fahrenheit); got != tt.want {
+			t.Errorf("%+v.String(%v, %v) = %q, want %q", tt.format, tt.temp, tt.fahrenheit, got, tt.want)
+		}
+	}
+}
+
+func TestFormatRound(t *testing.T) {
+	f := Format{Precision: 1}
+	if got := f.Round(41.04); got != 41 {
+		t.Errorf("Round(41.04) = %v, want 41", got)
+	}
+}
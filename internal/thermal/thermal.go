@@ -0,0 +1,99 @@
+package thermal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	// ModeMax uses the hottest sensor
+	ModeMax = "max"
+	// ModeAvg averages all readable sensors
+	ModeAvg = "avg"
+)
+
+var sysClassThermal = "/sys/class/thermal"
+
+// DefaultSensors is used when no CPU sensors are configured
+var DefaultSensors = []string{"thermal_zone0"}
+
+// ResolvePath maps a sensor name to the sysfs file holding its temperature in
+// millidegrees. Accepted forms are a zone directory ("thermal_zone1"), a zone type
+// ("cpu-thermal") or an absolute path to a temperature file (e.g. a hwmon temp1_input).
+func ResolvePath(sensor string) (string, error) {
+	if filepath.IsAbs(sensor) {
+		return sensor, nil
+	}
+	if strings.HasPrefix(sensor, "thermal_zone") {
+		return filepath.Join(sysClassThermal, sensor, "temp"), nil
+	}
+
+	zones, _ := filepath.Glob(filepath.Join(sysClassThermal, "thermal_zone*"))
+	for _, zone := range zones {
+		data, err := os.ReadFile(filepath.Join(zone, "type"))
+		if err != nil {
+			continue
+		}
+		if strings.TrimSpace(string(data)) == sensor {
+			return filepath.Join(zone, "temp"), nil
+		}
+	}
+	return "", fmt.Errorf("no thermal zone of type %q", sensor)
+}
+
+// ReadCelsius reads a millidegree temperature file
+func ReadCelsius(path string) (float64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	temp, err := strconv.ParseFloat(strings.TrimSpace(string(data)), 64)
+	if err != nil {
+		return 0, err
+	}
+	return temp / 1000.0, nil
+}
+
+// Read combines the readable sensors with the given mode, failing only when none can be read
+func Read(sensors []string, mode string) (float64, error) {
+	if len(sensors) == 0 {
+		sensors = DefaultSensors
+	}
+
+	var temps []float64
+	var lastErr error
+	for _, sensor := range sensors {
+		path, err := ResolvePath(strings.TrimSpace(sensor))
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		temp, err := ReadCelsius(path)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		temps = append(temps, temp)
+	}
+	if len(temps) == 0 {
+		return 0, fmt.Errorf("no readable CPU sensor: %w", lastErr)
+	}
+
+	result := temps[0]
+	switch mode {
+	case ModeAvg:
+		var sum float64
+		for _, t := range temps {
+			sum += t
+		}
+		result = sum / float64(len(temps))
+	default:
+		for _, t := range temps[1:] {
+			result = max(result, t)
+		}
+	}
+	return result, nil
+}
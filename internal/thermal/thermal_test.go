@@ -0,0 +1,62 @@
+package thermal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeZone(t *testing.T, dir, name, zoneType, temp string) {
+	t.Helper()
+	zone := filepath.Join(dir, name)
+	if err := os.MkdirAll(zone, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(zone, "type"), []byte(zoneType+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(zone, "temp"), []byte(temp+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRead(t *testing.T) {
+	dir := t.TempDir()
+	sysClassThermal = dir
+	t.Cleanup(func() { sysClassThermal = "/sys/class/thermal" })
+
+	writeZone(t, dir, "thermal_zone0", "cpu-thermal", "45000")
+	writeZone(t, dir, "thermal_zone1", "gpu-thermal", "55000")
+
+	hwmon := filepath.Join(dir, "temp1_input")
+	if err := os.WriteFile(hwmon, []byte("50000\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		sensors []string
+		mode    string
+		want    float64
+		wantErr bool
+	}{
+		{"default zone", nil, ModeMax, 45, false},
+		{"by type", []string{"gpu-thermal"}, ModeMax, 55, false},
+		{"max of zones", []string{"thermal_zone0", "gpu-thermal"}, ModeMax, 55, false},
+		{"avg with hwmon", []string{"thermal_zone0", "thermal_zone1", hwmon}, ModeAvg, 50, false},
+		{"skips unreadable", []string{"thermal_zone9", "thermal_zone0"}, ModeMax, 45, false},
+		{"nothing readable", []string{"pmic-thermal"}, ModeMax, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Read(tt.sensors, tt.mode)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Read() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Read() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package ups
+
+import (
+	"context"
+	"fmt"
+
+	i2c "github.com/d2r2/go-i2c"
+)
+
+// MAX17048 registers
+const (
+	max17048RegSOC   = 0x04
+	max17048RegCRate = 0x16
+
+	// MAX17048DefaultAddr is the fixed address of the fuel gauge
+	MAX17048DefaultAddr = 0x36
+)
+
+// MAX17048 is an I2C LiPo fuel gauge found on many UPS HATs. It has no mains sensing,
+// so the battery counts as in use while it discharges.
+type MAX17048 struct {
+	i2c *i2c.I2C
+}
+
+// NewMAX17048 opens the fuel gauge on the given I2C bus
+func NewMAX17048(bus, addr int) (*MAX17048, error) {
+	i2cBus, err := i2c.NewI2C(uint8(addr), bus) // #nosec G115 - addresses are 7-bit
+	if err != nil {
+		return nil, fmt.Errorf("failed to open I2C: %w", err)
+	}
+	return &MAX17048{i2c: i2cBus}, nil
+}
+
+// Read returns the state of charge and whether the cell is discharging
+func (g *MAX17048) Read(context.Context) (Status, error) {
+	soc, err := g.i2c.ReadRegU16BE(max17048RegSOC)
+	if err != nil {
+		return Status{}, fmt.Errorf("failed to read state of charge: %w", err)
+	}
+	crate, err := g.i2c.ReadRegS16BE(max17048RegCRate)
+	if err != nil {
+		return Status{}, fmt.Errorf("failed to read charge rate: %w", err)
+	}
+	return gaugeStatus(soc, crate), nil
+}
+
+// gaugeStatus converts raw registers: SOC is 1/256 %, CRATE is 0.208 %/hour
+func gaugeStatus(soc uint16, crate int16) Status {
+	return Status{
+		Charge:      min(float64(soc)/256, 100),
+		ChargeKnown: true,
+		OnBattery:   float64(crate)*0.208 < -0.5,
+	}
+}
+
+// Close releases the I2C connection
+func (g *MAX17048) Close() error {
+	return g.i2c.Close()
+}
@@ -0,0 +1,111 @@
+package ups
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/kolobock/rockpi-quad-go/internal/logger"
+)
+
+// EventType is a change of the power state
+type EventType string
+
+const (
+	// PowerLost is sent when the system switches to battery
+	PowerLost EventType = "power_lost"
+	// PowerRestored is sent when mains power returns
+	PowerRestored EventType = "power_restored"
+	// LowBattery is sent once per outage when the charge falls to the shutdown threshold
+	LowBattery EventType = "low_battery"
+)
+
+// Event reports a change together with the status that caused it
+type Event struct {
+	Type   EventType
+	Status Status
+}
+
+// Monitor polls a source and reports power events
+type Monitor struct {
+	src            Source
+	shutdownCharge float64
+
+	mu     sync.Mutex
+	status Status
+	known  bool
+	low    bool
+	events chan Event
+}
+
+// NewMonitor creates a monitor; shutdownCharge is the charge in percent at which
+// LowBattery is sent while on battery, 0 to rely on the source's own low flag only,
+// as it is for a source that does not report the charge
+func NewMonitor(src Source, shutdownCharge float64) *Monitor {
+	return &Monitor{src: src, shutdownCharge: shutdownCharge, events: make(chan Event, 4)}
+}
+
+// Events returns the channel receiving power events
+func (m *Monitor) Events() <-chan Event {
+	return m.events
+}
+
+// Status returns the last reading, false before the first successful one
+func (m *Monitor) Status() (Status, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.status, m.known
+}
+
+// record stores a reading and returns the events it causes
+func (m *Monitor) record(st Status) []Event {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var events []Event
+	switch {
+	case st.OnBattery && (!m.known || !m.status.OnBattery):
+		events = append(events, Event{Type: PowerLost, Status: st})
+	case !st.OnBattery && m.known && m.status.OnBattery:
+		events = append(events, Event{Type: PowerRestored, Status: st})
+		m.low = false
+	}
+
+	// Without a reported charge only the source's own low flag counts, or every
+	// outage would look like an empty battery
+	low := st.LowBattery || (m.shutdownCharge > 0 && st.ChargeKnown && st.Charge <= m.shutdownCharge)
+	if st.OnBattery && low && !m.low {
+		m.low = true
+		events = append(events, Event{Type: LowBattery, Status: st})
+	}
+
+	m.status, m.known = st, true
+	return events
+}
+
+// Run polls the source until the context is cancelled, then closes it
+func (m *Monitor) Run(ctx context.Context, interval time.Duration) {
+	defer m.src.Close()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if st, err := m.src.Read(ctx); err != nil {
+			logger.Infof("Failed to read UPS status: %v", err)
+		} else {
+			for _, evt := range m.record(st) {
+				select {
+				case m.events <- evt:
+				default:
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
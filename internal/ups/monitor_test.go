@@ -0,0 +1,67 @@
+package ups
+
+import "testing"
+
+func TestMonitorEvents(t *testing.T) {
+	m := NewMonitor(nil, 20)
+
+	steps := []struct {
+		name   string
+		status Status
+		want   []EventType
+	}{
+		{"on mains", Status{Charge: 100, ChargeKnown: true}, nil},
+		{"power lost", Status{Charge: 90, ChargeKnown: true, OnBattery: true}, []EventType{PowerLost}},
+		{"discharging", Status{Charge: 40, ChargeKnown: true, OnBattery: true}, nil},
+		{"threshold reached", Status{Charge: 20, ChargeKnown: true, OnBattery: true}, []EventType{LowBattery}},
+		{"still low", Status{Charge: 15, ChargeKnown: true, OnBattery: true}, nil},
+		{"power restored", Status{Charge: 16, ChargeKnown: true}, []EventType{PowerRestored}},
+		{"low on mains", Status{Charge: 17, ChargeKnown: true, LowBattery: true}, nil},
+		{"lost again while low", Status{Charge: 17, ChargeKnown: true, OnBattery: true}, []EventType{PowerLost, LowBattery}},
+	}
+
+	for _, step := range steps {
+		var got []EventType
+		for _, evt := range m.record(step.status) {
+			got = append(got, evt.Type)
+		}
+		if len(got) != len(step.want) {
+			t.Errorf("%s: events = %v, want %v", step.name, got, step.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != step.want[i] {
+				t.Errorf("%s: events = %v, want %v", step.name, got, step.want)
+			}
+		}
+	}
+
+	if st, ok := m.Status(); !ok || st.Charge != 17 {
+		t.Errorf("Status() = %+v, %v, want the last reading", st, ok)
+	}
+}
+
+func TestMonitorUnknownCharge(t *testing.T) {
+	m := NewMonitor(nil, 20)
+	st, err := parseUPSC("device.mfr: APC\nups.status: OB DISCHRG\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := m.record(st)
+	if len(events) != 1 || events[0].Type != PowerLost {
+		t.Errorf("events = %+v, want only power lost without a reported charge", events)
+	}
+
+	st.LowBattery = true
+	if events := m.record(st); len(events) != 1 || events[0].Type != LowBattery {
+		t.Errorf("events = %+v, want low battery from the UPS flag", events)
+	}
+}
+
+func TestMonitorSourceLowFlag(t *testing.T) {
+	m := NewMonitor(nil, 0)
+	events := m.record(Status{Charge: 50, ChargeKnown: true, OnBattery: true, LowBattery: true})
+	if len(events) != 2 || events[1].Type != LowBattery {
+		t.Errorf("events = %+v, want power lost and low battery", events)
+	}
+}
@@ -0,0 +1,100 @@
+// Package ups reads UPS and battery state from NUT or an I2C fuel gauge and reports
+// power loss, power restore and low battery.
+package ups
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Sources
+const (
+	SourceNUT      = "nut"
+	SourceMAX17048 = "max17048"
+)
+
+// Status is the power state reported by a source
+type Status struct {
+	// Charge is the battery charge in percent, valid when ChargeKnown is set; some
+	// UPSes do not report it
+	Charge      float64
+	ChargeKnown bool
+	OnBattery   bool
+	// LowBattery is set when the source itself flags the battery as low
+	LowBattery bool
+	// Runtime is the estimated time left on battery, 0 when unknown
+	Runtime time.Duration
+}
+
+// Source reads the current power state
+type Source interface {
+	Read(ctx context.Context) (Status, error)
+	Close() error
+}
+
+// NUT reads a UPS through the Network UPS Tools `upsc` client
+type NUT struct {
+	name string
+}
+
+// NewNUT creates a source for the UPS name, e.g. "ups@localhost"
+func NewNUT(name string) *NUT {
+	return &NUT{name: name}
+}
+
+// Read runs `upsc <name>`
+func (n *NUT) Read(ctx context.Context) (Status, error) {
+	// #nosec G204 - the UPS name comes from the configuration file
+	out, err := exec.CommandContext(ctx, "upsc", n.name).Output()
+	if err != nil {
+		return Status{}, fmt.Errorf("upsc %s failed: %w", n.name, err)
+	}
+	return parseUPSC(string(out))
+}
+
+// Close does nothing; upsc runs per read
+func (n *NUT) Close() error {
+	return nil
+}
+
+// parseUPSC parses `upsc` variables: battery.charge, battery.runtime (seconds) and
+// ups.status flags such as "OL", "OB DISCHRG" or "OB LB"
+func parseUPSC(output string) (Status, error) {
+	var st Status
+	var haveStatus bool
+	for _, line := range strings.Split(output, "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.TrimSpace(key) {
+		case "battery.charge":
+			if charge, err := strconv.ParseFloat(value, 64); err == nil {
+				st.Charge, st.ChargeKnown = charge, true
+			}
+		case "battery.runtime":
+			if secs, err := strconv.ParseFloat(value, 64); err == nil {
+				st.Runtime = time.Duration(secs * float64(time.Second))
+			}
+		case "ups.status":
+			haveStatus = true
+			for _, flag := range strings.Fields(value) {
+				switch flag {
+				case "OB":
+					st.OnBattery = true
+				case "LB":
+					st.LowBattery = true
+				}
+			}
+		}
+	}
+	if !haveStatus {
+		return Status{}, fmt.Errorf("no ups.status in upsc output")
+	}
+	return st, nil
+}
@@ -0,0 +1,75 @@
+package ups
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseUPSC(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  string
+		want    Status
+		wantErr bool
+	}{
+		{
+			name:   "on line",
+			output: "battery.charge: 100\nbattery.runtime: 1800\ndevice.mfr: EATON\nups.status: OL CHRG\n",
+			want:   Status{Charge: 100, ChargeKnown: true, Runtime: 30 * time.Minute},
+		},
+		{
+			name:   "on battery",
+			output: "battery.charge: 64\nbattery.runtime: 540\nups.status: OB DISCHRG\n",
+			want:   Status{Charge: 64, ChargeKnown: true, OnBattery: true, Runtime: 9 * time.Minute},
+		},
+		{
+			name:   "low battery",
+			output: "battery.charge: 9\nups.status: OB LB\n",
+			want:   Status{Charge: 9, ChargeKnown: true, OnBattery: true, LowBattery: true},
+		},
+		{
+			name:   "no charge",
+			output: "battery.runtime: 600\nups.status: OB DISCHRG\n",
+			want:   Status{OnBattery: true, Runtime: 10 * time.Minute},
+		},
+		{
+			name:    "no status",
+			output:  "Init SSL without certificate database\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseUPSC(tt.output)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseUPSC() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseUPSC() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGaugeStatus(t *testing.T) {
+	tests := []struct {
+		name  string
+		soc   uint16
+		crate int16
+		want  Status
+	}{
+		{"charging", 80 << 8, 50, Status{Charge: 80, ChargeKnown: true}},
+		{"idle", 100 << 8, -1, Status{Charge: 100, ChargeKnown: true}},
+		{"discharging", 42<<8 | 128, -100, Status{Charge: 42.5, ChargeKnown: true, OnBattery: true}},
+		{"over full", 101 << 8, 0, Status{Charge: 100, ChargeKnown: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := gaugeStatus(tt.soc, tt.crate); got != tt.want {
+				t.Errorf("gaugeStatus(%d, %d) = %+v, want %+v", tt.soc, tt.crate, got, tt.want)
+			}
+		})
+	}
+}
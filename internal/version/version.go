@@ -0,0 +1,113 @@
+// Package version reports how the binary was built and which optional subsystems a
+// configuration enables, for triaging reports across boards and HATs
+package version
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+
+	"github.com/kolobock/rockpi-quad-go/internal/config"
+)
+
+// Commit and Date are set at build time with
+// -ldflags "-X github.com/kolobock/rockpi-quad-go/internal/version.Commit=... -X ...Date=...";
+// without them the VCS stamp the Go toolchain embeds is used
+var (
+	Commit string
+	Date   string
+)
+
+// readBuildInfo is replaced in tests
+var readBuildInfo = debug.ReadBuildInfo
+
+// Info describes the running binary
+type Info struct {
+	Commit string `json:"commit"`
+	// Date is the build date, or the commit date when the build did not set one
+	Date      string `json:"date"`
+	Modified  bool   `json:"modified,omitempty"`
+	GoVersion string `json:"go_version"`
+	Platform  string `json:"platform"`
+	// Features lists the optional subsystems the configuration enables
+	Features []string `json:"features,omitempty"`
+}
+
+// Get returns the build information with the features of cfg, none when cfg is nil
+func Get(cfg *config.Config) Info {
+	info := Info{
+		Commit:    Commit,
+		Date:      Date,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+		Features:  Features(cfg),
+	}
+	if bi, ok := readBuildInfo(); ok {
+		for _, s := range bi.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				info.Commit = valueOr(info.Commit, s.Value)
+			case "vcs.time":
+				info.Date = valueOr(info.Date, s.Value)
+			case "vcs.modified":
+				info.Modified, _ = strconv.ParseBool(s.Value)
+			}
+		}
+	}
+	info.Commit = valueOr(info.Commit, "unknown")
+	info.Date = valueOr(info.Date, "unknown")
+	return info
+}
+
+// Features lists the optional subsystems cfg enables, e.g. "fan:pwm", "hw-pwm" or
+// "oled:64px"
+func Features(cfg *config.Config) []string {
+	if cfg == nil {
+		return nil
+	}
+	var features []string
+	add := func(on bool, feature string) {
+		if on {
+			features = append(features, feature)
+		}
+	}
+
+	add(cfg.Env.Board != "", "board:"+cfg.Env.Board)
+	add(cfg.Modules.Fan, "fan:"+cfg.Fan.Mode)
+	add(cfg.Modules.Fan && cfg.Fan.HardwarePWM, "hw-pwm")
+	add(cfg.Modules.Fan && cfg.Fan.DryRun, "fan-dry-run")
+	add(cfg.Modules.OLED && cfg.OLED.Enabled, fmt.Sprintf("oled:%dpx", cfg.OLED.Height))
+	add(cfg.Modules.Button, fmt.Sprintf("buttons:%d", len(cfg.Buttons)))
+	add(len(cfg.HATs) > 1, fmt.Sprintf("hats:%d", len(cfg.HATs)))
+	add(cfg.Modules.API, "http-api")
+	add(cfg.Modules.API && cfg.Modules.Metrics, "metrics")
+	add(cfg.Modules.Alerts, "alerts")
+	add(cfg.Modules.DiskMonitor, "disk-monitor")
+	add(cfg.Store.Path != "", "store")
+	add(cfg.SNMP.Enabled, "snmp")
+	add(cfg.UPS.Source != "" && cfg.UPS.Source != "none", "ups:"+cfg.UPS.Source)
+	add(len(cfg.Rails.Sensors) > 0, "rails")
+	return features
+}
+
+// String renders the information for --version
+func (i Info) String() string {
+	commit := i.Commit
+	if i.Modified {
+		commit += "-dirty"
+	}
+	s := fmt.Sprintf("rockpi-quad-go %s, built %s with %s for %s", commit, i.Date, i.GoVersion, i.Platform)
+	if len(i.Features) > 0 {
+		s += "\nfeatures: " + strings.Join(i.Features, " ")
+	}
+	return s
+}
+
+func valueOr(v, fallback string) string {
+	if v == "" {
+		return fallback
+	}
+	return v
+}
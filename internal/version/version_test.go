@@ -0,0 +1,51 @@
+package version
+
+import (
+	"runtime/debug"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/kolobock/rockpi-quad-go/internal/config"
+)
+
+func TestGet(t *testing.T) {
+	old := readBuildInfo
+	defer func() { readBuildInfo = old }()
+	readBuildInfo = func() (*debug.BuildInfo, bool) {
+		return &debug.BuildInfo{Settings: []debug.BuildSetting{
+			{Key: "vcs.revision", Value: "6d08341"},
+			{Key: "vcs.time", Value: "2026-10-01T12:00:00Z"},
+			{Key: "vcs.modified", Value: "true"},
+		}}, true
+	}
+
+	info := Get(nil)
+	if info.Commit != "6d08341" || info.Date != "2026-10-01T12:00:00Z" || !info.Modified || info.Features != nil {
+		t.Errorf("Get() = %+v, want the VCS stamp and no features", info)
+	}
+	if s := info.String(); !strings.HasPrefix(s, "rockpi-quad-go 6d08341-dirty, built 2026-10-01T12:00:00Z with go") {
+		t.Errorf("String() = %q", s)
+	}
+
+	Commit, Date = "abc123", "2026-10-16"
+	defer func() { Commit, Date = "", "" }()
+	if info := Get(nil); info.Commit != "abc123" || info.Date != "2026-10-16" {
+		t.Errorf("Get() = %+v, want the link-time values over the VCS stamp", info)
+	}
+}
+
+func TestFeatures(t *testing.T) {
+	cfg := &config.Config{
+		Fan:     config.FanConfig{Mode: "pwm", HardwarePWM: true},
+		OLED:    config.OLEDConfig{Enabled: true, Height: 64},
+		Buttons: []config.ButtonConfig{{ID: "main"}},
+		Modules: config.ModulesConfig{Fan: true, OLED: true, Button: true, API: true},
+		UPS:     config.UPSConfig{Source: "none"},
+		Env:     config.EnvConfig{Board: "rpi5"},
+	}
+	want := []string{"board:rpi5", "fan:pwm", "hw-pwm", "oled:64px", "buttons:1", "http-api"}
+	if got := Features(cfg); !slices.Equal(got, want) {
+		t.Errorf("Features() = %q, want %q", got, want)
+	}
+}
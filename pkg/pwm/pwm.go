@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type PWM struct {
@@ -14,36 +15,113 @@ type PWM struct {
 	basePath string
 	period   int64
 	inversed bool
+
+	failures     int
+	backoff      time.Duration
+	nextRecovery time.Time
 }
 
+const (
+	minRecoveryBackoff = time.Second
+	maxRecoveryBackoff = 5 * time.Minute
+)
+
+// DefaultFrequency is the PWM frequency used when none is configured (25kHz, 40000ns period)
+const DefaultFrequency = 25000
+
 const defaultPeriod = 40000
 
-func New(chip string, channel int) (*PWM, error) {
+// PeriodFromFrequency converts a frequency in Hz to a period in nanoseconds
+func PeriodFromFrequency(hz int) (int64, error) {
+	if hz <= 0 || hz > 1000000 {
+		return 0, fmt.Errorf("PWM frequency %d Hz out of range 1-1000000", hz)
+	}
+	return int64(1e9 / hz), nil
+}
+
+// Path returns the sysfs directory of a PWM channel
+func Path(chip string, channel int) string {
+	return fmt.Sprintf("/sys/class/pwm/%s/pwm%d", chip, channel)
+}
+
+// New exports and enables a PWM channel with the given frequency in Hz (0 for the default)
+func New(chip string, channel, hz int) (*PWM, error) {
+	period := int64(defaultPeriod)
+	if hz != 0 {
+		var err error
+		if period, err = PeriodFromFrequency(hz); err != nil {
+			return nil, err
+		}
+	}
+
 	p := &PWM{
 		chip:     chip,
 		channel:  channel,
-		basePath: fmt.Sprintf("/sys/class/pwm/%s/pwm%d", chip, channel),
-		period:   defaultPeriod,
+		basePath: Path(chip, channel),
+		period:   period,
 	}
 
 	if _, err := os.Stat(p.basePath); os.IsNotExist(err) {
-		exportPath := "/sys/class/pwm/" + chip + "/export"
-		if err := os.WriteFile(exportPath, []byte(strconv.Itoa(channel)), 0600); err != nil {
-			if !strings.Contains(err.Error(), "device or resource busy") {
-				return nil, fmt.Errorf("failed to export PWM: %w", err)
-			}
+		if err := p.export(); err != nil {
+			return nil, err
 		}
 	}
 
-	if err := p.writeSysfs("period", strconv.FormatInt(p.period, 10)); err != nil {
+	if err := p.configure(); err != nil {
 		return nil, err
 	}
 
-	if err := p.writeSysfs("enable", "1"); err != nil {
-		return nil, err
+	return p, nil
+}
+
+func (p *PWM) export() error {
+	exportPath := filepath.Join(filepath.Dir(p.basePath), "export")
+	if err := os.WriteFile(exportPath, []byte(strconv.Itoa(p.channel)), 0600); err != nil {
+		if !strings.Contains(err.Error(), "device or resource busy") {
+			return fmt.Errorf("failed to export PWM: %w", err)
+		}
 	}
+	return nil
+}
 
-	return p, nil
+// reinit unexports and re-exports the channel and programs it again, used to
+// recover after the chip was reset (suspend, driver reload)
+func (p *PWM) reinit() error {
+	unexportPath := filepath.Join(filepath.Dir(p.basePath), "unexport")
+	_ = os.WriteFile(unexportPath, []byte(strconv.Itoa(p.channel)), 0600)
+
+	if err := p.export(); err != nil {
+		return err
+	}
+	if err := p.configure(); err != nil {
+		return err
+	}
+	if p.inversed {
+		p.SetInversed(true)
+	}
+	return nil
+}
+
+// configure programs the period with the channel disabled, since some controllers
+// reject period changes while enabled, then verifies the chip accepted it
+func (p *PWM) configure() error {
+	// Ignore errors: a freshly exported channel may already be disabled, and
+	// duty_cycle must not exceed the new period when shrinking it
+	_ = p.writeSysfs("enable", "0")
+	_ = p.writeSysfs("duty_cycle", "0")
+
+	if err := p.writeSysfs("period", strconv.FormatInt(p.period, 10)); err != nil {
+		return fmt.Errorf("PWM %s/pwm%d rejected period %dns: %w", p.chip, p.channel, p.period, err)
+	}
+
+	if got, err := p.readSysfs("period"); err == nil && got != p.period {
+		return fmt.Errorf("PWM %s/pwm%d period is %dns, requested %dns", p.chip, p.channel, got, p.period)
+	}
+
+	if err := p.writeSysfs("enable", "1"); err != nil {
+		return fmt.Errorf("failed to enable PWM: %w", err)
+	}
+	return nil
 }
 
 func (p *PWM) SetInversed(inversed bool) {
@@ -56,13 +134,48 @@ func (p *PWM) SetInversed(inversed bool) {
 	// Ignore error, as polarity may not be supported on all systems
 }
 
+// SetDutyCycle sets the duty cycle (0-1). If the write fails the channel is
+// re-exported and the write retried, backing off between recovery attempts.
 func (p *PWM) SetDutyCycle(dutyCycle float64) error {
 	if p.inversed {
 		dutyCycle = 1.0 - dutyCycle
 	}
 
-	duty := int64(float64(p.period) * dutyCycle)
-	return p.writeSysfs("duty_cycle", strconv.FormatInt(duty, 10))
+	duty := strconv.FormatInt(int64(float64(p.period)*dutyCycle), 10)
+	err := p.writeSysfs("duty_cycle", duty)
+	if err == nil {
+		p.failures = 0
+		p.backoff = 0
+		return nil
+	}
+
+	p.failures++
+	if time.Now().Before(p.nextRecovery) {
+		return err
+	}
+
+	if rerr := p.reinit(); rerr != nil {
+		p.backoff = min(max(p.backoff*2, minRecoveryBackoff), maxRecoveryBackoff)
+		p.nextRecovery = time.Now().Add(p.backoff)
+		return fmt.Errorf("%w (recovery failed, next attempt in %s: %v)", err, p.backoff, rerr)
+	}
+
+	if err := p.writeSysfs("duty_cycle", duty); err != nil {
+		return err
+	}
+	p.failures = 0
+	p.backoff = 0
+	return nil
+}
+
+// Healthy reports whether the last duty cycle write succeeded
+func (p *PWM) Healthy() bool {
+	return p.failures == 0
+}
+
+// Failures returns the number of consecutive failed duty cycle writes
+func (p *PWM) Failures() int {
+	return p.failures
 }
 
 func (p *PWM) Close() error {
@@ -72,6 +185,14 @@ func (p *PWM) Close() error {
 	return nil
 }
 
+func (p *PWM) readSysfs(filename string) (int64, error) {
+	data, err := os.ReadFile(filepath.Join(p.basePath, filename))
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+}
+
 func (p *PWM) writeSysfs(filename, value string) error {
 	path := filepath.Join(p.basePath, filename)
 	return os.WriteFile(path, []byte(value), 0600)
@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestPWMDutyCycleCalculation(t *testing.T) {
@@ -54,3 +55,75 @@ func TestPWMWriteSysfs(t *testing.T) {
 		t.Errorf("writeSysfs wrote %q, want %q", string(content), testValue)
 	}
 }
+
+func TestPeriodFromFrequency(t *testing.T) {
+	tests := []struct {
+		hz      int
+		want    int64
+		wantErr bool
+	}{
+		{25000, 40000, false},
+		{100, 10000000, false},
+		{0, 0, true},
+		{2000000, 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := PeriodFromFrequency(tt.hz)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("PeriodFromFrequency(%d) = %v, %v; want %v, err %v", tt.hz, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestPWMConfigure(t *testing.T) {
+	tmpDir := t.TempDir()
+	p := &PWM{basePath: tmpDir, period: 50000}
+
+	if err := p.configure(); err != nil {
+		t.Fatalf("configure failed: %v", err)
+	}
+
+	for file, want := range map[string]string{"period": "50000", "duty_cycle": "0", "enable": "1"} {
+		content, err := os.ReadFile(filepath.Join(tmpDir, file))
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", file, err)
+		}
+		if string(content) != want {
+			t.Errorf("%s = %q, want %q", file, string(content), want)
+		}
+	}
+}
+
+func TestPWMRecovery(t *testing.T) {
+	chipDir := t.TempDir()
+	p := &PWM{basePath: filepath.Join(chipDir, "pwm0"), period: 40000}
+
+	// Channel directory missing: write fails and recovery cannot configure it yet
+	if err := p.SetDutyCycle(0.5); err == nil {
+		t.Fatal("expected error with missing channel directory")
+	}
+	if p.Healthy() || p.Failures() != 1 {
+		t.Errorf("Healthy = %v, Failures = %d; want false, 1", p.Healthy(), p.Failures())
+	}
+	if _, err := os.Stat(filepath.Join(chipDir, "export")); err != nil {
+		t.Errorf("recovery did not write export: %v", err)
+	}
+
+	// Still backing off: no recovery attempt
+	if err := p.SetDutyCycle(0.5); err == nil {
+		t.Fatal("expected error while backing off")
+	}
+
+	// Channel reappears and the backoff elapses
+	if err := os.Mkdir(p.basePath, 0750); err != nil {
+		t.Fatalf("mkdir failed: %v", err)
+	}
+	p.nextRecovery = time.Time{}
+	if err := p.SetDutyCycle(0.5); err != nil {
+		t.Fatalf("SetDutyCycle after recovery failed: %v", err)
+	}
+	if !p.Healthy() {
+		t.Error("PWM should be healthy after recovery")
+	}
+}